@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inercia/go-llm/pkg/bench"
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// runBench load-tests the configured client with concurrent streams
+func runBench(ctx context.Context, a *app, args []string) error {
+	var f clientFlags
+	var config bench.Config
+	var prompt string
+	fs := a.flagSet("bench", "")
+	f.register(fs)
+	fs.IntVar(&config.Concurrency, "concurrency", 4, "number of concurrent streams")
+	fs.IntVar(&config.Requests, "requests", 0, "total number of requests (until -duration elapses when zero)")
+	fs.DurationVar(&config.Duration, "duration", 0, "length of the test")
+	fs.DurationVar(&config.RequestTimeout, "request-timeout", 0, "timeout of each request")
+	fs.StringVar(&prompt, "prompt", "", "prompt of the requests (a short message when empty)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	client, err := f.client()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if prompt != "" {
+		model := client.GetModelInfo().Name
+		config.Request = func(int) llm.ChatRequest {
+			return llm.ChatRequest{
+				Model:    model,
+				Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, prompt)},
+				Stream:   true,
+			}
+		}
+	}
+
+	report, err := bench.Run(ctx, client, config)
+	if err != nil {
+		return err
+	}
+	if f.json {
+		return report.WriteJSON(a.stdout)
+	}
+
+	_, _ = fmt.Fprintf(a.stdout, "%s/%s: %d requests, %d failed in %.1fs (concurrency %d)\n",
+		report.Provider, report.Model, report.Requests, report.Failures, report.DurationSeconds, report.Concurrency)
+	_, _ = fmt.Fprintf(a.stdout, "  Throughput: %.2f req/s, %.0f chars/s\n",
+		report.Throughput.RequestsPerSecond, report.Throughput.CharsPerSecond)
+	_, _ = fmt.Fprintf(a.stdout, "  TTFT:       p50 %.0fms, p95 %.0fms, p99 %.0fms\n", report.TTFT.P50, report.TTFT.P95, report.TTFT.P99)
+	_, _ = fmt.Fprintf(a.stdout, "  Latency:    p50 %.0fms, p95 %.0fms, p99 %.0fms\n", report.Latency.P50, report.Latency.P95, report.Latency.P99)
+	for code, count := range report.Errors {
+		_, _ = fmt.Fprintf(a.stdout, "  Error %s: %d\n", code, count)
+	}
+	return nil
+}
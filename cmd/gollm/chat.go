@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// chatFlags are the flags of the chat command
+type chatFlags struct {
+	clientFlags
+	system      string
+	stream      bool
+	jsonMode    bool
+	interactive bool
+	temperature float64
+	maxTokens   int
+}
+
+// runChat sends a prompt, from the arguments or stdin, and prints the answer. In
+// interactive mode, every line of stdin is a turn of the conversation.
+func runChat(ctx context.Context, a *app, args []string) error {
+	var f chatFlags
+	fs := a.flagSet("chat", "[prompt]")
+	f.register(fs)
+	fs.StringVar(&f.system, "system", "", "system prompt")
+	fs.BoolVar(&f.stream, "stream", true, "stream the answer as it is generated")
+	fs.BoolVar(&f.jsonMode, "json-mode", false, "ask the model for a JSON object")
+	fs.BoolVar(&f.interactive, "i", false, "interactive conversation: every line of stdin is a turn")
+	fs.Float64Var(&f.temperature, "temperature", -1, "sampling temperature (provider default when negative)")
+	fs.IntVar(&f.maxTokens, "max-tokens", 0, "maximum tokens of the answer (provider default when zero)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	client, err := f.client()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	var messages []llm.Message
+	if f.system != "" {
+		messages = append(messages, llm.NewTextMessage(llm.RoleSystem, f.system))
+	}
+
+	if !f.interactive {
+		prompt := strings.Join(fs.Args(), " ")
+		if prompt == "" {
+			data, err := io.ReadAll(a.stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read the prompt: %w", err)
+			}
+			prompt = strings.TrimSpace(string(data))
+		}
+		if prompt == "" {
+			return usageError{"no prompt given in the arguments or stdin"}
+		}
+		_, err := f.send(ctx, a, client, append(messages, llm.NewTextMessage(llm.RoleUser, prompt)))
+		return err
+	}
+
+	lines := bufio.NewScanner(a.stdin)
+	lines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		if !f.json {
+			_, _ = fmt.Fprint(a.stderr, "> ")
+		}
+		if !lines.Scan() {
+			return lines.Err()
+		}
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+
+		messages = append(messages, llm.NewTextMessage(llm.RoleUser, line))
+		answer, err := f.send(ctx, a, client, messages)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, answer)
+	}
+}
+
+// send sends the conversation and prints the answer, returning it
+func (f *chatFlags) send(ctx context.Context, a *app, client llm.Client, messages []llm.Message) (llm.Message, error) {
+	req := llm.ChatRequest{Model: client.GetModelInfo().Name, Messages: messages, Stream: f.stream}
+	if f.temperature >= 0 {
+		temperature := float32(f.temperature)
+		req.Temperature = &temperature
+	}
+	if f.maxTokens > 0 {
+		req.MaxTokens = &f.maxTokens
+	}
+	if f.jsonMode {
+		req.ResponseFormat = &llm.ResponseFormat{Type: llm.ResponseFormatJSON}
+	}
+
+	if !f.stream {
+		resp, err := client.ChatCompletion(ctx, req)
+		if err != nil {
+			return llm.Message{}, err
+		}
+		if len(resp.Choices) == 0 {
+			return llm.Message{}, fmt.Errorf("the response has no choices")
+		}
+		if f.json {
+			return resp.Choices[0].Message, a.writeJSON(resp)
+		}
+		answer := resp.Choices[0].Message
+		_, err = fmt.Fprintln(a.stdout, answer.GetText())
+		return answer, err
+	}
+
+	stream, err := client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	encoder := llm.NewNDJSONEncoder(a.stdout)
+
+	var text strings.Builder
+	for event := range stream {
+		if f.json {
+			if err := encoder.Encode(event); err != nil {
+				return llm.Message{}, err
+			}
+		}
+		switch {
+		case event.IsDelta():
+			for _, content := range event.Choice.Delta.Content {
+				if chunk, ok := content.(*llm.TextContent); ok {
+					text.WriteString(chunk.GetText())
+					if !f.json {
+						_, _ = fmt.Fprint(a.stdout, chunk.GetText())
+					}
+				}
+			}
+		case event.IsError():
+			return llm.Message{}, event.Error
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return llm.Message{}, err
+	}
+	if !f.json {
+		_, _ = fmt.Fprintln(a.stdout)
+	}
+	return llm.NewTextMessage(llm.RoleAssistant, text.String()), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/factory"
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// clientFlags are the flags configuring the client, shared by all the commands
+type clientFlags struct {
+	provider string
+	model    string
+	apiKey   string
+	baseURL  string
+	timeout  time.Duration
+	extra    extraFlag
+	json     bool
+}
+
+// register adds the flags to a flag set, with their environment variable defaults
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.provider, "provider", os.Getenv("GOLLM_PROVIDER"),
+		"provider (openai, gemini, ollama...), detected from the environment when unset [$GOLLM_PROVIDER]")
+	fs.StringVar(&f.model, "model", os.Getenv("GOLLM_MODEL"), "model name [$GOLLM_MODEL]")
+	fs.StringVar(&f.apiKey, "api-key", os.Getenv("GOLLM_API_KEY"), "API key [$GOLLM_API_KEY]")
+	fs.StringVar(&f.baseURL, "base-url", os.Getenv("GOLLM_BASE_URL"), "API base URL [$GOLLM_BASE_URL]")
+	fs.DurationVar(&f.timeout, "timeout", 0, "timeout of the requests of the client")
+	fs.Var(&f.extra, "extra", "provider-specific setting as key=value (repeatable)")
+	fs.BoolVar(&f.json, "json", false, "write machine-readable JSON output")
+}
+
+// config returns the client configuration: the flags override the configuration
+// detected from the environment, which is only used when no provider is given
+func (f *clientFlags) config() llm.ClientConfig {
+	var config llm.ClientConfig
+	if f.provider == "" {
+		config = detectConfig()
+	} else {
+		config.Provider = f.provider
+	}
+
+	if f.model != "" {
+		config.Model = f.model
+	}
+	if f.apiKey != "" {
+		config.APIKey = f.apiKey
+	}
+	if f.baseURL != "" {
+		config.BaseURL = f.baseURL
+	}
+	if f.timeout > 0 {
+		config.Timeout = f.timeout
+	}
+	if len(f.extra) > 0 {
+		if config.Extra == nil {
+			config.Extra = make(map[string]string, len(f.extra))
+		}
+		for key, value := range f.extra {
+			config.Extra[key] = value
+		}
+	}
+	return config
+}
+
+// client creates the configured client with the factory
+func (f *clientFlags) client() (llm.Client, error) {
+	config := f.config()
+	if config.Model == "" {
+		return nil, usageError{fmt.Sprintf("no model given for provider %s (use -model or $GOLLM_MODEL)", config.Provider)}
+	}
+	return factory.New().CreateClient(config)
+}
+
+// detectConfig returns the configuration of llm.GetLLMFromEnv, whose report of the
+// selected provider goes to stderr, as stdout is reserved for the output
+func detectConfig() llm.ClientConfig {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+	return llm.GetLLMFromEnv()
+}
+
+// extraFlag collects key=value settings
+type extraFlag map[string]string
+
+func (e *extraFlag) String() string {
+	pairs := make([]string, 0, len(*e))
+	for key, value := range *e {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (e *extraFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if *e == nil {
+		*e = make(extraFlag)
+	}
+	(*e)[key] = val
+	return nil
+}
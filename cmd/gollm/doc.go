@@ -0,0 +1,26 @@
+// Package main implements gollm, a command-line tool for smoke-testing the providers
+// configured for go-llm.
+//
+// The clients are created with the factory package, from flags or, when no provider is
+// given, from the environment variables read by llm.GetLLMFromEnv (OPENAI_API_KEY,
+// GEMINI_API_KEY...). The client flags fall back to the GOLLM_PROVIDER, GOLLM_MODEL,
+// GOLLM_API_KEY and GOLLM_BASE_URL environment variables.
+//
+// Commands:
+//   - chat: Sends a prompt (from the arguments or stdin), streaming the answer by default,
+//     optionally in JSON mode (-json-mode) or as an interactive conversation (-i)
+//   - models: Lists the registered providers and the information of the configured model
+//   - probe: Tests the capabilities of the configured model (llm.ProbeCapabilities)
+//   - bench: Load-tests the configured client with concurrent streams (bench.Run)
+//
+// Usage:
+//
+//	gollm chat -provider openai -model gpt-4o-mini "Say hello"
+//	echo '{"city": "Paris"}' | gollm chat -json-mode -system "Add the country field"
+//	gollm probe -provider ollama -model llama3.1 -json
+//	gollm bench -concurrency 10 -requests 100
+//
+// With -json, the commands write machine-readable output: the response of chat (or its
+// events as NDJSON when streaming), the model information, the capability report and
+// the load test report.
+package main
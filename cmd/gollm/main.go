@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// command runs a subcommand with its arguments
+type command struct {
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+}
+
+// commands are the subcommands, by name
+var commands = map[string]command{
+	"chat":   {"send a prompt and print the answer", runChat},
+	"models": {"list the providers and the configured model", runModels},
+	"probe":  {"test the capabilities of the configured model", runProbe},
+	"bench":  {"load-test the configured client", runBench},
+}
+
+// app holds the standard streams of the command
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr})
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command line and returns the exit code: 0 on success, 1 on failure and
+// 2 on usage errors
+func run(ctx context.Context, args []string, a *app) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		a.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(a.stderr, "gollm: unknown command %q\n\n", args[0])
+		a.usage()
+		return 2
+	}
+	err := cmd.run(ctx, a, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errFlags):
+		// Already reported by the flag set
+		return 2
+	}
+
+	_, _ = fmt.Fprintf(a.stderr, "gollm %s: %v\n", args[0], err)
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		return 2
+	}
+	return 1
+}
+
+// usage prints the commands
+func (a *app) usage() {
+	_, _ = fmt.Fprintln(a.stderr, "Usage: gollm <command> [flags] [arguments]")
+	_, _ = fmt.Fprintln(a.stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(a.stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+	_, _ = fmt.Fprintln(a.stderr, "\nRun 'gollm <command> -h' for the flags of a command.")
+}
+
+// flagSet returns the flag set of a command, printing its usage to stderr
+func (a *app) flagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(a.stderr, "Usage: gollm %s [flags] %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// errFlags is returned for invalid flags
+var errFlags = errors.New("invalid flags")
+
+// parseFlags parses the flags of a command, returning flag.ErrHelp when help was
+// requested and errFlags for invalid flags
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return errFlags
+}
+
+// usageError is an invalid command line
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+// writeJSON writes a value as indented JSON
+func (a *app) writeJSON(v any) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
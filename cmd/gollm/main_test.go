@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// runCommand runs a command line with the mock provider and returns its exit code and
+// output
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	a := &app{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := run(context.Background(), args, a)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestChat(t *testing.T) {
+	code, stdout, stderr := runCommand(t, "", "chat", "-provider", "mock", "-model", "m", "-stream=false", "hello")
+	if code != 0 || stdout != "Hello! How can I help you today?\n" {
+		t.Fatalf("Unexpected output (exit %d): %q %q", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCommand(t, "hello from stdin", "chat", "-provider", "mock", "-model", "m", "-stream=false", "-json")
+	var resp llm.ChatResponse
+	if code != 0 || json.Unmarshal([]byte(stdout), &resp) != nil || len(resp.Choices) != 1 {
+		t.Errorf("Expected a JSON response (exit %d): %q", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(t, "", "chat", "-provider", "mock", "-model", "m", "hello")
+	if code != 0 || strings.TrimSpace(stdout) == "" {
+		t.Errorf("Expected the streamed answer (exit %d): %q", code, stdout)
+	}
+}
+
+func TestChat_Interactive(t *testing.T) {
+	code, stdout, _ := runCommand(t, "hello\n\nhelp\n", "chat", "-provider", "mock", "-model", "m", "-stream=false", "-i")
+	if code != 0 || strings.Count(stdout, "\n") != 2 {
+		t.Errorf("Expected two answers (exit %d): %q", code, stdout)
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	if code, _, stderr := runCommand(t, "", "unknown"); code != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("Expected a usage error for an unknown command, got %d: %q", code, stderr)
+	}
+	if code, _, stderr := runCommand(t, "", "chat", "-provider", "mock", "-model", "m"); code != 2 || !strings.Contains(stderr, "no prompt") {
+		t.Errorf("Expected a usage error without prompt, got %d: %q", code, stderr)
+	}
+	if code, _, _ := runCommand(t, "", "chat", "-unknown-flag"); code != 2 {
+		t.Errorf("Expected a usage error for an unknown flag, got %d", code)
+	}
+	if code, _, _ := runCommand(t, "", "probe", "-h"); code != 0 {
+		t.Errorf("Expected help to succeed, got %d", code)
+	}
+}
+
+func TestModels(t *testing.T) {
+	code, stdout, _ := runCommand(t, "", "models", "-provider", "mock", "-model", "m", "-api-key", "secret", "-json")
+	var output modelsOutput
+	if code != 0 || json.Unmarshal([]byte(stdout), &output) != nil {
+		t.Fatalf("Expected the JSON output (exit %d): %q", code, stdout)
+	}
+	if output.Model == nil || output.Model.Name != "m" || len(output.Providers) == 0 {
+		t.Errorf("Unexpected output: %+v", output)
+	}
+	if strings.Contains(stdout, "secret") {
+		t.Error("Expected the API key not to be printed")
+	}
+}
+
+func TestClientFlags_Config(t *testing.T) {
+	f := clientFlags{provider: "ollama", model: "llama3.1"}
+	if err := f.extra.Set("keep_alive=10m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.extra.Set("invalid"); err == nil {
+		t.Error("Expected an error for a setting without value")
+	}
+
+	config := f.config()
+	if config.Provider != "ollama" || config.Model != "llama3.1" || config.Extra["keep_alive"] != "10m" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/factory"
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// modelsOutput is the JSON output of the models command
+type modelsOutput struct {
+	Providers []string         `json:"providers"`
+	Provider  string           `json:"provider,omitempty"`
+	Model     *llm.ModelInfo   `json:"model,omitempty"`
+	Config    llm.ClientConfig `json:"config"`
+}
+
+// runModels lists the registered providers and the information of the configured model
+func runModels(ctx context.Context, a *app, args []string) error {
+	var f clientFlags
+	fs := a.flagSet("models", "")
+	f.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	config := f.config()
+	config.APIKey = "" // not printed
+	output := modelsOutput{Providers: factory.ListProviders(), Provider: config.Provider, Config: config}
+
+	// The configured model is only described when the client can be created
+	client, err := f.client()
+	if err == nil {
+		defer func() { _ = client.Close() }()
+		info := client.GetModelInfo()
+		output.Model = &info
+	}
+
+	if f.json {
+		return a.writeJSON(output)
+	}
+	_, _ = fmt.Fprintf(a.stdout, "Providers: %s\n", strings.Join(output.Providers, ", "))
+	if output.Model == nil {
+		_, _ = fmt.Fprintf(a.stdout, "Configured: %s/%s (%v)\n", config.Provider, config.Model, err)
+		return nil
+	}
+
+	info := output.Model
+	_, _ = fmt.Fprintf(a.stdout, "Configured: %s/%s\n", info.Provider, info.Name)
+	_, _ = fmt.Fprintf(a.stdout, "  Max tokens: %d\n", info.MaxTokens)
+	_, _ = fmt.Fprintf(a.stdout, "  Tools: %v, vision: %v, files: %v, streaming: %v\n",
+		info.SupportsTools, info.SupportsVision, info.SupportsFiles, info.SupportsStreaming)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// runProbe tests the capabilities of the configured model
+func runProbe(ctx context.Context, a *app, args []string) error {
+	var f clientFlags
+	var capabilities string
+	config := llm.DefaultProbeConfig()
+	fs := a.flagSet("probe", "")
+	f.register(fs)
+	fs.StringVar(&capabilities, "capabilities", "",
+		"comma-separated capabilities to probe (tools, json_mode, vision, streaming, max_output), all when empty")
+	fs.DurationVar(&config.Timeout, "probe-timeout", config.Timeout, "timeout of each probe request")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if capabilities != "" {
+		config.Capabilities = strings.Split(capabilities, ",")
+	}
+
+	client, err := f.client()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	report, err := llm.ProbeCapabilities(ctx, client, config)
+	if err != nil {
+		return err
+	}
+	if f.json {
+		return a.writeJSON(report)
+	}
+
+	_, _ = fmt.Fprintf(a.stdout, "%s/%s\n", report.ModelInfo.Provider, report.ModelInfo.Name)
+	for _, result := range report.Results {
+		status := "supported"
+		if !result.Supported {
+			status = "unsupported"
+			if result.Error != nil {
+				status += ": " + result.Error.Message
+			}
+		}
+		_, _ = fmt.Fprintf(a.stdout, "  %-11s %-8s %s\n", result.Capability, result.Latency.Round(time.Millisecond), status)
+	}
+	if report.MaxOutputTokens > 0 {
+		_, _ = fmt.Fprintf(a.stdout, "  Max output tokens: %d\n", report.MaxOutputTokens)
+	}
+	return nil
+}
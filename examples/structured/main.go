@@ -96,6 +96,13 @@ func main() {
 	if err != nil {
 		log.Printf("Basic JSON mode example failed: %v", err)
 	}
+
+	// Example 5: Typed client (schema, parsing, validation and retries in one call)
+	fmt.Println("\n=== Example 5: Typed Client ===")
+	err = demonstrateTypedClient(ctx, client)
+	if err != nil {
+		log.Printf("Typed client example failed: %v", err)
+	}
 }
 
 func demonstrateTextAnalysis(ctx context.Context, client llm.Client, model string) error {
@@ -281,3 +288,16 @@ func demonstrateBasicJSONMode(ctx context.Context, client llm.Client, model stri
 
 	return nil
 }
+
+func demonstrateTypedClient(ctx context.Context, client llm.Client) error {
+	// The schema is generated from the type, and invalid answers are sent back to the
+	// model with the validation error
+	solution, err := llm.Typed[MathProblemSolution](client).Complete(ctx, "Solve 3x + 5 = 20")
+	if err != nil {
+		return fmt.Errorf("typed completion failed: %w", err)
+	}
+
+	fmt.Printf("Method: %s\n", solution.Method)
+	fmt.Printf("Final Answer: %s (%d steps)\n", solution.FinalAnswer, len(solution.Steps))
+	return nil
+}
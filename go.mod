@@ -9,6 +9,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/bedrockruntime v1.41.1
 	github.com/aws/smithy-go v1.23.1
 	github.com/cohesion-org/deepseek-go v1.3.2
+	github.com/gorilla/websocket v1.5.3
 	github.com/revrost/go-openrouter v0.2.6
 	github.com/sashabaranov/go-openai v1.41.2
 	github.com/stretchr/testify v1.11.1
@@ -40,7 +41,6 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.3.6 // indirect
 	github.com/googleapis/gax-go/v2 v2.15.0 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/ollama/ollama v0.12.5 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
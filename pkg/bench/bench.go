@@ -0,0 +1,299 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// Default values of Config
+const (
+	DefaultConcurrency    = 10
+	DefaultRequests       = 100
+	DefaultSampleInterval = 100 * time.Millisecond
+)
+
+// Config configures a load test
+type Config struct {
+	// Concurrency is the number of workers streaming completions in parallel
+	Concurrency int `json:"concurrency"`
+
+	// Requests is the total number of requests sent. When zero, workers send requests
+	// until Duration elapses (or DefaultRequests when Duration is zero too).
+	Requests int `json:"requests,omitempty"`
+
+	// Duration limits the length of the test. Requests in flight when it elapses are
+	// cancelled and reported as errors.
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// RequestTimeout limits each request, without limit when zero
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"`
+
+	// Request returns the i-th request of the test. Defaults to a short user message.
+	Request func(i int) llm.ChatRequest `json:"-"`
+
+	// SampleInterval is the period of the memory and goroutine samples
+	SampleInterval time.Duration `json:"sample_interval,omitempty"`
+}
+
+// ClientFactory returns the client used by a worker, for clients that cannot be shared
+// between goroutines (e.g. mock clients)
+type ClientFactory func(worker int) (llm.Client, error)
+
+// Run drives streaming load against a client shared by all the workers and reports the
+// measurements
+func Run(ctx context.Context, client llm.Client, config Config) (*Report, error) {
+	return RunPerWorker(ctx, func(int) (llm.Client, error) { return client, nil }, config)
+}
+
+// RunPerWorker drives streaming load with a client per worker, created by factory, and
+// reports the measurements
+func RunPerWorker(ctx context.Context, factory ClientFactory, config Config) (*Report, error) {
+	config = withDefaults(config)
+
+	clients := make([]llm.Client, config.Concurrency)
+	for worker := range clients {
+		client, err := factory(worker)
+		if err != nil {
+			return nil, err
+		}
+		clients[worker] = client
+	}
+
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	info := clients[0].GetModelInfo()
+	report := &Report{
+		Provider:    info.Provider,
+		Model:       info.Name,
+		Concurrency: config.Concurrency,
+		Errors:      make(map[string]int),
+		StartedAt:   time.Now(),
+	}
+
+	sampler := startSampler(config.SampleInterval)
+	collector := &collector{}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client llm.Client) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if config.Requests > 0 && i >= config.Requests {
+					return
+				}
+				collector.add(streamOnce(ctx, client, config, i))
+			}
+		}(client)
+	}
+	wg.Wait()
+
+	report.DurationSeconds = time.Since(report.StartedAt).Seconds()
+	report.Memory, report.Goroutines = sampler.stop()
+	collector.summarize(report)
+	return report, nil
+}
+
+// withDefaults fills the unset fields of a configuration
+func withDefaults(config Config) Config {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	if config.Requests <= 0 && config.Duration <= 0 {
+		config.Requests = DefaultRequests
+	}
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = DefaultSampleInterval
+	}
+	if config.Request == nil {
+		config.Request = func(int) llm.ChatRequest {
+			return llm.ChatRequest{Messages: []llm.Message{
+				llm.NewTextMessage(llm.RoleUser, "Write a short paragraph about the sea."),
+			}}
+		}
+	}
+	return config
+}
+
+// result holds the measurements of a request
+type result struct {
+	ttft    time.Duration // zero when no content was received
+	latency time.Duration
+	events  int
+	chars   int
+	errCode string // empty on success
+}
+
+// streamOnce sends a streaming request and consumes the whole stream
+func streamOnce(ctx context.Context, client llm.Client, config Config, i int) result {
+	if config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.RequestTimeout)
+		defer cancel()
+	}
+
+	var r result
+	start := time.Now()
+	stream, err := client.StreamChatCompletion(ctx, config.Request(i))
+	if err != nil {
+		r.latency = time.Since(start)
+		r.errCode = errorCode(err)
+		return r
+	}
+
+	for event := range stream {
+		switch {
+		case event.IsError():
+			if r.errCode == "" {
+				r.errCode = errorCode(event.Error)
+			}
+		case event.IsDelta():
+			r.events++
+			for _, content := range event.Choice.Delta.Content {
+				if text, ok := content.(*llm.TextContent); ok && text.GetText() != "" {
+					if r.ttft == 0 {
+						r.ttft = time.Since(start)
+					}
+					r.chars += len(text.GetText())
+				}
+			}
+		}
+	}
+	r.latency = time.Since(start)
+
+	// Streams closed by a cancelled context end without an error event
+	if r.errCode == "" && ctx.Err() != nil {
+		r.errCode = errorCode(ctx.Err())
+	}
+	return r
+}
+
+// errorCode returns the code reported for an error
+func errorCode(err error) string {
+	var llmErr *llm.Error
+	switch {
+	case errors.As(err, &llmErr) && llmErr.Code != "":
+		return llmErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// collector accumulates the results of the workers
+type collector struct {
+	mu      sync.Mutex
+	results []result
+}
+
+func (c *collector) add(r result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, r)
+}
+
+// summarize fills the request, throughput and latency figures of a report
+func (c *collector) summarize(report *Report) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ttfts, latencies []time.Duration
+	for _, r := range c.results {
+		report.Requests++
+		report.Events += r.events
+		report.OutputChars += r.chars
+		latencies = append(latencies, r.latency)
+		if r.errCode != "" {
+			report.Failures++
+			report.Errors[r.errCode]++
+			continue
+		}
+		report.Successes++
+		if r.ttft > 0 {
+			ttfts = append(ttfts, r.ttft)
+		}
+	}
+
+	if report.DurationSeconds > 0 {
+		report.Throughput = Throughput{
+			RequestsPerSecond: float64(report.Successes) / report.DurationSeconds,
+			EventsPerSecond:   float64(report.Events) / report.DurationSeconds,
+			CharsPerSecond:    float64(report.OutputChars) / report.DurationSeconds,
+		}
+	}
+	report.TTFT = newDistribution(ttfts)
+	report.Latency = newDistribution(latencies)
+}
+
+// sampler periodically samples the heap and the goroutine count
+type sampler struct {
+	done    chan struct{}
+	stopped chan struct{}
+
+	memory     MemoryStats
+	goroutines GoroutineStats
+	start      runtime.MemStats
+}
+
+func startSampler(interval time.Duration) *sampler {
+	s := &sampler{done: make(chan struct{}), stopped: make(chan struct{})}
+	runtime.ReadMemStats(&s.start)
+	s.memory.HeapAllocStart = s.start.HeapAlloc
+	s.memory.HeapAllocPeak = s.start.HeapAlloc
+	s.goroutines.Start = runtime.NumGoroutine()
+	s.goroutines.Peak = s.goroutines.Start
+
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+	return s
+}
+
+// sample records the current heap and goroutine count
+func (s *sampler) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	s.memory.HeapAllocPeak = max(s.memory.HeapAllocPeak, stats.HeapAlloc)
+	s.goroutines.Peak = max(s.goroutines.Peak, runtime.NumGoroutine())
+}
+
+// stop takes a last sample and returns the statistics
+func (s *sampler) stop() (MemoryStats, GoroutineStats) {
+	close(s.done)
+	<-s.stopped
+	s.sample()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	s.memory.HeapAllocEnd = stats.HeapAlloc
+	s.memory.TotalAlloc = stats.TotalAlloc - s.start.TotalAlloc
+	s.memory.Mallocs = stats.Mallocs - s.start.Mallocs
+	s.memory.NumGC = stats.NumGC - s.start.NumGC
+	s.goroutines.End = runtime.NumGoroutine()
+	return s.memory, s.goroutines
+}
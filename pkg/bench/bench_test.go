@@ -0,0 +1,146 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/mock"
+)
+
+// slowClient streams a fixed number of chunks with a delay between them, failing every
+// failEvery-th request
+type slowClient struct {
+	chunks    int
+	delay     time.Duration
+	failEvery int
+}
+
+func (c *slowClient) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	return nil, &llm.Error{Code: "not_implemented", Message: "streaming only"}
+}
+
+func (c *slowClient) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	if c.failEvery > 0 && req.Messages[0].GetText() == "fail" {
+		return nil, &llm.Error{Code: "rate_limit_exceeded", Message: "slow down", StatusCode: 429}
+	}
+	ch := make(chan llm.StreamEvent)
+	go func() {
+		defer close(ch)
+		for i := 0; i < c.chunks; i++ {
+			select {
+			case <-time.After(c.delay):
+			case <-ctx.Done():
+				return
+			}
+			ch <- llm.NewDeltaEvent(0, &llm.MessageDelta{Content: []llm.MessageContent{llm.NewTextContent("abcd")}})
+		}
+		ch <- llm.NewDoneEvent(0, "stop")
+	}()
+	return ch, nil
+}
+
+func (c *slowClient) GetRemote() llm.ClientRemoteInfo { return llm.ClientRemoteInfo{Name: "slow"} }
+
+func (c *slowClient) GetModelInfo() llm.ModelInfo {
+	return llm.ModelInfo{Name: "slow-model", Provider: "slow", SupportsStreaming: true}
+}
+
+func (c *slowClient) Close() error { return nil }
+
+func TestRun(t *testing.T) {
+	client := &slowClient{chunks: 3, delay: 5 * time.Millisecond, failEvery: 4}
+	report, err := Run(context.Background(), client, Config{
+		Concurrency: 4,
+		Requests:    20,
+		Request: func(i int) llm.ChatRequest {
+			text := "hello"
+			if i%client.failEvery == 0 {
+				text = "fail"
+			}
+			return llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, text)}}
+		},
+		SampleInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.Requests != 20 || report.Successes != 15 || report.Failures != 5 {
+		t.Errorf("Unexpected request counts: %+v", report)
+	}
+	if report.Errors["rate_limit_exceeded"] != 5 {
+		t.Errorf("Expected failures by code, got %v", report.Errors)
+	}
+	if report.Events != 45 || report.OutputChars != 180 {
+		t.Errorf("Unexpected stream counts: events=%d chars=%d", report.Events, report.OutputChars)
+	}
+	if report.TTFT.Count != 15 || report.TTFT.Min < 5 || report.TTFT.P50 > report.TTFT.P99 {
+		t.Errorf("Unexpected TTFT distribution: %+v", report.TTFT)
+	}
+	if report.Latency.Count != 20 || report.Latency.Max < 15 {
+		t.Errorf("Unexpected latency distribution: %+v", report.Latency)
+	}
+	if report.Throughput.RequestsPerSecond <= 0 || report.Goroutines.Peak < report.Goroutines.Start {
+		t.Errorf("Unexpected throughput or goroutines: %+v %+v", report.Throughput, report.Goroutines)
+	}
+	if report.Provider != "slow" || report.Model != "slow-model" || report.Concurrency != 4 {
+		t.Errorf("Unexpected report header: %+v", report)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded["ttft"] == nil {
+		t.Errorf("Expected a JSON report, got %s (%v)", buf.String(), err)
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	client := &slowClient{chunks: 1000, delay: time.Millisecond}
+	report, err := Run(context.Background(), client, Config{
+		Concurrency:    2,
+		Duration:       50 * time.Millisecond,
+		RequestTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.DurationSeconds > 1 {
+		t.Errorf("Expected the test to stop after its duration, took %.2fs", report.DurationSeconds)
+	}
+	if report.Requests != 2 || report.Errors["timeout"] != 2 {
+		t.Errorf("Expected requests cut by the duration to be reported as timeouts, got %+v", report)
+	}
+}
+
+func TestRunPerWorker_Mock(t *testing.T) {
+	report, err := RunPerWorker(context.Background(), func(worker int) (llm.Client, error) {
+		return mock.NewClientEmulating("openai")
+	}, Config{Concurrency: 3, Requests: 9})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Successes != 9 || report.Events == 0 || report.TTFT.Count != 9 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestNewDistribution(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	d := newDistribution(durations)
+	if d.Count != 100 || d.Min != 1 || d.Max != 100 || d.P50 != 50 || d.P90 != 90 || d.P99 != 99 || d.Mean != 50.5 {
+		t.Errorf("Unexpected distribution: %+v", d)
+	}
+	if (newDistribution(nil) != Distribution{}) {
+		t.Error("Expected an empty distribution")
+	}
+}
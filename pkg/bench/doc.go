@@ -0,0 +1,27 @@
+// Package bench provides a load-testing harness for llm.Client implementations.
+//
+// Run drives concurrent streaming completions against a client and returns a Report
+// with the measurements of the test, to catch performance regressions in providers:
+//   - Throughput: successful requests, delta events and streamed characters per second
+//   - Time to first token (TTFT) and end-to-end latency distributions (p50, p90, p95, p99)
+//   - Errors by code, including timeouts of requests and of the whole test
+//   - Heap usage (peak, allocations, GC cycles) and goroutine counts, sampled during the test
+//
+// Usage:
+//
+//	report, err := bench.Run(ctx, client, bench.Config{
+//	    Concurrency: 20,
+//	    Duration:    time.Minute,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	_ = report.WriteJSON(os.Stdout)
+//
+// Clients that cannot be shared between goroutines, such as mock clients, get a client
+// per worker with RunPerWorker:
+//
+//	report, err := bench.RunPerWorker(ctx, func(worker int) (llm.Client, error) {
+//	    return mock.NewClientEmulating("openai")
+//	}, bench.Config{Concurrency: 50, Requests: 1000})
+package bench
@@ -0,0 +1,120 @@
+package bench
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Report holds the measurements of a load test
+type Report struct {
+	Provider        string    `json:"provider"`
+	Model           string    `json:"model"`
+	Concurrency     int       `json:"concurrency"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+
+	// Requests is the number of requests sent, that succeeded or failed
+	Requests  int `json:"requests"`
+	Successes int `json:"successes"`
+	Failures  int `json:"failures"`
+
+	// Errors counts the failed requests by error code ("timeout" and "canceled" for
+	// context errors)
+	Errors map[string]int `json:"errors,omitempty"`
+
+	// Events and OutputChars count the delta events and the streamed text
+	Events      int `json:"events"`
+	OutputChars int `json:"output_chars"`
+
+	Throughput Throughput `json:"throughput"`
+
+	// TTFT is the time to the first streamed text of the successful requests
+	TTFT Distribution `json:"ttft"`
+
+	// Latency is the time until the end of the stream, for all the requests
+	Latency Distribution `json:"latency"`
+
+	Memory     MemoryStats    `json:"memory"`
+	Goroutines GoroutineStats `json:"goroutines"`
+}
+
+// Throughput holds the rates of a load test, over its whole duration
+type Throughput struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	EventsPerSecond   float64 `json:"events_per_second"`
+	CharsPerSecond    float64 `json:"chars_per_second"`
+}
+
+// Distribution summarizes durations, in milliseconds
+type Distribution struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min_ms"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// MemoryStats holds heap measurements, in bytes. TotalAlloc, Mallocs and NumGC are
+// counted during the test only.
+type MemoryStats struct {
+	HeapAllocStart uint64 `json:"heap_alloc_start"`
+	HeapAllocPeak  uint64 `json:"heap_alloc_peak"`
+	HeapAllocEnd   uint64 `json:"heap_alloc_end"`
+	TotalAlloc     uint64 `json:"total_alloc"`
+	Mallocs        uint64 `json:"mallocs"`
+	NumGC          uint32 `json:"num_gc"`
+}
+
+// GoroutineStats holds goroutine counts. An End count above Start hints at goroutines
+// leaked by the client, such as streams not closed after their context is cancelled.
+type GoroutineStats struct {
+	Start int `json:"start"`
+	Peak  int `json:"peak"`
+	End   int `json:"end"`
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// newDistribution summarizes durations, using nearest-rank percentiles
+func newDistribution(durations []time.Duration) Distribution {
+	if len(durations) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return milliseconds(sorted[min(max(rank, 0), len(sorted)-1)])
+	}
+
+	return Distribution{
+		Count: len(sorted),
+		Min:   milliseconds(sorted[0]),
+		Mean:  milliseconds(total / time.Duration(len(sorted))),
+		P50:   percentile(0.50),
+		P90:   percentile(0.90),
+		P95:   percentile(0.95),
+		P99:   percentile(0.99),
+		Max:   milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,126 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// MetadataKeyCompressed is the message metadata key set to the name of the strategy
+// that rewrote the message
+const MetadataKeyCompressed = "compressed"
+
+// Strategy is a single compression technique applied to a conversation
+type Strategy interface {
+	// Name returns the strategy name used in reports
+	Name() string
+
+	// Compress returns the compressed messages. Implementations must not modify
+	// the input messages.
+	Compress(ctx context.Context, messages []llm.Message) ([]llm.Message, error)
+}
+
+// StrategyReport holds the estimated savings of one strategy
+type StrategyReport struct {
+	Name         string `json:"name"`
+	TokensBefore int    `json:"tokens_before"`
+	TokensAfter  int    `json:"tokens_after"`
+}
+
+// Saved returns the number of tokens removed by the strategy
+func (r StrategyReport) Saved() int {
+	return r.TokensBefore - r.TokensAfter
+}
+
+// Report describes the estimated token savings of a compression run
+type Report struct {
+	OriginalTokens   int              `json:"original_tokens"`
+	CompressedTokens int              `json:"compressed_tokens"`
+	Strategies       []StrategyReport `json:"strategies"`
+}
+
+// Saved returns the number of tokens removed from the prompt
+func (r *Report) Saved() int {
+	return r.OriginalTokens - r.CompressedTokens
+}
+
+// Ratio returns the compressed size as a fraction of the original size (1 means no savings)
+func (r *Report) Ratio() float64 {
+	if r.OriginalTokens == 0 {
+		return 1
+	}
+	return float64(r.CompressedTokens) / float64(r.OriginalTokens)
+}
+
+// String returns a human-readable summary of the report
+func (r *Report) String() string {
+	return fmt.Sprintf("%d -> %d tokens (saved %d, %.0f%%)",
+		r.OriginalTokens, r.CompressedTokens, r.Saved(), (1-r.Ratio())*100)
+}
+
+// Compressor applies a sequence of strategies to conversations
+type Compressor struct {
+	model      string
+	strategies []Strategy
+}
+
+// NewCompressor creates a compressor that runs the strategies in order. The model is
+// only used to estimate token counts (see llm.CountMessagesTokens).
+func NewCompressor(model string, strategies ...Strategy) *Compressor {
+	return &Compressor{
+		model:      model,
+		strategies: strategies,
+	}
+}
+
+// Compress runs all strategies over the messages, returning the compressed messages
+// and a report of the savings. The input messages are not modified.
+func (c *Compressor) Compress(ctx context.Context, messages []llm.Message) ([]llm.Message, *Report, error) {
+	_, original := llm.CountMessagesTokens(c.model, messages)
+	report := &Report{
+		OriginalTokens:   original,
+		CompressedTokens: original,
+	}
+
+	current := messages
+	for _, strategy := range c.strategies {
+		compressed, err := strategy.Compress(ctx, current)
+		if err != nil {
+			return nil, nil, fmt.Errorf("compression strategy %s failed: %w", strategy.Name(), err)
+		}
+
+		_, after := llm.CountMessagesTokens(c.model, compressed)
+		report.Strategies = append(report.Strategies, StrategyReport{
+			Name:         strategy.Name(),
+			TokensBefore: report.CompressedTokens,
+			TokensAfter:  after,
+		})
+		report.CompressedTokens = after
+		current = compressed
+	}
+
+	return current, report, nil
+}
+
+// messageText joins the text contents of a message
+func messageText(msg llm.Message) string {
+	var parts []string
+	for _, content := range msg.Content {
+		if text, ok := content.(*llm.TextContent); ok {
+			parts = append(parts, text.GetText())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// rolesContain checks if a role is in the list
+func rolesContain(roles []llm.MessageRole, role llm.MessageRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
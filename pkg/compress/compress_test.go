@@ -0,0 +1,179 @@
+package compress
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// completerFunc adapts a function to llm.ChatCompleter
+type completerFunc func(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error)
+
+func (f completerFunc) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	return f(ctx, req)
+}
+
+func textResponse(text string) *llm.ChatResponse {
+	return &llm.ChatResponse{Choices: []llm.Choice{{Message: llm.NewTextMessage(llm.RoleAssistant, text)}}}
+}
+
+func toolMessage(id, text string) llm.Message {
+	msg := llm.NewTextMessage(llm.RoleTool, text)
+	msg.ToolCallID = id
+	return msg
+}
+
+func TestStopwordPruner(t *testing.T) {
+	pruner := NewStopwordPruner(nil)
+
+	text := "Please list all of the files in the directory that are not hidden.\n```\nls -a the dir\n```"
+	assert.Equal(t, "list files directory not hidden.\n```\nls -a the dir\n```", pruner.PruneText(text))
+
+	messages := []llm.Message{
+		llm.NewTextMessage(llm.RoleSystem, "You are a helpful assistant."),
+		llm.NewTextMessage(llm.RoleUser, "What is the capital of France?"),
+		llm.NewTextMessage(llm.RoleUser, "And what is the capital of Spain?"),
+	}
+	compressed, err := pruner.Compress(context.Background(), messages)
+	require.NoError(t, err)
+
+	assert.Equal(t, "You are a helpful assistant.", compressed[0].GetText())
+	assert.Equal(t, "What capital France?", compressed[1].GetText())
+	assert.Equal(t, "stopwords", compressed[1].Metadata[MetadataKeyCompressed])
+	// The last message is kept verbatim, and the input is not modified
+	assert.Equal(t, "And what is the capital of Spain?", compressed[2].GetText())
+	assert.Equal(t, "What is the capital of France?", messages[1].GetText())
+}
+
+func TestToolOutputDeduplicator(t *testing.T) {
+	output := strings.Repeat("main.go README.md go.mod go.sum ", 10)
+	messages := []llm.Message{
+		toolMessage("call_1", output),
+		toolMessage("call_2", "short"),
+		toolMessage("call_3", output),
+		toolMessage("call_4", "short"),
+	}
+
+	compressed, err := NewToolOutputDeduplicator().Compress(context.Background(), messages)
+	require.NoError(t, err)
+
+	assert.Equal(t, output, compressed[0].GetText())
+	assert.Equal(t, "[same output as tool call call_1]", compressed[2].GetText())
+	assert.Equal(t, "call_3", compressed[2].ToolCallID)
+	// Outputs below MinTokens are not worth replacing
+	assert.Equal(t, "short", compressed[3].GetText())
+	assert.Equal(t, output, messages[2].GetText())
+}
+
+func TestSummarizer(t *testing.T) {
+	long := strings.Repeat("The quarterly revenue grew by twelve percent thanks to new customers. ", 40)
+
+	var requests []llm.ChatRequest
+	client := completerFunc(func(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+		requests = append(requests, req)
+		return textResponse("Revenue +12% (new customers)."), nil
+	})
+
+	summarizer := NewSummarizer(client, &SummarizerConfig{Model: "small-model", SkipLastMessages: 1})
+	messages := []llm.Message{
+		llm.NewTextMessage(llm.RoleUser, long),
+		llm.NewTextMessage(llm.RoleUser, "Short question"),
+		llm.NewTextMessage(llm.RoleUser, long),
+	}
+
+	compressed, err := summarizer.Compress(context.Background(), messages)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "small-model", requests[0].Model)
+	assert.Equal(t, DefaultSummaryPrompt, requests[0].Messages[0].GetText())
+	assert.Equal(t, "Revenue +12% (new customers).", compressed[0].GetText())
+	assert.Equal(t, "Short question", compressed[1].GetText())
+	assert.Equal(t, long, compressed[2].GetText())
+
+	failing := NewSummarizer(completerFunc(func(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+		return nil, errors.New("unavailable")
+	}), nil)
+	_, err = failing.Compress(context.Background(), messages)
+	assert.Error(t, err)
+}
+
+func TestCompressorReport(t *testing.T) {
+	output := strings.Repeat("the result of the query is the same as before ", 10)
+	messages := []llm.Message{
+		toolMessage("call_1", output),
+		toolMessage("call_2", output),
+		llm.NewTextMessage(llm.RoleUser, "What is the answer?"),
+	}
+
+	compressor := NewCompressor("gpt-4o", NewToolOutputDeduplicator(), NewStopwordPruner(nil))
+	_, report, err := compressor.Compress(context.Background(), messages)
+	require.NoError(t, err)
+
+	require.Len(t, report.Strategies, 2)
+	assert.Equal(t, "tool_output_dedup", report.Strategies[0].Name)
+	assert.Positive(t, report.Strategies[0].Saved())
+	assert.Positive(t, report.Strategies[1].Saved())
+	assert.Equal(t, report.OriginalTokens, report.Strategies[0].TokensBefore)
+	assert.Equal(t, report.CompressedTokens, report.Strategies[1].TokensAfter)
+	assert.Equal(t, report.Strategies[0].Saved()+report.Strategies[1].Saved(), report.Saved())
+	assert.Less(t, report.Ratio(), 1.0)
+	assert.Contains(t, report.String(), "saved")
+}
+
+func TestMiddleware(t *testing.T) {
+	var sent llm.ChatRequest
+	client := mockClient{completerFunc(func(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+		sent = req
+		return textResponse("Paris"), nil
+	})}
+
+	middleware := NewMiddleware(NewCompressor("gpt-4o", NewStopwordPruner(&StopwordPrunerConfig{})))
+	enhanced := llm.ClientWithMiddleware(client, []llm.Middleware{middleware})
+
+	resp, err := enhanced.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "What is the capital of France?")},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "What capital France?", sent.Messages[0].GetText())
+	report, ok := resp.Metadata[MetadataKeyReport].(*Report)
+	require.True(t, ok)
+	assert.Positive(t, report.Saved())
+
+	stats := middleware.Stats()
+	assert.Equal(t, int64(1), stats.Requests)
+	assert.Equal(t, int64(report.Saved()), stats.Saved())
+	assert.Empty(t, middleware.pending)
+
+	// Failures fall back to the original request
+	failing := NewMiddleware(NewCompressor("gpt-4o", NewSummarizer(completerFunc(func(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+		return nil, errors.New("unavailable")
+	}), &SummarizerConfig{MinTokens: 1})))
+	req := &llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello there")}}
+	processed, err := failing.ProcessRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Same(t, req, processed)
+	assert.Equal(t, int64(1), failing.Stats().Failures)
+}
+
+// mockClient is a minimal llm.Client backed by a completer
+type mockClient struct {
+	completerFunc
+}
+
+func (mockClient) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (mockClient) GetRemote() llm.ClientRemoteInfo { return llm.ClientRemoteInfo{} }
+
+func (mockClient) GetModelInfo() llm.ModelInfo { return llm.ModelInfo{} }
+
+func (mockClient) Close() error { return nil }
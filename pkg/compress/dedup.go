@@ -0,0 +1,75 @@
+package compress
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultDuplicateFormat is the text that replaces a repeated tool output; it receives
+// the ID of the tool call that produced the original output
+const DefaultDuplicateFormat = "[same output as tool call %s]"
+
+// ToolOutputDeduplicator replaces tool results that are identical to an earlier tool
+// result with a short reference to the original call. Agents frequently call the same
+// tool repeatedly (listing a directory, reading a file...) and get the same output back.
+type ToolOutputDeduplicator struct {
+	// MinTokens is the minimum size of an output worth deduplicating
+	MinTokens int
+
+	// Format is the replacement text; it must contain one %s for the original tool call ID
+	Format string
+}
+
+// NewToolOutputDeduplicator creates a deduplicator with the default settings
+func NewToolOutputDeduplicator() *ToolOutputDeduplicator {
+	return &ToolOutputDeduplicator{
+		MinTokens: 20,
+		Format:    DefaultDuplicateFormat,
+	}
+}
+
+// Name returns the strategy name
+func (d *ToolOutputDeduplicator) Name() string {
+	return "tool_output_dedup"
+}
+
+// Compress replaces repeated tool outputs, keeping the first occurrence
+func (d *ToolOutputDeduplicator) Compress(ctx context.Context, messages []llm.Message) ([]llm.Message, error) {
+	format := d.Format
+	if format == "" {
+		format = DefaultDuplicateFormat
+	}
+
+	result := make([]llm.Message, len(messages))
+	seen := make(map[[sha256.Size]byte]string)
+
+	for i, msg := range messages {
+		result[i] = msg
+		if msg.Role != llm.RoleTool || !msg.IsTextOnly() {
+			continue
+		}
+
+		text := messageText(msg)
+		if llm.EstimateTextTokens(text) < d.MinTokens {
+			continue
+		}
+
+		hash := sha256.Sum256([]byte(text))
+		original, duplicate := seen[hash]
+		if !duplicate {
+			seen[hash] = msg.ToolCallID
+			continue
+		}
+
+		// Tool messages must keep their call ID so the conversation stays valid
+		replaced := msg.DeepCopy()
+		replaced.SetText(fmt.Sprintf(format, original))
+		replaced.SetMetadata(MetadataKeyCompressed, d.Name())
+		result[i] = replaced
+	}
+
+	return result, nil
+}
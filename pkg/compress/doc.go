@@ -0,0 +1,22 @@
+// Package compress provides lossy prompt compression for LLM conversations.
+//
+// A Compressor runs a sequence of strategies over the messages of a request and
+// reports the estimated token savings of each one. Available strategies:
+//   - StopwordPruner: drops low-information words (articles, auxiliaries...) from
+//     user and tool messages, keeping code blocks intact
+//   - Summarizer: rewrites long messages into dense summaries using a small model,
+//     in the spirit of LLMLingua-style compression
+//   - ToolOutputDeduplicator: replaces tool outputs identical to an earlier one with
+//     a short reference to the original call
+//
+// Compression is available as a standalone call or as an llm.Middleware:
+//
+//	compressor := compress.NewCompressor("gpt-4o",
+//	    compress.NewToolOutputDeduplicator(),
+//	    compress.NewStopwordPruner(nil),
+//	)
+//	client = llm.ClientWithMiddleware(client, []llm.Middleware{compress.NewMiddleware(compressor)})
+//
+// The middleware records the report of every request in the response metadata
+// under MetadataKeyReport and accumulates the savings returned by Stats.
+package compress
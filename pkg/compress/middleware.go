@@ -0,0 +1,122 @@
+package compress
+
+import (
+	"context"
+	"sync"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// MetadataKeyReport is the response metadata key holding the *Report of the request
+const MetadataKeyReport = "compression_report"
+
+// maxPendingReports bounds the reports kept for in-flight requests, in case responses
+// are never processed (e.g. cancelled streams)
+const maxPendingReports = 1024
+
+// Stats holds the savings accumulated by a Middleware
+type Stats struct {
+	Requests         int64 `json:"requests"`
+	Failures         int64 `json:"failures"`
+	OriginalTokens   int64 `json:"original_tokens"`
+	CompressedTokens int64 `json:"compressed_tokens"`
+}
+
+// Saved returns the total number of tokens removed from prompts
+func (s Stats) Saved() int64 {
+	return s.OriginalTokens - s.CompressedTokens
+}
+
+// Middleware compresses the messages of every request before it is sent. It implements
+// llm.Middleware so it can be plugged into an EnhancedClient.
+//
+// Compression is an optimization: when it fails (e.g. the summarization model is
+// unavailable) the original request is sent unchanged and the failure is counted in Stats.
+//
+// Reports are matched to responses by request, so the middleware should be the last one
+// in the chain that rewrites requests.
+type Middleware struct {
+	compressor *Compressor
+
+	mu    sync.Mutex
+	stats Stats
+
+	// pending holds the reports of in-flight requests, keyed by the processed request
+	pending map[*llm.ChatRequest]*Report
+}
+
+// NewMiddleware creates a middleware that compresses requests with the given compressor
+func NewMiddleware(compressor *Compressor) *Middleware {
+	return &Middleware{
+		compressor: compressor,
+		pending:    make(map[*llm.ChatRequest]*Report),
+	}
+}
+
+// Name returns the middleware name
+func (m *Middleware) Name() string {
+	return "prompt_compression"
+}
+
+// Stats returns the savings accumulated so far
+func (m *Middleware) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// ProcessRequest compresses the request messages
+func (m *Middleware) ProcessRequest(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRequest, error) {
+	if req == nil || len(req.Messages) == 0 {
+		return req, nil
+	}
+
+	messages, report, err := m.compressor.Compress(ctx, req.Messages)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Requests++
+	if err != nil {
+		m.stats.Failures++
+		return req, nil
+	}
+	m.stats.OriginalTokens += int64(report.OriginalTokens)
+	m.stats.CompressedTokens += int64(report.CompressedTokens)
+
+	processed := *req
+	processed.Messages = messages
+	if len(m.pending) >= maxPendingReports {
+		clear(m.pending)
+	}
+	m.pending[&processed] = report
+
+	return &processed, nil
+}
+
+// ProcessResponse records the compression report in the response metadata
+func (m *Middleware) ProcessResponse(ctx context.Context, req *llm.ChatRequest, resp *llm.ChatResponse, err error) (*llm.ChatResponse, error) {
+	report := m.takeReport(req)
+	if report != nil && resp != nil {
+		resp.SetMetadata(MetadataKeyReport, report)
+	}
+	return resp, err
+}
+
+// ProcessStreamEvent releases the report of a stream once it finishes
+func (m *Middleware) ProcessStreamEvent(ctx context.Context, req *llm.ChatRequest, event llm.StreamEvent) (llm.StreamEvent, error) {
+	if event.IsDone() || event.IsError() {
+		m.takeReport(req)
+	}
+	return event, nil
+}
+
+// takeReport removes and returns the pending report of a request
+func (m *Middleware) takeReport(req *llm.ChatRequest) *Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.pending[req]
+	delete(m.pending, req)
+	return report
+}
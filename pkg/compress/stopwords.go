@@ -0,0 +1,143 @@
+package compress
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultStopwords returns common English words that carry little meaning in a prompt.
+// Negations ("not", "no", "never"...) are deliberately excluded since dropping them
+// changes the meaning of the text.
+func DefaultStopwords() []string {
+	return []string{
+		"a", "an", "the", "and", "or", "of", "to", "in", "on", "at", "by", "for", "with",
+		"from", "as", "into", "about", "that", "this", "these", "those", "is", "are", "was",
+		"were", "be", "been", "being", "am", "do", "does", "did", "have", "has", "had",
+		"it", "its", "so", "such", "very", "just", "really", "quite", "also", "then",
+		"there", "here", "which", "would", "could", "should", "please", "basically",
+		"actually", "some", "any", "all", "each", "own", "same", "than", "too",
+	}
+}
+
+// StopwordPrunerConfig configures a StopwordPruner
+type StopwordPrunerConfig struct {
+	// Stopwords are matched case-insensitively. Defaults to DefaultStopwords().
+	Stopwords []string `json:"stopwords"`
+
+	// Roles whose messages are pruned. Defaults to user and tool messages.
+	Roles []llm.MessageRole `json:"roles"`
+
+	// SkipLastMessages leaves the most recent messages untouched, so the current
+	// question reaches the model verbatim
+	SkipLastMessages int `json:"skip_last_messages"`
+}
+
+// DefaultStopwordPrunerConfig returns the default pruner configuration
+func DefaultStopwordPrunerConfig() *StopwordPrunerConfig {
+	return &StopwordPrunerConfig{
+		Stopwords:        DefaultStopwords(),
+		Roles:            []llm.MessageRole{llm.RoleUser, llm.RoleTool},
+		SkipLastMessages: 1,
+	}
+}
+
+// StopwordPruner removes stopwords from the text of messages. Fenced code blocks
+// are kept unchanged.
+type StopwordPruner struct {
+	config    *StopwordPrunerConfig
+	stopwords map[string]bool
+}
+
+// NewStopwordPruner creates a new stopword pruner, filling unset fields with defaults
+func NewStopwordPruner(config *StopwordPrunerConfig) *StopwordPruner {
+	defaults := DefaultStopwordPrunerConfig()
+	if config == nil {
+		config = defaults
+	}
+	if len(config.Stopwords) == 0 {
+		config.Stopwords = defaults.Stopwords
+	}
+	if len(config.Roles) == 0 {
+		config.Roles = defaults.Roles
+	}
+
+	stopwords := make(map[string]bool, len(config.Stopwords))
+	for _, word := range config.Stopwords {
+		stopwords[strings.ToLower(word)] = true
+	}
+
+	return &StopwordPruner{
+		config:    config,
+		stopwords: stopwords,
+	}
+}
+
+// Name returns the strategy name
+func (p *StopwordPruner) Name() string {
+	return "stopwords"
+}
+
+// Compress prunes stopwords from the configured messages
+func (p *StopwordPruner) Compress(ctx context.Context, messages []llm.Message) ([]llm.Message, error) {
+	result := make([]llm.Message, len(messages))
+	last := len(messages) - p.config.SkipLastMessages
+
+	for i, msg := range messages {
+		if i >= last || !rolesContain(p.config.Roles, msg.Role) {
+			result[i] = msg
+			continue
+		}
+
+		pruned := msg.DeepCopy()
+		changed := false
+		for _, content := range pruned.Content {
+			if text, ok := content.(*llm.TextContent); ok {
+				if prunedText := p.PruneText(text.Text); prunedText != text.Text {
+					text.Text = prunedText
+					changed = true
+				}
+			}
+		}
+		if changed {
+			pruned.SetMetadata(MetadataKeyCompressed, p.Name())
+			result[i] = pruned
+		} else {
+			result[i] = msg
+		}
+	}
+
+	return result, nil
+}
+
+// PruneText removes stopwords from a text, line by line, leaving fenced code blocks unchanged
+func (p *StopwordPruner) PruneText(text string) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+
+		words := strings.Fields(line)
+		kept := words[:0]
+		for _, word := range words {
+			bare := strings.TrimFunc(strings.ToLower(word), func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if !p.stopwords[bare] {
+				kept = append(kept, word)
+			}
+		}
+		lines[i] = strings.Join(kept, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,134 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultSummaryPrompt is the system prompt sent to the summarization model
+const DefaultSummaryPrompt = `Compress the text given by the user so that another language model can ` +
+	`reconstruct its meaning. Keep all facts, names, numbers, identifiers, code and constraints. ` +
+	`Drop filler words, repetitions and formatting. Reply with the compressed text only.`
+
+// SummarizerConfig configures a Summarizer
+type SummarizerConfig struct {
+	// Model is the model requested from the client. Empty uses the client's default model.
+	Model string `json:"model"`
+
+	// Prompt is the system prompt of the summarization request. Defaults to DefaultSummaryPrompt.
+	Prompt string `json:"prompt"`
+
+	// MinTokens is the minimum estimated size of a message worth summarizing
+	MinTokens int `json:"min_tokens"`
+
+	// Roles whose messages are summarized. Defaults to user and tool messages.
+	Roles []llm.MessageRole `json:"roles"`
+
+	// SkipLastMessages leaves the most recent messages untouched
+	SkipLastMessages int `json:"skip_last_messages"`
+}
+
+// DefaultSummarizerConfig returns the default summarizer configuration
+func DefaultSummarizerConfig() *SummarizerConfig {
+	return &SummarizerConfig{
+		Prompt:           DefaultSummaryPrompt,
+		MinTokens:        200,
+		Roles:            []llm.MessageRole{llm.RoleUser, llm.RoleTool},
+		SkipLastMessages: 1,
+	}
+}
+
+// Summarizer rewrites long messages into dense summaries using a (usually small and
+// cheap) model. Summaries that are not shorter than the original are discarded.
+type Summarizer struct {
+	client llm.ChatCompleter
+	config *SummarizerConfig
+}
+
+// NewSummarizer creates a new summarizer backed by the given client, filling unset
+// config fields with defaults
+func NewSummarizer(client llm.ChatCompleter, config *SummarizerConfig) *Summarizer {
+	defaults := DefaultSummarizerConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Prompt == "" {
+		config.Prompt = defaults.Prompt
+	}
+	if config.MinTokens <= 0 {
+		config.MinTokens = defaults.MinTokens
+	}
+	if len(config.Roles) == 0 {
+		config.Roles = defaults.Roles
+	}
+
+	return &Summarizer{
+		client: client,
+		config: config,
+	}
+}
+
+// Name returns the strategy name
+func (s *Summarizer) Name() string {
+	return "summarize"
+}
+
+// Compress summarizes the long text-only messages of the configured roles
+func (s *Summarizer) Compress(ctx context.Context, messages []llm.Message) ([]llm.Message, error) {
+	result := make([]llm.Message, len(messages))
+	last := len(messages) - s.config.SkipLastMessages
+
+	for i, msg := range messages {
+		result[i] = msg
+		if i >= last || !rolesContain(s.config.Roles, msg.Role) || !msg.IsTextOnly() || msg.HasToolCalls() {
+			continue
+		}
+
+		text := messageText(msg)
+		tokens := llm.EstimateTextTokens(text)
+		if tokens < s.config.MinTokens {
+			continue
+		}
+
+		summary, err := s.Summarize(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if summary == "" || llm.EstimateTextTokens(summary) >= tokens {
+			continue
+		}
+
+		summarized := msg.DeepCopy()
+		summarized.SetText(summary)
+		summarized.SetMetadata(MetadataKeyCompressed, s.Name())
+		result[i] = summarized
+	}
+
+	return result, nil
+}
+
+// Summarize asks the model for a compressed version of the text
+func (s *Summarizer) Summarize(ctx context.Context, text string) (string, error) {
+	resp, err := s.client.ChatCompletion(ctx, llm.ChatRequest{
+		Model: s.config.Model,
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, s.config.Prompt),
+			llm.NewTextMessage(llm.RoleUser, text),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", &llm.Error{
+			Code:    "empty_response",
+			Message: "summarization model returned no choices",
+			Type:    "api_error",
+		}
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Message.GetText()), nil
+}
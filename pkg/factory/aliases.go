@@ -0,0 +1,386 @@
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// ModelAlias is the target of a logical model name (e.g. "fast" or "smart"), so
+// application code does not depend on provider-specific model IDs
+type ModelAlias struct {
+	// Provider of the model; the provider of the client configuration when empty
+	Provider string `json:"provider,omitempty"`
+
+	// Model is the provider model ID; the model of the profile when empty
+	Model string `json:"model,omitempty"`
+
+	// Profile is the name of a registered profile whose configuration (API key, base
+	// URL...), middleware and defaults are used, with Provider and Model replacing its own
+	// when set
+	Profile string `json:"profile,omitempty"`
+}
+
+// ParseModelAlias parses a "provider/model" target. The model keeps any further slashes,
+// e.g. "openrouter/qwen/qwen3-32b" is the "qwen/qwen3-32b" model of OpenRouter.
+func ParseModelAlias(target string) (ModelAlias, error) {
+	provider, model, ok := strings.Cut(strings.TrimSpace(target), "/")
+	if !ok || provider == "" || model == "" {
+		return ModelAlias{}, aliasError(fmt.Sprintf("invalid model alias target %q: expected provider/model", target))
+	}
+	return ModelAlias{Provider: provider, Model: model}, nil
+}
+
+// UnmarshalJSON decodes an alias from an object or a "provider/model" string
+func (a *ModelAlias) UnmarshalJSON(data []byte) error {
+	var target string
+	if json.Unmarshal(data, &target) == nil {
+		alias, err := ParseModelAlias(target)
+		if err != nil {
+			return err
+		}
+		*a = alias
+		return nil
+	}
+	type plain ModelAlias
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// Validate checks that the alias has a target
+func (a ModelAlias) Validate() error {
+	if a.Model == "" && a.Profile == "" {
+		return aliasError("model alias needs a model or a profile")
+	}
+	return nil
+}
+
+// AliasTable maps logical model names to their targets. Names are case insensitive. The
+// table can be changed, or reloaded from a file, while clients use it: the clients of a
+// factory resolve their aliases on every request (see Factory.WithAliases). It is safe
+// for concurrent use.
+type AliasTable struct {
+	mu      sync.RWMutex
+	aliases map[string]ModelAlias
+	clock   llm.Clock
+}
+
+// NewAliasTable creates a table with the aliases. It fails with an "invalid_alias" error
+// when an alias has no name or no target.
+func NewAliasTable(aliases map[string]ModelAlias) (*AliasTable, error) {
+	t := &AliasTable{aliases: make(map[string]ModelAlias)}
+	if err := t.Replace(aliases); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadAliasTable creates a table with the aliases of a JSON file (see ReloadFile)
+func LoadAliasTable(path string) (*AliasTable, error) {
+	t := &AliasTable{aliases: make(map[string]ModelAlias)}
+	if err := t.ReloadFile(path); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WithClock sets the clock of the file watches, for tests
+func (t *AliasTable) WithClock(clock llm.Clock) *AliasTable {
+	t.clock = clock
+	return t
+}
+
+// Set adds (or replaces) an alias
+func (t *AliasTable) Set(name string, alias ModelAlias) error {
+	name = aliasName(name)
+	if name == "" {
+		return aliasError("model alias name is required")
+	}
+	if err := alias.Validate(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.aliases[name] = alias
+	return nil
+}
+
+// Remove removes an alias
+func (t *AliasTable) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.aliases, aliasName(name))
+}
+
+// Replace replaces all the aliases at once. The table is unchanged when an alias is
+// invalid.
+func (t *AliasTable) Replace(aliases map[string]ModelAlias) error {
+	replaced := make(map[string]ModelAlias, len(aliases))
+	for name, alias := range aliases {
+		key := aliasName(name)
+		if key == "" {
+			return aliasError("model alias name is required")
+		}
+		if alias.Validate() != nil {
+			return aliasError(fmt.Sprintf("model alias %q needs a model or a profile", name))
+		}
+		replaced[key] = alias
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.aliases = replaced
+	return nil
+}
+
+// Resolve returns the target of an alias
+func (t *AliasTable) Resolve(name string) (ModelAlias, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	alias, ok := t.aliases[aliasName(name)]
+	return alias, ok
+}
+
+// Aliases returns a copy of the aliases of the table
+func (t *AliasTable) Aliases() map[string]ModelAlias {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return maps.Clone(t.aliases)
+}
+
+// Names returns the alias names, sorted
+func (t *AliasTable) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.aliases))
+	for name := range t.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReloadFile replaces the aliases with those of a JSON file, an object mapping the names
+// to their targets as objects or "provider/model" strings:
+//
+//	{
+//	    "fast":  "openrouter/qwen/qwen3-32b",
+//	    "smart": {"provider": "openai", "model": "gpt-4o"},
+//	    "eu":    {"profile": "prod-eu"}
+//	}
+//
+// The table is unchanged when the file cannot be read or has invalid aliases.
+func (t *AliasTable) ReloadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read model aliases: %w", err)
+	}
+	var aliases map[string]ModelAlias
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return fmt.Errorf("failed to decode model aliases %s: %w", path, err)
+	}
+	return t.Replace(aliases)
+}
+
+// WatchFile reloads the aliases from a JSON file (see ReloadFile) every time it changes,
+// checking its modification time and size at every interval. A failed reload keeps the
+// previous aliases and is reported once to onError, when not nil, as a file going missing
+// is. The returned function stops the watch.
+func (t *AliasTable) WatchFile(path string, interval time.Duration, onError func(error)) (stop func()) {
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+	last, err := os.Stat(path)
+	missing := err != nil
+
+	clock := t.clock
+	if clock == nil {
+		clock = llm.SystemClock
+	}
+	ticker := clock.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C():
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				if !missing {
+					report(fmt.Errorf("failed to read model aliases: %w", err))
+				}
+				missing = true
+				continue
+			}
+			missing = false
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			// A broken file is reported once, not at every tick until it is fixed
+			last = info
+			if err := t.ReloadFile(path); err != nil {
+				report(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// aliasName normalizes an alias name
+func aliasName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// aliasError creates an invalid alias error
+func aliasError(message string) *llm.Error {
+	return &llm.Error{Code: "invalid_alias", Message: message, Type: "validation_error"}
+}
+
+// aliasClient routes the requests of a client created for an alias to the client of its
+// current target, so reloading the aliases moves the traffic without recreating clients.
+// Requests whose model is an alias are routed to the target of that alias.
+type aliasClient struct {
+	factory *Factory
+	config  llm.ClientConfig
+	alias   string
+
+	mu      sync.Mutex
+	clients map[ModelAlias]llm.Client
+}
+
+// ChatCompletion sends the request to the client of the target of its alias
+func (c *aliasClient) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	client, req, err := c.route(req)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChatCompletion(ctx, req)
+}
+
+// StreamChatCompletion streams the request from the client of the target of its alias
+func (c *aliasClient) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	client, req, err := c.route(req)
+	if err != nil {
+		return nil, err
+	}
+	return client.StreamChatCompletion(ctx, req)
+}
+
+// GetRemote returns information about the client of the current target
+func (c *aliasClient) GetRemote() llm.ClientRemoteInfo {
+	client, err := c.target(c.alias)
+	if err != nil {
+		return llm.ClientRemoteInfo{Name: c.alias}
+	}
+	return client.GetRemote()
+}
+
+// GetModelInfo returns information about the model of the current target
+func (c *aliasClient) GetModelInfo() llm.ModelInfo {
+	client, err := c.target(c.alias)
+	if err != nil {
+		return llm.ModelInfo{Name: c.alias}
+	}
+	return client.GetModelInfo()
+}
+
+// Unwrap returns the client of the current target, for llm.ClientAs
+func (c *aliasClient) Unwrap() llm.Client {
+	client, err := c.target(c.alias)
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
+// Close closes the clients of all the targets used so far
+func (c *aliasClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, client := range c.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.clients = make(map[ModelAlias]llm.Client)
+	return errors.Join(errs...)
+}
+
+// route returns the client of a request: the target of its model when it is an alias,
+// else the target of the alias of the client. Aliases are removed from the request, so
+// the target client uses its own model.
+func (c *aliasClient) route(req llm.ChatRequest) (llm.Client, llm.ChatRequest, error) {
+	alias := c.alias
+	if req.Model != "" {
+		if _, ok := c.factory.aliases.Resolve(req.Model); ok {
+			alias = req.Model
+			req.Model = ""
+		}
+	}
+	client, err := c.target(alias)
+	return client, req, err
+}
+
+// target returns the client of the current target of an alias, creating it on first use
+func (c *aliasClient) target(name string) (llm.Client, error) {
+	alias, ok := c.factory.aliases.Resolve(name)
+	if !ok {
+		return nil, &llm.Error{
+			Code:    "unknown_alias",
+			Message: fmt.Sprintf("unknown model alias: %s", name),
+			Type:    "validation_error",
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[alias]; ok {
+		return client, nil
+	}
+	client, err := c.factory.createAliasTarget(c.config, alias)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[alias] = client
+	return client, nil
+}
+
+// createAliasTarget creates the client of the target of an alias, from the configuration
+// of its profile or else the given one
+func (f *Factory) createAliasTarget(config llm.ClientConfig, alias ModelAlias) (llm.Client, error) {
+	if alias.Profile == "" {
+		if alias.Provider != "" {
+			config.Provider = alias.Provider
+		}
+		config.Model = alias.Model
+		return f.createClient(config)
+	}
+
+	profile, err := getProfile(alias.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if alias.Provider != "" {
+		profile.Config.Provider = alias.Provider
+	}
+	if alias.Model != "" {
+		profile.Config.Model = alias.Model
+	}
+	return f.createProfileClient(profile)
+}
@@ -0,0 +1,260 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/mock"
+)
+
+// registerAliasProvider registers a mock provider recording the clients it creates
+func registerAliasProvider(t *testing.T, name string) map[string]*mock.Client {
+	t.Helper()
+	created := make(map[string]*mock.Client)
+	var mu sync.Mutex
+	err := RegisterProviderFactory(name, func(config llm.ClientConfig) (llm.Client, error) {
+		client, err := mock.NewClient(config.Model, name)
+		mu.Lock()
+		defer mu.Unlock()
+		created[config.Model] = client
+		return client, err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { UnregisterProvider(name) })
+	return created
+}
+
+func TestAliasTable_ReloadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	data := `{
+		"Fast":  "openrouter/qwen/qwen3-32b",
+		"smart": {"provider": "openai", "model": "gpt-4o"},
+		"eu":    {"profile": "prod-eu"}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := LoadAliasTable(path)
+	if err != nil {
+		t.Fatalf("LoadAliasTable failed: %v", err)
+	}
+	want := map[string]ModelAlias{
+		"fast":  {Provider: "openrouter", Model: "qwen/qwen3-32b"},
+		"smart": {Provider: "openai", Model: "gpt-4o"},
+		"eu":    {Profile: "prod-eu"},
+	}
+	if got := table.Aliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected aliases: %v", got)
+	}
+	if alias, ok := table.Resolve(" FAST "); !ok || alias.Model != "qwen/qwen3-32b" {
+		t.Errorf("Expected case insensitive names, got %v %v", alias, ok)
+	}
+	if names := table.Names(); !reflect.DeepEqual(names, []string{"eu", "fast", "smart"}) {
+		t.Errorf("Unexpected names: %v", names)
+	}
+
+	// Invalid files leave the table unchanged
+	var llmErr *llm.Error
+	for _, invalid := range []string{`{"fast": "gpt-4o"}`, `{"fast": {}}`, `not json`} {
+		if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := table.ReloadFile(path); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+	if err := os.WriteFile(path, []byte(`{"fast": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.ReloadFile(path); !errors.As(err, &llmErr) || llmErr.Code != "invalid_alias" {
+		t.Errorf("Expected invalid_alias, got %v", err)
+	}
+	if got := table.Aliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the aliases to be kept, got %v", got)
+	}
+}
+
+func TestAliasTable_WatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	if err := os.WriteFile(path, []byte(`{"fast": "openai/gpt-4o-mini"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clock := llm.NewFakeClock(time.Now())
+	table, err := LoadAliasTable(path)
+	if err != nil {
+		t.Fatalf("LoadAliasTable failed: %v", err)
+	}
+	table.WithClock(clock)
+
+	errs := make(chan error, 10)
+	stop := table.WatchFile(path, time.Second, func(err error) { errs <- err })
+	defer stop()
+
+	// waitFor advances the clock until the alias has the model
+	waitFor := func(model string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			clock.Advance(time.Second)
+			if alias, _ := table.Resolve("fast"); alias.Model == model {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Expected the alias to be reloaded with %s", model)
+	}
+
+	// Changes are detected by modification time, set explicitly as filesystems may have a
+	// coarse resolution
+	modified := time.Now().Add(time.Hour)
+	if err := os.WriteFile(path, []byte(`{"fast": "openai/gpt-4.1-mini"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("gpt-4.1-mini")
+
+	// Broken files are reported once and keep the aliases
+	if err := os.WriteFile(path, []byte(`{"fast": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified.Add(time.Hour), modified.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(5 * time.Second)
+reported:
+	for {
+		clock.Advance(time.Second)
+		select {
+		case <-errs:
+			break reported
+		case <-deadline:
+			t.Fatal("Expected the broken file to be reported")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("Expected a single report, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if alias, _ := table.Resolve("fast"); alias.Model != "gpt-4.1-mini" {
+		t.Errorf("Expected the aliases to be kept, got %v", alias)
+	}
+}
+
+func TestFactory_Aliases(t *testing.T) {
+	fast := registerAliasProvider(t, "alias-fast")
+	smart := registerAliasProvider(t, "alias-smart")
+
+	table, err := NewAliasTable(map[string]ModelAlias{
+		"fast":  {Provider: "alias-fast", Model: "small"},
+		"smart": {Provider: "alias-smart", Model: "large"},
+	})
+	if err != nil {
+		t.Fatalf("NewAliasTable failed: %v", err)
+	}
+	f := New().WithAliases(table)
+
+	client, err := f.CreateClient(llm.ClientConfig{Model: "fast", APIKey: "key"})
+	if err != nil {
+		t.Fatalf("CreateClient failed: %v", err)
+	}
+	defer client.Close()
+	if info := client.GetModelInfo(); info.Name != "small" || info.Provider != "alias-fast" {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+	if inner, ok := llm.ClientAs[*mock.Client](client); !ok || inner != fast["small"] {
+		t.Error("Expected the target client to be reachable with llm.ClientAs")
+	}
+
+	ctx := context.Background()
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")}}
+	if _, err := client.ChatCompletion(ctx, req); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if fast["small"].GetLastCall() == nil {
+		t.Error("Expected the request to reach the target of the alias")
+	}
+
+	// Requests can select another alias, which is removed from the request
+	req.Model = "Smart"
+	if _, err := client.ChatCompletion(ctx, req); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if last := smart["large"].GetLastCall(); last == nil || last.Model != "" {
+		t.Errorf("Expected the request to reach the target of its alias, got %+v", last)
+	}
+
+	// Changes of the table apply to the existing clients
+	if err := table.Set("fast", ModelAlias{Provider: "alias-fast", Model: "small-v2"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	req.Model = ""
+	if _, err := client.ChatCompletion(ctx, req); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if fast["small-v2"] == nil || fast["small-v2"].GetLastCall() == nil {
+		t.Error("Expected the request to reach the new target of the alias")
+	}
+
+	var llmErr *llm.Error
+	table.Remove("fast")
+	if _, err := client.ChatCompletion(ctx, req); !errors.As(err, &llmErr) || llmErr.Code != "unknown_alias" {
+		t.Errorf("Expected unknown_alias, got %v", err)
+	}
+
+	// Models that are not aliases create provider clients
+	plain, err := f.CreateClient(llm.ClientConfig{Provider: "alias-fast", Model: "other"})
+	if err != nil {
+		t.Fatalf("CreateClient failed: %v", err)
+	}
+	if plain != llm.Client(fast["other"]) {
+		t.Errorf("Expected the provider client, got %T", plain)
+	}
+}
+
+func TestFactory_AliasProfile(t *testing.T) {
+	created := registerAliasProvider(t, "alias-profile")
+	maxTokens := 128
+	err := RegisterProfile("alias-eu", Profile{
+		Config:   llm.ClientConfig{Provider: "alias-profile", Model: "eu-default", BaseURL: "https://eu.example.com"},
+		Defaults: &RequestDefaults{MaxTokens: &maxTokens},
+	})
+	if err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	defer UnregisterProfile("alias-eu")
+
+	table, err := NewAliasTable(map[string]ModelAlias{"eu": {Profile: "alias-eu", Model: "eu-large"}})
+	if err != nil {
+		t.Fatalf("NewAliasTable failed: %v", err)
+	}
+	client, err := New().WithAliases(table).CreateClient(llm.ClientConfig{Model: "eu"})
+	if err != nil {
+		t.Fatalf("CreateClient failed: %v", err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")},
+	}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	last := created["eu-large"].GetLastCall()
+	if last == nil || last.MaxTokens == nil || *last.MaxTokens != maxTokens {
+		t.Errorf("Expected the profile defaults to apply, got %+v", last)
+	}
+}
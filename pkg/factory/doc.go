@@ -8,6 +8,12 @@
 //   - Provider registration system with thread-safe registry
 //   - Factory for creating clients based on configuration
 //   - Automatic import of all available providers
+//   - Runtime registration of external providers (RegisterProviderFactory) and an
+//     optional Go plugin loader (LoadPlugin, LoadPlugins) for private providers
+//   - Named profiles (RegisterProfile, CreateClientForProfile) with per-profile middleware and request defaults
+//   - Factory-wide request defaults, system prompt and max tokens ceiling (NewWithDefaults)
+//   - Model aliases such as "fast" or "smart" resolved on every request, reloadable from a
+//     watched JSON file (AliasTable, Factory.WithAliases)
 //
 // Example usage:
 //
@@ -22,4 +28,13 @@
 //	    Model: "gpt-4",
 //	    APIKey: "your-api-key",
 //	})
+//
+// Downstream projects can add their own providers without forking the import list:
+//
+//	err := factory.RegisterProviderFactory("acme", func(config llm.ClientConfig) (llm.Client, error) {
+//	    return acme.NewClient(config)
+//	})
+//
+// The same call can live in the init function of a package built with -buildmode=plugin,
+// which is then loaded at runtime with factory.LoadPlugin("/path/to/acme.so").
 package factory
@@ -2,7 +2,6 @@ package factory
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/inercia/go-llm/pkg/llm"
 )
@@ -10,21 +9,62 @@ import (
 const DefaultProvider = "openai"
 
 // Factory creates LLM clients based on configuration
-type Factory struct{}
+type Factory struct {
+	defaults *RequestDefaults
+	aliases  *AliasTable
+}
 
 // New creates a new client factory
 func New() *Factory {
 	return &Factory{}
 }
 
-// CreateClient creates an LLM client based on the configuration
+// NewWithDefaults creates a client factory whose clients apply the defaults to every
+// request, e.g. to enforce a max tokens ceiling or a system prompt across a service.
+// Requests still override the defaults, except MaxTokensLimit.
+//
+// The defaults are applied by a middleware, so the clients are *llm.EnhancedClient
+// wrappers: use llm.ClientAs to reach the provider client, e.g.
+// llm.ClientAs[*openai.Client](client).
+func NewWithDefaults(defaults *RequestDefaults) *Factory {
+	return &Factory{defaults: defaults}
+}
+
+// WithAliases makes the factory resolve the model aliases of the table (see CreateClient)
+func (f *Factory) WithAliases(aliases *AliasTable) *Factory {
+	f.aliases = aliases
+	return f
+}
+
+// CreateClient creates an LLM client based on the configuration. The provider client is
+// wrapped in an *llm.EnhancedClient when the factory has defaults (see NewWithDefaults).
+//
+// When the model is an alias of the table of the factory (see WithAliases), the client
+// routes every request to a client of the current target of the alias, so changes of the
+// table apply to the clients already created. The requests of such clients can also
+// select another alias with their Model.
 func (f *Factory) CreateClient(config llm.ClientConfig) (llm.Client, error) {
+	if f.aliases != nil {
+		if _, ok := f.aliases.Resolve(config.Model); ok && config.Model != "" {
+			return &aliasClient{
+				factory: f,
+				config:  config,
+				alias:   config.Model,
+				clients: make(map[ModelAlias]llm.Client),
+			}, nil
+		}
+	}
+	return f.createClient(config)
+}
+
+// createClient creates the client of a provider model
+func (f *Factory) createClient(config llm.ClientConfig) (llm.Client, error) {
 	// Default to "openai" if provider is empty for backward compatibility
 	provider := config.Provider
 	if provider == "" {
 		provider = DefaultProvider
 	}
-	provider = strings.ToLower(provider)
+	provider = normalizeProviderName(provider)
 
 	// Validate required fields
 	if config.Model == "" {
@@ -44,5 +84,9 @@ func (f *Factory) CreateClient(config llm.ClientConfig) (llm.Client, error) {
 		}
 	}
 
-	return constructor(config)
+	client, err := constructor(config)
+	if err != nil || f.defaults == nil {
+		return client, err
+	}
+	return llm.NewEnhancedClient(client, []llm.Middleware{&requestDefaultsMiddleware{defaults: f.defaults}}), nil
 }
@@ -4,11 +4,14 @@ import (
 	"github.com/inercia/go-llm/pkg/llm"
 	"github.com/inercia/go-llm/pkg/providers/bedrock"
 	"github.com/inercia/go-llm/pkg/providers/deepseek"
+	"github.com/inercia/go-llm/pkg/providers/fireworks"
 	"github.com/inercia/go-llm/pkg/providers/gemini"
 	"github.com/inercia/go-llm/pkg/providers/mock"
+	"github.com/inercia/go-llm/pkg/providers/nim"
 	"github.com/inercia/go-llm/pkg/providers/ollama"
 	"github.com/inercia/go-llm/pkg/providers/openai"
 	"github.com/inercia/go-llm/pkg/providers/openrouter"
+	"github.com/inercia/go-llm/pkg/providers/watsonx"
 )
 
 func init() {
@@ -37,16 +40,34 @@ func init() {
 		return ollama.NewClient(config)
 	})
 
+	// Register the Fireworks AI provider
+	RegisterProvider("fireworks", func(config llm.ClientConfig) (llm.Client, error) {
+		return fireworks.NewClient(config)
+	})
+
+	// Register the NVIDIA NIM provider
+	RegisterProvider("nim", func(config llm.ClientConfig) (llm.Client, error) {
+		return nim.NewClient(config)
+	})
+
+	// Register the IBM watsonx.ai provider
+	RegisterProvider("watsonx", func(config llm.ClientConfig) (llm.Client, error) {
+		return watsonx.NewClient(config)
+	})
+
 	// Register the bedrock provider
 	RegisterProvider("bedrock", func(config llm.ClientConfig) (llm.Client, error) {
 		return bedrock.NewClient(config)
 	})
 
 	// Register the mock provider
-	RegisterProvider("mock", func(config llm.ClientConfig) (llm.Client, error) {
-		return mock.NewClient(config.Model, "mock")
-	})
-	RegisterProvider("mocked", func(config llm.ClientConfig) (llm.Client, error) {
-		return mock.NewClient(config.Model, "mock")
-	})
+	newMockClient := func(config llm.ClientConfig) (llm.Client, error) {
+		client, err := mock.NewClient(config.Model, "mock")
+		if err != nil {
+			return nil, err
+		}
+		return client.WithStreamBuffer(config.StreamBuffer).WithClock(config.Clock), nil
+	}
+	RegisterProvider("mock", newMockClient)
+	RegisterProvider("mocked", newMockClient)
 }
@@ -0,0 +1,43 @@
+//go:build cgo && (linux || darwin || freebsd)
+
+package factory
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// LoadPlugin opens a Go plugin (built with -buildmode=plugin) containing private providers.
+// Plugins usually register themselves with RegisterProviderFactory from an init function;
+// if the plugin also exports a RegisterProviders function it is called after loading.
+func LoadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return newPluginError(path, err)
+	}
+
+	sym, err := p.Lookup(PluginRegisterSymbol)
+	if err != nil {
+		// The symbol is optional: registration may happen in init()
+		return nil
+	}
+
+	register, ok := sym.(func() error)
+	if !ok {
+		return newPluginError(path, fmt.Errorf("%s has type %T, expected func() error", PluginRegisterSymbol, sym))
+	}
+	if err := register(); err != nil {
+		return newPluginError(path, err)
+	}
+	return nil
+}
+
+func newPluginError(path string, err error) error {
+	return &llm.Error{
+		Code:    "plugin_load_failed",
+		Message: fmt.Sprintf("failed to load provider plugin %s: %v", path, err),
+		Type:    "plugin_error",
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !cgo || !(linux || darwin || freebsd)
+
+package factory
+
+import (
+	"fmt"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// LoadPlugin is not available on this platform: Go plugins require cgo on Linux, macOS or FreeBSD.
+// Providers can still be registered at runtime with RegisterProviderFactory.
+func LoadPlugin(path string) error {
+	return &llm.Error{
+		Code:    "plugins_unsupported",
+		Message: fmt.Sprintf("cannot load provider plugin %s: Go plugins are not supported on this platform", path),
+		Type:    "plugin_error",
+	}
+}
@@ -0,0 +1,26 @@
+package factory
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// PluginRegisterSymbol is the optional function a provider plugin can export
+// (with signature func() error) to register its providers when loaded
+const PluginRegisterSymbol = "RegisterProviders"
+
+// LoadPlugins loads every provider plugin (*.so) found in a directory, in lexical order
+func LoadPlugins(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := LoadPlugin(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// Profile is a named client configuration, e.g. one per tenant or deployment region,
+// with the middleware stack and request defaults its clients use
+type Profile struct {
+	// Config is the client configuration of the profile
+	Config llm.ClientConfig `json:"config"`
+
+	// Middlewares wrap every client created for the profile, in order. Middleware
+	// instances are shared by those clients and must be safe for concurrent use.
+	Middlewares []llm.Middleware `json:"-"`
+
+	// Defaults are applied to the requests that do not set the parameters themselves
+	Defaults *RequestDefaults `json:"defaults,omitempty"`
+}
+
+// RequestDefaults holds request parameters applied when a request leaves them unset
+type RequestDefaults struct {
+	Temperature          *float32                 `json:"temperature,omitempty"`
+	MaxTokens            *int                     `json:"max_tokens,omitempty"`
+	TopP                 *float32                 `json:"top_p,omitempty"`
+	ResponseFormatPolicy llm.ResponseFormatPolicy `json:"response_format_policy,omitempty"`
+
+	// SystemPrompt is prepended as a system message to the requests without one
+	SystemPrompt string `json:"system_prompt,omitempty"`
+
+	// MaxTokensLimit is a ceiling that requests cannot override: larger (or unset) max
+	// tokens are lowered to it
+	MaxTokensLimit *int `json:"max_tokens_limit,omitempty"`
+
+	// Metadata entries are added to the request metadata unless already present
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// profileRegistry holds all registered profiles
+type profileRegistry struct {
+	mu       sync.RWMutex
+	profiles map[string]Profile
+}
+
+var globalProfiles = &profileRegistry{
+	profiles: make(map[string]Profile),
+}
+
+// RegisterProfile registers (or replaces) a named profile. Names are case insensitive.
+func RegisterProfile(name string, profile Profile) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return &llm.Error{
+			Code:    "invalid_profile",
+			Message: "profile name is required",
+			Type:    "validation_error",
+		}
+	}
+
+	profile.Middlewares = append([]llm.Middleware(nil), profile.Middlewares...)
+	globalProfiles.mu.Lock()
+	defer globalProfiles.mu.Unlock()
+	globalProfiles.profiles[name] = profile
+	return nil
+}
+
+// UnregisterProfile removes a profile from the registry
+func UnregisterProfile(name string) {
+	globalProfiles.mu.Lock()
+	defer globalProfiles.mu.Unlock()
+	delete(globalProfiles.profiles, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// GetProfile returns a profile by name
+func GetProfile(name string) (Profile, bool) {
+	globalProfiles.mu.RLock()
+	defer globalProfiles.mu.RUnlock()
+	profile, exists := globalProfiles.profiles[strings.ToLower(strings.TrimSpace(name))]
+	return profile, exists
+}
+
+// ListProfiles returns all registered profile names, sorted
+func ListProfiles() []string {
+	globalProfiles.mu.RLock()
+	defer globalProfiles.mu.RUnlock()
+
+	names := make([]string, 0, len(globalProfiles.profiles))
+	for name := range globalProfiles.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateClientForProfile creates a client with the configuration of a registered
+// profile, wrapped with the profile middleware stack and request defaults. The profile
+// defaults take precedence over the factory defaults.
+func (f *Factory) CreateClientForProfile(name string) (llm.Client, error) {
+	profile, err := getProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.createProfileClient(profile)
+}
+
+// getProfile returns a profile by name, failing when it is not registered
+func getProfile(name string) (Profile, error) {
+	profile, exists := GetProfile(name)
+	if !exists {
+		return Profile{}, &llm.Error{
+			Code:    "profile_not_found",
+			Message: fmt.Sprintf("profile not found: %s", name),
+			Type:    "validation_error",
+		}
+	}
+	return profile, nil
+}
+
+// createProfileClient creates a client with the configuration of a profile, wrapped with
+// its middleware stack and request defaults
+func (f *Factory) createProfileClient(profile Profile) (llm.Client, error) {
+	client, err := f.createClient(profile.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var middlewares []llm.Middleware
+	if profile.Defaults != nil {
+		middlewares = append(middlewares, &requestDefaultsMiddleware{defaults: profile.Defaults})
+	}
+	middlewares = append(middlewares, profile.Middlewares...)
+	if len(middlewares) == 0 {
+		return client, nil
+	}
+	return llm.NewEnhancedClient(client, middlewares), nil
+}
+
+// requestDefaultsMiddleware fills the request parameters left unset with defaults
+type requestDefaultsMiddleware struct {
+	defaults *RequestDefaults
+}
+
+// Name returns the middleware name
+func (m *requestDefaultsMiddleware) Name() string {
+	return "request_defaults"
+}
+
+// ProcessRequest applies the defaults to a copy of the request
+func (m *requestDefaultsMiddleware) ProcessRequest(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRequest, error) {
+	out := *req
+	d := m.defaults
+	if out.Temperature == nil {
+		out.Temperature = d.Temperature
+	}
+	if out.MaxTokens == nil {
+		out.MaxTokens = d.MaxTokens
+	}
+	if out.TopP == nil {
+		out.TopP = d.TopP
+	}
+	if out.ResponseFormatPolicy == "" {
+		out.ResponseFormatPolicy = d.ResponseFormatPolicy
+	}
+	if d.MaxTokensLimit != nil && (out.MaxTokens == nil || *out.MaxTokens > *d.MaxTokensLimit) {
+		out.MaxTokens = d.MaxTokensLimit
+	}
+	if d.SystemPrompt != "" && !hasSystemMessage(req.Messages) {
+		messages := make([]llm.Message, 0, len(req.Messages)+1)
+		messages = append(messages, llm.NewTextMessage(llm.RoleSystem, d.SystemPrompt))
+		out.Messages = append(messages, req.Messages...)
+	}
+	if len(d.Metadata) > 0 {
+		metadata := maps.Clone(d.Metadata)
+		maps.Copy(metadata, req.Metadata)
+		out.Metadata = metadata
+	}
+	return &out, nil
+}
+
+// hasSystemMessage reports whether a conversation has a system message
+func hasSystemMessage(messages []llm.Message) bool {
+	for _, message := range messages {
+		if message.Role == llm.RoleSystem {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessResponse passes responses through unchanged
+func (m *requestDefaultsMiddleware) ProcessResponse(ctx context.Context, req *llm.ChatRequest, resp *llm.ChatResponse, err error) (*llm.ChatResponse, error) {
+	return resp, err
+}
+
+// ProcessStreamEvent passes events through unchanged
+func (m *requestDefaultsMiddleware) ProcessStreamEvent(ctx context.Context, req *llm.ChatRequest, event llm.StreamEvent) (llm.StreamEvent, error) {
+	return event, nil
+}
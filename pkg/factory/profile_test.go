@@ -0,0 +1,215 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/mock"
+)
+
+// tagMiddleware tags requests with the profile that handled them
+type tagMiddleware struct {
+	tag string
+}
+
+func (m *tagMiddleware) Name() string { return "tag" }
+
+func (m *tagMiddleware) ProcessRequest(ctx context.Context, req *llm.ChatRequest) (*llm.ChatRequest, error) {
+	out := *req
+	out.Metadata = map[string]any{"tag": m.tag}
+	for k, v := range req.Metadata {
+		out.Metadata[k] = v
+	}
+	return &out, nil
+}
+
+func (m *tagMiddleware) ProcessResponse(ctx context.Context, req *llm.ChatRequest, resp *llm.ChatResponse, err error) (*llm.ChatResponse, error) {
+	return resp, err
+}
+
+func (m *tagMiddleware) ProcessStreamEvent(ctx context.Context, req *llm.ChatRequest, event llm.StreamEvent) (llm.StreamEvent, error) {
+	return event, nil
+}
+
+func TestCreateClientForProfile(t *testing.T) {
+	var created *mock.Client
+	err := RegisterProviderFactory("profile-test", func(config llm.ClientConfig) (llm.Client, error) {
+		client, err := mock.NewClient(config.Model, "profile-test")
+		created = client
+		return client, err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UnregisterProvider("profile-test")
+
+	temperature := float32(0.2)
+	maxTokens := 512
+	err = RegisterProfile("Prod-EU", Profile{
+		Config:      llm.ClientConfig{Provider: "profile-test", Model: "eu-model"},
+		Middlewares: []llm.Middleware{&tagMiddleware{tag: "eu"}},
+		Defaults: &RequestDefaults{
+			Temperature: &temperature,
+			MaxTokens:   &maxTokens,
+			Metadata:    map[string]any{"tenant": "acme", "region": "eu"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	defer UnregisterProfile("prod-eu")
+
+	client, err := New().CreateClientForProfile("prod-eu")
+	if err != nil {
+		t.Fatalf("CreateClientForProfile failed: %v", err)
+	}
+	if client.GetModelInfo().Name != "eu-model" {
+		t.Errorf("Unexpected model: %s", client.GetModelInfo().Name)
+	}
+
+	// Requests override the defaults
+	override := 64
+	_, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages:  []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")},
+		MaxTokens: &override,
+		Metadata:  map[string]any{"region": "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	req := created.GetLastCall()
+	if req == nil {
+		t.Fatal("Expected the request to reach the provider")
+	}
+	if req.Temperature == nil || *req.Temperature != temperature {
+		t.Errorf("Expected default temperature, got %v", req.Temperature)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != override {
+		t.Errorf("Expected request max tokens to win, got %v", req.MaxTokens)
+	}
+	want := map[string]any{"tenant": "acme", "region": "eu-west-1", "tag": "eu"}
+	if !reflect.DeepEqual(req.Metadata, want) {
+		t.Errorf("Unexpected metadata: %v", req.Metadata)
+	}
+
+	if names := ListProfiles(); !reflect.DeepEqual(names, []string{"prod-eu"}) {
+		t.Errorf("Unexpected profiles: %v", names)
+	}
+
+	var llmErr *llm.Error
+	if _, err := New().CreateClientForProfile("missing"); !errors.As(err, &llmErr) || llmErr.Code != "profile_not_found" {
+		t.Errorf("Expected profile_not_found, got %v", err)
+	}
+	if err := RegisterProfile(" ", Profile{}); !errors.As(err, &llmErr) || llmErr.Code != "invalid_profile" {
+		t.Errorf("Expected invalid_profile, got %v", err)
+	}
+}
+
+func TestCreateClientForProfile_Plain(t *testing.T) {
+	if err := RegisterProfile("plain", Profile{Config: llm.ClientConfig{Provider: "mock", Model: "m"}}); err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	defer UnregisterProfile("plain")
+
+	client, err := New().CreateClientForProfile("plain")
+	if err != nil {
+		t.Fatalf("CreateClientForProfile failed: %v", err)
+	}
+	if _, ok := client.(*mock.Client); !ok {
+		t.Errorf("Expected an unwrapped client without middleware, got %T", client)
+	}
+}
+
+func TestNewWithDefaults(t *testing.T) {
+	var created *mock.Client
+	err := RegisterProviderFactory("defaults-test", func(config llm.ClientConfig) (llm.Client, error) {
+		client, err := mock.NewClient(config.Model, "defaults-test")
+		created = client
+		return client, err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UnregisterProvider("defaults-test")
+
+	factoryTemperature, profileTemperature := float32(0.1), float32(0.7)
+	limit := 256
+	f := NewWithDefaults(&RequestDefaults{
+		Temperature:    &factoryTemperature,
+		SystemPrompt:   "You are a support assistant.",
+		MaxTokensLimit: &limit,
+	})
+
+	client, err := f.CreateClient(llm.ClientConfig{Provider: "defaults-test", Model: "m"})
+	if err != nil {
+		t.Fatalf("CreateClient failed: %v", err)
+	}
+	if inner, ok := llm.ClientAs[*mock.Client](client); !ok || inner != created {
+		t.Error("Expected the provider client to be reachable with llm.ClientAs")
+	}
+	requested := 4096
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages:  []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")},
+		MaxTokens: &requested,
+	}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	req := created.GetLastCall()
+	if req.MaxTokens == nil || *req.MaxTokens != limit {
+		t.Errorf("Expected max tokens lowered to the limit, got %v", req.MaxTokens)
+	}
+	if req.Temperature == nil || *req.Temperature != factoryTemperature {
+		t.Errorf("Expected the factory temperature, got %v", req.Temperature)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != llm.RoleSystem || req.Messages[0].GetText() != "You are a support assistant." {
+		t.Errorf("Expected the system prompt to be prepended, got %+v", req.Messages)
+	}
+
+	// Requests with their own system message keep it
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "Be terse."),
+			llm.NewTextMessage(llm.RoleUser, "hi"),
+		},
+	}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	req = created.GetLastCall()
+	if len(req.Messages) != 2 || req.Messages[0].GetText() != "Be terse." {
+		t.Errorf("Expected the request system message to be kept, got %+v", req.Messages)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != limit {
+		t.Errorf("Expected unset max tokens to be set to the limit, got %v", req.MaxTokens)
+	}
+
+	// Profile defaults take precedence over the factory defaults
+	err = RegisterProfile("defaults-profile", Profile{
+		Config:   llm.ClientConfig{Provider: "defaults-test", Model: "m"},
+		Defaults: &RequestDefaults{Temperature: &profileTemperature},
+	})
+	if err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	defer UnregisterProfile("defaults-profile")
+
+	client, err = f.CreateClientForProfile("defaults-profile")
+	if err != nil {
+		t.Fatalf("CreateClientForProfile failed: %v", err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")},
+	}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	req = created.GetLastCall()
+	if req.Temperature == nil || *req.Temperature != profileTemperature {
+		t.Errorf("Expected the profile temperature, got %v", req.Temperature)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != limit {
+		t.Errorf("Expected the factory limit to still apply, got %v", req.MaxTokens)
+	}
+}
@@ -2,6 +2,8 @@
 package factory
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/inercia/go-llm/pkg/llm"
@@ -20,21 +22,64 @@ var globalRegistry = &providerRegistry{
 	providers: make(map[string]ProviderConstructor),
 }
 
-// RegisterProvider registers a provider constructor function
+// RegisterProvider registers a provider constructor function. The name is stored as
+// is, so it must be lower case to be found.
 func RegisterProvider(name string, constructor ProviderConstructor) {
 	globalRegistry.mu.Lock()
 	defer globalRegistry.mu.Unlock()
 	globalRegistry.providers[name] = constructor
 }
 
-// GetProvider returns a provider constructor by name
+// RegisterProviderFactory registers a provider constructor from an external module.
+// Unlike RegisterProvider, the name is normalized to lower case (as CreateClient does)
+// and registering a name that is already taken returns an error instead of silently
+// replacing the existing provider.
+func RegisterProviderFactory(name string, constructor ProviderConstructor) error {
+	name = normalizeProviderName(name)
+	if name == "" || constructor == nil {
+		return &llm.Error{
+			Code:    "invalid_provider",
+			Message: "provider name and constructor are required",
+			Type:    "validation_error",
+		}
+	}
+
+	globalRegistry.mu.Lock()
+	defer globalRegistry.mu.Unlock()
+	if _, exists := globalRegistry.providers[name]; exists {
+		return &llm.Error{
+			Code:    "provider_already_registered",
+			Message: fmt.Sprintf("provider already registered: %s", name),
+			Type:    "validation_error",
+		}
+	}
+	globalRegistry.providers[name] = constructor
+	return nil
+}
+
+// UnregisterProvider removes a provider from the registry, by its name normalized as
+// in RegisterProviderFactory
+func UnregisterProvider(name string) {
+	globalRegistry.mu.Lock()
+	defer globalRegistry.mu.Unlock()
+	delete(globalRegistry.providers, normalizeProviderName(name))
+}
+
+// GetProvider returns a provider constructor by name, normalized as in
+// RegisterProviderFactory
 func GetProvider(name string) (ProviderConstructor, bool) {
 	globalRegistry.mu.RLock()
 	defer globalRegistry.mu.RUnlock()
-	constructor, exists := globalRegistry.providers[name]
+	constructor, exists := globalRegistry.providers[normalizeProviderName(name)]
 	return constructor, exists
 }
 
+// normalizeProviderName returns the registry key of a provider name: trimmed and in
+// lower case
+func normalizeProviderName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
 // ListProviders returns all registered provider names
 func ListProviders() []string {
 	globalRegistry.mu.RLock()
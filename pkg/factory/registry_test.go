@@ -0,0 +1,82 @@
+package factory
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/mock"
+)
+
+func TestRegisterProviderFactory(t *testing.T) {
+	constructor := func(config llm.ClientConfig) (llm.Client, error) {
+		return mock.NewClient(config.Model, "private")
+	}
+
+	if err := RegisterProviderFactory("Private-LLM", constructor); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UnregisterProvider("private-llm")
+
+	client, err := New().CreateClient(llm.ClientConfig{Provider: "private-llm", Model: "m1"})
+	if err != nil {
+		t.Fatalf("Failed to create client from registered factory: %v", err)
+	}
+	if client.GetModelInfo().Name != "m1" {
+		t.Errorf("Unexpected model: %s", client.GetModelInfo().Name)
+	}
+
+	var llmErr *llm.Error
+	err = RegisterProviderFactory("private-llm", constructor)
+	if !errors.As(err, &llmErr) || llmErr.Code != "provider_already_registered" {
+		t.Errorf("Expected provider_already_registered, got %v", err)
+	}
+
+	err = RegisterProviderFactory("openai", constructor)
+	if !errors.As(err, &llmErr) || llmErr.Code != "provider_already_registered" {
+		t.Errorf("Built-in providers must not be replaced, got %v", err)
+	}
+
+	err = RegisterProviderFactory(" ", constructor)
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_provider" {
+		t.Errorf("Expected invalid_provider, got %v", err)
+	}
+}
+
+func TestUnregisterProvider_MixedCase(t *testing.T) {
+	constructor := func(config llm.ClientConfig) (llm.Client, error) {
+		return mock.NewClient(config.Model, "acme")
+	}
+	if err := RegisterProviderFactory("Acme", constructor); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UnregisterProvider("acme")
+	if _, ok := GetProvider(" ACME "); !ok {
+		t.Fatal("Expected the provider to be found with any case")
+	}
+
+	UnregisterProvider("Acme")
+	if _, ok := GetProvider("acme"); ok {
+		t.Error("Expected the provider to be unregistered")
+	}
+	if _, err := New().CreateClient(llm.ClientConfig{Provider: "Acme", Model: "m1"}); err == nil {
+		t.Error("Expected the unregistered provider not to be resolvable")
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	// An empty directory has nothing to load
+	if err := LoadPlugins(t.TempDir()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	var llmErr *llm.Error
+	err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so"))
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.Code != "plugin_load_failed" && llmErr.Code != "plugins_unsupported" {
+		t.Errorf("Unexpected error code: %s", llmErr.Code)
+	}
+}
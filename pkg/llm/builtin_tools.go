@@ -0,0 +1,52 @@
+// Calls of the tools run by the providers themselves (code execution, web search...)
+package llm
+
+// BuiltinToolType is the kind of a tool run by a provider
+type BuiltinToolType string
+
+const (
+	// BuiltinToolCodeExecution runs code written by the model in a sandbox
+	BuiltinToolCodeExecution BuiltinToolType = "code_execution"
+
+	// BuiltinToolWebSearch searches the web to ground the response
+	BuiltinToolWebSearch BuiltinToolType = "web_search"
+
+	// BuiltinToolFileSearch searches documents uploaded to the provider
+	BuiltinToolFileSearch BuiltinToolType = "file_search"
+)
+
+// BuiltinToolCall is a call of a tool run by the provider while generating a response,
+// enabled with provider options (e.g. gemini.WithCodeExecution). Unlike ToolCalls, they
+// are informative: the application neither executes them nor answers them with tool
+// messages, and they are not sent back to the provider in later turns.
+type BuiltinToolCall struct {
+	ID   string          `json:"id,omitempty"`
+	Type BuiltinToolType `json:"type"`
+
+	// Language and Code are the code run by code execution tools
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code,omitempty"`
+
+	// Output is the output of the code, or the error when it failed
+	Output string `json:"output,omitempty"`
+
+	// Queries are the queries run by search tools
+	Queries []string `json:"queries,omitempty"`
+
+	// Results are the sources found by search tools
+	Results []Citation `json:"results,omitempty"`
+
+	// Status is the outcome reported by the provider (e.g. "OUTCOME_OK", "completed")
+	Status string `json:"status,omitempty"`
+}
+
+// BuiltinToolCallsOf returns the calls of a type of a message, in order
+func (m Message) BuiltinToolCallsOf(toolType BuiltinToolType) []BuiltinToolCall {
+	var calls []BuiltinToolCall
+	for _, call := range m.BuiltinToolCalls {
+		if call.Type == toolType {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
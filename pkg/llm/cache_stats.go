@@ -0,0 +1,225 @@
+// Prompt caching statistics aggregated from response usage
+package llm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheStatsConfig configures CacheStats
+type CacheStatsConfig struct {
+	// BucketSize is the period aggregated by every entry of the history
+	BucketSize time.Duration `json:"bucket_size"`
+
+	// Retention is how long the history is kept. Totals are kept forever.
+	Retention time.Duration `json:"retention"`
+}
+
+// DefaultCacheStatsConfig returns the default configuration: hourly buckets kept for a week
+func DefaultCacheStatsConfig() *CacheStatsConfig {
+	return &CacheStatsConfig{
+		BucketSize: time.Hour,
+		Retention:  7 * 24 * time.Hour,
+	}
+}
+
+// CacheUsage aggregates the prompt cache usage of some requests
+type CacheUsage struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+}
+
+// add adds the usage of a response
+func (u *CacheUsage) add(usage Usage) {
+	u.Requests++
+	u.PromptTokens += int64(usage.PromptTokens)
+	u.CacheReadTokens += int64(usage.CacheReadTokens)
+	u.CacheWriteTokens += int64(usage.CacheWriteTokens)
+}
+
+// merge adds another aggregate
+func (u *CacheUsage) merge(other CacheUsage) {
+	u.Requests += other.Requests
+	u.PromptTokens += other.PromptTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+}
+
+// HitRate returns the fraction of the prompt tokens served from the cache
+func (u CacheUsage) HitRate() float64 {
+	if u.PromptTokens == 0 {
+		return 0
+	}
+	return float64(u.CacheReadTokens) / float64(u.PromptTokens)
+}
+
+// SavedTokens returns the prompt tokens saved by caching, measured in uncached prompt
+// tokens, given the price of cache reads and writes relative to uncached tokens (e.g. 0.1
+// and 1.25 for Anthropic, 0.5 and 1 for OpenAI). It is negative when the cache writes
+// cost more than the reads saved.
+func (u CacheUsage) SavedTokens(readCost, writeCost float64) float64 {
+	return float64(u.CacheReadTokens)*(1-readCost) - float64(u.CacheWriteTokens)*(writeCost-1)
+}
+
+// CacheUsageBucket is the cache usage of a period of the history
+type CacheUsageBucket struct {
+	Start time.Time `json:"start"`
+	CacheUsage
+}
+
+// modelCacheStats is the cache usage of a model
+type modelCacheStats struct {
+	total   CacheUsage
+	buckets []CacheUsageBucket
+}
+
+// CacheStats aggregates the prompt tokens read from and written to provider prompt caches
+// per model over time, from the usage of responses, so the savings of prompt caching can
+// be quantified and prompts restructured to improve them. Record responses directly or
+// with CacheStatsMiddleware.
+//
+//	stats := llm.NewCacheStats(nil)
+//	client := llm.NewEnhancedClient(base, []llm.Middleware{llm.NewCacheStatsMiddleware(stats)})
+//	...
+//	for model, usage := range stats.Totals() {
+//	    log.Printf("%s: %.0f%% cached", model, usage.HitRate()*100)
+//	}
+type CacheStats struct {
+	config *CacheStatsConfig
+
+	mu     sync.Mutex
+	models map[string]*modelCacheStats
+
+	// now returns the current time; replaceable for testing
+	now func() time.Time
+}
+
+// NewCacheStats creates cache statistics, filling unset config fields with defaults
+func NewCacheStats(config *CacheStatsConfig) *CacheStats {
+	defaults := DefaultCacheStatsConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.BucketSize <= 0 {
+		config.BucketSize = defaults.BucketSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	return &CacheStats{config: config, models: make(map[string]*modelCacheStats), now: time.Now}
+}
+
+// Record adds the usage of a response of a model
+func (s *CacheStats) Record(model string, usage Usage) {
+	now := s.now()
+	start := now.Truncate(s.config.BucketSize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.models[model]
+	if !ok {
+		stats = &modelCacheStats{}
+		s.models[model] = stats
+	}
+	stats.total.add(usage)
+
+	if n := len(stats.buckets); n == 0 || !stats.buckets[n-1].Start.Equal(start) {
+		stats.buckets = append(stats.buckets, CacheUsageBucket{Start: start})
+	}
+	stats.buckets[len(stats.buckets)-1].add(usage)
+
+	// Drop the buckets older than the retention
+	cutoff := now.Add(-s.config.Retention)
+	expired := sort.Search(len(stats.buckets), func(i int) bool {
+		return !stats.buckets[i].Start.Add(s.config.BucketSize).Before(cutoff)
+	})
+	stats.buckets = stats.buckets[expired:]
+}
+
+// Totals returns the cache usage of every model since the statistics were created
+func (s *CacheStats) Totals() map[string]CacheUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	totals := make(map[string]CacheUsage, len(s.models))
+	for model, stats := range s.models {
+		totals[model] = stats.total
+	}
+	return totals
+}
+
+// Total returns the cache usage of all the models
+func (s *CacheStats) Total() CacheUsage {
+	var total CacheUsage
+	for _, usage := range s.Totals() {
+		total.merge(usage)
+	}
+	return total
+}
+
+// History returns the cache usage of a model per period since a time, oldest first.
+// Periods without requests are omitted.
+func (s *CacheStats) History(model string, since time.Time) []CacheUsageBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.models[model]
+	if !ok {
+		return nil
+	}
+	var history []CacheUsageBucket
+	for _, bucket := range stats.buckets {
+		if !bucket.Start.Add(s.config.BucketSize).Before(since) {
+			history = append(history, bucket)
+		}
+	}
+	return history
+}
+
+// Reset clears the statistics
+func (s *CacheStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = make(map[string]*modelCacheStats)
+}
+
+// CacheStatsMiddleware records the usage of responses in CacheStats, by the model of the
+// response, or of the request when the provider does not report it
+type CacheStatsMiddleware struct {
+	stats *CacheStats
+}
+
+// NewCacheStatsMiddleware creates a middleware recording in the statistics
+func NewCacheStatsMiddleware(stats *CacheStats) *CacheStatsMiddleware {
+	return &CacheStatsMiddleware{stats: stats}
+}
+
+// Name returns the middleware name
+func (m *CacheStatsMiddleware) Name() string {
+	return "cache_stats"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *CacheStatsMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse records the usage of successful responses
+func (m *CacheStatsMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	model := resp.Model
+	if model == "" && req != nil {
+		model = req.Model
+	}
+	m.stats.Record(model, resp.Usage)
+	return resp, nil
+}
+
+// ProcessStreamEvent passes events through unchanged
+func (m *CacheStatsMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheStatsTotals(t *testing.T) {
+	stats := NewCacheStats(nil)
+	stats.Record("gpt-4o", Usage{PromptTokens: 1000, CacheReadTokens: 800})
+	stats.Record("gpt-4o", Usage{PromptTokens: 1000})
+	stats.Record("claude", Usage{PromptTokens: 2000, CacheWriteTokens: 1500})
+
+	totals := stats.Totals()
+	require.Len(t, totals, 2)
+	assert.Equal(t, CacheUsage{Requests: 2, PromptTokens: 2000, CacheReadTokens: 800}, totals["gpt-4o"])
+	assert.InDelta(t, 0.4, totals["gpt-4o"].HitRate(), 1e-9)
+	assert.Equal(t, int64(3), stats.Total().Requests)
+
+	// Anthropic charges 10% for reads and 125% for writes
+	usage := CacheUsage{CacheReadTokens: 1000, CacheWriteTokens: 1000}
+	assert.InDelta(t, 650, usage.SavedTokens(0.1, 1.25), 1e-9)
+
+	stats.Reset()
+	assert.Empty(t, stats.Totals())
+}
+
+func TestCacheStatsHistory(t *testing.T) {
+	stats := NewCacheStats(&CacheStatsConfig{BucketSize: time.Hour, Retention: 3 * time.Hour})
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	stats.now = func() time.Time { return now }
+
+	stats.Record("m", Usage{PromptTokens: 100, CacheReadTokens: 10})
+	stats.Record("m", Usage{PromptTokens: 100, CacheReadTokens: 30})
+	now = now.Add(time.Hour)
+	stats.Record("m", Usage{PromptTokens: 100, CacheReadTokens: 90})
+
+	history := stats.History("m", time.Time{})
+	require.Len(t, history, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), history[0].Start)
+	assert.Equal(t, int64(40), history[0].CacheReadTokens)
+	assert.InDelta(t, 0.9, history[1].HitRate(), 1e-9)
+
+	// Only the buckets overlapping the period are returned
+	assert.Len(t, stats.History("m", now), 1)
+	assert.Nil(t, stats.History("other", time.Time{}))
+
+	// Buckets older than the retention are dropped, the totals are kept
+	now = now.Add(5 * time.Hour)
+	stats.Record("m", Usage{PromptTokens: 100})
+	assert.Len(t, stats.History("m", time.Time{}), 1)
+	assert.Equal(t, int64(4), stats.Totals()["m"].Requests)
+}
+
+func TestCacheStatsMiddleware(t *testing.T) {
+	stats := NewCacheStats(nil)
+	mock := NewMockClient("mock-model", "mock")
+	mock.responses = []*ChatResponse{{
+		Choices: []Choice{{Message: NewTextMessage(RoleAssistant, "Hi")}},
+		Usage:   Usage{PromptTokens: 500, CacheReadTokens: 400},
+	}}
+	client := NewEnhancedClient(mock, []Middleware{NewCacheStatsMiddleware(stats)})
+
+	_, err := client.ChatCompletion(context.Background(), ChatRequest{Model: "requested-model"})
+	require.NoError(t, err)
+	assert.Equal(t, CacheUsage{Requests: 1, PromptTokens: 500, CacheReadTokens: 400}, stats.Totals()["requested-model"])
+}
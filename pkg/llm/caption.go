@@ -0,0 +1,237 @@
+// Image captioning fallback for models without vision support
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ImageCaptionConfig configures an ImageCaptioner
+type ImageCaptionConfig struct {
+	// VisionClient is the vision-capable client generating the captions
+	VisionClient Client `json:"-"`
+
+	// Prompt is the instruction sent along with the image
+	Prompt string `json:"prompt"`
+
+	// MaxTokens limits the length of a caption
+	MaxTokens int `json:"max_tokens"`
+
+	// CacheSize is the number of captions remembered, so an image repeated across the
+	// turns of a conversation is only captioned once. Negative disables the cache.
+	CacheSize int `json:"cache_size"`
+}
+
+// DefaultImageCaptionConfig returns the default captioning configuration. The vision
+// client must still be set.
+func DefaultImageCaptionConfig() *ImageCaptionConfig {
+	return &ImageCaptionConfig{
+		Prompt:    "Describe this image in detail, including any text it contains. Reply with the description only.",
+		MaxTokens: 500,
+		CacheSize: 128,
+	}
+}
+
+// ImageCaptioner is a ContentTransformer replacing images with captions generated by a
+// vision-capable client, as "[Image: <caption>]" text. It can be registered for a
+// provider with RegisterContentTransformer, or used for a single client with
+// NewImageCaptioningClient.
+type ImageCaptioner struct {
+	config *ImageCaptionConfig
+
+	mu       sync.Mutex
+	captions map[[sha256.Size]byte]*list.Element
+	order    *list.List // least recently used first
+}
+
+// captionEntry is a cached caption
+type captionEntry struct {
+	key     [sha256.Size]byte
+	caption string
+}
+
+// NewImageCaptioner creates a captioner, filling unset config fields with defaults
+func NewImageCaptioner(config *ImageCaptionConfig) *ImageCaptioner {
+	defaults := DefaultImageCaptionConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Prompt == "" {
+		config.Prompt = defaults.Prompt
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = defaults.MaxTokens
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = defaults.CacheSize
+	}
+	return &ImageCaptioner{
+		config:   config,
+		captions: make(map[[sha256.Size]byte]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// TransformContent implements ContentTransformer, replacing images with their caption.
+// Other content is returned unchanged.
+func (c *ImageCaptioner) TransformContent(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+	image, ok := content.(*ImageContent)
+	if !ok {
+		return []MessageContent{content}, nil
+	}
+	caption, err := c.Caption(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	return []MessageContent{NewTextContent("[Image: " + caption + "]")}, nil
+}
+
+// Caption returns the caption of an image, from the cache when it was already captioned
+func (c *ImageCaptioner) Caption(ctx context.Context, image *ImageContent) (string, error) {
+	if c.config.VisionClient == nil {
+		return "", &Error{
+			Code:    "image_caption_failed",
+			Message: "no vision client configured for image captioning",
+			Type:    "validation_error",
+		}
+	}
+
+	key := imageKey(image)
+	if caption, ok := c.cached(key); ok {
+		return caption, nil
+	}
+
+	maxTokens := c.config.MaxTokens
+	resp, err := c.config.VisionClient.ChatCompletion(ctx, ChatRequest{
+		Messages: []Message{{
+			Role:    RoleUser,
+			Content: []MessageContent{NewTextContent(c.config.Prompt), image},
+		}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		captionErr := &Error{
+			Code:    "image_caption_failed",
+			Message: fmt.Sprintf("failed to caption image: %v", err),
+			Type:    "api_error",
+		}
+		var llmErr *Error
+		if errors.As(err, &llmErr) {
+			captionErr.StatusCode = llmErr.StatusCode
+			captionErr.RateLimit = llmErr.RateLimit
+		}
+		return "", captionErr
+	}
+	if len(resp.Choices) == 0 {
+		return "", &Error{
+			Code:    "image_caption_failed",
+			Message: "the vision client returned no caption",
+			Type:    "api_error",
+		}
+	}
+
+	caption := strings.TrimSpace(resp.Choices[0].Message.GetText())
+	c.store(key, caption)
+	return caption, nil
+}
+
+// imageKey identifies an image by its data, or its URL when it has no data
+func imageKey(image *ImageContent) [sha256.Size]byte {
+	if len(image.Data) > 0 {
+		return sha256.Sum256(image.Data)
+	}
+	return sha256.Sum256([]byte(image.URL))
+}
+
+// cached returns a cached caption, marking it as recently used
+func (c *ImageCaptioner) cached(key [sha256.Size]byte) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.captions[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToBack(element)
+	return element.Value.(*captionEntry).caption, true
+}
+
+// store caches a caption, evicting the least recently used one when full
+func (c *ImageCaptioner) store(key [sha256.Size]byte, caption string) {
+	if c.config.CacheSize < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.captions[key]; ok {
+		element.Value.(*captionEntry).caption = caption
+		c.order.MoveToBack(element)
+		return
+	}
+	c.captions[key] = c.order.PushBack(&captionEntry{key: key, caption: caption})
+	if c.order.Len() > c.config.CacheSize {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.captions, oldest.Value.(*captionEntry).key)
+	}
+}
+
+// ImageCaptioningClient wraps a client so that, when its model does not support vision,
+// images in requests are replaced by captions from a vision-capable client instead of
+// being rejected. Requests to vision models are forwarded unchanged.
+type ImageCaptioningClient struct {
+	client    Client
+	captioner *ImageCaptioner
+}
+
+// NewImageCaptioningClient wraps a client with an ImageCaptioner created from the config
+func NewImageCaptioningClient(client Client, config *ImageCaptionConfig) *ImageCaptioningClient {
+	return &ImageCaptioningClient{client: client, captioner: NewImageCaptioner(config)}
+}
+
+// ChatCompletion captions the images of the request when needed and performs it
+func (c *ImageCaptioningClient) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req, err := c.captionImages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.ChatCompletion(ctx, req)
+}
+
+// StreamChatCompletion captions the images of the request when needed and streams it
+func (c *ImageCaptioningClient) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	req, err := c.captionImages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.StreamChatCompletion(ctx, req)
+}
+
+// captionImages replaces the images of a request for a model without vision support
+func (c *ImageCaptioningClient) captionImages(ctx context.Context, req ChatRequest) (ChatRequest, error) {
+	return req.transformContent(ctx, c.client.GetModelInfo(), func(contentType MessageType) (ContentTransformer, bool) {
+		return c.captioner, contentType == MessageTypeImage
+	})
+}
+
+// GetRemote returns the remote information of the wrapped client
+func (c *ImageCaptioningClient) GetRemote() ClientRemoteInfo {
+	return c.client.GetRemote()
+}
+
+// GetModelInfo returns the model information of the wrapped client, reporting vision
+// support since images are accepted
+func (c *ImageCaptioningClient) GetModelInfo() ModelInfo {
+	info := c.client.GetModelInfo()
+	info.SupportsVision = true
+	return info
+}
+
+// Close closes the wrapped client
+func (c *ImageCaptioningClient) Close() error {
+	return c.client.Close()
+}
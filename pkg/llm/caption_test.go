@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// visionTestClient is a mock client whose model supports vision
+type visionTestClient struct {
+	testMockClient
+}
+
+func (c *visionTestClient) GetModelInfo() ModelInfo {
+	info := c.testMockClient.GetModelInfo()
+	info.SupportsVision = true
+	return info
+}
+
+func imageRequest(data []byte) ChatRequest {
+	return ChatRequest{Messages: []Message{{
+		Role:    RoleUser,
+		Content: []MessageContent{NewTextContent("What is in the picture?"), NewImageContentFromBytes(data, "image/png")},
+	}}}
+}
+
+func TestImageCaptioningClient(t *testing.T) {
+	vision := NewMockClient("vision-model", "test-provider")
+	vision.responses = []*ChatResponse{truncatedResponse(" A red square. ", FinishReasonStop)}
+	textClient := NewMockClient("text-model", "test-provider")
+	client := NewImageCaptioningClient(textClient, &ImageCaptionConfig{VisionClient: vision})
+
+	req := imageRequest([]byte{1, 2, 3})
+	for i := 0; i < 2; i++ {
+		if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+			t.Fatalf("ChatCompletion failed: %v", err)
+		}
+	}
+
+	calls := textClient.GetCallLog()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(calls))
+	}
+	content := calls[1].Messages[0].Content
+	if len(content) != 2 || content[1].(*TextContent).GetText() != "[Image: A red square.]" {
+		t.Errorf("Expected the image replaced by its caption, got %v", content)
+	}
+	if len(vision.GetCallLog()) != 1 {
+		t.Errorf("Expected the caption to be cached, got %d captioning requests", len(vision.GetCallLog()))
+	}
+	if _, ok := req.Messages[0].Content[1].(*ImageContent); !ok {
+		t.Error("Expected the original request to be untouched")
+	}
+	if !client.GetModelInfo().SupportsVision {
+		t.Error("Expected the wrapper to report vision support")
+	}
+}
+
+func TestImageCaptioningClient_VisionModel(t *testing.T) {
+	vision := NewMockClient("vision-model", "test-provider")
+	model := &visionTestClient{testMockClient: *NewMockClient("model", "test-provider")}
+	client := NewImageCaptioningClient(model, &ImageCaptionConfig{VisionClient: vision})
+
+	stream, err := client.StreamChatCompletion(context.Background(), imageRequest([]byte{1}))
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	for range stream {
+	}
+
+	if len(vision.GetCallLog()) != 0 {
+		t.Error("Expected no captioning for a vision model")
+	}
+	if _, ok := model.callLog[0].Messages[0].Content[1].(*ImageContent); !ok {
+		t.Error("Expected the image to be forwarded")
+	}
+}
+
+func TestImageCaptioner_Errors(t *testing.T) {
+	vision := NewMockClient("vision-model", "test-provider")
+	vision.errorToReturn = &Error{Code: "rate_limit_error", Message: "slow down", Type: "rate_limit_error", StatusCode: 429}
+	client := NewImageCaptioningClient(NewMockClient("text-model", "test-provider"), &ImageCaptionConfig{VisionClient: vision})
+
+	_, err := client.ChatCompletion(context.Background(), imageRequest([]byte{1}))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "image_caption_failed" || llmErr.StatusCode != 429 {
+		t.Errorf("Expected image_caption_failed with the vision status, got %v", err)
+	}
+
+	captioner := NewImageCaptioner(nil)
+	if _, err := captioner.Caption(context.Background(), NewImageContentFromBytes([]byte{1}, "image/png")); err == nil {
+		t.Error("Expected an error without a vision client")
+	}
+}
+
+func TestImageCaptioner_CacheEviction(t *testing.T) {
+	vision := NewMockClient("vision-model", "test-provider")
+	captioner := NewImageCaptioner(&ImageCaptionConfig{VisionClient: vision, CacheSize: 1})
+
+	for _, data := range [][]byte{{1}, {2}, {1}} {
+		if _, err := captioner.Caption(context.Background(), NewImageContentFromBytes(data, "image/png")); err != nil {
+			t.Fatalf("Caption failed: %v", err)
+		}
+	}
+	if len(vision.GetCallLog()) != 3 {
+		t.Errorf("Expected the first caption to be evicted, got %d requests", len(vision.GetCallLog()))
+	}
+}
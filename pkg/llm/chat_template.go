@@ -0,0 +1,258 @@
+// Client-side chat templates for raw completion endpoints
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ChatTemplate renders a conversation into the prompt format a model was trained on, for
+// providers exposing raw completion endpoints (Ollama in raw mode, llama.cpp servers...)
+// where the chat template is applied client-side.
+//
+// Templates use Go template syntax with the same data as Ollama Modelfile templates, so
+// the templates shipped with Ollama models render unchanged:
+//
+//   - .System: the system messages, joined with newlines
+//   - .Messages: every message, with .Role ("system", "user", "assistant" or "tool"),
+//     .Content (the text of the message), .ToolCallID and .ToolCalls (each with
+//     .Function.Name and .Function.Arguments, the arguments as a JSON string)
+//   - .Tools: the tools of the request (.Function.Name, .Function.Description and
+//     .Function.Parameters)
+//   - .Prompt: the text of the last user message, for single-turn templates
+//   - .Response: empty, as the model generates the response
+//
+// The json function encodes a value as JSON (e.g. {{ json .Function }}), and currentDate
+// returns the current date (YYYY-MM-DD). Models publishing Jinja templates can use one of
+// the presets (see ChatTemplatePresets) for their family.
+type ChatTemplate struct {
+	name     string
+	template *template.Template
+	stop     []string
+}
+
+// chatTemplateData is the data the templates are executed with
+type chatTemplateData struct {
+	System   string
+	Messages []chatTemplateMessage
+	Tools    []Tool
+	Prompt   string
+	Response string // always empty: the model completes the response
+}
+
+type chatTemplateMessage struct {
+	Role       string
+	Content    string
+	ToolCallID string
+	ToolCalls  []ToolCall
+}
+
+var chatTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"currentDate": func() string {
+		return time.Now().Format("2006-01-02")
+	},
+}
+
+// NewChatTemplate parses a chat template. The stop sequences end the generation of the
+// assistant turn (e.g. "<|im_end|>"), and are sent to the provider with the rendered prompt.
+func NewChatTemplate(name, text string, stop ...string) (*ChatTemplate, error) {
+	tmpl, err := template.New(name).Funcs(chatTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, &Error{
+			Code:    "invalid_chat_template",
+			Message: fmt.Sprintf("failed to parse chat template %s: %v", name, err),
+			Type:    "validation_error",
+		}
+	}
+	return &ChatTemplate{name: name, template: tmpl, stop: stop}, nil
+}
+
+// Name returns the name of the template
+func (t *ChatTemplate) Name() string {
+	return t.name
+}
+
+// Stop returns the stop sequences of the template
+func (t *ChatTemplate) Stop() []string {
+	return append([]string(nil), t.stop...)
+}
+
+// Render renders the messages and tools into a prompt, ending with the header of the
+// assistant turn the model completes
+func (t *ChatTemplate) Render(messages []Message, tools []Tool) (string, error) {
+	data := chatTemplateData{Tools: tools}
+	var system []string
+	for _, msg := range messages {
+		text := messageText(msg)
+		switch msg.Role {
+		case RoleSystem:
+			system = append(system, text)
+		case RoleUser:
+			data.Prompt = text
+		}
+		data.Messages = append(data.Messages, chatTemplateMessage{
+			Role:       string(msg.Role),
+			Content:    text,
+			ToolCallID: msg.ToolCallID,
+			ToolCalls:  msg.ToolCalls,
+		})
+	}
+	data.System = strings.Join(system, "\n")
+
+	var sb strings.Builder
+	if err := t.template.Execute(&sb, data); err != nil {
+		return "", &Error{
+			Code:    "invalid_chat_template",
+			Message: fmt.Sprintf("failed to render chat template %s: %v", t.name, err),
+			Type:    "validation_error",
+		}
+	}
+	return sb.String(), nil
+}
+
+// messageText joins the text contents of a message
+func messageText(msg Message) string {
+	var texts []string
+	for _, content := range msg.Content {
+		if text, ok := content.(*TextContent); ok {
+			texts = append(texts, text.GetText())
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// chatTemplatePresets are the built-in templates of common model families. They do not
+// include the BOS token, which the tokenizer of the serving runtime adds.
+var chatTemplatePresets = map[string]struct {
+	text string
+	stop []string
+}{
+	// ChatML (Qwen, Hermes, Yi...), with Hermes-style tool calls
+	"chatml": {
+		text: `{{- if or .System .Tools }}<|im_start|>system
+{{ .System }}
+{{- if .Tools }}
+
+You may call one or more functions to assist with the user query. You are provided with function signatures within <tools></tools> XML tags:
+<tools>
+{{- range .Tools }}
+{{ json .Function }}
+{{- end }}
+</tools>
+
+For each function call, return a json object with function name and arguments within <tool_call></tool_call> XML tags:
+<tool_call>
+{"name": <function-name>, "arguments": <args-json-object>}
+</tool_call>
+{{- end }}<|im_end|>
+{{ end }}
+{{- range .Messages }}
+{{- if eq .Role "user" }}<|im_start|>user
+{{ .Content }}<|im_end|>
+{{ else if eq .Role "assistant" }}<|im_start|>assistant
+{{ .Content }}
+{{- range .ToolCalls }}
+<tool_call>
+{"name": "{{ .Function.Name }}", "arguments": {{ .Function.Arguments }}}
+</tool_call>
+{{- end }}<|im_end|>
+{{ else if eq .Role "tool" }}<|im_start|>user
+<tool_response>
+{{ .Content }}
+</tool_response><|im_end|>
+{{ end }}
+{{- end }}<|im_start|>assistant
+`,
+		stop: []string{"<|im_end|>"},
+	},
+	// Llama 3.x instruct, with JSON tool calls and ipython tool results
+	"llama3": {
+		text: `{{- if or .System .Tools }}<|start_header_id|>system<|end_header_id|>
+
+{{ .System }}
+{{- if .Tools }}
+
+You have access to the following functions. To call a function, respond with JSON for a function call in the format {"name": function name, "parameters": dictionary of argument name and its value}.
+{{- range .Tools }}
+{{ json . }}
+{{- end }}
+{{- end }}<|eot_id|>
+{{- end }}
+{{- range .Messages }}
+{{- if eq .Role "user" }}<|start_header_id|>user<|end_header_id|>
+
+{{ .Content }}<|eot_id|>
+{{- else if eq .Role "assistant" }}<|start_header_id|>assistant<|end_header_id|>
+
+{{ .Content }}
+{{- range .ToolCalls }}{"name": "{{ .Function.Name }}", "parameters": {{ .Function.Arguments }}}{{ end }}<|eot_id|>
+{{- else if eq .Role "tool" }}<|start_header_id|>ipython<|end_header_id|>
+
+{{ .Content }}<|eot_id|>
+{{- end }}
+{{- end }}<|start_header_id|>assistant<|end_header_id|>
+
+`,
+		stop: []string{"<|eot_id|>", "<|eom_id|>"},
+	},
+	// Mistral instruct, with the system prompt prepended to the first user message
+	"mistral": {
+		text: `{{- $system := .System }}
+{{- range .Messages }}
+{{- if eq .Role "user" }}[INST] {{ if $system }}{{ $system }}
+
+{{ $system = "" }}{{ end }}{{ .Content }}[/INST]
+{{- else if eq .Role "assistant" }} {{ .Content }}
+{{- if .ToolCalls }}[TOOL_CALLS] [{{ range $i, $call := .ToolCalls }}{{ if $i }}, {{ end }}{"name": "{{ $call.Function.Name }}", "arguments": {{ $call.Function.Arguments }}}{{ end }}]{{ end }}</s>
+{{- else if eq .Role "tool" }}[TOOL_RESULTS] {"content": {{ json .Content }}}[/TOOL_RESULTS]
+{{- end }}
+{{- end }}`,
+		stop: []string{"</s>", "[INST]"},
+	},
+	// Gemma instruct, which has no system role nor tool messages
+	"gemma": {
+		text: `{{- $system := .System }}
+{{- range .Messages }}
+{{- if or (eq .Role "user") (eq .Role "tool") }}<start_of_turn>user
+{{ if $system }}{{ $system }}
+
+{{ $system = "" }}{{ end }}{{ .Content }}<end_of_turn>
+{{ else if eq .Role "assistant" }}<start_of_turn>model
+{{ .Content }}<end_of_turn>
+{{ end }}
+{{- end }}<start_of_turn>model
+`,
+		stop: []string{"<end_of_turn>"},
+	},
+}
+
+// ChatTemplatePreset returns a built-in chat template by name (see ChatTemplatePresets)
+func ChatTemplatePreset(name string) (*ChatTemplate, bool) {
+	preset, ok := chatTemplatePresets[name]
+	if !ok {
+		return nil, false
+	}
+	tmpl, err := NewChatTemplate(name, preset.text, preset.stop...)
+	if err != nil {
+		return nil, false
+	}
+	return tmpl, true
+}
+
+// ChatTemplatePresets returns the names of the built-in chat templates
+func ChatTemplatePresets() []string {
+	names := make([]string, 0, len(chatTemplatePresets))
+	for name := range chatTemplatePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
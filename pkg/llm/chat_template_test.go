@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatTemplatePresets(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, "You are terse."),
+		NewTextMessage(RoleUser, "Weather in Paris?"),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}},
+		{Role: RoleTool, ToolCallID: "call_1", Content: []MessageContent{NewTextContent("sunny")}},
+	}
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "get_weather", Description: "Get the weather"}}}
+
+	tests := []struct {
+		preset   string
+		contains []string
+		suffix   string
+	}{
+		{
+			preset: "chatml",
+			contains: []string{
+				"<|im_start|>system\nYou are terse.",
+				`{"name":"get_weather","description":"Get the weather","parameters":null}`,
+				"<|im_start|>user\nWeather in Paris?<|im_end|>",
+				"<tool_call>\n{\"name\": \"get_weather\", \"arguments\": {\"city\":\"Paris\"}}\n</tool_call><|im_end|>",
+				"<tool_response>\nsunny\n</tool_response>",
+			},
+			suffix: "<|im_start|>assistant\n",
+		},
+		{
+			preset: "llama3",
+			contains: []string{
+				"<|start_header_id|>system<|end_header_id|>\n\nYou are terse.",
+				"<|start_header_id|>user<|end_header_id|>\n\nWeather in Paris?<|eot_id|>",
+				`{"name": "get_weather", "parameters": {"city":"Paris"}}<|eot_id|>`,
+				"<|start_header_id|>ipython<|end_header_id|>\n\nsunny<|eot_id|>",
+			},
+			suffix: "<|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+		{
+			preset: "mistral",
+			contains: []string{
+				"[INST] You are terse.\n\nWeather in Paris?[/INST]",
+				`[TOOL_CALLS] [{"name": "get_weather", "arguments": {"city":"Paris"}}]</s>`,
+				`[TOOL_RESULTS] {"content": "sunny"}[/TOOL_RESULTS]`,
+			},
+		},
+		{
+			preset: "gemma",
+			contains: []string{
+				"<start_of_turn>user\nYou are terse.\n\nWeather in Paris?<end_of_turn>",
+				"<start_of_turn>user\nsunny<end_of_turn>",
+			},
+			suffix: "<start_of_turn>model\n",
+		},
+	}
+
+	assert.Len(t, ChatTemplatePresets(), len(tests))
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			tmpl, ok := ChatTemplatePreset(tt.preset)
+			require.True(t, ok)
+			assert.NotEmpty(t, tmpl.Stop())
+
+			prompt, err := tmpl.Render(messages, tools)
+			require.NoError(t, err)
+			for _, want := range tt.contains {
+				assert.Contains(t, prompt, want)
+			}
+			assert.True(t, len(prompt) >= len(tt.suffix) && prompt[len(prompt)-len(tt.suffix):] == tt.suffix,
+				"prompt should end with %q, got %q", tt.suffix, prompt)
+		})
+	}
+
+	_, ok := ChatTemplatePreset("unknown")
+	assert.False(t, ok)
+}
+
+func TestChatTemplate_OllamaTemplate(t *testing.T) {
+	// A template as shipped with Ollama models
+	tmpl, err := NewChatTemplate("phi3", `{{ if .System }}<|system|>
+{{ .System }}<|end|>
+{{ end }}{{ if .Prompt }}<|user|>
+{{ .Prompt }}<|end|>
+{{ end }}<|assistant|>
+{{ .Response }}`, "<|end|>")
+	require.NoError(t, err)
+
+	prompt, err := tmpl.Render([]Message{
+		NewTextMessage(RoleSystem, "Be brief."),
+		NewTextMessage(RoleUser, "Hi"),
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<|system|>\nBe brief.<|end|>\n<|user|>\nHi<|end|>\n<|assistant|>\n", prompt)
+	assert.Equal(t, []string{"<|end|>"}, tmpl.Stop())
+
+	_, err = NewChatTemplate("broken", "{{ if .System }")
+	assert.Error(t, err)
+}
@@ -16,12 +16,37 @@ type ClientRemoteInfo struct {
 	Status *ClientRemoteInfoStatus
 }
 
-// ClientRemoteInfoStatus represents the status of a remote client
+// ClientRemoteInfoStatus represents the status of a remote client, computed from the
+// outcome of its calls and health checks (see HealthStats)
 type ClientRemoteInfoStatus struct {
-	Healthy     *bool
+	// SuccessRate is the exponentially smoothed rate of successful calls (0.0 - 1.0)
+	SuccessRate float64
+
+	// LatencyP50 and LatencyP95 are latency percentiles over the most recent calls
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+
+	// Requests and Failures count the calls and health checks recorded so far
+	Requests int64
+	Failures int64
+
+	// LastError is the most recent error, and LastErrorAt the time it happened
+	LastError   *Error
+	LastErrorAt *time.Time
+
+	// LastChecked is the time of the last active health check
 	LastChecked *time.Time
 }
 
+// IsHealthy reports whether the smoothed success rate is at least DefaultHealthySuccessRate.
+// Clients without recorded calls are considered healthy.
+func (s *ClientRemoteInfoStatus) IsHealthy() bool {
+	if s == nil || s.Requests == 0 {
+		return true
+	}
+	return s.SuccessRate >= DefaultHealthySuccessRate
+}
+
 // Client defines the core interface that all LLM clients must implement
 type Client interface {
 	// ChatCompletion performs a chat completion request
@@ -40,10 +65,31 @@ type Client interface {
 	Close() error
 }
 
-// StreamingClient extends Client with tool stream injection capabilities
+// StreamingClient extends Client with tool stream injection capabilities. All the
+// providers implement it; use the StreamChatCompletionWithTools function to support
+// any client.
 type StreamingClient interface {
 	Client // Embed existing interface
 
-	// StreamChatCompletionWithTools performs streaming with real-time tool injection
+	// StreamChatCompletionWithTools performs streaming with real-time tool injection: the
+	// events of the tool streams are merged with those of the model, tagged with their
+	// source ("llm" or "tool:<index>"), and the merged stream is closed when all of them are
 	StreamChatCompletionWithTools(ctx context.Context, req ChatRequest, toolStreams []<-chan StreamEvent) (<-chan StreamEvent, error)
 }
+
+// ClientAs finds the first client of type T in the chain of wrappers of client, which
+// expose the client they wrap with an Unwrap() Client method (e.g. EnhancedClient)
+func ClientAs[T any](client Client) (T, bool) {
+	for client != nil {
+		if target, ok := client.(T); ok {
+			return target, true
+		}
+		wrapper, ok := client.(interface{ Unwrap() Client })
+		if !ok {
+			break
+		}
+		client = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
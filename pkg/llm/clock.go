@@ -0,0 +1,185 @@
+// Clocks for the time-dependent components, with a fake clock for tests
+package llm
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the components caching, expiring or cleaning up things
+// periodically (health checks, rate limits, resource monitors...), so tests can replace
+// it with a FakeClock instead of sleeping
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel receiving the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+
+	// NewTicker returns a ticker sending the time every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker sends the time periodically, like time.Ticker
+type Ticker interface {
+	// C returns the channel of the ticks
+	C() <-chan time.Time
+
+	// Stop stops the ticks
+	Stop()
+}
+
+// SystemClock is the clock of the system, used when no clock is configured
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct{ ticker *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.ticker.C }
+func (t systemTicker) Stop()               { t.ticker.Stop() }
+
+// clockOrSystem returns the clock, or SystemClock when it is nil
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
+
+// FakeClock is a Clock that only moves when told to, for deterministic tests of
+// time-dependent behavior without real sleeps:
+//
+//	clock := llm.NewFakeClock(time.Now())
+//	limiter := llm.NewRateLimiter(10).WithClock(clock)
+//	// ... exhaust the limit ...
+//	clock.Advance(time.Minute) // the limit is reset
+//
+// Advancing the clock fires the timers and tickers that are due, in order, like the
+// system clock would. Like time.Ticker, tickers drop ticks for slow receivers. It is
+// safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a timer (period zero) or a ticker of a FakeClock
+type fakeWaiter struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	at     time.Time
+	period time.Duration
+}
+
+// NewFakeClock creates a fake clock set at the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time once the clock has advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1), at: c.now.Add(d)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.waiters = append(c.waiters, w)
+	return w.ch
+}
+
+// NewTicker returns a ticker sending the time every time the clock advances by d. It
+// panics if d is not positive, like time.NewTicker.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{clock: c, ch: make(chan time.Time, 1), at: c.now.Add(d), period: d}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that are due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// Set moves the clock to a time, firing the timers and tickers that are due when it
+// moves forward
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.Before(c.now) {
+		c.now = t
+		return
+	}
+	c.advanceTo(t)
+}
+
+// Waiters returns the number of pending timers and tickers, so tests can wait for a
+// goroutine to start waiting before advancing the clock
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// advanceTo fires the waiters due until target, earliest first. Must be called with
+// c.mu held.
+func (c *FakeClock) advanceTo(target time.Time) {
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+		if len(c.waiters) == 0 || c.waiters[0].at.After(target) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.at
+		select {
+		case w.ch <- w.at:
+		default: // Slow receiver: the tick is dropped
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = target
+}
+
+// C implements Ticker
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Stop implements Ticker
+func (w *fakeWaiter) Stop() {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
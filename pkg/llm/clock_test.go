@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var clockEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_After(t *testing.T) {
+	clock := NewFakeClock(clockEpoch)
+	assert.Equal(t, clockEpoch, clock.Now())
+
+	ch := clock.After(time.Minute)
+	assert.Equal(t, 1, clock.Waiters())
+	clock.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("Expected the timer not to fire before its time")
+	default:
+	}
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, clockEpoch.Add(time.Minute), <-ch)
+	assert.Equal(t, clockEpoch.Add(time.Hour+59*time.Second), clock.Now())
+	assert.Equal(t, 0, clock.Waiters())
+
+	// Timers in the past fire at once
+	assert.Equal(t, clock.Now(), <-clock.After(0))
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	clock := NewFakeClock(clockEpoch)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, clockEpoch.Add(time.Second), <-ticker.C())
+
+	// Ticks are dropped for slow receivers, like time.Ticker
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, clockEpoch.Add(2*time.Second), <-ticker.C())
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected the ticks to be dropped")
+	default:
+	}
+
+	ticker.Stop()
+	assert.Equal(t, 0, clock.Waiters())
+	clock.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no ticks once stopped")
+	default:
+	}
+
+	assert.Panics(t, func() { clock.NewTicker(0) })
+}
+
+func TestFakeClock_Order(t *testing.T) {
+	clock := NewFakeClock(clockEpoch)
+	late := clock.After(2 * time.Second)
+	early := clock.After(time.Second)
+	ticker := clock.NewTicker(1500 * time.Millisecond)
+
+	clock.Set(clockEpoch.Add(2 * time.Second))
+	assert.Equal(t, clockEpoch.Add(time.Second), <-early)
+	assert.Equal(t, clockEpoch.Add(1500*time.Millisecond), <-ticker.C())
+	assert.Equal(t, clockEpoch.Add(2*time.Second), <-late)
+
+	// Moving backwards fires nothing
+	clock.Set(clockEpoch)
+	assert.Equal(t, clockEpoch, clock.Now())
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no tick when moving backwards")
+	default:
+	}
+}
+
+func TestResourceMonitor_FakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	monitor := NewResourceMonitor(&SecurityConfig{
+		TempDir:         t.TempDir(),
+		TempFileTTL:     time.Hour,
+		CleanupInterval: time.Minute,
+		Clock:           clock,
+	})
+	defer monitor.Shutdown()
+
+	path, err := monitor.NewManagedTempFile([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, monitor.CleanupExpiredFiles())
+
+	// The cleanup worker removes the file once the TTL has passed, without waiting
+	clock.Advance(time.Hour + time.Minute)
+	assert.Eventually(t, func() bool {
+		return monitor.GetResourceStats().TemporaryFiles == 0
+	}, time.Second, time.Millisecond)
+	assert.NoFileExists(t, path)
+	assert.Equal(t, clock.Now(), monitor.GetResourceStats().LastCleanup)
+}
+
+func TestHealthStats_Clock(t *testing.T) {
+	clock := NewFakeClock(clockEpoch)
+	stats := NewHealthStatsWithClock(clock)
+	stats.Record(time.Millisecond, &Error{Code: "server_error", Type: "server_error"})
+
+	status := stats.Status()
+	require.NotNil(t, status.LastErrorAt)
+	assert.Equal(t, clockEpoch, *status.LastErrorAt)
+}
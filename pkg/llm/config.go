@@ -15,6 +15,9 @@ const (
 	DefaultOpenRouterModel = "meta-llama/llama-3.1-8b-instruct:free"
 	DefaultBedrockModel    = "anthropic.claude-3-haiku-20240307-v1:0"
 	DefaultOllamaModel     = "gpt-oss:20b"
+	DefaultFireworksModel  = "accounts/fireworks/models/llama-v3p1-8b-instruct"
+	DefaultNIMModel        = "meta/llama-3.1-8b-instruct"
+	DefaultWatsonxModel    = "ibm/granite-3-8b-instruct"
 )
 
 const DefaultOllamaBaseURL = "http://localhost:11434"
@@ -33,12 +36,29 @@ type ClientConfig struct {
 	Timeout    time.Duration     `json:"timeout,omitempty"`
 	MaxRetries int               `json:"max_retries,omitempty"`
 	Extra      map[string]string `json:"extra,omitempty"` // Provider-specific configs
+
+	// Organization and Project attribute the usage to an organization and a project of
+	// the account, sent in the OpenAI-Organization and OpenAI-Project headers (OpenAI)
+	Organization string `json:"organization,omitempty"`
+	Project      string `json:"project,omitempty"`
+
+	// StreamBuffer configures the buffer of the streams and the policy with slow consumers
+	StreamBuffer StreamBufferConfig `json:"stream_buffer,omitempty"`
+
+	// Clock times the health checks and errors of the client (SystemClock when nil)
+	Clock Clock `json:"-"`
+}
+
+// GetClock returns the clock of the configuration, or SystemClock when it is not set
+func (c ClientConfig) GetClock() Clock {
+	return clockOrSystem(c.Clock)
 }
 
 // ResponseFormat specifies the desired response format for structured outputs
 type ResponseFormat struct {
 	Type       ResponseFormatType `json:"type"`
 	JSONSchema *JSONSchema        `json:"json_schema,omitempty"`
+	Grammar    string             `json:"grammar,omitempty"` // GBNF grammar for ResponseFormatGrammar
 }
 
 // ResponseFormatType defines the type of response format
@@ -51,6 +71,22 @@ const (
 	ResponseFormatJSON ResponseFormatType = "json_object"
 	// ResponseFormatJSONSchema indicates JSON response with strict schema validation
 	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+	// ResponseFormatGrammar constrains the response to a GBNF grammar (Fireworks grammar mode)
+	ResponseFormatGrammar ResponseFormatType = "grammar"
+)
+
+// ResponseFormatPolicy controls how providers honour ResponseFormat when the model
+// has no native structured output support
+type ResponseFormatPolicy string
+
+const (
+	// ResponseFormatPolicyPreferNative uses native structured outputs when available and
+	// falls back to prompt instructions otherwise (default)
+	ResponseFormatPolicyPreferNative ResponseFormatPolicy = "prefer_native"
+	// ResponseFormatPolicyRequireNative fails the request when native structured outputs are unavailable
+	ResponseFormatPolicyRequireNative ResponseFormatPolicy = "require_native"
+	// ResponseFormatPolicyPromptOnly always requests the format through prompt instructions
+	ResponseFormatPolicyPromptOnly ResponseFormatPolicy = "prompt_only"
 )
 
 // JSONSchema represents a JSON Schema specification for structured outputs
@@ -101,10 +137,12 @@ func GetLLMFromEnv() ClientConfig {
 	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
 		fmt.Println("🔑 Using OpenAI API")
 		return ClientConfig{
-			Provider: "openai",
-			Model:    DefaultOpenAIModel, // Cost-effective, high-quality model
-			APIKey:   apiKey,
-			Timeout:  parseTimeoutFromEnv("OPENAI_TIMEOUT", 30*time.Second),
+			Provider:     "openai",
+			Model:        DefaultOpenAIModel, // Cost-effective, high-quality model
+			APIKey:       apiKey,
+			Timeout:      parseTimeoutFromEnv("OPENAI_TIMEOUT", 30*time.Second),
+			Organization: os.Getenv("OPENAI_ORG_ID"),
+			Project:      os.Getenv("OPENAI_PROJECT_ID"),
 		}
 	}
 
@@ -162,7 +200,74 @@ func GetLLMFromEnv() ClientConfig {
 		}
 	}
 
-	// Priority 6: AWS Bedrock (uses AWS credential chain)
+	// Priority 6: Fireworks AI
+	if apiKey := os.Getenv("FIREWORKS_API_KEY"); apiKey != "" {
+		fmt.Println("🔑 Using Fireworks AI")
+		model := DefaultFireworksModel
+
+		// Allow model override via environment variable
+		if customModel := os.Getenv("FIREWORKS_MODEL"); customModel != "" {
+			model = customModel
+		}
+
+		return ClientConfig{
+			Provider: "fireworks",
+			Model:    model,
+			APIKey:   apiKey,
+			Timeout:  parseTimeoutFromEnv("FIREWORKS_TIMEOUT", 30*time.Second),
+		}
+	}
+
+	// Priority 7: NVIDIA NIM (API catalog, or a self-hosted endpoint)
+	if apiKey, baseURL := os.Getenv("NVIDIA_API_KEY"), os.Getenv("NIM_BASE_URL"); apiKey != "" || baseURL != "" {
+		fmt.Println("🔑 Using NVIDIA NIM")
+		model := DefaultNIMModel
+
+		// Allow model override via environment variable
+		if customModel := os.Getenv("NIM_MODEL"); customModel != "" {
+			model = customModel
+		}
+
+		return ClientConfig{
+			Provider: "nim",
+			Model:    model,
+			APIKey:   apiKey,
+			BaseURL:  baseURL,
+			Timeout:  parseTimeoutFromEnv("NIM_TIMEOUT", 60*time.Second),
+		}
+	}
+
+	// Priority 8: IBM watsonx.ai (needs a project or a deployment space)
+	if apiKey := os.Getenv("WATSONX_API_KEY"); apiKey != "" {
+		fmt.Println("🔑 Using IBM watsonx.ai")
+		model := DefaultWatsonxModel
+
+		// Allow model override via environment variable
+		if customModel := os.Getenv("WATSONX_MODEL"); customModel != "" {
+			model = customModel
+		}
+
+		config := ClientConfig{
+			Provider: "watsonx",
+			Model:    model,
+			APIKey:   apiKey,
+			BaseURL:  os.Getenv("WATSONX_URL"),
+			Timeout:  parseTimeoutFromEnv("WATSONX_TIMEOUT", 60*time.Second),
+			Extra:    make(map[string]string),
+		}
+		for env, key := range map[string]string{
+			"WATSONX_PROJECT_ID": "project_id",
+			"WATSONX_SPACE_ID":   "space_id",
+			"WATSONX_REGION":     "region",
+		} {
+			if value := os.Getenv(env); value != "" {
+				config.Extra[key] = value
+			}
+		}
+		return config
+	}
+
+	// Priority 9: AWS Bedrock (uses AWS credential chain)
 	if os.Getenv("AWS_ACCESS_KEY_ID") != "" || os.Getenv("AWS_PROFILE") != "" || os.Getenv("AWS_BEDROCK_MODEL") != "" || os.Getenv("AWS_BEDROCK_TOKEN") != "" {
 		fmt.Println("🔑 Using AWS Bedrock")
 		model := DefaultBedrockModel
@@ -219,7 +324,7 @@ func GetLLMFromEnv() ClientConfig {
 
 	// Default: Ollama (local, free)
 	fmt.Printf("🔑 Using Ollama (local) at %s\n", baseURL)
-	fmt.Println("💡 To use cloud providers: set OPENAI_API_KEY, GEMINI_API_KEY, DEEPSEEK_API_KEY, OPENROUTER_API_KEY, or configure AWS credentials")
+	fmt.Println("💡 To use cloud providers: set OPENAI_API_KEY, GEMINI_API_KEY, DEEPSEEK_API_KEY, OPENROUTER_API_KEY, FIREWORKS_API_KEY, NVIDIA_API_KEY, WATSONX_API_KEY, or configure AWS credentials")
 
 	return ClientConfig{
 		Provider: "ollama",
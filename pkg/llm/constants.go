@@ -1,10 +0,0 @@
-// Constants and finish reasons
-package llm
-
-// Constants for common finish reasons
-const (
-	FinishReasonStop          = "stop"
-	FinishReasonLength        = "length"
-	FinishReasonToolCalls     = "tool_calls"
-	FinishReasonContentFilter = "content_filter"
-)
@@ -0,0 +1,112 @@
+// MIME type detection and normalization of binary content
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// mimeTypeAliases maps non-standard MIME type variants to their canonical type
+var mimeTypeAliases = map[string]string{
+	"image/jpg":                   "image/jpeg",
+	"image/pjpeg":                 "image/jpeg",
+	"image/x-png":                 "image/png",
+	"image/x-ms-bmp":              "image/bmp",
+	"image/x-bmp":                 "image/bmp",
+	"image/svg":                   "image/svg+xml",
+	"text/x-csv":                  "text/csv",
+	"application/csv":             "text/csv",
+	"text/comma-separated-values": "text/csv",
+	"text/x-markdown":             "text/markdown",
+	"text/json":                   "application/json",
+	"application/x-json":          "application/json",
+	"application/x-pdf":           "application/pdf",
+	"text/xml":                    "application/xml",
+}
+
+// extensionMIMETypes types the extensions of common documents, which are missing from the
+// system MIME tables on some platforms
+var extensionMIMETypes = map[string]string{
+	".csv":  "text/csv",
+	".md":   "text/markdown",
+	".txt":  "text/plain",
+	".json": "application/json",
+	".pdf":  "application/pdf",
+	".svg":  "image/svg+xml",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// NormalizeMIMEType returns the canonical form of a MIME type: lower case, without
+// parameters, and with common variants replaced ("image/jpg" becomes "image/jpeg")
+func NormalizeMIMEType(mimeType string) string {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if canonical, ok := mimeTypeAliases[mediaType]; ok {
+		return canonical
+	}
+	return mediaType
+}
+
+// DetectContent returns the content for data: an ImageContent for images and a
+// FileContent otherwise. The MIME type is detected from the magic bytes of the data,
+// falling back to the extension of filename when they are not conclusive (plain text,
+// XML and ZIP-based documents), and normalized, so the declared type always matches
+// the signature checked by the SecurityValidator. Files without a filename are named
+// after their type ("file.pdf").
+func DetectContent(data []byte, filename string) (MessageContent, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cannot detect the type of empty content")
+	}
+
+	mimeType := sniffMIME(data, filename)
+	if supportedImageMimeTypes[mimeType] || mimeType == "image/svg+xml" || mimeType == "image/bmp" || mimeType == "image/tiff" {
+		img := NewImageContentFromBytes(data, mimeType)
+		img.Filename = filename
+		return img, nil
+	}
+
+	if filename == "" {
+		filename = "file"
+		if extensions, err := mime.ExtensionsByType(mimeType); err == nil && len(extensions) > 0 {
+			filename += extensions[0]
+		}
+	}
+	return NewFileContentFromBytes(data, filename, mimeType), nil
+}
+
+// sniffMIME detects the normalized MIME type of data from its first bytes, falling back
+// to the extension of filename when the bytes are not conclusive
+func sniffMIME(data []byte, filename string) string {
+	detected := NormalizeMIMEType(http.DetectContentType(data))
+	if (detected == "application/xml" || detected == "text/plain") && bytes.Contains(data[:min(len(data), 1024)], []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	switch detected {
+	case "application/octet-stream", "text/plain", "application/xml", "application/zip":
+	default:
+		return detected
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	byExtension, ok := extensionMIMETypes[ext]
+	if !ok {
+		byExtension = NormalizeMIMEType(mime.TypeByExtension(ext))
+	}
+	switch {
+	case byExtension == "":
+		return detected
+	case detected == "application/zip" && !strings.Contains(byExtension, "openxmlformats"):
+		// Only ZIP-based documents may be typed from their extension
+		return detected
+	case detected == "application/octet-stream" && strings.HasPrefix(byExtension, "text/"):
+		// Binary data is never typed as text
+		return detected
+	}
+	return byExtension
+}
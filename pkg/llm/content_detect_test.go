@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"testing"
+)
+
+func TestNormalizeMIMEType(t *testing.T) {
+	tests := map[string]string{
+		"image/jpg":                 "image/jpeg",
+		"IMAGE/JPEG":                "image/jpeg",
+		"image/x-png":               "image/png",
+		"text/plain; charset=utf-8": "text/plain",
+		"application/csv":           "text/csv",
+		"text/json":                 "application/json",
+		"application/pdf":           "application/pdf",
+	}
+	for input, want := range tests {
+		if got := NormalizeMIMEType(input); got != want {
+			t.Errorf("NormalizeMIMEType(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestDetectContent(t *testing.T) {
+	jpeg := append([]byte{0xff, 0xd8, 0xff, 0xe0}, make([]byte, 16)...)
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		image    bool
+		mimeType string
+		wantName string
+	}{
+		{"png", pngHeader, "", true, "image/png", ""},
+		{"jpeg named jpg", jpeg, "photo.jpg", true, "image/jpeg", "photo.jpg"},
+		{"magic bytes win over the extension", jpeg, "photo.png", true, "image/jpeg", "photo.png"},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", true, "image/svg+xml", ""},
+		{"pdf", []byte("%PDF-1.7\n..."), "", false, "application/pdf", "file.pdf"},
+		{"csv", []byte("a,b\n1,2\n"), "data.csv", false, "text/csv", "data.csv"},
+		{"json", []byte(`{"a": 1}`), "data.json", false, "application/json", "data.json"},
+		{"text", []byte("just some notes"), "notes", false, "text/plain", "notes"},
+		{"docx", []byte("PK\x03\x04rest of the archive"), "report.docx", false,
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "report.docx"},
+		{"binary named txt", []byte{0x00, 0x01, 0x02, 0x03}, "fake.txt", false, "application/octet-stream", "fake.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := DetectContent(tt.data, tt.filename)
+			if err != nil {
+				t.Fatalf("DetectContent failed: %v", err)
+			}
+			switch c := content.(type) {
+			case *ImageContent:
+				if !tt.image || c.MimeType != tt.mimeType || c.Filename != tt.wantName {
+					t.Errorf("Unexpected image %s %q", c.MimeType, c.Filename)
+				}
+			case *FileContent:
+				if tt.image || c.MimeType != tt.mimeType || c.Filename != tt.wantName {
+					t.Errorf("Unexpected file %s %q", c.MimeType, c.Filename)
+				}
+			default:
+				t.Fatalf("Unexpected content %T", content)
+			}
+		})
+	}
+
+	if _, err := DetectContent(nil, "empty.txt"); err == nil {
+		t.Error("Expected an error for empty content")
+	}
+}
+
+func TestDetectContentPassesSecurityValidation(t *testing.T) {
+	validator := NewSecurityValidator(nil)
+	jpeg := append([]byte{0xff, 0xd8, 0xff, 0xe0}, make([]byte, 16)...)
+
+	// The declared type of the caller is wrong; the detected one is not
+	content, err := DetectContent(jpeg, "photo.png")
+	if err != nil {
+		t.Fatalf("DetectContent failed: %v", err)
+	}
+	if err := validator.ValidateContentSecurity(content); err != nil {
+		t.Errorf("Expected the detected content to pass validation, got %v", err)
+	}
+	if err := validator.ValidateContentSecurity(NewImageContentFromBytes(jpeg, "image/png")); err == nil {
+		t.Error("Expected the wrongly declared content to fail validation")
+	}
+}
@@ -0,0 +1,81 @@
+// Content constructors reading from io.Reader sources
+package llm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// DefaultMaxReaderImageSize is the size limit of NewImageContentFromReader when none
+	// is given
+	DefaultMaxReaderImageSize int64 = 10 * 1024 * 1024
+
+	// DefaultMaxReaderFileSize is the size limit of NewFileContentFromReader when none is
+	// given
+	DefaultMaxReaderFileSize int64 = 50 * 1024 * 1024
+)
+
+// NewImageContentFromReader creates an ImageContent with the data read from r, such as a
+// file handle or an HTTP response body, failing with a "content_too_large" error when it
+// exceeds maxSize bytes (DefaultMaxReaderImageSize when zero or negative). The MIME type
+// is sniffed from the data when mimeType is empty. The reader is not closed.
+func NewImageContentFromReader(r io.Reader, mimeType string, maxSize int64) (*ImageContent, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxReaderImageSize
+	}
+	data, err := readContent(r, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "" {
+		mimeType = sniffMIME(data, "")
+		if !strings.HasPrefix(mimeType, "image/") {
+			return nil, &Error{
+				Code:    "invalid_content",
+				Message: fmt.Sprintf("the data is not an image (detected %s)", mimeType),
+				Type:    "validation_error",
+			}
+		}
+	}
+	return NewImageContentFromBytes(data, mimeType), nil
+}
+
+// NewFileContentFromReader creates a FileContent with the data read from r, failing with a
+// "content_too_large" error when it exceeds maxSize bytes (DefaultMaxReaderFileSize when
+// zero or negative). The MIME type is sniffed from the data and the filename extension when
+// mimeType is empty. The reader is not closed.
+func NewFileContentFromReader(r io.Reader, filename, mimeType string, maxSize int64) (*FileContent, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxReaderFileSize
+	}
+	data, err := readContent(r, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "" {
+		mimeType = sniffMIME(data, filename)
+	}
+	return NewFileContentFromBytes(data, filename, mimeType), nil
+}
+
+// readContent reads up to maxSize bytes, failing when there are more
+func readContent(r io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, &Error{
+			Code:    "read_failed",
+			Message: fmt.Sprintf("failed to read content: %v", err),
+			Type:    "validation_error",
+		}
+	}
+	if int64(len(data)) > maxSize {
+		return nil, &Error{
+			Code:    "content_too_large",
+			Message: fmt.Sprintf("content exceeds limit %d", maxSize),
+			Type:    "validation_error",
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pngHeader is the signature and start of the IHDR chunk of a PNG image
+var pngHeader = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d, 'I', 'H', 'D', 'R'}
+
+func TestNewImageContentFromReader(t *testing.T) {
+	img, err := NewImageContentFromReader(bytes.NewReader(pngHeader), "", 0)
+	if err != nil {
+		t.Fatalf("NewImageContentFromReader failed: %v", err)
+	}
+	if img.MimeType != "image/png" || !bytes.Equal(img.Data, pngHeader) {
+		t.Errorf("Unexpected image %s with %d bytes", img.MimeType, len(img.Data))
+	}
+
+	// A declared type is kept
+	img, err = NewImageContentFromReader(bytes.NewReader(pngHeader), "image/webp", 0)
+	if err != nil || img.MimeType != "image/webp" {
+		t.Errorf("Expected the declared type, got %v, %v", img, err)
+	}
+
+	_, err = NewImageContentFromReader(strings.NewReader("just some text"), "", 0)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_content" {
+		t.Errorf("Expected invalid_content for text, got %v", err)
+	}
+
+	_, err = NewImageContentFromReader(bytes.NewReader(pngHeader), "", 8)
+	if !errors.As(err, &llmErr) || llmErr.Code != "content_too_large" {
+		t.Errorf("Expected content_too_large, got %v", err)
+	}
+}
+
+func TestNewFileContentFromReader(t *testing.T) {
+	file, err := NewFileContentFromReader(strings.NewReader("%PDF-1.7\n..."), "report.pdf", "", 0)
+	if err != nil {
+		t.Fatalf("NewFileContentFromReader failed: %v", err)
+	}
+	if file.MimeType != "application/pdf" || file.FileSize != 12 || file.Filename != "report.pdf" {
+		t.Errorf("Unexpected file %+v", file)
+	}
+
+	// Text is typed from the extension
+	file, err = NewFileContentFromReader(strings.NewReader("a,b\n1,2\n"), "data.csv", "", 0)
+	if err != nil || file.MimeType != "text/csv" {
+		t.Errorf("Expected text/csv, got %v, %v", file, err)
+	}
+	file, err = NewFileContentFromReader(strings.NewReader("notes"), "notes", "", 0)
+	if err != nil || file.MimeType != "text/plain" {
+		t.Errorf("Expected text/plain, got %v, %v", file, err)
+	}
+
+	_, err = NewFileContentFromReader(strings.NewReader(strings.Repeat("x", 100)), "big.txt", "", 99)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "content_too_large" {
+		t.Errorf("Expected content_too_large, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Automatic continuation of responses truncated by the output token limit
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// ContinueOnLengthConfig configures a ContinueOnLengthClient
+type ContinueOnLengthConfig struct {
+	// MaxContinuations limits the follow-up requests made for a single request
+	MaxContinuations int `json:"max_continuations"`
+
+	// Prompt is the user message asking the model to continue
+	Prompt string `json:"prompt"`
+
+	// MaxSeamOverlap is the longest repeated text removed where a continuation starts
+	// with the end of the previous output
+	MaxSeamOverlap int `json:"max_seam_overlap"`
+
+	// MinSeamOverlap is the shortest overlap removed, so short coincidental repetitions
+	// (e.g. a single letter) are kept
+	MinSeamOverlap int `json:"min_seam_overlap"`
+}
+
+// DefaultContinueOnLengthConfig returns the default continuation configuration
+func DefaultContinueOnLengthConfig() *ContinueOnLengthConfig {
+	return &ContinueOnLengthConfig{
+		MaxContinuations: 3,
+		Prompt:           "Continue exactly where you stopped, without repeating any of the previous text.",
+		MaxSeamOverlap:   200,
+		MinSeamOverlap:   8,
+	}
+}
+
+// ContinueOnLengthClient wraps a client so text responses cut off by the output token
+// limit (finish reason "length") are completed with follow-up requests and returned as
+// a single response, or a single stream. Responses with tool calls are not continued.
+type ContinueOnLengthClient struct {
+	client Client
+	config *ContinueOnLengthConfig
+}
+
+// NewContinueOnLengthClient wraps a client, filling unset config fields with defaults
+func NewContinueOnLengthClient(client Client, config *ContinueOnLengthConfig) *ContinueOnLengthClient {
+	defaults := DefaultContinueOnLengthConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.MaxContinuations <= 0 {
+		config.MaxContinuations = defaults.MaxContinuations
+	}
+	if config.Prompt == "" {
+		config.Prompt = defaults.Prompt
+	}
+	if config.MaxSeamOverlap <= 0 {
+		config.MaxSeamOverlap = defaults.MaxSeamOverlap
+	}
+	if config.MinSeamOverlap <= 0 {
+		config.MinSeamOverlap = defaults.MinSeamOverlap
+	}
+	return &ContinueOnLengthClient{client: client, config: config}
+}
+
+// ChatCompletion performs the request, continuing truncated text responses
+func (c *ContinueOnLengthClient) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := c.client.ChatCompletion(ctx, req)
+	if err != nil || !continuable(resp) {
+		return resp, err
+	}
+
+	text := resp.Choices[0].Message.GetText()
+	usage := resp.Usage
+	finishReason, rawFinishReason := resp.Choices[0].FinishReason, resp.Choices[0].RawFinishReason
+	for i := 0; i < c.config.MaxContinuations && finishReason == FinishReasonLength; i++ {
+		next, err := c.client.ChatCompletion(ctx, c.continuationRequest(req, text))
+		if err != nil {
+			return nil, err
+		}
+		if len(next.Choices) == 0 || next.Choices[0].Message.HasToolCalls() {
+			break
+		}
+		text += trimSeam(text, next.Choices[0].Message.GetText(), c.config.MaxSeamOverlap, c.config.MinSeamOverlap)
+		usage = usage.Add(next.Usage)
+		finishReason, rawFinishReason = next.Choices[0].FinishReason, next.Choices[0].RawFinishReason
+	}
+
+	stitched := *resp
+	stitched.Choices = append([]Choice(nil), resp.Choices...)
+	stitched.Choices[0].Message.Content = []MessageContent{NewTextContent(text)}
+	stitched.Choices[0].FinishReason = finishReason
+	stitched.Choices[0].RawFinishReason = rawFinishReason
+	stitched.Usage = usage
+	return &stitched, nil
+}
+
+// StreamChatCompletion streams the response, transparently streaming continuations
+// after a truncated text stream. A single done event is sent at the end.
+func (c *ContinueOnLengthClient) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		send := func(event StreamEvent) bool {
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var text strings.Builder
+		for i := 0; ; i++ {
+			done, ok := c.forwardStream(stream, &text, i > 0, send)
+			if !ok {
+				return
+			}
+			if done == nil {
+				// The stream ended without a done event (or with tool calls)
+				return
+			}
+			if done.Choice.FinishReason != FinishReasonLength || i == c.config.MaxContinuations {
+				send(*done)
+				return
+			}
+
+			stream, err = c.client.StreamChatCompletion(ctx, c.continuationRequest(req, text.String()))
+			if err != nil {
+				llmErr, isLLMErr := err.(*Error)
+				if !isLLMErr {
+					llmErr = &Error{Code: "continuation_failed", Message: err.Error(), Type: "stream_error"}
+				}
+				send(NewErrorEvent(llmErr))
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// forwardStream forwards the events of one stream, accumulating its text. The done
+// event is returned instead of forwarded, unless the stream has tool calls (which are
+// never continued). For continuations the start of the text is held back until the
+// repeated seam can be removed. ok is false when the consumer went away.
+func (c *ContinueOnLengthClient) forwardStream(stream <-chan StreamEvent, text *strings.Builder, continuation bool, send func(StreamEvent) bool) (*StreamEvent, bool) {
+	var pending strings.Builder // continuation text held back for seam removal
+	holding := continuation
+	previous := text.String()
+	toolCalls := false
+
+	release := func() bool {
+		holding = false
+		trimmed := trimSeam(previous, pending.String(), c.config.MaxSeamOverlap, c.config.MinSeamOverlap)
+		if trimmed == "" {
+			return true
+		}
+		text.WriteString(trimmed)
+		return send(NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(trimmed)}}))
+	}
+
+	for event := range stream {
+		switch {
+		case event.IsDelta():
+			toolCalls = toolCalls || len(event.Choice.Delta.ToolCalls) > 0
+			chunk := deltaText(event.Choice.Delta)
+			if holding && !toolCalls {
+				pending.WriteString(chunk)
+				if pending.Len() > c.config.MaxSeamOverlap && !release() {
+					return nil, false
+				}
+				continue
+			}
+			text.WriteString(chunk)
+		case event.IsDone():
+			if holding && !release() {
+				return nil, false
+			}
+			if toolCalls {
+				return nil, send(event)
+			}
+			return &event, true
+		}
+		if !send(event) {
+			return nil, false
+		}
+	}
+	if holding && !release() {
+		return nil, false
+	}
+	return nil, true
+}
+
+// continuationRequest creates the follow-up request for the text produced so far
+func (c *ContinueOnLengthClient) continuationRequest(req ChatRequest, text string) ChatRequest {
+	follow := req
+	follow.Messages = append(append(make([]Message, 0, len(req.Messages)+2), req.Messages...),
+		NewTextMessage(RoleAssistant, text),
+		NewTextMessage(RoleUser, c.config.Prompt),
+	)
+	return follow
+}
+
+// GetRemote returns information about the wrapped client
+func (c *ContinueOnLengthClient) GetRemote() ClientRemoteInfo {
+	return c.client.GetRemote()
+}
+
+// GetModelInfo returns information about the wrapped model
+func (c *ContinueOnLengthClient) GetModelInfo() ModelInfo {
+	return c.client.GetModelInfo()
+}
+
+// Close closes the wrapped client
+func (c *ContinueOnLengthClient) Close() error {
+	return c.client.Close()
+}
+
+// continuable reports whether a response is a truncated text response
+func continuable(resp *ChatResponse) bool {
+	return resp != nil && len(resp.Choices) > 0 &&
+		resp.Choices[0].FinishReason == FinishReasonLength &&
+		!resp.Choices[0].Message.HasToolCalls()
+}
+
+// deltaText returns the text of a delta
+func deltaText(delta *MessageDelta) string {
+	var text strings.Builder
+	for _, content := range delta.Content {
+		if tc, ok := content.(*TextContent); ok {
+			text.WriteString(tc.GetText())
+		}
+	}
+	return text.String()
+}
+
+// trimSeam removes from the start of next the longest text (between minOverlap and
+// maxOverlap bytes) that repeats the end of previous
+func trimSeam(previous, next string, maxOverlap, minOverlap int) string {
+	longest := min(maxOverlap, len(previous), len(next))
+	for n := longest; n >= minOverlap; n-- {
+		if strings.HasSuffix(previous, next[:n]) {
+			return next[n:]
+		}
+	}
+	return next
+}
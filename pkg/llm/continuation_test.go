@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// truncatedResponse creates a text response with the given finish reason
+func truncatedResponse(text string, finishReason FinishReason) *ChatResponse {
+	return &ChatResponse{
+		ID:      "resp",
+		Choices: []Choice{{Message: NewTextMessage(RoleAssistant, text), FinishReason: finishReason}},
+		Usage:   Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+func TestTrimSeam(t *testing.T) {
+	tests := []struct {
+		previous, next, want string
+	}{
+		{"The quick brown fox jumps", "brown fox jumps over the lazy dog", " over the lazy dog"},
+		{"The quick brown fox", " jumps over", " jumps over"},
+		{"ends with a", "a new sentence", "a new sentence"}, // too short to be a repetition
+		{"", "start", "start"},
+	}
+	for _, tt := range tests {
+		if got := trimSeam(tt.previous, tt.next, 200, 8); got != tt.want {
+			t.Errorf("trimSeam(%q, %q) = %q, want %q", tt.previous, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestContinueOnLengthClient_ChatCompletion(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	mockClient.responses = []*ChatResponse{
+		truncatedResponse("Once upon a time, there was", FinishReasonLength),
+		truncatedResponse("upon a time, there was a dragon", FinishReasonLength),
+		truncatedResponse(" who slept.", FinishReasonStop),
+	}
+	client := NewContinueOnLengthClient(mockClient, nil)
+
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Tell me a story")}}
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	if got := resp.Choices[0].Message.GetText(); got != "Once upon a time, there was a dragon who slept." {
+		t.Errorf("Unexpected stitched text: %q", got)
+	}
+	if resp.Choices[0].FinishReason != FinishReasonStop || resp.Usage.TotalTokens != 45 {
+		t.Errorf("Unexpected finish reason %q or usage %+v", resp.Choices[0].FinishReason, resp.Usage)
+	}
+
+	calls := mockClient.GetCallLog()
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(calls))
+	}
+	follow := calls[2].Messages
+	if len(follow) != 3 || follow[1].Role != RoleAssistant || follow[1].GetText() != "Once upon a time, there was a dragon" {
+		t.Errorf("Unexpected continuation request: %q", conversationTexts(follow))
+	}
+	if len(req.Messages) != 1 {
+		t.Error("Expected the original request to be untouched")
+	}
+}
+
+func TestContinueOnLengthClient_MaxContinuations(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	for i := 0; i < 5; i++ {
+		mockClient.responses = append(mockClient.responses, truncatedResponse("part ", FinishReasonLength))
+	}
+	client := NewContinueOnLengthClient(mockClient, &ContinueOnLengthConfig{MaxContinuations: 2})
+
+	resp, err := client.ChatCompletion(context.Background(), ChatRequest{})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if len(mockClient.GetCallLog()) != 3 || resp.Choices[0].FinishReason != FinishReasonLength {
+		t.Errorf("Expected 3 requests ending in length, got %d requests and %q",
+			len(mockClient.GetCallLog()), resp.Choices[0].FinishReason)
+	}
+	if got := resp.Choices[0].Message.GetText(); got != "part part part " {
+		t.Errorf("Unexpected text: %q", got)
+	}
+}
+
+// continuationStreamClient returns a different stream for every request
+type continuationStreamClient struct {
+	testMockClient
+	streams [][]StreamEvent
+}
+
+func (c *continuationStreamClient) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	c.callLog = append(c.callLog, req)
+	events := c.streams[0]
+	c.streams = c.streams[1:]
+	ch := make(chan StreamEvent, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestContinueOnLengthClient_Stream(t *testing.T) {
+	delta := func(text string) StreamEvent {
+		return NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(text)}})
+	}
+	mockClient := &continuationStreamClient{
+		testMockClient: *NewMockClient("test-model", "test-provider"),
+		streams: [][]StreamEvent{
+			{delta("Once upon "), delta("a time, there was"), NewDoneEvent(0, FinishReasonLength)},
+			{delta("a time, there "), delta("was a dragon"), delta(" who slept."), NewDoneEvent(0, FinishReasonStop)},
+		},
+	}
+	client := NewContinueOnLengthClient(mockClient, &ContinueOnLengthConfig{MaxSeamOverlap: 20})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatRequest{})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var text strings.Builder
+	var done []StreamEvent
+	for event := range stream {
+		switch {
+		case event.IsDelta():
+			text.WriteString(deltaText(event.Choice.Delta))
+		case event.IsDone():
+			done = append(done, event)
+		}
+	}
+
+	if got := text.String(); got != "Once upon a time, there was a dragon who slept." {
+		t.Errorf("Unexpected streamed text: %q", got)
+	}
+	if len(done) != 1 || done[0].Choice.FinishReason != FinishReasonStop {
+		t.Errorf("Expected a single final done event, got %d", len(done))
+	}
+	if len(mockClient.callLog) != 2 || mockClient.callLog[1].Messages[0].GetText() != "Once upon a time, there was" {
+		t.Errorf("Unexpected continuation requests: %+v", mockClient.callLog)
+	}
+}
@@ -0,0 +1,252 @@
+// Conversation history with branching and forking
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultBranch is the name of the branch a new conversation starts on
+const DefaultBranch = "main"
+
+// conversationEntry is a message of a branch. Entries copied by Fork keep their id,
+// so branches can be compared without comparing message contents.
+type conversationEntry struct {
+	id      uint64
+	message Message
+}
+
+// Conversation is a tree-structured message history. Messages are appended to the
+// current branch; Fork creates a new branch from any message index of the current one,
+// so alternative continuations can be explored, compared with Diff and combined with
+// Merge. Messages are shared between branches and should not be modified after being
+// appended. It is safe for concurrent use.
+type Conversation struct {
+	mu       sync.RWMutex
+	branches map[string][]conversationEntry
+	current  string
+	nextID   uint64
+}
+
+// ConversationDiff describes how two branches diverge
+type ConversationDiff struct {
+	// Common is the number of leading messages shared by both branches
+	Common int `json:"common"`
+
+	// OnlyA and OnlyB are the messages of each branch after the shared ones
+	OnlyA []Message `json:"only_a,omitempty"`
+	OnlyB []Message `json:"only_b,omitempty"`
+}
+
+// NewConversation creates a conversation on DefaultBranch with the given messages
+func NewConversation(messages ...Message) *Conversation {
+	c := &Conversation{
+		branches: map[string][]conversationEntry{DefaultBranch: nil},
+		current:  DefaultBranch,
+	}
+	c.Append(messages...)
+	return c
+}
+
+// Append adds messages to the current branch
+func (c *Conversation) Append(messages ...Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, msg := range messages {
+		c.nextID++
+		c.branches[c.current] = append(c.branches[c.current], conversationEntry{id: c.nextID, message: msg})
+	}
+}
+
+// Messages returns the messages of the current branch, ready to be sent in a ChatRequest
+func (c *Conversation) Messages() []Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return entryMessages(c.branches[c.current])
+}
+
+// BranchMessages returns the messages of the named branch
+func (c *Conversation) BranchMessages(name string) ([]Message, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries, err := c.branch(name)
+	if err != nil {
+		return nil, err
+	}
+	return entryMessages(entries), nil
+}
+
+// Len returns the number of messages in the current branch
+func (c *Conversation) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.branches[c.current])
+}
+
+// CurrentBranch returns the name of the current branch
+func (c *Conversation) CurrentBranch() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+// Branches returns the names of all branches, sorted
+func (c *Conversation) Branches() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.branches))
+	for name := range c.branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fork creates a branch holding the first index messages of the current branch and
+// switches to it. Use index Len() to fork the whole branch, or a smaller index to
+// explore an alternative continuation from an earlier message.
+func (c *Conversation) Fork(name string, index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == "" {
+		return &Error{Code: "invalid_branch", Message: "branch name is required", Type: "validation_error"}
+	}
+	if _, exists := c.branches[name]; exists {
+		return &Error{
+			Code:    "branch_exists",
+			Message: fmt.Sprintf("branch %q already exists", name),
+			Type:    "validation_error",
+		}
+	}
+	entries := c.branches[c.current]
+	if index < 0 || index > len(entries) {
+		return &Error{
+			Code:    "invalid_index",
+			Message: fmt.Sprintf("message index %d out of range [0, %d]", index, len(entries)),
+			Type:    "validation_error",
+		}
+	}
+
+	c.branches[name] = append([]conversationEntry(nil), entries[:index]...)
+	c.current = name
+	return nil
+}
+
+// Switch makes the named branch the current one
+func (c *Conversation) Switch(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.branch(name); err != nil {
+		return err
+	}
+	c.current = name
+	return nil
+}
+
+// DeleteBranch removes a branch. The current branch cannot be deleted.
+func (c *Conversation) DeleteBranch(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.branch(name); err != nil {
+		return err
+	}
+	if name == c.current {
+		return &Error{
+			Code:    "invalid_branch",
+			Message: fmt.Sprintf("cannot delete the current branch %q", name),
+			Type:    "validation_error",
+		}
+	}
+	delete(c.branches, name)
+	return nil
+}
+
+// Diff compares two branches, returning the number of shared leading messages and the
+// messages where each branch diverges
+func (c *Conversation) Diff(a, b string) (*ConversationDiff, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entriesA, err := c.branch(a)
+	if err != nil {
+		return nil, err
+	}
+	entriesB, err := c.branch(b)
+	if err != nil {
+		return nil, err
+	}
+
+	common := commonPrefix(entriesA, entriesB)
+	return &ConversationDiff{
+		Common: common,
+		OnlyA:  entryMessages(entriesA[common:]),
+		OnlyB:  entryMessages(entriesB[common:]),
+	}, nil
+}
+
+// Merge appends messages of another branch to the current one. Indexes select the
+// messages (by their position in the source branch) to merge, in the given order; when
+// none are given, all messages after those shared with the current branch are merged.
+func (c *Conversation) Merge(from string, indexes ...int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	source, err := c.branch(from)
+	if err != nil {
+		return err
+	}
+
+	var selected []conversationEntry
+	if len(indexes) == 0 {
+		selected = source[commonPrefix(c.branches[c.current], source):]
+	}
+	for _, i := range indexes {
+		if i < 0 || i >= len(source) {
+			return &Error{
+				Code:    "invalid_index",
+				Message: fmt.Sprintf("message index %d out of range for branch %q with %d messages", i, from, len(source)),
+				Type:    "validation_error",
+			}
+		}
+		selected = append(selected, source[i])
+	}
+
+	c.branches[c.current] = append(c.branches[c.current], selected...)
+	return nil
+}
+
+// branch returns the entries of a branch. Callers must hold the lock.
+func (c *Conversation) branch(name string) ([]conversationEntry, error) {
+	entries, ok := c.branches[name]
+	if !ok {
+		return nil, &Error{
+			Code:    "branch_not_found",
+			Message: fmt.Sprintf("branch %q not found", name),
+			Type:    "validation_error",
+		}
+	}
+	return entries, nil
+}
+
+// commonPrefix returns the number of leading entries shared by two branches
+func commonPrefix(a, b []conversationEntry) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n].id == b[n].id {
+		n++
+	}
+	return n
+}
+
+// entryMessages returns the messages of the entries
+func entryMessages(entries []conversationEntry) []Message {
+	if len(entries) == 0 {
+		return nil
+	}
+	messages := make([]Message, len(entries))
+	for i, entry := range entries {
+		messages[i] = entry.message
+	}
+	return messages
+}
@@ -0,0 +1,256 @@
+// Encrypted at-rest storage of conversations
+package llm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+// encryptedConversationVersion is the version of the encrypted conversation format
+const encryptedConversationVersion = 1
+
+// conversationDataKeySize is the size of the data keys (AES-256)
+const conversationDataKeySize = 32
+
+// ConversationKey is a key encryption key of an EncryptedConversationStore
+type ConversationKey struct {
+	// ID identifies the key in the saved conversations, so it can be found after a
+	// rotation. It is not secret.
+	ID string
+
+	// Key is the AES key: 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+	Key []byte
+}
+
+// EncryptedConversationConfig configures an EncryptedConversationStore
+type EncryptedConversationConfig struct {
+	// Keys are the key encryption keys. The first one (the primary key) encrypts the
+	// data keys of the conversations saved; the others only decrypt those of the
+	// conversations saved before a rotation, until RotateKeys re-encrypts them.
+	Keys []ConversationKey
+}
+
+// EncryptedConversationStore is a ConversationStore encrypting conversations at rest
+// with AES-GCM, as chat histories often contain personal data. Every conversation is
+// encrypted with its own random data key, stored encrypted with the primary key of the
+// configuration (envelope encryption), so:
+//   - keys are rotated by re-encrypting the data keys only (RotateKeys), not the
+//     conversations
+//   - a leaked data key only exposes a single conversation
+//   - a conversation copied under another ID fails to decrypt, as the ID is
+//     authenticated
+type EncryptedConversationStore struct {
+	backend ConversationBackend
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// encryptedConversation is the stored form of an encrypted conversation
+type encryptedConversation struct {
+	Version int `json:"version"`
+
+	// KeyID is the ID of the key encryption key of the data key
+	KeyID string `json:"key_id"`
+
+	// DataKey is the data key sealed with the key encryption key (nonce and ciphertext)
+	DataKey []byte `json:"data_key"`
+
+	// Data is the encoded conversation sealed with the data key (nonce and ciphertext)
+	Data []byte `json:"data"`
+}
+
+// NewEncryptedConversationStore creates a store keeping the encrypted conversations in
+// the backend. It fails when no key is given, or when a key is invalid or duplicated.
+func NewEncryptedConversationStore(backend ConversationBackend, config *EncryptedConversationConfig) (*EncryptedConversationStore, error) {
+	if config == nil || len(config.Keys) == 0 {
+		return nil, &Error{Code: "invalid_request", Message: "at least one conversation key is required", Type: "validation_error"}
+	}
+
+	store := &EncryptedConversationStore{
+		backend: backend,
+		primary: config.Keys[0].ID,
+		keys:    make(map[string]cipher.AEAD, len(config.Keys)),
+	}
+	for _, key := range config.Keys {
+		if key.ID == "" {
+			return nil, &Error{Code: "invalid_request", Message: "conversation keys must have an ID", Type: "validation_error"}
+		}
+		if _, exists := store.keys[key.ID]; exists {
+			return nil, &Error{Code: "invalid_request", Message: fmt.Sprintf("duplicate conversation key %q", key.ID), Type: "validation_error"}
+		}
+		aead, err := newConversationAEAD(key.Key)
+		if err != nil {
+			return nil, &Error{Code: "invalid_request", Message: fmt.Sprintf("invalid conversation key %q: %v", key.ID, err), Type: "validation_error"}
+		}
+		store.keys[key.ID] = aead
+	}
+	return store, nil
+}
+
+// Save implements ConversationStore, encrypting the conversation with a new data key
+func (s *EncryptedConversationStore) Save(ctx context.Context, id string, messages []Message) error {
+	plaintext, err := encodeConversation(messages)
+	if err != nil {
+		return err
+	}
+
+	dataKey := make([]byte, conversationDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("failed to generate conversation key: %w", err)
+	}
+	dataAEAD, err := newConversationAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	data, err := sealConversation(dataAEAD, plaintext, conversationDataAD(id))
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, id, dataKey, data)
+}
+
+// put wraps the data key with the primary key and stores the conversation
+func (s *EncryptedConversationStore) put(ctx context.Context, id string, dataKey, data []byte) error {
+	wrapped, err := sealConversation(s.keys[s.primary], dataKey, conversationKeyAD(id, s.primary))
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(encryptedConversation{
+		Version: encryptedConversationVersion,
+		KeyID:   s.primary,
+		DataKey: wrapped,
+		Data:    data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+	return s.backend.Put(ctx, id, encoded)
+}
+
+// Load implements ConversationStore. It fails with a "decryption_failed" error when
+// the conversation was tampered with, or saved under another ID.
+func (s *EncryptedConversationStore) Load(ctx context.Context, id string) ([]Message, error) {
+	stored, dataKey, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newConversationAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openConversation(dataAEAD, stored.Data, conversationDataAD(id))
+	if err != nil {
+		return nil, storageError("decryption_failed", fmt.Sprintf("failed to decrypt conversation %q", id))
+	}
+	return decodeConversation(plaintext)
+}
+
+// get reads a conversation and unwraps its data key
+func (s *EncryptedConversationStore) get(ctx context.Context, id string) (*encryptedConversation, []byte, error) {
+	data, err := s.backend.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stored encryptedConversation
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, nil, storageError("invalid_conversation", fmt.Sprintf("conversation %q is not encrypted: %v", id, err))
+	}
+	if stored.Version != encryptedConversationVersion {
+		return nil, nil, storageError("invalid_conversation", fmt.Sprintf("unsupported version %d of conversation %q", stored.Version, id))
+	}
+
+	keyAEAD, ok := s.keys[stored.KeyID]
+	if !ok {
+		return nil, nil, storageError("unknown_key", fmt.Sprintf("conversation %q is encrypted with unknown key %q", id, stored.KeyID))
+	}
+	dataKey, err := openConversation(keyAEAD, stored.DataKey, conversationKeyAD(id, stored.KeyID))
+	if err != nil {
+		return nil, nil, storageError("decryption_failed", fmt.Sprintf("failed to decrypt the key of conversation %q", id))
+	}
+	return &stored, dataKey, nil
+}
+
+// Delete implements ConversationStore
+func (s *EncryptedConversationStore) Delete(ctx context.Context, id string) error {
+	return s.backend.Delete(ctx, id)
+}
+
+// List implements ConversationStore
+func (s *EncryptedConversationStore) List(ctx context.Context) ([]string, error) {
+	return sortedConversationIDs(ctx, s.backend)
+}
+
+// RotateKeys re-encrypts the data keys of the conversations encrypted with another key
+// than the primary one, returning the number of conversations updated. Once it
+// succeeds, the previous keys can be removed from the configuration. Conversations
+// themselves are not re-encrypted.
+func (s *EncryptedConversationStore) RotateKeys(ctx context.Context) (int, error) {
+	ids, err := s.backend.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return rotated, err
+		}
+		stored, dataKey, err := s.get(ctx, id)
+		if err != nil {
+			return rotated, err
+		}
+		if stored.KeyID == s.primary {
+			continue
+		}
+		if err := s.put(ctx, id, dataKey, stored.Data); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+// newConversationAEAD creates the AES-GCM cipher of a key
+func newConversationAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealConversation encrypts and authenticates data with a random nonce, returned
+// before the ciphertext
+func sealConversation(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openConversation decrypts data sealed by sealConversation
+func openConversation(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// conversationDataAD is the additional data authenticated with a conversation, binding
+// it to its ID
+func conversationDataAD(id string) []byte {
+	return []byte("go-llm conversation\x00" + id)
+}
+
+// conversationKeyAD is the additional data authenticated with the data key of a
+// conversation, binding it to the conversation and the key encryption key
+func conversationKeyAD(id, keyID string) []byte {
+	return []byte("go-llm conversation key\x00" + id + "\x00" + keyID)
+}
@@ -0,0 +1,284 @@
+// Persistent storage of conversations
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConversationStore persists the messages of conversations by ID. Messages are saved
+// with their binary contents (SerializationFormatEnhanced).
+//
+// Implementations must be safe for concurrent use.
+type ConversationStore interface {
+	// Save replaces the messages of the conversation
+	Save(ctx context.Context, id string, messages []Message) error
+
+	// Load returns the messages of the conversation, or an error with the
+	// "conversation_not_found" code when it was never saved
+	Load(ctx context.Context, id string) ([]Message, error)
+
+	// Delete removes the conversation. Deleting a missing conversation is not an error.
+	Delete(ctx context.Context, id string) error
+
+	// List returns the IDs of the saved conversations, sorted
+	List(ctx context.Context) ([]string, error)
+}
+
+// ConversationBackend stores the encoded conversations of a ConversationStore, so the
+// encoding (and encryption) is independent of where they are kept. Implementations for
+// databases or object stores only need to store opaque bytes by ID.
+//
+// Implementations must be safe for concurrent use.
+type ConversationBackend interface {
+	// Get returns the data of the conversation, or an error with the
+	// "conversation_not_found" code
+	Get(ctx context.Context, id string) ([]byte, error)
+
+	// Put replaces the data of the conversation
+	Put(ctx context.Context, id string, data []byte) error
+
+	// Delete removes the conversation, if it exists
+	Delete(ctx context.Context, id string) error
+
+	// List returns the IDs of the stored conversations, in any order
+	List(ctx context.Context) ([]string, error)
+}
+
+// storedConversation is the encoding of the messages of a conversation
+type storedConversation struct {
+	Version  string            `json:"version"`
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// encodeConversation serializes messages with their binary contents
+func encodeConversation(messages []Message) ([]byte, error) {
+	stored := storedConversation{Version: CurrentSerializationVersion, Messages: make([]json.RawMessage, len(messages))}
+	for i, msg := range messages {
+		data, err := SerializeMessage(msg, SerializationFormatEnhanced)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message %d: %w", i, err)
+		}
+		stored.Messages[i] = data
+	}
+	return json.Marshal(stored)
+}
+
+// decodeConversation deserializes the messages encoded by encodeConversation
+func decodeConversation(data []byte) ([]Message, error) {
+	var stored storedConversation
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode conversation: %w", err)
+	}
+	messages := make([]Message, len(stored.Messages))
+	for i, raw := range stored.Messages {
+		msg, err := DeserializeMessage(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode message %d: %w", i, err)
+		}
+		messages[i] = msg
+	}
+	return messages, nil
+}
+
+// conversationNotFound creates the error of a missing conversation
+func conversationNotFound(id string) *Error {
+	return storageError("conversation_not_found", fmt.Sprintf("conversation %q not found", id))
+}
+
+// storageError creates a conversation storage error
+func storageError(code, message string) *Error {
+	return &Error{Code: code, Message: message, Type: "storage_error"}
+}
+
+// JSONConversationStore is a ConversationStore saving conversations as plain JSON.
+// Use an EncryptedConversationStore for conversations with personal data.
+type JSONConversationStore struct {
+	backend ConversationBackend
+}
+
+// NewJSONConversationStore creates a store keeping the conversations in the backend
+func NewJSONConversationStore(backend ConversationBackend) *JSONConversationStore {
+	return &JSONConversationStore{backend: backend}
+}
+
+// Save implements ConversationStore
+func (s *JSONConversationStore) Save(ctx context.Context, id string, messages []Message) error {
+	data, err := encodeConversation(messages)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(ctx, id, data)
+}
+
+// Load implements ConversationStore
+func (s *JSONConversationStore) Load(ctx context.Context, id string) ([]Message, error) {
+	data, err := s.backend.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConversation(data)
+}
+
+// Delete implements ConversationStore
+func (s *JSONConversationStore) Delete(ctx context.Context, id string) error {
+	return s.backend.Delete(ctx, id)
+}
+
+// List implements ConversationStore
+func (s *JSONConversationStore) List(ctx context.Context) ([]string, error) {
+	return sortedConversationIDs(ctx, s.backend)
+}
+
+// sortedConversationIDs returns the IDs of a backend, sorted
+func sortedConversationIDs(ctx context.Context, backend ConversationBackend) ([]string, error) {
+	ids, err := backend.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// MemoryConversationBackend is a ConversationBackend in memory, for tests and
+// short-lived processes
+type MemoryConversationBackend struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryConversationBackend creates an empty in-memory backend
+func NewMemoryConversationBackend() *MemoryConversationBackend {
+	return &MemoryConversationBackend{data: make(map[string][]byte)}
+}
+
+// Get implements ConversationBackend
+func (b *MemoryConversationBackend) Get(_ context.Context, id string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	data, ok := b.data[id]
+	if !ok {
+		return nil, conversationNotFound(id)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Put implements ConversationBackend
+func (b *MemoryConversationBackend) Put(_ context.Context, id string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data[id] = append([]byte(nil), data...)
+	return nil
+}
+
+// Delete implements ConversationBackend
+func (b *MemoryConversationBackend) Delete(_ context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.data, id)
+	return nil
+}
+
+// List implements ConversationBackend
+func (b *MemoryConversationBackend) List(_ context.Context) ([]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := make([]string, 0, len(b.data))
+	for id := range b.data {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// conversationFileExt is the extension of the files of a FileConversationBackend
+const conversationFileExt = ".conv"
+
+// FileConversationBackend is a ConversationBackend keeping every conversation in a file
+// of a directory, readable only by its owner. Files are replaced atomically, so a crash
+// never leaves a truncated conversation.
+type FileConversationBackend struct {
+	dir string
+}
+
+// NewFileConversationBackend creates a backend in the directory, creating it if needed
+func NewFileConversationBackend(dir string) (*FileConversationBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create conversation directory: %w", err)
+	}
+	return &FileConversationBackend{dir: dir}, nil
+}
+
+// path returns the file of a conversation. IDs are escaped, so they cannot point
+// outside of the directory.
+func (b *FileConversationBackend) path(id string) string {
+	return filepath.Join(b.dir, url.PathEscape(id)+conversationFileExt)
+}
+
+// Get implements ConversationBackend
+func (b *FileConversationBackend) Get(_ context.Context, id string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(id))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, conversationNotFound(id)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read conversation %q: %w", id, err)
+	}
+	return data, nil
+}
+
+// Put implements ConversationBackend, writing a temporary file renamed over the
+// conversation file
+func (b *FileConversationBackend) Put(_ context.Context, id string, data []byte) error {
+	tmp, err := os.CreateTemp(b.dir, ".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", id, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save conversation %q: %w", id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", id, err)
+	}
+	if err := os.Rename(tmp.Name(), b.path(id)); err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", id, err)
+	}
+	return nil
+}
+
+// Delete implements ConversationBackend
+func (b *FileConversationBackend) Delete(_ context.Context, id string) error {
+	if err := os.Remove(b.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete conversation %q: %w", id, err)
+	}
+	return nil
+}
+
+// List implements ConversationBackend
+func (b *FileConversationBackend) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), conversationFileExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if id, err := url.PathUnescape(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConversationMessages() []Message {
+	return []Message{
+		NewTextMessage(RoleSystem, "You are a support agent"),
+		NewTextMessage(RoleUser, "My email is jane@example.com"),
+		{Role: RoleUser, Content: []MessageContent{
+			NewTextContent("Here is my ID card"),
+			&ImageContent{MimeType: "image/png", Data: []byte("png data")},
+		}},
+	}
+}
+
+func TestJSONConversationStore(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewFileConversationBackend(filepath.Join(t.TempDir(), "conversations"))
+	require.NoError(t, err)
+	store := NewJSONConversationStore(backend)
+
+	_, err = store.Load(ctx, "missing")
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "conversation_not_found", llmErr.Code)
+
+	messages := testConversationMessages()
+	require.NoError(t, store.Save(ctx, "user/1", messages))
+	require.NoError(t, store.Save(ctx, "user/2", messages[:1]))
+
+	loaded, err := store.Load(ctx, "user/1")
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	assert.Equal(t, "My email is jane@example.com", loaded[1].GetText())
+	assert.Equal(t, []byte("png data"), loaded[2].Content[1].(*ImageContent).Data)
+
+	ids, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user/1", "user/2"}, ids)
+
+	require.NoError(t, store.Delete(ctx, "user/1"))
+	require.NoError(t, store.Delete(ctx, "user/1"))
+	ids, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user/2"}, ids)
+}
+
+func testConversationKey(id string, b byte) ConversationKey {
+	return ConversationKey{ID: id, Key: bytes.Repeat([]byte{b}, 32)}
+}
+
+func TestEncryptedConversationStore(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryConversationBackend()
+	store, err := NewEncryptedConversationStore(backend, &EncryptedConversationConfig{Keys: []ConversationKey{testConversationKey("k1", 1)}})
+	require.NoError(t, err)
+
+	messages := testConversationMessages()
+	require.NoError(t, store.Save(ctx, "c1", messages))
+	require.NoError(t, store.Save(ctx, "c2", messages))
+
+	// Nothing is stored in clear, and every conversation has its own data key
+	data, err := backend.Get(ctx, "c1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "jane@example.com")
+	other, err := backend.Get(ctx, "c2")
+	require.NoError(t, err)
+	var a, b encryptedConversation
+	require.NoError(t, json.Unmarshal(data, &a))
+	require.NoError(t, json.Unmarshal(other, &b))
+	assert.Equal(t, "k1", a.KeyID)
+	assert.NotEqual(t, a.DataKey, b.DataKey)
+
+	loaded, err := store.Load(ctx, "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "My email is jane@example.com", loaded[1].GetText())
+
+	// A conversation copied under another ID, or tampered with, cannot be decrypted
+	require.NoError(t, backend.Put(ctx, "c3", data))
+	_, err = store.Load(ctx, "c3")
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "decryption_failed", llmErr.Code)
+
+	a.Data[len(a.Data)-1] ^= 1
+	tampered, err := json.Marshal(a)
+	require.NoError(t, err)
+	require.NoError(t, backend.Put(ctx, "c1", tampered))
+	_, err = store.Load(ctx, "c1")
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "decryption_failed", llmErr.Code)
+
+	// The plain store cannot be read as an encrypted one
+	require.NoError(t, NewJSONConversationStore(backend).Save(ctx, "plain", messages))
+	_, err = store.Load(ctx, "plain")
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_conversation", llmErr.Code)
+}
+
+func TestEncryptedConversationStore_RotateKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backend, err := NewFileConversationBackend(dir)
+	require.NoError(t, err)
+
+	old, err := NewEncryptedConversationStore(backend, &EncryptedConversationConfig{Keys: []ConversationKey{testConversationKey("k1", 1)}})
+	require.NoError(t, err)
+	require.NoError(t, old.Save(ctx, "c1", testConversationMessages()))
+	before, err := os.ReadFile(filepath.Join(dir, "c1.conv"))
+	require.NoError(t, err)
+
+	// The new primary key encrypts new conversations, the old one still decrypts the others
+	rotating, err := NewEncryptedConversationStore(backend, &EncryptedConversationConfig{Keys: []ConversationKey{
+		testConversationKey("k2", 2), testConversationKey("k1", 1),
+	}})
+	require.NoError(t, err)
+	require.NoError(t, rotating.Save(ctx, "c2", testConversationMessages()))
+	_, err = rotating.Load(ctx, "c1")
+	require.NoError(t, err)
+
+	rotated, err := rotating.RotateKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rotated)
+	rotated, err = rotating.RotateKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, rotated)
+
+	// Only the data key was re-encrypted
+	after, err := os.ReadFile(filepath.Join(dir, "c1.conv"))
+	require.NoError(t, err)
+	var a, b encryptedConversation
+	require.NoError(t, json.Unmarshal(before, &a))
+	require.NoError(t, json.Unmarshal(after, &b))
+	assert.Equal(t, "k2", b.KeyID)
+	assert.Equal(t, a.Data, b.Data)
+
+	// The old key can be removed
+	current, err := NewEncryptedConversationStore(backend, &EncryptedConversationConfig{Keys: []ConversationKey{testConversationKey("k2", 2)}})
+	require.NoError(t, err)
+	loaded, err := current.Load(ctx, "c1")
+	require.NoError(t, err)
+	assert.Len(t, loaded, 3)
+
+	_, err = old.Load(ctx, "c1")
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "unknown_key", llmErr.Code)
+}
+
+func TestNewEncryptedConversationStore_InvalidKeys(t *testing.T) {
+	backend := NewMemoryConversationBackend()
+	for name, config := range map[string]*EncryptedConversationConfig{
+		"no config":  nil,
+		"no keys":    {},
+		"no ID":      {Keys: []ConversationKey{{Key: make([]byte, 32)}}},
+		"short key":  {Keys: []ConversationKey{{ID: "k1", Key: make([]byte, 10)}}},
+		"duplicated": {Keys: []ConversationKey{testConversationKey("k1", 1), testConversationKey("k1", 2)}},
+	} {
+		_, err := NewEncryptedConversationStore(backend, config)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// conversationTexts returns the text of each message
+func conversationTexts(messages []Message) []string {
+	var out []string
+	for _, msg := range messages {
+		out = append(out, msg.GetText())
+	}
+	return out
+}
+
+func TestConversation_Fork(t *testing.T) {
+	c := NewConversation(
+		NewTextMessage(RoleSystem, "sys"),
+		NewTextMessage(RoleUser, "q1"),
+		NewTextMessage(RoleAssistant, "a1"),
+	)
+	if c.CurrentBranch() != DefaultBranch || c.Len() != 3 {
+		t.Fatalf("Unexpected initial state: %s with %d messages", c.CurrentBranch(), c.Len())
+	}
+
+	// Retry the first question with a different answer
+	if err := c.Fork("retry", 2); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if c.CurrentBranch() != "retry" {
+		t.Errorf("Expected fork to switch branch, got %s", c.CurrentBranch())
+	}
+	c.Append(NewTextMessage(RoleAssistant, "a1 bis"))
+
+	if got := conversationTexts(c.Messages()); !reflect.DeepEqual(got, []string{"sys", "q1", "a1 bis"}) {
+		t.Errorf("Unexpected retry branch: %v", got)
+	}
+	main, _ := c.BranchMessages(DefaultBranch)
+	if got := conversationTexts(main); !reflect.DeepEqual(got, []string{"sys", "q1", "a1"}) {
+		t.Errorf("Expected main branch to be untouched, got %v", got)
+	}
+	if got := c.Branches(); !reflect.DeepEqual(got, []string{"main", "retry"}) {
+		t.Errorf("Unexpected branches: %v", got)
+	}
+
+	var llmErr *Error
+	if err := c.Fork("retry", 0); !errors.As(err, &llmErr) || llmErr.Code != "branch_exists" {
+		t.Errorf("Expected branch_exists, got %v", err)
+	}
+	if err := c.Fork("other", 4); !errors.As(err, &llmErr) || llmErr.Code != "invalid_index" {
+		t.Errorf("Expected invalid_index, got %v", err)
+	}
+	if err := c.Switch("missing"); !errors.As(err, &llmErr) || llmErr.Code != "branch_not_found" {
+		t.Errorf("Expected branch_not_found, got %v", err)
+	}
+}
+
+func TestConversation_DiffAndMerge(t *testing.T) {
+	c := NewConversation(NewTextMessage(RoleUser, "q1"), NewTextMessage(RoleAssistant, "a1"))
+	_ = c.Fork("alt", 1)
+	c.Append(NewTextMessage(RoleAssistant, "a1 alt"), NewTextMessage(RoleUser, "q2 alt"))
+	_ = c.Switch(DefaultBranch)
+	c.Append(NewTextMessage(RoleUser, "q2"))
+
+	diff, err := c.Diff(DefaultBranch, "alt")
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if diff.Common != 1 || !reflect.DeepEqual(conversationTexts(diff.OnlyA), []string{"a1", "q2"}) ||
+		!reflect.DeepEqual(conversationTexts(diff.OnlyB), []string{"a1 alt", "q2 alt"}) {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+
+	// Merge selected messages
+	if err := c.Merge("alt", 2); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got := conversationTexts(c.Messages()); !reflect.DeepEqual(got, []string{"q1", "a1", "q2", "q2 alt"}) {
+		t.Errorf("Unexpected merge result: %v", got)
+	}
+
+	// Merge everything after the shared messages
+	_ = c.Fork("full", 1)
+	if err := c.Merge("alt"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got := conversationTexts(c.Messages()); !reflect.DeepEqual(got, []string{"q1", "a1 alt", "q2 alt"}) {
+		t.Errorf("Unexpected full merge result: %v", got)
+	}
+	if diff, _ := c.Diff("full", "alt"); diff.Common != 3 || len(diff.OnlyA)+len(diff.OnlyB) != 0 {
+		t.Errorf("Expected merged branches to be identical, got %+v", diff)
+	}
+
+	var llmErr *Error
+	if err := c.Merge("alt", 5); !errors.As(err, &llmErr) || llmErr.Code != "invalid_index" {
+		t.Errorf("Expected invalid_index, got %v", err)
+	}
+	if err := c.DeleteBranch("full"); err == nil {
+		t.Error("Expected error deleting the current branch")
+	}
+	if err := c.DeleteBranch("alt"); err != nil {
+		t.Errorf("DeleteBranch failed: %v", err)
+	}
+	if _, err := c.Diff("full", "alt"); !errors.As(err, &llmErr) || llmErr.Code != "branch_not_found" {
+		t.Errorf("Expected branch_not_found, got %v", err)
+	}
+}
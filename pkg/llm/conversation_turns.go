@@ -0,0 +1,178 @@
+// Usage, cost and latency of the turns of conversations
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// MetadataTurn is the message metadata key holding the TurnInfo of the replies of models
+const MetadataTurn = "turn"
+
+// CostFunc returns the cost of the usage of a model, in the unit of the caller (e.g. USD)
+type CostFunc func(model string, usage Usage) float64
+
+// TurnInfo describes the model call that produced a reply of a conversation
+type TurnInfo struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Usage    Usage  `json:"usage"`
+
+	// Cost is the cost of the usage, when a CostFunc was given
+	Cost float64 `json:"cost,omitempty"`
+
+	// Latency is the time from the request to the response
+	Latency time.Duration `json:"latency"`
+
+	// Time is when the response was received
+	Time time.Time `json:"time"`
+}
+
+// NewTurnInfo creates the turn of a response of a model (see Client.GetModelInfo), with
+// its cost when cost is not nil. The model of the response is preferred to the name of
+// the model info, as providers report the exact version.
+func NewTurnInfo(resp *ChatResponse, model ModelInfo, latency time.Duration, cost CostFunc) TurnInfo {
+	turn := TurnInfo{
+		Provider: model.Provider,
+		Model:    resp.Model,
+		Usage:    resp.Usage,
+		Latency:  latency,
+		Time:     time.Now(),
+	}
+	if turn.Model == "" {
+		turn.Model = model.Name
+	}
+	if cost != nil {
+		turn.Cost = cost(turn.Model, turn.Usage)
+	}
+	return turn
+}
+
+// TurnOf returns the turn recorded in the metadata of a message, also for messages
+// decoded from JSON, e.g. loaded from a ConversationStore
+func TurnOf(message Message) (TurnInfo, bool) {
+	switch value := message.Metadata[MetadataTurn].(type) {
+	case TurnInfo:
+		return value, true
+	case *TurnInfo:
+		if value != nil {
+			return *value, true
+		}
+	case map[string]any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return TurnInfo{}, false
+		}
+		var turn TurnInfo
+		if json.Unmarshal(data, &turn) == nil {
+			return turn, true
+		}
+	}
+	return TurnInfo{}, false
+}
+
+// TurnSummary aggregates the turns of a conversation
+type TurnSummary struct {
+	Turns   int           `json:"turns"`
+	Usage   Usage         `json:"usage"`
+	Cost    float64       `json:"cost"`
+	Latency time.Duration `json:"latency"`
+}
+
+// AverageLatency returns the mean latency of the turns
+func (s TurnSummary) AverageLatency() time.Duration {
+	if s.Turns == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Turns)
+}
+
+// add adds a turn to the summary
+func (s *TurnSummary) add(turn TurnInfo) {
+	s.Turns++
+	s.Usage = s.Usage.Add(turn.Usage)
+	s.Cost += turn.Cost
+	s.Latency += turn.Latency
+}
+
+// SummarizeTurns aggregates the turns recorded in messages, e.g. those of a stored
+// conversation. Messages without a turn are skipped.
+func SummarizeTurns(messages []Message) TurnSummary {
+	var summary TurnSummary
+	for _, message := range messages {
+		if turn, ok := TurnOf(message); ok {
+			summary.add(turn)
+		}
+	}
+	return summary
+}
+
+// SummarizeTurnsByModel aggregates the turns recorded in messages by model
+func SummarizeTurnsByModel(messages []Message) map[string]TurnSummary {
+	summaries := make(map[string]TurnSummary)
+	for _, message := range messages {
+		if turn, ok := TurnOf(message); ok {
+			summary := summaries[turn.Model]
+			summary.add(turn)
+			summaries[turn.Model] = summary
+		}
+	}
+	return summaries
+}
+
+// AppendTurn appends the reply of a response (its first choice) to the current branch,
+// with the turn in its MetadataTurn metadata
+func (c *Conversation) AppendTurn(resp *ChatResponse, turn TurnInfo) {
+	if len(resp.Choices) == 0 {
+		return
+	}
+	reply := resp.Choices[0].Message
+	metadata := make(map[string]any, len(reply.Metadata)+1)
+	for k, v := range reply.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataTurn] = turn
+	reply.Metadata = metadata
+	c.Append(reply)
+}
+
+// Complete sends the messages of the current branch with the settings of req (its
+// messages are replaced) and appends the reply with its turn: the provider and model of
+// the client, the usage and latency of the call and its cost when cost is not nil
+func (c *Conversation) Complete(ctx context.Context, client Client, req ChatRequest, cost CostFunc) (*ChatResponse, error) {
+	req.Messages = c.Messages()
+	start := time.Now()
+	resp, err := client.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.AppendTurn(resp, NewTurnInfo(resp, client.GetModelInfo(), time.Since(start), cost))
+	return resp, nil
+}
+
+// Turns returns the turns of the replies of the current branch, in order
+func (c *Conversation) Turns() []TurnInfo {
+	var turns []TurnInfo
+	for _, message := range c.Messages() {
+		if turn, ok := TurnOf(message); ok {
+			turns = append(turns, turn)
+		}
+	}
+	return turns
+}
+
+// TurnSummary aggregates the turns of the current branch
+func (c *Conversation) TurnSummary() TurnSummary {
+	return SummarizeTurns(c.Messages())
+}
+
+// TotalCost returns the cost of the turns of the current branch
+func (c *Conversation) TotalCost() float64 {
+	return c.TurnSummary().Cost
+}
+
+// CostByModel aggregates the turns of the current branch by model
+func (c *Conversation) CostByModel() map[string]TurnSummary {
+	return SummarizeTurnsByModel(c.Messages())
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversation_Turns(t *testing.T) {
+	// 1 per 1000 prompt tokens, 2 per 1000 completion tokens
+	cost := func(model string, usage Usage) float64 {
+		return float64(usage.PromptTokens)/1000 + 2*float64(usage.CompletionTokens)/1000
+	}
+
+	client := NewMockClient("mock-model", "mock")
+	conv := NewConversation(NewTextMessage(RoleUser, "Hi"))
+	resp, err := conv.Complete(context.Background(), client, ChatRequest{}, cost)
+	require.NoError(t, err)
+	assert.Equal(t, "Test response", resp.Choices[0].Message.GetText())
+	require.Len(t, client.callLog, 1)
+	assert.Len(t, client.callLog[0].Messages, 1)
+
+	conv.Append(NewTextMessage(RoleUser, "And now?"))
+	other := &ChatResponse{
+		Model:   "other-model",
+		Choices: []Choice{{Message: NewTextMessage(RoleAssistant, "Hello")}},
+		Usage:   Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500},
+	}
+	conv.AppendTurn(other, NewTurnInfo(other, ModelInfo{Name: "other", Provider: "other-provider"}, 2*time.Second, cost))
+
+	turns := conv.Turns()
+	require.Len(t, turns, 2)
+	assert.Equal(t, "mock", turns[0].Provider)
+	assert.Equal(t, "mock-model", turns[0].Model)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, turns[0].Usage)
+	assert.InDelta(t, 0.02, turns[0].Cost, 1e-9)
+	assert.Equal(t, "other-model", turns[1].Model)
+	assert.Equal(t, "other-provider", turns[1].Provider)
+
+	summary := conv.TurnSummary()
+	assert.Equal(t, 2, summary.Turns)
+	assert.Equal(t, 1515, summary.Usage.TotalTokens)
+	assert.InDelta(t, 2.02, conv.TotalCost(), 1e-9)
+	assert.Greater(t, summary.AverageLatency(), time.Second)
+
+	byModel := conv.CostByModel()
+	require.Len(t, byModel, 2)
+	assert.Equal(t, 1, byModel["other-model"].Turns)
+	assert.InDelta(t, 2.0, byModel["other-model"].Cost, 1e-9)
+
+	// Turns survive the stores
+	store := NewJSONConversationStore(NewMemoryConversationBackend())
+	require.NoError(t, store.Save(context.Background(), "conv", conv.Messages()))
+	loaded, err := store.Load(context.Background(), "conv")
+	require.NoError(t, err)
+	assert.Equal(t, summary.Usage, SummarizeTurns(loaded).Usage)
+	assert.InDelta(t, 2.02, SummarizeTurns(loaded).Cost, 1e-9)
+	turn, ok := TurnOf(loaded[3])
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, turn.Latency)
+
+	_, ok = TurnOf(loaded[0])
+	assert.False(t, ok, "user messages have no turn")
+}
@@ -0,0 +1,194 @@
+// Object detection outputs of vision models
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// DetectionBoxScale is the scale of the box coordinates exchanged with the models: Gemini
+// and the models following its convention answer with integer coordinates from 0 to 1000,
+// whatever the size of the image
+const DetectionBoxScale = 1000
+
+// DefaultObjectDetectionPrompt is the instruction sent with the image to detect objects
+const DefaultObjectDetectionPrompt = "Detect the prominent objects in the image. " +
+	"For each object, give its label and its bounding box as box_2d: [ymin, xmin, ymax, xmax], " +
+	"with coordinates normalized to 0-1000."
+
+// BoundingBox is the box of a detected object, with coordinates normalized to [0, 1]
+// from the top-left corner of the image, so it applies to any size of the image
+type BoundingBox struct {
+	XMin float64 `json:"x_min"`
+	YMin float64 `json:"y_min"`
+	XMax float64 `json:"x_max"`
+	YMax float64 `json:"y_max"`
+}
+
+// Width returns the normalized width of the box
+func (b BoundingBox) Width() float64 {
+	return b.XMax - b.XMin
+}
+
+// Height returns the normalized height of the box
+func (b BoundingBox) Height() float64 {
+	return b.YMax - b.YMin
+}
+
+// Area returns the normalized area of the box, the fraction of the image it covers
+func (b BoundingBox) Area() float64 {
+	return b.Width() * b.Height()
+}
+
+// Pixels returns the box in the pixels of an image of the given size, e.g. to crop or
+// draw it
+func (b BoundingBox) Pixels(width, height int) image.Rectangle {
+	return image.Rect(
+		int(math.Round(b.XMin*float64(width))),
+		int(math.Round(b.YMin*float64(height))),
+		int(math.Round(b.XMax*float64(width))),
+		int(math.Round(b.YMax*float64(height))),
+	)
+}
+
+// DetectedObject is an object located by a vision model in an image
+type DetectedObject struct {
+	Label string      `json:"label"`
+	Box   BoundingBox `json:"box"`
+}
+
+// ObjectDetectionResponseFormat returns the response format asking for detected objects:
+// an "objects" array of labels with their box_2d as [ymin, xmin, ymax, xmax] from 0 to
+// DetectionBoxScale, the convention of Gemini's spatial grounding
+func ObjectDetectionResponseFormat() *ResponseFormat {
+	coordinate := map[string]interface{}{"type": "integer", "minimum": 0, "maximum": DetectionBoxScale}
+	return NewJSONSchemaResponseFormat("detected_objects", "Objects detected in an image, with their bounding boxes", map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"objects": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"label": map[string]interface{}{"type": "string", "description": "Name of the object"},
+						"box_2d": map[string]interface{}{
+							"type":        "array",
+							"description": "Bounding box as [ymin, xmin, ymax, xmax], normalized to 0-1000",
+							"items":       coordinate,
+							"minItems":    4,
+							"maxItems":    4,
+						},
+					},
+					"required":             []interface{}{"label", "box_2d"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []interface{}{"objects"},
+		"additionalProperties": false,
+	})
+}
+
+// ObjectDetectionPrompt returns the instruction detecting objects, restricted to some
+// labels when given
+func ObjectDetectionPrompt(labels ...string) string {
+	if len(labels) == 0 {
+		return DefaultObjectDetectionPrompt
+	}
+	return fmt.Sprintf("Detect every object of these kinds in the image: %s. "+
+		"For each object, give its kind as label and its bounding box as box_2d: [ymin, xmin, ymax, xmax], "+
+		"with coordinates normalized to 0-1000.",
+		strings.Join(labels, ", "))
+}
+
+// NewObjectDetectionRequest creates a request detecting the objects of an image, with the
+// labels to look for (any prominent object when none)
+func NewObjectDetectionRequest(img MessageContent, labels ...string) ChatRequest {
+	return ChatRequest{
+		Messages: []Message{{
+			Role:    RoleUser,
+			Content: []MessageContent{img, NewTextContent(ObjectDetectionPrompt(labels...))},
+		}},
+		ResponseFormat: ObjectDetectionResponseFormat(),
+	}
+}
+
+// rawDetectedObject is a detected object as answered by the models
+type rawDetectedObject struct {
+	Label string    `json:"label"`
+	Box2D []float64 `json:"box_2d"`
+}
+
+// ParseDetectedObjects parses the detected objects of a response text: the object of
+// ObjectDetectionResponseFormat, or a bare array of objects as Gemini answers without a
+// response format, also in a markdown code block. Coordinates are normalized to [0, 1],
+// clamped to the image and ordered, as models sometimes swap them. It fails with an
+// "invalid_detection" error when the text holds no detected objects or a box does not
+// have 4 coordinates.
+func ParseDetectedObjects(text string) ([]DetectedObject, error) {
+	extraction, err := ExtractJSONWithOptions(text, JSONExtractionOptions{})
+	if err != nil {
+		return nil, detectionError(fmt.Sprintf("no detected objects in the response: %v", err))
+	}
+
+	var raw []rawDetectedObject
+	data := []byte(extraction.JSON)
+	if strings.HasPrefix(extraction.JSON, "[") {
+		err = json.Unmarshal(data, &raw)
+	} else {
+		var wrapper struct {
+			Objects []rawDetectedObject `json:"objects"`
+		}
+		err = json.Unmarshal(data, &wrapper)
+		raw = wrapper.Objects
+	}
+	if err != nil {
+		return nil, detectionError(fmt.Sprintf("invalid detected objects: %v", err))
+	}
+
+	objects := make([]DetectedObject, 0, len(raw))
+	for i, object := range raw {
+		if len(object.Box2D) != 4 {
+			return nil, detectionError(fmt.Sprintf("object %d (%q): expected 4 box coordinates, got %d", i, object.Label, len(object.Box2D)))
+		}
+		yMin, xMin := normalizeBoxCoordinate(object.Box2D[0]), normalizeBoxCoordinate(object.Box2D[1])
+		yMax, xMax := normalizeBoxCoordinate(object.Box2D[2]), normalizeBoxCoordinate(object.Box2D[3])
+		objects = append(objects, DetectedObject{
+			Label: strings.TrimSpace(object.Label),
+			Box: BoundingBox{
+				XMin: math.Min(xMin, xMax),
+				YMin: math.Min(yMin, yMax),
+				XMax: math.Max(xMin, xMax),
+				YMax: math.Max(yMin, yMax),
+			},
+		})
+	}
+	return objects, nil
+}
+
+// DetectObjects asks a vision client for the objects of an image, with the labels to look
+// for (any prominent object when none), and parses them with ParseDetectedObjects
+func DetectObjects(ctx context.Context, client ChatCompleter, img MessageContent, labels ...string) ([]DetectedObject, error) {
+	resp, err := client.ChatCompletion(ctx, NewObjectDetectionRequest(img, labels...))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, detectionError("the client returned no choices")
+	}
+	return ParseDetectedObjects(resp.Choices[0].Message.GetText())
+}
+
+// normalizeBoxCoordinate converts a coordinate from DetectionBoxScale to [0, 1]
+func normalizeBoxCoordinate(value float64) float64 {
+	return math.Max(0, math.Min(1, value/DetectionBoxScale))
+}
+
+// detectionError creates an object detection error
+func detectionError(message string) *Error {
+	return &Error{Code: "invalid_detection", Message: message, Type: "validation_error"}
+}
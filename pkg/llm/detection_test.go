@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDetectedObjects(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"response format", `{"objects": [{"label": "cat", "box_2d": [100, 250, 500, 750]}]}`},
+		{"bare array", `[{"label": "cat", "box_2d": [100, 250, 500, 750]}]`},
+		{"fenced", "Here are the objects:\n```json\n[{\"label\": \" cat \", \"box_2d\": [100, 250, 500, 750]}]\n```"},
+		{"swapped and out of range", `[{"label": "cat", "box_2d": [500, 750, 100, 250]}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := ParseDetectedObjects(tt.text)
+			require.NoError(t, err)
+			require.Len(t, objects, 1)
+			assert.Equal(t, "cat", objects[0].Label)
+			assert.Equal(t, BoundingBox{XMin: 0.25, YMin: 0.1, XMax: 0.75, YMax: 0.5}, objects[0].Box)
+		})
+	}
+
+	objects, err := ParseDetectedObjects(`[{"label": "sky", "box_2d": [-20, 0, 300, 1200]}]`)
+	require.NoError(t, err)
+	assert.Equal(t, BoundingBox{XMin: 0, YMin: 0, XMax: 1, YMax: 0.3}, objects[0].Box)
+
+	objects, err = ParseDetectedObjects(`{"objects": []}`)
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	var llmErr *Error
+	_, err = ParseDetectedObjects(`[{"label": "cat", "box_2d": [1, 2, 3]}]`)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_detection", llmErr.Code)
+	_, err = ParseDetectedObjects("I can't see any object.")
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_detection", llmErr.Code)
+}
+
+func TestBoundingBox(t *testing.T) {
+	box := BoundingBox{XMin: 0.25, YMin: 0.1, XMax: 0.75, YMax: 0.5}
+	assert.InDelta(t, 0.5, box.Width(), 1e-9)
+	assert.InDelta(t, 0.4, box.Height(), 1e-9)
+	assert.InDelta(t, 0.2, box.Area(), 1e-9)
+	assert.Equal(t, image.Rect(160, 48, 480, 240), box.Pixels(640, 480))
+}
+
+func TestDetectObjects(t *testing.T) {
+	client := NewMockClient("vision-model", "test")
+	client.responses = []*ChatResponse{textResponse(`{"objects": [{"label": "dog", "box_2d": [0, 0, 1000, 500]}]}`)}
+
+	objects, err := DetectObjects(context.Background(), client, NewImageContentFromBytes([]byte{1}, "image/png"), "dog", "cat")
+	require.NoError(t, err)
+	assert.Equal(t, []DetectedObject{{Label: "dog", Box: BoundingBox{XMax: 0.5, YMax: 1}}}, objects)
+
+	req := client.GetCallLog()[0]
+	require.NotNil(t, req.ResponseFormat)
+	assert.Equal(t, "detected_objects", req.ResponseFormat.JSONSchema.Name)
+	assert.NoError(t, LintSchema(req.ResponseFormat.JSONSchema.Schema, false))
+	require.Len(t, req.Messages[0].Content, 2)
+	assert.Equal(t, MessageTypeImage, req.Messages[0].Content[0].Type())
+	assert.Contains(t, req.Messages[0].Content[1].(*TextContent).GetText(), "dog, cat")
+}
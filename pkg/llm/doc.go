@@ -6,12 +6,51 @@
 // The main components include:
 //
 // - Client interface: Core LLM client functionality
-// - StreamingClient interface: Extended interface for streaming with tool injection
-// - Message types: Multi-modal message support (text, images, files)
-// - Tool system: Function calling and tool execution
-// - Configuration: Provider-agnostic configuration
-// - Error handling: Standardized error types
-// - Streaming: Real-time response streaming with tool integration
+// - StreamingClient interface: Extended interface for streaming with tool injection, implemented by all the providers (StreamChatCompletionWithTools)
+// - Message types: Multi-modal message support (text, images, files, also read from io.Reader sources, typed by DetectContent or read from image files without their EXIF/GPS metadata by NewImageContentFromFile) with source attribution (Citation), model reasoning (Message.Reasoning), calls of tools run by the providers (BuiltinToolCall), per-provider content transformers (ContentTransformer), image captioning for text-only models (ImageCaptioningClient), object detection with normalized bounding boxes (DetectObjects, DetectedObject) and PDF page rendering for vision models (PDFRenderer)
+// - Tool system: Function calling, argument validation against parameter schemas, sandboxed execution (ToolExecutor), namespaced tool registries (ToolRegistry) and tool loops with iteration, time, token and cost guards (RunToolLoop)
+// - Structured outputs: JSON Schema generation and validation (ValidateAgainstSchema), strict linting (LintSchema), versioned schemas with compatibility checks (SchemaRegistry), unions (OneOf), typed completions (Typed), multi-strategy extraction from free-form answers (Extractor), JSON extraction modes with diagnostics (ExtractJSONWithOptions) and the OpenAI-compatible response_format shared by providers (CompatibleResponseFormat)
+// - Post-processing: Composable response text cleanup (ResponseProcessor, PostProcessingMiddleware)
+// - Token counting: Per-message token estimates including images and files (CountMessageTokens)
+// - Warnings: Request parameters and features a provider did not honor or degraded, such as prompted response formats or files sent as text (ChatResponse.Warnings)
+// - Finish reasons: Normalized values with per-provider mapping tables (FinishReason, NormalizeFinishReason)
+// - Chat templates: Client-side prompt rendering for raw completion endpoints, with presets for common model families (ChatTemplate)
+// - Configuration: Provider-agnostic configuration, with typed per-request provider options (ProviderOption) and vendor fields merged into OpenAI-compatible payloads (ChatRequest.ExtraBody, ExtraBodyTransport)
+// - Capability probing: Empirical checks of tools, JSON mode, vision, streaming and max output (ProbeCapabilities)
+// - Error handling: Standardized error types with provider rate-limit details (RateLimitInfo), safety-filter blocks (ContentFilterInfo) and redaction of secrets and emails echoed in error messages (SecretRedactor, RegisterRedactionRule, ErrorRedactionMiddleware)
+// - Streaming: Real-time response streaming with tool integration, sequenced events (StreamSequencer), delta batching (CoalescingStream), output pacing at a maximum token rate (PacedStream), configurable buffers and back-pressure policies for slow consumers (StreamBufferConfig, StreamEmitter), classified stream errors with a guaranteed terminal event (StreamErrorInfo, ClassifyStream), Server-Sent Events and NDJSON encoders and decoders (SSEEncoder, NDJSONEncoder), a versioned JSON encoding of events (StreamEventVersion), deduplicated retries of interrupted streams (StreamResumer) and early JSON output validation (JSONStreamValidationMiddleware)
+// - Realtime: Bidirectional text/audio sessions (RealtimeClient, RealtimeSession)
+// - Transcriptions: Speech to text with the language and timed segments of recordings (TranscriptionClient, AudioContent)
+// - Speech: Text to speech, whole or streamed in chunks for low-latency playback (SpeechClient, CollectSpeech, PCMToWAV)
+// - Fine-tuning: Training file uploads and fine-tuning job management (FineTuneClient)
+// - Middleware: Request/response processing chains (e.g. PromptSanitizer for untrusted content, MessageNormalizer for turn ordering, LimitsMiddleware for size limits), with ClientAs reaching the clients wrapped by EnhancedClient, and per-middleware request diffs for debugging (DiffRequests, WithRequestDiffCapture)
+// - Provider switching: Requests translated for the capabilities of another model, degrading content, remapping tools and splitting system prompts (TranslateRequest)
+// - Conversations: Branching message histories with fork, diff and merge (Conversation), saved in stores encrypted at rest with per-conversation data keys and key rotation (ConversationStore, EncryptedConversationStore)
+// - Turn analytics: Usage, cost, latency, provider and model of the replies of conversations, with totals and per-model breakdowns (TurnInfo, Conversation.TurnSummary, SummarizeTurns)
+// - Continuations: Automatic completion of outputs truncated by the token limit (ContinueOnLengthClient)
+// - Response validation: Validators of responses re-asking the model with the failures, recording all the attempts (ResponseValidator, ValidatingClient)
+// - Scheduling: Queued dispatch with priorities and per-provider concurrency limits (Scheduler)
+// - Batch processing: Independent conversations run concurrently with bounded parallelism and aggregated errors (ParallelConversations)
+// - Persistent rate limits: Request counts of rate limiters kept in memory, files or shared stores across restarts and replicas (RateLimitStore, NewPersistentRateLimiter)
+// - Security profiles: Named, inheriting content and rate limit policies selected per client or by request metadata, for multi-tenant services (SecurityProfile, NewSecurityManagerWithProfiles)
+// - Key pools: Load balancing across API keys with rate-limit cooldowns (KeyPool)
+// - Load balancing: Weighted, health-aware distribution across providers with sticky sessions (LoadBalancer)
+// - History: Queryable ring buffer of recent requests, responses and errors (HistoryMiddleware)
+// - Tamper-evident histories: Hash-chained, HMAC-signed history entries (HistoryConfig.HashChain, VerifyHistoryLog)
+// - Raw responses: Provider-native responses and final stream chunks with lazy JSON field access (RawResponse, RawValue)
+// - System prompts: Composition from ordered, conditional parts with token budgets, as one or several messages per provider (SystemPromptBuilder)
+// - Retrieval: Vector stores of documents, in memory or hosted, with context injected in requests (VectorStore, MemoryVectorStore, RAGMiddleware)
+// - Event bus: Request start, first token, tool call, completion and error events for subscribers (EventBus, EventBusMiddleware)
+// - Dry runs: Provider-native payloads of requests converted and validated but not sent (DryRun, DryRunTransport)
+// - Prompt caching: Cache read/write token statistics per model over time (CacheStats, CacheStatsMiddleware)
+// - Drift detection: Response length, refusal and JSON validity changes of models over time (DriftMonitor, DriftMiddleware)
+// - Refusal detection: Refusals and safety deflections tagged by patterns or a judge model, with refusal rates by prompt and model (RefusalClassifier, RefusalMiddleware)
+// - Encoding: Single-allocation base64 and data URLs, streaming base64 readers and pooled JSON request bodies (EncodeDataURL, NewBase64Reader, JSONPayload)
+// - Content fetching: SSRF-safe download of remote images and files, inlined into messages for providers that cannot fetch URLs (ContentFetcher, InlineMessages)
+// - File uploads: Chunked, resumable uploads to provider file APIs with progress and SHA-256 verification (FileUploader, FileUpload, SendChunks)
+// - Transport: Shared, tunable HTTP connection pool for the provider clients (SharedTransport, TransportConfig)
+// - Health: Rolling success rates, latency percentiles and last errors reported by GetRemote (HealthStats)
+// - Clocks: Time of the rate limiters, resource cleanups, key pool cooldowns and health checks injected through their configuration, with a fake clock advanced by tests (Clock, FakeClock)
 //
 // Provider implementations are located in separate packages under /pkg/providers/
 // to maintain clean separation of concerns and avoid import cycles.
@@ -0,0 +1,487 @@
+// Detection of behavior changes of models over time
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics compared by DriftMonitor
+const (
+	DriftMetricLength       = "length"        // relative change of the mean response length
+	DriftMetricRefusalRate  = "refusal_rate"  // change of the fraction of refusals
+	DriftMetricJSONValidity = "json_validity" // change of the fraction of valid JSON answers
+	DriftMetricModelVersion = "model_version" // new model names reported by the provider
+)
+
+// DriftLengthBins are the upper bounds, in characters, of the response length histogram
+// of ResponseProfile. The last bin holds the longer responses.
+var DriftLengthBins = []int{100, 500, 2000, 8000}
+
+// DriftConfig configures a DriftMonitor
+type DriftConfig struct {
+	// BucketSize is the period aggregated by every entry of the history
+	BucketSize time.Duration `json:"bucket_size"`
+
+	// Retention is how long the history is kept. The baseline is the history older than
+	// RecentWindow.
+	Retention time.Duration `json:"retention"`
+
+	// RecentWindow is the period compared against the baseline
+	RecentWindow time.Duration `json:"recent_window"`
+
+	// MinSamples is the number of responses needed in the baseline and the recent window
+	// before they are compared
+	MinSamples int `json:"min_samples"`
+
+	// LengthThreshold is the relative change of the mean length raising an alert
+	LengthThreshold float64 `json:"length_threshold"`
+
+	// RefusalRateThreshold is the change of the refusal rate raising an alert
+	RefusalRateThreshold float64 `json:"refusal_rate_threshold"`
+
+	// JSONValidityThreshold is the change of the JSON validity rate raising an alert
+	JSONValidityThreshold float64 `json:"json_validity_threshold"`
+
+	// OnAlert is called when a metric of a model starts drifting. It is called again
+	// only after the metric got back within its threshold.
+	OnAlert func(DriftAlert) `json:"-"`
+}
+
+// DefaultDriftConfig returns the default configuration: hourly buckets kept for two
+// weeks, comparing the last day with the rest
+func DefaultDriftConfig() *DriftConfig {
+	return &DriftConfig{
+		BucketSize:            time.Hour,
+		Retention:             14 * 24 * time.Hour,
+		RecentWindow:          24 * time.Hour,
+		MinSamples:            20,
+		LengthThreshold:       0.5,
+		RefusalRateThreshold:  0.1,
+		JSONValidityThreshold: 0.1,
+	}
+}
+
+// ResponseFingerprint is what DriftMonitor records of a response
+type ResponseFingerprint struct {
+	// ResponseModel is the model name reported by the provider, which can reveal a new
+	// version served under a pinned name
+	ResponseModel string `json:"response_model,omitempty"`
+
+	// Length is the length of the answer, in characters
+	Length int `json:"length"`
+
+	// Refusal reports whether the model declined to answer
+	Refusal bool `json:"refusal"`
+
+	// JSONExpected reports whether the request asked for JSON, and ValidJSON whether the
+	// answer was valid JSON
+	JSONExpected bool `json:"json_expected"`
+	ValidJSON    bool `json:"valid_json"`
+}
+
+// FingerprintResponse computes the fingerprint of the first choice of a response
+func FingerprintResponse(req *ChatRequest, resp *ChatResponse) ResponseFingerprint {
+	fingerprint := ResponseFingerprint{ResponseModel: resp.Model}
+	if len(resp.Choices) == 0 {
+		return fingerprint
+	}
+	choice := resp.Choices[0]
+	text := strings.TrimSpace(choice.Message.GetText())
+	fingerprint.Length = len([]rune(text))
+	fingerprint.Refusal = choice.FinishReason == FinishReasonContentFilter || looksLikeRefusal(text)
+	if req != nil && req.ResponseFormat.IsJSON() {
+		fingerprint.JSONExpected = true
+		fingerprint.ValidJSON = isValidJSON(text) || len(findJSONBlocks(text)) > 0
+	}
+	return fingerprint
+}
+
+// looksLikeRefusal reports whether an answer is a refusal, by the default refusal patterns
+func looksLikeRefusal(text string) bool {
+	return defaultRefusalClassifier.ClassifyText(text).Kind == RefusalKindRefusal
+}
+
+// ResponseProfile aggregates the fingerprints of some responses
+type ResponseProfile struct {
+	Responses   int64 `json:"responses"`
+	TotalLength int64 `json:"total_length"`
+
+	// LengthHistogram counts the responses per length bin (see DriftLengthBins)
+	LengthHistogram []int64 `json:"length_histogram"`
+
+	Refusals      int64 `json:"refusals"`
+	JSONResponses int64 `json:"json_responses"`
+	ValidJSON     int64 `json:"valid_json"`
+
+	// ModelVersions counts the responses per model name reported by the provider
+	ModelVersions map[string]int64 `json:"model_versions,omitempty"`
+}
+
+// add adds a fingerprint
+func (p *ResponseProfile) add(fingerprint ResponseFingerprint) {
+	if p.LengthHistogram == nil {
+		p.LengthHistogram = make([]int64, len(DriftLengthBins)+1)
+	}
+	p.Responses++
+	p.TotalLength += int64(fingerprint.Length)
+	p.LengthHistogram[sort.SearchInts(DriftLengthBins, fingerprint.Length)]++
+	if fingerprint.Refusal {
+		p.Refusals++
+	}
+	if fingerprint.JSONExpected {
+		p.JSONResponses++
+		if fingerprint.ValidJSON {
+			p.ValidJSON++
+		}
+	}
+	if fingerprint.ResponseModel != "" {
+		if p.ModelVersions == nil {
+			p.ModelVersions = make(map[string]int64)
+		}
+		p.ModelVersions[fingerprint.ResponseModel]++
+	}
+}
+
+// merge adds another profile
+func (p *ResponseProfile) merge(other ResponseProfile) {
+	if p.LengthHistogram == nil {
+		p.LengthHistogram = make([]int64, len(DriftLengthBins)+1)
+	}
+	p.Responses += other.Responses
+	p.TotalLength += other.TotalLength
+	for i, count := range other.LengthHistogram {
+		p.LengthHistogram[i] += count
+	}
+	p.Refusals += other.Refusals
+	p.JSONResponses += other.JSONResponses
+	p.ValidJSON += other.ValidJSON
+	for version, count := range other.ModelVersions {
+		if p.ModelVersions == nil {
+			p.ModelVersions = make(map[string]int64)
+		}
+		p.ModelVersions[version] += count
+	}
+}
+
+// MeanLength returns the mean response length, in characters
+func (p ResponseProfile) MeanLength() float64 {
+	if p.Responses == 0 {
+		return 0
+	}
+	return float64(p.TotalLength) / float64(p.Responses)
+}
+
+// RefusalRate returns the fraction of the responses that were refusals
+func (p ResponseProfile) RefusalRate() float64 {
+	if p.Responses == 0 {
+		return 0
+	}
+	return float64(p.Refusals) / float64(p.Responses)
+}
+
+// JSONValidityRate returns the fraction of the JSON responses that were valid
+func (p ResponseProfile) JSONValidityRate() float64 {
+	if p.JSONResponses == 0 {
+		return 0
+	}
+	return float64(p.ValidJSON) / float64(p.JSONResponses)
+}
+
+// ResponseProfileBucket is the profile of a period of the history
+type ResponseProfileBucket struct {
+	Start time.Time `json:"start"`
+	ResponseProfile
+}
+
+// DriftAlert reports a metric of a model that changed beyond its threshold
+type DriftAlert struct {
+	Model    string  `json:"model"`
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Recent   float64 `json:"recent"`
+	Change   float64 `json:"change"`
+	Message  string  `json:"message"`
+}
+
+// DriftReport compares the recent responses of a model with its baseline
+type DriftReport struct {
+	Model    string          `json:"model"`
+	Baseline ResponseProfile `json:"baseline"`
+	Recent   ResponseProfile `json:"recent"`
+
+	// LengthChange is the relative change of the mean length (0.5 for 50% longer)
+	LengthChange float64 `json:"length_change"`
+
+	// RefusalRateChange and JSONValidityChange are the differences of the rates
+	RefusalRateChange  float64 `json:"refusal_rate_change"`
+	JSONValidityChange float64 `json:"json_validity_change"`
+
+	// Alerts are the metrics beyond their thresholds. They are only computed when both
+	// profiles have MinSamples responses.
+	Alerts []DriftAlert `json:"alerts,omitempty"`
+}
+
+// Drifting reports whether any metric is beyond its threshold
+func (r DriftReport) Drifting() bool {
+	return len(r.Alerts) > 0
+}
+
+// modelDrift is the history of a model
+type modelDrift struct {
+	buckets []ResponseProfileBucket
+	alerts  map[string]bool // metrics currently alerting
+}
+
+// DriftMonitor records fingerprints of the responses of every model (length, refusals,
+// JSON validity, reported model versions) over time, and compares the recent responses
+// with the older ones to detect provider-side model updates changing behavior, e.g. for
+// "stable" model names pinned through OpenRouter. Record responses directly or with
+// DriftMiddleware.
+//
+//	monitor := llm.NewDriftMonitor(&llm.DriftConfig{
+//	    OnAlert: func(alert llm.DriftAlert) { log.Print(alert.Message) },
+//	})
+//	client := llm.NewEnhancedClient(base, []llm.Middleware{llm.NewDriftMiddleware(monitor)})
+type DriftMonitor struct {
+	config *DriftConfig
+
+	mu     sync.Mutex
+	models map[string]*modelDrift
+
+	// now returns the current time; replaceable for testing
+	now func() time.Time
+}
+
+// NewDriftMonitor creates a monitor, filling unset config fields with defaults
+func NewDriftMonitor(config *DriftConfig) *DriftMonitor {
+	defaults := DefaultDriftConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.BucketSize <= 0 {
+		config.BucketSize = defaults.BucketSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	if config.RecentWindow <= 0 {
+		config.RecentWindow = defaults.RecentWindow
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaults.MinSamples
+	}
+	if config.LengthThreshold <= 0 {
+		config.LengthThreshold = defaults.LengthThreshold
+	}
+	if config.RefusalRateThreshold <= 0 {
+		config.RefusalRateThreshold = defaults.RefusalRateThreshold
+	}
+	if config.JSONValidityThreshold <= 0 {
+		config.JSONValidityThreshold = defaults.JSONValidityThreshold
+	}
+	return &DriftMonitor{config: config, models: make(map[string]*modelDrift), now: time.Now}
+}
+
+// Record adds the fingerprint of a response of a model, calling OnAlert for the metrics
+// that started drifting
+func (m *DriftMonitor) Record(model string, fingerprint ResponseFingerprint) {
+	now := m.now()
+	start := now.Truncate(m.config.BucketSize)
+
+	m.mu.Lock()
+	drift, ok := m.models[model]
+	if !ok {
+		drift = &modelDrift{alerts: make(map[string]bool)}
+		m.models[model] = drift
+	}
+	if n := len(drift.buckets); n == 0 || !drift.buckets[n-1].Start.Equal(start) {
+		drift.buckets = append(drift.buckets, ResponseProfileBucket{Start: start})
+	}
+	drift.buckets[len(drift.buckets)-1].add(fingerprint)
+
+	// Drop the buckets older than the retention
+	cutoff := now.Add(-m.config.Retention)
+	expired := sort.Search(len(drift.buckets), func(i int) bool {
+		return !drift.buckets[i].Start.Add(m.config.BucketSize).Before(cutoff)
+	})
+	drift.buckets = drift.buckets[expired:]
+
+	report := m.report(model, drift, now)
+	var started []DriftAlert
+	alerting := make(map[string]bool, len(report.Alerts))
+	for _, alert := range report.Alerts {
+		alerting[alert.Metric] = true
+		if !drift.alerts[alert.Metric] {
+			started = append(started, alert)
+		}
+	}
+	drift.alerts = alerting
+	m.mu.Unlock()
+
+	if m.config.OnAlert != nil {
+		for _, alert := range started {
+			m.config.OnAlert(alert)
+		}
+	}
+}
+
+// Report compares the recent responses of a model with its baseline
+func (m *DriftMonitor) Report(model string) DriftReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	drift, ok := m.models[model]
+	if !ok {
+		return DriftReport{Model: model}
+	}
+	return m.report(model, drift, m.now())
+}
+
+// Reports returns the reports of all the models
+func (m *DriftMonitor) Reports() map[string]DriftReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	reports := make(map[string]DriftReport, len(m.models))
+	for model, drift := range m.models {
+		reports[model] = m.report(model, drift, now)
+	}
+	return reports
+}
+
+// History returns the profiles of a model per period since a time, oldest first.
+// Periods without responses are omitted.
+func (m *DriftMonitor) History(model string, since time.Time) []ResponseProfileBucket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	drift, ok := m.models[model]
+	if !ok {
+		return nil
+	}
+	var history []ResponseProfileBucket
+	for _, bucket := range drift.buckets {
+		if !bucket.Start.Add(m.config.BucketSize).Before(since) {
+			history = append(history, bucket)
+		}
+	}
+	return history
+}
+
+// Reset clears the history, to start a new baseline (e.g. after reviewing an alert)
+func (m *DriftMonitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.models = make(map[string]*modelDrift)
+}
+
+// report computes the report of a model. The lock must be held.
+func (m *DriftMonitor) report(model string, drift *modelDrift, now time.Time) DriftReport {
+	report := DriftReport{Model: model}
+	recentStart := now.Add(-m.config.RecentWindow)
+	for _, bucket := range drift.buckets {
+		if bucket.Start.Before(recentStart.Truncate(m.config.BucketSize)) {
+			report.Baseline.merge(bucket.ResponseProfile)
+		} else {
+			report.Recent.merge(bucket.ResponseProfile)
+		}
+	}
+
+	baseline, recent := report.Baseline, report.Recent
+	if baseline.MeanLength() > 0 {
+		report.LengthChange = recent.MeanLength()/baseline.MeanLength() - 1
+	}
+	report.RefusalRateChange = recent.RefusalRate() - baseline.RefusalRate()
+	if baseline.JSONResponses > 0 && recent.JSONResponses > 0 {
+		report.JSONValidityChange = recent.JSONValidityRate() - baseline.JSONValidityRate()
+	}
+
+	minSamples := int64(m.config.MinSamples)
+	if baseline.Responses < minSamples || recent.Responses < minSamples {
+		return report
+	}
+	alert := func(metric string, baselineValue, recentValue, change float64, format string) {
+		report.Alerts = append(report.Alerts, DriftAlert{
+			Model:    model,
+			Metric:   metric,
+			Baseline: baselineValue,
+			Recent:   recentValue,
+			Change:   change,
+			Message:  fmt.Sprintf("%s: "+format, model, baselineValue, recentValue),
+		})
+	}
+	if math.Abs(report.LengthChange) >= m.config.LengthThreshold {
+		alert(DriftMetricLength, baseline.MeanLength(), recent.MeanLength(), report.LengthChange,
+			"mean response length changed from %.0f to %.0f characters")
+	}
+	if math.Abs(report.RefusalRateChange) >= m.config.RefusalRateThreshold {
+		alert(DriftMetricRefusalRate, baseline.RefusalRate(), recent.RefusalRate(), report.RefusalRateChange,
+			"refusal rate changed from %.2f to %.2f")
+	}
+	if baseline.JSONResponses >= minSamples && recent.JSONResponses >= minSamples &&
+		math.Abs(report.JSONValidityChange) >= m.config.JSONValidityThreshold {
+		alert(DriftMetricJSONValidity, baseline.JSONValidityRate(), recent.JSONValidityRate(), report.JSONValidityChange,
+			"JSON validity rate changed from %.2f to %.2f")
+	}
+
+	var versions []string
+	for version := range recent.ModelVersions {
+		if baseline.ModelVersions[version] == 0 {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) > 0 {
+		sort.Strings(versions)
+		report.Alerts = append(report.Alerts, DriftAlert{
+			Model:   model,
+			Metric:  DriftMetricModelVersion,
+			Change:  float64(len(versions)),
+			Message: fmt.Sprintf("%s: the provider reports new model versions: %s", model, strings.Join(versions, ", ")),
+		})
+	}
+	return report
+}
+
+// DriftMiddleware records the fingerprints of responses in a DriftMonitor, by the model of
+// the request (the name pinned by the caller), or of the response when the request does
+// not name one
+type DriftMiddleware struct {
+	monitor *DriftMonitor
+}
+
+// NewDriftMiddleware creates a middleware recording in the monitor
+func NewDriftMiddleware(monitor *DriftMonitor) *DriftMiddleware {
+	return &DriftMiddleware{monitor: monitor}
+}
+
+// Name returns the middleware name
+func (m *DriftMiddleware) Name() string {
+	return "drift"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *DriftMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse records the fingerprints of successful responses
+func (m *DriftMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	model := resp.Model
+	if req != nil && req.Model != "" {
+		model = req.Model
+	}
+	m.monitor.Record(model, FingerprintResponse(req, resp))
+	return resp, nil
+}
+
+// ProcessStreamEvent passes events through unchanged
+func (m *DriftMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
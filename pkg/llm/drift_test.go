@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintResponse(t *testing.T) {
+	jsonRequest := &ChatRequest{ResponseFormat: &ResponseFormat{Type: ResponseFormatJSON}}
+	tests := []struct {
+		name string
+		req  *ChatRequest
+		resp *ChatResponse
+		want ResponseFingerprint
+	}{
+		{
+			name: "plain answer",
+			resp: textResponse("Paris is the capital of France."),
+			want: ResponseFingerprint{Length: 31},
+		},
+		{
+			name: "refusal",
+			resp: textResponse("I'm sorry, but I can't help with that."),
+			want: ResponseFingerprint{Length: 38, Refusal: true},
+		},
+		{
+			name: "valid json",
+			req:  jsonRequest,
+			resp: textResponse(`{"city": "Paris"}`),
+			want: ResponseFingerprint{Length: 17, JSONExpected: true, ValidJSON: true},
+		},
+		{
+			name: "invalid json",
+			req:  jsonRequest,
+			resp: textResponse(`city: Paris`),
+			want: ResponseFingerprint{Length: 11, JSONExpected: true},
+		},
+		{
+			name: "content filter",
+			resp: &ChatResponse{Model: "m-2025", Choices: []Choice{{FinishReason: FinishReasonContentFilter}}},
+			want: ResponseFingerprint{ResponseModel: "m-2025", Refusal: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FingerprintResponse(tt.req, tt.resp))
+		})
+	}
+}
+
+func TestDriftMonitor(t *testing.T) {
+	var alerts []DriftAlert
+	monitor := NewDriftMonitor(&DriftConfig{
+		RecentWindow: 24 * time.Hour,
+		MinSamples:   5,
+		OnAlert:      func(alert DriftAlert) { alerts = append(alerts, alert) },
+	})
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+
+	// A week of short, compliant answers
+	for i := 0; i < 10; i++ {
+		monitor.Record("pinned", ResponseFingerprint{ResponseModel: "pinned-v1", Length: 100})
+	}
+	now = now.Add(7 * 24 * time.Hour)
+	for i := 0; i < 4; i++ {
+		monitor.Record("pinned", ResponseFingerprint{ResponseModel: "pinned-v1", Length: 100})
+	}
+	report := monitor.Report("pinned")
+	assert.Equal(t, int64(10), report.Baseline.Responses)
+	assert.Equal(t, int64(4), report.Recent.Responses)
+	assert.False(t, report.Drifting())
+
+	// The provider updates the model: longer answers and refusals
+	for i := 0; i < 6; i++ {
+		monitor.Record("pinned", ResponseFingerprint{ResponseModel: "pinned-v2", Length: 400, Refusal: i%2 == 0})
+	}
+	report = monitor.Report("pinned")
+	assert.InDelta(t, 1.8, report.LengthChange, 1e-9)
+	assert.InDelta(t, 0.3, report.RefusalRateChange, 1e-9)
+	require.True(t, report.Drifting())
+
+	var metrics []string
+	for _, alert := range alerts {
+		metrics = append(metrics, alert.Metric)
+	}
+	assert.ElementsMatch(t, []string{DriftMetricLength, DriftMetricModelVersion, DriftMetricRefusalRate}, metrics,
+		"every metric alerts once, when it starts drifting")
+	for _, alert := range alerts {
+		if alert.Metric == DriftMetricModelVersion {
+			assert.True(t, strings.Contains(alert.Message, "pinned-v2"), alert.Message)
+		}
+	}
+
+	assert.Len(t, monitor.History("pinned", time.Time{}), 2)
+	assert.Contains(t, monitor.Reports(), "pinned")
+	monitor.Reset()
+	assert.Empty(t, monitor.Reports())
+}
+
+func TestDriftMonitor_JSONValidity(t *testing.T) {
+	monitor := NewDriftMonitor(&DriftConfig{MinSamples: 4, BucketSize: time.Minute, RecentWindow: time.Hour})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		monitor.Record("m", ResponseFingerprint{Length: 20, JSONExpected: true, ValidJSON: true})
+	}
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 4; i++ {
+		monitor.Record("m", ResponseFingerprint{Length: 20, JSONExpected: true, ValidJSON: i == 0})
+	}
+
+	report := monitor.Report("m")
+	require.Len(t, report.Alerts, 1)
+	assert.Equal(t, DriftMetricJSONValidity, report.Alerts[0].Metric)
+	assert.InDelta(t, -0.75, report.JSONValidityChange, 1e-9)
+	assert.Equal(t, []int64{4, 0, 0, 0, 0}, report.Recent.LengthHistogram)
+}
+
+func TestDriftMiddleware(t *testing.T) {
+	monitor := NewDriftMonitor(nil)
+	mock := NewMockClient("mock-model", "mock")
+	resp := textResponse("Hello there")
+	resp.Model = "served-model"
+	mock.responses = []*ChatResponse{resp}
+	client := NewEnhancedClient(mock, []Middleware{NewDriftMiddleware(monitor)})
+
+	_, err := client.ChatCompletion(context.Background(), ChatRequest{Model: "pinned-model"})
+	require.NoError(t, err)
+	report := monitor.Report("pinned-model")
+	assert.Equal(t, int64(1), report.Recent.Responses)
+	assert.Equal(t, map[string]int64{"served-model": 1}, report.Recent.ModelVersions)
+}
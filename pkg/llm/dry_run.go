@@ -0,0 +1,186 @@
+// Dry runs of requests, capturing the provider-native payload instead of sending it
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DryRunRequest is an HTTP request built by a provider during a dry run
+type DryRunRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	// Header holds the request headers, with credentials redacted
+	Header http.Header `json:"header"`
+
+	// Body is the serialized payload, as it would have been sent
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// DryRunCapture receives the requests built with a context returned by WithDryRun
+type DryRunCapture struct {
+	mu       sync.Mutex
+	requests []DryRunRequest
+}
+
+// Requests returns the captured requests, in order
+func (c *DryRunCapture) Requests() []DryRunRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]DryRunRequest(nil), c.requests...)
+}
+
+// Last returns the last captured request, or nil
+func (c *DryRunCapture) Last() *DryRunRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		return nil
+	}
+	last := c.requests[len(c.requests)-1]
+	return &last
+}
+
+func (c *DryRunCapture) add(req DryRunRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context whose requests are converted and validated by the
+// provider clients but not sent: the DryRunTransport of the providers records them in
+// the returned capture and fails them with a "dry_run" error. See DryRun.
+func WithDryRun(ctx context.Context) (context.Context, *DryRunCapture) {
+	capture := &DryRunCapture{}
+	return context.WithValue(ctx, dryRunKey{}, capture), capture
+}
+
+// IsDryRun reports whether a context was returned by WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*DryRunCapture)
+	return ok
+}
+
+// DryRunTransport is an http.RoundTripper that captures the requests of dry runs instead
+// of sending them, and sends the other requests through Base
+type DryRunTransport struct {
+	// Base is the underlying transport (SharedTransport when nil)
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	capture, ok := req.Context().Value(dryRunKey{}).(*DryRunCapture)
+	if !ok {
+		base := t.Base
+		if base == nil {
+			base = SharedTransport()
+		}
+		return base.RoundTrip(req)
+	}
+
+	captured := DryRunRequest{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Header: redactHeader(req.Header),
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("dry run: failed to read the request body: %w", err)
+		}
+		captured.Body = dryRunBody(body)
+	}
+	capture.add(captured)
+
+	return nil, &Error{
+		Code:    "dry_run",
+		Message: fmt.Sprintf("dry run: %s %s was not sent", captured.Method, captured.URL),
+		Type:    "dry_run",
+	}
+}
+
+// DryRun converts and validates the request with the client, as ChatCompletion does, but
+// returns the provider-native request instead of sending it. Conversion and validation
+// errors are returned as usual. It requires a client whose HTTP requests go through a
+// DryRunTransport, as those of the built-in providers do; other clients fail with a
+// "dry_run_unsupported" error.
+func DryRun(ctx context.Context, client ChatCompleter, req ChatRequest) (*DryRunRequest, error) {
+	ctx, capture := WithDryRun(ctx)
+	_, err := client.ChatCompletion(ctx, req)
+	if last := capture.Last(); last != nil {
+		return last, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, &Error{
+		Code:    "dry_run_unsupported",
+		Message: "the client sent no HTTP request through a DryRunTransport",
+		Type:    "validation_error",
+	}
+}
+
+// dryRunBody returns the body as JSON, quoting bodies that are not JSON
+func dryRunBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			return indented.Bytes()
+		}
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// credentialHeaders are the headers carrying credentials, redacted from dry runs
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Amz-Security-Token"}
+
+// redactHeader returns a copy of the header with the credentials redacted
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "token") || strings.Contains(lower, "secret") {
+			redacted[name] = []string{"[REDACTED]"}
+		}
+	}
+	for _, name := range credentialHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// redactURL returns the URL with the credentials of its query redacted
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for name := range query {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "key") || strings.Contains(lower, "token") || strings.Contains(lower, "secret") {
+			query.Set(name, "REDACTED")
+			changed = true
+		}
+	}
+	redacted := *u
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRunTransport(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &DryRunTransport{}}
+
+	ctx, capture := WithDryRun(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/chat?key=secret&alt=sse", strings.NewReader(`{"model":"m"}`))
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("X-Goog-Api-Key", "secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err := client.Do(req)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "dry_run" {
+		t.Fatalf("Expected a dry_run error, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatal("The dry run request was sent")
+	}
+
+	captured := capture.Last()
+	if captured == nil || len(capture.Requests()) != 1 {
+		t.Fatalf("Expected one captured request, got %v", capture.Requests())
+	}
+	if strings.Contains(captured.URL, "secret") || !strings.Contains(captured.URL, "alt=sse") {
+		t.Errorf("Expected the key to be redacted from %s", captured.URL)
+	}
+	if captured.Header.Get("Authorization") != "[REDACTED]" || captured.Header.Get("X-Goog-Api-Key") != "[REDACTED]" {
+		t.Errorf("Expected the credentials to be redacted, got %v", captured.Header)
+	}
+	if captured.Header.Get("Content-Type") != "application/json" || !strings.Contains(string(captured.Body), `"model": "m"`) {
+		t.Errorf("Unexpected capture %+v", captured)
+	}
+
+	// Other requests are sent
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if sent != 1 {
+		t.Error("Expected the request to be sent")
+	}
+}
+
+func TestDryRun_UnsupportedClient(t *testing.T) {
+	_, err := DryRun(context.Background(), NewMockClient("m", "test"), ChatRequest{})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "dry_run_unsupported" {
+		t.Errorf("Expected dry_run_unsupported, got %v", err)
+	}
+}
+
+func TestRetryableChatCompleter_NoRetryOnDryRun(t *testing.T) {
+	client := NewMockClient("m", "test")
+	client.errorToReturn = &Error{Code: "server_error", StatusCode: 503}
+	retrying := RetryChatCompletion(client, RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond})
+
+	ctx, _ := WithDryRun(context.Background())
+	_, _ = retrying.ChatCompletion(ctx, ChatRequest{})
+	if calls := len(client.GetCallLog()); calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
@@ -0,0 +1,184 @@
+// Low-allocation encoding of binary content and request payloads
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MaxPooledPayloadSize is the capacity above which payload buffers are not returned to
+// the pool, so a few huge requests do not pin their memory
+const MaxPooledPayloadSize = 32 * 1024 * 1024
+
+// base64ChunkSize is the number of source bytes encoded at once by the streaming
+// encoders, a multiple of 3 so only the last chunk is padded
+const base64ChunkSize = 3 * 8 * 1024
+
+var (
+	// payloadPool holds the buffers of JSONPayload
+	payloadPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+	// base64ScratchPool holds the source and encoded chunks of the streaming encoders
+	base64ScratchPool = sync.Pool{New: func() interface{} {
+		return &base64Scratch{}
+	}}
+)
+
+// base64Scratch is the scratch space of a streaming base64 encoder
+type base64Scratch struct {
+	src [base64ChunkSize]byte
+	dst [base64ChunkSize / 3 * 4]byte
+}
+
+// EncodeBase64 encodes data in standard base64 with a single allocation of the exact
+// size of the result
+func EncodeBase64(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(base64.StdEncoding.EncodedLen(len(data)))
+	writeBase64(&sb, data)
+	return sb.String()
+}
+
+// EncodeDataURL encodes data as a "data:<mimeType>;base64,..." URL with a single
+// allocation of the exact size of the result, where formatting the encoded string
+// allocates the encoded size three times
+func EncodeDataURL(mimeType string, data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len("data:;base64,") + len(mimeType) + base64.StdEncoding.EncodedLen(len(data)))
+	sb.WriteString("data:")
+	sb.WriteString(mimeType)
+	sb.WriteString(";base64,")
+	writeBase64(&sb, data)
+	return sb.String()
+}
+
+// writeBase64 encodes data into a builder in chunks, through a pooled scratch buffer
+func writeBase64(sb *strings.Builder, data []byte) {
+	scratch := base64ScratchPool.Get().(*base64Scratch)
+	defer base64ScratchPool.Put(scratch)
+	for len(data) > 0 {
+		n := min(len(data), base64ChunkSize)
+		encoded := scratch.dst[:base64.StdEncoding.EncodedLen(n)]
+		base64.StdEncoding.Encode(encoded, data[:n])
+		sb.Write(encoded)
+		data = data[n:]
+	}
+}
+
+// base64Reader is a streaming base64 encoder
+type base64Reader struct {
+	src     io.Reader
+	scratch *base64Scratch
+	pending []byte // encoded bytes not read yet
+	err     error
+}
+
+// NewBase64Reader returns a reader of the standard base64 encoding of src, encoding it
+// as it is read with a pooled scratch buffer, so large content can be streamed into a
+// request body without holding its encoding in memory
+func NewBase64Reader(src io.Reader) io.Reader {
+	return &base64Reader{src: src}
+}
+
+// NewDataURLReader returns a reader of the "data:<mimeType>;base64,..." URL of src,
+// encoded as it is read
+func NewDataURLReader(mimeType string, src io.Reader) io.Reader {
+	return io.MultiReader(strings.NewReader("data:"+mimeType+";base64,"), NewBase64Reader(src))
+}
+
+// Read implements io.Reader, releasing the scratch buffer at the end of the source
+func (r *base64Reader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			if r.scratch != nil {
+				base64ScratchPool.Put(r.scratch)
+				r.scratch = nil
+			}
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// fill encodes the next chunk of the source
+func (r *base64Reader) fill() {
+	if r.scratch == nil {
+		r.scratch = base64ScratchPool.Get().(*base64Scratch)
+	}
+	n, err := io.ReadFull(r.src, r.scratch.src[:])
+	if n > 0 {
+		r.pending = r.scratch.dst[:base64.StdEncoding.EncodedLen(n)]
+		base64.StdEncoding.Encode(r.pending, r.scratch.src[:n])
+	}
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		r.err = io.EOF
+	default:
+		r.err = err
+	}
+}
+
+// JSONPayload is a request body encoded as JSON into a pooled buffer. The buffer goes
+// back to the pool when the body is closed, which the HTTP client does once the request
+// is sent, so concurrent multimodal requests reuse the memory of their payloads instead
+// of allocating new ones.
+type JSONPayload struct {
+	mu     sync.Mutex // the HTTP client may close the body while it is being read
+	buffer *bytes.Buffer
+	reader *bytes.Reader
+}
+
+// NewJSONPayload encodes a value as a JSON request body
+func NewJSONPayload(v interface{}) (*JSONPayload, error) {
+	buffer := payloadPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	if err := json.NewEncoder(buffer).Encode(v); err != nil {
+		payloadPool.Put(buffer)
+		return nil, err
+	}
+	// Drop the newline written by the encoder
+	buffer.Truncate(buffer.Len() - 1)
+	return &JSONPayload{buffer: buffer, reader: bytes.NewReader(buffer.Bytes())}, nil
+}
+
+// Len returns the size of the payload, for the Content-Length of requests
+func (p *JSONPayload) Len() int64 {
+	return int64(p.buffer.Len())
+}
+
+// Bytes returns the payload, valid until it is closed
+func (p *JSONPayload) Bytes() []byte {
+	return p.buffer.Bytes()
+}
+
+// Read implements io.Reader
+func (p *JSONPayload) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reader == nil {
+		return 0, io.EOF
+	}
+	return p.reader.Read(b)
+}
+
+// Close returns the buffer to the pool. The payload must not be used afterwards.
+func (p *JSONPayload) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reader == nil {
+		return nil
+	}
+	p.reader = nil
+	if p.buffer.Cap() <= MaxPooledPayloadSize {
+		payloadPool.Put(p.buffer)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBinary returns n bytes of binary data
+func testBinary(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestEncodeDataURL(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, base64ChunkSize - 1, base64ChunkSize, 3*base64ChunkSize + 5} {
+		data := testBinary(size)
+		expected := base64.StdEncoding.EncodeToString(data)
+		assert.Equal(t, expected, EncodeBase64(data), "size %d", size)
+		assert.Equal(t, "data:image/png;base64,"+expected, EncodeDataURL("image/png", data), "size %d", size)
+	}
+
+	data := testBinary(1 << 20)
+	allocs := testing.AllocsPerRun(10, func() { _ = EncodeDataURL("image/png", data) })
+	assert.Equal(t, 1.0, allocs, "the data URL is allocated once")
+}
+
+func TestBase64Reader(t *testing.T) {
+	for _, size := range []int{0, 2, base64ChunkSize, 2*base64ChunkSize + 1} {
+		data := testBinary(size)
+		encoded, err := io.ReadAll(NewBase64Reader(bytes.NewReader(data)))
+		require.NoError(t, err)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(data), string(encoded), "size %d", size)
+	}
+
+	// Small reads of a data URL
+	reader := NewDataURLReader("image/gif", bytes.NewReader([]byte("GIF89a")))
+	var out bytes.Buffer
+	buf := make([]byte, 3)
+	for {
+		n, err := reader.Read(buf)
+		out.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "data:image/gif;base64,R0lGODlh", out.String())
+
+	_, err := io.ReadAll(NewBase64Reader(io.MultiReader(bytes.NewReader([]byte("abc")), &failingReader{})))
+	assert.Error(t, err, "source errors are reported")
+}
+
+// failingReader fails every read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, fmt.Errorf("disk error") }
+
+func TestJSONPayload(t *testing.T) {
+	body := map[string]interface{}{"model": "m", "image": EncodeDataURL("image/png", testBinary(100)), "html": "<b>"}
+	expected, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	payload, err := NewJSONPayload(body)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(expected)), payload.Len())
+	read, err := io.ReadAll(payload)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(read), "payloads match json.Marshal")
+
+	require.NoError(t, payload.Close())
+	require.NoError(t, payload.Close(), "closing twice is harmless")
+	n, err := payload.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewJSONPayload(map[string]interface{}{"bad": make(chan int)})
+	assert.Error(t, err)
+}
+
+// BenchmarkImagePayload compares building the JSON body of a request with a 1MB image
+// with fmt/json.Marshal and with EncodeDataURL/JSONPayload
+func BenchmarkImagePayload(b *testing.B) {
+	image := testBinary(1 << 20)
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			url := fmt.Sprintf("data:%s;base64,%s", "image/png", base64.StdEncoding.EncodeToString(image))
+			data, _ := json.Marshal(map[string]string{"url": url})
+			_, _ = io.Copy(io.Discard, bytes.NewReader(data))
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			payload, _ := NewJSONPayload(map[string]string{"url": EncodeDataURL("image/png", image)})
+			_, _ = io.Copy(io.Discard, payload)
+			_ = payload.Close()
+		}
+	})
+}
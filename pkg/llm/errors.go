@@ -7,6 +7,50 @@ type Error struct {
 	Message    string `json:"message"`
 	Type       string `json:"type"`
 	StatusCode int    `json:"status_code,omitempty"`
+
+	// RateLimit holds the rate-limit details reported by the provider, when available
+	RateLimit *RateLimitInfo `json:"rate_limit,omitempty"`
+
+	// ContentFilter holds the safety-filter details of a blocked prompt or response
+	ContentFilter *ContentFilterInfo `json:"content_filter,omitempty"`
+
+	// Upload holds the session of an interrupted upload, to resume it
+	Upload *UploadSession `json:"upload,omitempty"`
+
+	// LoopGuard holds the guard that stopped a tool loop
+	LoopGuard *LoopGuardInfo `json:"loop_guard,omitempty"`
+}
+
+// ContentFilterInfo describes why a provider's safety filters blocked a request
+type ContentFilterInfo struct {
+	// Stage is "prompt" when the input was rejected and "response" when the generated
+	// output was withheld
+	Stage string `json:"stage"`
+
+	// Reason is the provider's block reason (e.g. "SAFETY", "PROHIBITED_CONTENT")
+	Reason string `json:"reason,omitempty"`
+
+	// Categories lists the harm categories rated by the provider, with the blocking ones
+	// marked
+	Categories []ContentFilterCategory `json:"categories,omitempty"`
+}
+
+// ContentFilterCategory is the rating of a single harm category
+type ContentFilterCategory struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability,omitempty"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// BlockedCategories returns the categories that caused the block
+func (i *ContentFilterInfo) BlockedCategories() []string {
+	var blocked []string
+	for _, category := range i.Categories {
+		if category.Blocked {
+			blocked = append(blocked, category.Category)
+		}
+	}
+	return blocked
 }
 
 func (e *Error) Error() string {
@@ -0,0 +1,405 @@
+// Event bus publishing the lifecycle of client operations to subscribers
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientEventType identifies a step of the lifecycle of a request
+type ClientEventType string
+
+const (
+	// EventRequestStart is published when a request is sent
+	EventRequestStart ClientEventType = "request_start"
+	// EventFirstToken is published when the first content of a stream arrives
+	EventFirstToken ClientEventType = "first_token"
+	// EventToolCall is published for every tool call requested by the model
+	EventToolCall ClientEventType = "tool_call"
+	// EventComplete is published when a request succeeds
+	EventComplete ClientEventType = "complete"
+	// EventError is published when a request fails
+	EventError ClientEventType = "error"
+)
+
+// ClientEvent is a step of a request published on an EventBus
+type ClientEvent struct {
+	Type ClientEventType `json:"type"`
+
+	// RequestID correlates the events of a request, unique within a bus
+	RequestID uint64 `json:"request_id"`
+
+	Time   time.Time `json:"time"`
+	Model  string    `json:"model,omitempty"`
+	Stream bool      `json:"stream,omitempty"`
+
+	// Elapsed is the time since the request was sent: the time to first token for
+	// EventFirstToken and the duration of the request for EventComplete and EventError
+	Elapsed time.Duration `json:"elapsed,omitempty"`
+
+	// Request is set for EventRequestStart
+	Request *ChatRequest `json:"request,omitempty"`
+
+	// Response is set for EventComplete, assembled from the events for streams
+	Response *ChatResponse `json:"response,omitempty"`
+
+	// ToolCall is set for EventToolCall
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+
+	// Err is set for EventError
+	Err error `json:"-"`
+}
+
+// EventHandler receives the events of a bus. Handlers run synchronously in the goroutine
+// of the request, so they must be fast; panics are recovered.
+type EventHandler func(event ClientEvent)
+
+type subscription struct {
+	handler EventHandler
+	types   map[ClientEventType]bool // nil for all the types
+}
+
+// EventBus is a single integration point for metrics, progress UIs and logging: an
+// EventBusMiddleware (or any client, with PublishEvent) publishes the lifecycle of every
+// request, and any number of subscribers receive it.
+//
+// Example:
+//
+//	bus := llm.NewEventBus()
+//	bus.OnFirstToken(func(e llm.ClientEvent) { ttft.Observe(e.Elapsed.Seconds()) })
+//	bus.OnError(func(e llm.ClientEvent) { log.Printf("request %d failed: %v", e.RequestID, e.Err) })
+//	client := llm.NewEnhancedClient(base, []llm.Middleware{llm.NewEventBusMiddleware(bus)})
+type EventBus struct {
+	mu            sync.RWMutex
+	subscriptions map[uint64]*subscription
+	nextSub       uint64
+
+	requests sync.Mutex
+	lastID   uint64
+}
+
+// NewEventBus creates a bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscriptions: make(map[uint64]*subscription)}
+}
+
+// Subscribe registers a handler for the events of the given types, or all the events when
+// none is given. It returns a function removing the subscription.
+func (b *EventBus) Subscribe(handler EventHandler, types ...ClientEventType) (unsubscribe func()) {
+	sub := &subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[ClientEventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.nextSub++
+	id := b.nextSub
+	b.subscriptions[id] = sub
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscriptions, id)
+	}
+}
+
+// OnRequestStart subscribes a handler to the EventRequestStart events
+func (b *EventBus) OnRequestStart(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe(handler, EventRequestStart)
+}
+
+// OnFirstToken subscribes a handler to the EventFirstToken events
+func (b *EventBus) OnFirstToken(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe(handler, EventFirstToken)
+}
+
+// OnToolCall subscribes a handler to the EventToolCall events
+func (b *EventBus) OnToolCall(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe(handler, EventToolCall)
+}
+
+// OnComplete subscribes a handler to the EventComplete events
+func (b *EventBus) OnComplete(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe(handler, EventComplete)
+}
+
+// OnError subscribes a handler to the EventError events
+func (b *EventBus) OnError(handler EventHandler) (unsubscribe func()) {
+	return b.Subscribe(handler, EventError)
+}
+
+// NewRequestID returns a new ID to correlate the events of a request
+func (b *EventBus) NewRequestID() uint64 {
+	b.requests.Lock()
+	defer b.requests.Unlock()
+	b.lastID++
+	return b.lastID
+}
+
+// Publish delivers an event to the subscribers of its type, setting its time when unset
+func (b *EventBus) Publish(event ClientEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.subscriptions))
+	for _, sub := range b.subscriptions {
+		if sub.types == nil || sub.types[event.Type] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() { _ = recover() }()
+			handler(event)
+		}()
+	}
+}
+
+// eventBusKey is the context key of the event bus
+type eventBusKey struct{}
+
+// WithEventBus returns a context whose requests publish their events to bus, in addition
+// to the bus of the EventBusMiddleware
+func WithEventBus(ctx context.Context, bus *EventBus) context.Context {
+	return context.WithValue(ctx, eventBusKey{}, bus)
+}
+
+// EventBusFrom returns the bus of a context, or nil
+func EventBusFrom(ctx context.Context) *EventBus {
+	bus, _ := ctx.Value(eventBusKey{}).(*EventBus)
+	return bus
+}
+
+// PublishEvent publishes an event to the bus of the context, if any. Custom clients and
+// middleware use it to report their own events.
+func PublishEvent(ctx context.Context, event ClientEvent) {
+	if bus := EventBusFrom(ctx); bus != nil {
+		bus.Publish(event)
+	}
+}
+
+// maxPendingEvents is the number of requests in flight tracked by an EventBusMiddleware.
+// Requests replaced or rejected by later middleware are never answered, so the oldest
+// ones are forgotten.
+const maxPendingEvents = 256
+
+// pendingEvents is a request in flight of an EventBusMiddleware
+type pendingEvents struct {
+	req   *ChatRequest
+	id    uint64
+	start time.Time
+}
+
+// EventBusMiddleware publishes the lifecycle of the requests of a client to an EventBus
+// and to the bus of the request context (see WithEventBus). Requests are observed as
+// sent, after the middleware preceding it in the chain: put it last.
+type EventBusMiddleware struct {
+	bus *EventBus
+
+	mu          sync.Mutex
+	pending     []pendingEvents // ring buffer of requests in flight
+	pendingNext int
+}
+
+// NewEventBusMiddleware creates a middleware publishing to bus. With a nil bus, events
+// are only published to the bus of the request context.
+func NewEventBusMiddleware(bus *EventBus) *EventBusMiddleware {
+	return &EventBusMiddleware{bus: bus, pending: make([]pendingEvents, maxPendingEvents)}
+}
+
+// Name returns the middleware name
+func (m *EventBusMiddleware) Name() string {
+	return "event_bus"
+}
+
+// ProcessRequest publishes an EventRequestStart event
+func (m *EventBusMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	pending := pendingEvents{req: req, id: m.newRequestID(ctx), start: time.Now()}
+	m.mu.Lock()
+	m.pending[m.pendingNext] = pending
+	m.pendingNext = (m.pendingNext + 1) % len(m.pending)
+	m.mu.Unlock()
+
+	m.publish(ctx, ClientEvent{
+		Type:      EventRequestStart,
+		RequestID: pending.id,
+		Time:      pending.start,
+		Model:     req.Model,
+		Stream:    req.Stream,
+		Request:   req,
+	})
+	return req, nil
+}
+
+// ProcessResponse publishes the tool calls and the EventComplete event of a response,
+// or the EventError event of a failure. Streams are published by their processors.
+func (m *EventBusMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if resp == nil && err == nil {
+		return resp, err
+	}
+	pending := m.finish(ctx, req)
+	m.publishOutcome(ctx, req, pending, false, resp, err)
+	return resp, err
+}
+
+// ProcessStreamEvent passes events through unchanged; streams are published by the
+// processors created by NewStreamProcessor
+func (m *EventBusMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// NewStreamProcessor creates a processor publishing the first token, tool calls and
+// outcome of a stream
+func (m *EventBusMiddleware) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	return &eventBusStreamProcessor{middleware: m, ctx: ctx, req: req, pending: m.finish(ctx, req),
+		resp: &ChatResponse{}, text: make(map[int]*strings.Builder)}
+}
+
+// newRequestID returns an ID from the bus of the middleware, or else from the context bus
+func (m *EventBusMiddleware) newRequestID(ctx context.Context) uint64 {
+	if m.bus != nil {
+		return m.bus.NewRequestID()
+	}
+	if bus := EventBusFrom(ctx); bus != nil {
+		return bus.NewRequestID()
+	}
+	return 0
+}
+
+// finish returns the pending request, forgetting it. Requests replaced by later
+// middleware are not found and get a new ID.
+func (m *EventBusMiddleware) finish(ctx context.Context, req *ChatRequest) pendingEvents {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, pending := range m.pending {
+		if pending.req == req && req != nil {
+			m.pending[i] = pendingEvents{}
+			return pending
+		}
+	}
+	return pendingEvents{req: req, id: m.newRequestID(ctx), start: time.Now()}
+}
+
+// publish sends an event to the bus of the middleware and to the one of the context
+func (m *EventBusMiddleware) publish(ctx context.Context, event ClientEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if m.bus != nil {
+		m.bus.Publish(event)
+	}
+	if bus := EventBusFrom(ctx); bus != nil && bus != m.bus {
+		bus.Publish(event)
+	}
+}
+
+// publishOutcome publishes the tool calls and completion of a response, or its error
+func (m *EventBusMiddleware) publishOutcome(ctx context.Context, req *ChatRequest, pending pendingEvents, stream bool, resp *ChatResponse, err error) {
+	event := ClientEvent{RequestID: pending.id, Stream: stream}
+	if req != nil {
+		event.Model = req.Model
+	}
+
+	if err != nil {
+		event.Type, event.Err, event.Elapsed = EventError, err, time.Since(pending.start)
+		m.publish(ctx, event)
+		return
+	}
+
+	if resp.Model != "" {
+		event.Model = resp.Model
+	}
+	for _, choice := range resp.Choices {
+		for i := range choice.Message.ToolCalls {
+			toolEvent := event
+			toolEvent.Type, toolEvent.ToolCall = EventToolCall, &choice.Message.ToolCalls[i]
+			m.publish(ctx, toolEvent)
+		}
+	}
+	event.Type, event.Response, event.Elapsed = EventComplete, resp, time.Since(pending.start)
+	m.publish(ctx, event)
+}
+
+// eventBusStreamProcessor publishes the events of a stream
+type eventBusStreamProcessor struct {
+	middleware *EventBusMiddleware
+	ctx        context.Context
+	req        *ChatRequest
+	pending    pendingEvents
+	resp       *ChatResponse
+	text       map[int]*strings.Builder
+	firstToken bool
+	err        error
+}
+
+// Process publishes the first token and records the tool calls and errors of the stream
+func (p *eventBusStreamProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	switch {
+	case event.IsError():
+		p.err = event.Error
+	case event.IsDelta():
+		delta := event.Choice.Delta
+		if !p.firstToken && (len(delta.Content) > 0 || len(delta.ToolCalls) > 0 || delta.Reasoning != "") {
+			p.firstToken = true
+			p.middleware.publish(p.ctx, ClientEvent{
+				Type:      EventFirstToken,
+				RequestID: p.pending.id,
+				Model:     p.req.Model,
+				Stream:    true,
+				Elapsed:   time.Since(p.pending.start),
+			})
+		}
+		choice := p.choice(event.Choice.Index)
+		for _, content := range delta.Content {
+			if text, ok := content.(*TextContent); ok {
+				if p.text[event.Choice.Index] == nil {
+					p.text[event.Choice.Index] = &strings.Builder{}
+				}
+				p.text[event.Choice.Index].WriteString(text.Text)
+			}
+		}
+		choice.Message.Reasoning += delta.Reasoning
+		addToolCallDeltas(&choice.Message, delta.ToolCalls)
+	case event.IsDone():
+		choice := p.choice(event.Choice.Index)
+		choice.FinishReason, choice.RawFinishReason = event.Choice.FinishReason, event.Choice.RawFinishReason
+	}
+	return []StreamEvent{event}, nil
+}
+
+// Flush publishes the outcome of the stream
+func (p *eventBusStreamProcessor) Flush() ([]StreamEvent, error) {
+	for i := range p.resp.Choices {
+		if text := p.text[p.resp.Choices[i].Index]; text != nil {
+			p.resp.Choices[i].Message.Content = []MessageContent{NewTextContent(text.String())}
+		}
+	}
+	err := p.err
+	if err == nil && errors.Is(p.ctx.Err(), context.Canceled) {
+		err = p.ctx.Err()
+	}
+	p.middleware.publishOutcome(p.ctx, p.req, p.pending, true, p.resp, err)
+	return nil, nil
+}
+
+// choice returns the choice of the assembled response with an index, adding it
+func (p *eventBusStreamProcessor) choice(index int) *Choice {
+	for i := range p.resp.Choices {
+		if p.resp.Choices[i].Index == index {
+			return &p.resp.Choices[i]
+		}
+	}
+	p.resp.Choices = append(p.resp.Choices, Choice{Index: index, Message: Message{Role: RoleAssistant}})
+	return &p.resp.Choices[len(p.resp.Choices)-1]
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordEvents subscribes to all the events of a bus, returning the recorded events
+func recordEvents(bus *EventBus) func() []ClientEvent {
+	var mu sync.Mutex
+	var events []ClientEvent
+	bus.Subscribe(func(event ClientEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return func() []ClientEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ClientEvent(nil), events...)
+	}
+}
+
+func eventTypes(events []ClientEvent) []ClientEventType {
+	types := make([]ClientEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := NewEventBus()
+	var starts, errs int
+	bus.OnRequestStart(func(ClientEvent) { starts++ })
+	unsubscribe := bus.OnError(func(ClientEvent) { errs++ })
+	bus.Subscribe(func(ClientEvent) { panic("handler bug") })
+
+	bus.Publish(ClientEvent{Type: EventRequestStart})
+	bus.Publish(ClientEvent{Type: EventError})
+	unsubscribe()
+	bus.Publish(ClientEvent{Type: EventError})
+
+	assert.Equal(t, 1, starts)
+	assert.Equal(t, 1, errs)
+}
+
+func TestEventBusMiddleware_ChatCompletion(t *testing.T) {
+	bus := NewEventBus()
+	events := recordEvents(bus)
+
+	mock := NewMockClient("model", "mock")
+	mock.responses = []*ChatResponse{{
+		Model: "model",
+		Choices: []Choice{{Message: Message{
+			Role:      RoleAssistant,
+			ToolCalls: []ToolCall{toolCall("1", "search", `{}`), toolCall("2", "fetch", `{}`)},
+		}}},
+	}}
+	client := NewEnhancedClient(mock, []Middleware{NewEventBusMiddleware(bus)})
+
+	_, err := client.ChatCompletion(context.Background(), ChatRequest{Model: "model"})
+	require.NoError(t, err)
+
+	recorded := events()
+	assert.Equal(t, []ClientEventType{EventRequestStart, EventToolCall, EventToolCall, EventComplete}, eventTypes(recorded))
+	for _, event := range recorded {
+		assert.Equal(t, recorded[0].RequestID, event.RequestID)
+	}
+	assert.Equal(t, "search", recorded[1].ToolCall.Function.Name)
+	assert.NotNil(t, recorded[3].Response)
+
+	mock.errorToReturn = &Error{Code: "server_error", Message: "down", Type: "api_error", StatusCode: 500}
+	_, err = client.ChatCompletion(context.Background(), ChatRequest{Model: "model"})
+	require.Error(t, err)
+
+	recorded = events()[4:]
+	assert.Equal(t, []ClientEventType{EventRequestStart, EventError}, eventTypes(recorded))
+	assert.Equal(t, recorded[0].RequestID, recorded[1].RequestID)
+	assert.NotEqual(t, events()[0].RequestID, recorded[0].RequestID)
+	var llmErr *Error
+	require.True(t, errors.As(recorded[1].Err, &llmErr))
+	assert.Equal(t, "server_error", llmErr.Code)
+}
+
+func TestEventBusMiddleware_Stream(t *testing.T) {
+	bus := NewEventBus()
+	events := recordEvents(bus)
+
+	mock := NewMockClient("model", "mock")
+	mock.streamEvents = []StreamEvent{
+		NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("Hel")}}),
+		NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("lo")}}),
+		NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{Index: 0, ID: "call_1", Function: &ToolCallFunctionDelta{Name: "search", Arguments: `{"q":`}}}}),
+		NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{Index: 0, Function: &ToolCallFunctionDelta{Arguments: `"go"}`}}}}),
+		NewDoneEvent(0, FinishReasonToolCalls),
+	}
+	client := NewEnhancedClient(mock, []Middleware{NewEventBusMiddleware(bus)})
+
+	// Events are also published to the bus of the context
+	requestBus := NewEventBus()
+	requestEvents := recordEvents(requestBus)
+
+	stream, err := client.StreamChatCompletion(WithEventBus(context.Background(), requestBus), ChatRequest{Model: "model", Stream: true})
+	require.NoError(t, err)
+	collectEvents(stream)
+
+	recorded := events()
+	assert.Equal(t, []ClientEventType{EventRequestStart, EventFirstToken, EventToolCall, EventComplete}, eventTypes(recorded))
+	assert.True(t, recorded[1].Stream)
+	assert.Equal(t, `{"q":"go"}`, recorded[2].ToolCall.Function.Arguments)
+	assert.Equal(t, "Hello", recorded[3].Response.Choices[0].Message.GetText())
+	assert.Equal(t, FinishReasonToolCalls, recorded[3].Response.Choices[0].FinishReason)
+	assert.Equal(t, eventTypes(recorded), eventTypes(requestEvents()))
+}
+
+func TestEventBusMiddleware_StreamError(t *testing.T) {
+	bus := NewEventBus()
+	events := recordEvents(bus)
+
+	mock := NewMockClient("model", "mock")
+	mock.streamEvents = []StreamEvent{NewErrorEvent(&Error{Code: "stream_truncated", Message: "cut", Type: "stream_error"})}
+	client := NewEnhancedClient(mock, []Middleware{NewEventBusMiddleware(bus)})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatRequest{Model: "model", Stream: true})
+	require.NoError(t, err)
+	collectEvents(stream)
+
+	recorded := events()
+	assert.Equal(t, []ClientEventType{EventRequestStart, EventError}, eventTypes(recorded))
+	assert.Equal(t, "cut", recorded[1].Err.(*Error).Message)
+}
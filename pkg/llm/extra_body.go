@@ -0,0 +1,98 @@
+// Vendor fields merged into the JSON body of provider requests
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+type extraBodyKey struct{}
+
+// WithExtraBody returns a context whose JSON requests sent through an ExtraBodyTransport
+// get the fields merged into their body (see ChatRequest.ExtraBody)
+func WithExtraBody(ctx context.Context, extra map[string]any) context.Context {
+	if len(extra) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, extraBodyKey{}, extra)
+}
+
+// ExtraBodyTransport is an http.RoundTripper merging the fields of WithExtraBody into the
+// JSON object bodies of requests, and sending them through Base. Requests with other
+// bodies are sent unchanged.
+type ExtraBodyTransport struct {
+	// Base is the underlying transport (SharedTransport when nil)
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ExtraBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = SharedTransport()
+	}
+
+	extra, ok := req.Context().Value(extraBodyKey{}).(map[string]any)
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if !ok || req.Body == nil || mediaType != "application/json" {
+		return base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("extra body: failed to read the request body: %w", err)
+	}
+	merged, err := MergeExtraBody(body, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the request
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(merged))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(merged)), nil }
+	req.ContentLength = int64(len(merged))
+	return base.RoundTrip(req)
+}
+
+// MergeExtraBody returns a JSON object with the fields of extra merged into it, replacing
+// the fields with the same name (nil values remove them). It fails with an
+// "invalid_request" error when the body is not a JSON object or a field cannot be
+// serialized.
+func MergeExtraBody(body []byte, extra map[string]any) ([]byte, error) {
+	if len(extra) == 0 {
+		return body, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, &Error{
+			Code:    "invalid_request",
+			Message: "extra body fields can only be merged into a JSON object",
+			Type:    "validation_error",
+		}
+	}
+
+	for name, value := range extra {
+		if value == nil {
+			delete(fields, name)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, &Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("failed to serialize extra body field %q: %v", name, err),
+				Type:    "validation_error",
+			}
+		}
+		fields[name] = data
+	}
+	return json.Marshal(fields)
+}
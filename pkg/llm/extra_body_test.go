@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeExtraBody(t *testing.T) {
+	merged, err := MergeExtraBody([]byte(`{"model":"gpt-4o","stream":true,"n":1}`), map[string]any{
+		"model":    "custom",
+		"n":        nil,
+		"provider": map[string]any{"order": []string{"groq"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"custom","stream":true,"provider":{"order":["groq"]}}`, string(merged))
+
+	_, err = MergeExtraBody([]byte(`[1,2]`), map[string]any{"n": 1})
+	assert.Equal(t, "invalid_request", err.(*Error).Code)
+
+	_, err = MergeExtraBody([]byte(`{}`), map[string]any{"f": func() {}})
+	assert.Equal(t, "invalid_request", err.(*Error).Code)
+}
+
+func TestExtraBodyTransport(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &ExtraBodyTransport{}}
+
+	send := func(ctx context.Context, contentType, body string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	ctx := WithExtraBody(context.Background(), map[string]any{"top_k": 5})
+	send(ctx, "application/json; charset=utf-8", `{"model":"m"}`)
+	send(ctx, "text/plain", `{"model":"m"}`)
+	send(context.Background(), "application/json", `{"model":"m"}`)
+
+	require.Len(t, received, 3)
+	assert.JSONEq(t, `{"model":"m","top_k":5}`, received[0])
+	assert.Equal(t, `{"model":"m"}`, received[1], "only JSON bodies are merged")
+	assert.Equal(t, `{"model":"m"}`, received[2])
+}
@@ -0,0 +1,348 @@
+// Multi-strategy structured extraction from free-form responses
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExtractionStrategy identifies a way of extracting structured data from a response
+type ExtractionStrategy string
+
+const (
+	// ExtractionFencedJSON decodes the JSON of markdown code blocks
+	ExtractionFencedJSON ExtractionStrategy = "fenced_json"
+	// ExtractionInlineJSON decodes the JSON objects and arrays found in the text
+	ExtractionInlineJSON ExtractionStrategy = "inline_json"
+	// ExtractionKeyValue matches "key: value" lines against the fields of the target struct
+	ExtractionKeyValue ExtractionStrategy = "key_value"
+	// ExtractionReask asks the model to convert the response to JSON matching the target
+	ExtractionReask ExtractionStrategy = "reask"
+)
+
+// DefaultExtractionStrategies are the strategies tried by an Extractor, in order
+var DefaultExtractionStrategies = []ExtractionStrategy{
+	ExtractionFencedJSON,
+	ExtractionInlineJSON,
+	ExtractionKeyValue,
+	ExtractionReask,
+}
+
+// ExtractionAttempt records a strategy that failed
+type ExtractionAttempt struct {
+	Strategy ExtractionStrategy `json:"strategy"`
+	Error    string             `json:"error"`
+}
+
+// ExtractionResult reports how a value was extracted
+type ExtractionResult struct {
+	// Strategy is the strategy that succeeded
+	Strategy ExtractionStrategy `json:"strategy"`
+
+	// JSON is the JSON the value was decoded from
+	JSON string `json:"json"`
+
+	// Attempts are the strategies tried before, with their errors
+	Attempts []ExtractionAttempt `json:"attempts,omitempty"`
+}
+
+// Extractor extracts structured values from free-form responses, trying several
+// strategies in order until one yields a value that decodes into the target (and
+// matches Schema, when set). It is more robust than the single pass of
+// ExtractJSONFromResponse for models that ignore formatting instructions:
+//
+//	var person Person
+//	result, err := llm.NewExtractor(client).Extract(ctx, response, &person)
+//	if err == nil {
+//	    log.Printf("extracted with %s", result.Strategy)
+//	}
+type Extractor struct {
+	// Client is asked to convert the response for ExtractionReask, which is skipped when nil
+	Client ChatCompleter
+
+	// Model is the model of the re-ask requests, the default model of Client when empty
+	Model string
+
+	// Strategies are the strategies tried, in order. Defaults to DefaultExtractionStrategies.
+	Strategies []ExtractionStrategy
+
+	// Schema optionally validates the extracted JSON (see ValidateAgainstSchema). Values
+	// that do not match are rejected and the next strategy is tried.
+	Schema interface{}
+}
+
+// NewExtractor creates an extractor with the default strategies, re-asking client when
+// the other strategies fail (nil disables re-asking)
+func NewExtractor(client ChatCompleter) *Extractor {
+	return &Extractor{Client: client}
+}
+
+// Extract extracts a value from a response into out, a pointer to the target. out is
+// only modified when a strategy succeeds. When they all fail, the error has the
+// "extraction_failed" code and the result lists the attempts.
+func (e *Extractor) Extract(ctx context.Context, response string, out interface{}) (*ExtractionResult, error) {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return nil, &Error{
+			Code:    "invalid_request",
+			Message: "extraction target must be a non-nil pointer",
+			Type:    "validation_error",
+		}
+	}
+
+	strategies := e.Strategies
+	if len(strategies) == 0 {
+		strategies = DefaultExtractionStrategies
+	}
+
+	result := &ExtractionResult{}
+	for _, strategy := range strategies {
+		jsonStr, err := e.apply(ctx, strategy, response, target.Type().Elem())
+		if err == nil {
+			err = e.decode(jsonStr, target)
+		}
+		if err == nil {
+			result.Strategy = strategy
+			result.JSON = jsonStr
+			return result, nil
+		}
+		result.Attempts = append(result.Attempts, ExtractionAttempt{Strategy: strategy, Error: err.Error()})
+	}
+
+	failures := make([]string, len(result.Attempts))
+	for i, attempt := range result.Attempts {
+		failures[i] = fmt.Sprintf("%s: %s", attempt.Strategy, attempt.Error)
+	}
+	return result, &Error{
+		Code:    "extraction_failed",
+		Message: fmt.Sprintf("no extraction strategy succeeded (%s)", strings.Join(failures, "; ")),
+		Type:    "validation_error",
+	}
+}
+
+// apply runs a strategy, returning the JSON it found
+func (e *Extractor) apply(ctx context.Context, strategy ExtractionStrategy, response string, targetType reflect.Type) (string, error) {
+	switch strategy {
+	case ExtractionFencedJSON:
+		return firstJSON(fencedBlocks(response), "no JSON code block found")
+	case ExtractionInlineJSON:
+		return firstJSON(findJSONBlocks(response), "no JSON object or array found")
+	case ExtractionKeyValue:
+		return extractKeyValues(response, targetType)
+	case ExtractionReask:
+		return e.reask(ctx, response, targetType)
+	default:
+		return "", fmt.Errorf("unknown extraction strategy %q", strategy)
+	}
+}
+
+// decode validates the JSON against the schema and decodes it into a fresh value, set
+// into target on success
+func (e *Extractor) decode(jsonStr string, target reflect.Value) error {
+	if e.Schema != nil {
+		if err := ValidateAgainstSchema([]byte(jsonStr), e.Schema); err != nil {
+			return err
+		}
+	}
+	value := reflect.New(target.Type().Elem())
+	if err := json.Unmarshal([]byte(jsonStr), value.Interface()); err != nil {
+		return err
+	}
+	target.Elem().Set(value.Elem())
+	return nil
+}
+
+// fencedBlockPattern matches markdown code blocks, with any language
+var fencedBlockPattern = regexp.MustCompile("(?s)```[\\w-]*\\s*(.*?)```")
+
+// fencedBlocks returns the contents of the markdown code blocks of a text
+func fencedBlocks(text string) []string {
+	var blocks []string
+	for _, match := range fencedBlockPattern.FindAllStringSubmatch(text, -1) {
+		blocks = append(blocks, match[1])
+	}
+	return blocks
+}
+
+// trailingCommaPattern matches commas before closing braces and brackets, across lines
+var trailingCommaPattern = regexp.MustCompile(`,(\s*[}\]])`)
+
+// firstJSON returns the first candidate that is valid JSON, once cleaned of comments and
+// trailing commas
+func firstJSON(candidates []string, notFound string) (string, error) {
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if !isValidJSONStart(candidate) {
+			continue
+		}
+		if isValidJSON(candidate) {
+			return candidate, nil
+		}
+		if cleaned := cleanJSON(trailingCommaPattern.ReplaceAllString(candidate, "$1")); cleaned != "" {
+			return cleaned, nil
+		}
+	}
+	return "", errors.New(notFound)
+}
+
+// keyValuePattern matches "key: value" and "key = value" lines, optionally in list items
+// and with bold or quoted keys
+var keyValuePattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])?\s*[*_"']*([\p{L}_][\p{L}\p{N} _.-]*?)[*_"']*\s*[:=]\s*(.+?)\s*$`)
+
+// extractKeyValues builds a JSON object from the "key: value" lines of a text whose keys
+// match fields of a struct type, converting the values to the field types
+func extractKeyValues(text string, targetType reflect.Type) (string, error) {
+	for targetType.Kind() == reflect.Pointer {
+		targetType = targetType.Elem()
+	}
+	if targetType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("key-value extraction requires a struct target, got %s", targetType)
+	}
+
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		fields[normalizeKey(jsonFieldName(field))] = field
+	}
+
+	object := make(map[string]interface{})
+	for _, line := range strings.Split(text, "\n") {
+		match := keyValuePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		field, ok := fields[normalizeKey(match[1])]
+		if !ok {
+			continue
+		}
+		name := jsonFieldName(field)
+		if _, seen := object[name]; seen {
+			continue
+		}
+		if value, ok := convertKeyValue(strings.Trim(match[2], "*_`"), field.Type); ok {
+			object[name] = value
+		}
+	}
+	if len(object) == 0 {
+		return "", fmt.Errorf("no key-value line matches the fields of %s", targetType)
+	}
+
+	data, err := json.Marshal(object)
+	return string(data), err
+}
+
+// jsonFieldName returns the JSON name of a struct field
+func jsonFieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// normalizeKey lowercases a key and drops separators, so "First Name", "first_name"
+// and "firstName" match
+func normalizeKey(key string) string {
+	var sb strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// convertKeyValue converts a text value to the JSON value of a field type
+func convertKeyValue(value string, fieldType reflect.Type) (interface{}, bool) {
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	unquoted := strings.Trim(value, `"'`)
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		return unquoted, true
+	case reflect.Bool:
+		switch strings.ToLower(unquoted) {
+		case "true", "yes", "y":
+			return true, true
+		case "false", "no", "n":
+			return false, true
+		}
+		return nil, false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(strings.ReplaceAll(unquoted, ",", ""), 64)
+		if err != nil {
+			return nil, false
+		}
+		return number, true
+	case reflect.Slice:
+		if isValidJSON(value) {
+			return json.RawMessage(value), true
+		}
+		var items []interface{}
+		for _, item := range strings.Split(unquoted, ",") {
+			converted, ok := convertKeyValue(strings.TrimSpace(item), fieldType.Elem())
+			if !ok {
+				return nil, false
+			}
+			items = append(items, converted)
+		}
+		return items, true
+	default:
+		if isValidJSON(value) {
+			return json.RawMessage(value), true
+		}
+		return nil, false
+	}
+}
+
+// reask asks the client to convert the response to JSON matching the target type
+func (e *Extractor) reask(ctx context.Context, response string, targetType reflect.Type) (string, error) {
+	if e.Client == nil {
+		return "", fmt.Errorf("no client to re-ask")
+	}
+
+	format, err := NewJSONSchemaResponseFormatFromStruct(typedSchemaName(targetType), "", reflect.New(targetType).Elem().Interface())
+	if err != nil {
+		return "", err
+	}
+	schema, err := json.Marshal(format.JSONSchema.Schema)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := e.Client.ChatCompletion(ctx, ChatRequest{
+		Model: e.Model,
+		Messages: []Message{NewTextMessage(RoleUser, fmt.Sprintf(
+			"Extract the data from the following text as a JSON value matching this JSON schema: %s\n"+
+				"Answer with only the JSON value.\n\nText:\n%s", schema, response))},
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("the re-ask response has no choices")
+	}
+
+	answer := resp.Choices[0].Message.GetText()
+	if isValidJSON(strings.TrimSpace(answer)) {
+		return strings.TrimSpace(answer), nil
+	}
+	if jsonStr, err := firstJSON(fencedBlocks(answer), ""); err == nil {
+		return jsonStr, nil
+	}
+	return firstJSON(findJSONBlocks(answer), "the re-ask response has no JSON")
+}
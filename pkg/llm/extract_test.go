@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type extractedPerson struct {
+	FirstName string   `json:"first_name"`
+	Age       int      `json:"age"`
+	Active    bool     `json:"active"`
+	Skills    []string `json:"skills,omitempty"`
+}
+
+func TestExtractor_Strategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		strategy ExtractionStrategy
+		want     extractedPerson
+		attempts int
+	}{
+		{
+			name:     "fenced json",
+			response: "Here you go:\n```json\n{\"first_name\": \"Ada\", \"age\": 36, \"active\": true}\n```",
+			strategy: ExtractionFencedJSON,
+			want:     extractedPerson{FirstName: "Ada", Age: 36, Active: true},
+		},
+		{
+			name:     "inline json with trailing comma",
+			response: "The person is {\"first_name\": \"Ada\",\n\"age\": 36,\n} as requested.",
+			strategy: ExtractionInlineJSON,
+			want:     extractedPerson{FirstName: "Ada", Age: 36},
+			attempts: 1,
+		},
+		{
+			name:     "key value lines",
+			response: "Sure!\n- **First Name**: Ada\n- Age: 36\n- Active: yes\n- Skills: math, poetry\n- Favorite color: blue",
+			strategy: ExtractionKeyValue,
+			want:     extractedPerson{FirstName: "Ada", Age: 36, Active: true, Skills: []string{"math", "poetry"}},
+			attempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var person extractedPerson
+			result, err := NewExtractor(nil).Extract(context.Background(), tt.response, &person)
+			require.NoError(t, err)
+			assert.Equal(t, tt.strategy, result.Strategy)
+			assert.Len(t, result.Attempts, tt.attempts)
+			assert.Equal(t, tt.want, person)
+			assert.True(t, isValidJSON(result.JSON))
+		})
+	}
+}
+
+func TestExtractor_Reask(t *testing.T) {
+	client := NewMockClient("test-model", "test")
+	client.responses = []*ChatResponse{textResponse(`{"first_name": "Ada", "age": 36, "active": false}`)}
+
+	var person extractedPerson
+	result, err := NewExtractor(client).Extract(context.Background(), "Ada turned thirty-six last week.", &person)
+	require.NoError(t, err)
+	assert.Equal(t, ExtractionReask, result.Strategy)
+	assert.Len(t, result.Attempts, 3)
+	assert.Equal(t, extractedPerson{FirstName: "Ada", Age: 36}, person)
+
+	calls := client.GetCallLog()
+	require.Len(t, calls, 1)
+	require.NotNil(t, calls[0].ResponseFormat)
+	assert.Contains(t, calls[0].Messages[0].GetText(), "Ada turned thirty-six last week.")
+}
+
+func TestExtractor_Failure(t *testing.T) {
+	person := extractedPerson{FirstName: "unchanged"}
+	result, err := NewExtractor(nil).Extract(context.Background(), "Nothing to see here.", &person)
+
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "extraction_failed", llmErr.Code)
+	assert.Len(t, result.Attempts, len(DefaultExtractionStrategies))
+	assert.Equal(t, "unchanged", person.FirstName)
+
+	_, err = NewExtractor(nil).Extract(context.Background(), "{}", person)
+	assert.Error(t, err, "non-pointer targets are rejected")
+}
+
+func TestExtractor_Schema(t *testing.T) {
+	schema, err := SchemaFromStruct(typedAnswer{})
+	require.NoError(t, err)
+
+	// The first block does not match the schema, so the inline strategy is used
+	response := "```json\n{\"city\": \"Paris\", \"population\": -1}\n```\nCorrected: {\"city\": \"Paris\", \"population\": 2100000}"
+	extractor := &Extractor{Strategies: []ExtractionStrategy{ExtractionInlineJSON}, Schema: schema}
+
+	var answer typedAnswer
+	result, err := extractor.Extract(context.Background(), response, &answer)
+	require.Error(t, err, "the first inline object does not match the schema")
+	assert.Len(t, result.Attempts, 1)
+
+	extractor.Strategies = []ExtractionStrategy{ExtractionFencedJSON, ExtractionKeyValue}
+	result, err = extractor.Extract(context.Background(), "```json\n{\"city\": \"Paris\", \"population\": -1}\n```\ncity: Paris\npopulation: 2,100,000", &answer)
+	require.NoError(t, err)
+	assert.Equal(t, ExtractionKeyValue, result.Strategy)
+	assert.Equal(t, 2100000, answer.Population)
+}
@@ -0,0 +1,413 @@
+// SSRF-safe fetching of remote content
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ContentFetcherConfig configures a ContentFetcher
+type ContentFetcherConfig struct {
+	MaxSize        int64         `json:"max_size"`        // Maximum download size in bytes
+	Timeout        time.Duration `json:"timeout"`         // Timeout for the whole download
+	MaxRedirects   int           `json:"max_redirects"`   // Maximum number of redirects to follow, NoRedirects for none
+	AllowedSchemes []string      `json:"allowed_schemes"` // URL schemes that may be fetched
+
+	// AllowedHosts restricts fetching to these host names when not empty
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+
+	// BlockedCIDRs adds extra networks to the built-in blocklist
+	BlockedCIDRs []string `json:"blocked_cidrs,omitempty"`
+
+	// AllowPrivateNetworks disables the built-in blocklist (loopback, private, link-local...).
+	// Only use this in trusted environments such as tests.
+	AllowPrivateNetworks bool `json:"allow_private_networks"`
+}
+
+// NoRedirects is the ContentFetcherConfig.MaxRedirects value that disables redirects, as
+// zero selects the default limit
+const NoRedirects = -1
+
+// DefaultContentFetcherConfig returns a conservative fetcher configuration
+func DefaultContentFetcherConfig() *ContentFetcherConfig {
+	return &ContentFetcherConfig{
+		MaxSize:        20 * 1024 * 1024, // 20MB
+		Timeout:        30 * time.Second,
+		MaxRedirects:   3,
+		AllowedSchemes: []string{"https"},
+	}
+}
+
+// defaultBlockedCIDRs are networks that fetched URLs must never reach
+var defaultBlockedCIDRs = []string{
+	"0.0.0.0/8",      // "this" network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, including cloud metadata endpoints
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // NAT64, can embed private IPv4 addresses
+	"2002::/16",      // 6to4, can embed private IPv4 addresses
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+}
+
+// ContentFetcher downloads URL-based content with SSRF protections.
+// Host names are resolved at connection time and every resolved address is
+// checked against the blocklist, so DNS rebinding and redirects to internal
+// addresses are rejected.
+type ContentFetcher struct {
+	config     *ContentFetcherConfig
+	blocked    []*net.IPNet
+	httpClient *http.Client
+
+	// lookupIP resolves host names; replaceable for testing
+	lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+}
+
+// NewContentFetcher creates a new content fetcher, filling unset fields with defaults
+func NewContentFetcher(config *ContentFetcherConfig) (*ContentFetcher, error) {
+	defaults := DefaultContentFetcherConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaults.MaxSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxRedirects == 0 {
+		config.MaxRedirects = defaults.MaxRedirects
+	} else if config.MaxRedirects < 0 {
+		config.MaxRedirects = NoRedirects
+	}
+	if len(config.AllowedSchemes) == 0 {
+		config.AllowedSchemes = defaults.AllowedSchemes
+	}
+
+	cidrs := config.BlockedCIDRs
+	if !config.AllowPrivateNetworks {
+		cidrs = append(append([]string{}, defaultBlockedCIDRs...), cidrs...)
+	}
+
+	f := &ContentFetcher{config: config}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked CIDR %q: %w", cidr, err)
+		}
+		f.blocked = append(f.blocked, network)
+	}
+
+	f.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		return net.DefaultResolver.LookupIP(ctx, "ip", host)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	f.httpClient = &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			// Never use a proxy: it would bypass the address checks below
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				ip, err := f.resolveSafe(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				// Dial the address we validated, not the host name, so a second
+				// DNS answer can't point the connection somewhere else
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: config.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > max(config.MaxRedirects, 0) {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return f.validateURL(req.URL)
+		},
+	}
+
+	return f, nil
+}
+
+// Fetch downloads the content at rawURL and returns its data and MIME type
+func (f *ContentFetcher) Fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", newFetchError("invalid_url", fmt.Sprintf("invalid URL: %v", err))
+	}
+	if err := f.validateURL(parsed); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, "", newFetchError("invalid_url", fmt.Sprintf("failed to create request: %v", err))
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		var llmErr *Error
+		if errors.As(err, &llmErr) {
+			return nil, "", llmErr
+		}
+		return nil, "", newFetchError("fetch_failed", fmt.Sprintf("failed to fetch %s: %v", parsed.Redacted(), err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newFetchError("fetch_failed", fmt.Sprintf("failed to fetch %s: HTTP %d", parsed.Redacted(), resp.StatusCode))
+	}
+
+	if resp.ContentLength > f.config.MaxSize {
+		return nil, "", newFetchError("content_too_large",
+			fmt.Sprintf("remote content size %d exceeds limit %d", resp.ContentLength, f.config.MaxSize))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxSize+1))
+	if err != nil {
+		return nil, "", newFetchError("fetch_failed", fmt.Sprintf("failed to read %s: %v", parsed.Redacted(), err))
+	}
+	if int64(len(data)) > f.config.MaxSize {
+		return nil, "", newFetchError("content_too_large",
+			fmt.Sprintf("remote content exceeds limit %d", f.config.MaxSize))
+	}
+
+	mimeType := ""
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		mimeType = mediaType
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	}
+
+	return data, mimeType, nil
+}
+
+// FetchImage returns a copy of the image with the remote URL replaced by inline data
+func (f *ContentFetcher) FetchImage(ctx context.Context, img *ImageContent) (*ImageContent, error) {
+	if img.HasData() || !img.HasURL() {
+		return img, nil
+	}
+
+	data, mimeType, err := f.Fetch(ctx, img.URL)
+	if err != nil {
+		return nil, err
+	}
+	if img.MimeType != "" {
+		mimeType = img.MimeType
+	}
+
+	fetched := NewImageContentFromBytes(data, mimeType)
+	fetched.Width = img.Width
+	fetched.Height = img.Height
+	fetched.Filename = img.Filename
+	return fetched, nil
+}
+
+// FetchFile returns a copy of the file with the remote URL replaced by inline data
+func (f *ContentFetcher) FetchFile(ctx context.Context, file *FileContent) (*FileContent, error) {
+	if file.HasData() || !file.HasURL() {
+		return file, nil
+	}
+
+	data, mimeType, err := f.Fetch(ctx, file.URL)
+	if err != nil {
+		return nil, err
+	}
+	if file.MimeType != "" {
+		mimeType = file.MimeType
+	}
+
+	filename := file.Filename
+	if filename == "" {
+		if parsed, err := url.Parse(file.URL); err == nil {
+			filename = parsed.Path[strings.LastIndex(parsed.Path, "/")+1:]
+		}
+	}
+
+	return NewFileContentFromBytes(data, filename, mimeType), nil
+}
+
+// InlineMessages returns copies of the messages where URL-based content of the
+// given types (images and files when none are given) is replaced by inline data.
+// The original messages are not modified.
+func (f *ContentFetcher) InlineMessages(ctx context.Context, messages []Message, types ...MessageType) ([]Message, error) {
+	if len(types) == 0 {
+		types = []MessageType{MessageTypeImage, MessageTypeFile}
+	}
+	wanted := func(t MessageType) bool {
+		for _, candidate := range types {
+			if candidate == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+
+		needsFetch := false
+		for _, content := range msg.Content {
+			if wanted(content.Type()) && isRemoteOnly(content) {
+				needsFetch = true
+				break
+			}
+		}
+		if !needsFetch {
+			continue
+		}
+
+		inlined := msg.DeepCopy()
+		for j, content := range inlined.Content {
+			if !wanted(content.Type()) {
+				continue
+			}
+
+			var err error
+			switch c := content.(type) {
+			case *ImageContent:
+				inlined.Content[j], err = f.FetchImage(ctx, c)
+			case *FileContent:
+				inlined.Content[j], err = f.FetchFile(ctx, c)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("message %d content %d: %w", i, j, err)
+			}
+		}
+		result[i] = inlined
+	}
+
+	return result, nil
+}
+
+// validateURL checks the scheme and host of a URL before any connection is made
+func (f *ContentFetcher) validateURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	allowed := false
+	for _, s := range f.config.AllowedSchemes {
+		if strings.EqualFold(s, scheme) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return newFetchError("url_blocked", fmt.Sprintf("URL scheme %q is not allowed", u.Scheme))
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return newFetchError("invalid_url", "URL has no host")
+	}
+	if u.User != nil {
+		return newFetchError("url_blocked", "URLs with credentials are not allowed")
+	}
+
+	if len(f.config.AllowedHosts) > 0 {
+		for _, h := range f.config.AllowedHosts {
+			if strings.EqualFold(h, host) {
+				return nil
+			}
+		}
+		return newFetchError("url_blocked", fmt.Sprintf("host %q is not in the allowed hosts list", host))
+	}
+
+	// Literal IPs can be rejected before dialing
+	if ip := net.ParseIP(host); ip != nil && f.isBlockedIP(ip) {
+		return newFetchError("url_blocked", fmt.Sprintf("address %s is not allowed", ip))
+	}
+
+	return nil
+}
+
+// resolveSafe resolves a host and returns the first address, failing if any
+// resolved address is blocked
+func (f *ContentFetcher) resolveSafe(ctx context.Context, host string) (net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		resolved, err := f.lookupIP(ctx, host)
+		if err != nil {
+			return nil, newFetchError("fetch_failed", fmt.Sprintf("failed to resolve %s: %v", host, err))
+		}
+		ips = resolved
+	}
+
+	if len(ips) == 0 {
+		return nil, newFetchError("fetch_failed", fmt.Sprintf("no addresses found for %s", host))
+	}
+
+	// Reject the host if any of its addresses is blocked, otherwise an
+	// attacker could mix public and internal records
+	for _, ip := range ips {
+		if f.isBlockedIP(ip) {
+			return nil, newFetchError("url_blocked", fmt.Sprintf("host %s resolves to blocked address %s", host, ip))
+		}
+	}
+
+	return ips[0], nil
+}
+
+// isBlockedIP checks an address against the blocklist
+func (f *ContentFetcher) isBlockedIP(ip net.IP) bool {
+	// Check IPv4-mapped IPv6 addresses as IPv4
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range f.blocked {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRemoteOnly checks if the content references a URL but has no inline data
+func isRemoteOnly(content MessageContent) bool {
+	switch c := content.(type) {
+	case *ImageContent:
+		return c.HasURL() && !c.HasData()
+	case *FileContent:
+		return c.HasURL() && !c.HasData()
+	}
+	return false
+}
+
+// newFetchError creates a standardized error for content fetching failures
+func newFetchError(code, message string) *Error {
+	errType := "fetch_error"
+	if code == "url_blocked" {
+		errType = "security_error"
+	}
+	return &Error{
+		Code:    code,
+		Message: message,
+		Type:    errType,
+	}
+}
@@ -0,0 +1,145 @@
+// Fine-tuning job management interfaces
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// DefaultFineTunePurpose is the purpose of the training and validation files uploaded
+// for fine-tuning jobs
+const DefaultFineTunePurpose = "fine-tune"
+
+// FineTuneClient manages the fine-tuning jobs of a provider (OpenAI): training files are
+// uploaded, a job is created from them and followed until it produces a fine-tuned
+// model, which is then used as the model of a regular Client
+type FineTuneClient interface {
+	// UploadTrainingFile uploads a training or validation file (JSONL), returning the
+	// file ID to use in a FineTuneJobRequest
+	UploadTrainingFile(ctx context.Context, upload *FileUpload) (*UploadedFile, error)
+
+	// CreateJob starts a fine-tuning job
+	CreateJob(ctx context.Context, req FineTuneJobRequest) (*FineTuneJob, error)
+
+	// GetJob returns the current state of a job
+	GetJob(ctx context.Context, id string) (*FineTuneJob, error)
+
+	// ListJobs returns a page of jobs, most recent first
+	ListJobs(ctx context.Context, opts FineTuneListOptions) (*FineTuneJobPage, error)
+
+	// CancelJob cancels a job that has not finished yet
+	CancelJob(ctx context.Context, id string) (*FineTuneJob, error)
+
+	// ListEvents returns a page of the events of a job, most recent first
+	ListEvents(ctx context.Context, id string, opts FineTuneListOptions) (*FineTuneEventPage, error)
+}
+
+// FineTuneJobStatus is the state of a fine-tuning job
+type FineTuneJobStatus string
+
+const (
+	FineTuneStatusValidatingFiles FineTuneJobStatus = "validating_files"
+	FineTuneStatusQueued          FineTuneJobStatus = "queued"
+	FineTuneStatusRunning         FineTuneJobStatus = "running"
+	FineTuneStatusSucceeded       FineTuneJobStatus = "succeeded"
+	FineTuneStatusFailed          FineTuneJobStatus = "failed"
+	FineTuneStatusCancelled       FineTuneJobStatus = "cancelled"
+)
+
+// IsTerminal returns true for the states of finished jobs
+func (s FineTuneJobStatus) IsTerminal() bool {
+	return s == FineTuneStatusSucceeded || s == FineTuneStatusFailed || s == FineTuneStatusCancelled
+}
+
+// FineTuneHyperparameters are the training settings of a job. Zero values let the
+// provider choose.
+type FineTuneHyperparameters struct {
+	Epochs                 int     `json:"epochs,omitempty"`
+	BatchSize              int     `json:"batch_size,omitempty"`
+	LearningRateMultiplier float64 `json:"learning_rate_multiplier,omitempty"`
+}
+
+// FineTuneJobRequest describes a fine-tuning job to create
+type FineTuneJobRequest struct {
+	// Model is the base model to fine-tune
+	Model string `json:"model"`
+
+	// TrainingFile and ValidationFile are the IDs of uploaded files (see
+	// FineTuneClient.UploadTrainingFile). The validation file is optional.
+	TrainingFile   string `json:"training_file"`
+	ValidationFile string `json:"validation_file,omitempty"`
+
+	// Suffix is added to the name of the fine-tuned model
+	Suffix string `json:"suffix,omitempty"`
+
+	Seed            *int                     `json:"seed,omitempty"`
+	Hyperparameters *FineTuneHyperparameters `json:"hyperparameters,omitempty"`
+	Metadata        map[string]string        `json:"metadata,omitempty"`
+}
+
+// Validate checks that a job request has a model and a training file
+func (r FineTuneJobRequest) Validate() error {
+	switch {
+	case r.Model == "":
+		return &Error{Code: "invalid_request", Message: "fine-tuning job requires a base model", Type: "validation_error"}
+	case r.TrainingFile == "":
+		return &Error{Code: "invalid_request", Message: "fine-tuning job requires a training file", Type: "validation_error"}
+	}
+	return nil
+}
+
+// FineTuneJob is the state of a fine-tuning job
+type FineTuneJob struct {
+	ID     string            `json:"id"`
+	Model  string            `json:"model"`
+	Status FineTuneJobStatus `json:"status"`
+
+	// FineTunedModel is the name of the resulting model, set once the job succeeded
+	FineTunedModel string `json:"fine_tuned_model,omitempty"`
+
+	TrainingFile    string                  `json:"training_file"`
+	ValidationFile  string                  `json:"validation_file,omitempty"`
+	ResultFiles     []string                `json:"result_files,omitempty"`
+	TrainedTokens   int                     `json:"trained_tokens,omitempty"`
+	Hyperparameters FineTuneHyperparameters `json:"hyperparameters"`
+	Metadata        map[string]string       `json:"metadata,omitempty"`
+
+	CreatedAt       time.Time  `json:"created_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	EstimatedFinish *time.Time `json:"estimated_finish,omitempty"`
+
+	// Error is the reason of the failure of failed jobs
+	Error *Error `json:"error,omitempty"`
+}
+
+// FineTuneEvent is a progress or log message of a fine-tuning job
+type FineTuneEvent struct {
+	ID        string    `json:"id"`
+	Level     string    `json:"level"` // "info", "warn" or "error"
+	Type      string    `json:"type,omitempty"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Data holds the provider-specific details of the event (e.g. training metrics)
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// FineTuneListOptions paginates the job and event listings. The next page starts after
+// the ID of the last item of the previous one.
+type FineTuneListOptions struct {
+	After string `json:"after,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// FineTuneJobPage is a page of fine-tuning jobs
+type FineTuneJobPage struct {
+	Jobs    []FineTuneJob `json:"jobs"`
+	HasMore bool          `json:"has_more"`
+}
+
+// FineTuneEventPage is a page of events of a fine-tuning job
+type FineTuneEventPage struct {
+	Events  []FineTuneEvent `json:"events"`
+	HasMore bool            `json:"has_more"`
+}
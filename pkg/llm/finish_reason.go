@@ -0,0 +1,149 @@
+// Normalized finish reasons and per-provider mapping
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// FinishReason is the normalized reason a choice stopped generating. Providers map
+// their own values with NormalizeFinishReason and keep the original value in the
+// RawFinishReason field of choices and done events.
+type FinishReason string
+
+// Normalized finish reasons
+const (
+	FinishReasonStop          FinishReason = "stop"           // natural end or stop sequence
+	FinishReasonLength        FinishReason = "length"         // output token limit reached
+	FinishReasonToolCalls     FinishReason = "tool_calls"     // the model requested tool calls
+	FinishReasonContentFilter FinishReason = "content_filter" // output withheld by safety filters
+	FinishReasonError         FinishReason = "error"          // generation failed (e.g. malformed tool call)
+	FinishReasonOther         FinishReason = "other"          // any other provider reason
+)
+
+// IsValid reports whether the finish reason is one of the normalized values
+func (r FinishReason) IsValid() bool {
+	switch r {
+	case FinishReasonStop, FinishReasonLength, FinishReasonToolCalls,
+		FinishReasonContentFilter, FinishReasonError, FinishReasonOther:
+		return true
+	}
+	return false
+}
+
+// defaultFinishReasons maps the OpenAI-style values used by most providers and
+// OpenAI-compatible APIs
+var defaultFinishReasons = map[string]FinishReason{
+	"stop":           FinishReasonStop,
+	"length":         FinishReasonLength,
+	"tool_calls":     FinishReasonToolCalls,
+	"function_call":  FinishReasonToolCalls,
+	"content_filter": FinishReasonContentFilter,
+	"error":          FinishReasonError,
+}
+
+// anthropicFinishReasons maps the Anthropic Messages API stop reasons
+var anthropicFinishReasons = map[string]FinishReason{
+	"end_turn":      FinishReasonStop,
+	"stop_sequence": FinishReasonStop,
+	"max_tokens":    FinishReasonLength,
+	"tool_use":      FinishReasonToolCalls,
+	"refusal":       FinishReasonContentFilter,
+}
+
+// finishReasonRegistry holds the per-provider mapping tables, keyed by provider and raw
+// value
+type finishReasonRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]FinishReason
+}
+
+var globalFinishReasons = &finishReasonRegistry{
+	tables: map[string]map[string]FinishReason{
+		"openai":    defaultFinishReasons,
+		"anthropic": anthropicFinishReasons,
+		"gemini": {
+			"STOP":                     FinishReasonStop,
+			"MAX_TOKENS":               FinishReasonLength,
+			"SAFETY":                   FinishReasonContentFilter,
+			"RECITATION":               FinishReasonContentFilter,
+			"BLOCKLIST":                FinishReasonContentFilter,
+			"PROHIBITED_CONTENT":       FinishReasonContentFilter,
+			"SPII":                     FinishReasonContentFilter,
+			"IMAGE_SAFETY":             FinishReasonContentFilter,
+			"IMAGE_PROHIBITED_CONTENT": FinishReasonContentFilter,
+			"MALFORMED_FUNCTION_CALL":  FinishReasonError,
+			"UNEXPECTED_TOOL_CALL":     FinishReasonError,
+		},
+		"bedrock": mergeFinishReasons(anthropicFinishReasons, map[string]FinishReason{
+			// Titan completion reasons
+			"FINISH":           FinishReasonStop,
+			"LENGTH":           FinishReasonLength,
+			"CONTENT_FILTERED": FinishReasonContentFilter,
+			// Llama stop reasons
+			"stop":   FinishReasonStop,
+			"length": FinishReasonLength,
+		}),
+		"ollama": {
+			"stop":   FinishReasonStop,
+			"length": FinishReasonLength,
+		},
+		"watsonx": mergeFinishReasons(defaultFinishReasons, map[string]FinishReason{
+			// Text generation stop reasons, also reported by some chat models
+			"eos_token":     FinishReasonStop,
+			"stop_sequence": FinishReasonStop,
+			"max_tokens":    FinishReasonLength,
+			"token_limit":   FinishReasonLength,
+			"time_limit":    FinishReasonLength,
+			"cancelled":     FinishReasonOther,
+		}),
+	},
+}
+
+// mergeFinishReasons returns the union of mapping tables, later tables winning
+func mergeFinishReasons(tables ...map[string]FinishReason) map[string]FinishReason {
+	merged := make(map[string]FinishReason)
+	for _, table := range tables {
+		for raw, reason := range table {
+			merged[raw] = reason
+		}
+	}
+	return merged
+}
+
+// RegisterFinishReasons adds mappings from raw provider values to normalized finish
+// reasons, e.g. for a custom provider. Existing mappings of the provider are kept
+// unless overridden.
+func RegisterFinishReasons(provider string, mapping map[string]FinishReason) {
+	provider = strings.ToLower(provider)
+	globalFinishReasons.mu.Lock()
+	defer globalFinishReasons.mu.Unlock()
+	globalFinishReasons.tables[provider] = mergeFinishReasons(globalFinishReasons.tables[provider], mapping)
+}
+
+// NormalizeFinishReason maps a raw finish reason reported by a provider to its
+// normalized value. Providers without a table of their own, and values missing from
+// it, use the OpenAI-style values. Empty values stay empty (the choice has not
+// finished) and unknown values map to FinishReasonOther.
+func NormalizeFinishReason(provider, raw string) FinishReason {
+	if raw == "" {
+		return ""
+	}
+	globalFinishReasons.mu.RLock()
+	reason, ok := globalFinishReasons.tables[strings.ToLower(provider)][raw]
+	globalFinishReasons.mu.RUnlock()
+	if ok {
+		return reason
+	}
+	if reason, ok := defaultFinishReasons[strings.ToLower(raw)]; ok {
+		return reason
+	}
+	return FinishReasonOther
+}
+
+// SetFinishReason sets the normalized finish reason of the choice from the raw provider
+// value, keeping the raw value
+func (c *Choice) SetFinishReason(provider, raw string) {
+	c.FinishReason = NormalizeFinishReason(provider, raw)
+	c.RawFinishReason = raw
+}
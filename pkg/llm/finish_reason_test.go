@@ -0,0 +1,47 @@
+package llm
+
+import "testing"
+
+func TestNormalizeFinishReason(t *testing.T) {
+	tests := []struct {
+		provider, raw string
+		want          FinishReason
+	}{
+		{"openai", "stop", FinishReasonStop},
+		{"openai", "function_call", FinishReasonToolCalls},
+		{"anthropic", "end_turn", FinishReasonStop},
+		{"anthropic", "tool_use", FinishReasonToolCalls},
+		{"bedrock", "max_tokens", FinishReasonLength},
+		{"bedrock", "CONTENT_FILTERED", FinishReasonContentFilter},
+		{"gemini", "SAFETY", FinishReasonContentFilter},
+		{"Gemini", "MAX_TOKENS", FinishReasonLength},
+		{"gemini", "MALFORMED_FUNCTION_CALL", FinishReasonError},
+		{"gemini", "LANGUAGE", FinishReasonOther},
+		{"openrouter", "tool_calls", FinishReasonToolCalls}, // OpenAI-compatible fallback
+		{"ollama", "load", FinishReasonOther},
+		{"openai", "", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFinishReason(tt.provider, tt.raw); got != tt.want {
+			t.Errorf("NormalizeFinishReason(%q, %q) = %q, want %q", tt.provider, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterFinishReasons(t *testing.T) {
+	RegisterFinishReasons("custom-provider", map[string]FinishReason{"COMPLETE": FinishReasonStop})
+
+	choice := Choice{}
+	choice.SetFinishReason("custom-provider", "COMPLETE")
+	if choice.FinishReason != FinishReasonStop || choice.RawFinishReason != "COMPLETE" {
+		t.Errorf("Unexpected finish reason %q (raw %q)", choice.FinishReason, choice.RawFinishReason)
+	}
+
+	event := NewProviderDoneEvent(0, "custom-provider", "TRUNCATED")
+	if event.Choice.FinishReason != FinishReasonOther || event.Choice.RawFinishReason != "TRUNCATED" {
+		t.Errorf("Unexpected done event: %+v", event.Choice)
+	}
+	if !FinishReasonContentFilter.IsValid() || FinishReason("end_turn").IsValid() {
+		t.Error("Unexpected IsValid result")
+	}
+}
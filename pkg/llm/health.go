@@ -0,0 +1,183 @@
+// Health statistics collected from client calls
+package llm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHealthySuccessRate is the smoothed success rate below which a client is
+	// reported as unhealthy
+	DefaultHealthySuccessRate = 0.5
+
+	// DefaultHealthSmoothing is the weight given to the latest call in the smoothed success rate
+	DefaultHealthSmoothing = 0.2
+
+	// DefaultLatencyWindow is the number of recent calls used to compute latency percentiles
+	DefaultLatencyWindow = 100
+)
+
+// HealthStats collects rolling health statistics of a client: an exponentially smoothed
+// success rate, latency percentiles over a window of recent calls and the last error.
+// Providers update it on every call and report it from GetRemote. It is safe for
+// concurrent use.
+type HealthStats struct {
+	mu sync.Mutex
+
+	smoothing   float64
+	successRate float64
+	requests    int64
+	failures    int64
+
+	latencies []time.Duration
+	next      int
+
+	lastError   *Error
+	lastErrorAt time.Time
+
+	clock Clock
+}
+
+// NewHealthStats creates a collector with the default smoothing and latency window
+func NewHealthStats() *HealthStats {
+	return NewHealthStatsWithClock(nil)
+}
+
+// NewHealthStatsWithClock is NewHealthStats with the clock timing the errors (the system
+// clock when nil). Latencies are always measured with the system clock.
+func NewHealthStatsWithClock(clock Clock) *HealthStats {
+	return &HealthStats{
+		smoothing: DefaultHealthSmoothing,
+		latencies: make([]time.Duration, 0, DefaultLatencyWindow),
+		clock:     clockOrSystem(clock),
+	}
+}
+
+// Record adds the outcome of a call. A zero latency (e.g. for health checks) only
+// updates the success rate and errors.
+func (h *HealthStats) Record(latency time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	outcome := 1.0
+	if err != nil {
+		outcome = 0
+		h.failures++
+		h.lastError = toHealthError(err)
+		h.lastErrorAt = h.clock.Now()
+	}
+
+	if h.requests == 0 {
+		h.successRate = outcome
+	} else {
+		h.successRate = h.smoothing*outcome + (1-h.smoothing)*h.successRate
+	}
+	h.requests++
+
+	if latency > 0 {
+		if len(h.latencies) < cap(h.latencies) {
+			h.latencies = append(h.latencies, latency)
+		} else {
+			h.latencies[h.next] = latency
+		}
+		h.next = (h.next + 1) % cap(h.latencies)
+	}
+}
+
+// Track records a call that started at the given time. Calls cancelled by the caller
+// and dry runs are not recorded since they say nothing about the provider.
+func (h *HealthStats) Track(ctx context.Context, start time.Time, err error) {
+	if errors.Is(ctx.Err(), context.Canceled) || IsDryRun(ctx) {
+		return
+	}
+	h.Record(time.Since(start), err)
+}
+
+// TrackStream forwards a stream and records it once it ends, using the time until the
+// last event as latency and the last error event (if any) as outcome. Like
+// ClassifyStream, it classifies the error events and guarantees that the stream ends
+// with a terminal event.
+func (h *HealthStats) TrackStream(ctx context.Context, start time.Time, stream <-chan StreamEvent) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	classifier := newStreamClassifier(start)
+
+	go func() {
+		defer close(out)
+
+		var streamErr error
+		for event := range stream {
+			event = classifier.observe(event)
+			if event.IsError() {
+				streamErr = event.Error
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				// Keep draining so the producer can finish
+				for range stream {
+				}
+				return
+			}
+		}
+		if event, ok := classifier.terminal(ctx); ok {
+			streamErr = event.Error
+			select {
+			case out <- event:
+			case <-ctx.Done():
+			}
+		}
+		h.Track(ctx, start, streamErr)
+	}()
+
+	return out
+}
+
+// Status returns a snapshot of the statistics
+func (h *HealthStats) Status() *ClientRemoteInfoStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := &ClientRemoteInfoStatus{
+		SuccessRate: h.successRate,
+		Requests:    h.requests,
+		Failures:    h.failures,
+		LastError:   h.lastError,
+	}
+	if !h.lastErrorAt.IsZero() {
+		lastErrorAt := h.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+
+	if len(h.latencies) > 0 {
+		sorted := append([]time.Duration(nil), h.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		status.LatencyP50 = percentile(sorted, 0.50)
+		status.LatencyP95 = percentile(sorted, 0.95)
+	}
+
+	return status
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// toHealthError converts an error to an *Error for reporting
+func toHealthError(err error) *Error {
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		return llmErr
+	}
+	return &Error{
+		Code:    "unknown_error",
+		Message: err.Error(),
+		Type:    "api_error",
+	}
+}
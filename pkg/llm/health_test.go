@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthStats_SuccessRate(t *testing.T) {
+	h := NewHealthStats()
+
+	if status := h.Status(); status.Requests != 0 || !status.IsHealthy() {
+		t.Errorf("Expected empty stats to be healthy, got %+v", status)
+	}
+
+	h.Record(10*time.Millisecond, nil)
+	if status := h.Status(); status.SuccessRate != 1 {
+		t.Errorf("Expected first success to seed the rate with 1, got %v", status.SuccessRate)
+	}
+
+	// Each failure reduces the rate by the smoothing factor
+	h.Record(10*time.Millisecond, &Error{Code: "rate_limit", Message: "slow down", Type: "rate_limit_error"})
+	status := h.Status()
+	if diff := status.SuccessRate - 0.8; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected success rate 0.8, got %v", status.SuccessRate)
+	}
+	if status.Requests != 2 || status.Failures != 1 {
+		t.Errorf("Expected 2 requests and 1 failure, got %d/%d", status.Requests, status.Failures)
+	}
+	if status.LastError == nil || status.LastError.Code != "rate_limit" || status.LastErrorAt == nil {
+		t.Errorf("Expected last error to be recorded, got %+v", status)
+	}
+
+	for i := 0; i < 5; i++ {
+		h.Record(0, errors.New("connection refused"))
+	}
+	status = h.Status()
+	if status.IsHealthy() {
+		t.Errorf("Expected client to be unhealthy after repeated failures, rate %v", status.SuccessRate)
+	}
+	if status.LastError.Code != "unknown_error" || status.LastError.Message != "connection refused" {
+		t.Errorf("Expected plain errors to be wrapped, got %+v", status.LastError)
+	}
+
+	for i := 0; i < 10; i++ {
+		h.Record(0, nil)
+	}
+	if !h.Status().IsHealthy() {
+		t.Errorf("Expected client to recover after successes, rate %v", h.Status().SuccessRate)
+	}
+}
+
+func TestHealthStats_Latency(t *testing.T) {
+	h := NewHealthStats()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i)*time.Millisecond, nil)
+	}
+
+	status := h.Status()
+	if status.LatencyP50 != 50*time.Millisecond || status.LatencyP95 != 95*time.Millisecond {
+		t.Errorf("Expected p50=50ms p95=95ms, got %s/%s", status.LatencyP50, status.LatencyP95)
+	}
+
+	// Older calls fall out of the window
+	for i := 0; i < DefaultLatencyWindow; i++ {
+		h.Record(time.Second, nil)
+	}
+	if status := h.Status(); status.LatencyP50 != time.Second {
+		t.Errorf("Expected p50=1s after the window rolled over, got %s", status.LatencyP50)
+	}
+
+	// Zero latencies (health checks) do not affect percentiles
+	h.Record(0, nil)
+	if status := h.Status(); status.LatencyP50 != time.Second {
+		t.Errorf("Expected zero latency to be ignored, got %s", status.LatencyP50)
+	}
+}
+
+func TestHealthStats_Track(t *testing.T) {
+	h := NewHealthStats()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.Track(ctx, time.Now(), context.Canceled)
+	if status := h.Status(); status.Requests != 0 {
+		t.Errorf("Expected cancelled calls not to be recorded, got %d requests", status.Requests)
+	}
+
+	h.Track(context.Background(), time.Now().Add(-time.Second), nil)
+	if status := h.Status(); status.Requests != 1 || status.LatencyP50 < time.Second {
+		t.Errorf("Expected call to be recorded with its latency, got %+v", status)
+	}
+}
+
+func TestHealthStats_TrackStream(t *testing.T) {
+	h := NewHealthStats()
+
+	stream := make(chan StreamEvent, 3)
+	stream <- NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("Hi")}})
+	stream <- NewErrorEvent(&Error{Code: "server_error", Message: "boom", Type: "api_error"})
+	close(stream)
+
+	var events int
+	for range h.TrackStream(context.Background(), time.Now(), stream) {
+		events++
+	}
+	if events != 2 {
+		t.Errorf("Expected 2 forwarded events, got %d", events)
+	}
+
+	status := h.Status()
+	if status.Requests != 1 || status.Failures != 1 || status.LastError == nil || status.LastError.Code != "server_error" {
+		t.Errorf("Expected stream error to be recorded, got %+v", status)
+	}
+}
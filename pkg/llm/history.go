@@ -0,0 +1,388 @@
+// Queryable in-memory history of the requests and responses of a client
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryConfig configures a HistoryMiddleware
+type HistoryConfig struct {
+	// Capacity is the number of entries kept; the oldest ones are dropped first
+	Capacity int `json:"capacity"`
+
+	// Persist, when set, receives every entry as a line of JSON, so the history survives
+	// restarts (see HistoryMiddleware.Load). Write errors are ignored.
+	Persist io.Writer `json:"-"`
+
+	// HashChain makes every entry carry a SHA-256 hash of its content and of the hash of
+	// the previous entry, so changes, insertions and removals of entries of an exported
+	// history are detected by VerifyHistory
+	HashChain bool `json:"hash_chain,omitempty"`
+
+	// SigningKey, when set, signs the hash of every entry with HMAC-SHA256, so the chain
+	// cannot be recomputed by someone without the key. It implies HashChain.
+	SigningKey []byte `json:"-"`
+}
+
+// DefaultHistoryConfig returns the default history configuration
+func DefaultHistoryConfig() *HistoryConfig {
+	return &HistoryConfig{Capacity: 100}
+}
+
+// HistoryEntry is a request recorded by a HistoryMiddleware, with its outcome
+type HistoryEntry struct {
+	ID       uint64        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Model    string        `json:"model"`
+	Stream   bool          `json:"stream,omitempty"`
+	Request  ChatRequest   `json:"request"`
+
+	// Response is the response, assembled from the events for streams
+	Response *ChatResponse `json:"response,omitempty"`
+
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+	ErrorType string `json:"error_type,omitempty"`
+
+	// PrevHash, Hash and Signature chain and sign the entries (see HistoryConfig.HashChain)
+	PrevHash  string `json:"prev_hash,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Failed reports whether the request failed
+func (e HistoryEntry) Failed() bool {
+	return e.Error != ""
+}
+
+// HistoryQuery selects history entries. Zero fields match every entry.
+type HistoryQuery struct {
+	Model     string    `json:"model,omitempty"`
+	ErrorType string    `json:"error_type,omitempty"`
+	Failed    bool      `json:"failed,omitempty"` // only failed requests
+	Since     time.Time `json:"since,omitempty"`
+	Until     time.Time `json:"until,omitempty"`
+	Limit     int       `json:"limit,omitempty"` // the most recent entries, when positive
+}
+
+// matches reports whether an entry is selected by the query
+func (q HistoryQuery) matches(entry HistoryEntry) bool {
+	switch {
+	case q.Model != "" && entry.Model != q.Model:
+		return false
+	case q.ErrorType != "" && entry.ErrorType != q.ErrorType:
+		return false
+	case q.Failed && !entry.Failed():
+		return false
+	case !q.Since.IsZero() && entry.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !entry.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// pendingRequest is a request sent and not answered yet
+type pendingRequest struct {
+	req   *ChatRequest
+	start time.Time
+}
+
+// HistoryMiddleware keeps the last requests of a client with their responses or errors,
+// in a ring buffer that can be queried, to debug live systems without a tracing
+// infrastructure:
+//
+//	history := llm.NewHistoryMiddleware(&llm.HistoryConfig{Capacity: 500})
+//	client := llm.NewEnhancedClient(base, []llm.Middleware{history})
+//	...
+//	for _, entry := range history.Query(llm.HistoryQuery{ErrorType: "rate_limit_error", Since: time.Now().Add(-time.Hour)}) {
+//	    log.Printf("%s %s: %s", entry.Time, entry.Model, entry.Error)
+//	}
+//
+// Requests are recorded as sent, after the middleware preceding it in the chain. Put it
+// last to measure the duration of requests modified by the other middleware.
+type HistoryMiddleware struct {
+	config *HistoryConfig
+
+	mu          sync.Mutex
+	entries     []HistoryEntry // ring buffer
+	next        int            // position of the next entry
+	lastID      uint64
+	lastHash    string           // hash of the last entry, for chained histories
+	pending     []pendingRequest // ring buffer of requests in flight
+	pendingNext int
+}
+
+// NewHistoryMiddleware creates a history, filling unset config fields with defaults
+func NewHistoryMiddleware(config *HistoryConfig) *HistoryMiddleware {
+	if config == nil {
+		config = DefaultHistoryConfig()
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultHistoryConfig().Capacity
+	}
+	return &HistoryMiddleware{
+		config:  config,
+		entries: make([]HistoryEntry, 0, config.Capacity),
+		pending: make([]pendingRequest, config.Capacity),
+	}
+}
+
+// Name returns the middleware name
+func (m *HistoryMiddleware) Name() string {
+	return "history"
+}
+
+// ProcessRequest notes the time the request is sent
+func (m *HistoryMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[m.pendingNext] = pendingRequest{req: req, start: time.Now()}
+	m.pendingNext = (m.pendingNext + 1) % len(m.pending)
+	return req, nil
+}
+
+// ProcessResponse records the request with its response or error. The completion of
+// streams, without response nor error, is recorded by the stream processor instead.
+func (m *HistoryMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if resp == nil && err == nil {
+		return resp, err
+	}
+	entry := HistoryEntry{Response: resp}
+	if err != nil {
+		entry.setError(err)
+	}
+	m.record(req, entry)
+	return resp, err
+}
+
+// ProcessStreamEvent passes events through unchanged; streams are recorded by the
+// processors created by NewStreamProcessor
+func (m *HistoryMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// NewStreamProcessor creates a processor assembling the response of a stream, recorded
+// when the stream ends
+func (m *HistoryMiddleware) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	return &historyStreamProcessor{history: m, req: req, resp: &ChatResponse{}}
+}
+
+// Entries returns all the entries, oldest first
+func (m *HistoryMiddleware) Entries() []HistoryEntry {
+	return m.Query(HistoryQuery{})
+}
+
+// Query returns the entries selected by the query, oldest first
+func (m *HistoryMiddleware) Query(query HistoryQuery) []HistoryEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []HistoryEntry
+	for i := range m.entries {
+		entry := m.entries[(m.next+i)%len(m.entries)]
+		if query.matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[len(result)-query.Limit:]
+	}
+	return result
+}
+
+// ByModel returns the entries of a model, oldest first
+func (m *HistoryMiddleware) ByModel(model string) []HistoryEntry {
+	return m.Query(HistoryQuery{Model: model})
+}
+
+// ByErrorType returns the requests that failed with an error type (e.g.
+// "rate_limit_error"), oldest first
+func (m *HistoryMiddleware) ByErrorType(errorType string) []HistoryEntry {
+	return m.Query(HistoryQuery{ErrorType: errorType})
+}
+
+// Between returns the requests sent in a time range, oldest first
+func (m *HistoryMiddleware) Between(since, until time.Time) []HistoryEntry {
+	return m.Query(HistoryQuery{Since: since, Until: until})
+}
+
+// Clear removes all the entries
+func (m *HistoryMiddleware) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = m.entries[:0]
+	m.next = 0
+}
+
+// Load adds the entries persisted as JSON lines (see HistoryConfig.Persist), keeping the
+// most recent ones when there are more than the capacity. Chained histories continue the
+// chain of the last entry loaded.
+func (m *HistoryMiddleware) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.add(entry)
+		m.lastID = max(m.lastID, entry.ID)
+		if entry.Hash != "" {
+			m.lastHash = entry.Hash
+		}
+		m.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// record adds an entry for a request, measuring its duration from the time it was sent
+func (m *HistoryMiddleware) record(req *ChatRequest, entry HistoryEntry) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.Time = now
+	for i, pending := range m.pending {
+		if pending.req == req && req != nil {
+			entry.Time = pending.start
+			entry.Duration = now.Sub(pending.start)
+			m.pending[i] = pendingRequest{}
+			break
+		}
+	}
+	if req != nil {
+		entry.Request = *req
+		entry.Stream = entry.Stream || req.Stream
+		entry.Model = req.Model
+	}
+	if entry.Response != nil && entry.Response.Model != "" {
+		entry.Model = entry.Response.Model
+	}
+	m.lastID++
+	entry.ID = m.lastID
+	if m.config.HashChain || len(m.config.SigningKey) > 0 {
+		m.seal(&entry)
+	}
+	m.add(entry)
+
+	if m.config.Persist != nil {
+		if data, err := json.Marshal(entry); err == nil {
+			_, _ = m.config.Persist.Write(append(data, '\n'))
+		}
+	}
+}
+
+// add adds an entry to the ring buffer. Called with the lock held.
+func (m *HistoryMiddleware) add(entry HistoryEntry) {
+	if len(m.entries) < m.config.Capacity {
+		m.entries = append(m.entries, entry)
+		return
+	}
+	m.entries[m.next] = entry
+	m.next = (m.next + 1) % len(m.entries)
+}
+
+// setError sets the error of an entry
+func (e *HistoryEntry) setError(err error) {
+	e.Error = err.Error()
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		e.ErrorCode = llmErr.Code
+		e.ErrorType = llmErr.Type
+	}
+}
+
+// historyStreamProcessor assembles the response of a stream for the history
+type historyStreamProcessor struct {
+	history *HistoryMiddleware
+	req     *ChatRequest
+	resp    *ChatResponse
+	err     error
+	text    map[int]*strings.Builder
+}
+
+// Process adds an event to the response
+func (p *historyStreamProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	switch {
+	case event.IsError():
+		p.err = event.Error
+	case event.Choice != nil:
+		choice := p.choice(event.Choice.Index)
+		if event.Choice.Delta != nil {
+			if p.text == nil {
+				p.text = make(map[int]*strings.Builder)
+			}
+			if p.text[event.Choice.Index] == nil {
+				p.text[event.Choice.Index] = &strings.Builder{}
+			}
+			for _, content := range event.Choice.Delta.Content {
+				if text, ok := content.(*TextContent); ok {
+					p.text[event.Choice.Index].WriteString(text.Text)
+				}
+			}
+			choice.Message.Reasoning += event.Choice.Delta.Reasoning
+			addToolCallDeltas(&choice.Message, event.Choice.Delta.ToolCalls)
+		}
+		if event.Choice.FinishReason != "" {
+			choice.FinishReason = event.Choice.FinishReason
+			choice.RawFinishReason = event.Choice.RawFinishReason
+		}
+	}
+	return []StreamEvent{event}, nil
+}
+
+// Flush records the stream
+func (p *historyStreamProcessor) Flush() ([]StreamEvent, error) {
+	for i := range p.resp.Choices {
+		if text := p.text[p.resp.Choices[i].Index]; text != nil {
+			p.resp.Choices[i].Message.Content = []MessageContent{NewTextContent(text.String())}
+		}
+	}
+	entry := HistoryEntry{Stream: true, Response: p.resp}
+	if p.err != nil {
+		entry.setError(p.err)
+	}
+	p.history.record(p.req, entry)
+	return nil, nil
+}
+
+// choice returns the choice of the response with an index, adding it
+func (p *historyStreamProcessor) choice(index int) *Choice {
+	for i := range p.resp.Choices {
+		if p.resp.Choices[i].Index == index {
+			return &p.resp.Choices[i]
+		}
+	}
+	p.resp.Choices = append(p.resp.Choices, Choice{Index: index, Message: Message{Role: RoleAssistant}})
+	return &p.resp.Choices[len(p.resp.Choices)-1]
+}
+
+// addToolCallDeltas merges streamed tool call fragments into a message
+func addToolCallDeltas(message *Message, deltas []ToolCallDelta) {
+	for _, delta := range deltas {
+		for len(message.ToolCalls) <= delta.Index {
+			message.ToolCalls = append(message.ToolCalls, ToolCall{Type: "function"})
+		}
+		call := &message.ToolCalls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function != nil {
+			call.Function.Name += delta.Function.Name
+			call.Function.Arguments += delta.Function.Arguments
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Tamper-evident histories: hash chaining and HMAC signing of the entries
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// seal chains an entry to the previous one and signs it. Called with the lock held.
+func (m *HistoryMiddleware) seal(entry *HistoryEntry) {
+	entry.PrevHash = m.lastHash
+	hash, err := historyEntryHash(*entry)
+	if err != nil {
+		// Left unsealed, so VerifyHistory reports it instead of trusting it
+		return
+	}
+	entry.Hash = hash
+	if len(m.config.SigningKey) > 0 {
+		entry.Signature = historySignature(m.config.SigningKey, hash)
+	}
+	m.lastHash = hash
+}
+
+// VerifyHistory checks the chain of hashes of entries recorded with
+// HistoryConfig.HashChain, oldest first, and their signatures when key is not nil. The
+// first entry may link to entries that are not given, e.g. those dropped from the ring
+// buffer, so the removal of the oldest entries of an export is only detected by checking
+// the whole log (see VerifyHistoryLog). It fails with an "integrity_error" error telling
+// the first entry that does not match.
+func VerifyHistory(entries []HistoryEntry, key []byte) error {
+	for i, entry := range entries {
+		if entry.Hash == "" {
+			return historyIntegrityError(entry, "is not sealed")
+		}
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			return historyIntegrityError(entry, fmt.Sprintf("does not follow entry %d", entries[i-1].ID))
+		}
+		hash, err := historyEntryHash(entry)
+		if err != nil || hash != entry.Hash {
+			return historyIntegrityError(entry, "was modified")
+		}
+		if key != nil && !hmac.Equal([]byte(entry.Signature), []byte(historySignature(key, hash))) {
+			return historyIntegrityError(entry, "has an invalid signature")
+		}
+	}
+	return nil
+}
+
+// VerifyHistoryLog checks a whole history persisted as JSON lines (see
+// HistoryConfig.Persist) like VerifyHistory, also requiring its first entry to start the
+// chain
+func VerifyHistoryLog(r io.Reader, key []byte) error {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return &Error{
+				Code:    "integrity_error",
+				Message: fmt.Sprintf("history entry %d cannot be decoded: %v", len(entries)+1, err),
+				Type:    "validation_error",
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(entries) > 0 && entries[0].PrevHash != "" {
+		return historyIntegrityError(entries[0], "does not start the history")
+	}
+	return VerifyHistory(entries, key)
+}
+
+// historyEntryHash returns the hex SHA-256 of the previous hash and the canonical JSON of
+// an entry, without its hash and signature. The JSON is canonical (object keys sorted) so
+// the hash of an entry decoded from a persisted history matches the one it was sealed
+// with, even when metadata structs became maps.
+func historyEntryHash(entry HistoryEntry) (string, error) {
+	entry.Hash, entry.Signature = "", ""
+	data, err := canonicalJSON(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	sum.Write([]byte(entry.PrevHash))
+	sum.Write([]byte{'\n'})
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// historySignature returns the hex HMAC-SHA256 of a hash
+func historySignature(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalJSON encodes a value as JSON with the keys of all the objects sorted, keeping
+// numbers as written
+func canonicalJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// historyIntegrityError creates the error of an entry failing the verification
+func historyIntegrityError(entry HistoryEntry, problem string) *Error {
+	return &Error{
+		Code:    "integrity_error",
+		Message: fmt.Sprintf("history entry %d %s", entry.ID, problem),
+		Type:    "validation_error",
+	}
+}
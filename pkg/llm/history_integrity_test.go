@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// integrityCode returns the code of an integrity error
+func integrityCode(t *testing.T, err error) string {
+	t.Helper()
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr), "expected an *Error, got %v", err)
+	return llmErr.Code
+}
+
+func TestHistoryIntegrity(t *testing.T) {
+	key := []byte("audit-key")
+	var persisted bytes.Buffer
+	history := NewHistoryMiddleware(&HistoryConfig{Persist: &persisted, SigningKey: key})
+	mock := NewMockClient("mock-model", "mock")
+	// Metadata structs become maps once persisted, which must not change the hashes
+	turn := TurnInfo{Model: "mock-model", Usage: Usage{TotalTokens: 3}, Time: time.Now()}
+	mock.responses = []*ChatResponse{{
+		Model:    "mock-model",
+		Choices:  []Choice{{Message: NewTextMessage(RoleAssistant, "Approved")}},
+		Metadata: map[string]any{MetadataTurn: turn, "score": 0.1},
+	}}
+	client := NewEnhancedClient(mock, []Middleware{history})
+	for _, text := range []string{"Approve the loan?", "Why?", "Thanks"} {
+		_, err := client.ChatCompletion(context.Background(), ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, text)}})
+		require.NoError(t, err)
+	}
+
+	entries := history.Entries()
+	require.Len(t, entries, 3)
+	assert.Empty(t, entries[0].PrevHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.NotEmpty(t, entries[2].Signature)
+	require.NoError(t, VerifyHistory(entries, key))
+	require.NoError(t, VerifyHistoryLog(bytes.NewReader(persisted.Bytes()), key))
+
+	lines := strings.Split(strings.TrimSpace(persisted.String()), "\n")
+	require.Len(t, lines, 3)
+	verify := func(lines []string, key []byte) error {
+		return VerifyHistoryLog(strings.NewReader(strings.Join(lines, "\n")), key)
+	}
+
+	// Modified, removed and reordered entries are detected
+	modified := append([]string(nil), lines...)
+	modified[0] = strings.Replace(modified[0], "Approve the loan?", "Deny the loan?", 1)
+	err := verify(modified, key)
+	assert.Equal(t, "integrity_error", integrityCode(t, err))
+	assert.Contains(t, err.Error(), "history entry 1 was modified")
+
+	assert.Contains(t, verify([]string{lines[0], lines[2]}, key).Error(), "history entry 3 does not follow entry 1")
+	assert.Contains(t, verify(lines[1:], key).Error(), "history entry 2 does not start the history")
+
+	// The chain alone can be verified without the key, but not with another one
+	require.NoError(t, verify(lines, nil))
+	assert.Contains(t, verify(lines, []byte("other-key")).Error(), "invalid signature")
+
+	// Restored histories continue the chain
+	restored := NewHistoryMiddleware(&HistoryConfig{Persist: &persisted, SigningKey: key})
+	require.NoError(t, restored.Load(strings.NewReader(strings.Join(lines, "\n"))))
+	_, err = NewEnhancedClient(NewMockClient("mock-model", "mock"), []Middleware{restored}).
+		ChatCompletion(context.Background(), ChatRequest{Model: "m"})
+	require.NoError(t, err)
+	require.NoError(t, VerifyHistoryLog(bytes.NewReader(persisted.Bytes()), key))
+}
+
+func TestHistoryIntegrityUnsealed(t *testing.T) {
+	history := NewHistoryMiddleware(nil)
+	_, err := NewEnhancedClient(NewMockClient("mock-model", "mock"), []Middleware{history}).
+		ChatCompletion(context.Background(), ChatRequest{Model: "m"})
+	require.NoError(t, err)
+
+	entries := history.Entries()
+	assert.Empty(t, entries[0].Hash)
+	assert.Contains(t, VerifyHistory(entries, nil).Error(), "history entry 1 is not sealed")
+}
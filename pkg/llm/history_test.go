@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryMiddlewareRecordsRequests(t *testing.T) {
+	history := NewHistoryMiddleware(&HistoryConfig{Capacity: 3})
+	mock := NewMockClient("mock-model", "mock")
+	client := NewEnhancedClient(mock, []Middleware{history})
+	ctx := context.Background()
+
+	for _, model := range []string{"a", "b", "a"} {
+		_, err := client.ChatCompletion(ctx, ChatRequest{Model: model, Messages: []Message{NewTextMessage(RoleUser, "Hi")}})
+		require.NoError(t, err)
+	}
+	mock.errorToReturn = &Error{Code: "rate_limit_exceeded", Message: "slow down", Type: "rate_limit_error"}
+	_, err := client.ChatCompletion(ctx, ChatRequest{Model: "b"})
+	require.Error(t, err)
+
+	// The oldest entry was dropped
+	entries := history.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, []uint64{2, 3, 4}, []uint64{entries[0].ID, entries[1].ID, entries[2].ID})
+	assert.Equal(t, "Hi", entries[1].Request.Messages[0].GetText())
+	assert.NotNil(t, entries[1].Response)
+
+	failed := history.ByErrorType("rate_limit_error")
+	require.Len(t, failed, 1)
+	assert.Equal(t, "b", failed[0].Model)
+	assert.Equal(t, "rate_limit_exceeded", failed[0].ErrorCode)
+	assert.True(t, failed[0].Failed())
+
+	// Entries have the model of the response, or of the request without response
+	assert.Len(t, history.ByModel("mock-model"), 2)
+	assert.Len(t, history.Query(HistoryQuery{Model: "b"}), 1)
+	assert.Len(t, history.Query(HistoryQuery{Failed: true}), 1)
+	assert.Len(t, history.Query(HistoryQuery{Limit: 1}), 1)
+	assert.Len(t, history.Between(time.Now().Add(-time.Minute), time.Now().Add(time.Minute)), 3)
+	assert.Empty(t, history.Between(time.Now().Add(time.Minute), time.Time{}))
+
+	history.Clear()
+	assert.Empty(t, history.Entries())
+}
+
+func TestHistoryMiddlewareStreams(t *testing.T) {
+	history := NewHistoryMiddleware(nil)
+	mock := NewMockClient("mock-model", "mock")
+	client := NewEnhancedClient(mock, []Middleware{history})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatRequest{Model: "m"})
+	require.NoError(t, err)
+	for range stream {
+	}
+
+	entries := history.Entries()
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Stream)
+	require.NotNil(t, entries[0].Response)
+	require.Len(t, entries[0].Response.Choices, 1)
+	assert.NotEmpty(t, entries[0].Response.Choices[0].Message.GetText())
+	assert.NotEmpty(t, entries[0].Response.Choices[0].FinishReason)
+}
+
+func TestHistoryMiddlewarePersistence(t *testing.T) {
+	var persisted bytes.Buffer
+	history := NewHistoryMiddleware(&HistoryConfig{Persist: &persisted})
+	client := NewEnhancedClient(NewMockClient("mock-model", "mock"), []Middleware{history})
+	for i := 0; i < 2; i++ {
+		_, err := client.ChatCompletion(context.Background(), ChatRequest{Model: "m"})
+		require.NoError(t, err)
+	}
+
+	restored := NewHistoryMiddleware(nil)
+	require.NoError(t, restored.Load(&persisted))
+	entries := restored.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, history.Entries()[1].Time.UnixNano(), entries[1].Time.UnixNano())
+	assert.Equal(t, "m", entries[1].Request.Model)
+
+	// New entries continue the numbering
+	_, err := NewEnhancedClient(NewMockClient("mock-model", "mock"), []Middleware{restored}).
+		ChatCompletion(context.Background(), ChatRequest{Model: "m"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), restored.Entries()[2].ID)
+}
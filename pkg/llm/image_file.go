@@ -0,0 +1,326 @@
+// Images from local files, with metadata stripping
+package llm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// ImageFileOptions configures NewImageContentFromFile
+type ImageFileOptions struct {
+	// StripMetadata removes the EXIF (GPS location, camera, dates), XMP, IPTC and comment
+	// metadata of the image before it is sent (see StripImageMetadata)
+	StripMetadata bool `json:"strip_metadata,omitempty"`
+}
+
+// NewImageContentFromFile reads an image file, with its MIME type detected from its
+// content and its dimensions from its header. Files that are not JPEG, PNG, GIF or WebP
+// images fail with an "unsupported_image" error.
+//
+// Example:
+//
+//	img, err := llm.NewImageContentFromFile("photo.jpg", llm.ImageFileOptions{StripMetadata: true})
+//	if err != nil {
+//	    return err
+//	}
+//	msg := llm.Message{Role: llm.RoleUser, Content: []llm.MessageContent{llm.NewTextContent("What is this?"), img}}
+func NewImageContentFromFile(path string, opts ...ImageFileOptions) (*ImageContent, error) {
+	var options ImageFileOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	filename := filepath.Base(path)
+	mimeType := sniffMIME(data, filename)
+	if !IsValidImageMimeType(mimeType) {
+		return nil, &Error{
+			Code:    "unsupported_image",
+			Message: fmt.Sprintf("%s is not a supported image (%s)", filename, mimeType),
+			Type:    "validation_error",
+		}
+	}
+
+	if options.StripMetadata {
+		if data, err = StripImageMetadata(data, mimeType); err != nil {
+			return nil, err
+		}
+	}
+
+	img := NewImageContentFromBytes(data, mimeType)
+	img.Filename = filename
+	img.SetDimensions(decodeImageDimensions(data, mimeType))
+	return img, nil
+}
+
+// StripImageMetadata returns a copy of an image without its EXIF (GPS location, camera,
+// dates), XMP, IPTC and comment metadata. The image data is not re-encoded, so there is
+// no quality loss, and the metadata needed to display it is kept: the color profile
+// and, for JPEG images, the EXIF orientation. GIF images carry no such metadata and are
+// returned unchanged; other types fail with an "unsupported_image" error.
+func StripImageMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch NormalizeMIMEType(mimeType) {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	case "image/gif":
+		return data, nil
+	default:
+		return nil, &Error{
+			Code:    "unsupported_image",
+			Message: fmt.Sprintf("cannot strip the metadata of %s images", mimeType),
+			Type:    "validation_error",
+		}
+	}
+}
+
+// invalidImage returns the error of a corrupt image
+func invalidImage(format, reason string) error {
+	return &Error{
+		Code:    "invalid_image",
+		Message: fmt.Sprintf("invalid %s image: %s", format, reason),
+		Type:    "validation_error",
+	}
+}
+
+// stripJPEGMetadata drops the application segments other than JFIF, ICC profiles and
+// Adobe color information, and the comments, before the image scan
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, invalidImage("JPEG", "missing start of image")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	exifAt, orientation := len(out), 0
+
+	i := 2
+	for {
+		// Markers may be preceded by fill bytes
+		for i+1 < len(data) && data[i] == 0xFF && data[i+1] == 0xFF {
+			i++
+		}
+		if i+1 >= len(data) || data[i] != 0xFF {
+			return nil, invalidImage("JPEG", fmt.Sprintf("no marker at offset %d", i))
+		}
+		marker := data[i+1]
+
+		// The scan, up to the end of the image, holds no metadata
+		if marker == 0xDA || marker == 0xD9 {
+			out = append(out, data[i:]...)
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return nil, invalidImage("JPEG", "truncated segment")
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end < i+4 || end > len(data) {
+			return nil, invalidImage("JPEG", "truncated segment")
+		}
+		segment, payload := data[i:end], data[i+4:end]
+		i = end
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			if orientation == 0 {
+				orientation = exifOrientation(payload[6:])
+			}
+			continue
+		case marker == 0xE0:
+			// JFIF must stay the first segment, before the orientation
+			if exifAt == len(out) {
+				out = append(out, segment...)
+				exifAt = len(out)
+				continue
+			}
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")),
+			marker == 0xEE && bytes.HasPrefix(payload, []byte("Adobe")):
+		case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE:
+			continue
+		}
+		out = append(out, segment...)
+	}
+
+	if orientation > 1 {
+		exif := orientationEXIF(orientation)
+		out = append(out[:exifAt], append(exif, out[exifAt:]...)...)
+	}
+	return out, nil
+}
+
+// exifOrientation returns the orientation tag of the first IFD of EXIF data (a TIFF
+// structure), or zero
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		// Tag 0x0112 (orientation), of type SHORT
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientationEXIF returns an APP1 segment with EXIF data holding only the orientation
+func orientationEXIF(orientation int) []byte {
+	var segment bytes.Buffer
+	segment.Write([]byte{0xFF, 0xE1, 0x00, 34})
+	segment.WriteString("Exif\x00\x00")
+	// Big endian TIFF header, with the first IFD right after it
+	segment.Write([]byte{'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08})
+	// One entry: orientation (0x0112), SHORT, count 1, value padded to 4 bytes
+	segment.Write([]byte{0x00, 0x01, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, byte(orientation), 0x00, 0x00})
+	// No next IFD
+	segment.Write([]byte{0x00, 0x00, 0x00, 0x00})
+	return segment.Bytes()
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// strippedPNGChunks are the PNG chunks holding metadata
+var strippedPNGChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNGMetadata drops the EXIF, text and time chunks
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, invalidImage("PNG", "missing signature")
+	}
+
+	out := append(make([]byte, 0, len(data)), pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, invalidImage("PNG", "truncated chunk")
+		}
+		// Length, type, data and CRC
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:i+4]))
+		if end < i+12 || end > len(data) {
+			return nil, invalidImage("PNG", "truncated chunk")
+		}
+		chunkType := string(data[i+4 : i+8])
+		if !strippedPNGChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks, clearing their flags in the extended
+// header
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, invalidImage("WebP", "missing RIFF header")
+	}
+
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, invalidImage("WebP", "truncated chunk")
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		// Chunks are padded to an even size
+		end := i + 8 + size + size%2
+		if end < i+8 || end > len(data) {
+			return nil, invalidImage("WebP", "truncated chunk")
+		}
+		chunk := data[i:end]
+		i = end
+
+		switch string(chunk[:4]) {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			if size > 0 {
+				chunk = append([]byte(nil), chunk...)
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+		}
+		out = append(out, chunk...)
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// decodeImageDimensions returns the dimensions of an image from its header, or zeros
+func decodeImageDimensions(data []byte, mimeType string) (int, int) {
+	if mimeType == "image/webp" {
+		return webpDimensions(data)
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return config.Width, config.Height
+	}
+	return 0, 0
+}
+
+// webpDimensions returns the dimensions of a WebP image from its first chunk: the canvas
+// of extended images, or the frame of simple lossy and lossless ones
+func webpDimensions(data []byte) (int, int) {
+	if len(data) < 30 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0
+	}
+	payload := data[20:]
+	uint24 := func(b []byte) int { return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 }
+
+	switch string(data[12:16]) {
+	case "VP8X":
+		return uint24(payload[4:7]) + 1, uint24(payload[7:10]) + 1
+	case "VP8 ":
+		// Frame tag, then start code 9d 01 2a, then 14-bit dimensions
+		if !bytes.Equal(payload[3:6], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0
+		}
+		return int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3FFF), int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3FFF)
+	case "VP8L":
+		if payload[0] != 0x2F {
+			return 0, 0
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:5])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1
+	}
+	return 0, 0
+}
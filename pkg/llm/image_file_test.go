@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithMetadata returns a 40x30 JPEG image with EXIF data (orientation 6 and a fake
+// GPS payload) and a comment
+func jpegWithMetadata(t *testing.T) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil))
+
+	// Little endian TIFF with one orientation entry, followed by the fake GPS payload
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00,
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00}
+	tiff = append(tiff, "GPS 48.8584N 2.2945E"...)
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	comment := []byte("shot by Jane")
+
+	segment := func(marker byte, payload []byte) []byte {
+		return append([]byte{0xFF, marker, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	}
+	data := []byte{0xFF, 0xD8}
+	data = append(data, segment(0xE1, exif)...)
+	data = append(data, segment(0xFE, comment)...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// pngWithMetadata returns a 20x10 PNG image with a text chunk
+func pngWithMetadata(t *testing.T) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 20, 10))))
+
+	text := []byte("tEXtAuthor\x00Jane")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+
+	// After the signature and the IHDR chunk
+	data := append([]byte(nil), encoded.Bytes()[:33]...)
+	data = append(data, chunk...)
+	return append(data, encoded.Bytes()[33:]...)
+}
+
+func TestStripImageMetadata_JPEG(t *testing.T) {
+	stripped, err := StripImageMetadata(jpegWithMetadata(t), "image/jpg")
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "Jane")
+	assert.Contains(t, string(stripped), string(orientationEXIF(6)), "the orientation is kept")
+	assert.Equal(t, 6, exifOrientation(orientationEXIF(6)[10:]))
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, 40, config.Width)
+}
+
+func TestStripImageMetadata_PNG(t *testing.T) {
+	data := pngWithMetadata(t)
+	require.Contains(t, string(data), "Jane")
+
+	stripped, err := StripImageMetadata(data, "image/png")
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "Jane")
+
+	_, err = png.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestStripImageMetadata_WebP(t *testing.T) {
+	chunk := func(fourcc string, payload []byte) []byte {
+		data := binary.LittleEndian.AppendUint32([]byte(fourcc), uint32(len(payload)))
+		data = append(data, payload...)
+		if len(payload)%2 == 1 {
+			data = append(data, 0)
+		}
+		return data
+	}
+	// Extended header with the EXIF flag and a 300x200 canvas
+	vp8x := []byte{0x08, 0, 0, 0, 0x2B, 0x01, 0x00, 0xC7, 0x00, 0x00}
+	body := append([]byte("WEBP"), chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", []byte{0x2F, 0, 0, 0, 0})...)
+	body = append(body, chunk("EXIF", []byte("GPS 48.8584N"))...)
+	data := append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+
+	stripped, err := StripImageMetadata(data, "image/webp")
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.Equal(t, byte(0), stripped[20], "the EXIF flag is cleared")
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:8]))
+
+	width, height := webpDimensions(stripped)
+	assert.Equal(t, 300, width)
+	assert.Equal(t, 200, height)
+}
+
+func TestStripImageMetadata_Errors(t *testing.T) {
+	_, err := StripImageMetadata([]byte("not an image"), "image/jpeg")
+	assert.Equal(t, "invalid_image", err.(*Error).Code)
+
+	_, err = StripImageMetadata([]byte("%PDF-1.4"), "application/pdf")
+	assert.Equal(t, "unsupported_image", err.(*Error).Code)
+}
+
+func TestNewImageContentFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.bin")
+	require.NoError(t, os.WriteFile(path, jpegWithMetadata(t), 0o600))
+
+	img, err := NewImageContentFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.MimeType)
+	assert.Equal(t, "photo.bin", img.Filename)
+	assert.Equal(t, 40, img.Width)
+	assert.Equal(t, 30, img.Height)
+	assert.Contains(t, string(img.Data), "GPS")
+
+	img, err = NewImageContentFromFile(path, ImageFileOptions{StripMetadata: true})
+	require.NoError(t, err)
+	assert.NotContains(t, string(img.Data), "GPS")
+	assert.Equal(t, 40, img.Width)
+
+	textPath := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(textPath, []byte("hello"), 0o600))
+	_, err = NewImageContentFromFile(textPath)
+	assert.Equal(t, "unsupported_image", err.(*Error).Code)
+
+	_, err = NewImageContentFromFile(filepath.Join(dir, "missing.png"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,220 @@
+// Configurable extraction of JSON from responses, with diagnostics
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// JSONExtractionMode selects how ExtractJSONWithOptions finds the JSON of a response
+type JSONExtractionMode string
+
+const (
+	// JSONExtractionLenient tries fenced code blocks, inline code, then the JSON values
+	// of the text, in that order, as ExtractJSONFromResponse does (default)
+	JSONExtractionLenient JSONExtractionMode = "lenient"
+	// JSONExtractionStrict requires the whole response to be JSON, except for surrounding
+	// whitespace, and never repairs it
+	JSONExtractionStrict JSONExtractionMode = "strict"
+	// JSONExtractionFenced only considers markdown code blocks, taking the first one
+	// holding JSON
+	JSONExtractionFenced JSONExtractionMode = "fenced"
+	// JSONExtractionFirstObject takes the first JSON object or array of the text
+	JSONExtractionFirstObject JSONExtractionMode = "first_object"
+	// JSONExtractionLargestObject takes the longest JSON object or array of the text,
+	// skipping small examples and fragments quoted around the answer
+	JSONExtractionLargestObject JSONExtractionMode = "largest_object"
+)
+
+// JSONExtractionOptions configures ExtractJSONWithOptions
+type JSONExtractionOptions struct {
+	Mode JSONExtractionMode `json:"mode,omitempty"`
+}
+
+// JSONExtraction reports the JSON extracted from a response and what was stripped
+type JSONExtraction struct {
+	// JSON is the extracted JSON, empty when none was found
+	JSON string `json:"json"`
+
+	Mode JSONExtractionMode `json:"mode"`
+
+	// Source is where the JSON was found: "whole" (the whole response), "fenced" (a
+	// markdown code block), "inline_code" (a `code` span) or "inline" (the text)
+	Source string `json:"source,omitempty"`
+
+	// Prefix and Suffix are the text stripped before and after the JSON, including the
+	// code block markers, without the surrounding whitespace
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+
+	// Repaired reports that comments or trailing commas were removed to make the JSON valid
+	Repaired bool `json:"repaired,omitempty"`
+
+	// Candidates is the number of JSON objects and arrays found in the response (nested
+	// values are not counted). More than one means the choice depends on the mode.
+	Candidates int `json:"candidates"`
+}
+
+// jsonSpan is a JSON value found at text[start:end]
+type jsonSpan struct {
+	start, end int
+	json       string
+	repaired   bool
+}
+
+// lenientPatterns are the code patterns tried by the lenient mode, in order
+var lenientPatterns = []struct {
+	re     *regexp.Regexp
+	source string
+}{
+	// Markdown code blocks with a JSON-like language, then any language
+	{regexp.MustCompile("```json\\s*([\\s\\S]*?)```"), "fenced"},
+	{regexp.MustCompile("```JSON\\s*([\\s\\S]*?)```"), "fenced"},
+	{regexp.MustCompile("```javascript\\s*([\\s\\S]*?)```"), "fenced"},
+	{regexp.MustCompile("```js\\s*([\\s\\S]*?)```"), "fenced"},
+	{regexp.MustCompile("```\\w*\\s*([\\s\\S]*?)```"), "fenced"},
+	// Single backticks
+	{regexp.MustCompile("`([^`]+)`"), "inline_code"},
+}
+
+// ExtractJSONWithOptions extracts the JSON of a response according to the mode, reporting
+// where it was found and what was stripped. When no JSON is found, it returns the
+// diagnostics with a "json_not_found" error.
+//
+// Example:
+//
+//	extraction, err := llm.ExtractJSONWithOptions(response, llm.JSONExtractionOptions{
+//	    Mode: llm.JSONExtractionLargestObject,
+//	})
+//	if err == nil && extraction.Candidates > 1 {
+//	    log.Printf("picked 1 of %d JSON values, stripped %q", extraction.Candidates, extraction.Prefix)
+//	}
+func ExtractJSONWithOptions(text string, opts JSONExtractionOptions) (*JSONExtraction, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = JSONExtractionLenient
+	}
+	if mode != JSONExtractionStrict {
+		text = stripANSI(text)
+	}
+	text = strings.TrimSpace(text)
+
+	spans := topLevelJSONSpans(text)
+	result := &JSONExtraction{Mode: mode, Candidates: len(spans)}
+
+	var span jsonSpan
+	var found bool
+	switch mode {
+	case JSONExtractionLenient:
+		span, result.Source, found = lenientJSONSpan(text)
+	case JSONExtractionStrict:
+		if isValidJSONStart(text) && isValidJSON(text) {
+			span, result.Source, found = jsonSpan{start: 0, end: len(text), json: text}, "whole", true
+		}
+	case JSONExtractionFenced:
+		for _, loc := range fencedBlockPattern.FindAllStringSubmatchIndex(text, -1) {
+			if span, found = spanJSON(text, loc[2], loc[3]); found {
+				result.Source = "fenced"
+				break
+			}
+		}
+	case JSONExtractionFirstObject, JSONExtractionLargestObject:
+		for _, candidate := range spans {
+			if !found || (mode == JSONExtractionLargestObject && candidate.end-candidate.start > span.end-span.start) {
+				span, found = candidate, true
+			}
+		}
+		if found {
+			result.Source = "inline"
+			if span.start == 0 && span.end == len(text) {
+				result.Source = "whole"
+			}
+		}
+	default:
+		return nil, &Error{
+			Code:    "invalid_request",
+			Message: fmt.Sprintf("unknown JSON extraction mode %q", mode),
+			Type:    "validation_error",
+		}
+	}
+
+	if !found {
+		return result, &Error{
+			Code:    "json_not_found",
+			Message: fmt.Sprintf("no JSON found in the response with the %s mode (%d candidates)", mode, len(spans)),
+			Type:    "validation_error",
+		}
+	}
+
+	result.JSON, result.Repaired = span.json, span.repaired
+	result.Prefix = strings.TrimSpace(text[:span.start])
+	result.Suffix = strings.TrimSpace(text[span.end:])
+	return result, nil
+}
+
+// lenientJSONSpan finds the JSON of a text as ExtractJSONFromResponse does: in the first
+// match of each code pattern, then in the JSON blocks of the text, then in the whole text
+func lenientJSONSpan(text string) (jsonSpan, string, bool) {
+	for _, pattern := range lenientPatterns {
+		if loc := pattern.re.FindStringSubmatchIndex(text); loc != nil {
+			if span, ok := spanJSON(text, loc[2], loc[3]); ok {
+				return span, pattern.source, true
+			}
+		}
+	}
+	for _, block := range findJSONBlockSpans(text) {
+		if span, ok := spanJSON(text, block[0], block[1]); ok {
+			return span, "inline", true
+		}
+	}
+	if span, ok := spanJSON(text, 0, len(text)); ok {
+		return span, "whole", true
+	}
+	return jsonSpan{}, "", false
+}
+
+// topLevelJSONSpans returns the JSON objects and arrays of a text that are not nested in
+// another one, in order
+func topLevelJSONSpans(text string) []jsonSpan {
+	var spans []jsonSpan
+	for _, block := range findJSONBlockSpans(text) {
+		if len(spans) > 0 && block[0] < spans[len(spans)-1].end {
+			continue
+		}
+		if span, ok := spanJSON(text, block[0], block[1]); ok {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// spanJSON returns the JSON of text[start:end] without its surrounding whitespace,
+// repairing comments and trailing commas, or false when it is not JSON
+func spanJSON(text string, start, end int) (jsonSpan, bool) {
+	candidate := text[start:end]
+	trimmed := strings.TrimLeftFunc(candidate, unicode.IsSpace)
+	start += len(candidate) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	end = start + len(trimmed)
+
+	if !isValidJSONStart(trimmed) {
+		return jsonSpan{}, false
+	}
+	if isValidJSON(trimmed) {
+		return jsonSpan{start: start, end: end, json: trimmed}, true
+	}
+	if cleaned := cleanJSON(trimmed); cleaned != "" {
+		return jsonSpan{start: start, end: end, json: cleaned, repaired: true}, true
+	}
+	return jsonSpan{}, false
+}
+
+// stripANSI removes the color escape sequences some models and tools leave in responses
+func stripANSI(text string) string {
+	text = strings.ReplaceAll(text, "\033[92m", "")
+	text = strings.ReplaceAll(text, "\033[0m", "")
+	text = strings.ReplaceAll(text, "[92m", "")
+	return strings.ReplaceAll(text, "[0m", "")
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractJSONWithOptions_Modes(t *testing.T) {
+	// An example object quoted before the answer, which is in a code block
+	response := "For example {\"id\": 1} is valid. The answer:\n```json\n{\"id\": 2, \"items\": [1, 2, 3]}\n```\nDone."
+
+	tests := []struct {
+		mode   JSONExtractionMode
+		want   string
+		source string
+		prefix string
+		suffix string
+	}{
+		{JSONExtractionLenient, `{"id": 2, "items": [1, 2, 3]}`, "fenced", "For example {\"id\": 1} is valid. The answer:\n```json", "```\nDone."},
+		{JSONExtractionFenced, `{"id": 2, "items": [1, 2, 3]}`, "fenced", "For example {\"id\": 1} is valid. The answer:\n```json", "```\nDone."},
+		{JSONExtractionFirstObject, `{"id": 1}`, "inline", "For example", "is valid. The answer:\n```json\n{\"id\": 2, \"items\": [1, 2, 3]}\n```\nDone."},
+		{JSONExtractionLargestObject, `{"id": 2, "items": [1, 2, 3]}`, "inline", "For example {\"id\": 1} is valid. The answer:\n```json", "```\nDone."},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			extraction, err := ExtractJSONWithOptions(response, JSONExtractionOptions{Mode: tt.mode})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, extraction.JSON)
+			assert.Equal(t, tt.source, extraction.Source)
+			assert.Equal(t, tt.prefix, extraction.Prefix)
+			assert.Equal(t, tt.suffix, extraction.Suffix)
+			assert.Equal(t, 2, extraction.Candidates)
+		})
+	}
+
+	_, err := ExtractJSONWithOptions(response, JSONExtractionOptions{Mode: JSONExtractionStrict})
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "json_not_found", llmErr.Code)
+
+	_, err = ExtractJSONWithOptions(response, JSONExtractionOptions{Mode: "greedy"})
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_request", llmErr.Code)
+}
+
+func TestExtractJSONWithOptions_Strict(t *testing.T) {
+	extraction, err := ExtractJSONWithOptions("  [1, 2]\n", JSONExtractionOptions{Mode: JSONExtractionStrict})
+	require.NoError(t, err)
+	assert.Equal(t, "[1, 2]", extraction.JSON)
+	assert.Equal(t, "whole", extraction.Source)
+
+	// Strict mode does not repair
+	_, err = ExtractJSONWithOptions("{\"a\": 1,}", JSONExtractionOptions{Mode: JSONExtractionStrict})
+	assert.Error(t, err)
+}
+
+func TestExtractJSONWithOptions_Repaired(t *testing.T) {
+	extraction, err := ExtractJSONWithOptions("Result:\n{\n  \"a\": 1, // first\n  \"b\": [2,]\n}", JSONExtractionOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, JSONExtractionLenient, extraction.Mode)
+	assert.Equal(t, "{\n\"a\": 1,\n\"b\": [2]\n}", extraction.JSON)
+	assert.True(t, extraction.Repaired)
+	assert.Equal(t, "Result:", extraction.Prefix)
+
+	extraction, err = ExtractJSONWithOptions("no JSON here", JSONExtractionOptions{Mode: JSONExtractionFenced})
+	require.Error(t, err)
+	assert.Equal(t, 0, extraction.Candidates)
+}
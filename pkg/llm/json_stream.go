@@ -0,0 +1,313 @@
+// Incremental validation of streamed JSON output
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// JSONStreamValidatorConfig configures a JSONStreamValidator
+type JSONStreamValidatorConfig struct {
+	// AllowCodeFence accepts a markdown code fence (e.g. "```json") around the JSON
+	// value, as often produced when JSON is requested through prompt instructions
+	AllowCodeFence bool `json:"allow_code_fence"`
+
+	// AllowTrailingText ignores any text after the JSON value is complete
+	AllowTrailingText bool `json:"allow_trailing_text"`
+}
+
+// DefaultJSONStreamValidatorConfig returns the default validator configuration
+func DefaultJSONStreamValidatorConfig() *JSONStreamValidatorConfig {
+	return &JSONStreamValidatorConfig{
+		AllowCodeFence: true,
+	}
+}
+
+// jsonStreamState is the position of a JSONStreamValidator in the output
+type jsonStreamState int
+
+const (
+	jsonStreamStart jsonStreamState = iota // before the value
+	jsonStreamFence                        // inside the opening code fence line
+	jsonStreamValue                        // inside the value
+	jsonStreamDone                         // after the value
+)
+
+// jsonLiterals are the bare words allowed in JSON
+var jsonLiterals = []string{"true", "false", "null"}
+
+// JSONStreamValidator checks streamed text incrementally, tracking bracket and string
+// state across chunks, so output that is not JSON (e.g. the model answering in prose)
+// is detected at the first offending character instead of after the whole response.
+// It checks the lexical structure (brackets, strings, literals and numbers), not the
+// full JSON grammar.
+type JSONStreamValidator struct {
+	config *JSONStreamValidatorConfig
+
+	state    jsonStreamState
+	stack    []byte
+	inString bool
+	escaped  bool
+	token    []byte
+	fenced   bool
+	fence    int // backticks seen in the current fence
+	offset   int
+	err      *Error
+}
+
+// NewJSONStreamValidator creates a new validator, using the default configuration when
+// config is nil
+func NewJSONStreamValidator(config *JSONStreamValidatorConfig) *JSONStreamValidator {
+	if config == nil {
+		config = DefaultJSONStreamValidatorConfig()
+	}
+	return &JSONStreamValidator{config: config}
+}
+
+// Write validates the next chunk of text. Once an error is found, it is returned by
+// every subsequent call.
+func (v *JSONStreamValidator) Write(text string) error {
+	if v.err != nil {
+		return v.err
+	}
+	for i := 0; i < len(text); i++ {
+		if err := v.writeByte(text[i]); err != nil {
+			v.err = err
+			return err
+		}
+		v.offset++
+	}
+	return nil
+}
+
+// Complete reports whether a whole JSON value has been received
+func (v *JSONStreamValidator) Complete() bool {
+	return v.err == nil && v.state == jsonStreamDone
+}
+
+// Err returns the validation error found so far, if any
+func (v *JSONStreamValidator) Err() error {
+	if v.err == nil {
+		return nil
+	}
+	return v.err
+}
+
+// writeByte advances the validator by one byte
+func (v *JSONStreamValidator) writeByte(c byte) *Error {
+	switch v.state {
+	case jsonStreamStart:
+		if v.fence > 0 && c != '`' {
+			return v.errorf("unexpected %q in code fence", c)
+		}
+		switch {
+		case isJSONSpace(c):
+		case c == '{' || c == '[':
+			v.stack = append(v.stack, c)
+			v.state = jsonStreamValue
+		case c == '`' && v.config.AllowCodeFence && !v.fenced:
+			v.fence++
+			if v.fence == 3 {
+				v.fence = 0
+				v.fenced = true
+				v.state = jsonStreamFence
+			}
+		default:
+			return v.errorf("expected a JSON object or array, got %q", c)
+		}
+
+	case jsonStreamFence:
+		// The language tag runs until the end of the line
+		switch {
+		case c == '\n':
+			v.state = jsonStreamStart
+		case c == '\r', isLetter(c):
+		default:
+			return v.errorf("unexpected %q in code fence", c)
+		}
+
+	case jsonStreamValue:
+		return v.writeValueByte(c)
+
+	case jsonStreamDone:
+		switch {
+		case v.config.AllowTrailingText:
+		case isJSONSpace(c):
+		case c == '`' && v.fenced && v.fence < 3:
+			v.fence++
+		default:
+			return v.errorf("unexpected %q after the JSON value", c)
+		}
+	}
+	return nil
+}
+
+// writeValueByte advances the validator by one byte inside the JSON value
+func (v *JSONStreamValidator) writeValueByte(c byte) *Error {
+	if v.inString {
+		switch {
+		case v.escaped:
+			v.escaped = false
+		case c == '\\':
+			v.escaped = true
+		case c == '"':
+			v.inString = false
+		}
+		return nil
+	}
+
+	if len(v.token) > 0 {
+		if isJSONTokenByte(c) {
+			v.token = append(v.token, c)
+			return v.checkToken(false)
+		}
+		if err := v.checkToken(true); err != nil {
+			return err
+		}
+		v.token = v.token[:0]
+	}
+
+	switch {
+	case isJSONSpace(c), c == ':', c == ',':
+	case c == '"':
+		v.inString = true
+	case c == '{' || c == '[':
+		v.stack = append(v.stack, c)
+	case c == '}' || c == ']':
+		open := v.stack[len(v.stack)-1]
+		if (c == '}') != (open == '{') {
+			return v.errorf("unexpected %q closing %q", c, open)
+		}
+		v.stack = v.stack[:len(v.stack)-1]
+		if len(v.stack) == 0 {
+			v.state = jsonStreamDone
+		}
+	case c == '-' || (c >= '0' && c <= '9') || isLetter(c):
+		v.token = append(v.token, c)
+		return v.checkToken(false)
+	default:
+		return v.errorf("unexpected %q in JSON value", c)
+	}
+	return nil
+}
+
+// checkToken validates the bare token (literal or number) being read. Complete tokens
+// must be whole literals; incomplete ones only need to be a prefix of one.
+func (v *JSONStreamValidator) checkToken(complete bool) *Error {
+	token := string(v.token)
+	if v.token[0] == '-' || (v.token[0] >= '0' && v.token[0] <= '9') {
+		if strings.Trim(token, "0123456789+-.eE") != "" {
+			return v.errorf("invalid number %q", token)
+		}
+		return nil
+	}
+	for _, literal := range jsonLiterals {
+		if token == literal || (!complete && strings.HasPrefix(literal, token)) {
+			return nil
+		}
+	}
+	return v.errorf("unexpected word %q in JSON value", token)
+}
+
+// errorf creates the validation error for the current offset
+func (v *JSONStreamValidator) errorf(format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    "invalid_json_stream",
+		Message: fmt.Sprintf("output is not valid JSON at offset %d: %s", v.offset, fmt.Sprintf(format, args...)),
+		Type:    "validation_error",
+	}
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isJSONTokenByte reports whether c can continue a literal or a number
+func isJSONTokenByte(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.'
+}
+
+// JSONStreamValidationMiddleware validates the streamed output of requests whose
+// ResponseFormat is JSON, emitting an "invalid_json_stream" error event as soon as the
+// model produces something else (e.g. prose) so callers can abort and retry cheaply.
+// Events after the error are dropped. Requests without a JSON format are unaffected.
+type JSONStreamValidationMiddleware struct {
+	config *JSONStreamValidatorConfig
+}
+
+// NewJSONStreamValidationMiddleware creates a new validation middleware, using the
+// default configuration when config is nil
+func NewJSONStreamValidationMiddleware(config *JSONStreamValidatorConfig) *JSONStreamValidationMiddleware {
+	if config == nil {
+		config = DefaultJSONStreamValidatorConfig()
+	}
+	return &JSONStreamValidationMiddleware{config: config}
+}
+
+// Name returns the middleware name
+func (m *JSONStreamValidationMiddleware) Name() string {
+	return "json_stream_validation"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *JSONStreamValidationMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse passes responses through unchanged
+func (m *JSONStreamValidationMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, err
+}
+
+// ProcessStreamEvent passes events through unchanged; validation needs per-stream
+// state and is done by the processors created by NewStreamProcessor
+func (m *JSONStreamValidationMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// NewStreamProcessor creates the validator of a single stream
+func (m *JSONStreamValidationMiddleware) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	if req == nil || !req.ResponseFormat.IsJSON() {
+		return &eventMiddlewareProcessor{ctx: ctx, req: req, middleware: m}
+	}
+	return &jsonStreamProcessor{validator: NewJSONStreamValidator(m.config)}
+}
+
+// jsonStreamProcessor validates the text deltas of a stream
+type jsonStreamProcessor struct {
+	validator *JSONStreamValidator
+	failed    bool
+}
+
+// Process validates the text of delta events, replacing the rest of the stream with
+// an error event once the output stops being JSON
+func (p *jsonStreamProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	if p.failed {
+		return nil, nil
+	}
+	if !event.IsDelta() || event.Choice == nil || event.Choice.Delta == nil {
+		return []StreamEvent{event}, nil
+	}
+
+	var text strings.Builder
+	for _, content := range event.Choice.Delta.Content {
+		if tc, ok := content.(*TextContent); ok {
+			text.WriteString(tc.GetText())
+		}
+	}
+	if err := p.validator.Write(text.String()); err != nil {
+		p.failed = true
+		return []StreamEvent{event, NewErrorEvent(p.validator.err)}, nil
+	}
+	return []StreamEvent{event}, nil
+}
+
+// Flush has nothing to flush: deltas are never buffered
+func (p *jsonStreamProcessor) Flush() ([]StreamEvent, error) {
+	return nil, nil
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// writeChunks writes the chunks to the validator, returning the first error
+func writeChunks(v *JSONStreamValidator, chunks ...string) error {
+	for _, chunk := range chunks {
+		if err := v.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestJSONStreamValidator(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *JSONStreamValidatorConfig
+		chunks   []string
+		wantErr  bool
+		complete bool
+	}{
+		{
+			name:     "object split across chunks",
+			chunks:   []string{`{"name": "Al`, `ice", "tags": ["a", "b"], "age": 3`, `0, "ok": tr`, `ue, "x": null}`},
+			complete: true,
+		},
+		{
+			name:     "strings with brackets and escaped quotes",
+			chunks:   []string{`{"text": "a } ] \"quoted\`, `" {"}`},
+			complete: true,
+		},
+		{
+			name:     "array with numbers",
+			chunks:   []string{"\n [1, -2.5, 3e", "10]\n"},
+			complete: true,
+		},
+		{
+			name:    "prose",
+			chunks:  []string{"Sure! Here is", ` the JSON: {"a": 1}`},
+			wantErr: true,
+		},
+		{
+			name:    "bare word inside object",
+			chunks:  []string{`{"a": yes}`},
+			wantErr: true,
+		},
+		{
+			name:    "mismatched brackets",
+			chunks:  []string{`{"a": [1, 2}`},
+			wantErr: true,
+		},
+		{
+			name:    "trailing text",
+			chunks:  []string{`{"a": 1}`, ` I hope this helps!`},
+			wantErr: true,
+		},
+		{
+			name:     "trailing text allowed",
+			config:   &JSONStreamValidatorConfig{AllowTrailingText: true},
+			chunks:   []string{`{"a": 1}`, ` I hope this helps!`},
+			complete: true,
+		},
+		{
+			name:     "code fence",
+			chunks:   []string{"``", "`json\n{\"a\"", ": 1}\n``", "`\n"},
+			complete: true,
+		},
+		{
+			name:    "code fence not allowed",
+			config:  &JSONStreamValidatorConfig{},
+			chunks:  []string{"```json\n{\"a\": 1}\n```"},
+			wantErr: true,
+		},
+		{
+			name:   "incomplete value",
+			chunks: []string{`{"a": [1, 2`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewJSONStreamValidator(tt.config)
+			err := writeChunks(v, tt.chunks...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if v.Complete() != tt.complete {
+				t.Errorf("Expected complete %v, got %v", tt.complete, v.Complete())
+			}
+			if tt.wantErr {
+				var llmErr *Error
+				if !errors.As(v.Err(), &llmErr) || llmErr.Code != "invalid_json_stream" {
+					t.Errorf("Expected invalid_json_stream error, got %v", v.Err())
+				}
+				if v.Write(`{}`) == nil {
+					t.Error("Expected the error to be sticky")
+				}
+			}
+		})
+	}
+}
+
+func TestJSONStreamValidator_EarlyDetection(t *testing.T) {
+	v := NewJSONStreamValidator(nil)
+	if err := v.Write("Sure"); err == nil {
+		t.Fatal("Expected prose to be rejected at the first chunk")
+	}
+	var llmErr *Error
+	if !errors.As(v.Err(), &llmErr) || llmErr.Message != `output is not valid JSON at offset 0: expected a JSON object or array, got 'S'` {
+		t.Errorf("Unexpected error: %v", v.Err())
+	}
+}
+
+func TestJSONStreamValidationMiddleware(t *testing.T) {
+	delta := func(text string) StreamEvent {
+		return NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(text)}})
+	}
+
+	collect := func(t *testing.T, req ChatRequest, events []StreamEvent) []StreamEvent {
+		t.Helper()
+		mockClient := NewMockClient("test-model", "test-provider")
+		mockClient.WithStreamResponse(events)
+		client := NewEnhancedClient(mockClient, []Middleware{NewJSONStreamValidationMiddleware(nil)})
+
+		eventChan, err := client.StreamChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out []StreamEvent
+		for event := range eventChan {
+			out = append(out, event)
+		}
+		return out
+	}
+
+	jsonReq := ChatRequest{Model: "test-model", ResponseFormat: &ResponseFormat{Type: ResponseFormatJSON}}
+
+	t.Run("valid JSON passes through", func(t *testing.T) {
+		events := collect(t, jsonReq, []StreamEvent{delta(`{"a": `), delta(`1}`), NewDoneEvent(0, "stop")})
+		if len(events) != 3 || !events[2].IsDone() {
+			t.Fatalf("Expected the stream unchanged, got %d events", len(events))
+		}
+	})
+
+	t.Run("prose emits an error and drops the rest", func(t *testing.T) {
+		events := collect(t, jsonReq, []StreamEvent{
+			delta("I'm sorry, "), delta("I cannot do that"), NewDoneEvent(0, "stop"),
+		})
+		if len(events) != 2 || !events[0].IsDelta() || !events[1].IsError() {
+			t.Fatalf("Expected the offending delta and an error, got %d events", len(events))
+		}
+		if events[1].Error.Code != "invalid_json_stream" {
+			t.Errorf("Unexpected error code: %s", events[1].Error.Code)
+		}
+	})
+
+	t.Run("requests without JSON format are unaffected", func(t *testing.T) {
+		events := collect(t, ChatRequest{Model: "test-model"}, []StreamEvent{delta("Hello"), NewDoneEvent(0, "stop")})
+		if len(events) != 2 || events[1].IsError() {
+			t.Fatalf("Expected the stream unchanged, got %d events", len(events))
+		}
+	})
+}
@@ -0,0 +1,327 @@
+// Load balancing across multiple API keys of the same provider
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyPoolStrategy selects which key serves the next request
+type KeyPoolStrategy string
+
+const (
+	// KeyPoolRoundRobin rotates through the available keys in order (default)
+	KeyPoolRoundRobin KeyPoolStrategy = "round_robin"
+	// KeyPoolLeastLoaded picks the available key with the fewest in-flight requests
+	KeyPoolLeastLoaded KeyPoolStrategy = "least_loaded"
+)
+
+// DefaultKeyCooldown is how long a rate-limited key is kept out of rotation
+const DefaultKeyCooldown = 60 * time.Second
+
+// KeyPoolConfig configures a KeyPool
+type KeyPoolConfig struct {
+	Strategy KeyPoolStrategy `json:"strategy"`
+	Cooldown time.Duration   `json:"cooldown"` // Time a rate-limited key stays out of rotation
+
+	// Clock times the cooldowns (SystemClock when nil)
+	Clock Clock `json:"-"`
+}
+
+// KeyUsage reports the usage of a single key in a KeyPool
+type KeyUsage struct {
+	Key              string    `json:"key"` // Masked key, safe to log
+	Requests         int64     `json:"requests"`
+	Errors           int64     `json:"errors"`
+	RateLimited      int64     `json:"rate_limited"`
+	InFlight         int       `json:"in_flight"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CooldownUntil    time.Time `json:"cooldown_until,omitempty"`
+}
+
+type pooledKey struct {
+	client Client
+	usage  KeyUsage
+}
+
+// KeyPool is a Client that spreads requests across several clients of the same provider,
+// each configured with a different API key. Keys that hit rate limits are cooled down and
+// the request is transparently retried with the next available key.
+//
+// Example:
+//
+//	f := factory.New()
+//	pool, err := llm.NewKeyPool(config, []string{key1, key2, key3}, f.CreateClient,
+//	    llm.KeyPoolConfig{Strategy: llm.KeyPoolLeastLoaded})
+//	resp, err := pool.ChatCompletion(ctx, req)
+//	for _, usage := range pool.Usage() {
+//	    log.Printf("%s: %d requests", usage.Key, usage.Requests)
+//	}
+type KeyPool struct {
+	mu     sync.Mutex
+	keys   []*pooledKey
+	next   int
+	config KeyPoolConfig
+}
+
+// NewKeyPool creates a client for every key using newClient (typically a factory's
+// CreateClient) with a copy of config, and pools them together
+func NewKeyPool(config ClientConfig, keys []string, newClient func(ClientConfig) (Client, error), poolConfig ...KeyPoolConfig) (*KeyPool, error) {
+	if len(keys) == 0 {
+		return nil, &Error{Code: "invalid_config", Message: "key pool requires at least one API key", Type: "validation_error"}
+	}
+
+	clients := make([]Client, 0, len(keys))
+	for i, key := range keys {
+		keyConfig := config
+		keyConfig.APIKey = key
+
+		client, err := newClient(keyConfig)
+		if err != nil {
+			for _, c := range clients {
+				_ = c.Close()
+			}
+			return nil, fmt.Errorf("failed to create client for key %d: %w", i, err)
+		}
+		clients = append(clients, client)
+	}
+
+	pool, err := NewKeyPoolFromClients(clients, poolConfig...)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		pool.keys[i].usage.Key = maskKey(key)
+	}
+	return pool, nil
+}
+
+// NewKeyPoolFromClients pools already created clients, one per API key
+func NewKeyPoolFromClients(clients []Client, config ...KeyPoolConfig) (*KeyPool, error) {
+	if len(clients) == 0 {
+		return nil, &Error{Code: "invalid_config", Message: "key pool requires at least one client", Type: "validation_error"}
+	}
+
+	cfg := KeyPoolConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = KeyPoolRoundRobin
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultKeyCooldown
+	}
+
+	cfg.Clock = clockOrSystem(cfg.Clock)
+
+	pool := &KeyPool{config: cfg}
+	for i, client := range clients {
+		pool.keys = append(pool.keys, &pooledKey{
+			client: client,
+			usage:  KeyUsage{Key: fmt.Sprintf("key-%d", i)},
+		})
+	}
+	return pool, nil
+}
+
+// ChatCompletion performs a chat completion with the next available key, moving on to
+// other keys when a key is rate limited
+func (p *KeyPool) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+	for range p.keys {
+		key, err := p.acquire()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := key.client.ChatCompletion(ctx, req)
+		p.release(key, err)
+		if err == nil {
+			if resp != nil {
+				p.recordUsage(key, resp.Usage)
+			}
+			return resp, nil
+		}
+		if !isRateLimitError(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// StreamChatCompletion starts a stream with the next available key, moving on to other
+// keys when a key is rate limited before the stream starts
+func (p *KeyPool) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	var lastErr error
+	for range p.keys {
+		key, err := p.acquire()
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err := key.client.StreamChatCompletion(ctx, req)
+		if err != nil {
+			p.release(key, err)
+			if !isRateLimitError(err) {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+
+		// The key stays in flight until the stream is drained
+		out := make(chan StreamEvent)
+		go func() {
+			defer close(out)
+			var streamErr error
+			for event := range stream {
+				if event.Error != nil {
+					streamErr = event.Error
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					p.release(key, ctx.Err())
+					// Unblock the provider until it notices the cancellation
+					for range stream {
+					}
+					return
+				}
+			}
+			p.release(key, streamErr)
+		}()
+		return out, nil
+	}
+	return nil, lastErr
+}
+
+// GetRemote returns information about the pooled provider
+func (p *KeyPool) GetRemote() ClientRemoteInfo {
+	return p.keys[0].client.GetRemote()
+}
+
+// GetModelInfo returns information about the pooled model
+func (p *KeyPool) GetModelInfo() ModelInfo {
+	return p.keys[0].client.GetModelInfo()
+}
+
+// Close closes all the pooled clients
+func (p *KeyPool) Close() error {
+	var errs []error
+	for _, key := range p.keys {
+		if err := key.client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Usage returns a snapshot of the usage of every key, in pool order
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := make([]KeyUsage, len(p.keys))
+	for i, key := range p.keys {
+		usage[i] = key.usage
+	}
+	return usage
+}
+
+// acquire selects an available key according to the strategy and marks it in flight
+func (p *KeyPool) acquire() (*pooledKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.config.Clock.Now()
+	var selected *pooledKey
+	var earliest time.Time
+
+	for i := range p.keys {
+		idx := (p.next + i) % len(p.keys)
+		key := p.keys[idx]
+
+		if now.Before(key.usage.CooldownUntil) {
+			if earliest.IsZero() || key.usage.CooldownUntil.Before(earliest) {
+				earliest = key.usage.CooldownUntil
+			}
+			continue
+		}
+
+		if p.config.Strategy != KeyPoolLeastLoaded {
+			selected = key
+			p.next = idx + 1
+			break
+		}
+		if selected == nil || key.usage.InFlight < selected.usage.InFlight {
+			selected = key
+		}
+	}
+
+	if selected == nil {
+		return nil, &Error{
+			Code:       "all_keys_rate_limited",
+			Message:    fmt.Sprintf("all %d API keys are cooling down after rate limits, next available in %v", len(p.keys), earliest.Sub(now).Round(time.Second)),
+			Type:       "rate_limit_error",
+			StatusCode: 429,
+		}
+	}
+
+	selected.usage.Requests++
+	selected.usage.InFlight++
+	return selected, nil
+}
+
+// release marks a request as finished, cooling the key down when it was rate limited
+func (p *KeyPool) release(key *pooledKey, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key.usage.InFlight--
+	if err == nil {
+		return
+	}
+
+	key.usage.Errors++
+	if isRateLimitError(err) {
+		key.usage.RateLimited++
+		// Prefer the wait reported by the provider over the configured cooldown
+		cooldown := p.config.Cooldown
+		if delay := RateLimitOf(err).Delay(); delay > 0 {
+			cooldown = delay
+		}
+		key.usage.CooldownUntil = p.config.Clock.Now().Add(cooldown)
+	}
+}
+
+func (p *KeyPool) recordUsage(key *pooledKey, usage Usage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key.usage.PromptTokens += int64(usage.PromptTokens)
+	key.usage.CompletionTokens += int64(usage.CompletionTokens)
+}
+
+// isRateLimitError checks if an error reports that a provider rate limit was hit
+func isRateLimitError(err error) bool {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	return llmErr.StatusCode == 429 || llmErr.Type == "rate_limit_error" || llmErr.Code == "rate_limit_exceeded" ||
+		(llmErr.RateLimit != nil && llmErr.RateLimit.CreditsExhausted)
+}
+
+// maskKey hides all but the last four characters of an API key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
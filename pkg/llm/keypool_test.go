@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitError() *Error {
+	return &Error{Code: "rate_limit_exceeded", Message: "too many requests", Type: "rate_limit_error", StatusCode: 429}
+}
+
+func TestKeyPool_RoundRobin(t *testing.T) {
+	clients := []*testMockClient{NewMockClient("m", "p"), NewMockClient("m", "p"), NewMockClient("m", "p")}
+	pool, err := NewKeyPoolFromClients([]Client{clients[0], clients[1], clients[2]})
+	require.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		_, err := pool.ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+
+	for i, c := range clients {
+		assert.Len(t, c.callLog, 2, "client %d", i)
+	}
+
+	usage := pool.Usage()
+	require.Len(t, usage, 3)
+	assert.Equal(t, int64(2), usage[0].Requests)
+	assert.Equal(t, int64(20), usage[0].PromptTokens)
+	assert.Equal(t, int64(10), usage[0].CompletionTokens)
+	assert.Equal(t, 0, usage[0].InFlight)
+}
+
+func TestKeyPool_RateLimitCooldown(t *testing.T) {
+	limited := NewMockClient("m", "p")
+	limited.errorToReturn = rateLimitError()
+	healthy := NewMockClient("m", "p")
+
+	now := time.Now()
+	clock := NewFakeClock(now)
+	pool, err := NewKeyPoolFromClients([]Client{limited, healthy}, KeyPoolConfig{Cooldown: time.Minute, Clock: clock})
+	require.NoError(t, err)
+
+	// The rate-limited key is skipped transparently
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+
+	// While cooling down, every request goes to the healthy key
+	for i := 0; i < 3; i++ {
+		_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, limited.callLog, 1)
+	assert.Len(t, healthy.callLog, 4)
+
+	usage := pool.Usage()
+	assert.Equal(t, int64(1), usage[0].RateLimited)
+	assert.Equal(t, now.Add(time.Minute), usage[0].CooldownUntil)
+
+	// After the cooldown the key is used again
+	limited.errorToReturn = nil
+	clock.Advance(2 * time.Minute)
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	assert.Len(t, limited.callLog, 2)
+}
+
+func TestKeyPool_AllKeysRateLimited(t *testing.T) {
+	a, b := NewMockClient("m", "p"), NewMockClient("m", "p")
+	a.errorToReturn = rateLimitError()
+	b.errorToReturn = rateLimitError()
+	pool, err := NewKeyPoolFromClients([]Client{a, b})
+	require.NoError(t, err)
+
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	assert.True(t, isRateLimitError(err))
+
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "all_keys_rate_limited", llmErr.Code)
+	assert.Len(t, a.callLog, 1)
+	assert.Len(t, b.callLog, 1)
+}
+
+func TestKeyPool_NonRateLimitErrorIsReturned(t *testing.T) {
+	failing, other := NewMockClient("m", "p"), NewMockClient("m", "p")
+	failing.errorToReturn = &Error{Code: "invalid_request", Message: "bad request", StatusCode: 400}
+	pool, err := NewKeyPoolFromClients([]Client{failing, other})
+	require.NoError(t, err)
+
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.Error(t, err)
+	assert.Empty(t, other.callLog)
+	assert.Equal(t, int64(1), pool.Usage()[0].Errors)
+	assert.True(t, pool.Usage()[0].CooldownUntil.IsZero())
+}
+
+func TestKeyPool_LeastLoaded(t *testing.T) {
+	a, b := NewMockClient("m", "p"), NewMockClient("m", "p")
+	pool, err := NewKeyPoolFromClients([]Client{a, b}, KeyPoolConfig{Strategy: KeyPoolLeastLoaded})
+	require.NoError(t, err)
+
+	// An undrained stream keeps the first key busy
+	stream, err := pool.StreamChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Usage()[0].InFlight)
+
+	_, err = pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	assert.Len(t, b.callLog, 1)
+
+	for range stream {
+	}
+	assert.Eventually(t, func() bool { return pool.Usage()[0].InFlight == 0 }, time.Second, time.Millisecond)
+}
+
+// blockingStreamClient streams events on an unbuffered channel, reporting when it is done
+type blockingStreamClient struct {
+	testMockClient
+	done chan struct{}
+}
+
+func (c *blockingStreamClient) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent)
+	go func() {
+		defer close(c.done)
+		defer close(ch)
+		for i := 0; i < 5; i++ {
+			ch <- NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("x")}})
+		}
+	}()
+	return ch, nil
+}
+
+func TestKeyPool_CancelledStreamIsDrained(t *testing.T) {
+	client := &blockingStreamClient{testMockClient: *NewMockClient("m", "p"), done: make(chan struct{})}
+	pool, err := NewKeyPoolFromClients([]Client{client})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pool.StreamChatCompletion(ctx, ChatRequest{})
+	require.NoError(t, err)
+	<-stream
+	cancel()
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("the provider stream was not drained after cancellation")
+	}
+	assert.Eventually(t, func() bool { return pool.Usage()[0].InFlight == 0 }, time.Second, time.Millisecond)
+}
+
+func TestKeyPool_NilResponse(t *testing.T) {
+	client := NewMockClient("m", "p")
+	client.responses = []*ChatResponse{nil}
+	pool, err := NewKeyPoolFromClients([]Client{client})
+	require.NoError(t, err)
+
+	resp, err := pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	_, err = NewKeyPoolFromClients(nil)
+	assert.Error(t, err)
+}
+
+func TestNewKeyPool(t *testing.T) {
+	var configs []ClientConfig
+	pool, err := NewKeyPool(ClientConfig{Provider: "openai", Model: "gpt-4o"}, []string{"sk-first-1111", "sk-second-2222"},
+		func(config ClientConfig) (Client, error) {
+			configs = append(configs, config)
+			return NewMockClient(config.Model, config.Provider), nil
+		})
+	require.NoError(t, err)
+	defer func() { _ = pool.Close() }()
+
+	require.Len(t, configs, 2)
+	assert.Equal(t, "sk-first-1111", configs[0].APIKey)
+	assert.Equal(t, "sk-second-2222", configs[1].APIKey)
+	assert.Equal(t, "****2222", pool.Usage()[1].Key)
+	assert.Equal(t, "openai", pool.GetModelInfo().Provider)
+
+	_, err = NewKeyPool(ClientConfig{}, nil, nil)
+	assert.Error(t, err)
+}
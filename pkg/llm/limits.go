@@ -0,0 +1,194 @@
+// Request and response size limits
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LimitsConfig configures a LimitsMiddleware. A zero limit is not enforced.
+type LimitsConfig struct {
+	// MaxRequestBytes limits the estimated payload of a request: its messages, with
+	// images and files base64-encoded, and its tool definitions
+	MaxRequestBytes int64 `json:"max_request_bytes"`
+
+	// MaxMessages limits the messages of a request
+	MaxMessages int `json:"max_messages"`
+
+	// MaxTools limits the tool definitions of a request
+	MaxTools int `json:"max_tools"`
+
+	// MaxResponseBytes limits the text and tool call arguments of a response. Streams
+	// exceeding it are aborted with an error event.
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+}
+
+// DefaultLimitsConfig returns the default limits
+func DefaultLimitsConfig() *LimitsConfig {
+	return &LimitsConfig{
+		MaxRequestBytes:  20 * 1024 * 1024,
+		MaxMessages:      1000,
+		MaxTools:         128,
+		MaxResponseBytes: 1024 * 1024,
+	}
+}
+
+// LimitsMiddleware rejects requests and responses exceeding size limits, with
+// "request_too_large", "too_many_messages", "too_many_tools" or "response_too_large"
+// errors (see IsLimitError). Unlike SecurityValidator it does not inspect the content,
+// so it is cheap enough for every request.
+type LimitsMiddleware struct {
+	config *LimitsConfig
+}
+
+// NewLimitsMiddleware creates a limits middleware, using the default limits when
+// config is nil
+func NewLimitsMiddleware(config *LimitsConfig) *LimitsMiddleware {
+	if config == nil {
+		config = DefaultLimitsConfig()
+	}
+	return &LimitsMiddleware{config: config}
+}
+
+// Name returns the middleware name
+func (m *LimitsMiddleware) Name() string {
+	return "limits"
+}
+
+// ProcessRequest rejects requests exceeding the message, tool or payload limits
+func (m *LimitsMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	if m.config.MaxMessages > 0 && len(req.Messages) > m.config.MaxMessages {
+		return nil, limitError("too_many_messages",
+			fmt.Sprintf("request has %d messages, the limit is %d", len(req.Messages), m.config.MaxMessages))
+	}
+	if m.config.MaxTools > 0 && len(req.Tools) > m.config.MaxTools {
+		return nil, limitError("too_many_tools",
+			fmt.Sprintf("request has %d tools, the limit is %d", len(req.Tools), m.config.MaxTools))
+	}
+	if m.config.MaxRequestBytes > 0 {
+		if size := requestPayloadSize(req); size > m.config.MaxRequestBytes {
+			err := limitError("request_too_large",
+				fmt.Sprintf("request payload is about %d bytes, the limit is %d", size, m.config.MaxRequestBytes))
+			err.StatusCode = 413
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// ProcessResponse passes responses through unchanged; their size is checked by
+// CheckResponse
+func (m *LimitsMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, err
+}
+
+// CheckResponse rejects responses exceeding MaxResponseBytes
+func (m *LimitsMiddleware) CheckResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse) error {
+	if m.config.MaxResponseBytes <= 0 {
+		return nil
+	}
+	var size int64
+	for _, choice := range resp.Choices {
+		size += int64(len(choice.Message.GetText()))
+		for _, call := range choice.Message.ToolCalls {
+			size += int64(len(call.Function.Arguments))
+		}
+	}
+	if size > m.config.MaxResponseBytes {
+		return m.responseTooLarge()
+	}
+	return nil
+}
+
+// ProcessStreamEvent passes events through unchanged; the response size of a stream is
+// tracked by the processors created by NewStreamProcessor
+func (m *LimitsMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// NewStreamProcessor creates the response size tracker of a single stream
+func (m *LimitsMiddleware) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	if m.config.MaxResponseBytes <= 0 {
+		return &eventMiddlewareProcessor{ctx: ctx, req: req, middleware: m}
+	}
+	return &limitsStreamProcessor{middleware: m}
+}
+
+// responseTooLarge returns the error of a response exceeding MaxResponseBytes
+func (m *LimitsMiddleware) responseTooLarge() *Error {
+	return limitError("response_too_large",
+		fmt.Sprintf("response exceeds the limit of %d bytes", m.config.MaxResponseBytes))
+}
+
+// limitsStreamProcessor counts the bytes of the deltas of a stream
+type limitsStreamProcessor struct {
+	middleware *LimitsMiddleware
+	size       int64
+	aborted    bool
+}
+
+// Process forwards events until the deltas exceed MaxResponseBytes, then replaces the
+// rest of the stream with a single error event
+func (p *limitsStreamProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	if p.aborted {
+		return nil, nil
+	}
+	if !event.IsDelta() {
+		return []StreamEvent{event}, nil
+	}
+
+	p.size += int64(len(deltaText(event.Choice.Delta)))
+	for _, call := range event.Choice.Delta.ToolCalls {
+		if call.Function != nil {
+			p.size += int64(len(call.Function.Arguments))
+		}
+	}
+	if p.size > p.middleware.config.MaxResponseBytes {
+		p.aborted = true
+		return []StreamEvent{NewErrorEvent(p.middleware.responseTooLarge())}, nil
+	}
+	return []StreamEvent{event}, nil
+}
+
+// Flush has nothing to flush: deltas are never buffered
+func (p *limitsStreamProcessor) Flush() ([]StreamEvent, error) {
+	return nil, nil
+}
+
+// requestPayloadSize estimates the size of the payload sent for a request
+func requestPayloadSize(req *ChatRequest) int64 {
+	var size int64
+	for _, message := range req.Messages {
+		size += EstimateSerializedSize(message, SerializationFormatEnhanced)
+		for _, call := range message.ToolCalls {
+			size += int64(len(call.ID) + len(call.Function.Name) + len(call.Function.Arguments))
+		}
+	}
+	if len(req.Tools) > 0 {
+		if tools, err := json.Marshal(req.Tools); err == nil {
+			size += int64(len(tools))
+		}
+	}
+	return size
+}
+
+// limitError creates the error of an exceeded limit
+func limitError(code, message string) *Error {
+	return &Error{Code: code, Message: message, Type: "validation_error"}
+}
+
+// IsLimitError checks if an error (or any error it wraps) was produced by a
+// LimitsMiddleware limit
+func IsLimitError(err error) bool {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	switch llmErr.Code {
+	case "request_too_large", "too_many_messages", "too_many_tools", "response_too_large":
+		return true
+	}
+	return false
+}
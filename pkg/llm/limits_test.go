@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsMiddleware_Request(t *testing.T) {
+	middleware := NewLimitsMiddleware(&LimitsConfig{MaxRequestBytes: 4096, MaxMessages: 2, MaxTools: 1})
+	tool := Tool{Type: "function", Function: ToolFunction{Name: "search"}}
+
+	tests := []struct {
+		name string
+		req  ChatRequest
+		code string
+	}{
+		{
+			name: "within limits",
+			req:  ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "hi")}, Tools: []Tool{tool}},
+		},
+		{
+			name: "too many messages",
+			req: ChatRequest{Messages: []Message{
+				NewTextMessage(RoleSystem, "a"), NewTextMessage(RoleUser, "b"), NewTextMessage(RoleUser, "c"),
+			}},
+			code: "too_many_messages",
+		},
+		{
+			name: "too many tools",
+			req:  ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "hi")}, Tools: []Tool{tool, tool}},
+			code: "too_many_tools",
+		},
+		{
+			name: "payload too large",
+			req: ChatRequest{Messages: []Message{{
+				Role:    RoleUser,
+				Content: []MessageContent{NewImageContentFromBytes(make([]byte, 4096), "image/png")},
+			}}},
+			code: "request_too_large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := middleware.ProcessRequest(context.Background(), &tt.req)
+			if tt.code == "" {
+				if err != nil || out == nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var llmErr *Error
+			if !errors.As(err, &llmErr) || llmErr.Code != tt.code || !IsLimitError(err) {
+				t.Fatalf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+
+	// Zero limits are not enforced
+	unlimited := NewLimitsMiddleware(&LimitsConfig{})
+	big := ChatRequest{Messages: make([]Message, 5000)}
+	if _, err := unlimited.ProcessRequest(context.Background(), &big); err != nil {
+		t.Errorf("Expected no limits, got %v", err)
+	}
+}
+
+func TestLimitsMiddleware_Response(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	mockClient.responses = []*ChatResponse{{
+		Choices: []Choice{{Message: NewTextMessage(RoleAssistant, strings.Repeat("x", 100))}},
+	}}
+	client := NewEnhancedClient(mockClient, []Middleware{NewLimitsMiddleware(&LimitsConfig{MaxResponseBytes: 64})})
+
+	_, err := client.ChatCompletion(context.Background(), ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "hi")}})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "response_too_large" {
+		t.Fatalf("Expected response_too_large, got %v", err)
+	}
+
+	if _, err := client.ChatCompletion(context.Background(), ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "hi")}}); err != nil {
+		t.Errorf("Expected a short response to pass, got %v", err)
+	}
+}
+
+func TestLimitsMiddleware_Stream(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	mockClient.WithStreamResponse([]StreamEvent{
+		textDelta(strings.Repeat("a", 40)), textDelta(strings.Repeat("b", 40)), textDelta("c"), NewDoneEvent(0, FinishReasonStop),
+	})
+	client := NewEnhancedClient(mockClient, []Middleware{NewLimitsMiddleware(&LimitsConfig{MaxResponseBytes: 64})})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "hi")}})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	events := collectEvents(stream)
+	if len(events) != 2 || !events[0].IsDelta() || !events[1].IsError() {
+		t.Fatalf("Expected the first delta and an error, got %+v", events)
+	}
+	if events[1].Error.Code != "response_too_large" {
+		t.Errorf("Unexpected error code %s", events[1].Error.Code)
+	}
+}
@@ -0,0 +1,447 @@
+// Weighted load balancing across clients of different providers
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadBalancerStrategy selects how a LoadBalancer picks the backend of the next request
+type LoadBalancerStrategy string
+
+const (
+	// LoadBalancerWeightedRandom picks a backend at random, with a probability
+	// proportional to its effective weight (default)
+	LoadBalancerWeightedRandom LoadBalancerStrategy = "weighted_random"
+	// LoadBalancerWeightedRoundRobin interleaves the backends deterministically,
+	// following their effective weights (smooth weighted round-robin)
+	LoadBalancerWeightedRoundRobin LoadBalancerStrategy = "weighted_round_robin"
+)
+
+const (
+	// DefaultStickySessionTTL is how long a conversation stays pinned to its backend
+	// after its last request
+	DefaultStickySessionTTL = 30 * time.Minute
+
+	// MetadataConversationID is the request metadata key read as the conversation ID
+	// of sticky sessions when the context has none (see WithConversationID)
+	MetadataConversationID = "conversation_id"
+
+	// minHealthFactor is the fraction of its weight kept by a failing backend, so it still
+	// receives a trickle of requests and can recover
+	minHealthFactor = 0.05
+)
+
+// LoadBalancerPolicy configures a LoadBalancer
+type LoadBalancerPolicy struct {
+	Strategy LoadBalancerStrategy `json:"strategy"`
+
+	// StickyTTL is how long a conversation stays pinned to the backend that served it
+	// after its last request. Zero uses DefaultStickySessionTTL, a negative value
+	// disables sticky sessions.
+	StickyTTL time.Duration `json:"sticky_ttl"`
+
+	// IgnoreHealth disables health-aware weighting, using the configured weights as is
+	IgnoreHealth bool `json:"ignore_health"`
+}
+
+// BackendUsage reports the usage of a single backend of a LoadBalancer
+type BackendUsage struct {
+	Backend          string  `json:"backend"` // Provider and model of the backend
+	Weight           int     `json:"weight"`
+	EffectiveWeight  float64 `json:"effective_weight"` // Weight scaled by the backend health
+	Healthy          bool    `json:"healthy"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	Failovers        int64   `json:"failovers"` // Requests moved to another backend after an error
+	InFlight         int     `json:"in_flight"`
+	Sessions         int     `json:"sessions"` // Conversations currently pinned to the backend
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+}
+
+type lbBackend struct {
+	client  Client
+	weight  int
+	current float64 // Smooth weighted round-robin state
+	health  *HealthStats
+	usage   BackendUsage
+}
+
+type stickySession struct {
+	backend  *lbBackend
+	lastUsed time.Time
+}
+
+// LoadBalancer is a Client distributing requests across clients of one or more providers
+// in proportion to their weights (e.g. 80% OpenAI, 20% Azure). Weights are scaled down by
+// the success rate of each backend, so failing backends receive less traffic, and requests
+// failing with a retryable error move to another backend. Requests of a conversation (see
+// WithConversationID) stick to the backend that served the first one.
+//
+// Example:
+//
+//	lb, err := llm.NewLoadBalancer(map[llm.Client]int{openaiClient: 80, azureClient: 20},
+//	    llm.LoadBalancerPolicy{Strategy: llm.LoadBalancerWeightedRoundRobin})
+//	ctx = llm.WithConversationID(ctx, conversationID)
+//	resp, err := lb.ChatCompletion(ctx, req)
+//	for _, usage := range lb.Usage() {
+//	    log.Printf("%s: %d requests", usage.Backend, usage.Requests)
+//	}
+type LoadBalancer struct {
+	mu       sync.Mutex
+	backends []*lbBackend
+	sessions map[string]*stickySession
+	pruneAt  int
+	policy   LoadBalancerPolicy
+
+	// now and random are replaceable for testing
+	now    func() time.Time
+	random func() float64
+}
+
+// NewLoadBalancer balances requests across the clients of weights. Clients with a zero
+// weight only receive requests when all the others fail.
+func NewLoadBalancer(weights map[Client]int, policy ...LoadBalancerPolicy) (*LoadBalancer, error) {
+	total := 0
+	for _, weight := range weights {
+		if weight < 0 {
+			return nil, &Error{Code: "invalid_config", Message: fmt.Sprintf("load balancer weights must not be negative, got %d", weight), Type: "validation_error"}
+		}
+		total += weight
+	}
+	if total == 0 {
+		return nil, &Error{Code: "invalid_config", Message: "load balancer requires at least one client with a positive weight", Type: "validation_error"}
+	}
+
+	cfg := LoadBalancerPolicy{}
+	if len(policy) > 0 {
+		cfg = policy[0]
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = LoadBalancerWeightedRandom
+	}
+	if cfg.StickyTTL == 0 {
+		cfg.StickyTTL = DefaultStickySessionTTL
+	}
+
+	lb := &LoadBalancer{
+		sessions: make(map[string]*stickySession),
+		policy:   cfg,
+		now:      time.Now,
+		random:   rand.Float64,
+	}
+	for client, weight := range weights {
+		lb.backends = append(lb.backends, &lbBackend{
+			client: client,
+			weight: weight,
+			health: NewHealthStats(),
+			usage: BackendUsage{
+				Backend: client.GetModelInfo().Provider + "/" + client.GetModelInfo().Name,
+				Weight:  weight,
+			},
+		})
+	}
+	// Keep a stable order for round-robin and Usage, heaviest backends first
+	sort.SliceStable(lb.backends, func(i, j int) bool {
+		a, b := lb.backends[i], lb.backends[j]
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		return a.usage.Backend < b.usage.Backend
+	})
+	return lb, nil
+}
+
+// conversationIDKey is the context key of the conversation ID
+type conversationIDKey struct{}
+
+// WithConversationID returns a context whose requests belong to the conversation id, so a
+// LoadBalancer sends them to the same backend
+func WithConversationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, conversationIDKey{}, id)
+}
+
+// ConversationIDFrom returns the conversation ID of a request: the one set with
+// WithConversationID, or else the MetadataConversationID request metadata
+func ConversationIDFrom(ctx context.Context, req ChatRequest) string {
+	if id, ok := ctx.Value(conversationIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	if id, ok := req.Metadata[MetadataConversationID].(string); ok {
+		return id
+	}
+	return ""
+}
+
+// ChatCompletion performs a chat completion with the selected backend, moving on to
+// other backends when it fails with a retryable error
+func (lb *LoadBalancer) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	conversation := ConversationIDFrom(ctx, req)
+	tried := make(map[*lbBackend]bool, len(lb.backends))
+
+	var lastErr error
+	for range lb.backends {
+		backend := lb.acquire(conversation, tried)
+		start := time.Now()
+		resp, err := backend.client.ChatCompletion(ctx, req)
+		backend.health.Track(ctx, start, err)
+		lb.release(backend, conversation, err)
+		if err == nil {
+			if resp != nil {
+				lb.recordUsage(backend, resp.Usage)
+			}
+			return resp, nil
+		}
+		if !lb.failover(backend, tried, err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// StreamChatCompletion starts a stream with the selected backend, moving on to other
+// backends when it fails with a retryable error before the stream starts
+func (lb *LoadBalancer) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	conversation := ConversationIDFrom(ctx, req)
+	tried := make(map[*lbBackend]bool, len(lb.backends))
+
+	var lastErr error
+	for range lb.backends {
+		backend := lb.acquire(conversation, tried)
+		start := time.Now()
+		stream, err := backend.client.StreamChatCompletion(ctx, req)
+		if err != nil {
+			backend.health.Track(ctx, start, err)
+			lb.release(backend, conversation, err)
+			if !lb.failover(backend, tried, err) {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+
+		// The backend stays in flight until the stream is drained
+		tracked := backend.health.TrackStream(ctx, start, stream)
+		out := make(chan StreamEvent)
+		go func() {
+			defer close(out)
+			var streamErr error
+			for event := range tracked {
+				if event.Error != nil {
+					streamErr = event.Error
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					lb.release(backend, conversation, ctx.Err())
+					for range tracked {
+					}
+					return
+				}
+			}
+			lb.release(backend, conversation, streamErr)
+		}()
+		return out, nil
+	}
+	return nil, lastErr
+}
+
+// GetRemote returns the name "load_balancer" and the status of the heaviest backend
+func (lb *LoadBalancer) GetRemote() ClientRemoteInfo {
+	return ClientRemoteInfo{Name: "load_balancer", Status: lb.backends[0].health.Status()}
+}
+
+// GetModelInfo returns information about the model of the heaviest backend
+func (lb *LoadBalancer) GetModelInfo() ModelInfo {
+	return lb.backends[0].client.GetModelInfo()
+}
+
+// Close closes all the backends
+func (lb *LoadBalancer) Close() error {
+	var errs []error
+	for _, backend := range lb.backends {
+		if err := backend.client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Usage returns a snapshot of the usage of every backend, heaviest first
+func (lb *LoadBalancer) Usage() []BackendUsage {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.pruneSessions(true)
+	sessions := make(map[*lbBackend]int, len(lb.backends))
+	for _, session := range lb.sessions {
+		sessions[session.backend]++
+	}
+
+	usage := make([]BackendUsage, len(lb.backends))
+	for i, backend := range lb.backends {
+		usage[i] = backend.usage
+		usage[i].EffectiveWeight, usage[i].Healthy = lb.effectiveWeight(backend)
+		usage[i].Sessions = sessions[backend]
+	}
+	return usage
+}
+
+// acquire selects the backend of a request, preferring the one its conversation is pinned
+// to, and marks it in flight. Backends in tried are only selected when all have been tried.
+func (lb *LoadBalancer) acquire(conversation string, tried map[*lbBackend]bool) *lbBackend {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	selected := lb.sticky(conversation, tried)
+	if selected == nil {
+		selected = lb.pick(tried)
+	}
+	if conversation != "" && lb.policy.StickyTTL > 0 {
+		lb.sessions[conversation] = &stickySession{backend: selected, lastUsed: lb.now()}
+		lb.pruneSessions(false)
+	}
+
+	selected.usage.Requests++
+	selected.usage.InFlight++
+	return selected
+}
+
+// sticky returns the healthy backend a conversation is pinned to, if any
+func (lb *LoadBalancer) sticky(conversation string, tried map[*lbBackend]bool) *lbBackend {
+	if conversation == "" || lb.policy.StickyTTL <= 0 {
+		return nil
+	}
+	session, ok := lb.sessions[conversation]
+	if !ok || lb.now().Sub(session.lastUsed) > lb.policy.StickyTTL || tried[session.backend] {
+		return nil
+	}
+	if _, healthy := lb.effectiveWeight(session.backend); !healthy {
+		return nil
+	}
+	return session.backend
+}
+
+// pick selects a backend not in tried according to the strategy, falling back to the
+// backends in tried and to those with a zero weight when there is no other choice
+func (lb *LoadBalancer) pick(tried map[*lbBackend]bool) *lbBackend {
+	candidates := make([]*lbBackend, 0, len(lb.backends))
+	weights := make([]float64, 0, len(lb.backends))
+	total := 0.0
+	for _, backend := range lb.backends {
+		if tried[backend] {
+			continue
+		}
+		weight, _ := lb.effectiveWeight(backend)
+		candidates = append(candidates, backend)
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return lb.backends[0]
+	case total == 0:
+		return candidates[0]
+	}
+
+	if lb.policy.Strategy == LoadBalancerWeightedRoundRobin {
+		var selected *lbBackend
+		for i, backend := range candidates {
+			backend.current += weights[i]
+			if selected == nil || backend.current > selected.current {
+				selected = backend
+			}
+		}
+		selected.current -= total
+		return selected
+	}
+
+	target := lb.random() * total
+	for i, backend := range candidates {
+		if target < weights[i] {
+			return backend
+		}
+		target -= weights[i]
+	}
+	// Rounding may leave target past the last positive weight
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return candidates[i]
+		}
+	}
+	return candidates[0]
+}
+
+// effectiveWeight returns the weight of a backend scaled by the success rate of the calls
+// it served, and whether it is healthy. The status reported by the client is not used
+// since GetRemote may perform a health check.
+func (lb *LoadBalancer) effectiveWeight(backend *lbBackend) (float64, bool) {
+	if lb.policy.IgnoreHealth {
+		return float64(backend.weight), true
+	}
+
+	rate := 1.0
+	if status := backend.health.Status(); status.Requests > 0 {
+		rate = status.SuccessRate
+	}
+	return float64(backend.weight) * max(rate, minHealthFactor), rate >= DefaultHealthySuccessRate
+}
+
+// pruneSessions drops expired sessions, only once the map doubled since the last pruning
+// unless force is set
+func (lb *LoadBalancer) pruneSessions(force bool) {
+	if !force && len(lb.sessions) < lb.pruneAt {
+		return
+	}
+	now := lb.now()
+	for id, session := range lb.sessions {
+		if now.Sub(session.lastUsed) > lb.policy.StickyTTL {
+			delete(lb.sessions, id)
+		}
+	}
+	lb.pruneAt = max(2*len(lb.sessions), 64)
+}
+
+// release marks a request as finished
+func (lb *LoadBalancer) release(backend *lbBackend, conversation string, err error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	backend.usage.InFlight--
+	if err != nil && !errors.Is(err, context.Canceled) {
+		backend.usage.Errors++
+	}
+	if session, ok := lb.sessions[conversation]; ok && session.backend == backend {
+		session.lastUsed = lb.now()
+	}
+}
+
+// failover reports whether a request that failed with err on backend should be sent to
+// another one, marking the backend as tried
+func (lb *LoadBalancer) failover(backend *lbBackend, tried map[*lbBackend]bool, err error) bool {
+	tried[backend] = true
+	if !IsRetryableError(err) || len(tried) == len(lb.backends) {
+		return false
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	backend.usage.Failovers++
+	return true
+}
+
+func (lb *LoadBalancer) recordUsage(backend *lbBackend, usage Usage) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	backend.usage.PromptTokens += int64(usage.PromptTokens)
+	backend.usage.CompletionTokens += int64(usage.CompletionTokens)
+}
@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancer_InvalidWeights(t *testing.T) {
+	_, err := NewLoadBalancer(map[Client]int{NewMockClient("m", "p"): 0})
+	require.Error(t, err)
+
+	_, err = NewLoadBalancer(map[Client]int{NewMockClient("m", "p"): -1, NewMockClient("m", "q"): 1})
+	require.Error(t, err)
+	assert.Equal(t, "invalid_config", err.(*Error).Code)
+}
+
+func TestLoadBalancer_WeightedRoundRobin(t *testing.T) {
+	primary, secondary := NewMockClient("gpt", "openai"), NewMockClient("gpt", "azure")
+	lb, err := NewLoadBalancer(map[Client]int{primary: 80, secondary: 20},
+		LoadBalancerPolicy{Strategy: LoadBalancerWeightedRoundRobin})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := lb.ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, primary.callLog, 8)
+	assert.Len(t, secondary.callLog, 2)
+
+	usage := lb.Usage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, "openai/gpt", usage[0].Backend)
+	assert.Equal(t, int64(8), usage[0].Requests)
+	assert.Equal(t, int64(80), usage[0].PromptTokens)
+	assert.Equal(t, int64(40), usage[0].CompletionTokens)
+	assert.Equal(t, 80.0, usage[0].EffectiveWeight)
+	assert.True(t, usage[0].Healthy)
+	assert.Equal(t, 0, usage[0].InFlight)
+}
+
+func TestLoadBalancer_WeightedRandom(t *testing.T) {
+	primary, secondary := NewMockClient("gpt", "openai"), NewMockClient("gpt", "azure")
+	lb, err := NewLoadBalancer(map[Client]int{primary: 80, secondary: 20})
+	require.NoError(t, err)
+
+	// Random values below 0.8 fall on the primary, the rest on the secondary
+	values := []float64{0.1, 0.79, 0.8, 0.95}
+	lb.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+	for range 4 {
+		_, err := lb.ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, primary.callLog, 2)
+	assert.Len(t, secondary.callLog, 2)
+}
+
+func TestLoadBalancer_StickySessions(t *testing.T) {
+	azure, openai := NewMockClient("gpt", "azure"), NewMockClient("gpt", "openai")
+	lb, err := NewLoadBalancer(map[Client]int{azure: 1, openai: 1},
+		LoadBalancerPolicy{Strategy: LoadBalancerWeightedRoundRobin, StickyTTL: time.Minute})
+	require.NoError(t, err)
+	now := time.Now()
+	lb.now = func() time.Time { return now }
+
+	ctx := WithConversationID(context.Background(), "conv-1")
+	for range 3 {
+		_, err := lb.ChatCompletion(ctx, ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, azure.callLog, 3)
+	assert.Empty(t, openai.callLog)
+
+	// The conversation ID can also be set in the request metadata
+	req := ChatRequest{Metadata: map[string]any{MetadataConversationID: "conv-2"}}
+	for range 2 {
+		_, err := lb.ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+	}
+	assert.Len(t, openai.callLog, 2)
+
+	usage := lb.Usage()
+	assert.Equal(t, 1, usage[0].Sessions)
+	assert.Equal(t, 1, usage[1].Sessions)
+
+	// Expired sessions are rebalanced
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 0, lb.Usage()[0].Sessions)
+	assert.Equal(t, 0, lb.Usage()[1].Sessions)
+}
+
+func TestLoadBalancer_FailoverAndHealth(t *testing.T) {
+	failing, healthy := NewMockClient("gpt", "openai"), NewMockClient("gpt", "azure")
+	failing.errorToReturn = &Error{Code: "server_error", Message: "unavailable", Type: "api_error", StatusCode: 503}
+	lb, err := NewLoadBalancer(map[Client]int{failing: 80, healthy: 20},
+		LoadBalancerPolicy{Strategy: LoadBalancerWeightedRoundRobin})
+	require.NoError(t, err)
+
+	ctx := WithConversationID(context.Background(), "conv")
+	_, err = lb.ChatCompletion(ctx, ChatRequest{})
+	require.NoError(t, err)
+	assert.Len(t, failing.callLog, 1)
+	assert.Len(t, healthy.callLog, 1)
+
+	usage := lb.Usage()
+	assert.Equal(t, int64(1), usage[0].Errors)
+	assert.Equal(t, int64(1), usage[0].Failovers)
+	assert.False(t, usage[0].Healthy)
+	assert.InDelta(t, 80*minHealthFactor, usage[0].EffectiveWeight, 1e-9)
+	// The conversation moved to the backend that answered
+	assert.Equal(t, 1, usage[1].Sessions)
+
+	// The failing backend gets only a trickle of the traffic
+	for range 10 {
+		_, err := lb.ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, healthy.callLog, 11)
+	assert.Less(t, len(failing.callLog), 3)
+}
+
+func TestLoadBalancer_NonRetryableError(t *testing.T) {
+	invalid := NewMockClient("gpt", "openai")
+	invalid.errorToReturn = &Error{Code: "invalid_request", Message: "bad request", Type: "invalid_request_error", StatusCode: 400}
+	other := NewMockClient("gpt", "azure")
+	lb, err := NewLoadBalancer(map[Client]int{invalid: 2, other: 1},
+		LoadBalancerPolicy{Strategy: LoadBalancerWeightedRoundRobin})
+	require.NoError(t, err)
+
+	_, err = lb.ChatCompletion(context.Background(), ChatRequest{})
+	require.Error(t, err)
+	assert.Equal(t, "invalid_request", err.(*Error).Code)
+	assert.Empty(t, other.callLog)
+}
+
+func TestLoadBalancer_Stream(t *testing.T) {
+	primary, secondary := NewMockClient("gpt", "openai"), NewMockClient("gpt", "azure")
+	secondary.errorToReturn = rateLimitError()
+	lb, err := NewLoadBalancer(map[Client]int{primary: 1, secondary: 3},
+		LoadBalancerPolicy{Strategy: LoadBalancerWeightedRoundRobin})
+	require.NoError(t, err)
+
+	stream, err := lb.StreamChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, err)
+	events := collectEvents(stream)
+	require.NotEmpty(t, events)
+	assert.True(t, events[len(events)-1].IsDone())
+
+	usage := lb.Usage()
+	assert.Equal(t, "azure/gpt", usage[0].Backend)
+	assert.Equal(t, int64(1), usage[0].Failovers)
+	assert.Equal(t, int64(1), usage[1].Requests)
+	assert.Equal(t, 0, usage[1].InFlight)
+}
@@ -13,6 +13,38 @@ type Message struct {
 	ToolCalls  []ToolCall       `json:"tool_calls,omitempty"`
 	ToolCallID string           `json:"tool_call_id,omitempty"`
 	Metadata   map[string]any   `json:"metadata,omitempty"`
+	Citations  []Citation       `json:"citations,omitempty"`
+
+	// Reasoning is the reasoning produced by the model before its answer, for providers
+	// returning it separately from the content (e.g. DeepSeek-R1). It is not sent back
+	// to the provider in later turns.
+	Reasoning string `json:"reasoning,omitempty"`
+	// BuiltinToolCalls are the tools run by the provider to produce the message (see
+	// BuiltinToolCall)
+	BuiltinToolCalls []BuiltinToolCall `json:"builtin_tool_calls,omitempty"`
+}
+
+// Citation attributes a span of a response to a source, as returned by providers with
+// grounding or citation support (Gemini grounding, Perplexity and other OpenRouter models)
+type Citation struct {
+	// URL is the address of the source
+	URL string `json:"url,omitempty"`
+
+	// Title is the title of the source, when known
+	Title string `json:"title,omitempty"`
+
+	// StartIndex and EndIndex are the byte offsets of the cited span in the message text.
+	// Both are zero when the provider only lists the sources of the whole response.
+	StartIndex int `json:"start_index,omitempty"`
+	EndIndex   int `json:"end_index,omitempty"`
+
+	// Text is the cited span of the response or an excerpt of the source, when provided
+	Text string `json:"text,omitempty"`
+}
+
+// HasSpan reports whether the citation refers to a span of the message text
+func (c Citation) HasSpan() bool {
+	return c.EndIndex > c.StartIndex
 }
 
 // MessageRole defines the role of a message sender
@@ -178,6 +210,7 @@ func (m Message) DeepCopy() Message {
 	copy := Message{
 		Role:       m.Role,
 		ToolCallID: m.ToolCallID,
+		Reasoning:  m.Reasoning,
 	}
 
 	// Deep copy the Content slice
@@ -196,6 +229,19 @@ func (m Message) DeepCopy() Message {
 		}
 	}
 
+	if len(m.Citations) > 0 {
+		copy.Citations = append([]Citation(nil), m.Citations...)
+	}
+
+	if len(m.BuiltinToolCalls) > 0 {
+		copy.BuiltinToolCalls = make([]BuiltinToolCall, len(m.BuiltinToolCalls))
+		for i, call := range m.BuiltinToolCalls {
+			call.Queries = append([]string(nil), call.Queries...)
+			call.Results = append([]Citation(nil), call.Results...)
+			copy.BuiltinToolCalls[i] = call
+		}
+	}
+
 	// Deep copy the Metadata map
 	if len(m.Metadata) > 0 {
 		copy.Metadata = make(map[string]any, len(m.Metadata))
@@ -373,38 +419,47 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 
 	// Process content items
 	if len(temp.Content) > 0 {
-		m.Content = make([]MessageContent, 0, len(temp.Content))
+		content, err := unmarshalContents(temp.Content)
+		if err != nil {
+			return err
+		}
+		m.Content = content
+	}
 
-		for i, contentBytes := range temp.Content {
-			// First unmarshal to get the type
-			var typeChecker struct {
-				Type MessageType `json:"type"`
-			}
+	return nil
+}
 
-			if err := json.Unmarshal(contentBytes, &typeChecker); err != nil {
-				return fmt.Errorf("failed to determine type for content item %d: %w", i, err)
-			}
+// unmarshalContents decodes content items, using their type field to pick the content type
+func unmarshalContents(items []json.RawMessage) ([]MessageContent, error) {
+	contents := make([]MessageContent, 0, len(items))
+	for i, contentBytes := range items {
+		// First unmarshal to get the type
+		var typeChecker struct {
+			Type MessageType `json:"type"`
+		}
 
-			// Create appropriate content type and unmarshal
-			var content MessageContent
-			switch typeChecker.Type {
-			case MessageTypeText:
-				content = &TextContent{}
-			case MessageTypeImage:
-				content = &ImageContent{}
-			case MessageTypeFile:
-				content = &FileContent{}
-			default:
-				return fmt.Errorf("unsupported content type: %s", typeChecker.Type)
-			}
+		if err := json.Unmarshal(contentBytes, &typeChecker); err != nil {
+			return nil, fmt.Errorf("failed to determine type for content item %d: %w", i, err)
+		}
 
-			if err := json.Unmarshal(contentBytes, content); err != nil {
-				return fmt.Errorf("failed to unmarshal content item %d of type %s: %w", i, typeChecker.Type, err)
-			}
+		// Create appropriate content type and unmarshal
+		var content MessageContent
+		switch typeChecker.Type {
+		case MessageTypeText:
+			content = &TextContent{}
+		case MessageTypeImage:
+			content = &ImageContent{}
+		case MessageTypeFile:
+			content = &FileContent{}
+		default:
+			return nil, fmt.Errorf("unsupported content type: %s", typeChecker.Type)
+		}
 
-			m.Content = append(m.Content, content)
+		if err := json.Unmarshal(contentBytes, content); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal content item %d of type %s: %w", i, typeChecker.Type, err)
 		}
-	}
 
-	return nil
+		contents = append(contents, content)
+	}
+	return contents, nil
 }
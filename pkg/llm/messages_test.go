@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -253,3 +254,22 @@ func TestDeepCopyWithTracing(t *testing.T) {
 		t.Log("✅ Multiple tracers can work independently with DeepCopy")
 	})
 }
+
+func TestMessageBuiltinToolCalls(t *testing.T) {
+	message := NewTextMessage(RoleAssistant, "1024")
+	message.BuiltinToolCalls = []BuiltinToolCall{
+		{Type: BuiltinToolCodeExecution, Language: "PYTHON", Code: "print(2**10)", Output: "1024"},
+		{Type: BuiltinToolWebSearch, Queries: []string{"q"}, Results: []Citation{{URL: "https://example.com"}}},
+	}
+
+	copied := message.DeepCopy()
+	copied.BuiltinToolCalls[1].Queries[0] = "changed"
+	assert.Equal(t, "q", message.BuiltinToolCalls[1].Queries[0])
+
+	data, err := json.Marshal(message)
+	require.NoError(t, err)
+	var decoded Message
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, message.BuiltinToolCalls, decoded.BuiltinToolCalls)
+	assert.Len(t, decoded.BuiltinToolCallsOf(BuiltinToolCodeExecution), 1)
+}
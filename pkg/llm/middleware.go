@@ -21,6 +21,45 @@ type Middleware interface {
 	ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error)
 }
 
+// StreamTransformer is an optional interface for middleware that needs to transform
+// the stream as a whole instead of mutating events one by one: it can drop, buffer,
+// merge or split events (e.g. masking words split across deltas, or coalescing deltas).
+// A new StreamProcessor is created for every stream, so processors can keep state.
+type StreamTransformer interface {
+	Middleware
+
+	// NewStreamProcessor creates the processor for a single stream
+	NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor
+}
+
+// ResponseChecker is an optional interface for middleware that can reject responses.
+// Errors returned by ProcessResponse are ignored by the chain, while an error returned by
+// CheckResponse replaces the response, for the rest of the chain and the caller.
+type ResponseChecker interface {
+	Middleware
+
+	// CheckResponse returns an error when a successful response must be rejected
+	CheckResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse) error
+}
+
+// ErrorRewriter is an optional interface for middleware that replaces the error of a failed
+// request (e.g. with a redacted copy), for the rest of the chain and the caller
+type ErrorRewriter interface {
+	Middleware
+
+	// RewriteError returns the error to report instead of err
+	RewriteError(ctx context.Context, req *ChatRequest, err error) error
+}
+
+// StreamProcessor transforms the events of a single stream
+type StreamProcessor interface {
+	// Process handles one incoming event and returns zero or more events to emit
+	Process(event StreamEvent) ([]StreamEvent, error)
+
+	// Flush is called when the upstream stream ends and returns any buffered events
+	Flush() ([]StreamEvent, error)
+}
+
 // MiddlewareChain manages a chain of LLM middleware
 type MiddlewareChain struct {
 	mu          sync.RWMutex
@@ -67,11 +106,21 @@ func (c *MiddlewareChain) ProcessRequest(ctx context.Context, req *ChatRequest)
 	currentReq := req
 	var err error
 
+	// Middleware may modify the request in place, so it is rendered before each one runs
+	capture := requestDiffCaptureFrom(ctx)
+
 	for _, middleware := range middlewares {
+		var before requestSnapshot
+		if capture != nil {
+			before = snapshotRequest(currentReq)
+		}
 		currentReq, err = middleware.ProcessRequest(ctx, currentReq)
 		if err != nil {
 			return nil, fmt.Errorf("middleware %s failed: %w", middleware.Name(), err)
 		}
+		if capture != nil {
+			capture.add(MiddlewareDiff{Middleware: middleware.Name(), Diff: before.diff(snapshotRequest(currentReq))})
+		}
 	}
 
 	return currentReq, nil
@@ -96,6 +145,17 @@ func (c *MiddlewareChain) ProcessResponse(ctx context.Context, req *ChatRequest,
 			continue
 		}
 		currentResp = processedResp
+
+		if checker, ok := middleware.(ResponseChecker); ok && currentErr == nil && currentResp != nil {
+			if checkErr := checker.CheckResponse(ctx, req, currentResp); checkErr != nil {
+				currentResp, currentErr = nil, checkErr
+			}
+		}
+		if rewriter, ok := middleware.(ErrorRewriter); ok && currentErr != nil {
+			if rewritten := rewriter.RewriteError(ctx, req, currentErr); rewritten != nil {
+				currentErr = rewritten
+			}
+		}
 	}
 
 	return currentResp, currentErr
@@ -122,6 +182,91 @@ func (c *MiddlewareChain) ProcessStreamEvent(ctx context.Context, req *ChatReque
 	return currentEvent, nil
 }
 
+// NewStreamPipeline creates a pipeline that runs the events of a single stream through
+// the chain. Middleware implementing StreamTransformer get their own StreamProcessor;
+// other middleware are applied one event at a time with ProcessStreamEvent.
+func (c *MiddlewareChain) NewStreamPipeline(ctx context.Context, req *ChatRequest) *StreamPipeline {
+	c.mu.RLock()
+	middlewares := make([]Middleware, len(c.middlewares))
+	copy(middlewares, c.middlewares)
+	c.mu.RUnlock()
+
+	stages := make([]StreamProcessor, len(middlewares))
+	for i, middleware := range middlewares {
+		if transformer, ok := middleware.(StreamTransformer); ok {
+			stages[i] = transformer.NewStreamProcessor(ctx, req)
+		} else {
+			stages[i] = &eventMiddlewareProcessor{ctx: ctx, req: req, middleware: middleware}
+		}
+	}
+
+	return &StreamPipeline{stages: stages}
+}
+
+// StreamPipeline runs stream events through the per-stream processors of a chain
+type StreamPipeline struct {
+	stages []StreamProcessor
+}
+
+// Process runs an event through all stages and returns the resulting events.
+// If a stage fails, its input events are passed through unchanged.
+func (p *StreamPipeline) Process(event StreamEvent) []StreamEvent {
+	return p.processFrom(0, []StreamEvent{event})
+}
+
+// Flush flushes every stage in order, running the flushed events through the
+// remaining stages, and returns the events to emit before the stream closes
+func (p *StreamPipeline) Flush() []StreamEvent {
+	var result []StreamEvent
+	for i, stage := range p.stages {
+		flushed, err := stage.Flush()
+		if err != nil || len(flushed) == 0 {
+			continue
+		}
+		result = append(result, p.processFrom(i+1, flushed)...)
+	}
+	return result
+}
+
+// processFrom runs events through the stages starting at the given index
+func (p *StreamPipeline) processFrom(start int, events []StreamEvent) []StreamEvent {
+	for _, stage := range p.stages[start:] {
+		var next []StreamEvent
+		for _, event := range events {
+			out, err := stage.Process(event)
+			if err != nil {
+				// Continue with the original event if the stage fails
+				next = append(next, event)
+				continue
+			}
+			next = append(next, out...)
+		}
+		events = next
+	}
+	return events
+}
+
+// eventMiddlewareProcessor adapts a regular Middleware to the StreamProcessor interface
+type eventMiddlewareProcessor struct {
+	ctx        context.Context
+	req        *ChatRequest
+	middleware Middleware
+}
+
+// Process applies the middleware's ProcessStreamEvent to a single event
+func (p *eventMiddlewareProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	processed, err := p.middleware.ProcessStreamEvent(p.ctx, p.req, event)
+	if err != nil {
+		return nil, err
+	}
+	return []StreamEvent{processed}, nil
+}
+
+// Flush has nothing to flush for 1:1 middleware
+func (p *eventMiddlewareProcessor) Flush() ([]StreamEvent, error) {
+	return nil, nil
+}
+
 // GetMiddlewareNames returns the names of all middleware in the chain
 func (c *MiddlewareChain) GetMiddlewareNames() []string {
 	c.mu.RLock()
@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"fmt"
+	"maps"
 )
 
 // EnhancedClient wraps an LLM client with middleware chain
@@ -29,11 +30,13 @@ func (e *EnhancedClient) ChatCompletion(ctx context.Context, req ChatRequest) (*
 
 	// Execute the actual LLM call
 	resp, err := e.client.ChatCompletion(ctx, *processedReq)
+	if resp != nil {
+		resp = propagateRequestMetadata(processedReq, resp)
+	}
 
-	// Process response through middleware chain
-	processedResp, _ := e.chain.ProcessResponse(ctx, processedReq, resp, err)
-
-	return processedResp, err
+	// Process response through middleware chain, which keeps the error unless a
+	// ResponseChecker rejects the response
+	return e.chain.ProcessResponse(ctx, processedReq, resp, err)
 }
 
 // StreamChatCompletion implements Client interface with middleware processing for streaming
@@ -47,32 +50,46 @@ func (e *EnhancedClient) StreamChatCompletion(ctx context.Context, req ChatReque
 	// Execute the actual LLM streaming call
 	eventChan, err := e.client.StreamChatCompletion(ctx, *processedReq)
 	if err != nil {
-		// Process error through middleware
-		_, _ = e.chain.ProcessResponse(ctx, processedReq, nil, err)
+		// Process error through middleware, which may rewrite it
+		_, err = e.chain.ProcessResponse(ctx, processedReq, nil, err)
 		return nil, err
 	}
 
 	// Create a new channel for processed events
 	processedChan := make(chan StreamEvent)
 
+	// Each stream gets its own pipeline so stream transformers can keep per-stream state
+	pipeline := e.chain.NewStreamPipeline(ctx, processedReq)
+
+	// Middleware may drop, split or merge events, so the output stream is re-sequenced
+	sequencer := NewStreamSequencer()
+
 	go func() {
 		defer close(processedChan)
 
-		for event := range eventChan {
-			// Process each stream event through middleware
-			processedEvent, processErr := e.chain.ProcessStreamEvent(ctx, processedReq, event)
-			if processErr != nil {
-				// Send original event if processing fails
-				processedEvent = event
+		send := func(events []StreamEvent) bool {
+			for _, event := range events {
+				select {
+				case processedChan <- sequencer.Stamp(event):
+				case <-ctx.Done():
+					return false
+				}
 			}
+			return true
+		}
 
-			select {
-			case processedChan <- processedEvent:
-			case <-ctx.Done():
+		for event := range eventChan {
+			// Process each stream event through middleware, which may emit zero or more events
+			if !send(pipeline.Process(event)) {
 				return
 			}
 		}
 
+		// Emit anything the middleware buffered before closing the stream
+		if !send(pipeline.Flush()) {
+			return
+		}
+
 		// Process final response through middleware (for completion tracking)
 		_, _ = e.chain.ProcessResponse(ctx, processedReq, nil, nil)
 	}()
@@ -80,6 +97,11 @@ func (e *EnhancedClient) StreamChatCompletion(ctx context.Context, req ChatReque
 	return processedChan, nil
 }
 
+// Unwrap returns the client wrapped by the middleware chain
+func (e *EnhancedClient) Unwrap() Client {
+	return e.client
+}
+
 // GetRemote implements Client interface
 func (e *EnhancedClient) GetRemote() ClientRemoteInfo {
 	return e.client.GetRemote()
@@ -110,6 +132,25 @@ func (e *EnhancedClient) GetMiddlewareNames() []string {
 	return e.chain.GetMiddlewareNames()
 }
 
+// propagateRequestMetadata returns a shallow copy of the response with the request
+// metadata added, so that response middleware and callers can use it for accounting.
+// Keys already set on the response take precedence. The response returned by the
+// client is left untouched, as clients may cache or share it.
+func propagateRequestMetadata(req *ChatRequest, resp *ChatResponse) *ChatResponse {
+	if len(req.Metadata) == 0 {
+		return resp
+	}
+
+	propagated := *resp
+	propagated.Metadata = maps.Clone(resp.Metadata)
+	for k, v := range req.Metadata {
+		if _, exists := propagated.Metadata[k]; !exists {
+			propagated.SetMetadata(k, v)
+		}
+	}
+	return &propagated
+}
+
 // ClientWithMiddleware wraps an existing LLM client with the enhanced middleware system
 // This is the main entry point for adding middleware to LLM clients
 func ClientWithMiddleware(client Client, chain []Middleware) Client {
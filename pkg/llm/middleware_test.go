@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -100,12 +101,11 @@ func (c *testMockClient) GetModelInfo() ModelInfo {
 }
 
 func (c *testMockClient) GetRemote() ClientRemoteInfo {
-	healthy := true // Test mock is always healthy
 	now := time.Now()
 	return ClientRemoteInfo{
 		Name: c.provider,
 		Status: &ClientRemoteInfoStatus{
-			Healthy:     &healthy,
+			SuccessRate: 1, // Test mock is always healthy
 			LastChecked: &now,
 		},
 	}
@@ -936,6 +936,225 @@ func TestClientWithMiddlewareStreaming(t *testing.T) {
 	})
 }
 
+// bufferingTransformer joins text deltas until the stream ends, then emits them as a single
+// delta followed by the done event, and drops empty deltas
+type bufferingTransformer struct {
+	mockMiddleware
+}
+
+type bufferingProcessor struct {
+	buffer strings.Builder
+	done   []StreamEvent
+}
+
+func (b *bufferingTransformer) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	return &bufferingProcessor{}
+}
+
+func (p *bufferingProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	switch {
+	case event.IsDelta():
+		for _, content := range event.Choice.Delta.Content {
+			if text, ok := content.(*TextContent); ok {
+				p.buffer.WriteString(text.GetText())
+			}
+		}
+		return nil, nil
+	case event.IsDone():
+		p.done = append(p.done, event)
+		return nil, nil
+	}
+	return []StreamEvent{event}, nil
+}
+
+func (p *bufferingProcessor) Flush() ([]StreamEvent, error) {
+	events := []StreamEvent{NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(p.buffer.String())}})}
+	return append(events, p.done...), nil
+}
+
+// splittingTransformer splits every text delta into one delta per word
+type splittingTransformer struct {
+	mockMiddleware
+}
+
+type splittingProcessor struct{}
+
+func (s *splittingTransformer) NewStreamProcessor(ctx context.Context, req *ChatRequest) StreamProcessor {
+	return splittingProcessor{}
+}
+
+func (splittingProcessor) Process(event StreamEvent) ([]StreamEvent, error) {
+	if !event.IsDelta() {
+		return []StreamEvent{event}, nil
+	}
+
+	var events []StreamEvent
+	for _, content := range event.Choice.Delta.Content {
+		if text, ok := content.(*TextContent); ok {
+			for _, word := range strings.Fields(text.GetText()) {
+				events = append(events, NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(word)}}))
+			}
+		}
+	}
+	return events, nil
+}
+
+func (splittingProcessor) Flush() ([]StreamEvent, error) {
+	return nil, nil
+}
+
+func TestEnhancedClient_StreamTransformers(t *testing.T) {
+	newStream := func() []StreamEvent {
+		return []StreamEvent{
+			NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("Hello ")}}),
+			NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("brave new ")}}),
+			NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("world")}}),
+			NewDoneEvent(0, "stop"),
+		}
+	}
+
+	collect := func(t *testing.T, middlewares []Middleware) []StreamEvent {
+		t.Helper()
+		mockClient := NewMockClient("test-model", "test-provider")
+		mockClient.WithStreamResponse(newStream())
+		client := NewEnhancedClient(mockClient, middlewares)
+
+		eventChan, err := client.StreamChatCompletion(context.Background(), ChatRequest{Model: "test-model"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var events []StreamEvent
+		for event := range eventChan {
+			events = append(events, event)
+		}
+		return events
+	}
+
+	deltaTexts := func(events []StreamEvent) []string {
+		var texts []string
+		for _, event := range events {
+			if event.IsDelta() {
+				texts = append(texts, event.Choice.Delta.Content[0].(*TextContent).GetText())
+			}
+		}
+		return texts
+	}
+
+	t.Run("buffering merges events and flushes at the end", func(t *testing.T) {
+		counter := 0
+		counting := &mockMiddleware{name: "counter", eventMods: func(event StreamEvent) (StreamEvent, error) {
+			counter++
+			return event, nil
+		}}
+
+		events := collect(t, []Middleware{&bufferingTransformer{mockMiddleware{name: "buffer"}}, counting})
+
+		if texts := deltaTexts(events); !equalStringSlice(texts, []string{"Hello brave new world"}) {
+			t.Errorf("unexpected deltas: %v", texts)
+		}
+		if len(events) != 2 || !events[1].IsDone() {
+			t.Fatalf("expected merged delta followed by done, got %d events", len(events))
+		}
+		// Later middleware only see the flushed events
+		if counter != 2 {
+			t.Errorf("expected downstream middleware to see 2 events, saw %d", counter)
+		}
+	})
+
+	t.Run("splitting emits multiple events per input", func(t *testing.T) {
+		events := collect(t, []Middleware{&splittingTransformer{mockMiddleware{name: "splitter"}}})
+
+		if texts := deltaTexts(events); !equalStringSlice(texts, []string{"Hello", "brave", "new", "world"}) {
+			t.Errorf("unexpected deltas: %v", texts)
+		}
+		if !events[len(events)-1].IsDone() {
+			t.Error("expected done event to be preserved")
+		}
+		// The output stream is re-sequenced without gaps or duplicates
+		for i, event := range events {
+			if event.Seq != uint64(i+1) || event.Timestamp.IsZero() {
+				t.Errorf("event %d: unexpected sequence %d (timestamp %v)", i, event.Seq, event.Timestamp)
+			}
+		}
+	})
+
+	t.Run("transformers compose in chain order", func(t *testing.T) {
+		events := collect(t, []Middleware{
+			&splittingTransformer{mockMiddleware{name: "splitter"}},
+			&bufferingTransformer{mockMiddleware{name: "buffer"}},
+		})
+
+		if texts := deltaTexts(events); !equalStringSlice(texts, []string{"Hellobravenewworld"}) {
+			t.Errorf("unexpected deltas: %v", texts)
+		}
+	})
+}
+
+func TestEnhancedClient_MetadataPropagation(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	mockClient.responses = []*ChatResponse{
+		{
+			ID:       "test-metadata",
+			Model:    "test-model",
+			Metadata: map[string]any{"region": "eu"},
+		},
+	}
+	client := NewEnhancedClient(mockClient, []Middleware{newTestMiddleware("m1")})
+
+	req := ChatRequest{
+		Model:    "test-model",
+		Messages: []Message{NewTextMessage(RoleUser, "hi")},
+		Metadata: map[string]any{"tenant_id": "acme", "region": "us"},
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := mockClient.callLog[0].Metadata["tenant_id"]; got != "acme" {
+		t.Errorf("expected request metadata to reach the client, got %v", got)
+	}
+	if got := resp.Metadata["tenant_id"]; got != "acme" {
+		t.Errorf("expected tenant_id to be propagated to response, got %v", got)
+	}
+	if got := resp.Metadata["region"]; got != "eu" {
+		t.Errorf("expected response metadata to take precedence, got %v", got)
+	}
+}
+
+func TestEnhancedClient_MetadataPropagationCopiesResponse(t *testing.T) {
+	shared := &ChatResponse{ID: "shared", Metadata: map[string]any{"region": "eu"}}
+	mockClient := NewMockClient("test-model", "test-provider")
+	mockClient.responses = []*ChatResponse{shared}
+	client := NewEnhancedClient(mockClient, nil)
+
+	resp, err := client.ChatCompletion(context.Background(), ChatRequest{Metadata: map[string]any{"tenant_id": "acme"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == shared || resp.Metadata["tenant_id"] != "acme" {
+		t.Errorf("expected a copy with the request metadata, got %+v", resp)
+	}
+	if len(shared.Metadata) != 1 {
+		t.Errorf("expected the client response to be untouched, got %v", shared.Metadata)
+	}
+}
+
+func TestClientAs(t *testing.T) {
+	mockClient := NewMockClient("test-model", "test-provider")
+	client := NewEnhancedClient(NewEnhancedClient(mockClient, nil), nil)
+
+	inner, ok := ClientAs[*testMockClient](client)
+	if !ok || inner != mockClient {
+		t.Errorf("expected the wrapped mock client, got %v", inner)
+	}
+	if _, ok := ClientAs[*KeyPool](client); ok {
+		t.Error("expected no KeyPool in the chain")
+	}
+}
+
 // Helper functions
 
 func equalStringSlice(a, b []string) bool {
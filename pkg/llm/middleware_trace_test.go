@@ -168,7 +168,7 @@ func TestTracingSolution(t *testing.T) {
 		choice := choices[0]
 		assert.Equal(t, "Hello World", choice["text_preview"])
 		assert.Equal(t, 11, choice["content_size"])
-		assert.Equal(t, "stop", choice["finish_reason"])
+		assert.Equal(t, FinishReasonStop, choice["finish_reason"])
 
 		// Simulate writing to trace file (the expected output)
 		traceEvent := map[string]interface{}{
@@ -0,0 +1,404 @@
+// Normalization of message ordering for providers with strict turn rules
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NormalizeStrictness selects which problems a MessageNormalizer fixes
+type NormalizeStrictness string
+
+const (
+	// NormalizeStrictnessReport only reports problems, leaving the messages untouched
+	NormalizeStrictnessReport NormalizeStrictness = "report"
+	// NormalizeStrictnessSafe applies the fixes that keep all content: merging
+	// consecutive messages, moving system messages first and moving tool results next
+	// to their calls (default)
+	NormalizeStrictnessSafe NormalizeStrictness = "safe"
+	// NormalizeStrictnessFull also applies the fixes that add or drop content: inserting
+	// a placeholder user turn and dropping orphan tool results and unanswered tool calls
+	NormalizeStrictnessFull NormalizeStrictness = "full"
+)
+
+// Fixes applied by a MessageNormalizer
+const (
+	NormalizationFixMergeConsecutive = "merge_consecutive"
+	NormalizationFixMoveSystem       = "move_system"
+	NormalizationFixInsertUserTurn   = "insert_user_turn"
+	NormalizationFixMoveToolResult   = "move_tool_result"
+	NormalizationFixDropToolResult   = "drop_orphan_tool_result"
+	NormalizationFixDropToolCall     = "drop_unanswered_tool_call"
+)
+
+// NormalizationRules are the message ordering rules a provider enforces
+type NormalizationRules struct {
+	// MergeConsecutive requires turns to alternate, merging consecutive messages with
+	// the same role (tool results excepted)
+	MergeConsecutive bool `json:"merge_consecutive"`
+
+	// SystemFirst requires system messages to precede all the others
+	SystemFirst bool `json:"system_first"`
+
+	// FirstUserTurn requires the first non-system message to be a user message
+	FirstUserTurn bool `json:"first_user_turn"`
+
+	// ToolAdjacency requires tool results to directly follow the assistant message that
+	// requested them, and every tool call to have a result
+	ToolAdjacency bool `json:"tool_adjacency"`
+}
+
+// NormalizationRulesFor returns the rules enforced by a provider. Anthropic models
+// (directly or through Bedrock) and Gemini require strictly alternating turns starting
+// with the user; the OpenAI-compatible providers only require tool messages to follow
+// their calls.
+func NormalizationRulesFor(provider string) NormalizationRules {
+	switch strings.ToLower(provider) {
+	case "anthropic", "bedrock", "gemini":
+		return NormalizationRules{MergeConsecutive: true, SystemFirst: true, FirstUserTurn: true, ToolAdjacency: true}
+	default:
+		return NormalizationRules{ToolAdjacency: true}
+	}
+}
+
+// NormalizationFix describes a problem found by a MessageNormalizer
+type NormalizationFix struct {
+	// Fix is the kind of fix (one of the NormalizationFix constants)
+	Fix string `json:"fix"`
+
+	// Index is the position of the offending message in the original messages
+	Index int `json:"index"`
+
+	// Description explains the problem
+	Description string `json:"description"`
+
+	// Applied is false when the strictness did not allow fixing the problem
+	Applied bool `json:"applied"`
+}
+
+// NormalizationReport lists the problems found by a MessageNormalizer
+type NormalizationReport struct {
+	Fixes []NormalizationFix `json:"fixes,omitempty"`
+}
+
+// Applied returns the number of fixes applied
+func (r *NormalizationReport) Applied() int {
+	n := 0
+	for _, fix := range r.Fixes {
+		if fix.Applied {
+			n++
+		}
+	}
+	return n
+}
+
+// MessageNormalizerConfig configures a MessageNormalizer
+type MessageNormalizerConfig struct {
+	// Provider selects the rules (see NormalizationRulesFor)
+	Provider string `json:"provider"`
+
+	// Rules overrides the provider rules when set
+	Rules *NormalizationRules `json:"rules,omitempty"`
+
+	// Strictness selects the fixes applied. Defaults to NormalizeStrictnessSafe.
+	Strictness NormalizeStrictness `json:"strictness"`
+
+	// PlaceholderText is the text of the user turn inserted when the conversation does
+	// not start with one
+	PlaceholderText string `json:"placeholder_text"`
+
+	// OnReport, when set, receives the report of every request with problems processed
+	// as middleware
+	OnReport func(ctx context.Context, report *NormalizationReport) `json:"-"`
+}
+
+// DefaultMessageNormalizerConfig returns the default normalizer configuration
+func DefaultMessageNormalizerConfig() *MessageNormalizerConfig {
+	return &MessageNormalizerConfig{
+		Strictness:      NormalizeStrictnessSafe,
+		PlaceholderText: "Continue.",
+	}
+}
+
+// MessageNormalizer fixes common message ordering problems (consecutive messages with
+// the same role, a missing first user turn, tool results away from their calls) that
+// some providers reject. It implements Middleware so it can be plugged into an
+// EnhancedClient.
+type MessageNormalizer struct {
+	config *MessageNormalizerConfig
+	rules  NormalizationRules
+}
+
+// NewMessageNormalizer creates a new normalizer, filling unset fields with defaults
+func NewMessageNormalizer(config *MessageNormalizerConfig) *MessageNormalizer {
+	defaults := DefaultMessageNormalizerConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Strictness == "" {
+		config.Strictness = defaults.Strictness
+	}
+	if config.PlaceholderText == "" {
+		config.PlaceholderText = defaults.PlaceholderText
+	}
+
+	rules := NormalizationRulesFor(config.Provider)
+	if config.Rules != nil {
+		rules = *config.Rules
+	}
+	return &MessageNormalizer{config: config, rules: rules}
+}
+
+// indexedMessage is a message with its position in the original messages
+type indexedMessage struct {
+	index   int
+	message Message
+}
+
+// Normalize returns the messages fixed according to the rules and strictness, and a
+// report of the problems found. The given messages are not modified.
+func (n *MessageNormalizer) Normalize(messages []Message) ([]Message, *NormalizationReport) {
+	report := &NormalizationReport{}
+	msgs := make([]indexedMessage, len(messages))
+	for i, msg := range messages {
+		msgs[i] = indexedMessage{index: i, message: msg}
+	}
+
+	if n.rules.ToolAdjacency {
+		msgs = n.fixToolAdjacency(msgs, report)
+	}
+	if n.rules.SystemFirst {
+		msgs = n.fixSystemFirst(msgs, report)
+	}
+	if n.rules.MergeConsecutive {
+		msgs = n.fixConsecutive(msgs, report)
+	}
+	if n.rules.FirstUserTurn {
+		msgs = n.fixFirstUserTurn(msgs, report)
+	}
+
+	if report.Applied() == 0 {
+		return messages, report
+	}
+	out := make([]Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = msg.message
+	}
+	return out, report
+}
+
+// record adds a problem to the report, returning whether it may be fixed
+func (n *MessageNormalizer) record(report *NormalizationReport, fix string, index int, format string, args ...interface{}) bool {
+	var applied bool
+	switch fix {
+	case NormalizationFixInsertUserTurn, NormalizationFixDropToolResult, NormalizationFixDropToolCall:
+		applied = n.config.Strictness == NormalizeStrictnessFull
+	default:
+		applied = n.config.Strictness != NormalizeStrictnessReport
+	}
+	report.Fixes = append(report.Fixes, NormalizationFix{
+		Fix:         fix,
+		Index:       index,
+		Description: fmt.Sprintf(format, args...),
+		Applied:     applied,
+	})
+	return applied
+}
+
+// fixToolAdjacency places tool results right after the assistant message with their
+// calls, dropping orphan results and unanswered calls
+func (n *MessageNormalizer) fixToolAdjacency(msgs []indexedMessage, report *NormalizationReport) []indexedMessage {
+	out := make([]indexedMessage, 0, len(msgs))
+	placed := make(map[int]bool)
+	matched := make(map[int]bool) // results left in place because they could not be moved
+
+	for i, msg := range msgs {
+		if msg.message.Role == RoleTool {
+			if placed[i] {
+				continue
+			}
+			if matched[i] {
+				out = append(out, msg)
+				continue
+			}
+			if n.record(report, NormalizationFixDropToolResult, msg.index,
+				"tool result %q does not follow a matching tool call", msg.message.ToolCallID) {
+				continue
+			}
+			out = append(out, msg)
+			continue
+		}
+		if !msg.message.HasToolCalls() {
+			out = append(out, msg)
+			continue
+		}
+
+		var results []indexedMessage
+		var unanswered []string
+		for _, call := range msg.message.ToolCalls {
+			j := findToolResult(msgs, i+1, call.ID, placed)
+			if j < 0 {
+				unanswered = append(unanswered, call.ID)
+				continue
+			}
+			if !onlyToolMessagesBetween(msgs, i, j) &&
+				!n.record(report, NormalizationFixMoveToolResult, msgs[j].index,
+					"tool result %q is separated from its call", call.ID) {
+				matched[j] = true
+				continue
+			}
+			placed[j] = true
+			results = append(results, msgs[j])
+		}
+
+		if len(unanswered) > 0 && n.record(report, NormalizationFixDropToolCall, msg.index,
+			"tool calls %s have no result", strings.Join(unanswered, ", ")) {
+			msg.message = withoutToolCalls(msg.message, unanswered)
+		}
+		if len(msg.message.Content) > 0 || msg.message.HasToolCalls() {
+			out = append(out, msg)
+		}
+		out = append(out, results...)
+	}
+	return out
+}
+
+// fixSystemFirst moves system messages before all the others
+func (n *MessageNormalizer) fixSystemFirst(msgs []indexedMessage, report *NormalizationReport) []indexedMessage {
+	var system, rest []indexedMessage
+	for _, msg := range msgs {
+		switch {
+		case msg.message.Role != RoleSystem:
+			rest = append(rest, msg)
+		case len(rest) == 0:
+			system = append(system, msg)
+		case n.record(report, NormalizationFixMoveSystem, msg.index, "system message after the conversation started"):
+			system = append(system, msg)
+		default:
+			rest = append(rest, msg)
+		}
+	}
+	return append(system, rest...)
+}
+
+// fixConsecutive merges consecutive messages with the same role
+func (n *MessageNormalizer) fixConsecutive(msgs []indexedMessage, report *NormalizationReport) []indexedMessage {
+	out := make([]indexedMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if len(out) > 0 {
+			last := &out[len(out)-1]
+			if last.message.Role == msg.message.Role && msg.message.Role != RoleTool &&
+				n.record(report, NormalizationFixMergeConsecutive, msg.index,
+					"consecutive %s messages", msg.message.Role) {
+				last.message = mergeMessages(last.message, msg.message)
+				continue
+			}
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// fixFirstUserTurn inserts a placeholder user message when the first non-system
+// message is not from the user
+func (n *MessageNormalizer) fixFirstUserTurn(msgs []indexedMessage, report *NormalizationReport) []indexedMessage {
+	first := 0
+	for first < len(msgs) && msgs[first].message.Role == RoleSystem {
+		first++
+	}
+	if first < len(msgs) && msgs[first].message.Role == RoleUser {
+		return msgs
+	}
+
+	index := len(msgs)
+	if first < len(msgs) {
+		index = msgs[first].index
+	}
+	if !n.record(report, NormalizationFixInsertUserTurn, index, "the conversation does not start with a user message") {
+		return msgs
+	}
+
+	placeholder := indexedMessage{index: -1, message: NewTextMessage(RoleUser, n.config.PlaceholderText)}
+	out := make([]indexedMessage, 0, len(msgs)+1)
+	out = append(out, msgs[:first]...)
+	out = append(out, placeholder)
+	return append(out, msgs[first:]...)
+}
+
+// findToolResult returns the index of the first unplaced tool result for a call after
+// position start, or -1
+func findToolResult(msgs []indexedMessage, start int, callID string, placed map[int]bool) int {
+	for j := start; j < len(msgs); j++ {
+		if msgs[j].message.Role == RoleTool && msgs[j].message.ToolCallID == callID && !placed[j] {
+			return j
+		}
+	}
+	return -1
+}
+
+// onlyToolMessagesBetween reports whether all the messages between positions i and j
+// are tool results
+func onlyToolMessagesBetween(msgs []indexedMessage, i, j int) bool {
+	for k := i + 1; k < j; k++ {
+		if msgs[k].message.Role != RoleTool {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutToolCalls returns a copy of the message without the given tool calls
+func withoutToolCalls(msg Message, ids []string) Message {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	var calls []ToolCall
+	for _, call := range msg.ToolCalls {
+		if !drop[call.ID] {
+			calls = append(calls, call)
+		}
+	}
+	msg.ToolCalls = calls
+	return msg
+}
+
+// mergeMessages appends the content and tool calls of b to a copy of a
+func mergeMessages(a, b Message) Message {
+	merged := a
+	merged.Content = append(append(make([]MessageContent, 0, len(a.Content)+len(b.Content)), a.Content...), b.Content...)
+	if len(b.ToolCalls) > 0 {
+		merged.ToolCalls = append(append(make([]ToolCall, 0, len(a.ToolCalls)+len(b.ToolCalls)), a.ToolCalls...), b.ToolCalls...)
+	}
+	return merged
+}
+
+// Name returns the middleware name
+func (n *MessageNormalizer) Name() string {
+	return "message_normalizer"
+}
+
+// ProcessRequest normalizes the request messages
+func (n *MessageNormalizer) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	messages, report := n.Normalize(req.Messages)
+	if len(report.Fixes) > 0 && n.config.OnReport != nil {
+		n.config.OnReport(ctx, report)
+	}
+	if report.Applied() == 0 {
+		return req, nil
+	}
+	out := *req
+	out.Messages = messages
+	return &out, nil
+}
+
+// ProcessResponse passes responses through unchanged
+func (n *MessageNormalizer) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, err
+}
+
+// ProcessStreamEvent passes events through unchanged
+func (n *MessageNormalizer) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
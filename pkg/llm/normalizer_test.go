@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// toolCallMessage creates an assistant message calling the tools with the given ids
+func toolCallMessage(ids ...string) Message {
+	msg := Message{Role: RoleAssistant}
+	for _, id := range ids {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: id, Type: "function", Function: ToolCallFunction{Name: "lookup"}})
+	}
+	return msg
+}
+
+// toolResultMessage creates the result of a tool call
+func toolResultMessage(id, text string) Message {
+	msg := NewTextMessage(RoleTool, text)
+	msg.ToolCallID = id
+	return msg
+}
+
+// normalizedFixes returns the kinds of the fixes in a report
+func normalizedFixes(report *NormalizationReport, applied bool) []string {
+	var fixes []string
+	for _, fix := range report.Fixes {
+		if fix.Applied == applied {
+			fixes = append(fixes, fix.Fix)
+		}
+	}
+	return fixes
+}
+
+func TestMessageNormalizer_Alternation(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleAssistant, "Hello, how can I help?"),
+		NewTextMessage(RoleUser, "q1"),
+		NewTextMessage(RoleUser, "q1 again"),
+		NewTextMessage(RoleSystem, "Be brief"),
+	}
+
+	t.Run("safe", func(t *testing.T) {
+		n := NewMessageNormalizer(&MessageNormalizerConfig{Provider: "bedrock"})
+		out, report := n.Normalize(messages)
+
+		if got := conversationTexts(out); !reflect.DeepEqual(got, []string{"Be brief", "Hello, how can I help?", "q1"}) {
+			t.Errorf("Unexpected messages: %q", got)
+		}
+		if len(out[2].Content) != 2 {
+			t.Errorf("Expected the user messages to be merged, got %d contents", len(out[2].Content))
+		}
+		if got := normalizedFixes(report, true); !reflect.DeepEqual(got, []string{NormalizationFixMoveSystem, NormalizationFixMergeConsecutive}) {
+			t.Errorf("Unexpected applied fixes: %v", got)
+		}
+		if got := normalizedFixes(report, false); !reflect.DeepEqual(got, []string{NormalizationFixInsertUserTurn}) {
+			t.Errorf("Unexpected skipped fixes: %v", got)
+		}
+		if messages[2].GetText() != "q1 again" || len(messages[1].Content) != 1 {
+			t.Error("Expected the original messages to be untouched")
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		n := NewMessageNormalizer(&MessageNormalizerConfig{Provider: "gemini", Strictness: NormalizeStrictnessFull})
+		out, _ := n.Normalize(messages)
+		if len(out) != 4 || out[1].Role != RoleUser || out[1].GetText() != "Continue." {
+			t.Errorf("Expected a placeholder user turn, got %q", conversationTexts(out))
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		n := NewMessageNormalizer(&MessageNormalizerConfig{Provider: "gemini", Strictness: NormalizeStrictnessReport})
+		out, report := n.Normalize(messages)
+		if &out[0] != &messages[0] || report.Applied() != 0 || len(report.Fixes) != 3 {
+			t.Errorf("Expected only a report, got %d fixes (%d applied)", len(report.Fixes), report.Applied())
+		}
+	})
+
+	t.Run("permissive provider", func(t *testing.T) {
+		_, report := NewMessageNormalizer(&MessageNormalizerConfig{Provider: "openai"}).Normalize(messages)
+		if len(report.Fixes) != 0 {
+			t.Errorf("Expected no problems for openai, got %+v", report.Fixes)
+		}
+	})
+}
+
+func TestMessageNormalizer_ToolAdjacency(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleUser, "q"),
+		toolCallMessage("a", "b", "c"),
+		toolResultMessage("b", "result b"),
+		NewTextMessage(RoleUser, "interruption"),
+		toolResultMessage("a", "result a"),
+		toolResultMessage("x", "orphan"),
+	}
+
+	n := NewMessageNormalizer(&MessageNormalizerConfig{Strictness: NormalizeStrictnessFull})
+	out, report := n.Normalize(messages)
+
+	if got := conversationTexts(out); !reflect.DeepEqual(got, []string{"q", "", "result a", "result b", "interruption"}) {
+		t.Errorf("Unexpected messages: %q", got)
+	}
+	if len(out[1].ToolCalls) != 2 || len(messages[1].ToolCalls) != 3 {
+		t.Errorf("Expected the unanswered call to be dropped from a copy, got %d calls", len(out[1].ToolCalls))
+	}
+	want := []string{NormalizationFixMoveToolResult, NormalizationFixDropToolCall, NormalizationFixDropToolResult}
+	if got := normalizedFixes(report, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected fixes: %v", got)
+	}
+
+	// Safe mode moves results but keeps all the content
+	out, report = NewMessageNormalizer(nil).Normalize(messages)
+	if len(out) != 6 || len(out[1].ToolCalls) != 3 || report.Applied() != 1 {
+		t.Errorf("Unexpected safe result: %q with %d fixes applied", conversationTexts(out), report.Applied())
+	}
+}
+
+func TestMessageNormalizer_Middleware(t *testing.T) {
+	var reports []*NormalizationReport
+	n := NewMessageNormalizer(&MessageNormalizerConfig{
+		Provider: "gemini",
+		OnReport: func(ctx context.Context, report *NormalizationReport) { reports = append(reports, report) },
+	})
+
+	mockClient := NewMockClient("test-model", "gemini")
+	client := NewEnhancedClient(mockClient, []Middleware{n})
+	_, err := client.ChatCompletion(context.Background(), ChatRequest{
+		Messages: []Message{NewTextMessage(RoleUser, "a"), NewTextMessage(RoleUser, "b")},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	sent := mockClient.GetCallLog()[0].Messages
+	if len(sent) != 1 || len(sent[0].Content) != 2 {
+		t.Errorf("Expected the messages to be merged, got %d messages", len(sent))
+	}
+	if len(reports) != 1 || reports[0].Applied() != 1 {
+		t.Errorf("Expected one report with one fix, got %v", reports)
+	}
+}
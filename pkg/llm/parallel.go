@@ -0,0 +1,158 @@
+// Concurrent execution of independent conversations
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ParallelConfig configures ParallelConversations
+type ParallelConfig struct {
+	// MaxConcurrency is the number of conversations running at the same time
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// FailFast cancels the conversations still running or waiting at the first failure.
+	// Otherwise every conversation runs, and failures are reported together.
+	FailFast bool `json:"fail_fast,omitempty"`
+
+	// Run runs a conversation, e.g. several turns or a tool loop. A single ChatCompletion
+	// is sent when nil.
+	Run func(ctx context.Context, client ChatCompleter, req ChatRequest) (*ChatResponse, error) `json:"-"`
+
+	// OnResult is called as every conversation finishes, e.g. to save its result or
+	// report progress. Calls are serialized, in completion order.
+	OnResult func(ConversationResult) `json:"-"`
+}
+
+// DefaultParallelConfig returns the default configuration of ParallelConversations
+func DefaultParallelConfig() *ParallelConfig {
+	return &ParallelConfig{MaxConcurrency: 4}
+}
+
+// ConversationResult is the outcome of a conversation run by ParallelConversations
+type ConversationResult struct {
+	// Index is the position of the request of the conversation
+	Index int `json:"index"`
+
+	Response *ChatResponse `json:"response,omitempty"`
+	Err      error         `json:"-"`
+
+	Duration time.Duration `json:"duration"`
+}
+
+// ConversationErrors is the error of ParallelConversations when some conversations
+// failed. It unwraps to their errors, so errors.As finds e.g. the *Error of a rate limit.
+type ConversationErrors struct {
+	// Total is the number of conversations
+	Total int `json:"total"`
+
+	// Failed are the results of the failed conversations, by index
+	Failed []ConversationResult `json:"failed"`
+
+	// First is the failure that happened first, the one that cancelled the others with
+	// FailFast
+	First ConversationResult `json:"first"`
+}
+
+func (e *ConversationErrors) Error() string {
+	return fmt.Sprintf("%d of %d conversations failed, first (conversation %d): %v", len(e.Failed), e.Total, e.First.Index, e.First.Err)
+}
+
+// Unwrap returns the errors of the failed conversations
+func (e *ConversationErrors) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, result := range e.Failed {
+		errs[i] = result.Err
+	}
+	return errs
+}
+
+// ParallelConversations runs independent conversations, one per request, against a
+// client, with at most MaxConcurrency of them at the same time. It returns the results
+// in the order of the requests, with a *ConversationErrors when any failed. With
+// FailFast, the conversations cancelled by a failure fail with the context error.
+//
+// Example:
+//
+//	results, err := llm.ParallelConversations(ctx, client, requests, &llm.ParallelConfig{
+//	    MaxConcurrency: 16,
+//	    OnResult: func(r llm.ConversationResult) { progress.Add(1) },
+//	})
+//	var failures *llm.ConversationErrors
+//	if errors.As(err, &failures) {
+//	    log.Printf("%d documents to retry", len(failures.Failed))
+//	}
+func ParallelConversations(ctx context.Context, client ChatCompleter, requests []ChatRequest, config *ParallelConfig) ([]ConversationResult, error) {
+	defaults := DefaultParallelConfig()
+	if config == nil {
+		config = defaults
+	}
+	workers := config.MaxConcurrency
+	if workers <= 0 {
+		workers = defaults.MaxConcurrency
+	}
+	workers = min(workers, len(requests))
+	run := config.Run
+	if run == nil {
+		run = func(ctx context.Context, client ChatCompleter, req ChatRequest) (*ChatResponse, error) {
+			return client.ChatCompletion(ctx, req)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]ConversationResult, len(requests))
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var first *ConversationResult
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := ConversationResult{Index: i}
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+				} else {
+					start := time.Now()
+					result.Response, result.Err = run(ctx, client, requests[i])
+					result.Duration = time.Since(start)
+				}
+				results[i] = result
+
+				mu.Lock()
+				if result.Err != nil && first == nil {
+					first = &result
+					if config.FailFast {
+						cancel()
+					}
+				}
+				if config.OnResult != nil {
+					config.OnResult(result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var failed []ConversationResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) > 0 {
+		return results, &ConversationErrors{Total: len(requests), Failed: failed, First: *first}
+	}
+	return results, nil
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelConversations(t *testing.T) {
+	requests := make([]ChatRequest, 20)
+	for i := range requests {
+		requests[i] = ChatRequest{Model: "model", Messages: []Message{NewTextMessage(RoleUser, string(rune('a'+i)))}}
+	}
+
+	var running, peak atomic.Int32
+	var callbacks int
+	results, err := ParallelConversations(context.Background(), NewMockClient("model", "mock"), requests, &ParallelConfig{
+		MaxConcurrency: 3,
+		Run: func(ctx context.Context, client ChatCompleter, req ChatRequest) (*ChatResponse, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			if req.Messages[0].GetText() == "e" {
+				return nil, &Error{Code: "rate_limit_exceeded", Message: "slow down", Type: "rate_limit_error"}
+			}
+			return &ChatResponse{Model: req.Messages[0].GetText()}, nil
+		},
+		OnResult: func(ConversationResult) { callbacks++ },
+	})
+
+	require.Len(t, results, 20)
+	assert.Equal(t, 20, callbacks)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		if i != 4 {
+			assert.Equal(t, string(rune('a'+i)), result.Response.Model)
+		}
+	}
+
+	var failures *ConversationErrors
+	require.ErrorAs(t, err, &failures)
+	assert.Equal(t, 20, failures.Total)
+	require.Len(t, failures.Failed, 1)
+	assert.Equal(t, 4, failures.First.Index)
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "rate_limit_exceeded", llmErr.Code)
+}
+
+func TestParallelConversations_FailFast(t *testing.T) {
+	requests := make([]ChatRequest, 50)
+	boom := errors.New("boom")
+
+	var started atomic.Int32
+	results, err := ParallelConversations(context.Background(), NewMockClient("model", "mock"), requests, &ParallelConfig{
+		MaxConcurrency: 2,
+		FailFast:       true,
+		Run: func(ctx context.Context, client ChatCompleter, req ChatRequest) (*ChatResponse, error) {
+			if started.Add(1) == 1 {
+				return nil, boom
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	var failures *ConversationErrors
+	require.ErrorAs(t, err, &failures)
+	assert.ErrorIs(t, failures.First.Err, boom)
+	assert.Len(t, failures.Failed, 50)
+	assert.Less(t, started.Load(), int32(50), "cancelled conversations are not started")
+	assert.ErrorIs(t, results[49].Err, context.Canceled)
+}
+
+func TestParallelConversations_Client(t *testing.T) {
+	// Without Run, every request is sent with ChatCompletion (one at a time, as the mock
+	// client is not safe for concurrent use)
+	mock := NewMockClient("model", "mock")
+	results, err := ParallelConversations(context.Background(), mock, make([]ChatRequest, 5), &ParallelConfig{MaxConcurrency: 1})
+	require.NoError(t, err)
+	assert.Len(t, mock.callLog, 5)
+	for _, result := range results {
+		assert.NotNil(t, result.Response)
+	}
+}
@@ -0,0 +1,291 @@
+// Rendering of PDF pages to images for vision models without PDF support
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PDFRasterizer renders the pages of a PDF document to images
+type PDFRasterizer interface {
+	// RasterizePDF renders the pages from first to last (1-based, inclusive) at the given
+	// resolution, in order. A zero last page renders to the end of the document.
+	RasterizePDF(ctx context.Context, pdf []byte, first, last, dpi int) ([]*ImageContent, error)
+}
+
+// PageRange is a range of pages of a document, 1-based and inclusive. A zero Last
+// selects up to the end of the document.
+type PageRange struct {
+	First int `json:"first"`
+	Last  int `json:"last,omitempty"`
+}
+
+// PDFRenderConfig configures a PDFRenderer
+type PDFRenderConfig struct {
+	// Rasterizer renders the pages. It defaults to a PdftoppmRasterizer.
+	Rasterizer PDFRasterizer `json:"-"`
+
+	// Pages selects the pages rendered. Empty renders the whole document.
+	Pages []PageRange `json:"pages,omitempty"`
+
+	// MaxPages limits the number of pages rendered
+	MaxPages int `json:"max_pages"`
+
+	// DPI is the resolution the pages are rendered at
+	DPI int `json:"dpi"`
+
+	// MinDPI is the lowest resolution the pages are rendered at to fit MaxTotalBytes;
+	// pages that still do not fit are omitted
+	MinDPI int `json:"min_dpi"`
+
+	// MaxTotalBytes is the budget for the size of all the images of a document
+	MaxTotalBytes int64 `json:"max_total_bytes"`
+}
+
+// DefaultPDFRenderConfig returns the default PDF rendering configuration
+func DefaultPDFRenderConfig() *PDFRenderConfig {
+	return &PDFRenderConfig{
+		MaxPages:      20,
+		DPI:           150,
+		MinDPI:        72,
+		MaxTotalBytes: 20 * 1024 * 1024,
+	}
+}
+
+// PDFRenderer is a ContentTransformer replacing PDF files with images of their pages,
+// for vision models that do not accept PDFs. It can be registered for a provider with
+// RegisterContentTransformer:
+//
+//	llm.RegisterContentTransformer("ollama", llm.MessageTypeFile, llm.NewPDFRenderer(&llm.PDFRenderConfig{
+//	    Pages: []llm.PageRange{{First: 1, Last: 5}},
+//	    DPI:   100,
+//	}))
+//
+// When the images exceed MaxTotalBytes, the pages are rendered again at a lower
+// resolution, down to MinDPI, and the last pages are then omitted, with a note telling
+// the model which ones.
+type PDFRenderer struct {
+	config *PDFRenderConfig
+}
+
+// NewPDFRenderer creates a PDF renderer, filling unset config fields with defaults
+func NewPDFRenderer(config *PDFRenderConfig) *PDFRenderer {
+	defaults := DefaultPDFRenderConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Rasterizer == nil {
+		config.Rasterizer = &PdftoppmRasterizer{}
+	}
+	if config.MaxPages <= 0 {
+		config.MaxPages = defaults.MaxPages
+	}
+	if config.DPI <= 0 {
+		config.DPI = defaults.DPI
+	}
+	if config.MinDPI <= 0 || config.MinDPI > config.DPI {
+		config.MinDPI = min(defaults.MinDPI, config.DPI)
+	}
+	if config.MaxTotalBytes <= 0 {
+		config.MaxTotalBytes = defaults.MaxTotalBytes
+	}
+	return &PDFRenderer{config: config}
+}
+
+// TransformContent implements ContentTransformer, replacing PDF files with the images of
+// their pages, each one after a "[report.pdf#page=N]" label, when the model supports
+// vision. Other content, and PDFs without data, are returned unchanged.
+func (r *PDFRenderer) TransformContent(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+	file, ok := content.(*FileContent)
+	if !ok || !model.SupportsVision || !isPDF(file) || len(file.Data) == 0 {
+		return []MessageContent{content}, nil
+	}
+
+	pages, omitted, err := r.Render(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MessageContent, 0, 2*len(pages)+1)
+	for _, page := range pages {
+		result = append(result, NewTextContent("["+page.Filename+"]"), page)
+	}
+	if omitted > 0 {
+		result = append(result, NewTextContent(fmt.Sprintf(
+			"[%s: %d of %d pages omitted to fit the size limit]", pdfName(file), omitted, len(pages)+omitted)))
+	}
+	return result, nil
+}
+
+// Render renders the selected pages of a PDF file within the size budget, returning the
+// images and the number of selected pages omitted to fit it. The filename of every image
+// is the filename of the file followed by "#page=N".
+func (r *PDFRenderer) Render(ctx context.Context, file *FileContent) ([]*ImageContent, int, error) {
+	ranges := r.config.Pages
+	if len(ranges) == 0 {
+		ranges = []PageRange{{First: 1}}
+	}
+
+	dpi := r.config.DPI
+	for {
+		pages, numbers, err := r.rasterize(ctx, file.Data, ranges, dpi)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, page := range pages {
+			page.Filename = fmt.Sprintf("%s#page=%d", pdfName(file), numbers[i])
+		}
+
+		total := imagesSize(pages)
+		if total <= r.config.MaxTotalBytes {
+			return pages, 0, nil
+		}
+		if dpi > r.config.MinDPI {
+			// The size of an image grows with the square of the resolution
+			scale := math.Sqrt(float64(r.config.MaxTotalBytes)/float64(total)) * 0.95
+			dpi = max(r.config.MinDPI, min(dpi-1, int(float64(dpi)*scale)))
+			continue
+		}
+
+		var size int64
+		for i, page := range pages {
+			size += int64(len(page.Data))
+			if size > r.config.MaxTotalBytes {
+				if i == 0 {
+					return nil, 0, &Error{
+						Code:    "pdf_too_large",
+						Message: fmt.Sprintf("page %d of %s does not fit in %d bytes at %d DPI", numbers[0], pdfName(file), r.config.MaxTotalBytes, dpi),
+						Type:    "validation_error",
+					}
+				}
+				return pages[:i], len(pages) - i, nil
+			}
+		}
+	}
+}
+
+// rasterize renders the page ranges, up to MaxPages, returning the images and their
+// page numbers
+func (r *PDFRenderer) rasterize(ctx context.Context, pdf []byte, ranges []PageRange, dpi int) ([]*ImageContent, []int, error) {
+	var pages []*ImageContent
+	var numbers []int
+	for _, pageRange := range ranges {
+		remaining := r.config.MaxPages - len(pages)
+		if remaining <= 0 {
+			break
+		}
+		first := max(pageRange.First, 1)
+		last := first + remaining - 1
+		if pageRange.Last > 0 {
+			last = min(pageRange.Last, last)
+		}
+		if last < first {
+			continue
+		}
+
+		images, err := r.config.Rasterizer.RasterizePDF(ctx, pdf, first, last, dpi)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, image := range images[:min(len(images), remaining)] {
+			pages = append(pages, image)
+			numbers = append(numbers, first+i)
+		}
+	}
+	return pages, numbers, nil
+}
+
+// isPDF reports whether a file is a PDF document
+func isPDF(file *FileContent) bool {
+	return file.MimeType == "application/pdf" || bytes.HasPrefix(file.Data, []byte("%PDF-"))
+}
+
+// pdfName returns the filename of a PDF file, or a generic one when it has none
+func pdfName(file *FileContent) string {
+	if file.Filename == "" {
+		return "document.pdf"
+	}
+	return file.Filename
+}
+
+// imagesSize returns the total size of the data of images
+func imagesSize(images []*ImageContent) int64 {
+	var total int64
+	for _, image := range images {
+		total += int64(len(image.Data))
+	}
+	return total
+}
+
+// PdftoppmRasterizer renders PDF pages to PNG images with the pdftoppm command of Poppler
+// (https://poppler.freedesktop.org), which must be installed
+type PdftoppmRasterizer struct {
+	// Path is the path of the pdftoppm command. It defaults to "pdftoppm", looked up in
+	// the PATH.
+	Path string
+}
+
+// RasterizePDF implements PDFRasterizer
+func (p *PdftoppmRasterizer) RasterizePDF(ctx context.Context, pdf []byte, first, last, dpi int) ([]*ImageContent, error) {
+	command := p.Path
+	if command == "" {
+		command = "pdftoppm"
+	}
+
+	dir, err := os.MkdirTemp("", "go-llm-pdf-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	args := []string{"-png", "-r", strconv.Itoa(dpi), "-f", strconv.Itoa(first)}
+	if last > 0 {
+		args = append(args, "-l", strconv.Itoa(last))
+	}
+	args = append(args, "-", filepath.Join(dir, "page"))
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(pdf)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, &Error{
+				Code:    "pdf_renderer_unavailable",
+				Message: fmt.Sprintf("%s not found: install Poppler or configure another PDFRasterizer", command),
+				Type:    "validation_error",
+			}
+		}
+		return nil, &Error{
+			Code:    "pdf_render_failed",
+			Message: fmt.Sprintf("failed to render PDF pages: %v: %s", err, strings.TrimSpace(stderr.String())),
+			Type:    "validation_error",
+		}
+	}
+
+	// Pages are written as page-N.png, with N zero-padded to the same width
+	files, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	images := make([]*ImageContent, 0, len(files))
+	for _, file := range files {
+		// #nosec G304 - Files written by pdftoppm in our temporary directory
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, NewImageContentFromBytes(data, "image/png"))
+	}
+	return images, nil
+}
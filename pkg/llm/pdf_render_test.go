@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRasterizer renders documents of a fixed number of pages to images whose size
+// grows with the square of the resolution
+type fakeRasterizer struct {
+	pages int
+	calls []PageRange
+	dpis  []int
+}
+
+func (f *fakeRasterizer) RasterizePDF(ctx context.Context, pdf []byte, first, last, dpi int) ([]*ImageContent, error) {
+	f.calls = append(f.calls, PageRange{First: first, Last: last})
+	f.dpis = append(f.dpis, dpi)
+	if last == 0 || last > f.pages {
+		last = f.pages
+	}
+	var images []*ImageContent
+	for page := first; page <= last; page++ {
+		images = append(images, NewImageContentFromBytes(make([]byte, dpi*dpi/10), "image/png"))
+	}
+	return images, nil
+}
+
+func pdfFile() *FileContent {
+	return NewFileContentFromBytes([]byte("%PDF-1.7 ..."), "report.pdf", "application/pdf")
+}
+
+func TestPDFRendererPageRanges(t *testing.T) {
+	rasterizer := &fakeRasterizer{pages: 10}
+	renderer := NewPDFRenderer(&PDFRenderConfig{
+		Rasterizer: rasterizer,
+		Pages:      []PageRange{{First: 2, Last: 3}, {First: 8}},
+		MaxPages:   4,
+	})
+
+	pages, omitted, err := renderer.Render(context.Background(), pdfFile())
+	require.NoError(t, err)
+	assert.Zero(t, omitted)
+	require.Len(t, pages, 4)
+	assert.Equal(t, []PageRange{{First: 2, Last: 3}, {First: 8, Last: 9}}, rasterizer.calls)
+	assert.Equal(t, "report.pdf#page=2", pages[0].Filename)
+	assert.Equal(t, "report.pdf#page=9", pages[3].Filename)
+	assert.Equal(t, []int{150, 150}, rasterizer.dpis)
+}
+
+func TestPDFRendererBudget(t *testing.T) {
+	// Every page is 2250 bytes at 150 DPI and 518 bytes at 72 DPI
+	rasterizer := &fakeRasterizer{pages: 4}
+	renderer := NewPDFRenderer(&PDFRenderConfig{Rasterizer: rasterizer, MaxTotalBytes: 5000})
+	pages, omitted, err := renderer.Render(context.Background(), pdfFile())
+	require.NoError(t, err)
+	assert.Zero(t, omitted)
+	assert.Len(t, pages, 4)
+	assert.LessOrEqual(t, imagesSize(pages), int64(5000))
+	assert.Less(t, rasterizer.dpis[len(rasterizer.dpis)-1], 150, "pages should be rendered again at a lower resolution")
+
+	// Pages that do not fit at the minimum resolution are omitted
+	renderer = NewPDFRenderer(&PDFRenderConfig{Rasterizer: &fakeRasterizer{pages: 4}, MaxTotalBytes: 1200})
+	pages, omitted, err = renderer.Render(context.Background(), pdfFile())
+	require.NoError(t, err)
+	assert.Len(t, pages, 2)
+	assert.Equal(t, 2, omitted)
+
+	_, _, err = NewPDFRenderer(&PDFRenderConfig{Rasterizer: &fakeRasterizer{pages: 4}, MaxTotalBytes: 100}).
+		Render(context.Background(), pdfFile())
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "pdf_too_large", llmErr.Code)
+}
+
+func TestPDFRendererTransformContent(t *testing.T) {
+	renderer := NewPDFRenderer(&PDFRenderConfig{Rasterizer: &fakeRasterizer{pages: 2}, MaxTotalBytes: 1000})
+	vision := ModelInfo{Provider: "test", SupportsVision: true}
+
+	content, err := renderer.TransformContent(context.Background(), pdfFile(), vision)
+	require.NoError(t, err)
+	require.Len(t, content, 3)
+	assert.Equal(t, "[report.pdf#page=1]", content[0].(*TextContent).Text)
+	assert.IsType(t, &ImageContent{}, content[1])
+	assert.Equal(t, "[report.pdf: 1 of 2 pages omitted to fit the size limit]", content[2].(*TextContent).Text)
+
+	// Models without vision and other files are left to the provider
+	text := NewFileContentFromBytes([]byte("hello"), "notes.txt", "text/plain")
+	content, err = renderer.TransformContent(context.Background(), text, vision)
+	require.NoError(t, err)
+	assert.Equal(t, []MessageContent{text}, content)
+	file := pdfFile()
+	content, err = renderer.TransformContent(context.Background(), file, ModelInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, []MessageContent{file}, content)
+}
+
+func TestPdftoppmRasterizerNotFound(t *testing.T) {
+	rasterizer := &PdftoppmRasterizer{Path: "go-llm-missing-pdftoppm"}
+	_, err := rasterizer.RasterizePDF(context.Background(), []byte("%PDF-1.7"), 1, 0, 72)
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "pdf_renderer_unavailable", llmErr.Code)
+}
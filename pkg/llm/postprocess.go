@@ -0,0 +1,173 @@
+// Composable post-processing of response text
+package llm
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// ResponseProcessor transforms the text of a response. Processors are composed with
+// ProcessText, applied to responses with ProcessResponse or PostProcessingMiddleware.
+type ResponseProcessor func(text string) string
+
+// ProcessText applies processors to a text, in order
+func ProcessText(text string, processors ...ResponseProcessor) string {
+	for _, process := range processors {
+		text = process(text)
+	}
+	return text
+}
+
+// ProcessResponse applies processors to the text of every choice of a response. The
+// text contents are replaced, so the contents of other copies of the response are not
+// modified.
+func ProcessResponse(resp *ChatResponse, processors ...ResponseProcessor) {
+	if resp == nil || len(processors) == 0 {
+		return
+	}
+	for i := range resp.Choices {
+		content := resp.Choices[i].Message.Content
+		processed := make([]MessageContent, len(content))
+		for j, part := range content {
+			if text, ok := part.(*TextContent); ok {
+				part = NewTextContent(ProcessText(text.Text, processors...))
+			}
+			processed[j] = part
+		}
+		resp.Choices[i].Message.Content = processed
+	}
+}
+
+// fencedBlock matches text wrapped in a single markdown code fence, with an optional
+// language tag
+var fencedBlock = regexp.MustCompile("^\\s*```[\\w+-]*[ \\t]*\\r?\\n?([\\s\\S]*?)\\r?\\n?```\\s*$")
+
+// StripCodeFences removes a markdown code fence wrapping the whole text (e.g. JSON
+// answered as "```json ... ```"). Text with fences among other prose is kept.
+func StripCodeFences() ResponseProcessor {
+	return func(text string) string {
+		if match := fencedBlock.FindStringSubmatch(text); match != nil {
+			return match[1]
+		}
+		return text
+	}
+}
+
+// ExtractJSON replaces the text with the JSON it contains, from a code block or inline
+// (see ExtractJSONFromResponse). Text without JSON is kept.
+func ExtractJSON() ResponseProcessor {
+	return func(text string) string {
+		if extracted := ExtractJSONFromResponse(text); extracted != "" {
+			return extracted
+		}
+		return text
+	}
+}
+
+// TrimAtStopSequences cuts the text at the first occurrence of any of the stop
+// sequences, for providers and models that do not honor them
+func TrimAtStopSequences(stops ...string) ResponseProcessor {
+	return func(text string) string {
+		cut := len(text)
+		for _, stop := range stops {
+			if stop == "" {
+				continue
+			}
+			if i := strings.Index(text[:cut], stop); i >= 0 {
+				cut = i
+			}
+		}
+		return text[:cut]
+	}
+}
+
+// blankLines matches runs of more than one empty line
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// NormalizeWhitespace converts line endings to "\n", removes trailing spaces from
+// lines, collapses runs of empty lines into one and trims the text
+func NormalizeWhitespace() ResponseProcessor {
+	return func(text string) string {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+		return strings.TrimSpace(text)
+	}
+}
+
+// DefaultReasoningTags are the tags removed by RemoveReasoning when none are given
+var DefaultReasoningTags = []string{"think", "thinking", "reasoning"}
+
+// RemoveReasoning removes the chain-of-thought blocks some models write in their
+// answer (e.g. "<think>...</think>"), for the given tags or DefaultReasoningTags. A
+// block left open at the start of the text, as when the model was cut off while
+// thinking, is removed to the end.
+func RemoveReasoning(tags ...string) ResponseProcessor {
+	if len(tags) == 0 {
+		tags = DefaultReasoningTags
+	}
+	return func(text string) string {
+		for _, tag := range tags {
+			text = RemoveBlocks(text, tag)
+			if open := "<" + tag + ">"; strings.HasPrefix(strings.TrimSpace(text), open) {
+				text = ""
+			}
+		}
+		return strings.TrimLeft(text, " \t\r\n")
+	}
+}
+
+// responseProcessorsKey is the context key of the processors of a request
+type responseProcessorsKey struct{}
+
+// WithResponseProcessors returns a context whose requests are post-processed with the
+// given processors by PostProcessingMiddleware, instead of its default ones. An empty
+// list disables post-processing.
+func WithResponseProcessors(ctx context.Context, processors ...ResponseProcessor) context.Context {
+	return context.WithValue(ctx, responseProcessorsKey{}, processors)
+}
+
+// PostProcessingMiddleware applies response processors to the text of responses, e.g.
+// to strip code fences and reasoning blocks before parsing. Requests can override the
+// processors with WithResponseProcessors. Streams are passed through unchanged.
+type PostProcessingMiddleware struct {
+	processors []ResponseProcessor
+}
+
+// NewPostProcessingMiddleware creates a middleware applying the processors, in order
+func NewPostProcessingMiddleware(processors ...ResponseProcessor) *PostProcessingMiddleware {
+	return &PostProcessingMiddleware{processors: processors}
+}
+
+// Name returns the middleware name
+func (m *PostProcessingMiddleware) Name() string {
+	return "post_processing"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *PostProcessingMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse applies the processors of the request, or the default ones, to the
+// response
+func (m *PostProcessingMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	processors := m.processors
+	if override, ok := ctx.Value(responseProcessorsKey{}).([]ResponseProcessor); ok {
+		processors = override
+	}
+	ProcessResponse(resp, processors...)
+	return resp, nil
+}
+
+// ProcessStreamEvent passes events through unchanged
+func (m *PostProcessingMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
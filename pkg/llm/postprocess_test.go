@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseProcessors(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor ResponseProcessor
+		input     string
+		want      string
+	}{
+		{"fenced json", StripCodeFences(), "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"fence without language", StripCodeFences(), "  ```\nplain\n```\n", "plain"},
+		{"fence among prose", StripCodeFences(), "See:\n```go\nx := 1\n```\nDone", "See:\n```go\nx := 1\n```\nDone"},
+		{"extract json", ExtractJSON(), "Here it is: `{\"a\": 1}`", `{"a": 1}`},
+		{"stop sequences", TrimAtStopSequences("\nUser:", "###"), "Answer\nUser: more ### end", "Answer"},
+		{"no stop sequence", TrimAtStopSequences("END"), "Answer", "Answer"},
+		{"whitespace", NormalizeWhitespace(), "  a  \r\nb\t\n\n\n\nc\n", "a\nb\n\nc"},
+		{"reasoning", RemoveReasoning(), "<think>\nsteps\n</think>\n\nAnswer <thinking>x</thinking>", "Answer "},
+		{"unterminated reasoning", RemoveReasoning(), "<think>steps that never end", ""},
+		{"custom tags", RemoveReasoning("scratchpad"), "<scratchpad>x</scratchpad>Answer <think>y</think>", "Answer <think>y</think>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.processor(tt.input))
+		})
+	}
+}
+
+func TestProcessTextComposes(t *testing.T) {
+	text := "<think>plan</think>\n```json\n{\"a\": 1}\n```\n"
+	assert.Equal(t, `{"a": 1}`, ProcessText(text, RemoveReasoning(), StripCodeFences(), NormalizeWhitespace()))
+}
+
+func TestPostProcessingMiddleware(t *testing.T) {
+	client := NewMockClient("test-model", "test")
+	shared := NewTextContent("<think>plan</think>Answer\n\n\n\nEND ignored")
+	client.responses = []*ChatResponse{
+		{Choices: []Choice{{Message: Message{Role: RoleAssistant, Content: []MessageContent{shared}}}}},
+		{Choices: []Choice{{Message: Message{Role: RoleAssistant, Content: []MessageContent{shared}}}}},
+	}
+	enhanced := NewEnhancedClient(client, []Middleware{
+		NewPostProcessingMiddleware(RemoveReasoning(), TrimAtStopSequences("END"), NormalizeWhitespace()),
+	})
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Hi")}}
+
+	resp, err := enhanced.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "Answer", resp.Choices[0].Message.GetText())
+	assert.Contains(t, shared.Text, "<think>", "the original content should not be modified")
+
+	// Requests can override the processors
+	ctx := WithResponseProcessors(context.Background(), RemoveReasoning())
+	resp, err = enhanced.ChatCompletion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Answer\n\n\n\nEND ignored", resp.Choices[0].Message.GetText())
+}
@@ -0,0 +1,307 @@
+// Empirical probing of model capabilities
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"time"
+)
+
+// Capabilities checked by ProbeCapabilities
+const (
+	CapabilityTools     = "tools"
+	CapabilityJSONMode  = "json_mode"
+	CapabilityVision    = "vision"
+	CapabilityStreaming = "streaming"
+	CapabilityMaxOutput = "max_output"
+)
+
+// probeToolName is the tool the model is asked to call in the tools probe
+const probeToolName = "get_probe_value"
+
+// ProbeConfig configures ProbeCapabilities
+type ProbeConfig struct {
+	// Timeout limits each probe request
+	Timeout time.Duration `json:"timeout"`
+
+	// Capabilities selects the capabilities to probe (all of them when empty)
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// MaxOutputCandidates are the max_tokens values tried, in order, by the max output
+	// probe; the first one accepted is reported
+	MaxOutputCandidates []int `json:"max_output_candidates,omitempty"`
+}
+
+// DefaultProbeConfig returns the default probe configuration
+func DefaultProbeConfig() *ProbeConfig {
+	return &ProbeConfig{
+		Timeout:             30 * time.Second,
+		MaxOutputCandidates: []int{131072, 65536, 32768, 16384, 8192, 4096, 2048, 1024},
+	}
+}
+
+// ProbeResult is the outcome of probing one capability
+type ProbeResult struct {
+	Capability string        `json:"capability"`
+	Supported  bool          `json:"supported"`
+	Latency    time.Duration `json:"latency"`
+
+	// Error is the error that made the probe fail, if any
+	Error *Error `json:"error,omitempty"`
+}
+
+// CapabilityReport holds the capabilities verified by ProbeCapabilities
+type CapabilityReport struct {
+	// ModelInfo is the client model information with the capability flags replaced by
+	// the probed ones
+	ModelInfo ModelInfo `json:"model_info"`
+
+	// SupportsJSONMode reports whether the model accepts a native JSON response format
+	SupportsJSONMode bool `json:"supports_json_mode"`
+
+	// MaxOutputTokens is the largest max_tokens candidate accepted (0 when not probed
+	// or none was accepted)
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// Results holds the outcome of each probe
+	Results []ProbeResult `json:"results"`
+}
+
+// ProbeCapabilities empirically tests the model of a client with tiny requests for tool
+// calling, native JSON mode, vision, streaming and maximum output size, so the static
+// metadata of unknown models (e.g. from OpenRouter or Ollama) can be verified. A failed
+// probe marks the capability as unsupported; an error is only returned when the model
+// cannot answer a basic request or the context is done. The default configuration is
+// used when config is nil.
+func ProbeCapabilities(ctx context.Context, client Client, config *ProbeConfig) (*CapabilityReport, error) {
+	if config == nil {
+		config = DefaultProbeConfig()
+	}
+	p := &prober{client: client, config: config}
+
+	// A model that cannot answer a basic request cannot be probed
+	if _, err := p.chat(ctx, ChatRequest{Messages: probeMessages("Reply with the word OK.")}); err != nil {
+		return nil, err
+	}
+
+	report := &CapabilityReport{ModelInfo: client.GetModelInfo()}
+	probes := []struct {
+		capability string
+		probe      func(ctx context.Context) (int, error)
+		supported  *bool
+	}{
+		{CapabilityTools, p.probeTools, &report.ModelInfo.SupportsTools},
+		{CapabilityJSONMode, p.probeJSONMode, &report.SupportsJSONMode},
+		{CapabilityVision, p.probeVision, &report.ModelInfo.SupportsVision},
+		{CapabilityStreaming, p.probeStreaming, &report.ModelInfo.SupportsStreaming},
+		{CapabilityMaxOutput, p.probeMaxOutput, nil},
+	}
+
+	for _, probe := range probes {
+		if !p.enabled(probe.capability) {
+			continue
+		}
+		start := time.Now()
+		value, err := probe.probe(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		result := ProbeResult{Capability: probe.capability, Supported: err == nil, Latency: time.Since(start)}
+		if err != nil {
+			result.Error = probeError(err)
+		}
+		if probe.supported != nil {
+			*probe.supported = result.Supported
+		}
+		if probe.capability == CapabilityMaxOutput {
+			report.MaxOutputTokens = value
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// prober runs the probes of ProbeCapabilities
+type prober struct {
+	client Client
+	config *ProbeConfig
+}
+
+// enabled reports whether a capability should be probed
+func (p *prober) enabled(capability string) bool {
+	if len(p.config.Capabilities) == 0 {
+		return true
+	}
+	for _, c := range p.config.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// chat sends a probe request with the probe timeout
+func (p *prober) chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+	if req.MaxTokens == nil {
+		maxTokens := 64
+		req.MaxTokens = &maxTokens
+	}
+	resp, err := p.client.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, &Error{Code: "empty_response", Message: "probe response has no choices", Type: "probe_error"}
+	}
+	return resp, nil
+}
+
+// probeTools asks the model to call a tool
+func (p *prober) probeTools(ctx context.Context) (int, error) {
+	resp, err := p.chat(ctx, ChatRequest{
+		Messages: probeMessages("Call the " + probeToolName + " tool. Do not answer with text."),
+		Tools: []Tool{{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        probeToolName,
+				Description: "Returns the probe value",
+				Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := resp.Choices[0].Message.GetToolCallByName(probeToolName); !ok {
+		return 0, probeFailure("the model did not call the probe tool")
+	}
+	return 0, nil
+}
+
+// probeJSONMode requests a native JSON object response
+func (p *prober) probeJSONMode(ctx context.Context) (int, error) {
+	resp, err := p.chat(ctx, ChatRequest{
+		Messages:             probeMessages(`Reply with the JSON object {"ok": true}.`),
+		ResponseFormat:       &ResponseFormat{Type: ResponseFormatJSON},
+		ResponseFormatPolicy: ResponseFormatPolicyRequireNative,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.GetText()), &value); err != nil {
+		return 0, probeFailure("the response is not a JSON object")
+	}
+	return 0, nil
+}
+
+// probeVision sends a tiny image
+func (p *prober) probeVision(ctx context.Context) (int, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return 0, err
+	}
+
+	_, err := p.chat(ctx, ChatRequest{
+		Messages: []Message{{
+			Role: RoleUser,
+			Content: []MessageContent{
+				NewTextContent("What color is this image? Answer with one word."),
+				NewImageContentFromBytes(buf.Bytes(), "image/png"),
+			},
+		}},
+	})
+	return 0, err
+}
+
+// probeStreaming streams a short answer, requiring at least one delta and no error
+func (p *prober) probeStreaming(ctx context.Context) (int, error) {
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+	maxTokens := 64
+	events, err := p.client.StreamChatCompletion(ctx, ChatRequest{
+		Messages:  probeMessages("Reply with the word OK."),
+		MaxTokens: &maxTokens,
+		Stream:    true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	deltas := 0
+	for event := range events {
+		switch {
+		case event.IsError():
+			return 0, event.Error
+		case event.IsDelta():
+			deltas++
+		}
+	}
+	if deltas == 0 {
+		return 0, probeFailure("the stream produced no deltas")
+	}
+	return 0, nil
+}
+
+// probeMaxOutput returns the first max_tokens candidate the model accepts
+func (p *prober) probeMaxOutput(ctx context.Context) (int, error) {
+	var lastErr error
+	for _, candidate := range p.config.MaxOutputCandidates {
+		maxTokens := candidate
+		_, err := p.chat(ctx, ChatRequest{
+			Messages:  probeMessages("Reply with the word OK."),
+			MaxTokens: &maxTokens,
+		})
+		if err == nil {
+			return candidate, nil
+		}
+		if ctx.Err() != nil {
+			return 0, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = probeFailure("no max_tokens candidates configured")
+	}
+	return 0, lastErr
+}
+
+// probeMessages returns the messages of a single-question probe
+func probeMessages(question string) []Message {
+	return []Message{NewTextMessage(RoleUser, question)}
+}
+
+// probeFailure creates the error of a probe whose request succeeded but whose response
+// shows the capability is missing
+func probeFailure(message string) *Error {
+	return &Error{Code: "capability_not_supported", Message: message, Type: "probe_error"}
+}
+
+// probeError converts a probe error to an *Error
+func probeError(err error) *Error {
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		return llmErr
+	}
+	return &Error{Code: "probe_failed", Message: err.Error(), Type: "probe_error"}
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+// probeTestClient emulates a model that supports tools and JSON mode, rejects images
+// and max_tokens above 8192, and streams answers
+type probeTestClient struct {
+	testMockClient
+}
+
+func (c *probeTestClient) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	for _, msg := range req.Messages {
+		for _, content := range msg.Content {
+			if content.Type() == MessageTypeImage {
+				return nil, &Error{Code: "invalid_request", Message: "images are not supported", Type: "invalid_request_error"}
+			}
+		}
+	}
+	if req.MaxTokens != nil && *req.MaxTokens > 8192 {
+		return nil, &Error{Code: "invalid_request", Message: "max_tokens is too large", Type: "invalid_request_error"}
+	}
+
+	msg := NewTextMessage(RoleAssistant, "OK")
+	switch {
+	case len(req.Tools) > 0:
+		msg.ToolCalls = []ToolCall{{ID: "1", Type: "function", Function: ToolCallFunction{Name: req.Tools[0].Function.Name, Arguments: "{}"}}}
+	case req.ResponseFormat.IsJSON():
+		msg = NewTextMessage(RoleAssistant, `{"ok": true}`)
+	}
+	return &ChatResponse{Choices: []Choice{{Message: msg}}}, nil
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	client := &probeTestClient{testMockClient: *NewMockClient("unknown-model", "openrouter")}
+	client.WithStreamResponse([]StreamEvent{
+		NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("OK")}}),
+		NewDoneEvent(0, "stop"),
+	})
+
+	report, err := ProbeCapabilities(context.Background(), client, nil)
+	if err != nil {
+		t.Fatalf("ProbeCapabilities failed: %v", err)
+	}
+
+	info := report.ModelInfo
+	if !info.SupportsTools || info.SupportsVision || !info.SupportsStreaming || !report.SupportsJSONMode {
+		t.Errorf("Unexpected capabilities: %+v, json mode %v", info, report.SupportsJSONMode)
+	}
+	if report.MaxOutputTokens != 8192 {
+		t.Errorf("Expected max output 8192, got %d", report.MaxOutputTokens)
+	}
+	if len(report.Results) != 5 {
+		t.Fatalf("Expected 5 probe results, got %d", len(report.Results))
+	}
+	for _, result := range report.Results {
+		if result.Capability == CapabilityVision && (result.Supported || result.Error == nil) {
+			t.Errorf("Expected vision probe to fail with an error, got %+v", result)
+		}
+	}
+}
+
+func TestProbeCapabilities_SelectedAndUnreachable(t *testing.T) {
+	client := NewMockClient("m", "p")
+	client.responses = []*ChatResponse{
+		{Choices: []Choice{{Message: NewTextMessage(RoleAssistant, "OK")}}},
+		{Choices: []Choice{{Message: NewTextMessage(RoleAssistant, "I can't call tools")}}},
+	}
+	report, err := ProbeCapabilities(context.Background(), client, &ProbeConfig{Capabilities: []string{CapabilityTools}})
+	if err != nil {
+		t.Fatalf("ProbeCapabilities failed: %v", err)
+	}
+	if len(report.Results) != 1 || report.ModelInfo.SupportsTools {
+		t.Errorf("Expected a single failed tools probe, got %+v", report.Results)
+	}
+	if report.Results[0].Error.Code != "capability_not_supported" {
+		t.Errorf("Unexpected error: %v", report.Results[0].Error)
+	}
+
+	failing := NewMockClient("m", "p")
+	failing.errorToReturn = &Error{Code: "authentication_error", Message: "bad key", Type: "auth_error"}
+	if _, err := ProbeCapabilities(context.Background(), failing, nil); err == nil {
+		t.Error("Expected an error when the model cannot answer")
+	}
+}
@@ -0,0 +1,44 @@
+// Provider-specific request options
+package llm
+
+// ProviderOption is a request setting understood by a single provider (logit bias for
+// OpenAI, safety thresholds for Gemini, guardrails for Bedrock...). Provider packages
+// define the option types and the helpers creating them (e.g. openai.WithLogitBias), so
+// requests are checked at compile time. Providers only read their own options and pass
+// the rest through untouched, so the same request can be sent to any provider.
+type ProviderOption interface {
+	// Provider returns the name of the provider the option is for (e.g. "openai")
+	Provider() string
+}
+
+// WithProviderOptions returns a copy of the request with the options appended
+func (r ChatRequest) WithProviderOptions(options ...ProviderOption) ChatRequest {
+	merged := make([]ProviderOption, 0, len(r.ProviderOptions)+len(options))
+	merged = append(merged, r.ProviderOptions...)
+	r.ProviderOptions = append(merged, options...)
+	return r
+}
+
+// ProviderOptionsFor returns the options of the request for a provider, in order
+func (r ChatRequest) ProviderOptionsFor(provider string) []ProviderOption {
+	var options []ProviderOption
+	for _, option := range r.ProviderOptions {
+		if option != nil && option.Provider() == provider {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// FindProviderOption returns the last option of type T in a request, so options added
+// later override earlier ones
+func FindProviderOption[T ProviderOption](req ChatRequest) (T, bool) {
+	var found T
+	ok := false
+	for _, option := range req.ProviderOptions {
+		if typed, match := option.(T); match {
+			found, ok = typed, true
+		}
+	}
+	return found, ok
+}
@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+// testProviderOption is a provider option carrying a value
+type testProviderOption struct {
+	provider string
+	value    int
+}
+
+func (o testProviderOption) Provider() string { return o.provider }
+
+func TestProviderOptions(t *testing.T) {
+	req := ChatRequest{}.WithProviderOptions(
+		testProviderOption{provider: "openai", value: 1},
+		testProviderOption{provider: "gemini", value: 2},
+	)
+	extended := req.WithProviderOptions(testProviderOption{provider: "openai", value: 3})
+
+	if len(req.ProviderOptions) != 2 || len(extended.ProviderOptions) != 3 {
+		t.Fatalf("Expected 2 and 3 options, got %d and %d", len(req.ProviderOptions), len(extended.ProviderOptions))
+	}
+	if options := extended.ProviderOptionsFor("openai"); len(options) != 2 {
+		t.Errorf("Expected 2 openai options, got %d", len(options))
+	}
+
+	// The last option of a type wins
+	option, ok := FindProviderOption[testProviderOption](extended)
+	if !ok || option.value != 3 {
+		t.Errorf("Expected the last option, got %+v", option)
+	}
+	if _, ok := FindProviderOption[testProviderOption](ChatRequest{}); ok {
+		t.Error("Expected no option in an empty request")
+	}
+}
@@ -0,0 +1,126 @@
+// Retrieval-augmented generation: context retrieved from a vector store
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RAGConfig configures a RAGMiddleware
+type RAGConfig struct {
+	// TopK is the number of documents retrieved for a request
+	TopK int `json:"top_k"`
+
+	// MinScore drops the documents scoring less, when not zero. Scores depend on the
+	// store (see ScoredDocument).
+	MinScore float64 `json:"min_score,omitempty"`
+
+	// Prompt introduces the retrieved documents in the system message added to requests
+	Prompt string `json:"prompt"`
+}
+
+// DefaultRAGConfig returns the default retrieval configuration
+func DefaultRAGConfig() *RAGConfig {
+	return &RAGConfig{
+		TopK:   4,
+		Prompt: "Use the following context to answer the user. Ignore it if it is not relevant.",
+	}
+}
+
+// RAGMiddleware retrieves the documents of a VectorStore most similar to the last user
+// message of a request and adds them as a system message, after the system messages of
+// the request. Requests without a user message, or without matching documents, are
+// sent unchanged. Retrieval errors fail the request.
+type RAGMiddleware struct {
+	store  VectorStore
+	config *RAGConfig
+}
+
+// NewRAGMiddleware creates a retrieval middleware, filling unset config fields with
+// defaults
+func NewRAGMiddleware(store VectorStore, config *RAGConfig) *RAGMiddleware {
+	defaults := DefaultRAGConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.TopK <= 0 {
+		config.TopK = defaults.TopK
+	}
+	if config.Prompt == "" {
+		config.Prompt = defaults.Prompt
+	}
+	return &RAGMiddleware{store: store, config: config}
+}
+
+// Name returns the middleware name
+func (m *RAGMiddleware) Name() string {
+	return "rag"
+}
+
+// ProcessRequest adds the documents retrieved for the last user message
+func (m *RAGMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	if req == nil {
+		return req, nil
+	}
+	query := lastUserText(req.Messages)
+	if query == "" {
+		return req, nil
+	}
+
+	docs, err := m.store.Query(ctx, query, m.config.TopK)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving context: %w", err)
+	}
+	var kept []ScoredDocument
+	for _, doc := range docs {
+		if m.config.MinScore == 0 || doc.Score >= m.config.MinScore {
+			kept = append(kept, doc)
+		}
+	}
+	if len(kept) == 0 {
+		return req, nil
+	}
+
+	// After the leading system messages, as some providers only accept them first
+	at := 0
+	for at < len(req.Messages) && req.Messages[at].Role == RoleSystem {
+		at++
+	}
+	processed := *req
+	processed.Messages = make([]Message, 0, len(req.Messages)+1)
+	processed.Messages = append(processed.Messages, req.Messages[:at]...)
+	processed.Messages = append(processed.Messages, NewTextMessage(RoleSystem, m.contextText(kept)))
+	processed.Messages = append(processed.Messages, req.Messages[at:]...)
+	return &processed, nil
+}
+
+// ProcessResponse passes responses through unchanged
+func (m *RAGMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, err
+}
+
+// ProcessStreamEvent passes stream events through unchanged
+func (m *RAGMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// contextText formats the retrieved documents, numbered, after the prompt
+func (m *RAGMiddleware) contextText(docs []ScoredDocument) string {
+	var text strings.Builder
+	text.WriteString(m.config.Prompt)
+	for i, doc := range docs {
+		fmt.Fprintf(&text, "\n\n[%d] %s", i+1, doc.Text)
+	}
+	return text.String()
+}
+
+// lastUserText returns the text of the last user message
+func lastUserText(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return strings.TrimSpace(messages[i].GetText())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+// Rate-limit details reported by providers
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitInfo holds the rate-limit details a provider reported with a response, so
+// retry and fallback components can wait exactly as long as needed instead of guessing
+type RateLimitInfo struct {
+	// RetryAfter is the wait requested by the provider (Retry-After header or body)
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+
+	// Request and token budgets of the current window. Remaining values are nil when
+	// not reported.
+	LimitRequests     int           `json:"limit_requests,omitempty"`
+	RemainingRequests *int          `json:"remaining_requests,omitempty"`
+	ResetRequests     time.Duration `json:"reset_requests,omitempty"`
+	LimitTokens       int           `json:"limit_tokens,omitempty"`
+	RemainingTokens   *int          `json:"remaining_tokens,omitempty"`
+	ResetTokens       time.Duration `json:"reset_tokens,omitempty"`
+
+	// CreditsExhausted is set when the account ran out of prepaid credits (e.g. OpenRouter
+	// HTTP 402). Waiting does not help: the request should go to another key or provider.
+	CreditsExhausted bool `json:"credits_exhausted,omitempty"`
+}
+
+// Delay returns how long to wait before the request can succeed: the requested
+// RetryAfter, or otherwise the reset time of an exhausted budget. Zero means unknown.
+func (i *RateLimitInfo) Delay() time.Duration {
+	if i == nil {
+		return 0
+	}
+	if i.RetryAfter > 0 {
+		return i.RetryAfter
+	}
+	var delay time.Duration
+	if i.RemainingRequests != nil && *i.RemainingRequests == 0 {
+		delay = max(delay, i.ResetRequests)
+	}
+	if i.RemainingTokens != nil && *i.RemainingTokens == 0 {
+		delay = max(delay, i.ResetTokens)
+	}
+	return delay
+}
+
+// ParseRateLimitHeaders extracts rate-limit details from response headers, supporting
+// Retry-After (seconds or HTTP date), retry-after-ms, the OpenAI style
+// x-ratelimit-{limit,remaining,reset}-{requests,tokens} headers and the generic
+// X-RateLimit-{Limit,Remaining,Reset} headers (reset as a duration, seconds or epoch
+// timestamp). It returns nil when no rate-limit header is present.
+func ParseRateLimitHeaders(header http.Header) *RateLimitInfo {
+	return parseRateLimitHeaders(header, time.Now())
+}
+
+func parseRateLimitHeaders(header http.Header, now time.Time) *RateLimitInfo {
+	info := &RateLimitInfo{}
+	found := false
+
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			info.RetryAfter, found = time.Duration(ms*float64(time.Millisecond)), true
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" && info.RetryAfter == 0 {
+		if d, ok := parseRetryAfter(v, now); ok {
+			info.RetryAfter, found = d, true
+		}
+	}
+
+	parseInt := func(name string) (int, bool) {
+		n, err := strconv.Atoi(strings.TrimSpace(header.Get(name)))
+		if err != nil {
+			return 0, false
+		}
+		found = true
+		return n, true
+	}
+	parseRemaining := func(name string) *int {
+		if n, ok := parseInt(name); ok {
+			return &n
+		}
+		return nil
+	}
+	parseReset := func(name string, dst *time.Duration) {
+		if v := header.Get(name); v != "" {
+			if d, ok := parseResetValue(v, now); ok {
+				*dst, found = d, true
+			}
+		}
+	}
+
+	info.LimitRequests, _ = parseInt("X-Ratelimit-Limit-Requests")
+	info.RemainingRequests = parseRemaining("X-Ratelimit-Remaining-Requests")
+	parseReset("X-Ratelimit-Reset-Requests", &info.ResetRequests)
+	info.LimitTokens, _ = parseInt("X-Ratelimit-Limit-Tokens")
+	info.RemainingTokens = parseRemaining("X-Ratelimit-Remaining-Tokens")
+	parseReset("X-Ratelimit-Reset-Tokens", &info.ResetTokens)
+
+	// Generic headers describe the request budget
+	if info.RemainingRequests == nil {
+		if n, ok := parseInt("X-Ratelimit-Limit"); ok {
+			info.LimitRequests = n
+		}
+		info.RemainingRequests = parseRemaining("X-Ratelimit-Remaining")
+		parseReset("X-Ratelimit-Reset", &info.ResetRequests)
+	}
+
+	if !found {
+		return nil
+	}
+	return info
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+// parseResetValue parses a reset time given as a duration ("1s", "6m0s", "20ms"), a
+// number of seconds or a Unix timestamp in seconds or milliseconds
+func parseResetValue(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil {
+		return max(0, d), true
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	switch {
+	case n >= 1e12: // Unix milliseconds
+		return max(0, time.UnixMilli(int64(n)).Sub(now)), true
+	case n >= 1e9: // Unix seconds
+		return max(0, time.Unix(int64(n), 0).Sub(now)), true
+	default:
+		return time.Duration(n * float64(time.Second)), true
+	}
+}
+
+// RateLimitCapture receives the rate-limit details of the responses of a request made
+// with a context returned by WithRateLimitCapture
+type RateLimitCapture struct {
+	mu   sync.Mutex
+	info *RateLimitInfo
+}
+
+// Info returns the details of the last response that reported any, or nil
+func (c *RateLimitCapture) Info() *RateLimitInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info
+}
+
+type rateLimitCaptureKey struct{}
+
+// WithRateLimitCapture returns a context whose HTTP responses, when sent through a
+// RateLimitTransport, record their rate-limit details in the returned capture. It lets
+// providers built on third-party SDKs attach the details to their errors.
+func WithRateLimitCapture(ctx context.Context) (context.Context, *RateLimitCapture) {
+	capture := &RateLimitCapture{}
+	return context.WithValue(ctx, rateLimitCaptureKey{}, capture), capture
+}
+
+// RateLimitTransport is an http.RoundTripper that records the rate-limit headers of
+// responses in the RateLimitCapture of the request context
+type RateLimitTransport struct {
+	// Base is the underlying transport (SharedTransport when nil)
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = SharedTransport()
+	}
+	resp, err := base.RoundTrip(req)
+	if resp != nil {
+		if capture, ok := req.Context().Value(rateLimitCaptureKey{}).(*RateLimitCapture); ok {
+			if info := ParseRateLimitHeaders(resp.Header); info != nil {
+				capture.mu.Lock()
+				capture.info = info
+				capture.mu.Unlock()
+			}
+		}
+	}
+	return resp, err
+}
+
+// AttachRateLimit sets the rate-limit details of an *Error that has none, returning the
+// error for convenience
+func AttachRateLimit(err error, info *RateLimitInfo) error {
+	var llmErr *Error
+	if info != nil && errors.As(err, &llmErr) && llmErr.RateLimit == nil {
+		llmErr.RateLimit = info
+	}
+	return err
+}
+
+// RateLimitOf returns the rate-limit details attached to an error, or nil
+func RateLimitOf(err error) *RateLimitInfo {
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		return llmErr.RateLimit
+	}
+	return nil
+}
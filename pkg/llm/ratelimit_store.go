@@ -0,0 +1,141 @@
+// Persistent state of rate limiters
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RateLimitStore keeps the counters of rate limiters outside of them, so limits survive
+// restarts and are shared by the replicas using the same store. Counters are grouped in
+// fixed windows: a counter only holds the count of its latest window.
+//
+// Implementations must be safe for concurrent use. A store shared by several replicas,
+// such as Redis (INCRBY on a key with the window, expiring after it), must increment
+// atomically.
+type RateLimitStore interface {
+	// Increment adds n, which may be negative, to the count of the key in the window
+	// starting at the given time, returning the new count. Counts of previous windows
+	// are discarded, and counts never go below zero.
+	Increment(ctx context.Context, key string, window time.Time, n int) (int, error)
+}
+
+// rateLimitCount is the count of a key in a window
+type rateLimitCount struct {
+	Window time.Time `json:"window"`
+	Count  int       `json:"count"`
+}
+
+// MemoryRateLimitStore is a RateLimitStore in memory, sharing the counters of the rate
+// limiters of a process
+type MemoryRateLimitStore struct {
+	mu     sync.Mutex
+	counts map[string]rateLimitCount
+	pruned time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counts: make(map[string]rateLimitCount)}
+}
+
+// Increment implements RateLimitStore
+func (s *MemoryRateLimitStore) Increment(_ context.Context, key string, window time.Time, n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.increment(key, window, n), nil
+}
+
+// increment updates the count of a key, with the lock held
+func (s *MemoryRateLimitStore) increment(key string, window time.Time, n int) int {
+	// Drop the counters of previous windows once per window
+	if window.After(s.pruned) {
+		for k, c := range s.counts {
+			if c.Window.Before(window) {
+				delete(s.counts, k)
+			}
+		}
+		s.pruned = window
+	}
+
+	c := s.counts[key]
+	if window.After(c.Window) {
+		c = rateLimitCount{Window: window}
+	}
+	// Late increments of a previous window, e.g. from a replica with a skewed clock,
+	// count in the current one
+	c.Count = max(0, c.Count+n)
+	s.counts[key] = c
+	return c.Count
+}
+
+// FileRateLimitStore is a RateLimitStore saved to a JSON file after every change, so the
+// limits of a process survive its restarts. The file must not be shared by processes
+// running at the same time: use a store with atomic increments, such as Redis, for that.
+type FileRateLimitStore struct {
+	path   string
+	memory *MemoryRateLimitStore
+}
+
+// NewFileRateLimitStore creates a store saved to the file, loading its counters when it
+// exists
+func NewFileRateLimitStore(path string) (*FileRateLimitStore, error) {
+	store := &FileRateLimitStore{path: path, memory: NewMemoryRateLimitStore()}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return store, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.memory.counts); err != nil {
+		return nil, fmt.Errorf("failed to decode rate limit state %s: %w", path, err)
+	}
+	if store.memory.counts == nil {
+		store.memory.counts = make(map[string]rateLimitCount)
+	}
+	return store, nil
+}
+
+// Increment implements RateLimitStore, saving the counters before returning
+func (s *FileRateLimitStore) Increment(_ context.Context, key string, window time.Time, n int) (int, error) {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	count := s.memory.increment(key, window, n)
+	return count, s.save()
+}
+
+// save writes the counters to a temporary file renamed over the state file, so a crash
+// never leaves a truncated state
+func (s *FileRateLimitStore) save() error {
+	data, err := json.Marshal(s.memory.counts)
+	if err != nil {
+		return fmt.Errorf("failed to encode rate limit state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRateLimitStore is a store that is down
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Increment(context.Context, string, time.Time, int) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestMemoryRateLimitStore_Windows(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRateLimitStore()
+	window := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	count, err := store.Increment(ctx, "a", window, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Late increments count in the current window, and counts never go negative
+	count, _ = store.Increment(ctx, "a", window.Add(-time.Minute), 1)
+	assert.Equal(t, 3, count)
+	count, _ = store.Increment(ctx, "a", window, -5)
+	assert.Equal(t, 0, count)
+
+	// A new window starts from zero and prunes the previous ones
+	_, _ = store.Increment(ctx, "b", window, 1)
+	count, _ = store.Increment(ctx, "a", window.Add(time.Minute), 1)
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, store.counts, "b")
+}
+
+func TestFileRateLimitStore_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "limits.json")
+	window := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	store, err := NewFileRateLimitStore(path)
+	require.NoError(t, err)
+	_, err = store.Increment(ctx, "a", window, 3)
+	require.NoError(t, err)
+
+	restarted, err := NewFileRateLimitStore(path)
+	require.NoError(t, err)
+	count, err := restarted.Increment(ctx, "a", window, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	_, err = NewFileRateLimitStore(filepath.Join(t.TempDir(), "missing", "limits.json"))
+	assert.NoError(t, err, "a missing state file starts empty")
+}
+
+func TestPersistentRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 30, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+
+	// Two replicas sharing the store share the limit
+	replicas := []*RateLimiter{NewPersistentRateLimiter(3, store), NewPersistentRateLimiter(3, store)}
+	for _, limiter := range replicas {
+		limiter.now = func() time.Time { return now }
+	}
+
+	require.NoError(t, replicas[0].AllowRequest("client"))
+	require.NoError(t, replicas[1].AllowRequest("client"))
+	require.NoError(t, replicas[0].AllowRequest("client"))
+	assert.Error(t, replicas[1].AllowRequest("client"))
+	assert.Error(t, replicas[0].AllowRequest("client"))
+
+	// A replica started after a restart sees the counts
+	restarted := NewPersistentRateLimiter(3, store)
+	restarted.now = func() time.Time { return now }
+	assert.Error(t, restarted.AllowRequest("client"))
+
+	// The next clock minute starts a new window
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, restarted.AllowRequest("client"))
+	assert.NoError(t, replicas[0].StoreError())
+}
+
+func TestPersistentRateLimiter_StoreDown(t *testing.T) {
+	limiter := NewPersistentRateLimiter(2, failingRateLimitStore{})
+
+	// Requests are limited locally while the store fails
+	require.NoError(t, limiter.AllowRequest("client"))
+	require.NoError(t, limiter.AllowRequest("client"))
+	assert.Error(t, limiter.AllowRequest("client"))
+	assert.ErrorContains(t, limiter.StoreError(), "connection refused")
+}
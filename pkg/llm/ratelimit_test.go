@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		check   func(t *testing.T, info *RateLimitInfo)
+	}{
+		{
+			name:    "no headers",
+			headers: map[string]string{"Content-Type": "application/json"},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Nil(t, info)
+			},
+		},
+		{
+			name:    "retry-after seconds",
+			headers: map[string]string{"Retry-After": "20"},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 20*time.Second, info.RetryAfter)
+				assert.Equal(t, 20*time.Second, info.Delay())
+			},
+		},
+		{
+			name:    "retry-after date",
+			headers: map[string]string{"Retry-After": now.Add(90 * time.Second).Format(http.TimeFormat)},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 90*time.Second, info.RetryAfter)
+			},
+		},
+		{
+			name:    "retry-after-ms takes precedence",
+			headers: map[string]string{"Retry-After": "2", "Retry-After-Ms": "1500"},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 1500*time.Millisecond, info.RetryAfter)
+			},
+		},
+		{
+			name: "openai budgets",
+			headers: map[string]string{
+				"X-Ratelimit-Limit-Requests":     "500",
+				"X-Ratelimit-Remaining-Requests": "0",
+				"X-Ratelimit-Reset-Requests":     "6m0s",
+				"X-Ratelimit-Limit-Tokens":       "30000",
+				"X-Ratelimit-Remaining-Tokens":   "120",
+				"X-Ratelimit-Reset-Tokens":       "20ms",
+			},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 500, info.LimitRequests)
+				require.NotNil(t, info.RemainingRequests)
+				assert.Equal(t, 0, *info.RemainingRequests)
+				assert.Equal(t, 6*time.Minute, info.ResetRequests)
+				require.NotNil(t, info.RemainingTokens)
+				assert.Equal(t, 120, *info.RemainingTokens)
+				assert.Equal(t, 20*time.Millisecond, info.ResetTokens)
+				// Only the exhausted request budget matters
+				assert.Equal(t, 6*time.Minute, info.Delay())
+			},
+		},
+		{
+			name: "generic headers with epoch milliseconds",
+			headers: map[string]string{
+				"X-RateLimit-Limit":     "20",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1735732830000",
+			},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 20, info.LimitRequests)
+				assert.Equal(t, 30*time.Second, info.ResetRequests)
+				assert.Equal(t, 30*time.Second, info.Delay())
+				assert.Nil(t, info.RemainingTokens)
+			},
+		},
+		{
+			name:    "budget not exhausted",
+			headers: map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "10"},
+			check: func(t *testing.T, info *RateLimitInfo) {
+				assert.Equal(t, 10*time.Second, info.ResetRequests)
+				assert.Zero(t, info.Delay())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			tt.check(t, parseRateLimitHeaders(header, now))
+		})
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RateLimitTransport{}}
+
+	ctx, capture := WithRateLimitCapture(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.NotNil(t, capture.Info())
+	assert.Equal(t, 3*time.Second, capture.Info().RetryAfter)
+
+	// Requests without a capture are unaffected
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// The details are attached to errors that have none
+	err = AttachRateLimit(rateLimitError(), capture.Info())
+	assert.Equal(t, 3*time.Second, RateLimitOf(err).Delay())
+	assert.Nil(t, RateLimitOf(errors.New("plain")))
+	assert.Nil(t, AttachRateLimit(nil, capture.Info()))
+}
+
+func TestRetryChatCompletion_RateLimitInfo(t *testing.T) {
+	config := RetryConfig{MaxRetries: 3, BaseDelay: time.Hour, MaxDelay: time.Second, BackoffFactor: 2}
+
+	t.Run("waits as requested", func(t *testing.T) {
+		limited := rateLimitError()
+		limited.RateLimit = &RateLimitInfo{RetryAfter: 10 * time.Millisecond}
+		mock := &MockChatCompleter{errors: []error{limited}, responses: []*ChatResponse{nil, {ID: "ok"}}}
+
+		start := time.Now()
+		resp, err := RetryChatCompletion(mock, config).ChatCompletion(context.Background(), ChatRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp.ID)
+		// The one hour BaseDelay was not used
+		assert.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("gives up when the wait exceeds MaxDelay", func(t *testing.T) {
+		limited := rateLimitError()
+		limited.RateLimit = &RateLimitInfo{RetryAfter: time.Minute}
+		mock := &MockChatCompleter{errors: []error{limited}}
+
+		_, err := RetryChatCompletion(mock, config).ChatCompletion(context.Background(), ChatRequest{})
+		assert.Same(t, limited, err)
+		assert.Equal(t, 1, mock.callCount)
+	})
+
+	t.Run("gives up when the wait exceeds the deadline", func(t *testing.T) {
+		limited := rateLimitError()
+		limited.RateLimit = &RateLimitInfo{RetryAfter: 500 * time.Millisecond}
+		mock := &MockChatCompleter{errors: []error{limited}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := RetryChatCompletion(mock, config).ChatCompletion(ctx, ChatRequest{})
+		assert.Same(t, limited, err)
+		assert.Equal(t, 1, mock.callCount)
+	})
+
+	t.Run("gives up when credits are exhausted", func(t *testing.T) {
+		noCredits := &Error{Code: "insufficient_credits", Type: "rate_limit_error", StatusCode: 402,
+			RateLimit: &RateLimitInfo{CreditsExhausted: true}}
+		mock := &MockChatCompleter{errors: []error{noCredits}}
+
+		_, err := RetryChatCompletion(mock, config).ChatCompletion(context.Background(), ChatRequest{})
+		assert.Same(t, noCredits, err)
+		assert.Equal(t, 1, mock.callCount)
+	})
+}
+
+func TestKeyPool_RateLimitInfoCooldown(t *testing.T) {
+	limited := NewMockClient("m", "p")
+	err := rateLimitError()
+	err.RateLimit = &RateLimitInfo{RetryAfter: 5 * time.Second}
+	limited.errorToReturn = err
+
+	now := time.Now()
+	pool, poolErr := NewKeyPoolFromClients([]Client{limited, NewMockClient("m", "p")},
+		KeyPoolConfig{Cooldown: time.Minute, Clock: NewFakeClock(now)})
+	require.NoError(t, poolErr)
+
+	_, chatErr := pool.ChatCompletion(context.Background(), ChatRequest{})
+	require.NoError(t, chatErr)
+	assert.Equal(t, now.Add(5*time.Second), pool.Usage()[0].CooldownUntil)
+}
@@ -0,0 +1,119 @@
+// Access to the provider-native responses
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RawResponse is the provider-native response a ChatResponse, or the final chunk a done
+// stream event, was converted from. It gives access to the fields the library does not
+// model yet, without sending the request again.
+type RawResponse interface {
+	// Value returns the native response, e.g. an openai.ChatCompletionResponse of the
+	// go-openai SDK or a *genai.GenerateContentResponse (see RawValue)
+	Value() any
+
+	// JSON returns the response as JSON: the body received when the provider client has
+	// it, or the native response encoded on first use. Fields the SDK of the provider
+	// does not decode are only in the body.
+	JSON() ([]byte, error)
+
+	// Field returns the JSON of a field of the response, by a path of dot-separated
+	// object keys and array indexes (e.g. "choices.0.logprobs"), or false when the
+	// response has no such field. The JSON is decoded on first use.
+	Field(path string) (json.RawMessage, bool)
+}
+
+// rawResponse implements RawResponse with lazy encoding and decoding
+type rawResponse struct {
+	value any
+
+	encodeOnce sync.Once
+	data       []byte
+	err        error
+
+	decodeOnce sync.Once
+	decoded    any
+}
+
+// NewRawResponse wraps a native response. The body it was decoded from, when known, is
+// returned by JSON instead of encoding the value again.
+func NewRawResponse(value any, body []byte) RawResponse {
+	raw := &rawResponse{value: value}
+	if body != nil {
+		raw.data = bytes.TrimSpace(body)
+		raw.encodeOnce.Do(func() {})
+	}
+	return raw
+}
+
+// Value returns the native response
+func (r *rawResponse) Value() any {
+	return r.value
+}
+
+// JSON returns the body of the response, or the native response encoded
+func (r *rawResponse) JSON() ([]byte, error) {
+	r.encodeOnce.Do(func() {
+		r.data, r.err = json.Marshal(r.value)
+	})
+	return r.data, r.err
+}
+
+// Field returns the JSON of a field of the response
+func (r *rawResponse) Field(path string) (json.RawMessage, bool) {
+	r.decodeOnce.Do(func() {
+		data, err := r.JSON()
+		if err != nil {
+			return
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		_ = decoder.Decode(&r.decoded)
+	})
+	if r.decoded == nil {
+		return nil, false
+	}
+
+	node := r.decoded
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch current := node.(type) {
+			case map[string]any:
+				next, ok := current[key]
+				if !ok {
+					return nil, false
+				}
+				node = next
+			case []any:
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 || index >= len(current) {
+					return nil, false
+				}
+				node = current[index]
+			default:
+				return nil, false
+			}
+		}
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// RawValue returns the native value of a raw response when it has type T, e.g.
+// RawValue[openai.ChatCompletionResponse](resp.Raw)
+func RawValue[T any](raw RawResponse) (T, bool) {
+	var zero T
+	if raw == nil {
+		return zero, false
+	}
+	value, ok := raw.Value().(T)
+	return value, ok
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nativeResponse stands for the response type of a provider SDK
+type nativeResponse struct {
+	ID          string `json:"id"`
+	Fingerprint string `json:"system_fingerprint"`
+	Choices     []struct {
+		Logprobs map[string]float64 `json:"logprobs"`
+	} `json:"choices"`
+}
+
+func TestRawResponse(t *testing.T) {
+	var native nativeResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "r1", "system_fingerprint": "fp_1", "choices": [{"logprobs": {"Hi": -0.5}}]}`), &native))
+
+	resp := ChatResponse{ID: "r1", Raw: NewRawResponse(native, nil)}
+	value, ok := RawValue[nativeResponse](resp.Raw)
+	require.True(t, ok)
+	assert.Equal(t, "fp_1", value.Fingerprint)
+	_, ok = RawValue[*nativeResponse](resp.Raw)
+	assert.False(t, ok)
+
+	field, ok := resp.Raw.Field("choices.0.logprobs")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"Hi": -0.5}`, string(field))
+	for _, missing := range []string{"choices.1", "choices.x", "id.length", "service_tier"} {
+		_, ok := resp.Raw.Field(missing)
+		assert.False(t, ok, missing)
+	}
+
+	// Raw responses are shared by copies and not serialized
+	assert.Same(t, resp.Raw, resp.DeepCopy().Raw)
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "fp_1")
+}
+
+func TestRawResponse_Body(t *testing.T) {
+	// Bodies keep the fields the native types do not decode, and large numbers
+	body := []byte(`{"id": "r1", "service_tier": "flex", "seed": 12345678901234567890}` + "\n")
+	var native nativeResponse
+	require.NoError(t, json.Unmarshal(body, &native))
+	raw := NewRawResponse(native, body)
+
+	data, err := raw.JSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id": "r1", "service_tier": "flex", "seed": 12345678901234567890}`, string(data))
+
+	tier, ok := raw.Field("service_tier")
+	require.True(t, ok)
+	assert.Equal(t, `"flex"`, string(tier))
+	seed, _ := raw.Field("seed")
+	assert.Equal(t, "12345678901234567890", string(seed))
+	whole, ok := raw.Field("")
+	require.True(t, ok)
+	assert.JSONEq(t, string(data), string(whole))
+
+	_, ok = RawValue[nativeResponse](nil)
+	assert.False(t, ok)
+}
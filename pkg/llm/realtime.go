@@ -0,0 +1,137 @@
+// Realtime (bidirectional, low-latency) session interfaces
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RealtimeClient opens bidirectional sessions with a realtime model, where text, audio
+// and tool calls flow in both directions over a single long-lived connection
+type RealtimeClient interface {
+	// Connect opens a new session configured with the given settings
+	Connect(ctx context.Context, config RealtimeSessionConfig) (RealtimeSession, error)
+}
+
+// RealtimeSession is a live realtime conversation. Events from the model are delivered
+// through Events until the session is closed.
+type RealtimeSession interface {
+	// Events returns the channel of events received from the model. It is closed when
+	// the session ends.
+	Events() <-chan RealtimeEvent
+
+	// SendText adds a user text message to the conversation
+	SendText(ctx context.Context, text string) error
+
+	// SendAudio appends a frame of input audio (in the session input audio format)
+	SendAudio(ctx context.Context, audio []byte) error
+
+	// CommitAudio marks the end of the user audio turn. Not needed with server-side
+	// voice activity detection.
+	CommitAudio(ctx context.Context) error
+
+	// SendToolResult returns the output of a tool call requested by the model
+	SendToolResult(ctx context.Context, callID string, output string) error
+
+	// CreateResponse asks the model to respond to the conversation so far
+	CreateResponse(ctx context.Context) error
+
+	// CancelResponse interrupts the response being generated
+	CancelResponse(ctx context.Context) error
+
+	// Close ends the session
+	Close() error
+}
+
+// RealtimeTurnDetection configures server-side voice activity detection
+type RealtimeTurnDetection struct {
+	// Threshold is the activation threshold (0.0 - 1.0)
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// SilenceDurationMs is the silence that ends a user turn
+	SilenceDurationMs int `json:"silence_duration_ms,omitempty"`
+
+	// PrefixPaddingMs is the audio kept before the detected speech
+	PrefixPaddingMs int `json:"prefix_padding_ms,omitempty"`
+}
+
+// RealtimeSessionConfig configures a realtime session
+type RealtimeSessionConfig struct {
+	// Instructions is the system prompt of the session
+	Instructions string `json:"instructions,omitempty"`
+
+	// Modalities lists the output modalities ("text", "audio"). Provider default when empty.
+	Modalities []string `json:"modalities,omitempty"`
+
+	// Voice selects the voice used for audio output
+	Voice string `json:"voice,omitempty"`
+
+	// InputAudioFormat and OutputAudioFormat select the audio encodings (e.g. "pcm16")
+	InputAudioFormat  string `json:"input_audio_format,omitempty"`
+	OutputAudioFormat string `json:"output_audio_format,omitempty"`
+
+	// TranscribeInput enables transcription of the user audio
+	TranscribeInput bool `json:"transcribe_input,omitempty"`
+
+	// TurnDetection enables server-side voice activity detection. When nil, the user
+	// turn ends with CommitAudio and responses must be requested with CreateResponse.
+	TurnDetection *RealtimeTurnDetection `json:"turn_detection,omitempty"`
+
+	// Tools available to the model during the session
+	Tools []Tool `json:"tools,omitempty"`
+
+	// Temperature for sampling
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+// RealtimeEventType identifies the kind of a realtime event
+type RealtimeEventType string
+
+const (
+	// RealtimeEventSessionCreated is sent once the session is ready
+	RealtimeEventSessionCreated RealtimeEventType = "session_created"
+	// RealtimeEventTextDelta carries a fragment of the model text output
+	RealtimeEventTextDelta RealtimeEventType = "text_delta"
+	// RealtimeEventAudioDelta carries a frame of the model audio output
+	RealtimeEventAudioDelta RealtimeEventType = "audio_delta"
+	// RealtimeEventTranscriptDelta carries a fragment of the transcript of the model audio
+	RealtimeEventTranscriptDelta RealtimeEventType = "transcript_delta"
+	// RealtimeEventInputTranscript carries the transcript of the user audio
+	RealtimeEventInputTranscript RealtimeEventType = "input_transcript"
+	// RealtimeEventSpeechStarted is sent when voice activity detection hears the user
+	RealtimeEventSpeechStarted RealtimeEventType = "speech_started"
+	// RealtimeEventSpeechStopped is sent when voice activity detection ends the user turn
+	RealtimeEventSpeechStopped RealtimeEventType = "speech_stopped"
+	// RealtimeEventToolCall is sent when the model calls a tool; reply with SendToolResult
+	RealtimeEventToolCall RealtimeEventType = "tool_call"
+	// RealtimeEventResponseDone is sent when the model finishes a response
+	RealtimeEventResponseDone RealtimeEventType = "response_done"
+	// RealtimeEventReconnected is sent after the session was re-established following a
+	// dropped connection
+	RealtimeEventReconnected RealtimeEventType = "reconnected"
+	// RealtimeEventError reports an error; the session remains usable unless it is closed
+	RealtimeEventError RealtimeEventType = "error"
+)
+
+// RealtimeEvent is an event received from a realtime session
+type RealtimeEvent struct {
+	Type RealtimeEventType `json:"type"`
+
+	// Text holds text deltas and transcripts
+	Text string `json:"text,omitempty"`
+
+	// Audio holds decoded audio frames in the session output audio format
+	Audio []byte `json:"audio,omitempty"`
+
+	// ToolCall is set for RealtimeEventToolCall
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+
+	// Usage is set for RealtimeEventResponseDone when the provider reports it
+	Usage *Usage `json:"usage,omitempty"`
+
+	// Error is set for RealtimeEventError
+	Error *Error `json:"error,omitempty"`
+
+	// Raw holds the provider event the event was built from
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
@@ -0,0 +1,187 @@
+// Redaction of secrets and personal data echoed in error messages
+package llm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync/atomic"
+)
+
+// RedactionRule replaces the matches of a pattern in error messages. The replacement
+// can reference the groups of the pattern (see regexp.Regexp.ReplaceAllString).
+type RedactionRule struct {
+	Name        string         `json:"name"`
+	Pattern     *regexp.Regexp `json:"-"`
+	Replacement string         `json:"replacement"`
+}
+
+// DefaultRedactionRules returns the rules redacting bearer tokens, API keys and emails
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{
+			Name:        "bearer_token",
+			Pattern:     regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+			Replacement: "${1}[REDACTED]",
+		},
+		{
+			Name:        "credential_parameter",
+			Pattern:     regexp.MustCompile(`(?i)\b((?:x-)?(?:api[_-]?key|access[_-]?token|auth[_-]?token|secret|password)["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`),
+			Replacement: "${1}[REDACTED]",
+		},
+		{
+			Name:        "api_key",
+			Pattern:     regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_\-]{16,}|AIza[0-9A-Za-z_\-]{35}|(?:AKIA|ASIA)[0-9A-Z]{16}|fw_[A-Za-z0-9]{16,})\b`),
+			Replacement: "[REDACTED]",
+		},
+		{
+			Name:        "jwt",
+			Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+			Replacement: "[REDACTED]",
+		},
+		{
+			Name:        "email",
+			Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+			Replacement: "[REDACTED_EMAIL]",
+		},
+	}
+}
+
+// SecretRedactor removes secrets and personal data from error messages, which providers
+// sometimes fill with parts of the request (e.g. the headers or the prompt)
+type SecretRedactor struct {
+	rules []RedactionRule
+}
+
+// NewSecretRedactor creates a redactor applying the default rules and the extra ones
+func NewSecretRedactor(extra ...RedactionRule) *SecretRedactor {
+	return &SecretRedactor{rules: append(DefaultRedactionRules(), extra...)}
+}
+
+// Redact returns the text with the matches of every rule replaced
+func (r *SecretRedactor) Redact(text string) string {
+	for _, rule := range r.rules {
+		if rule.Pattern != nil {
+			text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+		}
+	}
+	return text
+}
+
+// RedactError returns err with its message redacted. The Error in its chain is replaced
+// by a redacted copy, so that errors.As finds the copy while errors.Is still matches the
+// original chain; err itself is never modified.
+func (r *SecretRedactor) RedactError(err error) error {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr == nil {
+		return err
+	}
+	redacted := *llmErr
+	redacted.Message = r.Redact(llmErr.Message)
+	if err == error(llmErr) {
+		return &redacted
+	}
+	return &redactedError{message: r.Redact(err.Error()), redacted: &redacted, original: err}
+}
+
+// redactedError is an error chain wrapping an Error, with the message redacted
+type redactedError struct {
+	message  string
+	redacted *Error
+	original error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the redacted copy of the Error before the original chain
+func (e *redactedError) Unwrap() []error {
+	return []error{e.redacted, e.original}
+}
+
+// globalRedactor is the redactor used by the provider clients
+var globalRedactor atomic.Pointer[SecretRedactor]
+
+func init() {
+	globalRedactor.Store(NewSecretRedactor())
+}
+
+// RegisterRedactionRule adds a rule to the redactor applied to the errors of every
+// provider client, e.g. for customer identifiers
+func RegisterRedactionRule(rule RedactionRule) {
+	for {
+		current := globalRedactor.Load()
+		next := &SecretRedactor{rules: append(append([]RedactionRule(nil), current.rules...), rule)}
+		if globalRedactor.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
+
+// RedactSecrets redacts a text with the rules of the provider clients (see
+// RegisterRedactionRule)
+func RedactSecrets(text string) string {
+	return globalRedactor.Load().Redact(text)
+}
+
+// RedactError redacts the message of an error with the rules of the provider clients,
+// in place, and returns it. Provider clients apply it to all the errors they convert.
+func RedactError(err *Error) *Error {
+	if err != nil {
+		err.Message = RedactSecrets(err.Message)
+	}
+	return err
+}
+
+// ErrorRedactionMiddleware redacts secrets from the errors returned by a client and the
+// error events of its streams, for clients that do not redact them themselves (e.g.
+// custom Client implementations)
+type ErrorRedactionMiddleware struct {
+	redactor *SecretRedactor
+}
+
+// NewErrorRedactionMiddleware creates a middleware redacting errors with the redactor,
+// or with the rules of the provider clients when it is nil
+func NewErrorRedactionMiddleware(redactor *SecretRedactor) *ErrorRedactionMiddleware {
+	return &ErrorRedactionMiddleware{redactor: redactor}
+}
+
+// Name returns the middleware name
+func (m *ErrorRedactionMiddleware) Name() string {
+	return "error_redaction"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *ErrorRedactionMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse passes responses through unchanged
+func (m *ErrorRedactionMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, nil
+}
+
+// RewriteError replaces the error with a redacted copy, leaving the original (which retry
+// wrappers or the history may hold) untouched
+func (m *ErrorRedactionMiddleware) RewriteError(ctx context.Context, req *ChatRequest, err error) error {
+	return m.getRedactor().RedactError(err)
+}
+
+// ProcessStreamEvent redacts the error of error events
+func (m *ErrorRedactionMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	if event.Error != nil {
+		redacted := *event.Error
+		redacted.Message = m.getRedactor().Redact(redacted.Message)
+		event.Error = &redacted
+	}
+	return event, nil
+}
+
+// getRedactor returns the redactor of the middleware
+func (m *ErrorRedactionMiddleware) getRedactor() *SecretRedactor {
+	if m.redactor != nil {
+		return m.redactor
+	}
+	return globalRedactor.Load()
+}
@@ -0,0 +1,411 @@
+// Refusal and safety deflection detection
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RefusalKind classifies how a model declined to answer
+type RefusalKind string
+
+const (
+	// RefusalKindNone is an answer
+	RefusalKindNone RefusalKind = ""
+
+	// RefusalKindRefusal is an explicit refusal (e.g. "I can't help with that")
+	RefusalKindRefusal RefusalKind = "refusal"
+
+	// RefusalKindDeflection is a safety deflection: the model avoids the request by
+	// lecturing or redirecting (e.g. "please consult a professional") instead of answering
+	RefusalKindDeflection RefusalKind = "deflection"
+)
+
+// MetadataRefusal is the response metadata key holding the RefusalKind of refusals
+const MetadataRefusal = "refusal"
+
+// MetadataPromptID is the request metadata key identifying the prompt (e.g. the name
+// and version of a template), by which RefusalMiddleware aggregates refusal rates
+const MetadataPromptID = "prompt_id"
+
+// DefaultRefusalPatterns are the regular expressions of the usual refusals, matched
+// case-insensitively against the start of responses
+var DefaultRefusalPatterns = []string{
+	`^(?:i'm |i am )?sorry,? (?:but )?i(?:'m| am)? (?:can(?:'|’)?t|cannot|won(?:'|’)t|will not|unable|not able)`,
+	`^i (?:can(?:'|’)?t|cannot|won(?:'|’)t|will not) (?:help|assist|provide|comply|do that|create|generate|write|answer|engage)`,
+	`^i(?:'m| am) (?:not able|unable) to (?:help|assist|provide|comply|do that|create|generate|write|answer)`,
+	`^as an ai(?: language model| assistant)?,? i (?:can(?:'|’)?t|cannot|don(?:'|’)t|am not able|'m not able)`,
+	`^i must (?:decline|refuse)`,
+	`^unfortunately,? i (?:can(?:'|’)?t|cannot|am unable|'m unable)`,
+}
+
+// DefaultDeflectionPatterns are the regular expressions of the usual safety deflections,
+// matched case-insensitively anywhere in responses
+var DefaultDeflectionPatterns = []string{
+	`\b(?:against|violates?) (?:my|the) (?:guidelines|policies|usage polic(?:y|ies)|content policy)`,
+	`\bi(?:'m| am) not (?:comfortable|in a position to)\b`,
+	`\b(?:please |i(?: would|'d) (?:strongly )?(?:recommend|encourage|urge) (?:you )?(?:to )?)(?:consult|speak(?: with| to)|reach out to|contact) (?:a|an|your) (?:qualified |licensed |medical |legal |mental health )?(?:professional|doctor|lawyer|attorney|therapist|expert)`,
+	`\bif you(?:'re| are) (?:in crisis|having thoughts of)\b`,
+}
+
+// DefaultRefusalJudgePrompt is the instruction of the judge model
+const DefaultRefusalJudgePrompt = `You classify the answers of an AI assistant. Reply with one word:
+REFUSAL if the assistant declined the request,
+DEFLECTION if it avoided the request by lecturing or redirecting the user instead of answering,
+ANSWER otherwise.`
+
+// RefusalConfig configures a RefusalClassifier
+type RefusalConfig struct {
+	// RefusalPatterns are regular expressions of refusals, matched case-insensitively
+	RefusalPatterns []string `json:"refusal_patterns"`
+
+	// DeflectionPatterns are regular expressions of safety deflections, matched
+	// case-insensitively
+	DeflectionPatterns []string `json:"deflection_patterns"`
+
+	// Judge, when set, classifies the responses matching no pattern, to detect the
+	// refusals worded in unusual ways (at the cost of a request per response)
+	Judge ChatCompleter `json:"-"`
+
+	// JudgeModel is the model requested from the judge, when it serves several
+	JudgeModel string `json:"judge_model,omitempty"`
+
+	// JudgePrompt is the system prompt of the judge
+	JudgePrompt string `json:"judge_prompt"`
+}
+
+// DefaultRefusalConfig returns the default configuration, with the default patterns and
+// no judge
+func DefaultRefusalConfig() *RefusalConfig {
+	return &RefusalConfig{
+		RefusalPatterns:    DefaultRefusalPatterns,
+		DeflectionPatterns: DefaultDeflectionPatterns,
+		JudgePrompt:        DefaultRefusalJudgePrompt,
+	}
+}
+
+// RefusalClassification is the classification of a response
+type RefusalClassification struct {
+	Kind RefusalKind `json:"kind,omitempty"`
+
+	// Reason is what identified the refusal: "content_filter" for responses stopped by
+	// the provider filters, the matching pattern, or "judge"
+	Reason string `json:"reason,omitempty"`
+}
+
+// IsRefusal reports whether the model declined to answer
+func (c RefusalClassification) IsRefusal() bool {
+	return c.Kind != RefusalKindNone
+}
+
+// RefusalClassifier tags responses as refusals or safety deflections with regular
+// expressions and, optionally, a judge model
+type RefusalClassifier struct {
+	config     *RefusalConfig
+	refusal    []*regexp.Regexp
+	deflection []*regexp.Regexp
+}
+
+// NewRefusalClassifier creates a classifier, filling unset config fields with defaults.
+// It fails with an "invalid_request" error when a pattern does not compile.
+func NewRefusalClassifier(config *RefusalConfig) (*RefusalClassifier, error) {
+	defaults := DefaultRefusalConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.RefusalPatterns == nil {
+		config.RefusalPatterns = defaults.RefusalPatterns
+	}
+	if config.DeflectionPatterns == nil {
+		config.DeflectionPatterns = defaults.DeflectionPatterns
+	}
+	if config.JudgePrompt == "" {
+		config.JudgePrompt = defaults.JudgePrompt
+	}
+
+	c := &RefusalClassifier{config: config}
+	var err error
+	if c.refusal, err = compileRefusalPatterns(config.RefusalPatterns); err != nil {
+		return nil, err
+	}
+	if c.deflection, err = compileRefusalPatterns(config.DeflectionPatterns); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// compileRefusalPatterns compiles case-insensitive patterns
+func compileRefusalPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, &Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("invalid refusal pattern %q: %v", pattern, err),
+				Type:    "validation_error",
+			}
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// defaultRefusalClassifier classifies with the default patterns
+var defaultRefusalClassifier, _ = NewRefusalClassifier(nil)
+
+// ClassifyText classifies the text of a response with the patterns only
+func (c *RefusalClassifier) ClassifyText(text string) RefusalClassification {
+	text = strings.TrimSpace(text)
+	for _, re := range c.refusal {
+		if re.MatchString(text) {
+			return RefusalClassification{Kind: RefusalKindRefusal, Reason: re.String()[len("(?i)"):]}
+		}
+	}
+	for _, re := range c.deflection {
+		if re.MatchString(text) {
+			return RefusalClassification{Kind: RefusalKindDeflection, Reason: re.String()[len("(?i)"):]}
+		}
+	}
+	return RefusalClassification{}
+}
+
+// Classify classifies the first choice of a response: responses stopped by the provider
+// content filters are refusals, then the patterns are matched and, when none matches
+// and a judge is configured, the judge is asked. A judge failure is returned along
+// with the classification of the patterns.
+func (c *RefusalClassifier) Classify(ctx context.Context, req *ChatRequest, resp *ChatResponse) (RefusalClassification, error) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return RefusalClassification{}, nil
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason == FinishReasonContentFilter {
+		return RefusalClassification{Kind: RefusalKindRefusal, Reason: "content_filter"}, nil
+	}
+	// Tool calls are not refusals, even with some text
+	if len(choice.Message.ToolCalls) > 0 {
+		return RefusalClassification{}, nil
+	}
+
+	text := messageText(choice.Message)
+	classification := c.ClassifyText(text)
+	if classification.IsRefusal() || c.config.Judge == nil || strings.TrimSpace(text) == "" {
+		return classification, nil
+	}
+	return c.judge(ctx, req, text)
+}
+
+// judge asks the judge model to classify a response
+func (c *RefusalClassifier) judge(ctx context.Context, req *ChatRequest, text string) (RefusalClassification, error) {
+	var exchange strings.Builder
+	if req != nil {
+		for i := len(req.Messages) - 1; i >= 0; i-- {
+			if req.Messages[i].Role == RoleUser {
+				fmt.Fprintf(&exchange, "User request:\n%s\n\n", messageText(req.Messages[i]))
+				break
+			}
+		}
+	}
+	fmt.Fprintf(&exchange, "Assistant answer:\n%s", text)
+
+	maxTokens := 5
+	resp, err := c.config.Judge.ChatCompletion(ctx, ChatRequest{
+		Model: c.config.JudgeModel,
+		Messages: []Message{
+			NewTextMessage(RoleSystem, c.config.JudgePrompt),
+			NewTextMessage(RoleUser, exchange.String()),
+		},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		return RefusalClassification{}, &Error{
+			Code:    "refusal_judge_failed",
+			Message: fmt.Sprintf("failed to classify the response: %v", err),
+			Type:    "api_error",
+		}
+	}
+	if len(resp.Choices) == 0 {
+		return RefusalClassification{}, nil
+	}
+
+	verdict := strings.ToUpper(messageText(resp.Choices[0].Message))
+	switch {
+	case strings.Contains(verdict, "REFUSAL"):
+		return RefusalClassification{Kind: RefusalKindRefusal, Reason: "judge"}, nil
+	case strings.Contains(verdict, "DEFLECTION"):
+		return RefusalClassification{Kind: RefusalKindDeflection, Reason: "judge"}, nil
+	}
+	return RefusalClassification{}, nil
+}
+
+// RefusalCounts aggregates the classifications of some responses
+type RefusalCounts struct {
+	Responses   int64 `json:"responses"`
+	Refusals    int64 `json:"refusals"`
+	Deflections int64 `json:"deflections"`
+
+	// JudgeErrors are the responses the judge failed to classify
+	JudgeErrors int64 `json:"judge_errors,omitempty"`
+}
+
+// add adds a classification
+func (c *RefusalCounts) add(classification RefusalClassification, judgeErr error) {
+	c.Responses++
+	switch classification.Kind {
+	case RefusalKindRefusal:
+		c.Refusals++
+	case RefusalKindDeflection:
+		c.Deflections++
+	}
+	if judgeErr != nil {
+		c.JudgeErrors++
+	}
+}
+
+// merge adds another aggregate
+func (c *RefusalCounts) merge(other RefusalCounts) {
+	c.Responses += other.Responses
+	c.Refusals += other.Refusals
+	c.Deflections += other.Deflections
+	c.JudgeErrors += other.JudgeErrors
+}
+
+// RefusalRate returns the fraction of the responses that were refusals or deflections
+func (c RefusalCounts) RefusalRate() float64 {
+	if c.Responses == 0 {
+		return 0
+	}
+	return float64(c.Refusals+c.Deflections) / float64(c.Responses)
+}
+
+// RefusalStatsKey identifies the responses of a prompt by a model
+type RefusalStatsKey struct {
+	// Prompt is the MetadataPromptID of the requests, empty when they had none
+	Prompt string `json:"prompt"`
+	Model  string `json:"model"`
+}
+
+// PromptRefusals are the refusal counts of a prompt, across models
+type PromptRefusals struct {
+	Prompt string `json:"prompt"`
+	RefusalCounts
+
+	// Models are the counts of every model
+	Models map[string]RefusalCounts `json:"models"`
+}
+
+// RefusalMiddleware classifies the responses of ChatCompletion, tagging refusals with
+// their RefusalKind in the MetadataRefusal response metadata, and counts them by
+// prompt (the MetadataPromptID of the request) and model, so the prompts with high
+// refusal rates can be found across providers. Streamed responses are not classified.
+//
+//	classifier, _ := llm.NewRefusalClassifier(nil)
+//	refusals := llm.NewRefusalMiddleware(classifier)
+//	client := llm.NewEnhancedClient(base, []llm.Middleware{refusals})
+//	...
+//	for _, prompt := range refusals.Prompts(20) {
+//	    log.Printf("%s: %.0f%% refused", prompt.Prompt, prompt.RefusalRate()*100)
+//	}
+type RefusalMiddleware struct {
+	classifier *RefusalClassifier
+
+	mu    sync.Mutex
+	stats map[RefusalStatsKey]*RefusalCounts
+}
+
+// NewRefusalMiddleware creates a middleware classifying with the classifier
+func NewRefusalMiddleware(classifier *RefusalClassifier) *RefusalMiddleware {
+	return &RefusalMiddleware{classifier: classifier, stats: make(map[RefusalStatsKey]*RefusalCounts)}
+}
+
+// Name returns the middleware name
+func (m *RefusalMiddleware) Name() string {
+	return "refusal"
+}
+
+// ProcessRequest passes requests through unchanged
+func (m *RefusalMiddleware) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	return req, nil
+}
+
+// ProcessResponse classifies successful responses. Judge failures are counted but do
+// not fail the response.
+func (m *RefusalMiddleware) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	classification, judgeErr := m.classifier.Classify(ctx, req, resp)
+	if classification.IsRefusal() {
+		resp.SetMetadata(MetadataRefusal, string(classification.Kind))
+	}
+
+	key := RefusalStatsKey{Model: resp.Model}
+	if req != nil {
+		if req.Model != "" {
+			key.Model = req.Model
+		}
+		if prompt, ok := req.Metadata[MetadataPromptID].(string); ok {
+			key.Prompt = prompt
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts, ok := m.stats[key]
+	if !ok {
+		counts = &RefusalCounts{}
+		m.stats[key] = counts
+	}
+	counts.add(classification, judgeErr)
+	return resp, nil
+}
+
+// ProcessStreamEvent passes stream events through unchanged
+func (m *RefusalMiddleware) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// Stats returns the counts of every prompt and model
+func (m *RefusalMiddleware) Stats() map[RefusalStatsKey]RefusalCounts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[RefusalStatsKey]RefusalCounts, len(m.stats))
+	for key, counts := range m.stats {
+		stats[key] = *counts
+	}
+	return stats
+}
+
+// Prompts returns the counts of every prompt with at least minResponses responses,
+// across models, by decreasing refusal rate
+func (m *RefusalMiddleware) Prompts(minResponses int64) []PromptRefusals {
+	byPrompt := make(map[string]*PromptRefusals)
+	for key, counts := range m.Stats() {
+		prompt, ok := byPrompt[key.Prompt]
+		if !ok {
+			prompt = &PromptRefusals{Prompt: key.Prompt, Models: make(map[string]RefusalCounts)}
+			byPrompt[key.Prompt] = prompt
+		}
+		prompt.merge(counts)
+		prompt.Models[key.Model] = counts
+	}
+
+	prompts := make([]PromptRefusals, 0, len(byPrompt))
+	for _, prompt := range byPrompt {
+		if prompt.Responses >= minResponses {
+			prompts = append(prompts, *prompt)
+		}
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		if rate, other := prompts[i].RefusalRate(), prompts[j].RefusalRate(); rate != other {
+			return rate > other
+		}
+		return prompts[i].Prompt < prompts[j].Prompt
+	})
+	return prompts
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefusalClassifier_ClassifyText(t *testing.T) {
+	classifier, err := NewRefusalClassifier(nil)
+	require.NoError(t, err)
+
+	tests := map[string]RefusalKind{
+		"I'm sorry, but I can't help with that.":                      RefusalKindRefusal,
+		"Sorry, I am unable to do this.":                              RefusalKindRefusal,
+		"I cannot provide instructions for that.":                     RefusalKindRefusal,
+		"  As an AI language model, I don't have opinions.":           RefusalKindRefusal,
+		"That request goes against my guidelines.":                    RefusalKindDeflection,
+		"I'd strongly recommend you consult a licensed professional.": RefusalKindDeflection,
+		"Please consult your doctor before changing the dose.":        RefusalKindDeflection,
+		"I'm sorry to hear that. Here is how to fix it: ...":          RefusalKindNone,
+		"Paris is the capital of France.":                             RefusalKindNone,
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, classifier.ClassifyText(text).Kind, text)
+	}
+
+	_, err = NewRefusalClassifier(&RefusalConfig{RefusalPatterns: []string{"("}})
+	assert.Equal(t, "invalid_request", err.(*Error).Code)
+}
+
+func TestRefusalClassifier_Classify(t *testing.T) {
+	judge := NewMockClient("judge", "test")
+	judge.responses = []*ChatResponse{textResponse("DEFLECTION")}
+	classifier, err := NewRefusalClassifier(&RefusalConfig{Judge: judge, JudgeModel: "small"})
+	require.NoError(t, err)
+	ctx := context.Background()
+	req := &ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "How do I pick a lock?")}}
+
+	filtered := &ChatResponse{Choices: []Choice{{FinishReason: FinishReasonContentFilter}}}
+	classification, err := classifier.Classify(ctx, req, filtered)
+	require.NoError(t, err)
+	assert.Equal(t, RefusalClassification{Kind: RefusalKindRefusal, Reason: "content_filter"}, classification)
+
+	// Matched by a pattern, the judge is not asked
+	classification, err = classifier.Classify(ctx, req, textResponse("I won't help with that."))
+	require.NoError(t, err)
+	assert.Equal(t, RefusalKindRefusal, classification.Kind)
+	assert.Empty(t, judge.callLog)
+
+	classification, err = classifier.Classify(ctx, req, textResponse("Locks are fascinating devices with a long history."))
+	require.NoError(t, err)
+	assert.Equal(t, RefusalClassification{Kind: RefusalKindDeflection, Reason: "judge"}, classification)
+	require.Len(t, judge.callLog, 1)
+	assert.Equal(t, "small", judge.callLog[0].Model)
+	assert.Contains(t, messageText(judge.callLog[0].Messages[1]), "How do I pick a lock?")
+
+	judge.errorToReturn = &Error{Code: "api_error", Message: "down"}
+	classification, err = classifier.Classify(ctx, req, textResponse("Locks are fascinating."))
+	assert.Equal(t, "refusal_judge_failed", err.(*Error).Code)
+	assert.False(t, classification.IsRefusal())
+}
+
+func TestRefusalMiddleware(t *testing.T) {
+	classifier, err := NewRefusalClassifier(nil)
+	require.NoError(t, err)
+	refusals := NewRefusalMiddleware(classifier)
+
+	record := func(prompt, model, answer string) *ChatResponse {
+		req := &ChatRequest{Model: model}
+		if prompt != "" {
+			req.SetMetadata(MetadataPromptID, prompt)
+		}
+		resp, err := refusals.ProcessResponse(context.Background(), req, textResponse(answer), nil)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := record("lockpicking-v1", "gpt-4o", "I can't help with that.")
+	assert.Equal(t, map[string]any{MetadataRefusal: "refusal"}, resp.Metadata)
+	record("lockpicking-v1", "claude", "Here is how pin tumbler locks work.")
+	record("weather-v2", "gpt-4o", "Sunny.")
+	assert.Nil(t, record("", "gpt-4o", "Sunny.").Metadata)
+
+	stats := refusals.Stats()
+	assert.Equal(t, RefusalCounts{Responses: 1, Refusals: 1}, stats[RefusalStatsKey{Prompt: "lockpicking-v1", Model: "gpt-4o"}])
+
+	prompts := refusals.Prompts(1)
+	require.Len(t, prompts, 3)
+	assert.Equal(t, "lockpicking-v1", prompts[0].Prompt)
+	assert.InDelta(t, 0.5, prompts[0].RefusalRate(), 1e-9)
+	assert.Len(t, prompts[0].Models, 2)
+	assert.Len(t, refusals.Prompts(2), 1)
+}
@@ -0,0 +1,351 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RequestChangeKind is the kind of a change between two requests
+type RequestChangeKind string
+
+// Kinds of request changes
+const (
+	RequestChangeAdded    RequestChangeKind = "added"
+	RequestChangeRemoved  RequestChangeKind = "removed"
+	RequestChangeModified RequestChangeKind = "modified"
+)
+
+// RequestChange is a single difference between two requests
+type RequestChange struct {
+	Kind RequestChangeKind `json:"kind"`
+
+	// Path identifies the changed part of the request (e.g. "temperature", "messages[0]",
+	// "tools[get_weather]" or "metadata.tenant"). Messages are numbered as in the request
+	// they belong to: the first one for removals, the second one otherwise.
+	Path string `json:"path"`
+
+	// Before and After are readable renderings of the value in each request
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// String returns the change as a line of a diff
+func (c RequestChange) String() string {
+	switch c.Kind {
+	case RequestChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.After)
+	case RequestChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Before)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Before, c.After)
+	}
+}
+
+// RequestDiff is the list of differences between two requests
+type RequestDiff struct {
+	Changes []RequestChange `json:"changes,omitempty"`
+}
+
+// IsEmpty returns true when the requests are equivalent
+func (d RequestDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// String returns the diff with one change per line
+func (d RequestDiff) String() string {
+	lines := make([]string, len(d.Changes))
+	for i, change := range d.Changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffRequests returns the differences between two requests: messages (aligned, so an
+// inserted message is reported as such instead of shifting all the following ones),
+// parameters, tools (by name), metadata, extra body fields and provider options. A nil
+// request is treated as an empty one.
+func DiffRequests(a, b *ChatRequest) RequestDiff {
+	return snapshotRequest(a).diff(snapshotRequest(b))
+}
+
+// MiddlewareDiff holds the changes a middleware made to a request
+type MiddlewareDiff struct {
+	Middleware string      `json:"middleware"`
+	Diff       RequestDiff `json:"diff"`
+}
+
+type requestDiffCaptureKey struct{}
+
+// RequestDiffCapture records the changes made to requests by each middleware of a
+// MiddlewareChain
+type RequestDiffCapture struct {
+	mu    sync.Mutex
+	diffs []MiddlewareDiff
+}
+
+// WithRequestDiffCapture returns a context whose requests, when processed by a
+// MiddlewareChain (e.g. in an EnhancedClient), record what every middleware changed in
+// the returned capture. Requests are only compared when the capture is present.
+func WithRequestDiffCapture(ctx context.Context) (context.Context, *RequestDiffCapture) {
+	capture := &RequestDiffCapture{}
+	return context.WithValue(ctx, requestDiffCaptureKey{}, capture), capture
+}
+
+// Diffs returns the recorded diffs, one per middleware and request processed, in order.
+// Middleware that left the request unchanged have an empty diff.
+func (c *RequestDiffCapture) Diffs() []MiddlewareDiff {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]MiddlewareDiff(nil), c.diffs...)
+}
+
+// String returns the recorded diffs grouped by middleware
+func (c *RequestDiffCapture) String() string {
+	var b strings.Builder
+	for _, diff := range c.Diffs() {
+		if diff.Diff.IsEmpty() {
+			fmt.Fprintf(&b, "%s: no changes\n", diff.Middleware)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", diff.Middleware)
+		for _, change := range diff.Diff.Changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+	return b.String()
+}
+
+func (c *RequestDiffCapture) add(diff MiddlewareDiff) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diffs = append(c.diffs, diff)
+}
+
+// requestDiffCaptureFrom returns the capture of the context, if any
+func requestDiffCaptureFrom(ctx context.Context) *RequestDiffCapture {
+	capture, _ := ctx.Value(requestDiffCaptureKey{}).(*RequestDiffCapture)
+	return capture
+}
+
+// requestSnapshot is a rendering of a request that can be compared with another one. It
+// is taken before a middleware runs, as middleware may modify the request in place.
+type requestSnapshot struct {
+	messages []string
+	fields   []requestField
+}
+
+// requestField is a rendered field of a request
+type requestField struct {
+	path  string
+	value string
+}
+
+// snapshotRequest renders the parts of a request
+func snapshotRequest(req *ChatRequest) requestSnapshot {
+	var snapshot requestSnapshot
+	if req == nil {
+		return snapshot
+	}
+
+	snapshot.messages = make([]string, len(req.Messages))
+	for i, msg := range req.Messages {
+		snapshot.messages[i] = renderDiffMessage(msg)
+	}
+
+	add := func(path, value string) {
+		snapshot.fields = append(snapshot.fields, requestField{path: path, value: value})
+	}
+	if req.Model != "" {
+		add("model", strconv.Quote(req.Model))
+	}
+	for _, tool := range req.Tools {
+		value := strconv.Quote(tool.Function.Description)
+		if tool.Function.Parameters != nil {
+			value += " " + renderDiffValue(tool.Function.Parameters)
+		}
+		add("tools["+tool.Function.Name+"]", value)
+	}
+	if req.Temperature != nil {
+		add("temperature", strconv.FormatFloat(float64(*req.Temperature), 'g', -1, 32))
+	}
+	if req.MaxTokens != nil {
+		add("max_tokens", strconv.Itoa(*req.MaxTokens))
+	}
+	if req.TopP != nil {
+		add("top_p", strconv.FormatFloat(float64(*req.TopP), 'g', -1, 32))
+	}
+	if req.Stream {
+		add("stream", "true")
+	}
+	if req.ResponseFormat != nil {
+		add("response_format", renderDiffValue(req.ResponseFormat))
+	}
+	if req.ResponseFormatPolicy != "" {
+		add("response_format_policy", string(req.ResponseFormatPolicy))
+	}
+	if req.User != "" {
+		add("user", strconv.Quote(req.User))
+	}
+	for _, key := range sortedKeys(req.Metadata) {
+		add("metadata."+key, renderDiffValue(req.Metadata[key]))
+	}
+	for _, key := range sortedKeys(req.ExtraBody) {
+		add("extra_body."+key, renderDiffValue(req.ExtraBody[key]))
+	}
+	for i, option := range req.ProviderOptions {
+		add(fmt.Sprintf("provider_options[%d]", i), fmt.Sprintf("%s %+v", option.Provider(), option))
+	}
+	return snapshot
+}
+
+// diff returns the changes from the snapshot to another one
+func (s requestSnapshot) diff(other requestSnapshot) RequestDiff {
+	var changes []RequestChange
+	changes = append(changes, diffMessages(s.messages, other.messages)...)
+
+	after := make(map[string]string, len(other.fields))
+	for _, field := range other.fields {
+		after[field.path] = field.value
+	}
+	before := make(map[string]bool, len(s.fields))
+	for _, field := range s.fields {
+		before[field.path] = true
+		value, ok := after[field.path]
+		switch {
+		case !ok:
+			changes = append(changes, RequestChange{Kind: RequestChangeRemoved, Path: field.path, Before: field.value})
+		case value != field.value:
+			changes = append(changes, RequestChange{Kind: RequestChangeModified, Path: field.path, Before: field.value, After: value})
+		}
+	}
+	for _, field := range other.fields {
+		if !before[field.path] {
+			changes = append(changes, RequestChange{Kind: RequestChangeAdded, Path: field.path, After: field.value})
+		}
+	}
+	return RequestDiff{Changes: changes}
+}
+
+// diffMessages aligns two lists of rendered messages with their longest common
+// subsequence. Removals directly followed by additions are reported as modifications.
+func diffMessages(a, b []string) []RequestChange {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []RequestChange
+	var removed, added []int
+	flush := func() {
+		paired := min(len(removed), len(added))
+		for k := 0; k < paired; k++ {
+			changes = append(changes, RequestChange{
+				Kind:   RequestChangeModified,
+				Path:   fmt.Sprintf("messages[%d]", added[k]),
+				Before: a[removed[k]],
+				After:  b[added[k]],
+			})
+		}
+		for _, i := range removed[paired:] {
+			changes = append(changes, RequestChange{Kind: RequestChangeRemoved, Path: fmt.Sprintf("messages[%d]", i), Before: a[i]})
+		}
+		for _, j := range added[paired:] {
+			changes = append(changes, RequestChange{Kind: RequestChangeAdded, Path: fmt.Sprintf("messages[%d]", j), After: b[j]})
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, j)
+			j++
+		default:
+			removed = append(removed, i)
+			i++
+		}
+	}
+	flush()
+	return changes
+}
+
+// renderDiffMessage renders a message on a single line
+func renderDiffMessage(msg Message) string {
+	parts := make([]string, 0, len(msg.Content)+len(msg.ToolCalls)+2)
+	for _, content := range msg.Content {
+		switch c := content.(type) {
+		case *TextContent:
+			parts = append(parts, strconv.Quote(c.Text))
+		case *ImageContent:
+			parts = append(parts, fmt.Sprintf("image(%s)", renderDiffSource(c.MimeType, c.URL, c.Filename, len(c.Data))))
+		case *FileContent:
+			parts = append(parts, fmt.Sprintf("file(%s)", renderDiffSource(c.MimeType, c.URL, c.Filename, len(c.Data))))
+		case nil:
+		default:
+			parts = append(parts, fmt.Sprintf("%s(%d bytes)", content.Type(), content.Size()))
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		parts = append(parts, fmt.Sprintf("tool_call %s %s(%s)", call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	if msg.ToolCallID != "" {
+		parts = append(parts, "tool_call_id "+msg.ToolCallID)
+	}
+	if len(msg.Metadata) > 0 {
+		parts = append(parts, "metadata "+renderDiffValue(msg.Metadata))
+	}
+	return string(msg.Role) + ": " + strings.Join(parts, ", ")
+}
+
+// renderDiffSource describes binary content without its data
+func renderDiffSource(mimeType, url, filename string, size int) string {
+	parts := []string{mimeType}
+	if filename != "" {
+		parts = append(parts, filename)
+	}
+	if url != "" {
+		parts = append(parts, url)
+	} else {
+		parts = append(parts, fmt.Sprintf("%d bytes", size))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// renderDiffValue renders a value as compact JSON
+func renderDiffValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRequests(t *testing.T) {
+	temperature, lower := float32(0.7), float32(0.2)
+	a := &ChatRequest{
+		Model: "gpt-4o",
+		Messages: []Message{
+			NewTextMessage(RoleUser, "What is the weather?"),
+			NewTextMessage(RoleAssistant, "Where?"),
+			NewTextMessage(RoleUser, "In Paris"),
+		},
+		Tools: []Tool{
+			{Type: "function", Function: ToolFunction{Name: "weather", Description: "Get the weather"}},
+			{Type: "function", Function: ToolFunction{Name: "search", Description: "Search the web"}},
+		},
+		Temperature: &temperature,
+		Metadata:    map[string]any{"tenant": "acme"},
+	}
+	b := &ChatRequest{
+		Model: "gpt-4o",
+		Messages: []Message{
+			NewTextMessage(RoleSystem, "Be brief"),
+			NewTextMessage(RoleUser, "What is the weather?"),
+			NewTextMessage(RoleAssistant, "Where?"),
+			NewTextMessage(RoleUser, "In [REDACTED]"),
+		},
+		Tools: []Tool{
+			{Type: "function", Function: ToolFunction{Name: "weather", Description: "Get the current weather"}},
+		},
+		Temperature: &lower,
+		Metadata:    map[string]any{"tenant": "acme", "user": 42},
+		ExtraBody:   map[string]any{"seed": 1},
+	}
+
+	diff := DiffRequests(a, b)
+	assert.Equal(t, []RequestChange{
+		{Kind: RequestChangeAdded, Path: "messages[0]", After: `system: "Be brief"`},
+		{Kind: RequestChangeModified, Path: "messages[3]", Before: `user: "In Paris"`, After: `user: "In [REDACTED]"`},
+		{Kind: RequestChangeModified, Path: "tools[weather]", Before: `"Get the weather"`, After: `"Get the current weather"`},
+		{Kind: RequestChangeRemoved, Path: "tools[search]", Before: `"Search the web"`},
+		{Kind: RequestChangeModified, Path: "temperature", Before: "0.7", After: "0.2"},
+		{Kind: RequestChangeAdded, Path: "metadata.user", After: "42"},
+		{Kind: RequestChangeAdded, Path: "extra_body.seed", After: "1"},
+	}, diff.Changes)
+
+	assert.Equal(t, `+ messages[0]: system: "Be brief"
+~ messages[3]: user: "In Paris" -> user: "In [REDACTED]"
+~ tools[weather]: "Get the weather" -> "Get the current weather"
+- tools[search]: "Search the web"
+~ temperature: 0.7 -> 0.2
++ metadata.user: 42
++ extra_body.seed: 1`, diff.String())
+
+	assert.True(t, DiffRequests(a, a).IsEmpty())
+	assert.Len(t, DiffRequests(nil, a).Changes, 8)
+}
+
+func TestDiffRequests_Messages(t *testing.T) {
+	image := Message{Role: RoleUser, Content: []MessageContent{
+		NewTextContent("Describe it"),
+		&ImageContent{MimeType: "image/png", Data: make([]byte, 100)},
+	}}
+	call := Message{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Function: ToolCallFunction{Name: "search", Arguments: `{"q":"go"}`}}}}
+	result := NewTextMessage(RoleTool, "found")
+	result.ToolCallID = "call_1"
+
+	diff := DiffRequests(&ChatRequest{Messages: []Message{image, call}}, &ChatRequest{Messages: []Message{call, result}})
+	assert.Equal(t, []RequestChange{
+		{Kind: RequestChangeRemoved, Path: "messages[0]", Before: `user: "Describe it", image(image/png, 100 bytes)`},
+		{Kind: RequestChangeAdded, Path: "messages[1]", After: `tool: "found", tool_call_id call_1`},
+	}, diff.Changes)
+	assert.Contains(t, renderDiffMessage(call), `tool_call call_1 search({"q":"go"})`)
+}
+
+func TestMiddlewareChain_RequestDiffs(t *testing.T) {
+	inPlace := &mockMiddleware{name: "in-place", reqMods: func(req *ChatRequest) (*ChatRequest, error) {
+		req.Messages[0] = NewTextMessage(RoleUser, "[REDACTED]")
+		return req, nil
+	}}
+	chain := NewMiddlewareChain([]Middleware{
+		newTestMiddleware("noop"),
+		inPlace,
+		newModifyingMiddleware("suffix", "-v2"),
+	})
+	req := &ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, "my secret")}}
+
+	// Without capture nothing is recorded
+	_, err := chain.ProcessRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	req = &ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, "my secret")}}
+	ctx, capture := WithRequestDiffCapture(context.Background())
+	_, err = chain.ProcessRequest(ctx, req)
+	require.NoError(t, err)
+
+	diffs := capture.Diffs()
+	require.Len(t, diffs, 3)
+	assert.Equal(t, "noop", diffs[0].Middleware)
+	assert.True(t, diffs[0].Diff.IsEmpty())
+	assert.Equal(t, []RequestChange{
+		{Kind: RequestChangeModified, Path: "messages[0]", Before: `user: "my secret"`, After: `user: "[REDACTED]"`},
+	}, diffs[1].Diff.Changes)
+	assert.Equal(t, []RequestChange{
+		{Kind: RequestChangeModified, Path: "model", Before: `"m"`, After: `"m-v2"`},
+	}, diffs[2].Diff.Changes)
+
+	assert.Equal(t, `noop: no changes
+in-place:
+  ~ messages[0]: user: "my secret" -> user: "[REDACTED]"
+suffix:
+  ~ model: "m" -> "m-v2"
+`, capture.String())
+
+	// The capture also works through an EnhancedClient
+	client := NewEnhancedClient(NewMockClient("m", "mock"), []Middleware{newModifyingMiddleware("suffix", "-v2")})
+	ctx, capture = WithRequestDiffCapture(context.Background())
+	_, err = client.ChatCompletion(ctx, ChatRequest{Model: "m"})
+	require.NoError(t, err)
+	require.Len(t, capture.Diffs(), 1)
+	assert.Equal(t, "model", capture.Diffs()[0].Diff.Changes[0].Path)
+}
@@ -25,7 +25,8 @@ type ResourceManager struct {
 	// State management
 	mu              sync.RWMutex
 	tempFileCounter int64
-	cleanupTicker   *time.Ticker
+	clock           Clock
+	cleanupTicker   Ticker
 	cleanupStop     chan bool
 
 	// Metrics
@@ -42,6 +43,9 @@ type ResourceManagerConfig struct {
 	CleanupInterval time.Duration `json:"cleanup_interval"`
 	RetentionPeriod time.Duration `json:"retention_period"`
 	StreamThreshold int64         `json:"stream_threshold"`
+
+	// Clock times the cleanups and the retention of the temp files (SystemClock when nil)
+	Clock Clock `json:"-"`
 }
 
 // NewResourceManager creates a new ResourceManager instance with the given configuration
@@ -77,6 +81,7 @@ func NewResourceManager(config ResourceManagerConfig) *ResourceManager {
 		cleanupInterval: config.CleanupInterval,
 		retentionPeriod: config.RetentionPeriod,
 		streamThreshold: config.StreamThreshold,
+		clock:           clockOrSystem(config.Clock),
 		cleanupStop:     make(chan bool, 1),
 	}
 
@@ -203,7 +208,7 @@ func (rm *ResourceManager) StoreBinaryContent(data []byte) (string, error) {
 	rm.mu.Unlock()
 
 	// Create unique filename with timestamp and counter
-	timestamp := time.Now().UnixNano()
+	timestamp := rm.clock.Now().UnixNano()
 	filename := fmt.Sprintf("content_%d_%d.tmp", timestamp, counter)
 	filepath := filepath.Join(rm.tempStoragePath, filename)
 
@@ -264,7 +269,7 @@ func (rm *ResourceManager) CleanupTempFiles() error {
 		return fmt.Errorf("failed to read temp directory: %w", err)
 	}
 
-	cutoff := time.Now().Add(-rm.retentionPeriod)
+	cutoff := rm.clock.Now().Add(-rm.retentionPeriod)
 	filesDeleted := int64(0)
 
 	for _, entry := range entries {
@@ -417,12 +422,12 @@ func (rm *ResourceManager) GetAvailableDiskSpace() (int64, error) {
 
 // startCleanup starts the automatic cleanup process
 func (rm *ResourceManager) startCleanup() {
-	rm.cleanupTicker = time.NewTicker(rm.cleanupInterval)
+	rm.cleanupTicker = rm.clock.NewTicker(rm.cleanupInterval)
 
 	go func() {
 		for {
 			select {
-			case <-rm.cleanupTicker.C:
+			case <-rm.cleanupTicker.C():
 				_ = rm.CleanupTempFiles()
 			case <-rm.cleanupStop:
 				return
@@ -384,9 +384,11 @@ func TestResourceManager_TempFileOperations(t *testing.T) {
 func TestResourceManager_CleanupTempFiles(t *testing.T) {
 	// Create a custom temp directory for testing
 	testTempDir := filepath.Join(os.TempDir(), fmt.Sprintf("test-cleanup-%d", time.Now().UnixNano()))
+	clock := NewFakeClock(time.Now())
 	config := ResourceManagerConfig{
 		TempStoragePath: testTempDir,
 		RetentionPeriod: 100 * time.Millisecond, // Very short retention for testing
+		Clock:           clock,
 	}
 	rm := NewResourceManager(config)
 	defer func() {
@@ -420,8 +422,8 @@ func TestResourceManager_CleanupTempFiles(t *testing.T) {
 		t.Error("Second temp file should exist")
 	}
 
-	// Wait for retention period to pass
-	time.Sleep(150 * time.Millisecond)
+	// Let the retention period pass
+	clock.Advance(time.Second)
 
 	// Run cleanup
 	err = rm.CleanupTempFiles()
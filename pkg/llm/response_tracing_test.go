@@ -169,7 +169,7 @@ func TestResponseTracingScenario(t *testing.T) {
 		choice := choices[0]
 		assert.Equal(t, "Hello World", choice["text_preview"])
 		assert.Equal(t, 11, choice["content_size"]) // len("Hello World")
-		assert.Equal(t, "stop", choice["finish_reason"])
+		assert.Equal(t, FinishReasonStop, choice["finish_reason"])
 	})
 
 	t.Run("middleware_chain_scenario", func(t *testing.T) {
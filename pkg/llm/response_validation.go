@@ -0,0 +1,294 @@
+// Response validators with automatic re-asking of the model
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MetadataValidationAttempts is the response metadata key holding the number of requests
+// made by a ValidatingClient to get the response
+const MetadataValidationAttempts = "validation_attempts"
+
+// DefaultValidationFeedbackPrompt is the user message re-asking the model after a failed
+// validation, formatted with the failures
+const DefaultValidationFeedbackPrompt = "Your previous answer was rejected:\n%s\n\n" +
+	"Answer again, fixing these problems and keeping the rest of the answer."
+
+// ResponseValidator checks a response. The error explains what is wrong: it is sent back
+// to the model when re-asking, so it should tell how to fix the answer.
+type ResponseValidator interface {
+	ValidateResponse(ctx context.Context, resp *ChatResponse) error
+}
+
+// ResponseValidatorFunc adapts a function to a ResponseValidator
+type ResponseValidatorFunc func(ctx context.Context, resp *ChatResponse) error
+
+// ValidateResponse calls the function
+func (f ResponseValidatorFunc) ValidateResponse(ctx context.Context, resp *ChatResponse) error {
+	return f(ctx, resp)
+}
+
+// TextValidator creates a validator of the text of the first choice of responses
+func TextValidator(validate func(text string) error) ResponseValidator {
+	return ResponseValidatorFunc(func(_ context.Context, resp *ChatResponse) error {
+		return validate(responseText(resp))
+	})
+}
+
+// MustContain creates a validator requiring the text of responses to contain all the
+// substrings (case-sensitive)
+func MustContain(substrings ...string) ResponseValidator {
+	return TextValidator(func(text string) error {
+		var missing []string
+		for _, s := range substrings {
+			if !strings.Contains(text, s) {
+				missing = append(missing, fmt.Sprintf("%q", s))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("the answer must contain %s", strings.Join(missing, ", "))
+		}
+		return nil
+	})
+}
+
+// MustNotContain creates a validator rejecting responses whose text contains any of the
+// substrings (case-insensitive)
+func MustNotContain(substrings ...string) ResponseValidator {
+	return TextValidator(func(text string) error {
+		lower := strings.ToLower(text)
+		var found []string
+		for _, s := range substrings {
+			if strings.Contains(lower, strings.ToLower(s)) {
+				found = append(found, fmt.Sprintf("%q", s))
+			}
+		}
+		if len(found) > 0 {
+			return fmt.Errorf("the answer must not contain %s", strings.Join(found, ", "))
+		}
+		return nil
+	})
+}
+
+// MaxLength creates a validator rejecting responses whose text is longer than a number
+// of characters
+func MaxLength(characters int) ResponseValidator {
+	return TextValidator(func(text string) error {
+		if n := utf8.RuneCountInString(text); n > characters {
+			return fmt.Errorf("the answer has %d characters, but must have at most %d", n, characters)
+		}
+		return nil
+	})
+}
+
+// WithValidators returns a copy of the request with the validators appended
+func (r ChatRequest) WithValidators(validators ...ResponseValidator) ChatRequest {
+	merged := make([]ResponseValidator, 0, len(r.Validators)+len(validators))
+	merged = append(merged, r.Validators...)
+	r.Validators = append(merged, validators...)
+	return r
+}
+
+// ValidationAttempt is a response checked by a ValidatingClient, with the failures of
+// its validators (none for the accepted response)
+type ValidationAttempt struct {
+	Response *ChatResponse `json:"response"`
+	Failures []string      `json:"failures,omitempty"`
+}
+
+// ValidationResult reports the requests made to get a valid response
+type ValidationResult struct {
+	// Response is the accepted response, nil when all the attempts failed
+	Response *ChatResponse `json:"response,omitempty"`
+
+	// Attempts are all the responses, in order, the last one being the accepted one
+	Attempts []ValidationAttempt `json:"attempts"`
+}
+
+// ValidatingConfig configures a ValidatingClient
+type ValidatingConfig struct {
+	// MaxReasks limits the follow-up requests made after failed validations
+	MaxReasks int `json:"max_reasks"`
+
+	// FeedbackPrompt is the user message re-asking the model, with a %s for the failures
+	FeedbackPrompt string `json:"feedback_prompt"`
+
+	// Validators check every response, before those of the requests
+	Validators []ResponseValidator `json:"-"`
+}
+
+// DefaultValidatingConfig returns the default validation configuration
+func DefaultValidatingConfig() *ValidatingConfig {
+	return &ValidatingConfig{
+		MaxReasks:      2,
+		FeedbackPrompt: DefaultValidationFeedbackPrompt,
+	}
+}
+
+// ValidatingClient wraps a client so responses are checked by the validators of the
+// client and of the requests (ChatRequest.Validators). When a response fails, the model
+// is re-asked with the failures up to MaxReasks times. Responses with tool calls are not
+// validated.
+type ValidatingClient struct {
+	client Client
+	config *ValidatingConfig
+}
+
+// NewValidatingClient wraps a client, filling unset config fields with defaults
+func NewValidatingClient(client Client, config *ValidatingConfig) *ValidatingClient {
+	defaults := DefaultValidatingConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.MaxReasks <= 0 {
+		config.MaxReasks = defaults.MaxReasks
+	}
+	if config.FeedbackPrompt == "" {
+		config.FeedbackPrompt = defaults.FeedbackPrompt
+	}
+	return &ValidatingClient{client: client, config: config}
+}
+
+// ChatCompletion performs the request, re-asking the model until a response passes the
+// validators. The number of requests made is in the MetadataValidationAttempts of the
+// response; use Complete for the rejected responses.
+func (c *ValidatingClient) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	result, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := *result.Response
+	resp.Metadata = make(map[string]any, len(result.Response.Metadata)+1)
+	for k, v := range result.Response.Metadata {
+		resp.Metadata[k] = v
+	}
+	resp.Metadata[MetadataValidationAttempts] = len(result.Attempts)
+	return &resp, nil
+}
+
+// Complete performs the request like ChatCompletion, returning all the attempts. When
+// they all fail, the error has the "validation_failed" code and the result lists the
+// attempts.
+func (c *ValidatingClient) Complete(ctx context.Context, req ChatRequest) (*ValidationResult, error) {
+	validators := append(append([]ResponseValidator(nil), c.config.Validators...), req.Validators...)
+
+	result := &ValidationResult{}
+	current := req
+	for i := 0; ; i++ {
+		resp, err := c.client.ChatCompletion(ctx, current)
+		if err != nil {
+			return result, err
+		}
+		attempt := ValidationAttempt{Response: resp}
+		if len(resp.Choices) > 0 && !resp.Choices[0].Message.HasToolCalls() {
+			attempt.Failures = validateResponse(ctx, validators, resp)
+		}
+		result.Attempts = append(result.Attempts, attempt)
+		if len(attempt.Failures) == 0 {
+			result.Response = resp
+			return result, nil
+		}
+		if i == c.config.MaxReasks {
+			return result, validationFailedError(len(result.Attempts), attempt.Failures)
+		}
+		current = c.reaskRequest(current, resp, attempt.Failures)
+	}
+}
+
+// StreamChatCompletion streams the response, checking it with the validators when it is
+// done. The model is not re-asked, as the text was already sent: a failed validation
+// replaces the done event with a "validation_failed" error event.
+func (c *ValidatingClient) StreamChatCompletion(ctx context.Context, req ChatRequest) (<-chan StreamEvent, error) {
+	stream, err := c.client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	validators := append(append([]ResponseValidator(nil), c.config.Validators...), req.Validators...)
+	if len(validators) == 0 {
+		return stream, nil
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		toolCalls := false
+		for event := range stream {
+			switch {
+			case event.IsDelta():
+				toolCalls = toolCalls || len(event.Choice.Delta.ToolCalls) > 0
+				text.WriteString(deltaText(event.Choice.Delta))
+			case event.IsDone() && !toolCalls:
+				resp := &ChatResponse{Choices: []Choice{{Message: NewTextMessage(RoleAssistant, text.String())}}}
+				if event.Choice != nil {
+					resp.Choices[0].FinishReason = event.Choice.FinishReason
+				}
+				if failures := validateResponse(ctx, validators, resp); len(failures) > 0 {
+					event = NewErrorEvent(validationFailedError(1, failures))
+				}
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// reaskRequest creates the follow-up request with the rejected answer and the failures
+func (c *ValidatingClient) reaskRequest(req ChatRequest, resp *ChatResponse, failures []string) ChatRequest {
+	follow := req
+	follow.Messages = append(append(make([]Message, 0, len(req.Messages)+2), req.Messages...),
+		NewTextMessage(RoleAssistant, responseText(resp)),
+		NewTextMessage(RoleUser, fmt.Sprintf(c.config.FeedbackPrompt, "- "+strings.Join(failures, "\n- "))),
+	)
+	return follow
+}
+
+// GetRemote returns information about the wrapped client
+func (c *ValidatingClient) GetRemote() ClientRemoteInfo {
+	return c.client.GetRemote()
+}
+
+// GetModelInfo returns information about the wrapped model
+func (c *ValidatingClient) GetModelInfo() ModelInfo {
+	return c.client.GetModelInfo()
+}
+
+// Close closes the wrapped client
+func (c *ValidatingClient) Close() error {
+	return c.client.Close()
+}
+
+// validateResponse runs the validators, returning their failures
+func validateResponse(ctx context.Context, validators []ResponseValidator, resp *ChatResponse) []string {
+	var failures []string
+	for _, validator := range validators {
+		if err := validator.ValidateResponse(ctx, resp); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	return failures
+}
+
+// responseText returns the text of the first choice of a response
+func responseText(resp *ChatResponse) string {
+	if resp == nil || len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Message.GetText()
+}
+
+// validationFailedError creates the error of responses failing their validators
+func validationFailedError(attempts int, failures []string) *Error {
+	return &Error{
+		Code:    "validation_failed",
+		Message: fmt.Sprintf("response rejected after %d attempt(s): %s", attempts, strings.Join(failures, "; ")),
+		Type:    "validation_error",
+	}
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseValidators(t *testing.T) {
+	ctx := context.Background()
+	resp := textResponse("The capital of France is Paris. As an AI, I think so.")
+
+	assert.NoError(t, MustContain("Paris", "France").ValidateResponse(ctx, resp))
+	assert.EqualError(t, MustContain("Paris", "Lyon", "Nice").ValidateResponse(ctx, resp), `the answer must contain "Lyon", "Nice"`)
+	assert.EqualError(t, MustNotContain("as an ai").ValidateResponse(ctx, resp), `the answer must not contain "as an ai"`)
+	assert.NoError(t, MaxLength(60).ValidateResponse(ctx, resp))
+	assert.EqualError(t, MaxLength(10).ValidateResponse(ctx, resp), "the answer has 53 characters, but must have at most 10")
+}
+
+func TestValidatingClient(t *testing.T) {
+	mock := NewMockClient("test-model", "test")
+	mock.responses = []*ChatResponse{
+		textResponse("As an AI, I believe it is Paris."),
+		textResponse("It is Lyon."),
+		textResponse("It is Paris."),
+	}
+	client := NewValidatingClient(mock, &ValidatingConfig{Validators: []ResponseValidator{MustNotContain("as an AI")}})
+
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Capital of France?")}}
+	result, err := client.Complete(context.Background(), req.WithValidators(MustContain("Paris")))
+	require.NoError(t, err)
+	assert.Equal(t, "It is Paris.", result.Response.Choices[0].Message.GetText())
+	require.Len(t, result.Attempts, 3)
+	assert.Equal(t, []string{`the answer must not contain "as an AI"`}, result.Attempts[0].Failures)
+	assert.Equal(t, []string{`the answer must contain "Paris"`}, result.Attempts[1].Failures)
+	assert.Empty(t, result.Attempts[2].Failures)
+
+	// The re-asks hold the rejected answers and the feedback
+	require.Len(t, mock.callLog, 3)
+	last := mock.callLog[2].Messages
+	require.Len(t, last, 5)
+	assert.Equal(t, "It is Lyon.", last[3].GetText())
+	assert.Contains(t, last[4].GetText(), `- the answer must contain "Paris"`)
+
+	// Without re-asks left, the last failures are returned with all the attempts
+	mock.callLog = nil
+	mock.responses = []*ChatResponse{textResponse("Lyon"), textResponse("Lyon"), textResponse("Lyon")}
+	result, err = client.Complete(context.Background(), req.WithValidators(MustContain("Paris")))
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "validation_failed", llmErr.Code)
+	assert.Nil(t, result.Response)
+	assert.Len(t, result.Attempts, 3)
+
+	// ChatCompletion reports the number of attempts
+	mock.responses = []*ChatResponse{textResponse("As an AI: Paris"), textResponse("Paris")}
+	resp, err := client.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Metadata[MetadataValidationAttempts])
+}
+
+func TestValidatingClientStream(t *testing.T) {
+	mock := NewMockClient("test-model", "test")
+	client := NewValidatingClient(mock, nil)
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Hi")}}
+
+	stream, err := client.StreamChatCompletion(context.Background(), req.WithValidators(MaxLength(10)))
+	require.NoError(t, err)
+	var events []StreamEvent
+	for event := range stream {
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.True(t, events[1].IsDone())
+
+	stream, err = client.StreamChatCompletion(context.Background(), req.WithValidators(MustContain("Hello")))
+	require.NoError(t, err)
+	events = nil
+	for event := range stream {
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	require.True(t, events[1].IsError())
+	assert.Equal(t, "validation_failed", events[1].Error.Code)
+}
@@ -9,7 +9,9 @@ import (
 
 // ExtractJSONFromResponse extracts JSON from LLM response that may contain markdown
 // code blocks or other text. It returns the extracted JSON string or the original
-// response if no JSON is found.
+// response if no JSON is found. For other cleanups, composed with this one, see
+// ResponseProcessor, and for other extraction modes and diagnostics, see
+// ExtractJSONWithOptions.
 //
 // Example:
 //
@@ -17,80 +19,12 @@ import (
 //	jsonStr := ExtractJSONFromResponse(response)
 //	fmt.Println(jsonStr) // Output: {"key": "value"}
 func ExtractJSONFromResponse(text string) string {
-	// Remove color codes and ANSI escape sequences
-	text = strings.ReplaceAll(text, "\033[92m", "")
-	text = strings.ReplaceAll(text, "\033[0m", "")
-	text = strings.ReplaceAll(text, "[92m", "")
-	text = strings.ReplaceAll(text, "[0m", "")
-
-	// Remove leading/trailing whitespace
-	text = strings.TrimSpace(text)
-
-	// Try to find JSON within markdown code blocks (more flexible patterns)
-	patterns := []string{
-		// Markdown code blocks with json label (with optional newlines)
-		"```json\\s*([\\s\\S]*?)```",
-		"```JSON\\s*([\\s\\S]*?)```",
-		// Code blocks with other common language identifiers that might contain JSON
-		"```javascript\\s*([\\s\\S]*?)```",
-		"```js\\s*([\\s\\S]*?)```",
-		// Generic code blocks (any language or no language)
-		"```\\w*\\s*([\\s\\S]*?)```",
-		// Single backticks
-		"`([^`]+)`",
-	}
-
-	for _, pattern := range patterns {
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			continue
-		}
-
-		matches := re.FindStringSubmatch(text)
-		if len(matches) > 1 {
-			candidate := strings.TrimSpace(matches[1])
-			if isValidJSONStart(candidate) {
-				// Try to preserve original formatting if JSON is already valid
-				if isValidJSON(candidate) {
-					return candidate
-				}
-				// Clean up the JSON by removing comments and extra formatting
-				cleaned := cleanJSON(candidate)
-				if cleaned != "" {
-					return cleaned
-				}
-			}
-		}
-	}
-
-	// Try to find complete JSON objects/arrays using a more robust approach
-	jsonCandidates := findJSONBlocks(text)
-	for _, candidate := range jsonCandidates {
-		candidate = strings.TrimSpace(candidate)
-		if isValidJSONStart(candidate) {
-			if isValidJSON(candidate) {
-				return candidate
-			}
-			cleaned := cleanJSON(candidate)
-			if cleaned != "" {
-				return cleaned
-			}
-		}
-	}
-
-	// If no JSON patterns found, check if the entire text is JSON
-	if isValidJSONStart(text) {
-		if isValidJSON(text) {
-			return text
-		}
-		cleaned := cleanJSON(text)
-		if cleaned != "" {
-			return cleaned
-		}
+	extraction, err := ExtractJSONWithOptions(text, JSONExtractionOptions{Mode: JSONExtractionLenient})
+	if err != nil {
+		// Return the original text if no JSON extraction possible
+		return strings.TrimSpace(stripANSI(text))
 	}
-
-	// Return original text if no JSON extraction possible
-	return text
+	return extraction.JSON
 }
 
 // isValidJSONStart checks if text starts with valid JSON characters
@@ -108,6 +42,16 @@ func isValidJSON(text string) bool {
 // findJSONBlocks uses a more robust approach to find JSON objects and arrays
 func findJSONBlocks(text string) []string {
 	var results []string
+	for _, span := range findJSONBlockSpans(text) {
+		results = append(results, text[span[0]:span[1]])
+	}
+	return results
+}
+
+// findJSONBlockSpans returns the start and end offsets of the JSON objects and arrays of
+// a text, including the nested ones, by their start offset
+func findJSONBlockSpans(text string) [][2]int {
+	var results [][2]int
 
 	// Find JSON objects with proper bracket matching
 	for i := 0; i < len(text); i++ {
@@ -147,8 +91,7 @@ func findJSONBlocks(text string) []string {
 
 			if braceCount == 0 {
 				// Found complete JSON object
-				candidate := text[i : i+findLastBraceIndex(text[i:])+1]
-				results = append(results, candidate)
+				results = append(results, [2]int{i, i + findLastBraceIndex(text[i:]) + 1})
 			}
 		} else if text[i] == '[' {
 			// Find matching closing bracket
@@ -186,8 +129,7 @@ func findJSONBlocks(text string) []string {
 
 			if bracketCount == 0 {
 				// Found complete JSON array
-				candidate := text[i : i+findLastBracketIndex(text[i:])+1]
-				results = append(results, candidate)
+				results = append(results, [2]int{i, i + findLastBracketIndex(text[i:]) + 1})
 			}
 		}
 	}
@@ -77,8 +77,8 @@ func TestChatResponseDeepCopy(t *testing.T) {
 		require.Len(t, copied.Choices, 2)
 		assert.Equal(t, "First choice response", copied.Choices[0].Message.GetText())
 		assert.Equal(t, "Second choice response", copied.Choices[1].Message.GetText())
-		assert.Equal(t, "stop", copied.Choices[0].FinishReason)
-		assert.Equal(t, "length", copied.Choices[1].FinishReason)
+		assert.Equal(t, FinishReasonStop, copied.Choices[0].FinishReason)
+		assert.Equal(t, FinishReasonLength, copied.Choices[1].FinishReason)
 
 		// Verify independence - modify original first choice
 		original.Choices[0].Message.SetText("Modified first choice")
@@ -86,7 +86,7 @@ func TestChatResponseDeepCopy(t *testing.T) {
 
 		// Copy should be unaffected
 		assert.Equal(t, "First choice response", copied.Choices[0].Message.GetText())
-		assert.Equal(t, "length", copied.Choices[1].FinishReason)
+		assert.Equal(t, FinishReasonLength, copied.Choices[1].FinishReason)
 
 		t.Log("✅ Multi-choice ChatResponse deep copy works correctly")
 	})
@@ -273,9 +273,50 @@ func TestChatResponseDeepCopyWithTracing(t *testing.T) {
 		choice := choices[0]
 		assert.Equal(t, "Hello World", choice["text_preview"])
 		assert.Equal(t, 11, choice["content_size"]) // len("Hello World")
-		assert.Equal(t, "stop", choice["finish_reason"])
+		assert.Equal(t, FinishReasonStop, choice["finish_reason"])
 
 		t.Logf("✅ Traced content: %+v", content)
 		t.Log("✅ Real tracing scenario with DeepCopy produces correct non-empty content")
 	})
 }
+
+func TestChatResponseDeepCopyMetadata(t *testing.T) {
+	original := &ChatResponse{
+		ID:    "chatcmpl-metadata",
+		Model: "test-model",
+	}
+	original.SetMetadata("tenant_id", "acme")
+	original.SetMetadata("tags", []any{"a", "b"})
+
+	copied := original.DeepCopy()
+	require.Len(t, copied.Metadata, 2)
+
+	original.SetMetadata("tenant_id", "other")
+	original.Metadata["tags"].([]any)[0] = "changed"
+
+	tenant, ok := copied.GetMetadata("tenant_id")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, "a", copied.Metadata["tags"].([]any)[0])
+}
+
+func TestChatRequestMetadataAsStrings(t *testing.T) {
+	req := ChatRequest{}
+	assert.Nil(t, req.MetadataAsStrings())
+
+	req.SetMetadata("tenant_id", "acme")
+	req.SetMetadata("user_id", 42)
+	req.SetMetadata("beta", true)
+	req.SetMetadata("labels", map[string]any{"env": "prod"})
+
+	value, ok := req.GetMetadata("user_id")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+
+	assert.Equal(t, map[string]string{
+		"tenant_id": "acme",
+		"user_id":   "42",
+		"beta":      "true",
+		"labels":    `{"env":"prod"}`,
+	}, req.MetadataAsStrings())
+}
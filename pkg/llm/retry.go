@@ -281,13 +281,16 @@ func (r *RetryableChatCompleter) ChatCompletion(ctx context.Context, req ChatReq
 			break
 		}
 
-		// Check if this error should trigger a retry
-		if !r.isRetryableError(err) {
+		// Check if this error should trigger a retry. Dry runs are never retried.
+		if !r.isRetryableError(err) || IsDryRun(ctx) {
 			return nil, err
 		}
 
-		// Calculate delay for this attempt
-		delay := r.calculateDelay(attempt)
+		// Calculate delay for this attempt, honouring the wait requested by the provider
+		delay, ok := r.retryDelay(ctx, attempt, err)
+		if !ok {
+			return nil, err
+		}
 
 		// Create a timer for the delay, but also respect context cancellation
 		select {
@@ -376,6 +379,32 @@ func (r *RetryableChatCompleter) statusCodeMatches(statusCode int) bool {
 	return false
 }
 
+// retryDelay returns the delay before the next attempt. When the provider reported
+// how long to wait (see RateLimitInfo), that wait is used instead of the backoff, and
+// the retry is abandoned if the wait exceeds MaxDelay or the context deadline, or if
+// the account ran out of credits.
+func (r *RetryableChatCompleter) retryDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	info := RateLimitOf(err)
+	if info == nil {
+		return r.calculateDelay(attempt), true
+	}
+	if info.CreditsExhausted {
+		return 0, false
+	}
+
+	delay := info.Delay()
+	if delay <= 0 {
+		return r.calculateDelay(attempt), true
+	}
+	if delay > r.config.MaxDelay {
+		return 0, false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return 0, false
+	}
+	return delay, true
+}
+
 // calculateDelay computes the delay for a given retry attempt using exponential backoff
 func (r *RetryableChatCompleter) calculateDelay(attempt int) time.Duration {
 	// Calculate exponential backoff: baseDelay * (backoffFactor ^ attempt)
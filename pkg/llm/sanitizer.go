@@ -0,0 +1,266 @@
+// Prompt sanitization for untrusted content
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetadataKeySource is the message metadata key used to identify where the
+// content of a message comes from (e.g. a document URL or a tool name)
+const MetadataKeySource = "source"
+
+// SanitizeAction defines what the sanitizer does when a rule matches
+type SanitizeAction string
+
+const (
+	// SanitizeActionStrip removes the matched text
+	SanitizeActionStrip SanitizeAction = "strip"
+	// SanitizeActionNeutralize wraps the matched text so it reads as quoted data
+	SanitizeActionNeutralize SanitizeAction = "neutralize"
+	// SanitizeActionBlock rejects the whole request
+	SanitizeActionBlock SanitizeAction = "block"
+)
+
+// SanitizerRule is a single pattern checked against untrusted content
+type SanitizerRule struct {
+	Name    string         `json:"name"`
+	Pattern *regexp.Regexp `json:"-"`
+	Action  SanitizeAction `json:"action"`
+}
+
+// PromptSanitizerConfig configures a PromptSanitizer
+type PromptSanitizerConfig struct {
+	// Rules are applied in order. Defaults to DefaultSanitizerRules() when empty.
+	Rules []SanitizerRule `json:"rules"`
+
+	// Roles whose messages are sanitized. Defaults to user and tool messages.
+	Roles []MessageRole `json:"roles"`
+
+	// TrustedSources lists message sources (see MetadataKeySource) that are never sanitized.
+	// Entries ending with "*" are treated as prefixes.
+	TrustedSources []string `json:"trusted_sources"`
+
+	// OnlyTaggedSources restricts sanitization to messages that carry a source in their metadata,
+	// leaving plain user input untouched.
+	OnlyTaggedSources bool `json:"only_tagged_sources"`
+
+	// NeutralizeFormat is the format used by SanitizeActionNeutralize; it must contain one %s.
+	NeutralizeFormat string `json:"neutralize_format"`
+}
+
+// DefaultSanitizerRules returns a set of rules covering common prompt injection phrasing
+func DefaultSanitizerRules() []SanitizerRule {
+	return []SanitizerRule{
+		{
+			Name:    "ignore_instructions",
+			Pattern: regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+|any\s+)?(the\s+)?(previous|prior|above|earlier)\s+(instructions|prompts|directions|rules)\b`),
+			Action:  SanitizeActionNeutralize,
+		},
+		{
+			Name:    "role_override",
+			Pattern: regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in)\b[^.\n]*`),
+			Action:  SanitizeActionNeutralize,
+		},
+		{
+			Name:    "system_prompt_exfiltration",
+			Pattern: regexp.MustCompile(`(?i)\b(reveal|print|show|repeat)\s+(your\s+|the\s+)?(system\s+prompt|hidden\s+instructions)\b`),
+			Action:  SanitizeActionNeutralize,
+		},
+		{
+			Name:    "fake_role_tags",
+			Pattern: regexp.MustCompile(`(?i)<\|?\s*/?\s*(system|assistant|im_start|im_end)\s*\|?>`),
+			Action:  SanitizeActionStrip,
+		},
+	}
+}
+
+// DefaultPromptSanitizerConfig returns the default sanitizer configuration
+func DefaultPromptSanitizerConfig() *PromptSanitizerConfig {
+	return &PromptSanitizerConfig{
+		Rules:            DefaultSanitizerRules(),
+		Roles:            []MessageRole{RoleUser, RoleTool},
+		NeutralizeFormat: "[untrusted content: %s]",
+	}
+}
+
+// PromptSanitizer strips or neutralizes instructions embedded in untrusted content
+// (retrieved documents, file contents, tool outputs) before it reaches the model.
+// It implements Middleware so it can be plugged into an EnhancedClient.
+type PromptSanitizer struct {
+	config      *PromptSanitizerConfig
+	auditLogger *SecurityAuditLogger
+}
+
+// NewPromptSanitizer creates a new prompt sanitizer, filling unset fields with defaults
+func NewPromptSanitizer(config *PromptSanitizerConfig) *PromptSanitizer {
+	defaults := DefaultPromptSanitizerConfig()
+	if config == nil {
+		config = defaults
+	}
+	if len(config.Rules) == 0 {
+		config.Rules = defaults.Rules
+	}
+	if len(config.Roles) == 0 {
+		config.Roles = defaults.Roles
+	}
+	if config.NeutralizeFormat == "" {
+		config.NeutralizeFormat = defaults.NeutralizeFormat
+	}
+
+	return &PromptSanitizer{
+		config:      config,
+		auditLogger: NewSecurityAuditLogger(),
+	}
+}
+
+// Name returns the middleware name
+func (ps *PromptSanitizer) Name() string {
+	return "prompt_sanitizer"
+}
+
+// SanitizeText applies all rules to the given text, returning the sanitized text
+// and the names of the rules that matched
+func (ps *PromptSanitizer) SanitizeText(text string) (string, []string, error) {
+	var matched []string
+
+	for _, rule := range ps.config.Rules {
+		if rule.Pattern == nil || !rule.Pattern.MatchString(text) {
+			continue
+		}
+		matched = append(matched, rule.Name)
+
+		switch rule.Action {
+		case SanitizeActionBlock:
+			ps.auditLogger.LogSecurityEvent("PROMPT_INJECTION_BLOCKED", fmt.Sprintf("Rule: %s", rule.Name))
+			return "", matched, &Error{
+				Code:       "prompt_injection_detected",
+				Message:    fmt.Sprintf("content blocked by sanitizer rule %q", rule.Name),
+				Type:       "validation_error",
+				StatusCode: 400,
+			}
+		case SanitizeActionStrip:
+			text = rule.Pattern.ReplaceAllString(text, "")
+		default:
+			text = rule.Pattern.ReplaceAllStringFunc(text, func(s string) string {
+				return fmt.Sprintf(ps.config.NeutralizeFormat, s)
+			})
+		}
+		ps.auditLogger.LogSecurityEvent("PROMPT_INJECTION_SANITIZED", fmt.Sprintf("Rule: %s", rule.Name))
+	}
+
+	return text, matched, nil
+}
+
+// SanitizeMessage returns a sanitized copy of the message. Messages from trusted
+// sources or roles not configured for sanitization are returned unchanged.
+func (ps *PromptSanitizer) SanitizeMessage(msg Message) (Message, error) {
+	if !ps.shouldSanitize(msg) {
+		return msg, nil
+	}
+
+	sanitized := msg.DeepCopy()
+	var matched []string
+
+	for _, content := range sanitized.Content {
+		switch c := content.(type) {
+		case *TextContent:
+			text, rules, err := ps.SanitizeText(c.Text)
+			if err != nil {
+				return msg, err
+			}
+			c.Text = text
+			matched = append(matched, rules...)
+		case *FileContent:
+			if !c.HasData() || !strings.HasPrefix(c.MimeType, "text/") {
+				continue
+			}
+			text, rules, err := ps.SanitizeText(string(c.Data))
+			if err != nil {
+				return msg, err
+			}
+			c.Data = []byte(text)
+			c.FileSize = int64(len(c.Data))
+			matched = append(matched, rules...)
+		}
+	}
+
+	if len(matched) > 0 {
+		sanitized.SetMetadata("sanitized_rules", matched)
+	}
+
+	return sanitized, nil
+}
+
+// IsTrustedSource checks if a source is in the configured allowlist
+func (ps *PromptSanitizer) IsTrustedSource(source string) bool {
+	for _, trusted := range ps.config.TrustedSources {
+		if prefix, ok := strings.CutSuffix(trusted, "*"); ok {
+			if strings.HasPrefix(source, prefix) {
+				return true
+			}
+		} else if source == trusted {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuditEvents returns the sanitizer events logged so far
+func (ps *PromptSanitizer) GetAuditEvents() []SecurityEvent {
+	return append(ps.auditLogger.GetEventsByType("PROMPT_INJECTION_SANITIZED"),
+		ps.auditLogger.GetEventsByType("PROMPT_INJECTION_BLOCKED")...)
+}
+
+// ProcessRequest sanitizes the untrusted messages in the request
+func (ps *PromptSanitizer) ProcessRequest(ctx context.Context, req *ChatRequest) (*ChatRequest, error) {
+	if req == nil {
+		return req, nil
+	}
+
+	processed := *req
+	processed.Messages = make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		sanitized, err := ps.SanitizeMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		processed.Messages[i] = sanitized
+	}
+
+	return &processed, nil
+}
+
+// ProcessResponse passes responses through unchanged
+func (ps *PromptSanitizer) ProcessResponse(ctx context.Context, req *ChatRequest, resp *ChatResponse, err error) (*ChatResponse, error) {
+	return resp, nil
+}
+
+// ProcessStreamEvent passes stream events through unchanged
+func (ps *PromptSanitizer) ProcessStreamEvent(ctx context.Context, req *ChatRequest, event StreamEvent) (StreamEvent, error) {
+	return event, nil
+}
+
+// shouldSanitize checks the message role and source against the configuration
+func (ps *PromptSanitizer) shouldSanitize(msg Message) bool {
+	roleMatches := false
+	for _, role := range ps.config.Roles {
+		if msg.Role == role {
+			roleMatches = true
+			break
+		}
+	}
+	if !roleMatches {
+		return false
+	}
+
+	source, _ := msg.GetMetadata(MetadataKeySource)
+	sourceStr, _ := source.(string)
+	if sourceStr == "" {
+		return !ps.config.OnlyTaggedSources
+	}
+
+	return !ps.IsTrustedSource(sourceStr)
+}
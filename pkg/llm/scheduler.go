@@ -0,0 +1,281 @@
+// Queued dispatch of requests with priorities and per-provider concurrency limits
+package llm
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// SchedulerConfig configures a Scheduler
+type SchedulerConfig struct {
+	// MaxConcurrency is the number of requests running at the same time per provider
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// ProviderConcurrency overrides MaxConcurrency for some providers, by name
+	ProviderConcurrency map[string]int `json:"provider_concurrency,omitempty"`
+
+	// MinInterval spaces the dispatches to a provider, smoothing bursts. Zero dispatches
+	// as soon as there is capacity.
+	MinInterval time.Duration `json:"min_interval"`
+
+	// MaxQueued limits the requests waiting per provider; Submit fails with a
+	// "queue_full" error beyond it
+	MaxQueued int `json:"max_queued"`
+}
+
+// DefaultSchedulerConfig returns the default scheduler configuration
+func DefaultSchedulerConfig() *SchedulerConfig {
+	return &SchedulerConfig{
+		MaxConcurrency: 4,
+		MaxQueued:      1000,
+	}
+}
+
+// ChatFuture is the pending result of a request submitted to a Scheduler
+type ChatFuture struct {
+	done chan struct{}
+	resp *ChatResponse
+	err  error
+}
+
+// Done returns a channel closed when the result is available
+func (f *ChatFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the result is available or the context is done
+func (f *ChatFuture) Wait(ctx context.Context) (*ChatResponse, error) {
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// complete sets the result of the future
+func (f *ChatFuture) complete(resp *ChatResponse, err error) {
+	f.resp, f.err = resp, err
+	close(f.done)
+}
+
+// SchedulerStats reports the state of the queue of a provider
+type SchedulerStats struct {
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+}
+
+// scheduledRequest is a request waiting in a provider queue
+type scheduledRequest struct {
+	ctx      context.Context
+	client   ChatCompleter
+	req      ChatRequest
+	priority int
+	seq      uint64
+	future   *ChatFuture
+}
+
+// requestQueue orders requests by priority, then by submission
+type requestQueue []*scheduledRequest
+
+func (q requestQueue) Len() int { return len(q) }
+func (q requestQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q requestQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *requestQueue) Push(x any)   { *q = append(*q, x.(*scheduledRequest)) }
+func (q *requestQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
+
+// providerQueue is the queue and the running requests of a provider
+type providerQueue struct {
+	queue        requestQueue
+	running      int
+	lastDispatch time.Time
+	timer        *time.Timer
+}
+
+// Scheduler queues requests and dispatches them as the capacity of their provider
+// allows, higher priorities first and in submission order within a priority, so bursty
+// workloads are smoothed instead of tripping provider rate limits. Requests are grouped
+// by the provider of their client (see GetModelInfo); clients without one share the
+// "default" queue.
+//
+//	scheduler := llm.NewScheduler(&llm.SchedulerConfig{MaxConcurrency: 8})
+//	defer scheduler.Close()
+//	future, err := scheduler.Submit(ctx, client, req, 0)
+//	resp, err := future.Wait(ctx)
+type Scheduler struct {
+	config *SchedulerConfig
+
+	mu      sync.Mutex
+	queues  map[string]*providerQueue
+	seq     uint64
+	closed  bool
+	running sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler, filling unset config fields with defaults
+func NewScheduler(config *SchedulerConfig) *Scheduler {
+	defaults := DefaultSchedulerConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = defaults.MaxConcurrency
+	}
+	if config.MaxQueued <= 0 {
+		config.MaxQueued = defaults.MaxQueued
+	}
+	return &Scheduler{config: config, queues: make(map[string]*providerQueue)}
+}
+
+// Submit queues a request for the client. Higher priorities are dispatched first. The
+// request is dropped, with the context error as result, when its context is done before
+// it is dispatched.
+func (s *Scheduler) Submit(ctx context.Context, client ChatCompleter, req ChatRequest, priority int) (*ChatFuture, error) {
+	provider := schedulerProvider(client)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, &Error{Code: "scheduler_closed", Message: "the scheduler is closed", Type: "validation_error"}
+	}
+	pq := s.queue(provider)
+	if len(pq.queue) >= s.config.MaxQueued {
+		return nil, &Error{
+			Code:    "queue_full",
+			Message: "too many requests queued for provider " + provider,
+			Type:    "rate_limit_error",
+		}
+	}
+
+	s.seq++
+	future := &ChatFuture{done: make(chan struct{})}
+	heap.Push(&pq.queue, &scheduledRequest{
+		ctx:      ctx,
+		client:   client,
+		req:      req,
+		priority: priority,
+		seq:      s.seq,
+		future:   future,
+	})
+	s.dispatch(provider, pq)
+	return future, nil
+}
+
+// Stats returns the state of the queues, by provider
+func (s *Scheduler) Stats() map[string]SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]SchedulerStats, len(s.queues))
+	for provider, pq := range s.queues {
+		stats[provider] = SchedulerStats{Queued: len(pq.queue), Running: pq.running}
+	}
+	return stats
+}
+
+// Close stops accepting requests, fails the queued ones with a "scheduler_closed" error
+// and waits for the running ones to finish
+func (s *Scheduler) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, pq := range s.queues {
+		if pq.timer != nil {
+			pq.timer.Stop()
+		}
+		for _, item := range pq.queue {
+			item.future.complete(nil, &Error{
+				Code:    "scheduler_closed",
+				Message: "the scheduler was closed before the request was sent",
+				Type:    "validation_error",
+			})
+		}
+		pq.queue = nil
+	}
+	s.mu.Unlock()
+
+	s.running.Wait()
+	return nil
+}
+
+// queue returns the queue of a provider, creating it. Called with the lock held.
+func (s *Scheduler) queue(provider string) *providerQueue {
+	pq, ok := s.queues[provider]
+	if !ok {
+		pq = &providerQueue{}
+		s.queues[provider] = pq
+	}
+	return pq
+}
+
+// limit returns the concurrency limit of a provider
+func (s *Scheduler) limit(provider string) int {
+	if limit, ok := s.config.ProviderConcurrency[provider]; ok && limit > 0 {
+		return limit
+	}
+	return s.config.MaxConcurrency
+}
+
+// dispatch starts queued requests of a provider while it has capacity. When MinInterval
+// has not elapsed since the last dispatch, a timer dispatches later. Called with the
+// lock held.
+func (s *Scheduler) dispatch(provider string, pq *providerQueue) {
+	for len(pq.queue) > 0 && pq.running < s.limit(provider) && !s.closed {
+		if s.config.MinInterval > 0 {
+			if wait := time.Until(pq.lastDispatch.Add(s.config.MinInterval)); wait > 0 {
+				if pq.timer == nil {
+					pq.timer = time.AfterFunc(wait, func() {
+						s.mu.Lock()
+						defer s.mu.Unlock()
+						pq.timer = nil
+						s.dispatch(provider, pq)
+					})
+				}
+				return
+			}
+		}
+
+		item := heap.Pop(&pq.queue).(*scheduledRequest)
+		if err := item.ctx.Err(); err != nil {
+			item.future.complete(nil, err)
+			continue
+		}
+		pq.running++
+		pq.lastDispatch = time.Now()
+		s.running.Add(1)
+		go s.run(provider, pq, item)
+	}
+}
+
+// run sends a request and dispatches the next ones of its provider when it finishes
+func (s *Scheduler) run(provider string, pq *providerQueue, item *scheduledRequest) {
+	defer s.running.Done()
+	resp, err := item.client.ChatCompletion(item.ctx, item.req)
+	item.future.complete(resp, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pq.running--
+	s.dispatch(provider, pq)
+}
+
+// schedulerProvider returns the queue name of a client: its provider, when known
+func schedulerProvider(client ChatCompleter) string {
+	if c, ok := client.(interface{ GetModelInfo() ModelInfo }); ok {
+		if provider := c.GetModelInfo().Provider; provider != "" {
+			return provider
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedCompleter blocks every request until released, recording the order of the
+// requests and the highest number running at once
+type gatedCompleter struct {
+	provider string
+	release  chan struct{}
+
+	mu      sync.Mutex
+	order   []string
+	running int
+	peak    int
+}
+
+func newGatedCompleter(provider string) *gatedCompleter {
+	return &gatedCompleter{provider: provider, release: make(chan struct{})}
+}
+
+func (c *gatedCompleter) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	c.mu.Lock()
+	c.order = append(c.order, req.Messages[0].GetText())
+	c.running++
+	c.peak = max(c.peak, c.running)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return textResponse("answer to " + req.Messages[0].GetText()), nil
+}
+
+func (c *gatedCompleter) GetModelInfo() ModelInfo {
+	return ModelInfo{Provider: c.provider}
+}
+
+func (c *gatedCompleter) started() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.order...)
+}
+
+func promptRequest(text string) ChatRequest {
+	return ChatRequest{Messages: []Message{NewTextMessage(RoleUser, text)}}
+}
+
+func TestSchedulerPrioritiesAndConcurrency(t *testing.T) {
+	client := newGatedCompleter("openai")
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrency: 1})
+	defer func() { _ = scheduler.Close() }()
+	ctx := context.Background()
+
+	first, err := scheduler.Submit(ctx, client, promptRequest("first"), 0)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(client.started()) == 1 }, time.Second, time.Millisecond)
+
+	var futures []*ChatFuture
+	for _, item := range []struct {
+		text     string
+		priority int
+	}{{"low", 0}, {"high", 10}, {"low-2", 0}} {
+		future, err := scheduler.Submit(ctx, client, promptRequest(item.text), item.priority)
+		require.NoError(t, err)
+		futures = append(futures, future)
+	}
+	assert.Equal(t, SchedulerStats{Queued: 3, Running: 1}, scheduler.Stats()["openai"])
+
+	close(client.release)
+	resp, err := first.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "answer to first", resp.Choices[0].Message.GetText())
+	for _, future := range futures {
+		_, err := future.Wait(ctx)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"first", "high", "low", "low-2"}, client.started())
+	assert.Equal(t, 1, client.peak)
+}
+
+func TestSchedulerProviderLimits(t *testing.T) {
+	openai := newGatedCompleter("openai")
+	gemini := newGatedCompleter("gemini")
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrency: 1, ProviderConcurrency: map[string]int{"gemini": 3}})
+	defer func() { _ = scheduler.Close() }()
+
+	for i := 0; i < 3; i++ {
+		_, err := scheduler.Submit(context.Background(), openai, promptRequest("o"), 0)
+		require.NoError(t, err)
+		_, err = scheduler.Submit(context.Background(), gemini, promptRequest("g"), 0)
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool { return len(gemini.started()) == 3 }, time.Second, time.Millisecond)
+	assert.Len(t, openai.started(), 1, "providers have separate limits")
+	close(openai.release)
+	close(gemini.release)
+}
+
+func TestSchedulerQueueFullAndCancel(t *testing.T) {
+	client := newGatedCompleter("openai")
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrency: 1, MaxQueued: 1})
+
+	_, err := scheduler.Submit(context.Background(), client, promptRequest("running"), 0)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(client.started()) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled, err := scheduler.Submit(ctx, client, promptRequest("cancelled"), 0)
+	require.NoError(t, err)
+
+	_, err = scheduler.Submit(context.Background(), client, promptRequest("rejected"), 0)
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "queue_full", llmErr.Code)
+
+	// A request cancelled while queued is never sent
+	cancel()
+	close(client.release)
+	_, err = cancelled.Wait(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, scheduler.Close())
+	assert.Equal(t, []string{"running"}, client.started())
+
+	_, err = scheduler.Submit(context.Background(), client, promptRequest("closed"), 0)
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "scheduler_closed", llmErr.Code)
+}
+
+func TestSchedulerMinInterval(t *testing.T) {
+	client := newGatedCompleter("openai")
+	close(client.release)
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrency: 10, MinInterval: 20 * time.Millisecond})
+	defer func() { _ = scheduler.Close() }()
+
+	start := time.Now()
+	var futures []*ChatFuture
+	for i := 0; i < 3; i++ {
+		future, err := scheduler.Submit(context.Background(), client, promptRequest("burst"), 0)
+		require.NoError(t, err)
+		futures = append(futures, future)
+	}
+	for _, future := range futures {
+		_, err := future.Wait(context.Background())
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "dispatches should be spaced")
+}
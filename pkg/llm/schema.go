@@ -50,9 +50,9 @@ func SchemaFromStructAsMap(structType interface{}) (map[string]interface{}, erro
 	return schemaMap, nil
 }
 
-// ValidateAgainstSchema validates JSON data against a provided JSON Schema
-// This uses basic validation - the swaggest library provides schema generation but not validation
-// For full validation, consider using github.com/santhosh-tekuri/jsonschema along with this
+// ValidateAgainstSchema validates JSON data against a provided JSON Schema.
+// See ValidateValueAgainstSchema for the supported keywords; schema violations
+// are reported as a *SchemaValidationError.
 func ValidateAgainstSchema(data []byte, schema interface{}) error {
 	// Parse the data to ensure it's valid JSON
 	var parsed interface{}
@@ -60,9 +60,7 @@ func ValidateAgainstSchema(data []byte, schema interface{}) error {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	// TODO: Implement proper JSON Schema validation
-	// For now, this just validates the JSON is well-formed
-	return nil
+	return ValidateValueAgainstSchema(parsed, schema)
 }
 
 // NewJSONSchemaResponseFormat creates a ResponseFormat with JSON Schema
@@ -102,14 +100,25 @@ func NewJSONSchemaResponseFormatStrict(name, description string, schema interfac
 	}
 }
 
-// NewJSONSchemaResponseFormatStrictFromStruct creates a strict ResponseFormat with JSON Schema from a Go struct
+// NewJSONSchemaResponseFormatStrictFromStruct creates a strict ResponseFormat with JSON Schema from a Go struct.
+// The generated schema is converted with StrictSchema (so optional fields become nullable and recursive
+// types are kept as references) and checked with LintSchema, failing for constructs that strict
+// structured outputs cannot express, such as interface{} fields or maps.
 func NewJSONSchemaResponseFormatStrictFromStruct(name, description string, structType interface{}) (*ResponseFormat, error) {
 	schema, err := SchemaFromStructAsMap(structType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate schema from struct: %w", err)
 	}
 
-	return NewJSONSchemaResponseFormatStrict(name, description, schema), nil
+	strictSchema, err := StrictSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate strict schema from struct: %w", err)
+	}
+	if err := LintSchema(strictSchema, true); err != nil {
+		return nil, fmt.Errorf("schema for %T cannot be used in strict mode: %w", structType, err)
+	}
+
+	return NewJSONSchemaResponseFormatStrict(name, description, strictSchema), nil
 }
 
 // NewJSONResponseFormat creates a ResponseFormat for basic JSON object output (no schema)
@@ -118,3 +127,143 @@ func NewJSONResponseFormat() *ResponseFormat {
 		Type: ResponseFormatJSON,
 	}
 }
+
+// ResponseFormatInstruction returns the prompt instruction used to request the given format from
+// models without native structured output support. It returns an empty string for plain text.
+func ResponseFormatInstruction(format *ResponseFormat) string {
+	const jsonOnly = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
+
+	if format == nil {
+		return ""
+	}
+
+	switch format.Type {
+	case ResponseFormatJSON:
+		return jsonOnly
+	case ResponseFormatJSONSchema:
+		if format.JSONSchema != nil && format.JSONSchema.Schema != nil {
+			if schemaBytes, err := json.Marshal(format.JSONSchema.Schema); err == nil {
+				return fmt.Sprintf("Please respond only with valid JSON that conforms to this schema: %s. Do not include any text before or after the JSON object.", string(schemaBytes))
+			}
+		}
+		return jsonOnly
+	case ResponseFormatGrammar:
+		if format.Grammar != "" {
+			return fmt.Sprintf("Please respond only with output that matches this GBNF grammar: %s. Do not include any other text.", format.Grammar)
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
+// IsJSON reports whether the format requests a JSON response (json_object or json_schema)
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSON || f.Type == ResponseFormatJSONSchema)
+}
+
+// CompatibleResponseFormat is a ResponseFormat in the shape of the response_format of
+// OpenAI-compatible APIs, which provider clients map onto the types of their SDK
+type CompatibleResponseFormat struct {
+	Type       string                `json:"type"` // "json_object", "json_schema" or "grammar"
+	JSONSchema *CompatibleJSONSchema `json:"json_schema,omitempty"`
+	Grammar    string                `json:"grammar,omitempty"`
+}
+
+// CompatibleJSONSchema is the json_schema of a CompatibleResponseFormat
+type CompatibleJSONSchema struct {
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      json.Marshaler `json:"schema"`
+	Strict      bool           `json:"strict,omitempty"`
+}
+
+// SchemaMarshaler adapts an arbitrary schema value to the json.Marshaler expected by the
+// schema fields of SDKs
+type SchemaMarshaler struct {
+	Schema interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (s SchemaMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Schema)
+}
+
+// Compatible converts the format to the OpenAI-compatible response_format. JSON schema formats
+// without a schema become JSON object formats, and plain text returns nil.
+func (f *ResponseFormat) Compatible() *CompatibleResponseFormat {
+	if f == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case ResponseFormatJSON:
+		return &CompatibleResponseFormat{Type: string(ResponseFormatJSON)}
+	case ResponseFormatJSONSchema:
+		if f.JSONSchema == nil || f.JSONSchema.Schema == nil {
+			return &CompatibleResponseFormat{Type: string(ResponseFormatJSON)}
+		}
+		compatible := &CompatibleResponseFormat{
+			Type: string(ResponseFormatJSONSchema),
+			JSONSchema: &CompatibleJSONSchema{
+				Name:        f.JSONSchema.Name,
+				Description: f.JSONSchema.Description,
+				Schema:      SchemaMarshaler{f.JSONSchema.Schema},
+			},
+		}
+		if f.JSONSchema.Strict != nil {
+			compatible.JSONSchema.Strict = *f.JSONSchema.Strict
+		}
+		return compatible
+	case ResponseFormatGrammar:
+		return &CompatibleResponseFormat{Type: string(ResponseFormatGrammar), Grammar: f.Grammar}
+	default:
+		return nil
+	}
+}
+
+// ResolveResponseFormat decides, according to the request's ResponseFormatPolicy, whether a provider
+// should enforce ResponseFormat natively (true) or through prompt instructions (false).
+// nativeSupported reports whether the provider can enforce the requested format natively.
+// It fails with a "response_format_not_supported" error when native enforcement is required but unavailable.
+func (r ChatRequest) ResolveResponseFormat(nativeSupported bool) (bool, error) {
+	if ResponseFormatInstruction(r.ResponseFormat) == "" {
+		return false, nil
+	}
+
+	switch r.ResponseFormatPolicy {
+	case ResponseFormatPolicyPromptOnly:
+		return false, nil
+	case ResponseFormatPolicyRequireNative:
+		if !nativeSupported {
+			return false, &Error{
+				Code:       "response_format_not_supported",
+				Message:    fmt.Sprintf("native %s response format is not supported by this provider or model", r.ResponseFormat.Type),
+				Type:       "validation_error",
+				StatusCode: 400,
+			}
+		}
+	}
+
+	return nativeSupported, nil
+}
+
+// PrepareResponseFormat applies the ResponseFormatPolicy for a provider. When the format is to be
+// enforced natively the request is returned unchanged; otherwise a copy is returned with the format
+// requested through a leading system message and ResponseFormat cleared.
+func (r ChatRequest) PrepareResponseFormat(nativeSupported bool) (ChatRequest, error) {
+	native, err := r.ResolveResponseFormat(nativeSupported)
+	if err != nil || native {
+		return r, err
+	}
+
+	instruction := ResponseFormatInstruction(r.ResponseFormat)
+	if instruction == "" {
+		return r, nil
+	}
+
+	prepared := r
+	prepared.ResponseFormat = nil
+	prepared.Messages = append([]Message{NewTextMessage(RoleSystem, instruction)}, r.Messages...)
+	return prepared, nil
+}
@@ -0,0 +1,343 @@
+// Compatibility between versions of JSON schemas
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaCompatibility is the compatibility required between consecutive versions of a
+// schema
+type SchemaCompatibility string
+
+const (
+	// SchemaCompatibilityNone accepts any change
+	SchemaCompatibilityNone SchemaCompatibility = "none"
+
+	// SchemaCompatibilityBackward requires the new version to accept every value of the
+	// previous one, so code updated to the new version still reads the stored outputs of
+	// the previous one
+	SchemaCompatibilityBackward SchemaCompatibility = "backward"
+
+	// SchemaCompatibilityForward requires the previous version to accept every value of
+	// the new one, so code written for the previous version reads the outputs of the new one
+	SchemaCompatibilityForward SchemaCompatibility = "forward"
+
+	// SchemaCompatibilityFull requires both backward and forward compatibility
+	SchemaCompatibilityFull SchemaCompatibility = "full"
+)
+
+// SchemaCompatibilityError lists the changes of a schema breaking the required
+// compatibility
+type SchemaCompatibilityError struct {
+	Compatibility SchemaCompatibility `json:"compatibility"`
+	Issues        []SchemaViolation   `json:"issues"`
+}
+
+func (e *SchemaCompatibilityError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = fmt.Sprintf("%s: %s", issue.Path, issue.Message)
+	}
+	return fmt.Sprintf("schema is not %s compatible: %s", e.Compatibility, strings.Join(parts, "; "))
+}
+
+// CheckSchemaCompatibility checks that a new version of a schema has the compatibility
+// with the previous one, returning a *SchemaCompatibilityError listing the breaking
+// changes. A schema accepts every value of another one when it allows all their types,
+// enum values, properties and ranges: the check is structural and conservative, so some
+// equivalent schemas written differently are reported as incompatible. Properties not
+// declared by a schema are assumed absent from its values.
+func CheckSchemaCompatibility(previous, next interface{}, compatibility SchemaCompatibility) error {
+	previousRoot, err := normalizeSchema(previous)
+	if err != nil {
+		return err
+	}
+	nextRoot, err := normalizeSchema(next)
+	if err != nil {
+		return err
+	}
+
+	var issues []SchemaViolation
+	switch compatibility {
+	case SchemaCompatibilityNone, "":
+	case SchemaCompatibilityBackward:
+		issues = schemaSubsetIssues(previousRoot, nextRoot, "previous", "new")
+	case SchemaCompatibilityForward:
+		issues = schemaSubsetIssues(nextRoot, previousRoot, "new", "previous")
+	case SchemaCompatibilityFull:
+		issues = append(schemaSubsetIssues(previousRoot, nextRoot, "previous", "new"),
+			schemaSubsetIssues(nextRoot, previousRoot, "new", "previous")...)
+	default:
+		return &Error{Code: "invalid_request", Message: fmt.Sprintf("unknown schema compatibility %q", compatibility), Type: "validation_error"}
+	}
+
+	if len(issues) > 0 {
+		return &SchemaCompatibilityError{Compatibility: compatibility, Issues: issues}
+	}
+	return nil
+}
+
+// schemaSubsetIssues returns the reasons why the schema "to" rejects values of the
+// schema "from", named with the given labels in the messages
+func schemaSubsetIssues(from, to map[string]interface{}, fromLabel, toLabel string) []SchemaViolation {
+	c := &schemaSubsetChecker{
+		from:      schemaValidator{root: from},
+		to:        schemaValidator{root: to},
+		fromLabel: fromLabel,
+		toLabel:   toLabel,
+	}
+	c.check(from, to, "$", 0)
+	return c.issues
+}
+
+// schemaSubsetChecker checks that every value of a schema is a value of another one
+type schemaSubsetChecker struct {
+	from, to           schemaValidator
+	fromLabel, toLabel string
+	issues             []SchemaViolation
+}
+
+func (c *schemaSubsetChecker) report(path, format string, args ...interface{}) {
+	c.issues = append(c.issues, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// rejects reports values of the "from" schema rejected by the "to" schema
+func (c *schemaSubsetChecker) rejects(path, what string) {
+	c.report(path, "%s allowed by the %s version is rejected by the %s version", what, c.fromLabel, c.toLabel)
+}
+
+// resolveSchemaRefs follows the references of a schema
+func resolveSchemaRefs(v *schemaValidator, schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < maxSchemaDepth; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		resolved, err := v.resolveRef(ref)
+		if err != nil {
+			return schema
+		}
+		schema = resolved
+	}
+	return schema
+}
+
+// accepts returns whether "to" accepts every value of "from", without reporting
+func (c *schemaSubsetChecker) accepts(from, to map[string]interface{}, depth int) bool {
+	probe := &schemaSubsetChecker{from: c.from, to: c.to, fromLabel: c.fromLabel, toLabel: c.toLabel}
+	probe.check(from, to, "$", depth)
+	return len(probe.issues) == 0
+}
+
+func (c *schemaSubsetChecker) check(from, to map[string]interface{}, path string, depth int) {
+	if depth > maxSchemaDepth {
+		return
+	}
+	from = resolveSchemaRefs(&c.from, from)
+	to = resolveSchemaRefs(&c.to, to)
+	if len(to) == 0 || from == nil {
+		return
+	}
+
+	// Every alternative of "from" must be accepted, and "from" must be accepted by one
+	// of the alternatives of "to"
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		if branches, ok := from[keyword].([]interface{}); ok {
+			for i, branch := range branches {
+				if sub, ok := branch.(map[string]interface{}); ok {
+					c.check(sub, to, fmt.Sprintf("%s.%s[%d]", path, keyword, i), depth+1)
+				}
+			}
+			return
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		if branches, ok := to[keyword].([]interface{}); ok {
+			for _, branch := range branches {
+				if sub, ok := branch.(map[string]interface{}); ok && c.accepts(from, sub, depth+1) {
+					return
+				}
+			}
+			c.report(path, "values allowed by the %s version are rejected by every alternative of the %s version", c.fromLabel, c.toLabel)
+			return
+		}
+	}
+	if all, ok := to["allOf"].([]interface{}); ok {
+		for _, branch := range all {
+			if sub, ok := branch.(map[string]interface{}); ok {
+				c.check(from, sub, path, depth+1)
+			}
+		}
+	}
+
+	fromTypes, toTypes := schemaTypes(from["type"]), schemaTypes(to["type"])
+	if len(toTypes) > 0 {
+		if len(fromTypes) == 0 && schemaEnumValues(from) == nil {
+			c.rejects(path, "any type")
+		}
+		for _, t := range fromTypes {
+			if !schemaTypeAllowed(t, toTypes) {
+				c.rejects(path, fmt.Sprintf("type %q", t))
+			}
+		}
+	}
+
+	if toValues := schemaEnumValues(to); toValues != nil {
+		fromValues := schemaEnumValues(from)
+		if fromValues == nil {
+			c.rejects(path, "any value")
+		}
+		for _, value := range fromValues {
+			if !containsValue(toValues, value) {
+				c.rejects(path, "value "+compactJSON(value))
+			}
+		}
+	}
+
+	// The keywords of a type only apply to the values of "from" of that type
+	allows := func(t string) bool { return len(fromTypes) == 0 || schemaTypeAllowed(t, fromTypes) }
+
+	if allows("object") {
+		c.checkObject(from, to, path, depth)
+	}
+
+	if allows("array") {
+		if fromItems, toItems := schemaMap(from["items"]), schemaMap(to["items"]); toItems != nil {
+			if fromItems == nil {
+				fromItems = map[string]interface{}{}
+			}
+			c.check(fromItems, toItems, path+"[]", depth+1)
+		}
+		c.checkLowerBound(from, to, path, "minItems")
+		c.checkUpperBound(from, to, path, "maxItems")
+	}
+
+	if allows("string") {
+		c.checkLowerBound(from, to, path, "minLength")
+		c.checkUpperBound(from, to, path, "maxLength")
+		if pattern, ok := to["pattern"].(string); ok && from["pattern"] != pattern {
+			c.report(path, "pattern %q of the %s version may reject values allowed by the %s version", pattern, c.toLabel, c.fromLabel)
+		}
+	}
+
+	if allows("number") || allows("integer") {
+		c.checkLowerBound(from, to, path, "minimum")
+		c.checkLowerBound(from, to, path, "exclusiveMinimum")
+		c.checkUpperBound(from, to, path, "maximum")
+		c.checkUpperBound(from, to, path, "exclusiveMaximum")
+	}
+}
+
+// checkObject checks the properties of object schemas
+func (c *schemaSubsetChecker) checkObject(from, to map[string]interface{}, path string, depth int) {
+	fromProperties, _ := from["properties"].(map[string]interface{})
+	toProperties, _ := to["properties"].(map[string]interface{})
+
+	fromRequired := make(map[string]bool)
+	for _, name := range schemaRequired(from) {
+		fromRequired[name] = true
+	}
+	for _, name := range schemaRequired(to) {
+		if !fromRequired[name] {
+			c.report(path, "property %q is required by the %s version but optional or undefined in the %s version", name, c.toLabel, c.fromLabel)
+		}
+	}
+
+	toAdditional := to["additionalProperties"]
+	for _, name := range sortedKeys(fromProperties) {
+		fromProperty := schemaMap(fromProperties[name])
+		if fromProperty == nil {
+			continue
+		}
+		if toProperty, ok := toProperties[name]; ok {
+			if sub := schemaMap(toProperty); sub != nil {
+				c.check(fromProperty, sub, path+"."+name, depth+1)
+			}
+			continue
+		}
+		switch additional := toAdditional.(type) {
+		case bool:
+			if !additional {
+				c.rejects(path, fmt.Sprintf("property %q", name))
+			}
+		case map[string]interface{}:
+			c.check(fromProperty, additional, path+"."+name, depth+1)
+		}
+	}
+
+	// Additional properties of "from" must be accepted by "to"
+	switch additional := from["additionalProperties"].(type) {
+	case bool:
+		if additional && toAdditional == false {
+			c.rejects(path, "additional properties")
+		}
+	case map[string]interface{}:
+		if toAdditional == false {
+			c.rejects(path, "additional properties")
+		} else if sub := schemaMap(toAdditional); sub != nil {
+			c.check(additional, sub, path+".*", depth+1)
+		}
+	}
+}
+
+// checkLowerBound reports a minimum of "to" above the one of "from"
+func (c *schemaSubsetChecker) checkLowerBound(from, to map[string]interface{}, path, keyword string) {
+	toBound, ok := schemaNumber(to[keyword])
+	if !ok {
+		return
+	}
+	if fromBound, ok := schemaNumber(from[keyword]); !ok || fromBound < toBound {
+		c.report(path, "%s %v of the %s version rejects values allowed by the %s version", keyword, toBound, c.toLabel, c.fromLabel)
+	}
+}
+
+// checkUpperBound reports a maximum of "to" below the one of "from"
+func (c *schemaSubsetChecker) checkUpperBound(from, to map[string]interface{}, path, keyword string) {
+	toBound, ok := schemaNumber(to[keyword])
+	if !ok {
+		return
+	}
+	if fromBound, ok := schemaNumber(from[keyword]); !ok || fromBound > toBound {
+		c.report(path, "%s %v of the %s version rejects values allowed by the %s version", keyword, toBound, c.toLabel, c.fromLabel)
+	}
+}
+
+// schemaTypeAllowed returns whether values of a type are allowed by a list of types
+func schemaTypeAllowed(t string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if candidate == t || (t == "integer" && candidate == "number") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaEnumValues returns the values allowed by the enum or const keywords, or nil
+func schemaEnumValues(schema map[string]interface{}) []interface{} {
+	if value, ok := schema["const"]; ok {
+		return []interface{}{value}
+	}
+	if values, ok := schema["enum"].([]interface{}); ok {
+		return values
+	}
+	return nil
+}
+
+// schemaRequired returns the required properties of a schema
+func schemaRequired(schema map[string]interface{}) []string {
+	list, _ := schema["required"].([]interface{})
+	names := make([]string, 0, len(list))
+	for _, name := range list {
+		if s, ok := name.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// schemaMap returns a subschema as an object, or nil for boolean and missing schemas
+func schemaMap(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
@@ -0,0 +1,295 @@
+// Registry of versioned response schemas
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is a version of a schema registered in a SchemaRegistry. It must not be
+// modified.
+type SchemaVersion struct {
+	Name        string                 `json:"name"`
+	Version     int                    `json:"version"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Strict      bool                   `json:"strict,omitempty"`
+
+	// Fingerprint is the SHA-256 of the schema, to identify it in logs and metrics
+	Fingerprint string `json:"fingerprint"`
+
+	Created time.Time `json:"created"`
+}
+
+// ResponseFormat returns the response format asking for the schema, named after it
+func (v *SchemaVersion) ResponseFormat() *ResponseFormat {
+	if v.Strict {
+		return NewJSONSchemaResponseFormatStrict(v.Name, v.Description, v.Schema)
+	}
+	return NewJSONSchemaResponseFormat(v.Name, v.Description, v.Schema)
+}
+
+// Validate validates JSON data, such as the text of a response, against the schema
+func (v *SchemaVersion) Validate(data []byte) error {
+	return ValidateAgainstSchema(data, v.Schema)
+}
+
+// SchemaRegistryConfig configures a SchemaRegistry
+type SchemaRegistryConfig struct {
+	// Compatibility is required between a new version of a schema and the previous one
+	// (SchemaCompatibilityBackward by default)
+	Compatibility SchemaCompatibility `json:"compatibility"`
+}
+
+// DefaultSchemaRegistryConfig returns the default configuration of a SchemaRegistry
+func DefaultSchemaRegistryConfig() SchemaRegistryConfig {
+	return SchemaRegistryConfig{Compatibility: SchemaCompatibilityBackward}
+}
+
+// SchemaRegistry holds named JSON schemas for structured outputs, registered once (from
+// structs or raw schemas) and retrieved by name for the requests, instead of generating
+// them on every request. Every change of a schema is a new version, which must have the
+// configured compatibility with the previous one, and the registry can be saved and
+// loaded to share the schemas between processes and review their history:
+//
+//	registry := llm.NewSchemaRegistry(nil)
+//	_, err := registry.RegisterStruct("invoice", "An invoice", Invoice{})
+//	req.ResponseFormat, err = registry.ResponseFormat("invoice")
+//
+// It is safe for concurrent use.
+type SchemaRegistry struct {
+	config SchemaRegistryConfig
+
+	mu       sync.RWMutex
+	versions map[string][]*SchemaVersion // by name, oldest first
+}
+
+// NewSchemaRegistry creates an empty registry. A nil config uses
+// DefaultSchemaRegistryConfig.
+func NewSchemaRegistry(config *SchemaRegistryConfig) *SchemaRegistry {
+	registry := &SchemaRegistry{config: DefaultSchemaRegistryConfig(), versions: make(map[string][]*SchemaVersion)}
+	if config != nil && config.Compatibility != "" {
+		registry.config.Compatibility = config.Compatibility
+	}
+	return registry
+}
+
+// Register adds a version of a schema, returning it. Registering the same schema and
+// description as the latest version returns the latest version, so it can be called at
+// every startup. It fails with an "invalid_schema" error for names providers reject, with
+// a *SchemaLintError for schemas that do not pass LintSchema, and with a
+// *SchemaCompatibilityError for changes breaking the compatibility with the previous
+// version.
+func (r *SchemaRegistry) Register(name, description string, schema interface{}) (*SchemaVersion, error) {
+	return r.register(name, description, schema, false)
+}
+
+// RegisterStrict is like Register for schemas used with strict structured outputs,
+// which are checked with the strict rules of LintSchema
+func (r *SchemaRegistry) RegisterStrict(name, description string, schema interface{}) (*SchemaVersion, error) {
+	return r.register(name, description, schema, true)
+}
+
+// RegisterStruct is like Register with the schema generated from a Go struct
+func (r *SchemaRegistry) RegisterStruct(name, description string, structType interface{}) (*SchemaVersion, error) {
+	schema, err := SchemaFromStructAsMap(structType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema from struct: %w", err)
+	}
+	return r.register(name, description, schema, false)
+}
+
+// RegisterStrictStruct is like RegisterStrict with the schema generated from a Go struct
+// and converted with StrictSchema
+func (r *SchemaRegistry) RegisterStrictStruct(name, description string, structType interface{}) (*SchemaVersion, error) {
+	schema, err := SchemaFromStructAsMap(structType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema from struct: %w", err)
+	}
+	strictSchema, err := StrictSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate strict schema from struct: %w", err)
+	}
+	return r.register(name, description, strictSchema, true)
+}
+
+// register validates a schema and adds it as the latest version of the name
+func (r *SchemaRegistry) register(name, description string, schema interface{}, strict bool) (*SchemaVersion, error) {
+	if !toolNamePartPattern.MatchString(name) || len(name) > maxToolNameLength {
+		return nil, schemaRegistryError("invalid_schema", fmt.Sprintf("invalid schema name %q: use up to %d letters, digits, '_' or '-'", name, maxToolNameLength))
+	}
+	normalized, err := normalizeSchema(schema)
+	if err != nil {
+		return nil, schemaRegistryError("invalid_schema", fmt.Sprintf("invalid schema %q: %v", name, err))
+	}
+	if err := LintSchema(normalized, strict); err != nil {
+		return nil, err
+	}
+	fingerprint, err := schemaFingerprint(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.versions[name]
+	number := 1
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if latest.Fingerprint == fingerprint && latest.Description == description && latest.Strict == strict {
+			return latest, nil
+		}
+		if err := CheckSchemaCompatibility(latest.Schema, normalized, r.config.Compatibility); err != nil {
+			return nil, err
+		}
+		number = latest.Version + 1
+	}
+
+	version := &SchemaVersion{
+		Name:        name,
+		Version:     number,
+		Description: description,
+		Schema:      normalized,
+		Strict:      strict,
+		Fingerprint: fingerprint,
+		Created:     time.Now(),
+	}
+	r.versions[name] = append(versions, version)
+	return version, nil
+}
+
+// CheckCompatibility checks a schema against the latest version of a name with the
+// compatibility of the registry, without registering it, e.g. in the CI of the schemas
+func (r *SchemaRegistry) CheckCompatibility(name string, schema interface{}) error {
+	latest, err := r.Get(name)
+	if err != nil {
+		return err
+	}
+	return CheckSchemaCompatibility(latest.Schema, schema, r.config.Compatibility)
+}
+
+// Get returns the latest version of a schema, or a "schema_not_found" error
+func (r *SchemaRegistry) Get(name string) (*SchemaVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.versions[name]
+	if len(versions) == 0 {
+		return nil, schemaRegistryError("schema_not_found", fmt.Sprintf("schema %q is not registered", name))
+	}
+	return versions[len(versions)-1], nil
+}
+
+// GetVersion returns a version of a schema, or a "schema_not_found" error
+func (r *SchemaRegistry) GetVersion(name string, version int) (*SchemaVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.versions[name]
+	if version < 1 || version > len(versions) {
+		return nil, schemaRegistryError("schema_not_found", fmt.Sprintf("version %d of schema %q is not registered", version, name))
+	}
+	return versions[version-1], nil
+}
+
+// ResponseFormat returns the response format of the latest version of a schema
+func (r *SchemaRegistry) ResponseFormat(name string) (*ResponseFormat, error) {
+	version, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return version.ResponseFormat(), nil
+}
+
+// Versions returns the versions of a schema, oldest first
+func (r *SchemaRegistry) Versions(name string) []*SchemaVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*SchemaVersion(nil), r.versions[name]...)
+}
+
+// Names returns the names of the registered schemas, sorted
+func (r *SchemaRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.versions))
+	for name := range r.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// savedSchemaRegistry is the JSON form of a registry
+type savedSchemaRegistry struct {
+	Schemas []*SchemaVersion `json:"schemas"`
+}
+
+// Save writes every version of the registered schemas as JSON, sorted by name and
+// version, so the file can be versioned and reviewed
+func (r *SchemaRegistry) Save(w io.Writer) error {
+	var saved savedSchemaRegistry
+	for _, name := range r.Names() {
+		saved.Schemas = append(saved.Schemas, r.Versions(name)...)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(saved); err != nil {
+		return fmt.Errorf("failed to save schemas: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the registered schemas with those written by Save. The versions of
+// every schema must be numbered from 1 without gaps, and their fingerprints must match.
+func (r *SchemaRegistry) Load(reader io.Reader) error {
+	var saved savedSchemaRegistry
+	if err := json.NewDecoder(reader).Decode(&saved); err != nil {
+		return fmt.Errorf("failed to load schemas: %w", err)
+	}
+
+	versions := make(map[string][]*SchemaVersion)
+	for _, version := range saved.Schemas {
+		if version == nil {
+			continue
+		}
+		if expected := len(versions[version.Name]) + 1; version.Version != expected {
+			return schemaRegistryError("invalid_schema", fmt.Sprintf("schema %q: expected version %d, got %d", version.Name, expected, version.Version))
+		}
+		fingerprint, err := schemaFingerprint(version.Schema)
+		if err != nil {
+			return err
+		}
+		if fingerprint != version.Fingerprint {
+			return schemaRegistryError("invalid_schema", fmt.Sprintf("version %d of schema %q does not match its fingerprint", version.Version, version.Name))
+		}
+		versions[version.Name] = append(versions[version.Name], version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions = versions
+	return nil
+}
+
+// schemaFingerprint returns the SHA-256 of the canonical JSON of a schema (objects are
+// encoded with sorted keys)
+func schemaFingerprint(schema map[string]interface{}) (string, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode schema: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// schemaRegistryError creates a schema registry error
+func schemaRegistryError(code, message string) *Error {
+	return &Error{Code: code, Message: message, Type: "validation_error"}
+}
@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type registryInvoice struct {
+	Number string  `json:"number"`
+	Total  float64 `json:"total"`
+}
+
+func objectSchema(required []interface{}, properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	previous := objectSchema([]interface{}{"name"}, map[string]interface{}{
+		"name":   map[string]interface{}{"type": "string"},
+		"age":    map[string]interface{}{"type": "integer", "minimum": 0},
+		"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "inactive"}},
+	})
+
+	tests := []struct {
+		name       string
+		next       map[string]interface{}
+		backward   bool
+		forward    bool
+		issuePaths []string
+	}{
+		{
+			name:     "identical",
+			next:     previous,
+			backward: true,
+			forward:  true,
+		},
+		{
+			name: "new optional property",
+			next: objectSchema([]interface{}{"name"}, map[string]interface{}{
+				"name":   map[string]interface{}{"type": "string"},
+				"age":    map[string]interface{}{"type": "integer", "minimum": 0},
+				"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "inactive"}},
+				"email":  map[string]interface{}{"type": "string"},
+			}),
+			backward: true,
+			forward:  true,
+		},
+		{
+			name: "new required property",
+			next: objectSchema([]interface{}{"name", "email"}, map[string]interface{}{
+				"name":   map[string]interface{}{"type": "string"},
+				"age":    map[string]interface{}{"type": "integer", "minimum": 0},
+				"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "inactive"}},
+				"email":  map[string]interface{}{"type": "string"},
+			}),
+			backward:   false,
+			forward:    true,
+			issuePaths: []string{"$"},
+		},
+		{
+			name: "widened type and enum",
+			next: objectSchema([]interface{}{"name"}, map[string]interface{}{
+				"name":   map[string]interface{}{"type": "string"},
+				"age":    map[string]interface{}{"type": "number"},
+				"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "inactive", "banned"}},
+			}),
+			backward: true,
+			forward:  false,
+		},
+		{
+			name: "narrowed enum and range",
+			next: objectSchema([]interface{}{"name"}, map[string]interface{}{
+				"name":   map[string]interface{}{"type": "string"},
+				"age":    map[string]interface{}{"type": "integer", "minimum": 18},
+				"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active"}},
+			}),
+			backward:   false,
+			forward:    true,
+			issuePaths: []string{"$.age", "$.status"},
+		},
+		{
+			name: "nullable through anyOf",
+			next: objectSchema([]interface{}{"name"}, map[string]interface{}{
+				"name": map[string]interface{}{"anyOf": []interface{}{
+					map[string]interface{}{"type": "string"},
+					map[string]interface{}{"type": "null"},
+				}},
+				"age":    map[string]interface{}{"type": "integer", "minimum": 0},
+				"status": map[string]interface{}{"type": "string", "enum": []interface{}{"active", "inactive"}},
+			}),
+			backward: true,
+			forward:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSchemaCompatibility(previous, tt.next, SchemaCompatibilityBackward)
+			assert.Equal(t, tt.backward, err == nil, "backward: %v", err)
+			err = CheckSchemaCompatibility(previous, tt.next, SchemaCompatibilityForward)
+			assert.Equal(t, tt.forward, err == nil, "forward: %v", err)
+			assert.Equal(t, tt.backward && tt.forward, CheckSchemaCompatibility(previous, tt.next, SchemaCompatibilityFull) == nil)
+			assert.NoError(t, CheckSchemaCompatibility(previous, tt.next, SchemaCompatibilityNone))
+
+			if tt.issuePaths != nil {
+				err := CheckSchemaCompatibility(previous, tt.next, SchemaCompatibilityBackward)
+				var compatErr *SchemaCompatibilityError
+				require.ErrorAs(t, err, &compatErr)
+				var paths []string
+				for _, issue := range compatErr.Issues {
+					paths = append(paths, issue.Path)
+				}
+				assert.Equal(t, tt.issuePaths, paths)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaCompatibility_Refs(t *testing.T) {
+	previous, err := SchemaFromStructAsMap(registryInvoice{})
+	require.NoError(t, err)
+	next := map[string]interface{}{
+		"$defs": map[string]interface{}{
+			"invoice": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"number": map[string]interface{}{"type": "string"},
+					"total":  map[string]interface{}{"type": "number"},
+				},
+			},
+		},
+		"$ref": "#/$defs/invoice",
+	}
+	assert.NoError(t, CheckSchemaCompatibility(previous, next, SchemaCompatibilityBackward))
+
+	err = CheckSchemaCompatibility(previous, next, "sideways")
+	assert.Error(t, err)
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	registry := NewSchemaRegistry(nil)
+
+	v1, err := registry.RegisterStruct("invoice", "An invoice", registryInvoice{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+	assert.Len(t, v1.Fingerprint, 64)
+
+	// Registering the same schema again is a no-op
+	again, err := registry.RegisterStruct("invoice", "An invoice", registryInvoice{})
+	require.NoError(t, err)
+	assert.Same(t, v1, again)
+
+	format, err := registry.ResponseFormat("invoice")
+	require.NoError(t, err)
+	assert.Equal(t, ResponseFormatJSONSchema, format.Type)
+	assert.Equal(t, "invoice", format.JSONSchema.Name)
+	assert.Nil(t, format.JSONSchema.Strict)
+
+	// Compatible changes are new versions, breaking ones are rejected
+	widened := objectSchema(nil, map[string]interface{}{
+		"number":   map[string]interface{}{"type": "string"},
+		"total":    map[string]interface{}{"type": "number"},
+		"currency": map[string]interface{}{"type": "string"},
+	})
+	v2, err := registry.Register("invoice", "An invoice", widened)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	breaking := objectSchema([]interface{}{"currency"}, widened["properties"].(map[string]interface{}))
+	require.NoError(t, registry.CheckCompatibility("invoice", widened))
+	assert.Error(t, registry.CheckCompatibility("invoice", breaking))
+	_, err = registry.Register("invoice", "An invoice", breaking)
+	var compatErr *SchemaCompatibilityError
+	require.ErrorAs(t, err, &compatErr)
+	assert.Equal(t, SchemaCompatibilityBackward, compatErr.Compatibility)
+	assert.Len(t, registry.Versions("invoice"), 2)
+
+	got, err := registry.GetVersion("invoice", 1)
+	require.NoError(t, err)
+	assert.Same(t, v1, got)
+	assert.NoError(t, v2.Validate([]byte(`{"number": "A-1", "total": 10, "currency": "EUR"}`)))
+	assert.Error(t, v2.Validate([]byte(`{"total": "ten"}`)))
+
+	var llmErr *Error
+	_, err = registry.Get("missing")
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "schema_not_found", llmErr.Code)
+	_, err = registry.GetVersion("invoice", 3)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "schema_not_found", llmErr.Code)
+}
+
+func TestSchemaRegistry_Strict(t *testing.T) {
+	registry := NewSchemaRegistry(&SchemaRegistryConfig{Compatibility: SchemaCompatibilityNone})
+
+	version, err := registry.RegisterStrictStruct("invoice", "An invoice", registryInvoice{})
+	require.NoError(t, err)
+	format := version.ResponseFormat()
+	require.NotNil(t, format.JSONSchema.Strict)
+	assert.True(t, *format.JSONSchema.Strict)
+
+	// Schemas that strict structured outputs reject are not registered
+	_, err = registry.RegisterStrict("loose", "", objectSchema(nil, map[string]interface{}{
+		"name": map[string]interface{}{"type": "string"},
+	}))
+	var lintErr *SchemaLintError
+	require.ErrorAs(t, err, &lintErr)
+
+	// Without compatibility any change is accepted
+	_, err = registry.Register("invoice", "", map[string]interface{}{"type": "string"})
+	require.NoError(t, err)
+
+	var llmErr *Error
+	_, err = registry.Register("bad name!", "", map[string]interface{}{"type": "string"})
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_schema", llmErr.Code)
+}
+
+func TestSchemaRegistry_SaveLoad(t *testing.T) {
+	registry := NewSchemaRegistry(nil)
+	_, err := registry.RegisterStruct("invoice", "An invoice", registryInvoice{})
+	require.NoError(t, err)
+	_, err = registry.Register("invoice", "An invoice with a currency", objectSchema(nil, map[string]interface{}{
+		"number":   map[string]interface{}{"type": "string"},
+		"total":    map[string]interface{}{"type": "number"},
+		"currency": map[string]interface{}{"type": "string"},
+	}))
+	require.NoError(t, err)
+	_, err = registry.Register("answer", "", map[string]interface{}{"type": "string", "maxLength": 100})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, registry.Save(&buf))
+
+	loaded := NewSchemaRegistry(nil)
+	_, err = loaded.Register("stale", "", map[string]interface{}{"type": "string"})
+	require.NoError(t, err)
+	require.NoError(t, loaded.Load(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, []string{"answer", "invoice"}, loaded.Names())
+
+	for _, name := range registry.Names() {
+		expected, got := registry.Versions(name), loaded.Versions(name)
+		require.Len(t, got, len(expected))
+		for i := range expected {
+			assert.Equal(t, expected[i].Fingerprint, got[i].Fingerprint)
+			assert.Equal(t, expected[i].Description, got[i].Description)
+			assert.True(t, expected[i].Created.Equal(got[i].Created))
+		}
+	}
+
+	// Registering the latest schema again after loading is a no-op
+	latest, err := loaded.Register("answer", "", map[string]interface{}{"type": "string", "maxLength": 100})
+	require.NoError(t, err)
+	assert.Equal(t, 1, latest.Version)
+
+	// Edited schemas are detected
+	edited := strings.Replace(buf.String(), `"maxLength": 100`, `"maxLength": 10`, 1)
+	assert.Error(t, loaded.Load(strings.NewReader(edited)))
+}
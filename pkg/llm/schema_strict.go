@@ -0,0 +1,468 @@
+// Strict structured output schemas, union types and schema linting
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/swaggest/jsonschema-go"
+)
+
+// maxStrictSchemaNesting is the deepest object nesting accepted by strict structured outputs
+const maxStrictSchemaNesting = 10
+
+// strictUnsupportedKeywords are JSON Schema keywords rejected by strict structured outputs
+var strictUnsupportedKeywords = []string{
+	"not", "if", "then", "else", "patternProperties", "propertyNames", "dependencies",
+	"dependentRequired", "dependentSchemas", "unevaluatedProperties", "unevaluatedItems", "contains",
+}
+
+// StrictSchema returns a copy of a JSON Schema rewritten to satisfy strict structured output rules:
+// every object lists all its properties as required (optional properties become nullable) and
+// disallows additional properties, "oneOf" is expressed as "anyOf" and "definitions" are moved
+// to "$defs". Recursive schemas are preserved through their references.
+func StrictSchema(schema interface{}) (map[string]interface{}, error) {
+	root, err := normalizeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("invalid schema: schema is empty")
+	}
+
+	if definitions, ok := root["definitions"]; ok {
+		delete(root, "definitions")
+		defs, _ := root["$defs"].(map[string]interface{})
+		if defs == nil {
+			defs = map[string]interface{}{}
+		}
+		if definitionsMap, ok := definitions.(map[string]interface{}); ok {
+			for name, def := range definitionsMap {
+				defs[name] = def
+			}
+		}
+		root["$defs"] = defs
+	}
+
+	makeStrict(root)
+	return root, nil
+}
+
+func makeStrict(schema map[string]interface{}) {
+	if ref, ok := schema["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+		schema["$ref"] = "#/$defs/" + strings.TrimPrefix(ref, "#/definitions/")
+	}
+
+	if oneOf, ok := schema["oneOf"]; ok {
+		delete(schema, "oneOf")
+		if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+			if items, ok := oneOf.([]interface{}); ok {
+				schema["anyOf"] = append(anyOf, items...)
+			}
+		} else {
+			schema["anyOf"] = oneOf
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		required := map[string]bool{}
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, name := range list {
+				if key, ok := name.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		allRequired := make([]interface{}, len(keys))
+		for i, key := range keys {
+			allRequired[i] = key
+			if prop, ok := properties[key].(map[string]interface{}); ok && !required[key] {
+				properties[key] = makeNullable(prop)
+			}
+		}
+		schema["required"] = allRequired
+	}
+
+	if _, ok := schema["properties"]; ok || schemaHasType(schema, "object") {
+		if additional, ok := schema["additionalProperties"]; !ok || additional == true {
+			schema["additionalProperties"] = false
+		}
+	}
+
+	forEachSubschema(schema, func(_ string, sub map[string]interface{}) {
+		makeStrict(sub)
+	})
+}
+
+// makeNullable allows null in addition to the values accepted by a schema
+func makeNullable(schema map[string]interface{}) map[string]interface{} {
+	if _, hasRef := schema["$ref"]; !hasRef {
+		switch t := schema["type"].(type) {
+		case string:
+			if t != "null" {
+				schema["type"] = []interface{}{t, "null"}
+			}
+			addNullToEnum(schema)
+			return schema
+		case []interface{}:
+			if !containsValue(t, "null") {
+				schema["type"] = append(t, "null")
+			}
+			addNullToEnum(schema)
+			return schema
+		}
+
+		if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+			schema["anyOf"] = append(anyOf, map[string]interface{}{"type": "null"})
+			return schema
+		}
+	}
+
+	return map[string]interface{}{
+		"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}},
+	}
+}
+
+func addNullToEnum(schema map[string]interface{}) {
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, nil) {
+		schema["enum"] = append(enum, nil)
+	}
+}
+
+// forEachSubschema calls fn for every direct subschema, with the keyword path leading to it
+func forEachSubschema(schema map[string]interface{}, fn func(path string, sub map[string]interface{})) {
+	for _, keyword := range []string{"properties", "$defs", "definitions"} {
+		if children, ok := schema[keyword].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(children))
+			for key := range children {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if sub, ok := children[key].(map[string]interface{}); ok {
+					fn(keyword+"."+key, sub)
+				}
+			}
+		}
+	}
+
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		if sub, ok := schema[keyword].(map[string]interface{}); ok {
+			fn(keyword, sub)
+		}
+	}
+	if items, ok := schema["items"].([]interface{}); ok {
+		for i, item := range items {
+			if sub, ok := item.(map[string]interface{}); ok {
+				fn(fmt.Sprintf("items[%d]", i), sub)
+			}
+		}
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		if list, ok := schema[keyword].([]interface{}); ok {
+			for i, item := range list {
+				if sub, ok := item.(map[string]interface{}); ok {
+					fn(fmt.Sprintf("%s[%d]", keyword, i), sub)
+				}
+			}
+		}
+	}
+}
+
+func schemaHasType(schema map[string]interface{}, t string) bool {
+	for _, candidate := range schemaTypes(schema["type"]) {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaLintError lists the constructs of a schema that providers would reject
+type SchemaLintError struct {
+	Issues []SchemaViolation `json:"issues"`
+}
+
+func (e *SchemaLintError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = fmt.Sprintf("%s: %s", issue.Path, issue.Message)
+	}
+	return "unsupported schema: " + strings.Join(parts, "; ")
+}
+
+// LintSchema checks a JSON Schema for constructs that providers reject, so problems are
+// reported before calling the API. It always checks references, patterns, types and
+// combinators; with strict it also applies the rules of strict structured outputs
+// (required properties, no additional properties, no untyped values such as interface
+// fields, no "oneOf"/"allOf", limited nesting). Issues are returned as a *SchemaLintError.
+func LintSchema(schema interface{}, strict bool) error {
+	root, err := normalizeSchema(schema)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return &SchemaLintError{Issues: []SchemaViolation{{Path: "$", Message: "schema is empty"}}}
+	}
+
+	l := &schemaLinter{validator: schemaValidator{root: root}, strict: strict}
+	if strict && !schemaHasType(root, "object") {
+		l.report("$", "root schema must be an object in strict mode")
+	}
+	l.lint(root, "$", 0)
+
+	if len(l.issues) > 0 {
+		return &SchemaLintError{Issues: l.issues}
+	}
+	return nil
+}
+
+type schemaLinter struct {
+	validator schemaValidator
+	strict    bool
+	issues    []SchemaViolation
+}
+
+func (l *schemaLinter) report(path, format string, args ...interface{}) {
+	l.issues = append(l.issues, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *schemaLinter) lint(schema map[string]interface{}, path string, nesting int) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if _, err := l.validator.resolveRef(ref); err != nil {
+			l.report(path, "%v", err)
+		}
+	}
+
+	for _, t := range schemaTypes(schema["type"]) {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			l.report(path, "unknown type %q", t)
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			l.report(path, "invalid pattern %q", pattern)
+		}
+	}
+
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		if value, ok := schema[keyword]; ok {
+			if list, ok := value.([]interface{}); !ok || len(list) == 0 {
+				l.report(path, "%s must be a non-empty list of schemas", keyword)
+			}
+		}
+	}
+
+	properties, hasProperties := schema["properties"].(map[string]interface{})
+	if required, ok := schema["required"].([]interface{}); ok && hasProperties {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, exists := properties[key]; !exists {
+					l.report(path, "required property %q is not defined", key)
+				}
+			}
+		}
+	}
+
+	if l.strict {
+		l.lintStrict(schema, path, properties, nesting)
+	}
+
+	if hasProperties {
+		nesting++
+	}
+	forEachSubschema(schema, func(keyword string, sub map[string]interface{}) {
+		childNesting := nesting
+		if strings.HasPrefix(keyword, "$defs.") || strings.HasPrefix(keyword, "definitions.") {
+			childNesting = 0
+		}
+		l.lint(sub, path+"."+keyword, childNesting)
+	})
+}
+
+func (l *schemaLinter) lintStrict(schema map[string]interface{}, path string, properties map[string]interface{}, nesting int) {
+	if isUntypedSchema(schema) {
+		l.report(path, "schema accepts any value (e.g. an interface field); strict mode requires a type")
+	}
+
+	for _, keyword := range strictUnsupportedKeywords {
+		if _, ok := schema[keyword]; ok {
+			l.report(path, "keyword %q is not supported in strict mode", keyword)
+		}
+	}
+	if _, ok := schema["oneOf"]; ok {
+		l.report(path, "oneOf is not supported in strict mode, use anyOf (see StrictSchema)")
+	}
+	if _, ok := schema["allOf"]; ok {
+		l.report(path, "allOf is not supported in strict mode")
+	}
+
+	if properties != nil || schemaHasType(schema, "object") {
+		if nesting >= maxStrictSchemaNesting {
+			l.report(path, "objects are nested more than %d levels deep", maxStrictSchemaNesting)
+		}
+		if additional, ok := schema["additionalProperties"]; !ok || additional != false {
+			l.report(path, "additionalProperties must be false in strict mode")
+		}
+
+		required := map[string]bool{}
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, name := range list {
+				if key, ok := name.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !required[key] {
+				l.report(path, "property %q must be required in strict mode (make it nullable instead)", key)
+			}
+		}
+	}
+}
+
+// isUntypedSchema reports whether a schema places no constraint on the type of a value
+func isUntypedSchema(schema map[string]interface{}) bool {
+	for _, keyword := range []string{"type", "$ref", "anyOf", "oneOf", "allOf", "enum", "const"} {
+		if _, ok := schema[keyword]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Lint checks the schema of a json_schema response format with LintSchema (in strict mode when
+// the format is strict), returning an "invalid_schema" *Error so problems surface before the
+// request is sent
+func (f *ResponseFormat) Lint() error {
+	if f == nil || f.Type != ResponseFormatJSONSchema || f.JSONSchema == nil {
+		return nil
+	}
+
+	strict := f.JSONSchema.Strict != nil && *f.JSONSchema.Strict
+	if err := LintSchema(f.JSONSchema.Schema, strict); err != nil {
+		return &Error{
+			Code:       "invalid_schema",
+			Message:    fmt.Sprintf("response format %q: %v", f.JSONSchema.Name, err),
+			Type:       "validation_error",
+			StatusCode: 400,
+		}
+	}
+	return nil
+}
+
+// OneOf holds a value of type A or B. Its JSON Schema is the "oneOf" of the schemas of
+// both types (turned into "anyOf" by StrictSchema), and it is encoded as the wrapped value,
+// so struct fields of type OneOf describe union types in structured outputs.
+//
+// Example:
+//
+//	type Shape struct {
+//	    Kind  string                      `json:"kind"`
+//	    Value llm.OneOf[Circle, Rectangle] `json:"value"`
+//	}
+//
+//	if circle, ok := shape.Value.First(); ok {
+//	    ...
+//	}
+type OneOf[A, B any] struct {
+	value interface{}
+}
+
+// NewOneOfFirst creates a OneOf holding a value of the first type
+func NewOneOfFirst[A, B any](value A) OneOf[A, B] {
+	return OneOf[A, B]{value: value}
+}
+
+// NewOneOfSecond creates a OneOf holding a value of the second type
+func NewOneOfSecond[A, B any](value B) OneOf[A, B] {
+	return OneOf[A, B]{value: value}
+}
+
+// Value returns the wrapped value, or nil when the union is empty
+func (o OneOf[A, B]) Value() interface{} {
+	return o.value
+}
+
+// First returns the wrapped value if it is of the first type
+func (o OneOf[A, B]) First() (A, bool) {
+	value, ok := o.value.(A)
+	return value, ok
+}
+
+// Second returns the wrapped value if it is of the second type
+func (o OneOf[A, B]) Second() (B, bool) {
+	value, ok := o.value.(B)
+	return value, ok
+}
+
+// JSONSchemaOneOf exposes the variants of the union to the schema generator
+func (OneOf[A, B]) JSONSchemaOneOf() []interface{} {
+	var a A
+	var b B
+	return []interface{}{a, b}
+}
+
+// PrepareJSONSchema removes the object constraints implied by the Go struct
+func (OneOf[A, B]) PrepareJSONSchema(schema *jsonschema.Schema) error {
+	schema.Type = nil
+	schema.Items = nil
+	schema.Properties = nil
+	return nil
+}
+
+// InlineJSONSchema keeps the union inline instead of creating a definition for the generic type
+func (OneOf[A, B]) InlineJSONSchema() {}
+
+// MarshalJSON encodes the wrapped value
+func (o OneOf[A, B]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON decodes the value as the first type whose schema it matches
+func (o *OneOf[A, B]) UnmarshalJSON(data []byte) error {
+	var first A
+	if err := decodeUnionVariant(data, &first); err == nil {
+		o.value = first
+		return nil
+	}
+
+	var second B
+	err := decodeUnionVariant(data, &second)
+	if err != nil {
+		return fmt.Errorf("value matches none of the union types: %w", err)
+	}
+	o.value = second
+	return nil
+}
+
+// decodeUnionVariant decodes data into target after checking it against the target's schema
+func decodeUnionVariant(data []byte, target interface{}) error {
+	schema, err := SchemaFromStructAsMap(target)
+	if err != nil {
+		return err
+	}
+	if err := ValidateAgainstSchema(data, schema); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
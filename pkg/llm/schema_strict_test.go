@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TreeNode struct {
+	Name     string      `json:"name" required:"true"`
+	Note     string      `json:"note,omitempty"`
+	Children []*TreeNode `json:"children"`
+}
+
+type Circle struct {
+	Radius float64 `json:"radius" required:"true"`
+}
+
+type Rectangle struct {
+	Width  float64 `json:"width" required:"true"`
+	Height float64 `json:"height" required:"true"`
+}
+
+type Drawing struct {
+	Title string                   `json:"title" required:"true"`
+	Shape OneOf[Circle, Rectangle] `json:"shape" required:"true"`
+}
+
+type WithInterface struct {
+	Name  string                 `json:"name"`
+	Extra interface{}            `json:"extra"`
+	Tags  map[string]interface{} `json:"tags"`
+}
+
+func TestNewJSONSchemaResponseFormatStrictFromStruct_Recursive(t *testing.T) {
+	format, err := NewJSONSchemaResponseFormatStrictFromStruct("tree", "", TreeNode{})
+	require.NoError(t, err)
+
+	schema := format.JSONSchema.Schema.(map[string]interface{})
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.Equal(t, []interface{}{"children", "name", "note"}, schema["required"])
+
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"string", "null"}, properties["note"].(map[string]interface{})["type"])
+	items := properties["children"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, "#", items["$ref"])
+
+	// The recursive schema validates nested values through its reference
+	assert.NoError(t, ValidateAgainstSchema([]byte(`{"name":"a","note":null,"children":[{"name":"b","note":"x","children":null}]}`), schema))
+	assert.Error(t, ValidateAgainstSchema([]byte(`{"name":"a","note":null,"children":[{"name":1,"note":null,"children":null}]}`), schema))
+}
+
+func TestNewJSONSchemaResponseFormatStrictFromStruct_Unsupported(t *testing.T) {
+	_, err := NewJSONSchemaResponseFormatStrictFromStruct("bad", "", WithInterface{})
+	require.Error(t, err)
+
+	var lintErr *SchemaLintError
+	require.ErrorAs(t, err, &lintErr)
+	paths := make([]string, len(lintErr.Issues))
+	for i, issue := range lintErr.Issues {
+		paths[i] = issue.Path
+	}
+	assert.Contains(t, paths, "$.properties.extra.anyOf[0]")
+	assert.Contains(t, paths, "$.properties.tags")
+
+	// Non-strict formats accept the same struct
+	_, err = NewJSONSchemaResponseFormatFromStruct("ok", "", WithInterface{})
+	assert.NoError(t, err)
+}
+
+func TestOneOf(t *testing.T) {
+	format, err := NewJSONSchemaResponseFormatStrictFromStruct("drawing", "", Drawing{})
+	require.NoError(t, err)
+
+	schema := format.JSONSchema.Schema.(map[string]interface{})
+	shape := schema["properties"].(map[string]interface{})["shape"].(map[string]interface{})
+	assert.Len(t, shape["anyOf"], 2)
+	assert.NotContains(t, shape, "oneOf")
+	assert.NotContains(t, shape, "type")
+
+	var drawing Drawing
+	require.NoError(t, json.Unmarshal([]byte(`{"title":"r","shape":{"width":2,"height":3}}`), &drawing))
+	rect, ok := drawing.Shape.Second()
+	require.True(t, ok)
+	assert.Equal(t, Rectangle{Width: 2, Height: 3}, rect)
+	_, ok = drawing.Shape.First()
+	assert.False(t, ok)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"title":"c","shape":{"radius":1}}`), &drawing))
+	circle, ok := drawing.Shape.First()
+	require.True(t, ok)
+	assert.Equal(t, 1.0, circle.Radius)
+
+	data, err := json.Marshal(Drawing{Title: "c", Shape: NewOneOfFirst[Circle, Rectangle](Circle{Radius: 2})})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"c","shape":{"radius":2}}`, string(data))
+	assert.NoError(t, ValidateAgainstSchema(data, schema))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"title":"x","shape":{"side":1}}`), &drawing))
+}
+
+func TestValidateValueAgainstSchema_Combinators(t *testing.T) {
+	anyOf := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "integer", "minimum": 0},
+		},
+	}
+	assert.NoError(t, ValidateValueAgainstSchema("x", anyOf))
+	assert.NoError(t, ValidateValueAgainstSchema(float64(3), anyOf))
+	assert.Error(t, ValidateValueAgainstSchema(float64(-1), anyOf))
+
+	oneOf := map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "number"},
+			map[string]interface{}{"type": "integer"},
+		},
+	}
+	assert.NoError(t, ValidateValueAgainstSchema(1.5, oneOf))
+	// 2 is both a number and an integer
+	assert.Error(t, ValidateValueAgainstSchema(float64(2), oneOf))
+}
+
+func TestLintSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		strict bool
+		want   []string
+	}{
+		{
+			name:   "valid strict schema",
+			schema: `{"type":"object","properties":{"a":{"type":"string"}},"required":["a"],"additionalProperties":false}`,
+			strict: true,
+		},
+		{
+			name:   "non-strict accepts optional properties",
+			schema: `{"type":"object","properties":{"a":{"type":"string"}}}`,
+		},
+		{
+			name:   "broken reference and pattern",
+			schema: `{"type":"object","properties":{"a":{"$ref":"#/$defs/Missing"},"b":{"type":"string","pattern":"("}}}`,
+			want:   []string{"$.properties.a", "$.properties.b"},
+		},
+		{
+			name:   "strict rules",
+			schema: `{"type":"object","properties":{"a":{},"b":{"oneOf":[{"type":"string"},{"type":"null"}]}},"required":["a"]}`,
+			strict: true,
+			want:   []string{"$", "$", "$.properties.a", "$.properties.b"},
+		},
+		{
+			name:   "strict root must be an object",
+			schema: `{"type":"array","items":{"type":"string"}}`,
+			strict: true,
+			want:   []string{"$"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LintSchema(tt.schema, tt.strict)
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var lintErr *SchemaLintError
+			require.ErrorAs(t, err, &lintErr)
+			paths := make([]string, len(lintErr.Issues))
+			for i, issue := range lintErr.Issues {
+				paths[i] = issue.Path
+			}
+			assert.Equal(t, tt.want, paths, lintErr.Error())
+		})
+	}
+}
+
+func TestResponseFormatLint(t *testing.T) {
+	assert.NoError(t, (*ResponseFormat)(nil).Lint())
+	assert.NoError(t, NewJSONResponseFormat().Lint())
+
+	strict := NewJSONSchemaResponseFormatStrict("loose", "", map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}},
+	})
+	var llmErr *Error
+	require.ErrorAs(t, strict.Lint(), &llmErr)
+	assert.Equal(t, "invalid_schema", llmErr.Code)
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -231,6 +232,18 @@ func TestValidateAgainstSchema(t *testing.T) {
 			schema:   nil,
 			wantErr:  false,
 		},
+		{
+			name:     "missing required property",
+			jsonData: `{"name": "John"}`,
+			schema:   schema,
+			wantErr:  true,
+		},
+		{
+			name:     "wrong property type",
+			jsonData: `{"name": "John", "age": "thirty"}`,
+			schema:   schema,
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -347,6 +360,137 @@ func TestExtractAndValidateJSONToStruct(t *testing.T) {
 }
 
 // Benchmark tests
+func TestValidateValueAgainstSchema_References(t *testing.T) {
+	schema := map[string]interface{}{
+		"$ref": "#/definitions/Node",
+		"definitions": map[string]interface{}{
+			"Node": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":     map[string]interface{}{"type": []string{"string", "null"}},
+					"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/definitions/Node"}},
+				},
+			},
+		},
+	}
+
+	valid := map[string]interface{}{
+		"name":     nil,
+		"children": []interface{}{map[string]interface{}{"name": "leaf"}},
+	}
+	assert.NoError(t, ValidateValueAgainstSchema(valid, schema))
+
+	invalid := map[string]interface{}{
+		"children": []interface{}{map[string]interface{}{"name": 1.0}},
+	}
+	err := ValidateValueAgainstSchema(invalid, schema)
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Violations, 1)
+	assert.Equal(t, "$.children[0].name", validationErr.Violations[0].Path)
+}
+
+func TestValidateAgainstSchema_GeneratedSchema(t *testing.T) {
+	schema, err := SchemaFromStruct(NestedStruct{})
+	require.NoError(t, err)
+
+	assert.NoError(t, ValidateAgainstSchema([]byte(`{"person": {"name": "Ann", "age": 3}, "items": ["a"], "count": 1}`), schema))
+	assert.Error(t, ValidateAgainstSchema([]byte(`{"person": {"name": "Ann", "age": "3"}, "count": 1}`), schema))
+}
+
+func TestResponseFormatPolicy(t *testing.T) {
+	jsonReq := ChatRequest{
+		Messages:       []Message{NewTextMessage(RoleUser, "List three colors")},
+		ResponseFormat: NewJSONResponseFormat(),
+	}
+
+	t.Run("prefer native", func(t *testing.T) {
+		native, err := jsonReq.ResolveResponseFormat(true)
+		require.NoError(t, err)
+		assert.True(t, native)
+
+		prepared, err := jsonReq.PrepareResponseFormat(false)
+		require.NoError(t, err)
+		assert.Nil(t, prepared.ResponseFormat)
+		require.Len(t, prepared.Messages, 2)
+		assert.Equal(t, RoleSystem, prepared.Messages[0].Role)
+		assert.Contains(t, prepared.Messages[0].GetText(), "valid JSON")
+		assert.Len(t, jsonReq.Messages, 1, "original request should not be modified")
+	})
+
+	t.Run("require native", func(t *testing.T) {
+		req := jsonReq
+		req.ResponseFormatPolicy = ResponseFormatPolicyRequireNative
+
+		native, err := req.ResolveResponseFormat(true)
+		require.NoError(t, err)
+		assert.True(t, native)
+
+		_, err = req.PrepareResponseFormat(false)
+		var llmErr *Error
+		require.ErrorAs(t, err, &llmErr)
+		assert.Equal(t, "response_format_not_supported", llmErr.Code)
+	})
+
+	t.Run("prompt only", func(t *testing.T) {
+		req := jsonReq
+		req.ResponseFormatPolicy = ResponseFormatPolicyPromptOnly
+
+		prepared, err := req.PrepareResponseFormat(true)
+		require.NoError(t, err)
+		assert.Nil(t, prepared.ResponseFormat)
+		assert.Len(t, prepared.Messages, 2)
+	})
+
+	t.Run("text format needs nothing", func(t *testing.T) {
+		req := ChatRequest{
+			ResponseFormat:       &ResponseFormat{Type: ResponseFormatText},
+			ResponseFormatPolicy: ResponseFormatPolicyRequireNative,
+		}
+		prepared, err := req.PrepareResponseFormat(false)
+		require.NoError(t, err)
+		assert.Equal(t, req.ResponseFormat, prepared.ResponseFormat)
+	})
+}
+
+func TestResponseFormatInstruction(t *testing.T) {
+	schema := map[string]interface{}{"type": "object"}
+	instruction := ResponseFormatInstruction(NewJSONSchemaResponseFormat("obj", "", schema))
+	assert.Contains(t, instruction, `{"type":"object"}`)
+	assert.Empty(t, ResponseFormatInstruction(nil))
+
+	grammar := &ResponseFormat{Type: ResponseFormatGrammar, Grammar: `root ::= "yes" | "no"`}
+	assert.Contains(t, ResponseFormatInstruction(grammar), `root ::= "yes" | "no"`)
+	assert.False(t, grammar.IsJSON())
+	assert.True(t, NewJSONResponseFormat().IsJSON())
+
+	// Providers without grammar support fall back to instructions, or fail when native is required
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Is the sky blue?")}, ResponseFormat: grammar}
+	prepared, err := req.PrepareResponseFormat(grammar.IsJSON())
+	assert.NoError(t, err)
+	assert.Nil(t, prepared.ResponseFormat)
+	assert.Len(t, prepared.Messages, 2)
+
+	req.ResponseFormatPolicy = ResponseFormatPolicyRequireNative
+	_, err = req.PrepareResponseFormat(grammar.IsJSON())
+	assert.Error(t, err)
+}
+
+func TestResponseFormatCompatible(t *testing.T) {
+	assert.Nil(t, (*ResponseFormat)(nil).Compatible())
+	assert.Nil(t, (&ResponseFormat{Type: ResponseFormatText}).Compatible())
+	assert.Equal(t, "json_object", NewJSONResponseFormat().Compatible().Type)
+	assert.Equal(t, "json_object", (&ResponseFormat{Type: ResponseFormatJSONSchema}).Compatible().Type)
+
+	strict := NewJSONSchemaResponseFormatStrict("person", "A person", map[string]interface{}{"type": "object"})
+	data, err := json.Marshal(strict.Compatible())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "json_schema", "json_schema": {"name": "person", "description": "A person", "schema": {"type": "object"}, "strict": true}}`, string(data))
+
+	grammar := (&ResponseFormat{Type: ResponseFormatGrammar, Grammar: "root ::= x"}).Compatible()
+	assert.Equal(t, &CompatibleResponseFormat{Type: "grammar", Grammar: "root ::= x"}, grammar)
+}
+
 func BenchmarkSchemaFromStruct(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = SchemaFromStruct(PersonWithValidation{})
@@ -0,0 +1,378 @@
+// JSON Schema validation for structured outputs and tool arguments
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes a single place where a value does not conform to a schema
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SchemaValidationError lists every violation found while validating a value against a JSON Schema
+type SchemaValidationError struct {
+	Violations []SchemaViolation `json:"violations"`
+}
+
+func (e *SchemaValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s: %s", v.Path, v.Message)
+	}
+	return "schema validation failed: " + strings.Join(parts, "; ")
+}
+
+// ValidateValueAgainstSchema validates a decoded JSON value against a JSON Schema.
+// The schema can be any value that marshals to a JSON Schema object (a map, a generated
+// schema from SchemaFromStruct, etc). It supports the keywords commonly used for
+// structured outputs and tool parameters: type, enum, const, properties, required,
+// additionalProperties, items, min/maxItems, min/maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf and local $ref. Unknown keywords
+// are ignored.
+func ValidateValueAgainstSchema(value interface{}, schema interface{}) error {
+	root, err := normalizeSchema(schema)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+
+	v := &schemaValidator{root: root}
+	v.validate(root, value, "$", 0)
+	if len(v.violations) > 0 {
+		return &SchemaValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+// maxSchemaDepth bounds $ref resolution to protect against self-referencing schemas
+const maxSchemaDepth = 64
+
+// normalizeSchema converts any schema representation into a generic JSON object
+func normalizeSchema(schema interface{}) (map[string]interface{}, error) {
+	switch s := schema.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return unmarshalSchema(s)
+	case []byte:
+		return unmarshalSchema(s)
+	case string:
+		return unmarshalSchema([]byte(s))
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return unmarshalSchema(data)
+}
+
+func unmarshalSchema(data []byte) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return result, nil
+}
+
+type schemaValidator struct {
+	root       map[string]interface{}
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string, depth int) {
+	if depth > maxSchemaDepth {
+		v.fail(path, "schema nesting too deep")
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolveRef(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(resolved, value, path, depth+1)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(value, types) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		v.fail(path, "value %s is not one of the allowed values", compactJSON(value))
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		v.fail(path, "value must be %s", compactJSON(constant))
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				v.validate(subSchema, value, path, depth+1)
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if matches := v.countMatches(anyOf, value, path, depth); matches == 0 {
+			v.fail(path, "value does not match any of the anyOf schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matches := v.countMatches(oneOf, value, path, depth); matches != 1 {
+			v.fail(path, "value must match exactly one of the oneOf schemas, matched %d", matches)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, val, path, depth)
+	case []interface{}:
+		v.validateArray(schema, val, path, depth)
+	case string:
+		v.validateString(schema, val, path)
+	case float64:
+		v.validateNumber(schema, val, path)
+	}
+}
+
+// countMatches returns how many of the schemas accept the value, validating each one in isolation
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, path string, depth int) int {
+	matches := 0
+	for _, sub := range schemas {
+		subSchema, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		branch := &schemaValidator{root: v.root}
+		branch.validate(subSchema, value, path, depth+1)
+		if len(branch.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string, depth int) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, exists := obj[key]; !exists {
+					v.fail(path, "missing required property %q", key)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Iterate in a stable order so violations are reported deterministically
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			v.validate(propSchema, obj[key], childPath, depth+1)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(childPath, "unexpected property")
+			}
+		case map[string]interface{}:
+			v.validate(additional, obj[key], childPath, depth+1)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, arr []interface{}, path string, depth int) {
+	if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(arr)) < minItems {
+		v.fail(path, "expected at least %v items, got %d", minItems, len(arr))
+	}
+	if maxItems, ok := schemaNumber(schema["maxItems"]); ok && float64(len(arr)) > maxItems {
+		v.fail(path, "expected at most %v items, got %d", maxItems, len(arr))
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, s string, path string) {
+	length := float64(utf8.RuneCountInString(s))
+	if minLength, ok := schemaNumber(schema["minLength"]); ok && length < minLength {
+		v.fail(path, "expected at least %v characters, got %v", minLength, length)
+	}
+	if maxLength, ok := schemaNumber(schema["maxLength"]); ok && length > maxLength {
+		v.fail(path, "expected at most %v characters, got %v", maxLength, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern %q in schema", pattern)
+		} else if !re.MatchString(s) {
+			v.fail(path, "value does not match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, n float64, path string) {
+	if minimum, ok := schemaNumber(schema["minimum"]); ok && n < minimum {
+		v.fail(path, "value %v is less than minimum %v", n, minimum)
+	}
+	if maximum, ok := schemaNumber(schema["maximum"]); ok && n > maximum {
+		v.fail(path, "value %v is greater than maximum %v", n, maximum)
+	}
+	if exclusiveMinimum, ok := schemaNumber(schema["exclusiveMinimum"]); ok && n <= exclusiveMinimum {
+		v.fail(path, "value %v must be greater than %v", n, exclusiveMinimum)
+	}
+	if exclusiveMaximum, ok := schemaNumber(schema["exclusiveMaximum"]); ok && n >= exclusiveMaximum {
+		v.fail(path, "value %v must be less than %v", n, exclusiveMaximum)
+	}
+}
+
+// resolveRef resolves a local JSON pointer reference such as "#/definitions/Person"
+func (v *schemaValidator) resolveRef(ref string) (map[string]interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported schema reference %q", ref)
+	}
+
+	var current interface{} = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable schema reference %q", ref)
+		}
+		if current, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("unresolvable schema reference %q", ref)
+		}
+	}
+
+	resolved, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema reference %q does not point to a schema", ref)
+	}
+	return resolved, nil
+}
+
+// schemaTypes returns the allowed types of a schema "type" keyword (a string or a list of strings)
+func schemaTypes(t interface{}) []string {
+	switch tt := t.(type) {
+	case string:
+		return []string{tt}
+	case []interface{}:
+		types := make([]string, 0, len(tt))
+		for _, item := range tt {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	// Unknown types are not enforced
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
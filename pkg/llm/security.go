@@ -2,11 +2,13 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -32,12 +34,21 @@ type SecurityConfig struct {
 	EnableContentScan     bool `json:"enable_content_scan"`
 
 	// Rate limiting
-	MaxRequestsPerMinute int           `json:"max_requests_per_minute"`
-	MaxProcessingTime    time.Duration `json:"max_processing_time"`
+	MaxRequestsPerMinute int            `json:"max_requests_per_minute"`
+	MaxProcessingTime    time.Duration  `json:"max_processing_time"`
+	RateLimitStore       RateLimitStore `json:"-"` // Keeps the request counts across restarts and replicas (in memory when nil)
 
 	// Resource limits
 	MaxMemoryUsage  int64         `json:"max_memory_usage"`
 	CleanupInterval time.Duration `json:"cleanup_interval"`
+
+	// Temporary file management
+	TempDir        string        `json:"temp_dir"`         // Directory for managed temp files (defaults to <os temp>/go-llm-managed)
+	MaxTempStorage int64         `json:"max_temp_storage"` // Quota for managed temp files in bytes (0 = unlimited)
+	TempFileTTL    time.Duration `json:"temp_file_ttl"`    // Age after which temp files are removed (defaults to 1 hour)
+
+	// Clock times the rate limits and the temp file cleanups (SystemClock when nil)
+	Clock Clock `json:"-"`
 }
 
 // DefaultSecurityConfig returns a secure default configuration
@@ -67,6 +78,9 @@ func DefaultSecurityConfig() *SecurityConfig {
 
 		MaxMemoryUsage:  500 * 1024 * 1024, // 500MB
 		CleanupInterval: 5 * time.Minute,
+
+		MaxTempStorage: 200 * 1024 * 1024, // 200MB
+		TempFileTTL:    1 * time.Hour,
 	}
 }
 
@@ -93,7 +107,7 @@ func NewSecurityValidator(config *SecurityConfig) *SecurityValidator {
 	return &SecurityValidator{
 		config:          config,
 		requestCounts:   make(map[string]int),
-		lastReset:       time.Now(),
+		lastReset:       clockOrSystem(config.Clock).Now(),
 		resourceMonitor: NewResourceMonitor(config),
 		auditLogger:     NewSecurityAuditLogger(),
 	}
@@ -631,12 +645,7 @@ func (sv *SecurityValidator) validateFileURL(url string) error {
 
 func (sv *SecurityValidator) normalizeImageMIME(mimeType string) string {
 	// Handle common variations
-	switch mimeType {
-	case "image/jpg":
-		return "image/jpeg"
-	default:
-		return mimeType
-	}
+	return NormalizeMIMEType(mimeType)
 }
 
 // Helper function to detect SVG content when http.DetectContentType returns text/plain
@@ -795,42 +804,113 @@ func (sv *SecurityValidator) getExpectedExtensions(mimeType string) []string {
 	return []string{}
 }
 
+// managedTempFilePrefix is the name prefix of temp files created by a ResourceMonitor.
+// It is used to recognize files orphaned by a previous process on restart.
+const managedTempFilePrefix = "go-llm-managed-"
+
+// defaultTempFileTTL is the age after which temp files are removed when no TTL is configured
+const defaultTempFileTTL = 1 * time.Hour
+
 // ResourceMonitor tracks resource usage for security and cleanup
 type ResourceMonitor struct {
 	config         *SecurityConfig
 	mu             sync.RWMutex
 	temporaryFiles map[string]time.Time
+	managedSizes   map[string]int64
+	tempBytes      int64
 	memoryUsage    int64
 	processedCount int64
 	lastCleanup    time.Time
-	cleanupTicker  *time.Ticker
+	clock          Clock
+	cleanupTicker  Ticker
 	shutdownChan   chan bool
+	shutdownOnce   sync.Once
 }
 
-// NewResourceMonitor creates a new resource monitor
+// NewResourceMonitor creates a new resource monitor.
+// Managed temp files left behind by a previous process are removed once they exceed the TTL.
 func NewResourceMonitor(config *SecurityConfig) *ResourceMonitor {
+	clock := clockOrSystem(config.Clock)
 	rm := &ResourceMonitor{
 		config:         config,
 		temporaryFiles: make(map[string]time.Time),
-		lastCleanup:    time.Now(),
+		managedSizes:   make(map[string]int64),
+		lastCleanup:    clock.Now(),
+		clock:          clock,
 		shutdownChan:   make(chan bool, 1),
 	}
 
+	rm.removeOrphanedFiles()
+
 	// Start cleanup goroutine if interval is positive
 	if config.CleanupInterval > 0 {
-		rm.cleanupTicker = time.NewTicker(config.CleanupInterval)
+		rm.cleanupTicker = clock.NewTicker(config.CleanupInterval)
 		go rm.cleanupWorker()
 	}
 
 	return rm
 }
 
-// RegisterTemporaryFile registers a temporary file for cleanup tracking
+// RegisterTemporaryFile registers a temporary file owned by the caller for tracking.
+// The monitor stops tracking it once it expires or when the monitor is shut down, but
+// never deletes it except through RemoveTemporaryFile; files the monitor should delete
+// must be created with NewManagedTempFile.
 func (rm *ResourceMonitor) RegisterTemporaryFile(filepath string) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	rm.temporaryFiles[filepath] = time.Now()
+	rm.temporaryFiles[filepath] = rm.clock.Now()
+}
+
+// NewManagedTempFile writes data to a new temporary file owned by the monitor and
+// returns its path. The file counts against MaxTempStorage until it is removed.
+func (rm *ResourceMonitor) NewManagedTempFile(data []byte) (string, error) {
+	size := int64(len(data))
+
+	rm.mu.Lock()
+	if rm.config.MaxTempStorage > 0 && rm.tempBytes+size > rm.config.MaxTempStorage {
+		rm.mu.Unlock()
+		return "", fmt.Errorf("temporary storage quota exceeded: %d + %d bytes exceeds limit %d",
+			rm.tempBytes, size, rm.config.MaxTempStorage)
+	}
+	// Reserve the space before writing so concurrent callers can't exceed the quota
+	rm.tempBytes += size
+	rm.mu.Unlock()
+
+	path, err := rm.writeTempFile(data)
+	if err != nil {
+		rm.mu.Lock()
+		rm.tempBytes -= size
+		rm.mu.Unlock()
+		return "", err
+	}
+
+	rm.mu.Lock()
+	rm.temporaryFiles[path] = rm.clock.Now()
+	rm.managedSizes[path] = size
+	rm.mu.Unlock()
+
+	return path, nil
+}
+
+// RemoveTemporaryFile deletes a tracked temporary file and stops tracking it
+func (rm *ResourceMonitor) RemoveTemporaryFile(path string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, exists := rm.temporaryFiles[path]; !exists {
+		return fmt.Errorf("file %s is not tracked by the resource monitor", path)
+	}
+
+	return rm.removeFileLocked(path)
+}
+
+// TempDir returns the directory where managed temporary files are created
+func (rm *ResourceMonitor) TempDir() string {
+	if rm.config.TempDir != "" {
+		return rm.config.TempDir
+	}
+	return filepath.Join(os.TempDir(), "go-llm-managed")
 }
 
 // TrackMemoryUsage updates memory usage tracking
@@ -867,6 +947,7 @@ func (rm *ResourceMonitor) GetResourceStats() ResourceStats {
 
 	return ResourceStats{
 		TemporaryFiles: len(rm.temporaryFiles),
+		TemporaryBytes: rm.tempBytes,
 		TrackedMemory:  rm.memoryUsage,
 		SystemMemory:   safeUint64ToInt64(m.Sys),
 		HeapMemory:     safeUint64ToInt64(m.HeapSys),
@@ -875,21 +956,22 @@ func (rm *ResourceMonitor) GetResourceStats() ResourceStats {
 	}
 }
 
-// CleanupExpiredFiles removes expired temporary files
+// CleanupExpiredFiles releases the temporary files older than the configured TTL, deleting
+// the managed ones, and returns the number of files released
 func (rm *ResourceMonitor) CleanupExpiredFiles() int {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	now := time.Now()
-	cleanupAge := 1 * time.Hour // Files older than 1 hour
+	now := rm.clock.Now()
+	cleanupAge := rm.tempFileTTL()
 
 	cleaned := 0
-	for filepath, createdAt := range rm.temporaryFiles {
+	for path, createdAt := range rm.temporaryFiles {
 		if now.Sub(createdAt) > cleanupAge {
-			// Note: In a real implementation, you'd actually delete the file here
-			// For this implementation, we're tracking for monitoring purposes
-			delete(rm.temporaryFiles, filepath)
-			cleaned++
+			// A failed removal is retried on the next cleanup cycle
+			if err := rm.releaseFileLocked(path); err == nil {
+				cleaned++
+			}
 		}
 	}
 
@@ -897,33 +979,130 @@ func (rm *ResourceMonitor) CleanupExpiredFiles() int {
 	return cleaned
 }
 
-// Shutdown stops the resource monitor
+// Shutdown stops the resource monitor and deletes all its managed temporary files
 func (rm *ResourceMonitor) Shutdown() {
-	rm.shutdownChan <- true
-	if rm.cleanupTicker != nil {
-		rm.cleanupTicker.Stop()
-	}
+	rm.shutdownOnce.Do(func() {
+		rm.shutdownChan <- true
+		if rm.cleanupTicker != nil {
+			rm.cleanupTicker.Stop()
+		}
+
+		rm.mu.Lock()
+		defer rm.mu.Unlock()
+		for path := range rm.temporaryFiles {
+			_ = rm.releaseFileLocked(path)
+		}
+	})
 }
 
 // cleanupWorker runs periodic cleanup operations
 func (rm *ResourceMonitor) cleanupWorker() {
 	for {
 		select {
-		case <-rm.cleanupTicker.C:
-			cleaned := rm.CleanupExpiredFiles()
-			if cleaned > 0 {
-				// Log cleanup activity (in real impl, use proper logging)
-				_ = cleaned
-			}
+		case <-rm.cleanupTicker.C():
+			rm.CleanupExpiredFiles()
+			rm.removeOrphanedFiles()
 		case <-rm.shutdownChan:
 			return
 		}
 	}
 }
 
+// writeTempFile creates a managed temp file with owner-only permissions
+func (rm *ResourceMonitor) writeTempFile(data []byte) (string, error) {
+	dir := rm.TempDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, managedTempFilePrefix+"*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// removeFileLocked deletes a tracked file and updates the accounting. Must be called with rm.mu held.
+func (rm *ResourceMonitor) removeFileLocked(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove temp file %s: %w", path, err)
+	}
+
+	rm.tempBytes -= rm.managedSizes[path]
+	delete(rm.managedSizes, path)
+	delete(rm.temporaryFiles, path)
+	return nil
+}
+
+// releaseFileLocked stops tracking a file, deleting it only when it was created by the
+// monitor. Must be called with rm.mu held.
+func (rm *ResourceMonitor) releaseFileLocked(path string) error {
+	if _, managed := rm.managedSizes[path]; managed {
+		return rm.removeFileLocked(path)
+	}
+	delete(rm.temporaryFiles, path)
+	return nil
+}
+
+// removeOrphanedFiles deletes managed temp files that are not tracked by this
+// monitor and are older than the TTL, such as files left behind by a crashed process
+func (rm *ResourceMonitor) removeOrphanedFiles() int {
+	entries, err := os.ReadDir(rm.TempDir())
+	if err != nil {
+		return 0
+	}
+
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	cutoff := rm.clock.Now().Add(-rm.tempFileTTL())
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), managedTempFilePrefix) {
+			continue
+		}
+
+		path := filepath.Join(rm.TempDir(), entry.Name())
+		if _, tracked := rm.temporaryFiles[path]; tracked {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// tempFileTTL returns the configured temp file TTL or the default
+func (rm *ResourceMonitor) tempFileTTL() time.Duration {
+	if rm.config.TempFileTTL > 0 {
+		return rm.config.TempFileTTL
+	}
+	return defaultTempFileTTL
+}
+
 // ResourceStats contains resource usage statistics
 type ResourceStats struct {
 	TemporaryFiles int       `json:"temporary_files"`
+	TemporaryBytes int64     `json:"temporary_bytes"`
 	TrackedMemory  int64     `json:"tracked_memory"`
 	SystemMemory   int64     `json:"system_memory"`
 	HeapMemory     int64     `json:"heap_memory"`
@@ -1052,23 +1231,28 @@ func ValidateContentSecurityWithConfig(content MessageContent, config *SecurityC
 
 // ValidateMessageSecurity validates all content in a message for security threats
 func ValidateMessageSecurity(message *Message) error {
+	validator := NewSecurityValidator(nil)
+	defer validator.resourceMonitor.Shutdown()
+
+	return validator.ValidateMessage(message)
+}
+
+// ValidateMessage validates the total size and all content of a message
+func (sv *SecurityValidator) ValidateMessage(message *Message) error {
 	if message == nil {
 		return errors.New("message cannot be nil")
 	}
 
-	validator := NewSecurityValidator(nil)
-	defer validator.resourceMonitor.Shutdown()
-
 	totalSize := message.TotalSize()
-	if totalSize > validator.config.MaxTotalSize {
-		err := fmt.Errorf("message total size %d exceeds limit %d", totalSize, validator.config.MaxTotalSize)
-		validator.auditLogger.LogSecurityEvent("TOTAL_SIZE_EXCEEDED", err.Error())
+	if totalSize > sv.config.MaxTotalSize {
+		err := fmt.Errorf("message total size %d exceeds limit %d", totalSize, sv.config.MaxTotalSize)
+		sv.auditLogger.LogSecurityEvent("TOTAL_SIZE_EXCEEDED", err.Error())
 		return err
 	}
 
 	// Validate each content item
 	for i, content := range message.Content {
-		if err := validator.ValidateContentSecurity(content); err != nil {
+		if err := sv.ValidateContentSecurity(content); err != nil {
 			return fmt.Errorf("content item %d failed security validation: %w", i, err)
 		}
 	}
@@ -1082,6 +1266,11 @@ type RateLimiter struct {
 	requestCounts map[string]int
 	lastReset     time.Time
 	limit         int
+
+	// Persistent counters, with the last error of the store
+	store    RateLimitStore
+	storeErr error
+	now      func() time.Time
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -1090,15 +1279,46 @@ func NewRateLimiter(requestsPerMinute int) *RateLimiter {
 		requestCounts: make(map[string]int),
 		lastReset:     time.Now(),
 		limit:         requestsPerMinute,
+		now:           time.Now,
 	}
 }
 
+// NewPersistentRateLimiter creates a rate limiter whose counts are kept in a store, so
+// they survive restarts and are shared by the replicas using the same store. Minutes
+// start on the clock minute, for all the replicas to agree. While the store fails,
+// requests are counted by the limiter alone (see StoreError).
+func NewPersistentRateLimiter(requestsPerMinute int, store RateLimitStore) *RateLimiter {
+	rl := NewRateLimiter(requestsPerMinute)
+	rl.store = store
+	return rl
+}
+
+// WithClock makes the limiter tell the minutes with a clock, e.g. a FakeClock in tests,
+// and returns it. It must be called before the limiter is used.
+func (rl *RateLimiter) WithClock(clock Clock) *RateLimiter {
+	clock = clockOrSystem(clock)
+	rl.now = clock.Now
+	rl.lastReset = clock.Now()
+	return rl
+}
+
 // AllowRequest checks if a request should be allowed based on rate limiting
 func (rl *RateLimiter) AllowRequest(clientID string) error {
+	return rl.AllowRequestContext(context.Background(), clientID)
+}
+
+// AllowRequestContext is AllowRequest with a context for the calls to the store
+func (rl *RateLimiter) AllowRequestContext(ctx context.Context, clientID string) error {
+	if rl.store != nil {
+		if err := rl.allowStored(ctx, clientID); !errors.Is(err, errRateLimitStoreFailed) {
+			return err
+		}
+	}
+
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
+	now := rl.now()
 
 	// Reset counts every minute
 	if now.Sub(rl.lastReset) >= time.Minute {
@@ -1118,11 +1338,51 @@ func (rl *RateLimiter) AllowRequest(clientID string) error {
 	return nil
 }
 
-// SecurityManager provides centralized security management
+// errRateLimitStoreFailed reports that the store failed and the request must be counted
+// locally
+var errRateLimitStoreFailed = errors.New("rate limit store failed")
+
+// allowStored counts a request in the store, taking it back when it is rejected
+func (rl *RateLimiter) allowStored(ctx context.Context, clientID string) error {
+	key := "rate_limit/" + clientID
+	window := rl.now().Truncate(time.Minute)
+
+	count, err := rl.store.Increment(ctx, key, window, 1)
+	rl.mu.Lock()
+	rl.storeErr = err
+	rl.mu.Unlock()
+	if err != nil {
+		return errRateLimitStoreFailed
+	}
+
+	if count > rl.limit {
+		// Rejected requests do not count
+		_, _ = rl.store.Increment(ctx, key, window, -1)
+		return fmt.Errorf("rate limit exceeded for client %s: %d requests/minute", clientID, count-1)
+	}
+	return nil
+}
+
+// StoreError returns the error of the last call to the store, or nil when it succeeded
+// or the limiter has no store
+func (rl *RateLimiter) StoreError() error {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.storeErr
+}
+
+// SecurityManager provides centralized security management. Besides its base
+// configuration, it can enforce named profiles, e.g. stricter rules for untrusted tenants
+// (see NewSecurityManagerWithProfiles).
 type SecurityManager struct {
 	validator   *SecurityValidator
 	rateLimiter *RateLimiter
 	config      *SecurityConfig
+
+	// Named profiles and the profiles assigned to clients
+	mu             sync.RWMutex
+	profiles       map[string]*securityPolicy
+	clientProfiles map[string]string
 }
 
 // NewSecurityManager creates a new security manager with comprehensive security controls
@@ -1132,28 +1392,30 @@ func NewSecurityManager(config *SecurityConfig) *SecurityManager {
 	}
 
 	return &SecurityManager{
-		validator:   NewSecurityValidator(config),
-		rateLimiter: NewRateLimiter(config.MaxRequestsPerMinute),
-		config:      config,
+		validator:      NewSecurityValidator(config),
+		rateLimiter:    newSecurityRateLimiter(config),
+		config:         config,
+		profiles:       make(map[string]*securityPolicy),
+		clientProfiles: make(map[string]string),
 	}
 }
 
-// ValidateRequest performs comprehensive request validation
-func (sm *SecurityManager) ValidateRequest(clientID string, messages []Message) error {
-	// Rate limiting
-	if err := sm.rateLimiter.AllowRequest(clientID); err != nil {
-		sm.validator.auditLogger.LogSecurityEvent("RATE_LIMIT_EXCEEDED", err.Error())
-		return err
+// newSecurityRateLimiter creates the rate limiter of a security configuration
+func newSecurityRateLimiter(config *SecurityConfig) *RateLimiter {
+	if config.RateLimitStore != nil {
+		return NewPersistentRateLimiter(config.MaxRequestsPerMinute, config.RateLimitStore).WithClock(config.Clock)
 	}
+	return NewRateLimiter(config.MaxRequestsPerMinute).WithClock(config.Clock)
+}
 
-	// Content security validation
-	for i, message := range messages {
-		if err := ValidateMessageSecurity(&message); err != nil {
-			return fmt.Errorf("message %d failed security validation: %w", i, err)
-		}
-	}
+// ValidateRequest performs comprehensive request validation, with the profile assigned to
+// the client (see AssignProfile) or the base configuration
+func (sm *SecurityManager) ValidateRequest(clientID string, messages []Message) error {
+	sm.mu.RLock()
+	profile := sm.clientProfiles[clientID]
+	sm.mu.RUnlock()
 
-	return nil
+	return sm.ValidateRequestWithProfile(profile, clientID, messages)
 }
 
 // GetSecurityStats returns current security statistics
@@ -0,0 +1,261 @@
+// Named security profiles of the SecurityManager, for multi-tenant services
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SecurityProfileMetadataKey is the request metadata key selecting the security profile
+// of a request (see SecurityManager.ValidateChatRequest)
+const SecurityProfileMetadataKey = "security_profile"
+
+// SecurityProfile is a named set of overrides of the content and rate limiting policies
+// of a SecurityManager, e.g. stricter rules for untrusted tenants. Nil fields inherit
+// the value of the profile it extends, or of the base configuration of the manager. The
+// resource limits and temporary files are those of the base configuration, shared by all
+// the profiles.
+type SecurityProfile struct {
+	// Extends is the name of the profile this one inherits from (the base configuration
+	// when empty)
+	Extends string `json:"extends,omitempty"`
+
+	MaxImageSize *int64 `json:"max_image_size,omitempty"`
+	MaxFileSize  *int64 `json:"max_file_size,omitempty"`
+	MaxTotalSize *int64 `json:"max_total_size,omitempty"`
+
+	// MIME types allowed: a non-nil empty list allows none
+	AllowedImageMIMEs []string `json:"allowed_image_mimes,omitempty"`
+	AllowedFileMIMEs  []string `json:"allowed_file_mimes,omitempty"`
+
+	EnableMalwareScanning *bool `json:"enable_malware_scanning,omitempty"`
+	EnablePathValidation  *bool `json:"enable_path_validation,omitempty"`
+	EnableContentScan     *bool `json:"enable_content_scan,omitempty"`
+
+	MaxRequestsPerMinute *int           `json:"max_requests_per_minute,omitempty"`
+	MaxProcessingTime    *time.Duration `json:"max_processing_time,omitempty"`
+}
+
+// apply returns a copy of a configuration with the overrides of the profile
+func (p SecurityProfile) apply(base *SecurityConfig) *SecurityConfig {
+	config := *base
+	if p.MaxImageSize != nil {
+		config.MaxImageSize = *p.MaxImageSize
+	}
+	if p.MaxFileSize != nil {
+		config.MaxFileSize = *p.MaxFileSize
+	}
+	if p.MaxTotalSize != nil {
+		config.MaxTotalSize = *p.MaxTotalSize
+	}
+	if p.AllowedImageMIMEs != nil {
+		config.AllowedImageMIMEs = append([]string(nil), p.AllowedImageMIMEs...)
+	}
+	if p.AllowedFileMIMEs != nil {
+		config.AllowedFileMIMEs = append([]string(nil), p.AllowedFileMIMEs...)
+	}
+	if p.EnableMalwareScanning != nil {
+		config.EnableMalwareScanning = *p.EnableMalwareScanning
+	}
+	if p.EnablePathValidation != nil {
+		config.EnablePathValidation = *p.EnablePathValidation
+	}
+	if p.EnableContentScan != nil {
+		config.EnableContentScan = *p.EnableContentScan
+	}
+	if p.MaxRequestsPerMinute != nil {
+		config.MaxRequestsPerMinute = *p.MaxRequestsPerMinute
+	}
+	if p.MaxProcessingTime != nil {
+		config.MaxProcessingTime = *p.MaxProcessingTime
+	}
+	return &config
+}
+
+// securityPolicy is the resolved configuration of a profile, with its validator and rate
+// limiter
+type securityPolicy struct {
+	config      *SecurityConfig
+	validator   *SecurityValidator
+	rateLimiter *RateLimiter
+}
+
+// NewSecurityManagerWithProfiles creates a security manager enforcing the base
+// configuration (DefaultSecurityConfig when nil) and named profiles inheriting from it.
+// The profiles share the resource monitor and the audit log of the manager, but each one
+// has its own rate limiter. It fails with an "invalid_config" error when a profile has
+// no name, extends an unknown profile or the inheritance has a cycle.
+func NewSecurityManagerWithProfiles(base *SecurityConfig, profiles map[string]SecurityProfile) (*SecurityManager, error) {
+	sm := NewSecurityManager(base)
+
+	resolved := make(map[string]*SecurityConfig, len(profiles))
+	var resolve func(name string, chain []string) (*SecurityConfig, error)
+	resolve = func(name string, chain []string) (*SecurityConfig, error) {
+		if config, ok := resolved[name]; ok {
+			return config, nil
+		}
+		for _, visited := range chain {
+			if visited == name {
+				return nil, profileConfigError(fmt.Sprintf("security profiles inherit in a cycle: %s", strings.Join(append(chain, name), " -> ")))
+			}
+		}
+		profile, ok := profiles[name]
+		if !ok {
+			return nil, profileConfigError(fmt.Sprintf("security profile %q extends unknown profile %q", chain[len(chain)-1], name))
+		}
+		parent := sm.config
+		if profile.Extends != "" {
+			var err error
+			if parent, err = resolve(profile.Extends, append(chain, name)); err != nil {
+				return nil, err
+			}
+		}
+		resolved[name] = profile.apply(parent)
+		return resolved[name], nil
+	}
+
+	for name := range profiles {
+		if name == "" {
+			sm.Shutdown()
+			return nil, profileConfigError("security profiles must have a name")
+		}
+		config, err := resolve(name, nil)
+		if err != nil {
+			sm.Shutdown()
+			return nil, err
+		}
+		sm.profiles[name] = &securityPolicy{
+			config: config,
+			validator: &SecurityValidator{
+				config:          config,
+				requestCounts:   make(map[string]int),
+				lastReset:       clockOrSystem(config.Clock).Now(),
+				resourceMonitor: sm.validator.resourceMonitor,
+				auditLogger:     sm.validator.auditLogger,
+			},
+			rateLimiter: newSecurityRateLimiter(config),
+		}
+	}
+	return sm, nil
+}
+
+// Profiles returns the names of the profiles of the manager, sorted
+func (sm *SecurityManager) Profiles() []string {
+	names := make([]string, 0, len(sm.profiles))
+	for name := range sm.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileConfig returns the resolved configuration of a profile, or the base
+// configuration for an empty name. It must not be modified.
+func (sm *SecurityManager) ProfileConfig(profile string) (*SecurityConfig, bool) {
+	policy, err := sm.policy(profile)
+	if err != nil {
+		return nil, false
+	}
+	return policy.config, true
+}
+
+// AssignProfile makes the requests of a client use a profile, unless they select another
+// one in their metadata. An empty profile makes the client use the base configuration
+// again. It fails with an "unknown_security_profile" error for unknown profiles.
+func (sm *SecurityManager) AssignProfile(clientID, profile string) error {
+	if _, err := sm.policy(profile); err != nil {
+		return err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if profile == "" {
+		delete(sm.clientProfiles, clientID)
+	} else {
+		sm.clientProfiles[clientID] = profile
+	}
+	return nil
+}
+
+// ProfileFor returns the profile of a request of a client: the one given by the
+// SecurityProfileMetadataKey of the metadata, else the one assigned to the client, else
+// the base configuration (an empty name). The metadata is trusted: it must be set by the
+// service, never by the tenants themselves.
+func (sm *SecurityManager) ProfileFor(clientID string, metadata map[string]any) (string, error) {
+	if value, ok := metadata[SecurityProfileMetadataKey]; ok {
+		profile, isString := value.(string)
+		if !isString {
+			return "", unknownProfileError(fmt.Sprint(value))
+		}
+		if _, err := sm.policy(profile); err != nil {
+			return "", err
+		}
+		return profile, nil
+	}
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.clientProfiles[clientID], nil
+}
+
+// ValidateChatRequest validates the messages of a request with the profile selected by
+// its metadata or assigned to the client (see ProfileFor)
+func (sm *SecurityManager) ValidateChatRequest(clientID string, req ChatRequest) error {
+	profile, err := sm.ProfileFor(clientID, req.Metadata)
+	if err != nil {
+		return err
+	}
+	return sm.ValidateRequestWithProfile(profile, clientID, req.Messages)
+}
+
+// ValidateRequestWithProfile validates a request with the rate limit and content policies
+// of a profile, or of the base configuration for an empty name. It fails with an
+// "unknown_security_profile" error for unknown profiles, so requests never fall back to
+// looser rules.
+func (sm *SecurityManager) ValidateRequestWithProfile(profile, clientID string, messages []Message) error {
+	policy, err := sm.policy(profile)
+	if err != nil {
+		return err
+	}
+
+	// Rate limiting
+	if err := policy.rateLimiter.AllowRequest(clientID); err != nil {
+		policy.validator.auditLogger.LogSecurityEvent("RATE_LIMIT_EXCEEDED", err.Error())
+		return err
+	}
+
+	// Content security validation
+	for i, message := range messages {
+		if err := policy.validator.ValidateMessage(&message); err != nil {
+			return fmt.Errorf("message %d failed security validation: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// policy returns the policy of a profile, or of the base configuration for an empty name
+func (sm *SecurityManager) policy(profile string) (*securityPolicy, error) {
+	if profile == "" {
+		return &securityPolicy{config: sm.config, validator: sm.validator, rateLimiter: sm.rateLimiter}, nil
+	}
+	policy, ok := sm.profiles[profile]
+	if !ok {
+		return nil, unknownProfileError(profile)
+	}
+	return policy, nil
+}
+
+// profileConfigError creates an invalid security profile configuration error
+func profileConfigError(message string) *Error {
+	return &Error{Code: "invalid_config", Message: message, Type: "validation_error"}
+}
+
+// unknownProfileError creates the error of requests selecting an unknown profile
+func unknownProfileError(profile string) *Error {
+	return &Error{
+		Code:    "unknown_security_profile",
+		Message: fmt.Sprintf("unknown security profile %q", profile),
+		Type:    "validation_error",
+	}
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityManagerProfiles(t *testing.T) {
+	limit, batchLimit := 1, 2
+	noScan := false
+	manager, err := NewSecurityManagerWithProfiles(DefaultSecurityConfig(), map[string]SecurityProfile{
+		"untrusted": {
+			MaxRequestsPerMinute: &limit,
+			AllowedImageMIMEs:    []string{},
+		},
+		"untrusted-batch": {
+			Extends:              "untrusted",
+			MaxRequestsPerMinute: &batchLimit,
+			EnableContentScan:    &noScan,
+		},
+	})
+	require.NoError(t, err)
+	defer manager.Shutdown()
+
+	assert.Equal(t, []string{"untrusted", "untrusted-batch"}, manager.Profiles())
+	config, ok := manager.ProfileConfig("untrusted-batch")
+	require.True(t, ok)
+	assert.Equal(t, 2, config.MaxRequestsPerMinute)
+	assert.Empty(t, config.AllowedImageMIMEs, "inherited from untrusted")
+	assert.False(t, config.EnableContentScan)
+	assert.True(t, config.EnableMalwareScanning, "inherited from the base configuration")
+
+	image := Message{Role: RoleUser, Content: []MessageContent{NewImageContentFromBytes(testImagePNG, "image/png")}}
+	text := Message{Role: RoleUser, Content: []MessageContent{NewTextContent("Hello")}}
+
+	// The base configuration allows PNG images, the untrusted profile no image
+	require.NoError(t, manager.ValidateRequest("tenant-a", []Message{image}))
+	require.NoError(t, manager.AssignProfile("tenant-a", "untrusted"))
+	assert.Error(t, manager.ValidateRequest("tenant-a", []Message{image}))
+	assert.Error(t, manager.ValidateRequest("tenant-a", []Message{text}), "1 request per minute")
+
+	// The metadata selects the profile of a request
+	req := ChatRequest{Messages: []Message{text}, Metadata: map[string]any{SecurityProfileMetadataKey: "untrusted-batch"}}
+	require.NoError(t, manager.ValidateChatRequest("tenant-a", req))
+	require.NoError(t, manager.ValidateChatRequest("tenant-a", req))
+	assert.Error(t, manager.ValidateChatRequest("tenant-a", req), "2 requests per minute")
+
+	var llmErr *Error
+	req.Metadata[SecurityProfileMetadataKey] = "unknown"
+	err = manager.ValidateChatRequest("tenant-a", req)
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "unknown_security_profile", llmErr.Code)
+	err = manager.AssignProfile("tenant-b", "unknown")
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "unknown_security_profile", llmErr.Code)
+
+	// Clients without a profile use the base configuration
+	require.NoError(t, manager.AssignProfile("tenant-a", ""))
+	require.NoError(t, manager.ValidateRequest("tenant-a", []Message{image}))
+	assert.Equal(t, 2, manager.GetSecurityStats().RateLimitHits, "hits of all the profiles")
+}
+
+func TestSecurityManagerProfilesInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string]SecurityProfile
+		message  string
+	}{
+		{"unknown parent", map[string]SecurityProfile{"a": {Extends: "b"}}, `extends unknown profile "b"`},
+		{"cycle", map[string]SecurityProfile{"a": {Extends: "b"}, "b": {Extends: "a"}}, "cycle"},
+		{"no name", map[string]SecurityProfile{"": {}}, "must have a name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSecurityManagerWithProfiles(nil, tt.profiles)
+			var llmErr *Error
+			require.True(t, errors.As(err, &llmErr))
+			assert.Equal(t, "invalid_config", llmErr.Code)
+			assert.Contains(t, llmErr.Message, tt.message)
+		})
+	}
+}
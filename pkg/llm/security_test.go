@@ -3,6 +3,8 @@ package llm
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -622,7 +624,8 @@ func TestSecurityValidator_SizeValidation(t *testing.T) {
 }
 
 func TestRateLimiter(t *testing.T) {
-	limiter := NewRateLimiter(3) // 3 requests per minute
+	clock := NewFakeClock(time.Now())
+	limiter := NewRateLimiter(3).WithClock(clock) // 3 requests per minute
 
 	clientID := "test-client"
 
@@ -641,7 +644,11 @@ func TestRateLimiter(t *testing.T) {
 	}
 
 	// Test reset after time passes
-	limiter.lastReset = time.Now().Add(-2 * time.Minute) // Simulate time passage
+	clock.Advance(59 * time.Second)
+	if err := limiter.AllowRequest(clientID); err == nil {
+		t.Error("Request should still be rate limited within the minute")
+	}
+	clock.Advance(time.Second)
 	err = limiter.AllowRequest(clientID)
 	if err != nil {
 		t.Errorf("Request should be allowed after reset: %v", err)
@@ -656,6 +663,7 @@ func TestResourceMonitor(t *testing.T) {
 
 	monitor := NewResourceMonitor(config)
 	defer monitor.Shutdown()
+	tempDir := t.TempDir()
 
 	// Test memory tracking
 	err := monitor.TrackMemoryUsage(512)
@@ -674,7 +682,7 @@ func TestResourceMonitor(t *testing.T) {
 	}
 
 	// Test file registration
-	monitor.RegisterTemporaryFile("/tmp/test.txt")
+	monitor.RegisterTemporaryFile(filepath.Join(tempDir, "test.txt"))
 
 	stats := monitor.GetResourceStats()
 	if stats.TemporaryFiles != 1 {
@@ -683,7 +691,7 @@ func TestResourceMonitor(t *testing.T) {
 
 	// Test cleanup (files older than 1 hour)
 	monitor.mu.Lock()
-	monitor.temporaryFiles["/tmp/old.txt"] = time.Now().Add(-2 * time.Hour)
+	monitor.temporaryFiles[filepath.Join(tempDir, "old.txt")] = time.Now().Add(-2 * time.Hour)
 	monitor.mu.Unlock()
 
 	cleaned := monitor.CleanupExpiredFiles()
@@ -692,6 +700,158 @@ func TestResourceMonitor(t *testing.T) {
 	}
 }
 
+func TestResourceMonitor_ManagedTempFiles(t *testing.T) {
+	config := &SecurityConfig{
+		MaxMemoryUsage: 1024,
+		TempDir:        t.TempDir(),
+		MaxTempStorage: 10,
+	}
+
+	monitor := NewResourceMonitor(config)
+	defer monitor.Shutdown()
+
+	path, err := monitor.NewManagedTempFile([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Failed to create managed temp file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Expected file content 'hello', got %q (err: %v)", data, err)
+	}
+	if filepath.Dir(path) != config.TempDir {
+		t.Errorf("Expected file in %s, got %s", config.TempDir, path)
+	}
+
+	stats := monitor.GetResourceStats()
+	if stats.TemporaryFiles != 1 || stats.TemporaryBytes != 5 {
+		t.Errorf("Expected 1 file / 5 bytes, got %d / %d", stats.TemporaryFiles, stats.TemporaryBytes)
+	}
+
+	// Quota enforcement
+	if _, err := monitor.NewManagedTempFile([]byte("too much data")); err == nil {
+		t.Error("Should reject temp file exceeding storage quota")
+	}
+
+	// Explicit removal frees the quota
+	if err := monitor.RemoveTemporaryFile(path); err != nil {
+		t.Fatalf("Failed to remove temp file: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected temp file to be deleted")
+	}
+	if stats := monitor.GetResourceStats(); stats.TemporaryBytes != 0 {
+		t.Errorf("Expected 0 tracked bytes, got %d", stats.TemporaryBytes)
+	}
+	if err := monitor.RemoveTemporaryFile(path); err == nil {
+		t.Error("Should fail removing an untracked file")
+	}
+}
+
+func TestResourceMonitor_CleanupDeletesFiles(t *testing.T) {
+	config := &SecurityConfig{MaxMemoryUsage: 1024, TempDir: t.TempDir(), TempFileTTL: time.Minute}
+	monitor := NewResourceMonitor(config)
+
+	expired, err := monitor.NewManagedTempFile([]byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := monitor.NewManagedTempFile([]byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor.mu.Lock()
+	monitor.temporaryFiles[expired] = time.Now().Add(-2 * time.Minute)
+	monitor.mu.Unlock()
+
+	if cleaned := monitor.CleanupExpiredFiles(); cleaned != 1 {
+		t.Errorf("Expected to clean 1 file, cleaned %d", cleaned)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("Expected expired file to be deleted from disk")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("Fresh file should still exist: %v", err)
+	}
+
+	// Shutdown removes everything that is still tracked
+	monitor.Shutdown()
+	monitor.Shutdown() // must be safe to call twice
+	if _, err := os.Stat(fresh); !os.IsNotExist(err) {
+		t.Error("Expected Shutdown to delete remaining temp files")
+	}
+}
+
+func TestResourceMonitor_KeepsRegisteredFiles(t *testing.T) {
+	config := &SecurityConfig{MaxMemoryUsage: 1024, TempDir: t.TempDir(), TempFileTTL: time.Minute}
+	monitor := NewResourceMonitor(config)
+
+	registered := filepath.Join(t.TempDir(), "caller-owned.txt")
+	if err := os.WriteFile(registered, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	monitor.RegisterTemporaryFile(registered)
+	monitor.mu.Lock()
+	monitor.temporaryFiles[registered] = time.Now().Add(-2 * time.Minute)
+	monitor.mu.Unlock()
+
+	if cleaned := monitor.CleanupExpiredFiles(); cleaned != 1 {
+		t.Errorf("Expected to release 1 file, released %d", cleaned)
+	}
+	if stats := monitor.GetResourceStats(); stats.TemporaryFiles != 0 {
+		t.Errorf("Expected the expired file to be untracked, got %d files", stats.TemporaryFiles)
+	}
+
+	monitor.RegisterTemporaryFile(registered)
+	monitor.Shutdown()
+	if _, err := os.Stat(registered); err != nil {
+		t.Errorf("Registered file should never be deleted by the monitor: %v", err)
+	}
+}
+
+func TestResourceMonitor_RemovesOrphansOnRestart(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &SecurityConfig{MaxMemoryUsage: 1024, TempDir: tempDir, TempFileTTL: time.Minute}
+
+	// Simulate a crashed process: files are created but Shutdown is never called
+	crashed := NewResourceMonitor(config)
+	orphan, err := crashed.NewManagedTempFile([]byte("orphan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent, err := crashed.NewManagedTempFile([]byte("recent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(orphan, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unrelated files in the same directory must never be touched
+	unrelated := filepath.Join(tempDir, "user-file.txt")
+	if err := os.WriteFile(unrelated, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(unrelated, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewResourceMonitor(config)
+	defer restarted.Shutdown()
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Expected orphaned file older than the TTL to be removed on restart")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("Files younger than the TTL should be kept: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("Unrelated files should be kept: %v", err)
+	}
+}
+
 func TestSecurityAuditLogger(t *testing.T) {
 	logger := NewSecurityAuditLogger()
 
@@ -1124,8 +1284,9 @@ func TestSecurityValidator_FailureScenarios(t *testing.T) {
 	monitor := validator.resourceMonitor
 
 	// Simulate resource leak
+	tempDir := t.TempDir()
 	for i := 0; i < 10; i++ {
-		monitor.RegisterTemporaryFile(fmt.Sprintf("/tmp/file%d.tmp", i))
+		monitor.RegisterTemporaryFile(filepath.Join(tempDir, fmt.Sprintf("file%d.tmp", i)))
 	}
 
 	// Force cleanup
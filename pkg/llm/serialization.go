@@ -135,18 +135,24 @@ func EstimateSerializedSize(message Message, format SerializationFormat) int64 {
 func serializeMessageEnhanced(message Message) ([]byte, error) {
 	// Create enhanced message structure
 	enhanced := struct {
-		Role       MessageRole       `json:"role"`
-		Content    []json.RawMessage `json:"content"`
-		ToolCalls  []ToolCall        `json:"tool_calls,omitempty"`
-		ToolCallID string            `json:"tool_call_id,omitempty"`
-		Metadata   map[string]any    `json:"metadata,omitempty"`
-		Version    string            `json:"version"`
+		Role             MessageRole       `json:"role"`
+		Content          []json.RawMessage `json:"content"`
+		ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
+		ToolCallID       string            `json:"tool_call_id,omitempty"`
+		Metadata         map[string]any    `json:"metadata,omitempty"`
+		Citations        []Citation        `json:"citations,omitempty"`
+		Reasoning        string            `json:"reasoning,omitempty"`
+		BuiltinToolCalls []BuiltinToolCall `json:"builtin_tool_calls,omitempty"`
+		Version          string            `json:"version"`
 	}{
-		Role:       message.Role,
-		ToolCalls:  message.ToolCalls,
-		ToolCallID: message.ToolCallID,
-		Metadata:   message.Metadata,
-		Version:    CurrentSerializationVersion,
+		Role:             message.Role,
+		ToolCalls:        message.ToolCalls,
+		ToolCallID:       message.ToolCallID,
+		Metadata:         message.Metadata,
+		Citations:        message.Citations,
+		Reasoning:        message.Reasoning,
+		BuiltinToolCalls: message.BuiltinToolCalls,
+		Version:          CurrentSerializationVersion,
 	}
 
 	// Serialize each content item with enhanced format
@@ -238,12 +244,15 @@ func deserializeMessageEnhanced(data []byte, message *Message) error {
 
 	// Parse as enhanced format
 	var enhanced struct {
-		Role       MessageRole       `json:"role"`
-		Content    []json.RawMessage `json:"content"`
-		ToolCalls  []ToolCall        `json:"tool_calls,omitempty"`
-		ToolCallID string            `json:"tool_call_id,omitempty"`
-		Metadata   map[string]any    `json:"metadata,omitempty"`
-		Version    string            `json:"version"`
+		Role             MessageRole       `json:"role"`
+		Content          []json.RawMessage `json:"content"`
+		ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
+		ToolCallID       string            `json:"tool_call_id,omitempty"`
+		Metadata         map[string]any    `json:"metadata,omitempty"`
+		Citations        []Citation        `json:"citations,omitempty"`
+		Reasoning        string            `json:"reasoning,omitempty"`
+		BuiltinToolCalls []BuiltinToolCall `json:"builtin_tool_calls,omitempty"`
+		Version          string            `json:"version"`
 	}
 
 	if err := json.Unmarshal(data, &enhanced); err != nil {
@@ -255,6 +264,9 @@ func deserializeMessageEnhanced(data []byte, message *Message) error {
 	message.ToolCalls = enhanced.ToolCalls
 	message.ToolCallID = enhanced.ToolCallID
 	message.Metadata = enhanced.Metadata
+	message.Citations = enhanced.Citations
+	message.Reasoning = enhanced.Reasoning
+	message.BuiltinToolCalls = enhanced.BuiltinToolCalls
 
 	// Process enhanced content items
 	if len(enhanced.Content) > 0 {
@@ -359,12 +371,18 @@ func serializeMessageCompact(message Message) ([]byte, error) {
 		T []ToolCall        `json:"t,omitempty"` // Tool calls (shortened)
 		I string            `json:"i,omitempty"` // Tool call ID (shortened)
 		M map[string]any    `json:"m,omitempty"` // Metadata (shortened)
+		S []Citation        `json:"s,omitempty"` // Citations (sources)
+		Z string            `json:"z,omitempty"` // Reasoning
+		B []BuiltinToolCall `json:"b,omitempty"` // Builtin tool calls
 		V string            `json:"v"`           // Version
 	}{
 		R: string(message.Role),
 		T: message.ToolCalls,
 		I: message.ToolCallID,
 		M: message.Metadata,
+		S: message.Citations,
+		Z: message.Reasoning,
+		B: message.BuiltinToolCalls,
 		V: CurrentSerializationVersion,
 	}
 
@@ -471,20 +489,26 @@ func SerializeMessageWithOptions(message Message, options SerializationOptions)
 // serializeMessageWithEnhancedOptions serializes with enhanced options
 func serializeMessageWithEnhancedOptions(message Message, options SerializationOptions) ([]byte, error) {
 	enhanced := struct {
-		Role       MessageRole          `json:"role"`
-		Content    []json.RawMessage    `json:"content"`
-		ToolCalls  []ToolCall           `json:"tool_calls,omitempty"`
-		ToolCallID string               `json:"tool_call_id,omitempty"`
-		Metadata   map[string]any       `json:"metadata,omitempty"`
-		Version    string               `json:"version"`
-		Options    SerializationOptions `json:"options,omitempty"`
+		Role             MessageRole          `json:"role"`
+		Content          []json.RawMessage    `json:"content"`
+		ToolCalls        []ToolCall           `json:"tool_calls,omitempty"`
+		ToolCallID       string               `json:"tool_call_id,omitempty"`
+		Metadata         map[string]any       `json:"metadata,omitempty"`
+		Citations        []Citation           `json:"citations,omitempty"`
+		Reasoning        string               `json:"reasoning,omitempty"`
+		BuiltinToolCalls []BuiltinToolCall    `json:"builtin_tool_calls,omitempty"`
+		Version          string               `json:"version"`
+		Options          SerializationOptions `json:"options,omitempty"`
 	}{
-		Role:       message.Role,
-		ToolCalls:  message.ToolCalls,
-		ToolCallID: message.ToolCallID,
-		Metadata:   message.Metadata,
-		Version:    CurrentSerializationVersion,
-		Options:    options,
+		Role:             message.Role,
+		ToolCalls:        message.ToolCalls,
+		ToolCallID:       message.ToolCallID,
+		Metadata:         message.Metadata,
+		Citations:        message.Citations,
+		Reasoning:        message.Reasoning,
+		BuiltinToolCalls: message.BuiltinToolCalls,
+		Version:          CurrentSerializationVersion,
+		Options:          options,
 	}
 
 	// Process content with options
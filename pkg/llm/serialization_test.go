@@ -169,6 +169,40 @@ func TestDeserializeMessage_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestDeserializeMessage_Citations(t *testing.T) {
+	original := Message{
+		Role:    RoleAssistant,
+		Content: []MessageContent{NewTextContent("Go was released in 2009.")},
+		Citations: []Citation{
+			{URL: "https://go.dev/doc/faq", Title: "Go FAQ", StartIndex: 0, EndIndex: 24, Text: "Go was released in 2009."},
+			{URL: "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+	}
+
+	for _, format := range []SerializationFormat{SerializationFormatStandard, SerializationFormatEnhanced} {
+		serialized, err := SerializeMessage(original, format)
+		if err != nil {
+			t.Fatalf("Failed to serialize %s: %v", format, err)
+		}
+		deserialized, err := DeserializeMessage(serialized)
+		if err != nil {
+			t.Fatalf("Failed to deserialize %s: %v", format, err)
+		}
+		if !reflect.DeepEqual(deserialized.Citations, original.Citations) {
+			t.Errorf("Citations mismatch for %s: %+v", format, deserialized.Citations)
+		}
+	}
+
+	copied := original.DeepCopy()
+	copied.Citations[0].Title = "changed"
+	if original.Citations[0].Title != "Go FAQ" {
+		t.Error("Expected the copy citations to be independent")
+	}
+	if !original.Citations[0].HasSpan() || original.Citations[1].HasSpan() {
+		t.Error("Unexpected HasSpan results")
+	}
+}
+
 func TestEstimateSerializedSize(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,127 @@
+// Text-to-speech interfaces
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// SpeechClient converts text to speech (OpenAI, Gemini)
+type SpeechClient interface {
+	// Speak returns the whole recording of the text
+	Speak(ctx context.Context, text string, opts SpeechOptions) (*AudioContent, error)
+
+	// StreamSpeech returns the recording of the text in chunks, as soon as the provider
+	// produces them, for low-latency playback. The channel is closed after the last
+	// chunk; a failure is reported by a last chunk with an error.
+	StreamSpeech(ctx context.Context, text string, opts SpeechOptions) (<-chan SpeechChunk, error)
+}
+
+// AudioFormat is the encoding of generated speech
+type AudioFormat string
+
+const (
+	AudioFormatMP3  AudioFormat = "mp3"
+	AudioFormatOpus AudioFormat = "opus"
+	AudioFormatAAC  AudioFormat = "aac"
+	AudioFormatFLAC AudioFormat = "flac"
+	AudioFormatWAV  AudioFormat = "wav"
+
+	// AudioFormatPCM is raw signed 16-bit little-endian mono samples, at the sample rate of
+	// the provider (24kHz for OpenAI and Gemini), the lowest-latency format for streaming
+	AudioFormatPCM AudioFormat = "pcm"
+)
+
+// MimeType returns the MIME type of the format
+func (f AudioFormat) MimeType() string {
+	switch f {
+	case AudioFormatMP3:
+		return "audio/mpeg"
+	case AudioFormatOpus:
+		return "audio/ogg"
+	case AudioFormatAAC:
+		return "audio/aac"
+	case AudioFormatFLAC:
+		return "audio/flac"
+	case AudioFormatWAV:
+		return "audio/wav"
+	case AudioFormatPCM:
+		return "audio/pcm"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// SpeechOptions configures the generated speech. Zero values let the provider choose.
+type SpeechOptions struct {
+	// Model is the speech model (provider default when empty)
+	Model string `json:"model,omitempty"`
+
+	// Voice is the name of a voice of the provider (provider default when empty)
+	Voice string `json:"voice,omitempty"`
+
+	// Format is the encoding of the audio (provider default when empty). Providers fail
+	// with an "unsupported_audio_format" error for formats they cannot produce.
+	Format AudioFormat `json:"format,omitempty"`
+
+	// Speed scales the speaking rate (1 is the normal rate), when supported
+	Speed float64 `json:"speed,omitempty"`
+
+	// Instructions tell the tone, accent or emotion of the speech, when supported
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// SpeechChunk is a part of streamed speech
+type SpeechChunk struct {
+	// Data is the next part of the encoded audio
+	Data []byte `json:"-"`
+
+	// Error is set on the last chunk of a failed stream
+	Error *Error `json:"error,omitempty"`
+}
+
+// CollectSpeech reads a speech stream to the end, returning the whole recording or the
+// error of the stream
+func CollectSpeech(chunks <-chan SpeechChunk, format AudioFormat) (*AudioContent, error) {
+	var data bytes.Buffer
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			return nil, chunk.Error
+		}
+		data.Write(chunk.Data)
+	}
+	return &AudioContent{Data: data.Bytes(), MimeType: format.MimeType()}, nil
+}
+
+// PCMToWAV wraps raw signed 16-bit little-endian PCM samples in a WAV container
+func PCMToWAV(pcm []byte, sampleRate, channels int) []byte {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+
+	wav := make([]byte, 44, 44+len(pcm))
+	copy(wav[0:], "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:], uint32(36+len(pcm)))
+	copy(wav[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(wav[16:], 16) // size of the fmt chunk
+	binary.LittleEndian.PutUint16(wav[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(wav[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(wav[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(wav[28:], uint32(sampleRate*blockAlign)) // bytes per second
+	binary.LittleEndian.PutUint16(wav[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(wav[34:], bitsPerSample)
+	copy(wav[36:], "data")
+	binary.LittleEndian.PutUint32(wav[40:], uint32(len(pcm)))
+	return append(wav, pcm...)
+}
+
+// UnsupportedAudioFormatError creates the error of providers for the formats they cannot
+// produce
+func UnsupportedAudioFormatError(provider string, format AudioFormat) *Error {
+	return &Error{
+		Code:    "unsupported_audio_format",
+		Message: fmt.Sprintf("%s cannot generate speech in the %q format", provider, format),
+		Type:    "validation_error",
+	}
+}
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPCMToWAV(t *testing.T) {
+	wav := PCMToWAV([]byte{1, 2, 3, 4}, 24000, 1)
+
+	require.Len(t, wav, 48)
+	assert.Equal(t, "RIFF", string(wav[0:4]))
+	assert.Equal(t, uint32(40), binary.LittleEndian.Uint32(wav[4:]))
+	assert.Equal(t, "WAVEfmt ", string(wav[8:16]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(wav[22:]))
+	assert.Equal(t, uint32(24000), binary.LittleEndian.Uint32(wav[24:]))
+	assert.Equal(t, uint32(48000), binary.LittleEndian.Uint32(wav[28:]))
+	assert.Equal(t, uint16(16), binary.LittleEndian.Uint16(wav[34:]))
+	assert.Equal(t, "data", string(wav[36:40]))
+	assert.Equal(t, uint32(4), binary.LittleEndian.Uint32(wav[40:]))
+	assert.Equal(t, []byte{1, 2, 3, 4}, wav[44:])
+}
+
+func TestCollectSpeech(t *testing.T) {
+	chunks := make(chan SpeechChunk, 3)
+	chunks <- SpeechChunk{Data: []byte("ab")}
+	chunks <- SpeechChunk{Data: []byte("cd")}
+	close(chunks)
+
+	audio, err := CollectSpeech(chunks, AudioFormatMP3)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abcd"), audio.Data)
+	assert.Equal(t, "audio/mpeg", audio.MimeType)
+
+	chunks = make(chan SpeechChunk, 2)
+	chunks <- SpeechChunk{Data: []byte("ab")}
+	chunks <- SpeechChunk{Error: &Error{Code: "api_error", Message: "connection lost"}}
+	close(chunks)
+
+	_, err = CollectSpeech(chunks, AudioFormatMP3)
+	assert.ErrorContains(t, err, "connection lost")
+}
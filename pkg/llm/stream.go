@@ -3,12 +3,37 @@
 
 package llm
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // StreamEvent represents a single event in the streaming response
 type StreamEvent struct {
 	Type       string        `json:"type"` // "delta", "done", "error", "tool_result"
 	Choice     *StreamChoice `json:"choice,omitempty"`
 	Error      *Error        `json:"error,omitempty"`
 	ToolResult *ToolResult   `json:"tool_result,omitempty"`
+
+	// ErrorInfo classifies the error of error events (retryable, before or after the
+	// first token), see ClassifyStream
+	ErrorInfo *StreamErrorInfo `json:"error_info,omitempty"`
+
+	// Seq is the position of the event in its stream, starting at 1 and increasing by one
+	// for every event (see StreamSequencer). Zero means the event was not sequenced.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Timestamp is the time the event was produced
+	Timestamp time.Time `json:"timestamp,omitzero"`
+
+	// Source identifies the stream the event comes from when streams are merged
+	// (e.g. "llm" or "tool:0")
+	Source string `json:"source,omitempty"`
+
+	// Raw is the last provider-native chunk of done events, with the trailing metadata
+	// of the stream (e.g. the usage or timings), when the provider client keeps it. It is
+	// not serialized.
+	Raw RawResponse `json:"-"`
 }
 
 // ToolResult represents tool execution data in streaming responses
@@ -43,13 +68,44 @@ type ToolExecutionError struct {
 type StreamChoice struct {
 	Index        int           `json:"index"`
 	Delta        *MessageDelta `json:"delta,omitempty"`
-	FinishReason string        `json:"finish_reason,omitempty"`
+	FinishReason FinishReason  `json:"finish_reason,omitempty"`
+
+	// RawFinishReason is the finish reason as reported by the provider
+	RawFinishReason string `json:"raw_finish_reason,omitempty"`
 }
 
 // MessageDelta represents incremental updates to a message
 type MessageDelta struct {
 	Content   []MessageContent `json:"content,omitempty"`
 	ToolCalls []ToolCallDelta  `json:"tool_calls,omitempty"`
+
+	// Reasoning is a fragment of the reasoning of the model (see Message.Reasoning)
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for MessageDelta, decoding the
+// content items by their type
+func (d *MessageDelta) UnmarshalJSON(data []byte) error {
+	type Alias MessageDelta
+	temp := struct {
+		*Alias
+		Content []json.RawMessage `json:"content"`
+	}{
+		Alias: (*Alias)(d),
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+
+	d.Content = nil
+	if len(temp.Content) > 0 {
+		content, err := unmarshalContents(temp.Content)
+		if err != nil {
+			return err
+		}
+		d.Content = content
+	}
+	return nil
 }
 
 // ToolCallDelta represents an incremental tool call update
@@ -123,7 +179,7 @@ func NewDeltaEvent(index int, delta *MessageDelta) StreamEvent {
 }
 
 // NewDoneEvent creates a new done stream event
-func NewDoneEvent(index int, finishReason string) StreamEvent {
+func NewDoneEvent(index int, finishReason FinishReason) StreamEvent {
 	return StreamEvent{
 		Type: "done",
 		Choice: &StreamChoice{
@@ -133,6 +189,14 @@ func NewDoneEvent(index int, finishReason string) StreamEvent {
 	}
 }
 
+// NewProviderDoneEvent creates a done stream event from the raw finish reason reported
+// by a provider, normalizing it (see NormalizeFinishReason)
+func NewProviderDoneEvent(index int, provider, rawFinishReason string) StreamEvent {
+	event := NewDoneEvent(index, NormalizeFinishReason(provider, rawFinishReason))
+	event.Choice.RawFinishReason = rawFinishReason
+	return event
+}
+
 // NewErrorEvent creates a new error stream event
 func NewErrorEvent(err *Error) StreamEvent {
 	return StreamEvent{
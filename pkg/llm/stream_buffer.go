@@ -0,0 +1,176 @@
+// Buffering of provider streams and back-pressure from slow consumers
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultStreamBufferSize is the number of events buffered by provider streams when the
+// size is not configured
+const DefaultStreamBufferSize = 10
+
+// StreamBackpressure is the policy of provider streams when their buffer is full because
+// the consumer reads events slower than the provider produces them
+type StreamBackpressure string
+
+const (
+	// StreamBackpressureBlock waits for the consumer (default). No event is lost, but a
+	// stalled consumer stalls the connection to the provider until the context is done.
+	StreamBackpressureBlock StreamBackpressure = "block"
+
+	// StreamBackpressureDropOldest discards the oldest buffered event to make room for the
+	// new one, so the provider is never blocked. Consumers may miss deltas, but always get
+	// the terminal event; use it for live displays where only recent events matter.
+	StreamBackpressureDropOldest StreamBackpressure = "drop_oldest"
+
+	// StreamBackpressureError fails the stream with a "stream_overflow" error event, which
+	// replaces the oldest buffered event, and closes the connection to the provider at once
+	StreamBackpressureError StreamBackpressure = "error"
+)
+
+// StreamBufferConfig configures the buffer of the streams of a client (see
+// ClientConfig.StreamBuffer)
+type StreamBufferConfig struct {
+	// Size is the number of events buffered between the provider and the consumer
+	// (DefaultStreamBufferSize when zero)
+	Size int `json:"size,omitempty"`
+
+	// Backpressure is the policy when the buffer is full (StreamBackpressureBlock when empty)
+	Backpressure StreamBackpressure `json:"backpressure,omitempty"`
+}
+
+// Validate checks the configuration
+func (c StreamBufferConfig) Validate() error {
+	if c.Size < 0 {
+		return &Error{Code: "invalid_config", Message: fmt.Sprintf("invalid stream buffer size %d", c.Size), Type: "validation_error"}
+	}
+	switch c.Backpressure {
+	case "", StreamBackpressureBlock, StreamBackpressureDropOldest, StreamBackpressureError:
+		return nil
+	default:
+		return &Error{Code: "invalid_config", Message: fmt.Sprintf("unknown stream backpressure policy %q", c.Backpressure), Type: "validation_error"}
+	}
+}
+
+// StreamEmitter sends the events of a provider stream to its consumer through a buffered
+// channel, applying the back-pressure policy of the client. Providers create one per
+// stream, call Emit from a single goroutine and Close when done:
+//
+//	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+//	go func() {
+//		defer emitter.Close()
+//		for chunk := range chunks {
+//			if !emitter.Emit(toEvent(chunk)) {
+//				return // context done or overflow: stop reading from the provider
+//			}
+//		}
+//	}()
+//	return emitter.Events(), nil
+type StreamEmitter struct {
+	ctx    context.Context
+	policy StreamBackpressure
+	ch     chan StreamEvent
+
+	stopped   bool
+	dropped   atomic.Int64
+	closeOnce sync.Once
+}
+
+// NewStreamEmitter creates the emitter of a stream, which stops when ctx is done
+func NewStreamEmitter(ctx context.Context, config StreamBufferConfig) *StreamEmitter {
+	size := config.Size
+	if size <= 0 {
+		size = DefaultStreamBufferSize
+	}
+	policy := config.Backpressure
+	if policy == "" {
+		policy = StreamBackpressureBlock
+	}
+	return &StreamEmitter{ctx: ctx, policy: policy, ch: make(chan StreamEvent, size)}
+}
+
+// Events returns the channel of the consumer, closed by Close
+func (e *StreamEmitter) Events() <-chan StreamEvent {
+	return e.ch
+}
+
+// Emit sends an event, returning false when the producer must stop: the context is done,
+// or the buffer overflowed with StreamBackpressureError. Once the context is done, events
+// are only sent if there is room in the buffer, so the error event of a canceled stream
+// reaches consumers that are still reading. Terminal events are never dropped, as they
+// are the last events of the stream.
+func (e *StreamEmitter) Emit(event StreamEvent) bool {
+	if e.stopped {
+		return false
+	}
+	if e.ctx.Err() != nil {
+		e.stopped = true
+		select {
+		case e.ch <- event:
+		default:
+		}
+		return false
+	}
+
+	// Fast path: room in the buffer
+	select {
+	case e.ch <- event:
+		return true
+	default:
+	}
+
+	switch e.policy {
+	case StreamBackpressureDropOldest:
+		e.replaceOldest(event)
+		return true
+
+	case StreamBackpressureError:
+		e.stopped = true
+		overflow := NewErrorEvent(&Error{
+			Code:    "stream_overflow",
+			Message: fmt.Sprintf("stream buffer of %d events overflowed: the consumer is too slow", cap(e.ch)),
+			Type:    "stream_error",
+		})
+		overflow.Seq = event.Seq
+		e.replaceOldest(overflow)
+		return false
+
+	default:
+		select {
+		case e.ch <- event:
+			return true
+		case <-e.ctx.Done():
+			e.stopped = true
+			return false
+		}
+	}
+}
+
+// replaceOldest sends an event, discarding the oldest buffered events until there is room
+func (e *StreamEmitter) replaceOldest(event StreamEvent) {
+	for {
+		select {
+		case e.ch <- event:
+			return
+		default:
+		}
+		select {
+		case <-e.ch:
+			e.dropped.Add(1)
+		default: // The consumer read an event meanwhile
+		}
+	}
+}
+
+// Dropped returns the number of events discarded to make room for newer ones
+func (e *StreamEmitter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Close closes the channel of the consumer. It is safe to call more than once.
+func (e *StreamEmitter) Close() {
+	e.closeOnce.Do(func() { close(e.ch) })
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textEvent(seq uint64, text string) StreamEvent {
+	event := NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(text)}})
+	event.Seq = seq
+	return event
+}
+
+func drainEvents(ch <-chan StreamEvent) []StreamEvent {
+	var events []StreamEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestStreamEmitter_Block(t *testing.T) {
+	emitter := NewStreamEmitter(context.Background(), StreamBufferConfig{Size: 1})
+	require.True(t, emitter.Emit(textEvent(1, "a")))
+
+	// The second event waits for the consumer
+	sent := make(chan bool)
+	go func() { sent <- emitter.Emit(textEvent(2, "b")) }()
+	select {
+	case <-sent:
+		t.Fatal("Expected Emit to block while the buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(1), (<-emitter.Events()).Seq)
+	assert.True(t, <-sent)
+	emitter.Close()
+	emitter.Close()
+
+	events := drainEvents(emitter.Events())
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(2), events[0].Seq)
+}
+
+func TestStreamEmitter_BlockCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	emitter := NewStreamEmitter(ctx, StreamBufferConfig{Size: 1})
+	require.True(t, emitter.Emit(textEvent(1, "a")))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	assert.False(t, emitter.Emit(textEvent(2, "b")))
+	assert.False(t, emitter.Emit(textEvent(3, "c")), "Expected the emitter to stay stopped")
+	emitter.Close()
+	assert.Len(t, drainEvents(emitter.Events()), 1)
+
+	// Once canceled, the terminal event is still sent when there is room
+	emitter = NewStreamEmitter(ctx, StreamBufferConfig{Size: 1})
+	assert.False(t, emitter.Emit(NewErrorEvent(&Error{Code: "canceled"})))
+	emitter.Close()
+	events := drainEvents(emitter.Events())
+	require.Len(t, events, 1)
+	assert.True(t, events[0].IsError())
+}
+
+func TestStreamEmitter_DropOldest(t *testing.T) {
+	emitter := NewStreamEmitter(context.Background(), StreamBufferConfig{Size: 3, Backpressure: StreamBackpressureDropOldest})
+	for i := uint64(1); i <= 10; i++ {
+		require.True(t, emitter.Emit(textEvent(i, "x")))
+	}
+	require.True(t, emitter.Emit(NewDoneEvent(0, "stop")))
+	emitter.Close()
+
+	events := drainEvents(emitter.Events())
+	require.Len(t, events, 3)
+	assert.Equal(t, uint64(9), events[0].Seq)
+	assert.Equal(t, uint64(10), events[1].Seq)
+	assert.True(t, events[2].IsDone())
+	assert.Equal(t, int64(8), emitter.Dropped())
+}
+
+func TestStreamEmitter_Error(t *testing.T) {
+	emitter := NewStreamEmitter(context.Background(), StreamBufferConfig{Size: 2, Backpressure: StreamBackpressureError})
+	require.True(t, emitter.Emit(textEvent(1, "a")))
+	require.True(t, emitter.Emit(textEvent(2, "b")))
+	assert.False(t, emitter.Emit(textEvent(3, "c")))
+	assert.False(t, emitter.Emit(textEvent(4, "d")))
+	emitter.Close()
+
+	events := drainEvents(emitter.Events())
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(2), events[0].Seq)
+	require.True(t, events[1].IsError())
+	assert.Equal(t, "stream_overflow", events[1].Error.Code)
+	assert.Equal(t, uint64(3), events[1].Seq)
+	assert.True(t, IsRetryableError(events[1].Error))
+}
+
+func TestStreamBufferConfig_Validate(t *testing.T) {
+	assert.NoError(t, StreamBufferConfig{}.Validate())
+	assert.NoError(t, StreamBufferConfig{Size: 100, Backpressure: StreamBackpressureDropOldest}.Validate())
+	assert.Error(t, StreamBufferConfig{Size: -1}.Validate())
+	assert.Error(t, StreamBufferConfig{Backpressure: "drop_newest"}.Validate())
+}
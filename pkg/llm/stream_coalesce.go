@@ -0,0 +1,161 @@
+// Package llm provides abstractions for Large Language Model clients
+// stream_coalesce.go defines batching of small stream deltas
+
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+// CoalesceConfig configures a coalescing stream
+type CoalesceConfig struct {
+	// Window is the longest time a delta is held back waiting for more deltas
+	Window time.Duration `json:"window"`
+
+	// MaxBytes forwards the batched deltas as soon as their text and tool call
+	// arguments reach this size
+	MaxBytes int `json:"max_bytes"`
+}
+
+// DefaultCoalesceConfig returns the default coalescing configuration
+func DefaultCoalesceConfig() *CoalesceConfig {
+	return &CoalesceConfig{
+		Window:   30 * time.Millisecond,
+		MaxBytes: 1024,
+	}
+}
+
+// CoalescingStream batches the consecutive deltas of a stream into fewer, larger deltas,
+// forwarding them when the window since the first batched delta elapses or MaxBytes is
+// reached. Text and reasoning are concatenated and tool call argument fragments are
+// joined. Any other event (done, error, tool results) first flushes the batch and is
+// then forwarded immediately, so the order and the done/error semantics of the stream
+// are kept. Output events are re-sequenced. Unset config fields are filled with defaults.
+func CoalescingStream(ctx context.Context, stream <-chan StreamEvent, config *CoalesceConfig) <-chan StreamEvent {
+	defaults := DefaultCoalesceConfig()
+	if config == nil {
+		config = defaults
+	}
+	window, maxBytes := config.Window, config.MaxBytes
+	if window <= 0 {
+		window = defaults.Window
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaults.MaxBytes
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+
+		seq := NewStreamSequencer()
+		timer := time.NewTimer(window)
+		timer.Stop()
+		defer timer.Stop()
+
+		var pending *StreamEvent
+		var size int
+		var flushC <-chan time.Time
+
+		send := func(event StreamEvent) bool {
+			select {
+			case out <- seq.Stamp(event):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flush := func() bool {
+			if pending == nil {
+				return true
+			}
+			event := *pending
+			pending, size, flushC = nil, 0, nil
+			timer.Stop()
+			return send(event)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-flushC:
+				if !flush() {
+					return
+				}
+			case event, ok := <-stream:
+				if !ok {
+					flush()
+					return
+				}
+				if !event.IsDelta() {
+					if !flush() || !send(event) {
+						return
+					}
+					continue
+				}
+
+				if pending != nil && pending.Choice.Index != event.Choice.Index {
+					if !flush() {
+						return
+					}
+				}
+				if pending == nil {
+					batch := event
+					batch.Choice = &StreamChoice{Index: event.Choice.Index, Delta: &MessageDelta{}}
+					pending = &batch
+					timer.Reset(window)
+					flushC = timer.C
+				}
+				size += appendDelta(pending.Choice.Delta, event.Choice.Delta)
+				if size >= maxBytes && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// appendDelta appends a delta to a batch without modifying the delta, joining adjacent
+// text and continued tool call arguments. It returns the number of bytes appended.
+func appendDelta(batch, delta *MessageDelta) int {
+	size := len(delta.Reasoning)
+	batch.Reasoning += delta.Reasoning
+	for _, content := range delta.Content {
+		text, isText := content.(*TextContent)
+		if isText {
+			size += len(text.GetText())
+			if last := len(batch.Content) - 1; last >= 0 {
+				if previous, ok := batch.Content[last].(*TextContent); ok {
+					batch.Content[last] = NewTextContent(previous.GetText() + text.GetText())
+					continue
+				}
+			}
+		}
+		batch.Content = append(batch.Content, content)
+	}
+
+	for _, call := range delta.ToolCalls {
+		if call.Function != nil {
+			size += len(call.Function.Arguments)
+		}
+		// A fragment without ID or name continues the arguments of the previous one
+		if last := len(batch.ToolCalls) - 1; last >= 0 && continuesToolCall(batch.ToolCalls[last], call) {
+			function := *batch.ToolCalls[last].Function
+			function.Arguments += call.Function.Arguments
+			batch.ToolCalls[last].Function = &function
+			continue
+		}
+		batch.ToolCalls = append(batch.ToolCalls, call)
+	}
+	return size
+}
+
+// continuesToolCall reports whether a tool call fragment only adds arguments to the
+// previous fragment of the same call
+func continuesToolCall(previous, call ToolCallDelta) bool {
+	return previous.Index == call.Index && previous.Function != nil &&
+		call.ID == "" && call.Type == "" && call.Function != nil && call.Function.Name == ""
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// textDelta creates a delta event with text
+func textDelta(text string) StreamEvent {
+	return NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(text)}})
+}
+
+// bufferedStream returns a closed channel holding the events
+func bufferedStream(events ...StreamEvent) <-chan StreamEvent {
+	ch := make(chan StreamEvent, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
+
+func collectEvents(stream <-chan StreamEvent) []StreamEvent {
+	var events []StreamEvent
+	for event := range stream {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestCoalescingStream(t *testing.T) {
+	stream := bufferedStream(textDelta("Hel"), textDelta("lo"), textDelta(" world"), NewDoneEvent(0, FinishReasonStop))
+	events := collectEvents(CoalescingStream(context.Background(), stream, &CoalesceConfig{Window: time.Hour}))
+
+	if len(events) != 2 || !events[0].IsDelta() || !events[1].IsDone() {
+		t.Fatalf("Expected a single delta and done, got %+v", events)
+	}
+	if got := deltaText(events[0].Choice.Delta); got != "Hello world" {
+		t.Errorf("Unexpected coalesced text: %q", got)
+	}
+	if events[0].Seq != 1 || events[1].Seq != 2 || events[1].Choice.FinishReason != FinishReasonStop {
+		t.Errorf("Unexpected sequencing or finish reason: %+v", events)
+	}
+}
+
+func TestCoalescingStream_MaxBytes(t *testing.T) {
+	stream := bufferedStream(textDelta("abc"), textDelta("def"), textDelta("g"),
+		NewErrorEvent(&Error{Code: "api_error", Message: "boom"}))
+	events := collectEvents(CoalescingStream(context.Background(), stream, &CoalesceConfig{Window: time.Hour, MaxBytes: 5}))
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if deltaText(events[0].Choice.Delta) != "abcdef" || deltaText(events[1].Choice.Delta) != "g" {
+		t.Errorf("Unexpected batches %q and %q", deltaText(events[0].Choice.Delta), deltaText(events[1].Choice.Delta))
+	}
+	if !events[2].IsError() {
+		t.Error("Expected the error event to be forwarded last")
+	}
+}
+
+func TestCoalescingStream_Window(t *testing.T) {
+	stream := make(chan StreamEvent)
+	defer close(stream)
+	out := CoalescingStream(context.Background(), stream, &CoalesceConfig{Window: 10 * time.Millisecond})
+
+	stream <- textDelta("first")
+	select {
+	case event := <-out:
+		if deltaText(event.Choice.Delta) != "first" {
+			t.Errorf("Unexpected delta: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the delta to be flushed when the window elapsed")
+	}
+}
+
+func TestCoalescingStream_ToolCalls(t *testing.T) {
+	call := func(id, name, args string) StreamEvent {
+		return NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{
+			ID: id, Function: &ToolCallFunctionDelta{Name: name, Arguments: args},
+		}}})
+	}
+	first := call("call_1", "search", `{"q":`)
+	stream := bufferedStream(first, call("", "", `"go"`), call("", "", `}`))
+	events := collectEvents(CoalescingStream(context.Background(), stream, &CoalesceConfig{Window: time.Hour}))
+
+	if len(events) != 1 {
+		t.Fatalf("Expected a single delta, got %d", len(events))
+	}
+	calls := events[0].Choice.Delta.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"q":"go"}` {
+		t.Errorf("Unexpected tool calls: %+v", calls)
+	}
+	if first.Choice.Delta.ToolCalls[0].Function.Arguments != `{"q":` {
+		t.Error("Expected the input events to be untouched")
+	}
+}
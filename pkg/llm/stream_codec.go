@@ -0,0 +1,236 @@
+// Server-Sent Events and NDJSON encoding of streams, for web backends
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxWireEventSize limits the size of an encoded event read by the decoders
+const maxWireEventSize = 8 * 1024 * 1024
+
+// StreamEncoder writes stream events in a wire format
+type StreamEncoder interface {
+	Encode(event StreamEvent) error
+}
+
+// StreamDecoder reads stream events from a wire format, returning io.EOF at the end
+type StreamDecoder interface {
+	Decode() (StreamEvent, error)
+}
+
+// SSEEncoder writes events as Server-Sent Events: the event type in the "event" field,
+// the sequence number (when set) in the "id" field and the JSON event in the "data"
+// field. Every event is flushed when the writer is an http.Flusher.
+type SSEEncoder struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// NewSSEEncoder creates an encoder writing to w. When w is an http.ResponseWriter, the
+// headers of an event stream are set, so it must be created before anything is written.
+func NewSSEEncoder(w io.Writer) *SSEEncoder {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		header := rw.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		// Disables the response buffering of nginx
+		header.Set("X-Accel-Buffering", "no")
+	}
+	flusher, _ := w.(http.Flusher)
+	return &SSEEncoder{w: w, flusher: flusher}
+}
+
+// Encode writes an event
+func (e *SSEEncoder) Encode(event StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode stream event: %w", err)
+	}
+
+	var frame strings.Builder
+	if event.Seq > 0 {
+		frame.WriteString("id: " + strconv.FormatUint(event.Seq, 10) + "\n")
+	}
+	frame.WriteString("event: " + event.Type + "\n")
+	// JSON has no raw newlines, so the event always fits in a single data line
+	frame.WriteString("data: ")
+	frame.Write(data)
+	frame.WriteString("\n\n")
+
+	if _, err := io.WriteString(e.w, frame.String()); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+// NDJSONEncoder writes events as newline-delimited JSON, one event per line. Every event
+// is flushed when the writer is an http.Flusher.
+type NDJSONEncoder struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// NewNDJSONEncoder creates an encoder writing to w. When w is an http.ResponseWriter, the
+// Content-Type is set to application/x-ndjson.
+func NewNDJSONEncoder(w io.Writer) *NDJSONEncoder {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set("Content-Type", "application/x-ndjson")
+		rw.Header().Set("Cache-Control", "no-cache")
+	}
+	flusher, _ := w.(http.Flusher)
+	return &NDJSONEncoder{w: w, flusher: flusher}
+}
+
+// Encode writes an event
+func (e *NDJSONEncoder) Encode(event StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode stream event: %w", err)
+	}
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return nil
+}
+
+// EncodeStream writes the events of a stream with the encoder until the stream ends.
+// When the context is done or a write fails (e.g. the browser went away), the rest of
+// the stream is drained so the producer can finish, and the error is returned.
+func EncodeStream(ctx context.Context, enc StreamEncoder, stream <-chan StreamEvent) error {
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return nil
+			}
+			if err := enc.Encode(event); err != nil {
+				for range stream {
+				}
+				return err
+			}
+		case <-ctx.Done():
+			for range stream {
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// SSEDecoder reads the events written by an SSEEncoder. Comments and fields other than
+// "data" are ignored, and multi-line data fields are joined.
+type SSEDecoder struct {
+	scanner *bufio.Scanner
+}
+
+// NewSSEDecoder creates a decoder reading from r
+func NewSSEDecoder(r io.Reader) *SSEDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxWireEventSize)
+	return &SSEDecoder{scanner: scanner}
+}
+
+// Decode reads the next event, returning io.EOF at the end of the input
+func (d *SSEDecoder) Decode() (StreamEvent, error) {
+	var data []string
+	for d.scanner.Scan() {
+		line := d.scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				continue
+			}
+			return decodeWireEvent(strings.Join(data, "\n"))
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return StreamEvent{}, err
+	}
+	if len(data) > 0 {
+		// The last frame was not terminated by a blank line
+		return decodeWireEvent(strings.Join(data, "\n"))
+	}
+	return StreamEvent{}, io.EOF
+}
+
+// NDJSONDecoder reads the events written by an NDJSONEncoder, skipping blank lines
+type NDJSONDecoder struct {
+	scanner *bufio.Scanner
+}
+
+// NewNDJSONDecoder creates a decoder reading from r
+func NewNDJSONDecoder(r io.Reader) *NDJSONDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxWireEventSize)
+	return &NDJSONDecoder{scanner: scanner}
+}
+
+// Decode reads the next event, returning io.EOF at the end of the input
+func (d *NDJSONDecoder) Decode() (StreamEvent, error) {
+	for d.scanner.Scan() {
+		if line := strings.TrimSpace(d.scanner.Text()); line != "" {
+			return decodeWireEvent(line)
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return StreamEvent{}, err
+	}
+	return StreamEvent{}, io.EOF
+}
+
+// decodeWireEvent decodes a JSON event
+func decodeWireEvent(data string) (StreamEvent, error) {
+	var event StreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return StreamEvent{}, fmt.Errorf("failed to decode stream event: %w", err)
+	}
+	return event, nil
+}
+
+// DecodeStream returns the events read by the decoder as a stream, which ends at the end
+// of the input. Read and decoding errors end the stream with a "stream_decode_error"
+// error event.
+func DecodeStream(ctx context.Context, dec StreamDecoder) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		for {
+			event, err := dec.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				event = NewErrorEvent(&Error{
+					Code:    "stream_decode_error",
+					Message: err.Error(),
+					Type:    "stream_error",
+				})
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
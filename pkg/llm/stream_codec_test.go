@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wireStream returns a stream exercising every event type
+func wireStream() []StreamEvent {
+	seq := NewStreamSequencer()
+	toolCall := NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{
+		Index: 0, ID: "call-1", Type: "function",
+		Function: &ToolCallFunctionDelta{Name: "weather", Arguments: `{"city":`},
+	}}})
+	return []StreamEvent{
+		seq.Stamp(textDelta("Hello\nworld")),
+		seq.Stamp(toolCall),
+		seq.Stamp(NewDeltaEvent(0, &MessageDelta{Reasoning: "thinking"})),
+		seq.Stamp(NewDoneEvent(0, FinishReasonToolCalls)),
+	}
+}
+
+func TestStreamCodecRoundTrip(t *testing.T) {
+	codecs := map[string]struct {
+		encoder func(w *bytes.Buffer) StreamEncoder
+		decoder func(r *bytes.Buffer) StreamDecoder
+	}{
+		"sse": {
+			func(w *bytes.Buffer) StreamEncoder { return NewSSEEncoder(w) },
+			func(r *bytes.Buffer) StreamDecoder { return NewSSEDecoder(r) },
+		},
+		"ndjson": {
+			func(w *bytes.Buffer) StreamEncoder { return NewNDJSONEncoder(w) },
+			func(r *bytes.Buffer) StreamDecoder { return NewNDJSONDecoder(r) },
+		},
+	}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			events := wireStream()
+			var buf bytes.Buffer
+			require.NoError(t, EncodeStream(context.Background(), codec.encoder(&buf), bufferedStream(events...)))
+
+			decoded := collectEvents(DecodeStream(context.Background(), codec.decoder(&buf)))
+			require.Len(t, decoded, len(events))
+			assert.Equal(t, "Hello\nworld", deltaText(decoded[0].Choice.Delta))
+			assert.Equal(t, events[1].Choice.Delta.ToolCalls, decoded[1].Choice.Delta.ToolCalls)
+			assert.Equal(t, "thinking", decoded[2].Choice.Delta.Reasoning)
+			assert.True(t, decoded[3].IsDone())
+			assert.Equal(t, FinishReasonToolCalls, decoded[3].Choice.FinishReason)
+			for i := range events {
+				assert.Equal(t, events[i].Seq, decoded[i].Seq)
+				assert.True(t, events[i].Timestamp.Equal(decoded[i].Timestamp))
+			}
+		})
+	}
+}
+
+func TestSSEEncoder_HTTP(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	enc := NewSSEEncoder(recorder)
+	require.NoError(t, EncodeStream(context.Background(), enc, bufferedStream(
+		NewStreamSequencer().Stamp(textDelta("Hi")),
+		NewErrorEvent(&Error{Code: "rate_limit_exceeded", Message: "slow down", Type: "rate_limit_error"}),
+	)))
+
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+	assert.True(t, recorder.Flushed)
+	body := recorder.Body.String()
+	assert.True(t, strings.HasPrefix(body, "id: 1\nevent: delta\ndata: {"), body)
+	assert.Contains(t, body, "\n\nevent: error\ndata: {")
+
+	recorder = httptest.NewRecorder()
+	NewNDJSONEncoder(recorder)
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+}
+
+func TestSSEDecoder_Frames(t *testing.T) {
+	input := ": keep-alive comment\n\n" +
+		"retry: 1000\nevent: delta\ndata: {\"type\":\"done\",\n" +
+		"data: \"choice\":{\"index\":0,\"finish_reason\":\"stop\"}}\n"
+	events := collectEvents(DecodeStream(context.Background(), NewSSEDecoder(strings.NewReader(input))))
+	require.Len(t, events, 1)
+	assert.True(t, events[0].IsDone(), "multi-line data without a final blank line is decoded")
+}
+
+func TestDecodeStream_InvalidEvent(t *testing.T) {
+	input := `{"type":"delta","choice":{"index":0,"delta":{"content":[{"type":"text","text":"ok"}]}}}` + "\n" +
+		"not json\n" +
+		`{"type":"done","choice":{"index":0}}` + "\n"
+	events := collectEvents(DecodeStream(context.Background(), NewNDJSONDecoder(strings.NewReader(input))))
+	require.Len(t, events, 2)
+	assert.Equal(t, "ok", deltaText(events[0].Choice.Delta))
+	require.True(t, events[1].IsError())
+	assert.Equal(t, "stream_decode_error", events[1].Error.Code)
+	assert.False(t, IsRetryableError(events[1].Error))
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("connection closed") }
+
+func TestEncodeStream_DrainsOnWriteError(t *testing.T) {
+	stream := make(chan StreamEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(stream)
+		for i := 0; i < 5; i++ {
+			stream <- textDelta("x")
+		}
+	}()
+
+	err := EncodeStream(context.Background(), NewNDJSONEncoder(failingWriter{}), stream)
+	require.Error(t, err)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the producer to finish")
+	}
+}
@@ -0,0 +1,189 @@
+// Classification of stream errors and terminal events
+package llm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// StreamErrorPosition tells where in a stream an error happened
+type StreamErrorPosition string
+
+const (
+	// StreamErrorBeforeFirstToken is an error before any content was streamed, so the
+	// request can be retried transparently
+	StreamErrorBeforeFirstToken StreamErrorPosition = "before_first_token"
+
+	// StreamErrorMidStream is an error after part of the response was streamed, so a
+	// retry has to discard or continue the partial response (see StreamResumer)
+	StreamErrorMidStream StreamErrorPosition = "mid_stream"
+)
+
+// StreamErrorInfo classifies the error of an error event, for telemetry and retry
+// decisions
+type StreamErrorInfo struct {
+	// Retryable reports whether sending the request again may succeed (rate limits,
+	// server errors, network failures and truncated streams)
+	Retryable bool `json:"retryable"`
+
+	// Position tells whether content was streamed before the error
+	Position StreamErrorPosition `json:"position"`
+
+	// Deltas is the number of content deltas streamed before the error
+	Deltas int `json:"deltas"`
+
+	// Elapsed is the time from the start of the stream to the error
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// IsTerminal returns true for the events ending a stream: a done event or an error
+// event. Streams of the built-in providers always end with a terminal event.
+func (e StreamEvent) IsTerminal() bool {
+	return e.IsDone() || e.IsError()
+}
+
+// IsTruncated returns true for the terminal event of a stream closed without a done or
+// error event, which lost the end of the response
+func (e StreamEvent) IsTruncated() bool {
+	return e.IsError() && e.Error.Code == "stream_truncated"
+}
+
+// IsRetryableError reports whether a request that failed with err may succeed if sent
+// again: rate limits, server errors (5xx), network failures and truncated streams.
+// Cancellation, authentication and validation errors are not retryable.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var llmErr *Error
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	switch {
+	case llmErr.Type == "rate_limit_error", llmErr.StatusCode == 429:
+		return true
+	case llmErr.StatusCode >= 500 && llmErr.StatusCode < 600:
+		return true
+	case llmErr.StatusCode != 0:
+		return false
+	}
+	switch llmErr.Type {
+	case "network_error", "timeout_error":
+		return true
+	case "stream_error":
+		return llmErr.Code != "stream_cancelled" && llmErr.Code != "stream_decode_error"
+	}
+	switch llmErr.Code {
+	case "rate_limit_exceeded", "request_failed", "stream_error", "stream_truncated", "timeout":
+		return true
+	}
+	return false
+}
+
+// streamClassifier annotates the error events of a stream and detects streams closed
+// without a terminal event
+type streamClassifier struct {
+	start    time.Time
+	deltas   int
+	last     StreamEvent
+	received bool
+}
+
+// newStreamClassifier creates a classifier for a stream started at start
+func newStreamClassifier(start time.Time) *streamClassifier {
+	return &streamClassifier{start: start}
+}
+
+// observe records an event, setting the ErrorInfo of error events without one
+func (c *streamClassifier) observe(event StreamEvent) StreamEvent {
+	switch {
+	case event.IsDelta():
+		if delta := event.Choice.Delta; len(delta.Content) > 0 || len(delta.ToolCalls) > 0 || delta.Reasoning != "" {
+			c.deltas++
+		}
+	case event.IsError() && event.ErrorInfo == nil:
+		event.ErrorInfo = c.info(event.Error)
+	}
+	c.last, c.received = event, true
+	return event
+}
+
+// info classifies an error at the current position of the stream
+func (c *streamClassifier) info(err *Error) *StreamErrorInfo {
+	position := StreamErrorBeforeFirstToken
+	if c.deltas > 0 {
+		position = StreamErrorMidStream
+	}
+	return &StreamErrorInfo{
+		Retryable: IsRetryableError(err),
+		Position:  position,
+		Deltas:    c.deltas,
+		Elapsed:   time.Since(c.start),
+	}
+}
+
+// terminal returns the error event ending a stream whose last event is not terminal,
+// continuing its sequence, or false when the stream ended properly
+func (c *streamClassifier) terminal(ctx context.Context) (StreamEvent, bool) {
+	if c.received && c.last.IsTerminal() {
+		return StreamEvent{}, false
+	}
+
+	err := &Error{
+		Code:    "stream_truncated",
+		Message: "the stream ended without a done or error event",
+		Type:    "stream_error",
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = &Error{Code: "stream_cancelled", Message: "the stream was interrupted: " + ctxErr.Error(), Type: "stream_error"}
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			err.Code = "timeout"
+		}
+	}
+
+	event := NewErrorEvent(err)
+	event.ErrorInfo = c.info(err)
+	event.Timestamp = time.Now()
+	if c.received {
+		event.Source = c.last.Source
+		if c.last.Seq > 0 {
+			event.Seq = c.last.Seq + 1
+		}
+	}
+	return event, true
+}
+
+// ClassifyStream forwards a stream, setting the ErrorInfo of its error events and
+// ending it with a "stream_truncated" error event when it closes without a done or error
+// event (or a "stream_cancelled" or "timeout" one when the context is done), so every
+// stream ends with a terminal event. The streams of the built-in providers are already
+// classified; custom clients can use it for the same guarantee.
+func ClassifyStream(ctx context.Context, stream <-chan StreamEvent) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	classifier := newStreamClassifier(time.Now())
+
+	go func() {
+		defer close(out)
+		for event := range stream {
+			select {
+			case out <- classifier.observe(event):
+			case <-ctx.Done():
+				for range stream {
+				}
+				return
+			}
+		}
+		if event, ok := classifier.terminal(ctx); ok {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("plain error"), false},
+		{&Error{Code: "rate_limit_exceeded", Type: "rate_limit_error", StatusCode: 429}, true},
+		{&Error{Code: "server_error", StatusCode: 503}, true},
+		{&Error{Code: "invalid_api_key", Type: "authentication_error", StatusCode: 401}, false},
+		{&Error{Code: "invalid_request", Type: "validation_error"}, false},
+		{&Error{Code: "request_failed", Type: "network_error"}, true},
+		{&Error{Code: "stream_error", Message: "connection reset", Type: "api_error"}, true},
+		{&Error{Code: "stream_truncated", Type: "stream_error"}, true},
+		{&Error{Code: "stream_cancelled", Type: "stream_error"}, false},
+		{fmt.Errorf("wrapped: %w", &Error{Code: "x", StatusCode: 502}), true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsRetryableError(tt.err), "%v", tt.err)
+	}
+}
+
+func TestClassifyStream_ErrorPosition(t *testing.T) {
+	rateLimited := &Error{Code: "rate_limit_exceeded", Type: "rate_limit_error", StatusCode: 429}
+	events := collectEvents(ClassifyStream(context.Background(), bufferedStream(NewErrorEvent(rateLimited))))
+	require.Len(t, events, 1)
+	require.NotNil(t, events[0].ErrorInfo)
+	assert.Equal(t, StreamErrorBeforeFirstToken, events[0].ErrorInfo.Position)
+	assert.True(t, events[0].ErrorInfo.Retryable)
+	assert.Zero(t, events[0].ErrorInfo.Deltas)
+
+	invalid := &Error{Code: "invalid_request", Type: "validation_error", StatusCode: 400}
+	events = collectEvents(ClassifyStream(context.Background(), bufferedStream(
+		textDelta("Hel"), textDelta("lo"), NewErrorEvent(invalid))))
+	require.Len(t, events, 3)
+	info := events[2].ErrorInfo
+	require.NotNil(t, info)
+	assert.Equal(t, StreamErrorMidStream, info.Position)
+	assert.False(t, info.Retryable)
+	assert.Equal(t, 2, info.Deltas)
+}
+
+func TestClassifyStream_KeepsExistingErrorInfo(t *testing.T) {
+	event := NewErrorEvent(&Error{Code: "custom", Type: "api_error"})
+	event.ErrorInfo = &StreamErrorInfo{Retryable: true, Position: StreamErrorMidStream, Deltas: 7}
+
+	events := collectEvents(ClassifyStream(context.Background(), bufferedStream(event)))
+	require.Len(t, events, 1)
+	assert.Equal(t, 7, events[0].ErrorInfo.Deltas)
+}
+
+func TestClassifyStream_CompleteStreamUnchanged(t *testing.T) {
+	events := collectEvents(ClassifyStream(context.Background(), bufferedStream(
+		textDelta("Hello"), NewDoneEvent(0, FinishReasonStop))))
+	require.Len(t, events, 2)
+	assert.True(t, events[1].IsTerminal())
+	assert.False(t, events[1].IsTruncated())
+}
+
+func TestClassifyStream_Truncated(t *testing.T) {
+	seq := NewStreamSequencer()
+	first, second := seq.Stamp(textDelta("Hel")), seq.Stamp(textDelta("lo"))
+	first.Source, second.Source = "llm", "llm"
+
+	events := collectEvents(ClassifyStream(context.Background(), bufferedStream(first, second)))
+	require.Len(t, events, 3)
+	last := events[2]
+	assert.True(t, last.IsTerminal())
+	assert.True(t, last.IsTruncated())
+	assert.Equal(t, uint64(3), last.Seq, "the terminal event continues the sequence")
+	assert.Equal(t, "llm", last.Source)
+	require.NotNil(t, last.ErrorInfo)
+	assert.True(t, last.ErrorInfo.Retryable)
+	assert.Equal(t, StreamErrorMidStream, last.ErrorInfo.Position)
+
+	// An empty stream is truncated before the first token
+	events = collectEvents(ClassifyStream(context.Background(), bufferedStream()))
+	require.Len(t, events, 1)
+	assert.True(t, events[0].IsTruncated())
+	assert.Equal(t, StreamErrorBeforeFirstToken, events[0].ErrorInfo.Position)
+}
+
+func TestClassifyStream_DeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	classifier := newStreamClassifier(time.Now())
+	classifier.observe(textDelta("partial"))
+	event, ok := classifier.terminal(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "timeout", event.Error.Code)
+	assert.True(t, event.ErrorInfo.Retryable)
+}
+
+func TestTrackStream_EndsWithTerminalEvent(t *testing.T) {
+	stats := NewHealthStats()
+	events := collectEvents(stats.TrackStream(context.Background(), time.Now(), bufferedStream(textDelta("Hello"))))
+
+	require.Len(t, events, 2)
+	assert.True(t, events[1].IsTruncated())
+	status := stats.Status()
+	assert.Equal(t, int64(1), status.Failures)
+	assert.Equal(t, "stream_truncated", status.LastError.Code)
+}
@@ -0,0 +1,85 @@
+// Versioned JSON encoding of stream events
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StreamEventVersion is the version of the JSON encoding of stream events, written in
+// their "version" field. It only changes on incompatible changes of the encoding: new
+// optional fields are added without a new version, and decoders ignore unknown fields.
+//
+// The encoding of version 1 is the one given by the JSON tags of StreamEvent and the
+// types it references, where:
+//   - content items are objects with a "type" field ("text", "image" or "file")
+//   - timestamps are RFC 3339 strings with nanoseconds, and durations are nanoseconds
+//   - tool call arguments are strings with JSON fragments
+const StreamEventVersion = 1
+
+// MarshalJSON encodes an event with the current StreamEventVersion
+func (e StreamEvent) MarshalJSON() ([]byte, error) {
+	type Alias StreamEvent
+	return json.Marshal(struct {
+		Version int `json:"version"`
+		Alias
+	}{
+		Version: StreamEventVersion,
+		Alias:   Alias(e),
+	})
+}
+
+// UnmarshalJSON decodes an event of any version up to StreamEventVersion. Events without
+// a version, encoded before versioning was introduced, are decoded as version 1. Events
+// of newer versions fail with an "unsupported_version" error.
+func (e *StreamEvent) UnmarshalJSON(data []byte) error {
+	type Alias StreamEvent
+	var event StreamEvent
+	temp := struct {
+		Version int `json:"version"`
+		*Alias
+	}{
+		Alias: (*Alias)(&event),
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+
+	switch {
+	case temp.Version > StreamEventVersion:
+		return &Error{
+			Code:    "unsupported_version",
+			Message: fmt.Sprintf("stream event version %d is newer than the supported version %d", temp.Version, StreamEventVersion),
+			Type:    "validation_error",
+		}
+	case event.Type == "":
+		return &Error{Code: "invalid_event", Message: "stream event has no type", Type: "validation_error"}
+	}
+
+	*e = event
+	return nil
+}
+
+// UnmarshalJSON decodes a function call fragment. Arguments sent as a JSON object (by
+// producers that do not stream them) are kept as their encoding.
+func (f *ToolCallFunctionDelta) UnmarshalJSON(data []byte) error {
+	var temp struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+
+	f.Name, f.Arguments = temp.Name, ""
+	switch {
+	case len(temp.Arguments) == 0 || string(temp.Arguments) == "null":
+	case temp.Arguments[0] == '"':
+		if err := json.Unmarshal(temp.Arguments, &f.Arguments); err != nil {
+			return err
+		}
+	default:
+		f.Arguments = string(temp.Arguments)
+	}
+	return nil
+}
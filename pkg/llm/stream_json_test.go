@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenStreamEvents are the events of the golden files in testdata/stream_events, which
+// pin the JSON encoding of stream events. A failing comparison means the encoding changed:
+// only update the files (go test -run TestStreamEventJSON_Golden -update) for compatible
+// changes, and increase StreamEventVersion otherwise.
+func goldenStreamEvents() map[string]StreamEvent {
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 600000000, time.UTC)
+
+	text := NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("Hello")}})
+	text.Seq, text.Timestamp, text.Source = 1, timestamp, "llm"
+
+	toolCall := NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{
+		Index:    1,
+		ID:       "call_1",
+		Type:     "function",
+		Function: &ToolCallFunctionDelta{Name: "weather", Arguments: `{"city":`},
+	}}})
+	toolCall.Seq = 2
+
+	image := NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{
+		NewImageContentFromURL("https://example.com/cat.png", "image/png"),
+	}})
+
+	done := NewProviderDoneEvent(0, "openai", "length")
+	done.Seq, done.Timestamp = 3, timestamp
+
+	failure := NewErrorEvent(&Error{
+		Code:       "rate_limit_exceeded",
+		Message:    "too many requests",
+		Type:       "rate_limit_error",
+		StatusCode: 429,
+	})
+	failure.ErrorInfo = &StreamErrorInfo{Retryable: true, Position: StreamErrorMidStream, Deltas: 2, Elapsed: 1500 * time.Millisecond}
+
+	return map[string]StreamEvent{
+		"delta_text":      text,
+		"delta_reasoning": NewDeltaEvent(0, &MessageDelta{Reasoning: "Thinking"}),
+		"delta_tool_call": toolCall,
+		"delta_image":     image,
+		"done":            done,
+		"error":           failure,
+		"tool_progress": NewToolProgressEvent("search", "call_1", &ToolProgressInfo{
+			Phase: "fetching", Progress: 0.5, Message: "half way", ItemsTotal: 4, ItemsCurrent: 2,
+		}),
+		"tool_error": NewToolErrorEvent("search", "call_1", &ToolExecutionError{
+			Code: "timeout", Message: "search timed out", Type: "execution_error",
+		}),
+	}
+}
+
+func TestStreamEventJSON_Golden(t *testing.T) {
+	for name, event := range goldenStreamEvents() {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("testdata", "stream_events", name+".json")
+
+			data, err := json.MarshalIndent(event, "", "  ")
+			require.NoError(t, err)
+			data = append(data, '\n')
+
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, data, 0o644))
+			}
+
+			golden, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(golden), string(data), "encoding of %s changed", name)
+
+			var decoded StreamEvent
+			require.NoError(t, json.Unmarshal(golden, &decoded))
+			assert.Equal(t, event, decoded)
+		})
+	}
+}
+
+func TestStreamEventJSON_Versions(t *testing.T) {
+	// Events encoded before versioning decode as version 1
+	var event StreamEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"done","choice":{"index":0,"finish_reason":"stop"}}`), &event))
+	assert.True(t, event.IsDone())
+
+	// Unknown fields of the same version are ignored
+	require.NoError(t, json.Unmarshal([]byte(`{"version":1,"type":"delta","choice":{"index":0,"delta":{"reasoning":"x"}},"priority":2}`), &event))
+	assert.Equal(t, "x", event.Choice.Delta.Reasoning)
+
+	previous := event
+	err := json.Unmarshal([]byte(`{"version":2,"type":"delta"}`), &event)
+	require.Error(t, err)
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "unsupported_version", llmErr.Code)
+	assert.Equal(t, previous, event, "a rejected event must not be modified")
+
+	err = json.Unmarshal([]byte(`{"version":1}`), &event)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_event", llmErr.Code)
+}
+
+func TestToolCallFunctionDelta_ObjectArguments(t *testing.T) {
+	var delta ToolCallDelta
+	require.NoError(t, json.Unmarshal([]byte(`{"index":0,"function":{"name":"weather","arguments":{"city":"Paris"}}}`), &delta))
+	assert.Equal(t, `{"city":"Paris"}`, delta.Function.Arguments)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"index":0,"function":{"name":"weather","arguments":"{\"ci"}}`), &delta))
+	assert.Equal(t, `{"ci`, delta.Function.Arguments)
+}
@@ -5,6 +5,7 @@ package llm
 
 import (
 	"context"
+	"fmt"
 	"sync"
 )
 
@@ -59,18 +60,20 @@ func (sm *StreamMerger) mergeStreams() {
 	for i, toolStream := range sm.toolStreams {
 		if toolStream != nil {
 			sm.wg.Add(1)
-			go sm.forwardStream(toolStream, "tool")
+			go sm.forwardStream(toolStream, fmt.Sprintf("tool:%d", i))
 		}
-		_ = i // avoid unused variable
 	}
 
 	// Wait for all streams to complete
 	sm.wg.Wait()
 }
 
-func (sm *StreamMerger) forwardStream(stream <-chan StreamEvent, streamType string) {
+// forwardStream copies the events of one stream to the output, tagging them with their
+// source and sequencing the events that were produced without sequence numbers
+func (sm *StreamMerger) forwardStream(stream <-chan StreamEvent, source string) {
 	defer sm.wg.Done()
 
+	sequencer := NewStreamSequencer()
 	for {
 		select {
 		case event, ok := <-stream:
@@ -78,6 +81,13 @@ func (sm *StreamMerger) forwardStream(stream <-chan StreamEvent, streamType stri
 				return // Stream closed
 			}
 
+			if event.Source == "" {
+				event.Source = source
+			}
+			if event.Seq == 0 {
+				event = sequencer.Stamp(event)
+			}
+
 			// Forward the event with priority
 			select {
 			case sm.output <- event:
@@ -96,3 +106,18 @@ func MergeStreams(ctx context.Context, llmStream <-chan StreamEvent, toolStreams
 	merger := NewStreamMerger(ctx, llmStream, toolStreams)
 	return merger.Start()
 }
+
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams. It uses the StreamChatCompletionWithTools method of clients implementing
+// StreamingClient, and otherwise merges the streams itself, so wrappers such as
+// EnhancedClient and third-party clients produce the same merged streams as providers.
+func StreamChatCompletionWithTools(ctx context.Context, client Client, req ChatRequest, toolStreams []<-chan StreamEvent) (<-chan StreamEvent, error) {
+	if streaming, ok := client.(StreamingClient); ok {
+		return streaming.StreamChatCompletionWithTools(ctx, req, toolStreams)
+	}
+	stream, err := client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return MergeStreams(ctx, stream, toolStreams...), nil
+}
@@ -0,0 +1,187 @@
+// Package llm provides abstractions for Large Language Model clients
+// stream_pace.go defines rate limiting of the text of streams
+
+package llm
+
+import (
+	"context"
+	"time"
+	"unicode"
+)
+
+// PaceConfig configures a paced stream
+type PaceConfig struct {
+	// TokensPerSecond is the highest rate text is forwarded at
+	TokensPerSecond float64 `json:"tokens_per_second"`
+
+	// Burst is the number of tokens that can be forwarded at once after a pause, such as
+	// at the start of the stream
+	Burst int `json:"burst"`
+}
+
+// DefaultPaceConfig returns the default pacing configuration
+func DefaultPaceConfig() *PaceConfig {
+	return &PaceConfig{
+		TokensPerSecond: 30,
+		Burst:           1,
+	}
+}
+
+// pacedDelta is a fragment of a delta waiting to be forwarded
+type pacedDelta struct {
+	event  StreamEvent
+	tokens float64
+}
+
+// PacedStream re-emits the deltas of a stream at most at TokensPerSecond, splitting the
+// text and reasoning of the deltas into words (for a typewriter effect in UIs, or to
+// respect the rate of a consumer such as a text-to-speech engine). Tokens are estimated
+// with EstimateTextTokens, and tool call deltas and non-text content are forwarded in
+// order without using the budget. Any other event (done, error, tool results), and the
+// end of the stream, first flush the pending deltas without waiting and are then
+// forwarded immediately, so the pacing never delays the completion of the stream.
+// Output events are re-sequenced. Unset config fields are filled with defaults.
+func PacedStream(ctx context.Context, stream <-chan StreamEvent, config *PaceConfig) <-chan StreamEvent {
+	defaults := DefaultPaceConfig()
+	if config == nil {
+		config = defaults
+	}
+	rate, burst := config.TokensPerSecond, float64(config.Burst)
+	if rate <= 0 {
+		rate = defaults.TokensPerSecond
+	}
+	if burst <= 0 {
+		burst = float64(defaults.Burst)
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+
+		seq := NewStreamSequencer()
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		var queue []pacedDelta
+		available, last := burst, time.Now()
+
+		send := func(event StreamEvent) bool {
+			select {
+			case out <- seq.Stamp(event):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flush := func() bool {
+			for _, delta := range queue {
+				if !send(delta.event) {
+					return false
+				}
+			}
+			queue = nil
+			return true
+		}
+
+		input := stream
+		for {
+			// Forward the deltas the budget allows; a delta larger than the burst waits
+			// for a full budget and then leaves it in debt
+			var waitC <-chan time.Time
+			for len(queue) > 0 {
+				now := time.Now()
+				available = min(burst, available+now.Sub(last).Seconds()*rate)
+				last = now
+
+				next := queue[0]
+				if need := min(next.tokens, burst); available < need {
+					timer.Reset(time.Duration((need - available) / rate * float64(time.Second)))
+					waitC = timer.C
+					break
+				}
+				available -= next.tokens
+				queue = queue[1:]
+				if !send(next.event) {
+					return
+				}
+			}
+			if input == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-waitC:
+			case event, ok := <-input:
+				timer.Stop()
+				switch {
+				case !ok:
+					if !flush() {
+						return
+					}
+					input = nil
+				case !event.IsDelta():
+					if !flush() || !send(event) {
+						return
+					}
+				default:
+					queue = append(queue, splitPacedDelta(event)...)
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// splitPacedDelta splits a delta event into single-word text and reasoning fragments,
+// and fragments with the other content and the tool calls, keeping their order
+func splitPacedDelta(event StreamEvent) []pacedDelta {
+	var fragments []pacedDelta
+	add := func(delta *MessageDelta, tokens int) {
+		fragment := event
+		fragment.Choice = &StreamChoice{Index: event.Choice.Index, Delta: delta}
+		fragments = append(fragments, pacedDelta{event: fragment, tokens: float64(tokens)})
+	}
+
+	for _, word := range splitWords(event.Choice.Delta.Reasoning) {
+		add(&MessageDelta{Reasoning: word}, EstimateTextTokens(word))
+	}
+	for _, content := range event.Choice.Delta.Content {
+		text, ok := content.(*TextContent)
+		if !ok {
+			add(&MessageDelta{Content: []MessageContent{content}}, 0)
+			continue
+		}
+		for _, word := range splitWords(text.GetText()) {
+			add(&MessageDelta{Content: []MessageContent{NewTextContent(word)}}, EstimateTextTokens(word))
+		}
+	}
+	if len(event.Choice.Delta.ToolCalls) > 0 {
+		add(&MessageDelta{ToolCalls: event.Choice.Delta.ToolCalls}, 0)
+	}
+	return fragments
+}
+
+// splitWords splits a text into words, each with the spaces following it (leading
+// spaces go with the first word)
+func splitWords(text string) []string {
+	var words []string
+	start, inWord, spaced := 0, false, false
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			spaced = inWord
+		case spaced:
+			words = append(words, text[start:i])
+			start, spaced = i, false
+		default:
+			inWord = true
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
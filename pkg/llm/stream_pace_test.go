@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPacedStream(t *testing.T) {
+	stream := make(chan StreamEvent, 2)
+	stream <- textDelta("one two three four five")
+	stream <- NewDeltaEvent(0, &MessageDelta{ToolCalls: []ToolCallDelta{{ID: "call_1"}}})
+	out := PacedStream(context.Background(), stream, &PaceConfig{TokensPerSecond: 50, Burst: 1})
+
+	start := time.Now()
+	var words []string
+	for range 5 {
+		words = append(words, deltaText((<-out).Choice.Delta))
+	}
+	// Four words wait 20ms each
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("Expected the words to be paced, took %v", elapsed)
+	}
+	if want := []string{"one ", "two ", "three ", "four ", "five"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("Expected the words %q, got %q", want, words)
+	}
+
+	close(stream)
+	events := collectEvents(out)
+	if len(events) != 1 || events[0].Choice.Delta.ToolCalls[0].ID != "call_1" || events[0].Seq != 6 {
+		t.Errorf("Expected the tool call delta last, got %+v", events)
+	}
+}
+
+func TestPacedStream_FlushOnDone(t *testing.T) {
+	stream := bufferedStream(textDelta("a slow typewriter would take a while"), NewDoneEvent(0, FinishReasonStop))
+
+	start := time.Now()
+	events := collectEvents(PacedStream(context.Background(), stream, &PaceConfig{TokensPerSecond: 1}))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the pending words to be flushed on done, took %v", elapsed)
+	}
+
+	if len(events) != 8 || !events[7].IsDone() {
+		t.Fatalf("Expected 7 words and done, got %d events", len(events))
+	}
+	text := ""
+	for _, event := range events[:7] {
+		text += deltaText(event.Choice.Delta)
+	}
+	if text != "a slow typewriter would take a while" {
+		t.Errorf("Unexpected text: %q", text)
+	}
+}
+
+func TestPacedStream_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := make(chan StreamEvent, 1)
+	stream <- textDelta("one two three")
+	out := PacedStream(ctx, stream, &PaceConfig{TokensPerSecond: 0.1})
+
+	<-out
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("Expected no more words after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to be closed on cancellation")
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	if got := splitWords("  hello  big\nworld "); !reflect.DeepEqual(got, []string{"  hello  ", "big\n", "world "}) {
+		t.Errorf("Unexpected words: %q", got)
+	}
+}
@@ -0,0 +1,202 @@
+// Resumption of streams interrupted mid-response without duplicating text
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// StreamResumeConfig configures a StreamResumer
+type StreamResumeConfig struct {
+	// Prompt is the user message asking the model to continue the interrupted reply
+	Prompt string `json:"prompt"`
+
+	// MaxSeamOverlap is the longest repeated text removed where a retry starts with the
+	// end of the text already received. It is also how much retry text is held back
+	// before it is forwarded.
+	MaxSeamOverlap int `json:"max_seam_overlap"`
+
+	// MinSeamOverlap is the shortest overlap removed, so short coincidental repetitions
+	// are kept
+	MinSeamOverlap int `json:"min_seam_overlap"`
+}
+
+// DefaultStreamResumeConfig returns the default resumption configuration
+func DefaultStreamResumeConfig() *StreamResumeConfig {
+	return &StreamResumeConfig{
+		Prompt:         "Your previous reply was interrupted. Continue exactly where it stopped, without repeating any of the previous text.",
+		MaxSeamOverlap: 200,
+		MinSeamOverlap: 8,
+	}
+}
+
+// StreamResumer keeps the transcript of a streamed reply across retries. Streams are
+// read through Track, which records the assistant text received. When a stream fails
+// mid-response, RetryRequest builds a request that continues the reply, and Track
+// removes from the retry stream the text that was already received, whether the model
+// repeats the end of it or restarts the reply from the beginning. Callers can then show
+// the events of every attempt in order, without duplicated prose.
+//
+//	resumer := llm.NewStreamResumer(nil)
+//	stream, err := client.StreamChatCompletion(ctx, req)
+//	for attempt := 0; err == nil; attempt++ {
+//	    failed := false
+//	    for event := range resumer.Track(ctx, stream) {
+//	        failed = failed || event.IsError()
+//	        ... // show the delta
+//	    }
+//	    if !failed || attempt == maxRetries {
+//	        break
+//	    }
+//	    stream, err = client.StreamChatCompletion(ctx, resumer.RetryRequest(req))
+//	}
+//
+// Only the text of the first choice is deduplicated; tool call deltas are forwarded.
+type StreamResumer struct {
+	config *StreamResumeConfig
+	seq    *StreamSequencer
+
+	mu       sync.Mutex
+	received strings.Builder
+}
+
+// NewStreamResumer creates a resumer, filling unset config fields with defaults
+func NewStreamResumer(config *StreamResumeConfig) *StreamResumer {
+	defaults := DefaultStreamResumeConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Prompt == "" {
+		config.Prompt = defaults.Prompt
+	}
+	if config.MaxSeamOverlap <= 0 {
+		config.MaxSeamOverlap = defaults.MaxSeamOverlap
+	}
+	if config.MinSeamOverlap <= 0 {
+		config.MinSeamOverlap = defaults.MinSeamOverlap
+	}
+	return &StreamResumer{config: config, seq: NewStreamSequencer()}
+}
+
+// Received returns the assistant text received so far, across all the tracked streams
+func (r *StreamResumer) Received() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received.String()
+}
+
+// RetryRequest returns the request to send after an interrupted stream: the original
+// request followed by the text received so far and a prompt to continue it. The
+// original request is returned when nothing was received yet.
+func (r *StreamResumer) RetryRequest(req ChatRequest) ChatRequest {
+	received := r.Received()
+	if received == "" {
+		return req
+	}
+	retry := req
+	retry.Messages = append(append(make([]Message, 0, len(req.Messages)+2), req.Messages...),
+		NewTextMessage(RoleAssistant, received),
+		NewTextMessage(RoleUser, r.config.Prompt),
+	)
+	return retry
+}
+
+// Track forwards the events of a stream, recording its text. When text was received by
+// previously tracked streams, the start of this stream is held back until the text it
+// repeats can be removed. Events are re-sequenced so the attempts form one sequence.
+func (r *StreamResumer) Track(ctx context.Context, stream <-chan StreamEvent) <-chan StreamEvent {
+	previous := r.Received()
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		send := func(event StreamEvent) bool {
+			select {
+			case out <- r.seq.Stamp(event):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var pending strings.Builder // retry text held back until it can be deduplicated
+		holding := previous != ""
+		release := func() bool {
+			holding = false
+			text := r.unseen(previous, pending.String())
+			if text == "" {
+				return true
+			}
+			r.record(text)
+			return send(NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent(text)}}))
+		}
+
+		for {
+			var event StreamEvent
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok = <-stream:
+			}
+			if !ok {
+				if holding {
+					release()
+				}
+				return
+			}
+
+			if event.IsDelta() && event.Choice.Index == 0 {
+				text := deltaText(event.Choice.Delta)
+				if holding && len(event.Choice.Delta.ToolCalls) == 0 {
+					pending.WriteString(text)
+					if !r.mayRepeat(previous, pending.String()) && !release() {
+						return
+					}
+					continue
+				}
+				if holding && !release() {
+					return
+				}
+				r.record(text)
+			} else if holding && !release() {
+				return
+			}
+			if !send(event) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// mayRepeat reports whether the retry text could still turn out to repeat the text
+// received before, so it must be held back
+func (r *StreamResumer) mayRepeat(previous, retry string) bool {
+	if strings.HasPrefix(retry, previous) {
+		return false
+	}
+	return strings.HasPrefix(previous, retry) || len(retry) <= r.config.MaxSeamOverlap
+}
+
+// unseen returns the part of the retry text that was not received before: the text
+// after the whole previous reply when the model restarted it, or the text after the
+// overlap when the model repeated its end
+func (r *StreamResumer) unseen(previous, retry string) string {
+	switch {
+	case strings.HasPrefix(retry, previous):
+		return retry[len(previous):]
+	case strings.HasPrefix(previous, retry):
+		return ""
+	default:
+		return trimSeam(previous, retry, r.config.MaxSeamOverlap, r.config.MinSeamOverlap)
+	}
+}
+
+// record appends received text to the transcript
+func (r *StreamResumer) record(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received.WriteString(text)
+}
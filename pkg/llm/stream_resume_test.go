@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// streamText concatenates the delta text of events
+func streamText(events []StreamEvent) string {
+	var text strings.Builder
+	for _, event := range events {
+		if event.IsDelta() {
+			text.WriteString(deltaText(event.Choice.Delta))
+		}
+	}
+	return text.String()
+}
+
+func TestStreamResumer(t *testing.T) {
+	ctx := context.Background()
+	interrupted := NewErrorEvent(&Error{Code: "stream_error", Message: "connection reset", Type: "stream_error"})
+
+	tests := []struct {
+		name  string
+		retry []StreamEvent
+		want  string
+	}{
+		{
+			name:  "continues with overlap",
+			retry: []StreamEvent{textDelta("brown fox jumps"), textDelta(" over the lazy dog.")},
+			want:  "The quick brown fox jumps over the lazy dog.",
+		},
+		{
+			name:  "restarts the reply",
+			retry: []StreamEvent{textDelta("The quick "), textDelta("brown fox jumps over"), textDelta(" the lazy dog.")},
+			want:  "The quick brown fox jumps over the lazy dog.",
+		},
+		{
+			name:  "continues without overlap",
+			retry: []StreamEvent{textDelta(" jumps over the lazy dog.")},
+			want:  "The quick brown fox jumps over the lazy dog.",
+		},
+		{
+			name:  "repeats a prefix only",
+			retry: []StreamEvent{textDelta("The quick")},
+			want:  "The quick brown fox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumer := NewStreamResumer(nil)
+			first := collectEvents(resumer.Track(ctx, bufferedStream(
+				textDelta("The quick "), textDelta("brown fox"), interrupted,
+			)))
+			if len(first) != 3 || !first[2].IsError() {
+				t.Fatalf("Expected the first attempt to be forwarded, got %+v", first)
+			}
+			if resumer.Received() != "The quick brown fox" {
+				t.Fatalf("Unexpected received text %q", resumer.Received())
+			}
+
+			retry := collectEvents(resumer.Track(ctx, bufferedStream(append(tt.retry, NewDoneEvent(0, FinishReasonStop))...)))
+			if !retry[len(retry)-1].IsDone() {
+				t.Errorf("Expected the retry to end with the done event")
+			}
+			if got := streamText(first) + streamText(retry); got != tt.want {
+				t.Errorf("Unexpected shown text %q", got)
+			}
+			if resumer.Received() != tt.want {
+				t.Errorf("Unexpected transcript %q", resumer.Received())
+			}
+			for i, event := range append(first, retry...) {
+				if event.Seq != uint64(i+1) {
+					t.Errorf("Expected seq %d, got %d", i+1, event.Seq)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamResumer_RetryRequest(t *testing.T) {
+	resumer := NewStreamResumer(&StreamResumeConfig{Prompt: "go on"})
+	req := ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, "Tell me a story")}}
+
+	if retry := resumer.RetryRequest(req); len(retry.Messages) != 1 {
+		t.Errorf("Expected the original request before any text, got %+v", retry.Messages)
+	}
+
+	collectEvents(resumer.Track(context.Background(), bufferedStream(textDelta("Once upon a time"))))
+	retry := resumer.RetryRequest(req)
+	if len(retry.Messages) != 3 || len(req.Messages) != 1 {
+		t.Fatalf("Unexpected messages %+v", retry.Messages)
+	}
+	if retry.Messages[1].Role != RoleAssistant || retry.Messages[1].GetText() != "Once upon a time" {
+		t.Errorf("Expected the partial reply, got %+v", retry.Messages[1])
+	}
+	if retry.Messages[2].Role != RoleUser || retry.Messages[2].GetText() != "go on" {
+		t.Errorf("Expected the continue prompt, got %+v", retry.Messages[2])
+	}
+}
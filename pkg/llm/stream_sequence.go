@@ -0,0 +1,96 @@
+// Package llm provides abstractions for Large Language Model clients
+// stream_sequence.go defines sequence numbers and ordering utilities for stream events
+
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StreamSequencer assigns monotonically increasing sequence numbers and timestamps to
+// the events of a single stream. Providers create one sequencer per stream.
+type StreamSequencer struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+// NewStreamSequencer creates a sequencer whose first event gets sequence number 1
+func NewStreamSequencer() *StreamSequencer {
+	return &StreamSequencer{next: 1}
+}
+
+// Stamp assigns the next sequence number to the event, and the current time if the
+// event has no timestamp yet (so re-sequenced events keep the time they were produced)
+func (s *StreamSequencer) Stamp(event StreamEvent) StreamEvent {
+	s.mu.Lock()
+	event.Seq = s.next
+	s.next++
+	s.mu.Unlock()
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	return event
+}
+
+// StreamSequenceTracker detects gaps and out-of-order events in one or more streams,
+// tracking each Source separately
+type StreamSequenceTracker struct {
+	mu   sync.Mutex
+	last map[string]uint64
+}
+
+// NewStreamSequenceTracker creates a new tracker
+func NewStreamSequenceTracker() *StreamSequenceTracker {
+	return &StreamSequenceTracker{last: make(map[string]uint64)}
+}
+
+// Observe records an event, returning a stream_gap error when events are missing before
+// it and a stream_out_of_order error when it repeats or precedes an already seen event.
+// Events without sequence numbers are ignored.
+func (t *StreamSequenceTracker) Observe(event StreamEvent) error {
+	if event.Seq == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := t.last[event.Source]
+	switch {
+	case event.Seq <= last:
+		return &Error{
+			Code:    "stream_out_of_order",
+			Message: fmt.Sprintf("event %d of stream %q received after event %d", event.Seq, event.Source, last),
+			Type:    "stream_error",
+		}
+	case event.Seq > last+1:
+		t.last[event.Source] = event.Seq
+		return &Error{
+			Code:    "stream_gap",
+			Message: fmt.Sprintf("missing %d events before event %d of stream %q", event.Seq-last-1, event.Seq, event.Source),
+			Type:    "stream_error",
+		}
+	}
+
+	t.last[event.Source] = event.Seq
+	return nil
+}
+
+// SortStreamEvents sorts events collected from multiplexed streams deterministically:
+// by timestamp, then by source, then by sequence number
+func SortStreamEvents(events []StreamEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Seq < b.Seq
+	})
+}
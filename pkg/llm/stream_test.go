@@ -2,6 +2,8 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -477,3 +479,165 @@ func TestMergeStreams_Utility(t *testing.T) {
 		t.Error("Expected tool2 event")
 	}
 }
+
+func TestStreamSequencer(t *testing.T) {
+	sequencer := NewStreamSequencer()
+
+	first := sequencer.Stamp(NewDeltaEvent(0, &MessageDelta{}))
+	second := sequencer.Stamp(NewDoneEvent(0, "stop"))
+	if first.Seq != 1 || second.Seq != 2 {
+		t.Errorf("Expected sequence numbers 1 and 2, got %d and %d", first.Seq, second.Seq)
+	}
+	if first.Timestamp.IsZero() || second.Timestamp.Before(first.Timestamp) {
+		t.Errorf("Expected increasing timestamps, got %v and %v", first.Timestamp, second.Timestamp)
+	}
+
+	// Re-sequencing keeps the original timestamp
+	restamped := NewStreamSequencer().Stamp(second)
+	if restamped.Seq != 1 || !restamped.Timestamp.Equal(second.Timestamp) {
+		t.Errorf("Unexpected re-sequenced event: %+v", restamped)
+	}
+}
+
+func TestStreamSequenceTracker(t *testing.T) {
+	tracker := NewStreamSequenceTracker()
+
+	observe := func(source string, seq uint64) string {
+		err := tracker.Observe(StreamEvent{Type: "delta", Source: source, Seq: seq})
+		if err == nil {
+			return ""
+		}
+		return err.(*Error).Code
+	}
+
+	steps := []struct {
+		source string
+		seq    uint64
+		want   string
+	}{
+		{"llm", 1, ""},
+		{"tool:0", 1, ""},
+		{"llm", 2, ""},
+		{"llm", 5, "stream_gap"},
+		{"llm", 6, ""},
+		{"llm", 6, "stream_out_of_order"},
+		{"tool:0", 2, ""},
+		{"llm", 0, ""},
+	}
+	for _, step := range steps {
+		if got := observe(step.source, step.seq); got != step.want {
+			t.Errorf("Observe(%s, %d) = %q, want %q", step.source, step.seq, got, step.want)
+		}
+	}
+}
+
+func TestSortStreamEvents(t *testing.T) {
+	base := time.Now()
+	events := []StreamEvent{
+		{Source: "tool:0", Seq: 1, Timestamp: base.Add(2 * time.Millisecond)},
+		{Source: "llm", Seq: 2, Timestamp: base.Add(time.Millisecond)},
+		{Source: "llm", Seq: 3, Timestamp: base.Add(2 * time.Millisecond)},
+		{Source: "llm", Seq: 1, Timestamp: base},
+	}
+
+	SortStreamEvents(events)
+
+	want := []string{"llm#1", "llm#2", "llm#3", "tool:0#1"}
+	for i, event := range events {
+		if got := fmt.Sprintf("%s#%d", event.Source, event.Seq); got != want[i] {
+			t.Errorf("Position %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestStreamMerger_Sequencing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sequencer := NewStreamSequencer()
+	llmStream := make(chan StreamEvent, 2)
+	llmStream <- sequencer.Stamp(NewDeltaEvent(0, &MessageDelta{}))
+	llmStream <- sequencer.Stamp(NewDoneEvent(0, "stop"))
+	close(llmStream)
+
+	// Tool events produced without sequence numbers are sequenced by the merger
+	toolStream := make(chan StreamEvent, 2)
+	toolStream <- NewToolStartEvent("tool", "call_1", nil)
+	toolStream <- NewToolDoneEvent("tool", "call_1", nil)
+	close(toolStream)
+
+	tracker := NewStreamSequenceTracker()
+	seen := map[string]int{}
+	for event := range MergeStreams(ctx, llmStream, toolStream) {
+		if err := tracker.Observe(event); err != nil {
+			t.Errorf("Unexpected sequence error: %v", err)
+		}
+		if event.Seq == 0 || event.Timestamp.IsZero() {
+			t.Errorf("Expected sequenced event, got %+v", event)
+		}
+		seen[event.Source]++
+	}
+
+	if seen["llm"] != 2 || seen["tool:0"] != 2 {
+		t.Errorf("Unexpected sources: %v", seen)
+	}
+}
+
+// toolStreamingClient records the tool streams given to StreamChatCompletionWithTools
+type toolStreamingClient struct {
+	*testMockClient
+	toolStreams []<-chan StreamEvent
+}
+
+func (c *toolStreamingClient) StreamChatCompletionWithTools(ctx context.Context, req ChatRequest, toolStreams []<-chan StreamEvent) (<-chan StreamEvent, error) {
+	c.toolStreams = toolStreams
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return MergeStreams(ctx, stream, toolStreams...), nil
+}
+
+func TestStreamChatCompletionWithTools(t *testing.T) {
+	t.Parallel()
+
+	newToolStream := func() <-chan StreamEvent {
+		toolStream := make(chan StreamEvent, 1)
+		toolStream <- NewToolDoneEvent("test_tool", "call_123", map[string]interface{}{"result": "done"})
+		close(toolStream)
+		return toolStream
+	}
+
+	// Clients that do not implement StreamingClient are merged by the function
+	client := NewMockClient("test-model", "test")
+	client.streamEvents = []StreamEvent{NewDeltaEvent(0, &MessageDelta{Content: []MessageContent{NewTextContent("Hello")}})}
+	stream, err := StreamChatCompletionWithTools(context.Background(), client, ChatRequest{}, []<-chan StreamEvent{newToolStream()})
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools failed: %v", err)
+	}
+	sources := map[string]int{}
+	for event := range stream {
+		sources[event.Source]++
+	}
+	if sources["llm"] != 1 || sources["tool:0"] != 1 {
+		t.Errorf("Expected one event of the model and one of the tool, got %v", sources)
+	}
+
+	// StreamingClients merge the streams themselves
+	streaming := &toolStreamingClient{testMockClient: NewMockClient("test-model", "test")}
+	stream, err = StreamChatCompletionWithTools(context.Background(), streaming, ChatRequest{}, []<-chan StreamEvent{newToolStream()})
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools failed: %v", err)
+	}
+	for range stream {
+	}
+	if len(streaming.toolStreams) != 1 {
+		t.Errorf("Expected the client to receive the tool stream, got %d", len(streaming.toolStreams))
+	}
+
+	// Errors opening the stream are returned
+	client.errorToReturn = errors.New("boom")
+	if _, err := StreamChatCompletionWithTools(context.Background(), client, ChatRequest{}, nil); err == nil {
+		t.Error("Expected the error of the client")
+	}
+}
@@ -36,6 +36,7 @@ func ConvertToolStreamToLLMStream(toolEvent ToolStreamEvent, toolCallID string)
 // StreamChannel converts an external tool stream to LLM stream events
 func StreamChannel(ctx context.Context, toolStream <-chan ToolStreamEvent, toolCallID string) <-chan StreamEvent {
 	output := make(chan StreamEvent, 10)
+	sequencer := NewStreamSequencer()
 
 	go func() {
 		defer close(output)
@@ -47,7 +48,7 @@ func StreamChannel(ctx context.Context, toolStream <-chan ToolStreamEvent, toolC
 					return // Tool stream closed
 				}
 
-				llmEvent := ConvertToolStreamToLLMStream(toolEvent, toolCallID)
+				llmEvent := sequencer.Stamp(ConvertToolStreamToLLMStream(toolEvent, toolCallID))
 
 				select {
 				case output <- llmEvent:
@@ -72,18 +73,19 @@ func StreamChannel(ctx context.Context, toolStream <-chan ToolStreamEvent, toolC
 // CreateToolStream creates a simple tool stream from a content string
 func CreateToolStream(ctx context.Context, toolName, toolCallID, content string) <-chan StreamEvent {
 	output := make(chan StreamEvent, 3)
+	sequencer := NewStreamSequencer()
 
 	go func() {
 		defer close(output)
 
 		// Send start event
-		output <- NewToolStartEvent(toolName, toolCallID, nil)
+		output <- sequencer.Stamp(NewToolStartEvent(toolName, toolCallID, nil))
 
 		// Send data event
-		output <- NewToolDataEvent(toolName, toolCallID, content)
+		output <- sequencer.Stamp(NewToolDataEvent(toolName, toolCallID, content))
 
 		// Send done event
-		output <- NewToolDoneEvent(toolName, toolCallID, nil)
+		output <- sequencer.Stamp(NewToolDoneEvent(toolName, toolCallID, nil))
 	}()
 
 	return output
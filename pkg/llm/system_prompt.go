@@ -0,0 +1,198 @@
+// Composition of system prompts from parts
+package llm
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SystemPromptPart is a section of a system prompt, e.g. the persona of the assistant,
+// its rules, instructions for its tools or context computed for every request
+type SystemPromptPart struct {
+	// Name identifies the part, e.g. to replace or remove it
+	Name string `json:"name"`
+
+	// Text is the content of the part
+	Text string `json:"text,omitempty"`
+
+	// Render computes the content of the part for a request instead of Text, e.g. for
+	// dynamic context. Parts rendering an empty text are left out.
+	Render func(req *ChatRequest) string `json:"-"`
+
+	// When includes the part only in the requests it returns true for (see
+	// WhenTools). Parts without a condition are always included.
+	When func(req *ChatRequest) bool `json:"-"`
+
+	// Order places the part, lower first. Parts with the same order keep the order they
+	// were added in.
+	Order int `json:"order,omitempty"`
+
+	// MaxTokens truncates the content of the part to about that many tokens (see
+	// EstimateTextTokens), at a word boundary. Zero means no limit.
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// WhenTools is a SystemPromptPart condition including the part in requests with tools
+func WhenTools(req *ChatRequest) bool {
+	return req != nil && len(req.Tools) > 0
+}
+
+// SystemPromptBuilder composes the system prompt of requests from parts, included by
+// condition, ordered, and truncated to their token budget. It is safe for concurrent
+// builds once configured.
+type SystemPromptBuilder struct {
+	parts     []SystemPromptPart
+	separator string
+	separate  bool
+}
+
+// NewSystemPromptBuilder creates a builder with the given parts, separated by blank lines
+func NewSystemPromptBuilder(parts ...SystemPromptPart) *SystemPromptBuilder {
+	b := &SystemPromptBuilder{separator: "\n\n"}
+	return b.Add(parts...)
+}
+
+// Add adds parts, replacing those with the same names
+func (b *SystemPromptBuilder) Add(parts ...SystemPromptPart) *SystemPromptBuilder {
+	for _, part := range parts {
+		replaced := false
+		for i := range b.parts {
+			if part.Name != "" && b.parts[i].Name == part.Name {
+				b.parts[i] = part
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			b.parts = append(b.parts, part)
+		}
+	}
+	return b
+}
+
+// Remove removes the parts with the given names
+func (b *SystemPromptBuilder) Remove(names ...string) *SystemPromptBuilder {
+	kept := b.parts[:0]
+	for _, part := range b.parts {
+		removed := false
+		for _, name := range names {
+			removed = removed || part.Name == name
+		}
+		if !removed {
+			kept = append(kept, part)
+		}
+	}
+	b.parts = kept
+	return b
+}
+
+// WithSeparator sets the text joining the parts in a single message
+func (b *SystemPromptBuilder) WithSeparator(separator string) *SystemPromptBuilder {
+	b.separator = separator
+	return b
+}
+
+// WithSeparateMessages makes Messages return a system message per part, for the
+// providers accepting several system messages (see Messages)
+func (b *SystemPromptBuilder) WithSeparateMessages() *SystemPromptBuilder {
+	b.separate = true
+	return b
+}
+
+// Texts returns the contents of the parts included in the request, in order
+func (b *SystemPromptBuilder) Texts(req *ChatRequest) []string {
+	parts := make([]SystemPromptPart, 0, len(b.parts))
+	for _, part := range b.parts {
+		if part.When == nil || part.When(req) {
+			parts = append(parts, part)
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Order < parts[j].Order })
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		text := part.Text
+		if part.Render != nil {
+			text = part.Render(req)
+		}
+		text = strings.TrimSpace(text)
+		if part.MaxTokens > 0 {
+			text = truncateToTokens(text, part.MaxTokens)
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// Build returns the system prompt of a request, with the included parts joined by the
+// separator
+func (b *SystemPromptBuilder) Build(req *ChatRequest) string {
+	return strings.Join(b.Texts(req), b.separator)
+}
+
+// Messages returns the system messages of a request sent to a provider: a single one,
+// or one per part with WithSeparateMessages unless the provider merges consecutive
+// messages (see NormalizationRulesFor), as Anthropic, Bedrock and Gemini take a single
+// system prompt. It returns no message when no part is included.
+func (b *SystemPromptBuilder) Messages(req *ChatRequest, provider string) []Message {
+	texts := b.Texts(req)
+	if len(texts) == 0 {
+		return nil
+	}
+	if !b.separate || NormalizationRulesFor(provider).MergeConsecutive {
+		return []Message{NewTextMessage(RoleSystem, strings.Join(texts, b.separator))}
+	}
+	messages := make([]Message, len(texts))
+	for i, text := range texts {
+		messages[i] = NewTextMessage(RoleSystem, text)
+	}
+	return messages
+}
+
+// Apply returns a copy of the request with its system messages replaced by those built
+// for the provider. The other messages are kept in order.
+func (b *SystemPromptBuilder) Apply(req ChatRequest, provider string) ChatRequest {
+	system := b.Messages(&req, provider)
+	messages := make([]Message, 0, len(system)+len(req.Messages))
+	messages = append(messages, system...)
+	for _, msg := range req.Messages {
+		if msg.Role != RoleSystem {
+			messages = append(messages, msg)
+		}
+	}
+	req.Messages = messages
+	return req
+}
+
+// truncateToTokens cuts a text to the longest prefix of about maxTokens tokens ending
+// at a word boundary
+func truncateToTokens(text string, maxTokens int) string {
+	if EstimateTextTokens(text) <= maxTokens {
+		return text
+	}
+	runes := []rune(text)
+	// The estimate grows with the prefix, so the longest prefix within the budget is
+	// found by bisection
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if EstimateTextTokens(string(runes[:mid])) <= maxTokens {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	cut := low
+	if cut < len(runes) && !unicode.IsSpace(runes[cut]) {
+		for cut > 0 && !unicode.IsSpace(runes[cut-1]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = low
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPromptBuilder(t *testing.T) {
+	builder := NewSystemPromptBuilder(
+		SystemPromptPart{Name: "context", Order: 10, MaxTokens: 4, Render: func(req *ChatRequest) string {
+			return "The user is " + req.User + " and prefers short answers."
+		}},
+		SystemPromptPart{Name: "persona", Text: "You are a support agent."},
+		SystemPromptPart{Name: "tools", Text: "Look orders up before answering.", When: WhenTools},
+		SystemPromptPart{Name: "rules", Text: "Never share internal notes."},
+		SystemPromptPart{Name: "empty", Render: func(req *ChatRequest) string { return "  " }},
+	)
+
+	req := &ChatRequest{User: "alice"}
+	assert.Equal(t, []string{
+		"You are a support agent.",
+		"Never share internal notes.",
+		"The user is alice",
+	}, builder.Texts(req), "parts are ordered, conditional and truncated at word boundaries")
+
+	req.Tools = []Tool{{Type: "function", Function: ToolFunction{Name: "lookup_order"}}}
+	assert.Equal(t, "You are a support agent.\n\nLook orders up before answering.\n\nNever share internal notes.\n\nThe user is alice",
+		builder.Build(req))
+
+	// Parts are replaced and removed by name
+	builder.Add(SystemPromptPart{Name: "persona", Text: "You are a sales agent."}).Remove("rules", "context")
+	assert.Equal(t, "You are a sales agent.\n\nLook orders up before answering.", builder.Build(req))
+}
+
+func TestSystemPromptBuilder_Messages(t *testing.T) {
+	builder := NewSystemPromptBuilder(
+		SystemPromptPart{Name: "persona", Text: "You are terse."},
+		SystemPromptPart{Name: "rules", Text: "Answer in French."},
+	).WithSeparateMessages()
+
+	req := ChatRequest{Messages: []Message{
+		NewTextMessage(RoleSystem, "Old prompt"),
+		NewTextMessage(RoleUser, "Hi"),
+	}}
+	openai := builder.Apply(req, "openai")
+	require.Len(t, openai.Messages, 3)
+	assert.Equal(t, "You are terse.", openai.Messages[0].GetText())
+	assert.Equal(t, "Answer in French.", openai.Messages[1].GetText())
+	assert.Equal(t, "Hi", openai.Messages[2].GetText())
+	assert.Equal(t, "Old prompt", req.Messages[0].GetText(), "the request is not modified")
+
+	// Providers taking a single system prompt get the parts joined
+	anthropic := builder.Apply(req, "anthropic")
+	require.Len(t, anthropic.Messages, 2)
+	assert.Equal(t, RoleSystem, anthropic.Messages[0].Role)
+	assert.Equal(t, "You are terse.\n\nAnswer in French.", anthropic.Messages[0].GetText())
+
+	assert.Empty(t, NewSystemPromptBuilder().Messages(&req, "openai"))
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	assert.Equal(t, "short", truncateToTokens("short", 5))
+	assert.Equal(t, "one two", truncateToTokens("one two three four", 2))
+	// Words longer than the budget are cut
+	assert.Equal(t, "abcdef", truncateToTokens("abcdefghijkl", 1))
+}
@@ -0,0 +1,240 @@
+// Token counting estimates for messages and multimodal content
+package llm
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"  // register GIF decoder for image dimension detection
+	_ "image/jpeg" // register JPEG decoder for image dimension detection
+	_ "image/png"  // register PNG decoder for image dimension detection
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token accounting constants
+const (
+	// tokensPerMessage is the per-message overhead of chat formats (role and separators)
+	tokensPerMessage = 3
+	// tokensPerReply primes the assistant reply at the end of a conversation
+	tokensPerReply = 3
+
+	// OpenAI vision tiling: images are fit in 2048x2048, scaled so the shortest side is
+	// 768px and split in 512px tiles costing 170 tokens each, plus a base cost
+	openAIImageBaseTokens = 85
+	openAIImageTileTokens = 170
+
+	// Gemini counts 258 tokens per small image, per 768x768 tile of larger images and per PDF page
+	geminiMediaTokens = 258
+
+	// Claude charges roughly one token per 750 pixels
+	claudePixelsPerToken = 750
+
+	// defaultImageSide is assumed when the dimensions of an image cannot be determined
+	defaultImageSide = 1024
+)
+
+// TokenFamily identifies the tokenization rules used to estimate a model's token counts
+type TokenFamily string
+
+const (
+	// TokenFamilyOpenAI covers GPT and o-series models
+	TokenFamilyOpenAI TokenFamily = "openai"
+	// TokenFamilyGemini covers Gemini and Gemma models
+	TokenFamilyGemini TokenFamily = "gemini"
+	// TokenFamilyClaude covers Anthropic Claude models
+	TokenFamilyClaude TokenFamily = "claude"
+	// TokenFamilyGeneric is used for any other model (OpenAI-like estimates)
+	TokenFamilyGeneric TokenFamily = "generic"
+)
+
+// TokenFamilyForModel guesses the token family of a model from its name
+func TokenFamilyForModel(model string) TokenFamily {
+	model = strings.ToLower(model)
+	switch {
+	case strings.Contains(model, "gemini") || strings.Contains(model, "gemma"):
+		return TokenFamilyGemini
+	case strings.Contains(model, "claude") || strings.Contains(model, "anthropic"):
+		return TokenFamilyClaude
+	case strings.Contains(model, "gpt") || strings.HasPrefix(model, "o1") || strings.HasPrefix(model, "o3") ||
+		strings.HasPrefix(model, "o4") || strings.HasPrefix(model, "openai/"):
+		return TokenFamilyOpenAI
+	}
+	return TokenFamilyGeneric
+}
+
+// EstimateTextTokens estimates the number of tokens in a text. It counts one token per
+// six letters of a word (common words are a single token), one token per punctuation mark
+// and one token per character for CJK scripts, which is close to BPE tokenizers for most content.
+func EstimateTextTokens(text string) int {
+	tokens := 0
+	wordRunes := 0
+
+	flushWord := func() {
+		if wordRunes > 0 {
+			tokens += (wordRunes + 5) / 6
+			wordRunes = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flushWord()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			wordRunes++
+		case unicode.IsSpace(r):
+			flushWord()
+		default:
+			flushWord()
+			tokens++
+		}
+	}
+	flushWord()
+
+	return tokens
+}
+
+// CountMessageTokens estimates the tokens a message uses in the context window of the given
+// model, including role overhead, tool calls and multimodal content
+func CountMessageTokens(model string, msg Message) int {
+	family := TokenFamilyForModel(model)
+
+	tokens := tokensPerMessage
+	for _, content := range msg.Content {
+		tokens += countContentTokens(family, content)
+	}
+
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateTextTokens(call.Function.Name) + EstimateTextTokens(call.Function.Arguments) + tokensPerMessage
+	}
+	if msg.ToolCallID != "" {
+		tokens += EstimateTextTokens(msg.ToolCallID)
+	}
+
+	return tokens
+}
+
+// CountMessagesTokens estimates the tokens of every message in a conversation, returning
+// the per-message counts and the total for the whole prompt (including reply priming)
+func CountMessagesTokens(model string, messages []Message) ([]int, int) {
+	counts := make([]int, len(messages))
+	total := tokensPerReply
+	for i, msg := range messages {
+		counts[i] = CountMessageTokens(model, msg)
+		total += counts[i]
+	}
+	return counts, total
+}
+
+func countContentTokens(family TokenFamily, content MessageContent) int {
+	switch c := content.(type) {
+	case *TextContent:
+		return EstimateTextTokens(c.Text)
+	case *ImageContent:
+		width, height := imageDimensions(c)
+		return EstimateImageTokens(family, width, height)
+	case *FileContent:
+		return estimateFileTokens(family, c)
+	}
+	return 0
+}
+
+// EstimateImageTokens estimates the tokens used by an image of the given dimensions.
+// Unknown dimensions (zero) are treated as a 1024x1024 image.
+func EstimateImageTokens(family TokenFamily, width, height int) int {
+	if width <= 0 || height <= 0 {
+		width, height = defaultImageSide, defaultImageSide
+	}
+
+	switch family {
+	case TokenFamilyGemini:
+		if width <= 384 && height <= 384 {
+			return geminiMediaTokens
+		}
+		tiles := int(math.Ceil(float64(width)/768) * math.Ceil(float64(height)/768))
+		return tiles * geminiMediaTokens
+	case TokenFamilyClaude:
+		return int(math.Ceil(float64(width*height) / claudePixelsPerToken))
+	default:
+		return openAIImageTokens(width, height)
+	}
+}
+
+// openAIImageTokens implements OpenAI's high detail tile counting
+func openAIImageTokens(width, height int) int {
+	w, h := float64(width), float64(height)
+
+	// Fit within a 2048x2048 square
+	if scale := 2048 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	// Scale down so the shortest side is 768px
+	if scale := 768 / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return openAIImageBaseTokens + tiles*openAIImageTileTokens
+}
+
+// imageDimensions returns the known dimensions of an image, decoding the image header
+// when only the data is available
+func imageDimensions(img *ImageContent) (int, int) {
+	if img.Width > 0 && img.Height > 0 {
+		return img.Width, img.Height
+	}
+	if img.HasData() {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(img.Data)); err == nil {
+			return config.Width, config.Height
+		}
+	}
+	return 0, 0
+}
+
+// estimateFileTokens estimates file tokens: text files are counted as text, PDFs per page
+// (media tokens for Gemini, a page image plus its text elsewhere) and other files by size
+func estimateFileTokens(family TokenFamily, file *FileContent) int {
+	if !file.HasData() {
+		return 0
+	}
+
+	if strings.HasPrefix(file.MimeType, "text/") || file.MimeType == "application/json" {
+		if utf8.Valid(file.Data) {
+			return EstimateTextTokens(string(file.Data))
+		}
+	}
+
+	if file.MimeType == "application/pdf" {
+		pages := countPDFPages(file.Data)
+		if family == TokenFamilyGemini {
+			return pages * geminiMediaTokens
+		}
+		return pages*EstimateImageTokens(family, 0, 0) + len(file.Data)/16
+	}
+
+	return len(file.Data) / 4
+}
+
+// countPDFPages counts the page objects of a PDF document, returning at least one page
+func countPDFPages(data []byte) int {
+	pages := 0
+	for _, marker := range [][]byte{[]byte("/Type /Page"), []byte("/Type/Page")} {
+		for i := 0; ; {
+			idx := bytes.Index(data[i:], marker)
+			if idx < 0 {
+				break
+			}
+			end := i + idx + len(marker)
+			// Skip the /Pages tree nodes
+			if end >= len(data) || data[end] != 's' {
+				pages++
+			}
+			i = end
+		}
+	}
+	return max(pages, 1)
+}
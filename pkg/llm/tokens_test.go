@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTextTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTextTokens(""))
+	assert.Equal(t, 2, EstimateTextTokens("Hello world"))
+	assert.Equal(t, 4, EstimateTextTokens("Hello, world!"))
+	assert.Equal(t, 4, EstimateTextTokens("你好世界"))
+	assert.Equal(t, 4, EstimateTextTokens("internationalization"))
+}
+
+func TestEstimateImageTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		family TokenFamily
+		width  int
+		height int
+		want   int
+	}{
+		{"openai 1024 square", TokenFamilyOpenAI, 1024, 1024, 765},
+		{"openai 2048x4096", TokenFamilyOpenAI, 2048, 4096, 1105},
+		{"openai small", TokenFamilyOpenAI, 256, 256, 255},
+		{"gemini small", TokenFamilyGemini, 300, 300, 258},
+		{"gemini tiled", TokenFamilyGemini, 1536, 768, 516},
+		{"claude", TokenFamilyClaude, 1000, 750, 1000},
+		{"unknown dimensions", TokenFamilyOpenAI, 0, 0, 765},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, EstimateImageTokens(tt.family, tt.width, tt.height))
+		})
+	}
+}
+
+func TestTokenFamilyForModel(t *testing.T) {
+	assert.Equal(t, TokenFamilyOpenAI, TokenFamilyForModel("gpt-4o-mini"))
+	assert.Equal(t, TokenFamilyGemini, TokenFamilyForModel("gemini-2.0-flash"))
+	assert.Equal(t, TokenFamilyClaude, TokenFamilyForModel("anthropic.claude-3-sonnet"))
+	assert.Equal(t, TokenFamilyOpenAI, TokenFamilyForModel("o3-mini"))
+	assert.Equal(t, TokenFamilyGeneric, TokenFamilyForModel("llama3"))
+}
+
+func TestCountMessageTokens(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 300, 200))))
+
+	msg := Message{
+		Role: RoleUser,
+		Content: []MessageContent{
+			NewTextContent("Describe this image"),
+			NewImageContentFromBytes(buf.Bytes(), "image/png"),
+		},
+	}
+
+	textOnly := CountMessageTokens("gpt-4o", NewTextMessage(RoleUser, "Describe this image"))
+	assert.Equal(t, tokensPerMessage+EstimateTextTokens("Describe this image"), textOnly)
+
+	// Dimensions are decoded from the PNG header
+	assert.Equal(t, textOnly+EstimateImageTokens(TokenFamilyOpenAI, 300, 200), CountMessageTokens("gpt-4o", msg))
+	assert.Equal(t, textOnly+geminiMediaTokens, CountMessageTokens("gemini-1.5-pro", msg))
+
+	pdf := NewFileContentFromBytes([]byte("%PDF-1.4 /Type /Pages /Type /Page /Type/Page"), "doc.pdf", "application/pdf")
+	pdfMsg := Message{Role: RoleUser, Content: []MessageContent{pdf}}
+	assert.Equal(t, tokensPerMessage+2*geminiMediaTokens, CountMessageTokens("gemini-1.5-pro", pdfMsg))
+
+	counts, total := CountMessagesTokens("gpt-4o", []Message{NewTextMessage(RoleSystem, "Be brief"), msg})
+	require.Len(t, counts, 2)
+	assert.Equal(t, counts[0]+counts[1]+tokensPerReply, total)
+}
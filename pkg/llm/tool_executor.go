@@ -0,0 +1,279 @@
+// Sandboxed execution of tool calls
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ToolHandler runs a tool with the JSON arguments of a call, returning the result sent
+// back to the model. Handlers should return when the context is cancelled.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// ToolExecutorConfig configures a ToolExecutor
+type ToolExecutorConfig struct {
+	// Timeout limits the execution of every tool without its own timeout
+	Timeout time.Duration `json:"timeout"`
+
+	// Timeouts overrides Timeout for some tools, by name
+	Timeouts map[string]time.Duration `json:"timeouts,omitempty"`
+
+	// MaxConcurrency is the number of tool calls run at the same time by the executor
+	MaxConcurrency int `json:"max_concurrency"`
+
+	// LogSize is the number of executions kept by Executions. Negative disables the log.
+	LogSize int `json:"log_size"`
+
+	// OnExecution is called after every execution, e.g. to send it to a logger. It is
+	// called concurrently when calls run in parallel.
+	OnExecution func(ToolExecution) `json:"-"`
+}
+
+// DefaultToolExecutorConfig returns the default executor configuration
+func DefaultToolExecutorConfig() *ToolExecutorConfig {
+	return &ToolExecutorConfig{
+		Timeout:        30 * time.Second,
+		MaxConcurrency: 4,
+		LogSize:        100,
+	}
+}
+
+// ToolExecution is the record of a tool call execution
+type ToolExecution struct {
+	ToolCallID string `json:"tool_call_id"`
+	ToolName   string `json:"tool_name"`
+	Arguments  string `json:"arguments"`
+
+	// Result is the output of the handler, empty when it failed
+	Result string `json:"result,omitempty"`
+
+	// Error describes the failure: "tool_not_found", "invalid_tool_arguments",
+	// "tool_timeout", "tool_panic" or "tool_error" (returned by the handler)
+	Error *ToolExecutionError `json:"error,omitempty"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Failed reports whether the execution failed
+func (e ToolExecution) Failed() bool {
+	return e.Error != nil
+}
+
+// Message returns the tool message answering the call, with the result or the error
+// so the model can react to it
+func (e ToolExecution) Message() Message {
+	text := e.Result
+	if e.Error != nil {
+		text = "Error: " + e.Error.Message
+	}
+	return Message{
+		Role:       RoleTool,
+		Content:    []MessageContent{NewTextContent(text)},
+		ToolCallID: e.ToolCallID,
+	}
+}
+
+// registeredTool is a tool definition with its handler
+type registeredTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// ToolExecutor runs the tool calls requested by a model with registered handlers. Each
+// call runs with a timeout, with its arguments validated against the tool schema, and
+// a panicking handler is recovered: failures become tool error results instead of
+// crashing the caller. The number of calls running at once is limited, and executions
+// are logged. It can be used on its own or through RunToolLoop.
+type ToolExecutor struct {
+	config *ToolExecutorConfig
+	slots  chan struct{}
+
+	mu    sync.RWMutex
+	tools map[string]registeredTool
+
+	logMu sync.Mutex
+	log   []ToolExecution
+}
+
+// NewToolExecutor creates an executor, filling unset config fields with defaults
+func NewToolExecutor(config *ToolExecutorConfig) *ToolExecutor {
+	defaults := DefaultToolExecutorConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = defaults.MaxConcurrency
+	}
+	if config.LogSize == 0 {
+		config.LogSize = defaults.LogSize
+	}
+	return &ToolExecutor{
+		config: config,
+		slots:  make(chan struct{}, config.MaxConcurrency),
+		tools:  make(map[string]registeredTool),
+	}
+}
+
+// Register adds (or replaces) a tool and its handler
+func (e *ToolExecutor) Register(tool Tool, handler ToolHandler) error {
+	if tool.Function.Name == "" || handler == nil {
+		return &Error{
+			Code:    "invalid_tool",
+			Message: "a tool needs a name and a handler",
+			Type:    "validation_error",
+		}
+	}
+	if tool.Type == "" {
+		tool.Type = "function"
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tools[tool.Function.Name] = registeredTool{tool: tool, handler: handler}
+	return nil
+}
+
+// Tools returns the registered tool definitions sorted by name, for ChatRequest.Tools
+func (e *ToolExecutor) Tools() []Tool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	tools := make([]Tool, 0, len(e.tools))
+	for _, registered := range e.tools {
+		tools = append(tools, registered.tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Function.Name < tools[j].Function.Name })
+	return tools
+}
+
+// Execute runs a tool call. Failures are reported in the execution, never as panics.
+func (e *ToolExecutor) Execute(ctx context.Context, call ToolCall) ToolExecution {
+	execution := ToolExecution{
+		ToolCallID: call.ID,
+		ToolName:   call.Function.Name,
+		Arguments:  call.Function.Arguments,
+		StartedAt:  time.Now(),
+	}
+
+	e.mu.RLock()
+	registered, ok := e.tools[call.Function.Name]
+	e.mu.RUnlock()
+	switch {
+	case !ok:
+		execution.Error = toolError("tool_not_found", fmt.Sprintf("tool %q is not available", call.Function.Name))
+	case registered.tool.Function.Parameters != nil:
+		if err := registered.tool.ValidateArguments(call.Function.Arguments); err != nil {
+			execution.Error = toolError("invalid_tool_arguments", err.Error())
+		}
+	}
+	if execution.Error == nil {
+		execution.Result, execution.Error = e.run(ctx, registered.handler, call)
+	}
+
+	execution.Duration = time.Since(execution.StartedAt)
+	e.record(execution)
+	return execution
+}
+
+// ExecuteAll runs tool calls concurrently (within MaxConcurrency) and returns their
+// executions in the order of the calls
+func (e *ToolExecutor) ExecuteAll(ctx context.Context, calls []ToolCall) []ToolExecution {
+	executions := make([]ToolExecution, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			executions[i] = e.Execute(ctx, call)
+		}()
+	}
+	wg.Wait()
+	return executions
+}
+
+// Executions returns the latest executions, oldest first
+func (e *ToolExecutor) Executions() []ToolExecution {
+	e.logMu.Lock()
+	defer e.logMu.Unlock()
+	return append([]ToolExecution(nil), e.log...)
+}
+
+// run calls a handler in a slot, with the tool timeout and panic recovery. A handler
+// ignoring the cancellation of its context keeps running in the background, but its
+// result is discarded and its slot released.
+func (e *ToolExecutor) run(ctx context.Context, handler ToolHandler, call ToolCall) (string, *ToolExecutionError) {
+	select {
+	case e.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", toolError("tool_timeout", fmt.Sprintf("tool %q was not started: %v", call.Function.Name, ctx.Err()))
+	}
+	defer func() { <-e.slots }()
+
+	timeout := e.config.Timeout
+	if override, ok := e.config.Timeouts[call.Function.Name]; ok && override > 0 {
+		timeout = override
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    *ToolExecutionError
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err := toolError("tool_panic", fmt.Sprintf("tool %q panicked: %v", call.Function.Name, recovered))
+				err.Details = map[string]interface{}{"stack": string(debug.Stack())}
+				done <- outcome{err: err}
+			}
+		}()
+		result, err := handler(ctx, call.Function.Arguments)
+		if err != nil {
+			done <- outcome{err: toolError("tool_error", err.Error())}
+			return
+		}
+		done <- outcome{result: result}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", toolError("tool_timeout", fmt.Sprintf("tool %q timed out after %s", call.Function.Name, timeout))
+		}
+		return out.result, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", toolError("tool_timeout", fmt.Sprintf("tool %q timed out after %s", call.Function.Name, timeout))
+		}
+		return "", toolError("tool_error", fmt.Sprintf("tool %q was cancelled: %v", call.Function.Name, ctx.Err()))
+	}
+}
+
+// record adds an execution to the log and reports it to the OnExecution callback
+func (e *ToolExecutor) record(execution ToolExecution) {
+	if e.config.LogSize > 0 {
+		e.logMu.Lock()
+		e.log = append(e.log, execution)
+		if len(e.log) > e.config.LogSize {
+			e.log = append(e.log[:0], e.log[len(e.log)-e.config.LogSize:]...)
+		}
+		e.logMu.Unlock()
+	}
+	if e.config.OnExecution != nil {
+		e.config.OnExecution(execution)
+	}
+}
+
+// toolError creates a tool execution error
+func toolError(code, message string) *ToolExecutionError {
+	return &ToolExecutionError{Code: code, Message: message, Type: "tool_error"}
+}
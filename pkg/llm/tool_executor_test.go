@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// toolCall creates a call to a tool
+func toolCall(id, name, arguments string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: ToolCallFunction{Name: name, Arguments: arguments}}
+}
+
+// functionTool creates a tool definition
+func functionTool(name string, parameters any) Tool {
+	return Tool{Type: "function", Function: ToolFunction{Name: name, Parameters: parameters}}
+}
+
+func TestToolExecutor_Execute(t *testing.T) {
+	var logged []ToolExecution
+	executor := NewToolExecutor(&ToolExecutorConfig{
+		Timeout:     time.Second,
+		Timeouts:    map[string]time.Duration{"slow": 20 * time.Millisecond},
+		OnExecution: func(execution ToolExecution) { logged = append(logged, execution) },
+	})
+	weatherParameters := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		"required":   []any{"city"},
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+	must(executor.Register(functionTool("weather", weatherParameters), func(ctx context.Context, arguments string) (string, error) {
+		return "sunny", nil
+	}))
+	must(executor.Register(functionTool("slow", nil), func(ctx context.Context, arguments string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	must(executor.Register(functionTool("crash", nil), func(ctx context.Context, arguments string) (string, error) {
+		var m map[string]int
+		m["boom"]++
+		return "", nil
+	}))
+	must(executor.Register(functionTool("failing", nil), func(ctx context.Context, arguments string) (string, error) {
+		return "", errors.New("backend unavailable")
+	}))
+
+	tests := []struct {
+		call   ToolCall
+		result string
+		code   string
+	}{
+		{call: toolCall("1", "weather", `{"city":"Paris"}`), result: "sunny"},
+		{call: toolCall("2", "weather", `{}`), code: "invalid_tool_arguments"},
+		{call: toolCall("3", "slow", `{}`), code: "tool_timeout"},
+		{call: toolCall("4", "crash", `{}`), code: "tool_panic"},
+		{call: toolCall("5", "failing", `{}`), code: "tool_error"},
+		{call: toolCall("6", "unknown", `{}`), code: "tool_not_found"},
+	}
+	for _, tt := range tests {
+		execution := executor.Execute(context.Background(), tt.call)
+		if execution.ToolCallID != tt.call.ID || execution.Result != tt.result {
+			t.Errorf("%s: unexpected execution %+v", tt.call.Function.Name, execution)
+		}
+		if tt.code == "" {
+			if execution.Failed() {
+				t.Errorf("%s: unexpected error %+v", tt.call.Function.Name, execution.Error)
+			}
+			continue
+		}
+		if !execution.Failed() || execution.Error.Code != tt.code {
+			t.Errorf("%s: expected %s, got %+v", tt.call.Function.Name, tt.code, execution.Error)
+			continue
+		}
+		message := execution.Message()
+		if message.Role != RoleTool || message.ToolCallID != tt.call.ID || !strings.HasPrefix(message.GetText(), "Error: ") {
+			t.Errorf("%s: unexpected tool message %+v", tt.call.Function.Name, message)
+		}
+	}
+
+	if len(logged) != len(tests) || len(executor.Executions()) != len(tests) {
+		t.Errorf("Expected %d logged executions, got %d and %d", len(tests), len(logged), len(executor.Executions()))
+	}
+	if names := executor.Tools(); len(names) != 4 || names[0].Function.Name != "crash" {
+		t.Errorf("Unexpected tools %+v", names)
+	}
+	if err := executor.Register(Tool{}, nil); err == nil {
+		t.Error("Expected an error for an invalid tool")
+	}
+}
+
+func TestToolExecutor_ExecuteAll(t *testing.T) {
+	executor := NewToolExecutor(&ToolExecutorConfig{MaxConcurrency: 2, LogSize: 3})
+
+	var running, peak atomic.Int32
+	err := executor.Register(functionTool("echo", nil), func(ctx context.Context, arguments string) (string, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := peak.Load()
+			if current <= observed || peak.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return arguments, nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	calls := []ToolCall{
+		toolCall("a", "echo", "1"), toolCall("b", "echo", "2"), toolCall("c", "echo", "3"),
+		toolCall("d", "echo", "4"), toolCall("e", "echo", "5"),
+	}
+	executions := executor.ExecuteAll(context.Background(), calls)
+	for i, execution := range executions {
+		if execution.ToolCallID != calls[i].ID || execution.Result != calls[i].Function.Arguments {
+			t.Errorf("Unexpected execution %d: %+v", i, execution)
+		}
+	}
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent executions, got %d", peak.Load())
+	}
+	if len(executor.Executions()) != 3 {
+		t.Errorf("Expected the log to keep 3 executions, got %d", len(executor.Executions()))
+	}
+}
+
+func TestRunToolLoop(t *testing.T) {
+	executor := NewToolExecutor(nil)
+	err := executor.Register(functionTool("weather", nil), func(ctx context.Context, arguments string) (string, error) {
+		return "sunny", nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	toolResponse := &ChatResponse{
+		Choices: []Choice{{Message: Message{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall("call-1", "weather", `{}`)}}}},
+		Usage:   Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}
+	client := NewMockClient("m", "test")
+	client.responses = []*ChatResponse{toolResponse}
+
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Weather in Paris?")}}
+	result, err := RunToolLoop(context.Background(), client, req, executor, nil)
+	if err != nil {
+		t.Fatalf("RunToolLoop failed: %v", err)
+	}
+	if result.Iterations != 2 || result.Usage.TotalTokens != 27 || len(result.Executions) != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if len(result.Messages) != 4 || result.Messages[2].Role != RoleTool || result.Messages[2].GetText() != "sunny" {
+		t.Errorf("Unexpected conversation %+v", result.Messages)
+	}
+	calls := client.GetCallLog()
+	if len(calls[0].Tools) != 1 || len(calls[1].Messages) != 3 {
+		t.Errorf("Expected the executor tools and the tool result to be sent, got %+v", calls)
+	}
+
+	// The loop stops when the model keeps calling tools
+	client.responses = []*ChatResponse{toolResponse, toolResponse}
+	result, err = RunToolLoop(context.Background(), client, req, executor, &ToolLoopConfig{MaxIterations: 2})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "loop_guard_exceeded" || llmErr.LoopGuard.Guard != LoopGuardIterations {
+		t.Fatalf("Expected loop_guard_exceeded on max_iterations, got %v", err)
+	}
+	if result.Iterations != 2 || len(result.Executions) != 2 {
+		t.Errorf("Expected the partial result, got %+v", result)
+	}
+}
+
+func TestRunToolLoopGuards(t *testing.T) {
+	executor := NewToolExecutor(nil)
+	err := executor.Register(functionTool("weather", nil), func(ctx context.Context, arguments string) (string, error) {
+		return "sunny", nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	toolResponse := &ChatResponse{
+		Choices: []Choice{{Message: Message{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall("call-1", "weather", `{}`)}}}},
+		Usage:   Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Weather in Paris?")}}
+
+	tests := []struct {
+		name   string
+		config *ToolLoopConfig
+		guard  LoopGuard
+		calls  int
+	}{
+		{"tokens", &ToolLoopConfig{MaxTokens: 200}, LoopGuardTokens, 2},
+		{"cost", &ToolLoopConfig{MaxCost: 0.01, Cost: func(u Usage) float64 { return float64(u.TotalTokens) * 0.00005 }}, LoopGuardCost, 2},
+		{"iterations default", &ToolLoopConfig{MaxTokens: 1_000_000}, LoopGuardIterations, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient("m", "test")
+			for i := 0; i < 20; i++ {
+				client.responses = append(client.responses, toolResponse)
+			}
+
+			result, err := RunToolLoop(context.Background(), client, req, executor, tt.config)
+			var llmErr *Error
+			if !errors.As(err, &llmErr) || llmErr.Code != "loop_guard_exceeded" || llmErr.LoopGuard == nil {
+				t.Fatalf("Expected loop_guard_exceeded, got %v", err)
+			}
+			if llmErr.LoopGuard.Guard != tt.guard || llmErr.LoopGuard.Value < llmErr.LoopGuard.Limit {
+				t.Errorf("Unexpected guard %+v", llmErr.LoopGuard)
+			}
+			if result.Iterations != tt.calls || len(client.GetCallLog()) != tt.calls {
+				t.Errorf("Expected %d model calls, got %d", tt.calls, result.Iterations)
+			}
+			// The partial conversation ends with the tool results
+			if last := result.Messages[len(result.Messages)-1]; last.Role != RoleTool {
+				t.Errorf("Expected the conversation to end with a tool message, got %s", last.Role)
+			}
+		})
+	}
+
+	// A final answer is returned even when it exceeds a guard
+	client := NewMockClient("m", "test")
+	result, err := RunToolLoop(context.Background(), client, req, executor, &ToolLoopConfig{MaxTokens: 1})
+	if err != nil || result.Iterations != 1 {
+		t.Errorf("Expected the answer without error, got %+v, %v", result, err)
+	}
+
+	if _, err := RunToolLoop(context.Background(), client, req, executor, &ToolLoopConfig{MaxCost: 1}); err == nil {
+		t.Error("Expected an error for MaxCost without a Cost function")
+	}
+}
+
+func TestRunToolLoopMaxDuration(t *testing.T) {
+	executor := NewToolExecutor(nil)
+	err := executor.Register(functionTool("slow", nil), func(ctx context.Context, arguments string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	client := NewMockClient("m", "test")
+	client.responses = []*ChatResponse{{
+		Choices: []Choice{{Message: Message{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall("call-1", "slow", `{}`)}}}},
+	}}
+
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleUser, "Do something slow")}}
+	start := time.Now()
+	result, err := RunToolLoop(context.Background(), client, req, executor, &ToolLoopConfig{MaxDuration: 50 * time.Millisecond})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.LoopGuard == nil || llmErr.LoopGuard.Guard != LoopGuardDuration {
+		t.Fatalf("Expected loop_guard_exceeded on max_duration, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the slow tool to be interrupted, took %s", elapsed)
+	}
+	if result.Iterations != 1 || len(result.Executions) != 1 || result.Duration < 50*time.Millisecond {
+		t.Errorf("Unexpected partial result %+v", result)
+	}
+}
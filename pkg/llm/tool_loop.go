@@ -0,0 +1,194 @@
+// Tool-calling loop driving a model and a ToolExecutor
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ToolLoopConfig configures RunToolLoop. The guards stop loops that would otherwise run
+// away; a zero value disables a guard, except MaxIterations which defaults to 10.
+type ToolLoopConfig struct {
+	// MaxIterations limits the model calls made by the loop
+	MaxIterations int `json:"max_iterations"`
+
+	// MaxDuration limits the wall time of the loop, model calls and tool executions
+	// included
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+
+	// MaxTokens limits the total tokens used by the model calls of the loop
+	MaxTokens int `json:"max_tokens,omitempty"`
+
+	// MaxCost limits the cost of the model calls of the loop, as computed by Cost
+	MaxCost float64 `json:"max_cost,omitempty"`
+
+	// Cost returns the cost of the usage of a model call, in the unit of MaxCost.
+	// Required by MaxCost.
+	Cost func(usage Usage) float64 `json:"-"`
+}
+
+// DefaultToolLoopConfig returns the default tool loop configuration
+func DefaultToolLoopConfig() *ToolLoopConfig {
+	return &ToolLoopConfig{MaxIterations: 10}
+}
+
+// LoopGuard names a guard of ToolLoopConfig
+type LoopGuard string
+
+const (
+	LoopGuardIterations LoopGuard = "max_iterations"
+	LoopGuardDuration   LoopGuard = "max_duration"
+	LoopGuardTokens     LoopGuard = "max_tokens"
+	LoopGuardCost       LoopGuard = "max_cost"
+)
+
+// LoopGuardInfo describes the guard that stopped a tool loop, in the "loop_guard_exceeded"
+// error returned with the partial result
+type LoopGuardInfo struct {
+	// Guard is the exceeded guard
+	Guard LoopGuard `json:"guard"`
+
+	// Limit is the configured limit (seconds for LoopGuardDuration)
+	Limit float64 `json:"limit"`
+
+	// Value is the value reached by the loop, in the unit of Limit
+	Value float64 `json:"value"`
+}
+
+// ToolLoopResult is the outcome of a tool loop
+type ToolLoopResult struct {
+	// Response is the last response of the model
+	Response *ChatResponse `json:"response,omitempty"`
+
+	// Messages is the conversation, with the assistant and tool messages of the loop
+	Messages []Message `json:"messages"`
+
+	// Executions are the tool calls executed, in order
+	Executions []ToolExecution `json:"executions,omitempty"`
+
+	// Iterations is the number of model calls made
+	Iterations int `json:"iterations"`
+
+	// Usage is the token usage of all the model calls
+	Usage Usage `json:"usage"`
+
+	// Cost is the cost of all the model calls, when ToolLoopConfig.Cost is set
+	Cost float64 `json:"cost,omitempty"`
+
+	// Duration is the wall time of the loop
+	Duration time.Duration `json:"duration"`
+}
+
+// RunToolLoop sends the request and executes the tool calls of the responses with the
+// executor, sending the results back to the model until it answers without tool calls.
+// The executor tools are used when the request has none. The guards of the configuration
+// are checked before every model call, and MaxDuration also interrupts the call or tool
+// execution in progress: when one is exceeded while the model is still calling tools,
+// the result so far is returned with a "loop_guard_exceeded" error whose LoopGuard tells
+// which guard stopped the loop.
+func RunToolLoop(ctx context.Context, client ChatCompleter, req ChatRequest, executor *ToolExecutor, config *ToolLoopConfig) (*ToolLoopResult, error) {
+	if config == nil {
+		config = DefaultToolLoopConfig()
+	}
+	if config.MaxIterations <= 0 {
+		defaults := *config
+		defaults.MaxIterations = DefaultToolLoopConfig().MaxIterations
+		config = &defaults
+	}
+	if config.MaxCost > 0 && config.Cost == nil {
+		return nil, &Error{
+			Code:    "invalid_config",
+			Message: "tool loop MaxCost requires a Cost function",
+			Type:    "validation_error",
+		}
+	}
+	if len(req.Tools) == 0 {
+		req.Tools = executor.Tools()
+	}
+
+	start := time.Now()
+	loopCtx := ctx
+	if config.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithTimeout(ctx, config.MaxDuration)
+		defer cancel()
+	}
+
+	result := &ToolLoopResult{Messages: append([]Message(nil), req.Messages...)}
+	for {
+		result.Duration = time.Since(start)
+		if info := config.exceeded(result); info != nil {
+			return result, loopGuardError(info)
+		}
+
+		req.Messages = result.Messages
+		resp, err := client.ChatCompletion(loopCtx, req)
+		if err != nil {
+			result.Duration = time.Since(start)
+			if durationExceeded(ctx, loopCtx) {
+				return result, loopGuardError(config.durationInfo(result))
+			}
+			return result, err
+		}
+		result.Iterations++
+		result.Response = resp
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+		if config.Cost != nil {
+			result.Cost += config.Cost(resp.Usage)
+		}
+
+		if len(resp.Choices) == 0 {
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+		message := resp.Choices[0].Message
+		result.Messages = append(result.Messages, message)
+		if !message.HasToolCalls() {
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+
+		for _, execution := range executor.ExecuteAll(loopCtx, message.ToolCalls) {
+			result.Executions = append(result.Executions, execution)
+			result.Messages = append(result.Messages, execution.Message())
+		}
+	}
+}
+
+// exceeded returns the first guard exceeded by the loop, or nil
+func (c *ToolLoopConfig) exceeded(result *ToolLoopResult) *LoopGuardInfo {
+	switch {
+	case result.Iterations >= c.MaxIterations:
+		return &LoopGuardInfo{Guard: LoopGuardIterations, Limit: float64(c.MaxIterations), Value: float64(result.Iterations)}
+	case c.MaxDuration > 0 && result.Duration >= c.MaxDuration:
+		return c.durationInfo(result)
+	case c.MaxTokens > 0 && result.Usage.TotalTokens >= c.MaxTokens:
+		return &LoopGuardInfo{Guard: LoopGuardTokens, Limit: float64(c.MaxTokens), Value: float64(result.Usage.TotalTokens)}
+	case c.MaxCost > 0 && result.Cost >= c.MaxCost:
+		return &LoopGuardInfo{Guard: LoopGuardCost, Limit: c.MaxCost, Value: result.Cost}
+	}
+	return nil
+}
+
+func (c *ToolLoopConfig) durationInfo(result *ToolLoopResult) *LoopGuardInfo {
+	return &LoopGuardInfo{Guard: LoopGuardDuration, Limit: c.MaxDuration.Seconds(), Value: result.Duration.Seconds()}
+}
+
+// durationExceeded reports whether the loop context expired on MaxDuration rather than
+// the caller's context being done
+func durationExceeded(ctx, loopCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(loopCtx.Err(), context.DeadlineExceeded)
+}
+
+func loopGuardError(info *LoopGuardInfo) *Error {
+	return &Error{
+		Code:      "loop_guard_exceeded",
+		Message:   fmt.Sprintf("tool loop stopped by the %s guard (limit %g, reached %g) while the model was still calling tools", info.Guard, info.Limit, info.Value),
+		Type:      "validation_error",
+		LoopGuard: info,
+	}
+}
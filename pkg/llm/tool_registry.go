@@ -0,0 +1,310 @@
+// Namespaced tool registry
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ToolNamespaceSeparator separates the namespaces of a qualified tool name ("fs.read")
+const ToolNamespaceSeparator = "."
+
+// DefaultToolWireSeparator replaces ToolNamespaceSeparator in the tool names sent to
+// providers, which only accept letters, digits, "_" and "-" ("fs.read" becomes "fs__read")
+const DefaultToolWireSeparator = "__"
+
+// maxToolNameLength is the longest function name accepted by the providers
+const maxToolNameLength = 64
+
+// toolNamePartPattern matches a namespace or tool name part
+var toolNamePartPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// registryEntry is a tool of a registry
+type registryEntry struct {
+	name     string // qualified name
+	wireName string
+	tool     Tool
+	handler  ToolHandler
+	disabled bool
+}
+
+// ToolRegistry holds tools under namespaced names such as "fs.read" or "web.search", for
+// agents managing many tools. Tools can be enabled and disabled individually or by
+// namespace, and exported for ChatRequest.Tools filtered by namespace:
+//
+//	registry := llm.NewToolRegistry()
+//	registry.Register("fs", readTool, readHandler)     // "fs.read"
+//	registry.Register("web", searchTool, searchHandler) // "web.search"
+//	req.Tools = registry.Tools("fs")
+//
+// Providers do not accept dots in function names, so the exported tools are named with
+// the wire separator ("fs__read"), as are the tool calls of the responses. Lookup
+// accepts both forms, and QualifiedName maps the called names back.
+type ToolRegistry struct {
+	wireSeparator string
+
+	mu      sync.RWMutex
+	entries map[string]*registryEntry // by qualified name
+	wire    map[string]*registryEntry // by wire name
+}
+
+// NewToolRegistry creates an empty registry using DefaultToolWireSeparator
+func NewToolRegistry() *ToolRegistry {
+	return NewToolRegistryWithSeparator(DefaultToolWireSeparator)
+}
+
+// NewToolRegistryWithSeparator creates an empty registry replacing the namespace
+// separator with wireSeparator in the exported tool names
+func NewToolRegistryWithSeparator(wireSeparator string) *ToolRegistry {
+	return &ToolRegistry{
+		wireSeparator: wireSeparator,
+		entries:       make(map[string]*registryEntry),
+		wire:          make(map[string]*registryEntry),
+	}
+}
+
+// QualifiedToolName joins a namespace and a tool name. The namespace can be nested
+// ("cloud.s3") or empty.
+func QualifiedToolName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ToolNamespaceSeparator + name
+}
+
+// SplitToolName splits a qualified tool name into its namespace and its name
+func SplitToolName(qualified string) (namespace, name string) {
+	if i := strings.LastIndex(qualified, ToolNamespaceSeparator); i >= 0 {
+		return qualified[:i], qualified[i+len(ToolNamespaceSeparator):]
+	}
+	return "", qualified
+}
+
+// Register adds a tool under a namespace. The handler is optional, and only needed by
+// the executors built with Executor. Registering a name twice is an error with the
+// "duplicate_tool" code, as is a name colliding with another tool once exported.
+func (r *ToolRegistry) Register(namespace string, tool Tool, handler ToolHandler) error {
+	qualified := QualifiedToolName(namespace, tool.Function.Name)
+	for _, part := range strings.Split(qualified, ToolNamespaceSeparator) {
+		if !toolNamePartPattern.MatchString(part) {
+			return &Error{
+				Code:    "invalid_tool",
+				Message: fmt.Sprintf("invalid tool name %q: namespaces and names need letters, digits, '_' or '-'", qualified),
+				Type:    "validation_error",
+			}
+		}
+	}
+	wireName := strings.ReplaceAll(qualified, ToolNamespaceSeparator, r.wireSeparator)
+	if len(wireName) > maxToolNameLength {
+		return &Error{
+			Code:    "invalid_tool",
+			Message: fmt.Sprintf("tool name %q is longer than %d characters", wireName, maxToolNameLength),
+			Type:    "validation_error",
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.entries[qualified]; exists {
+		return &Error{
+			Code:    "duplicate_tool",
+			Message: fmt.Sprintf("tool %q is already registered", qualified),
+			Type:    "validation_error",
+		}
+	}
+	if existing, exists := r.wire[wireName]; exists {
+		return &Error{
+			Code:    "duplicate_tool",
+			Message: fmt.Sprintf("tool %q collides with %q as %q", qualified, existing.name, wireName),
+			Type:    "validation_error",
+		}
+	}
+
+	if tool.Type == "" {
+		tool.Type = "function"
+	}
+	tool.Function.Name = wireName
+	entry := &registryEntry{name: qualified, wireName: wireName, tool: tool, handler: handler}
+	r.entries[qualified] = entry
+	r.wire[wireName] = entry
+	return nil
+}
+
+// Unregister removes a tool, returning whether it was registered
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.lookup(name)
+	if entry == nil {
+		return false
+	}
+	delete(r.entries, entry.name)
+	delete(r.wire, entry.wireName)
+	return true
+}
+
+// Enable enables a tool, or all the tools of a namespace. It returns an error with the
+// "tool_not_found" code when nothing matches.
+func (r *ToolRegistry) Enable(name string) error {
+	return r.setDisabled(name, false)
+}
+
+// Disable disables a tool, or all the tools of a namespace, excluding them from Tools
+// and the executors. It returns an error with the "tool_not_found" code when nothing
+// matches.
+func (r *ToolRegistry) Disable(name string) error {
+	return r.setDisabled(name, true)
+}
+
+// Enabled reports whether a tool is registered and enabled
+func (r *ToolRegistry) Enabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.lookup(name)
+	return entry != nil && !entry.disabled
+}
+
+// Lookup returns a tool, by qualified or wire name, as exported by Tools
+func (r *ToolRegistry) Lookup(name string) (Tool, ToolHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.lookup(name)
+	if entry == nil {
+		return Tool{}, nil, false
+	}
+	return entry.tool, entry.handler, true
+}
+
+// QualifiedName returns the qualified name of a tool called by its wire name, as found
+// in the tool calls of a response
+func (r *ToolRegistry) QualifiedName(wireName string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if entry := r.lookup(wireName); entry != nil {
+		return entry.name, true
+	}
+	return "", false
+}
+
+// Names returns the sorted qualified names of the enabled tools in the given namespaces
+// (all of them when none is given)
+func (r *ToolRegistry) Names(namespaces ...string) []string {
+	entries := r.enabled(namespaces)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.name
+	}
+	return names
+}
+
+// Namespaces returns the sorted top-level namespaces of the registered tools
+func (r *ToolRegistry) Namespaces() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool)
+	var namespaces []string
+	for name := range r.entries {
+		namespace, _, found := strings.Cut(name, ToolNamespaceSeparator)
+		if found && !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// Tools returns the enabled tools of the given namespaces (all of them when none is
+// given) sorted by name, for ChatRequest.Tools. A namespace includes its nested
+// namespaces: "cloud" selects "cloud.s3.get".
+func (r *ToolRegistry) Tools(namespaces ...string) []Tool {
+	entries := r.enabled(namespaces)
+	tools := make([]Tool, len(entries))
+	for i, entry := range entries {
+		tools[i] = entry.tool
+	}
+	return tools
+}
+
+// Executor creates a ToolExecutor running the enabled tools of the given namespaces (all
+// of them when none is given), for RunToolLoop. Tools registered without a handler are
+// skipped. The executor is a snapshot: later registry changes do not affect it.
+func (r *ToolRegistry) Executor(config *ToolExecutorConfig, namespaces ...string) (*ToolExecutor, error) {
+	executor := NewToolExecutor(config)
+	for _, entry := range r.enabled(namespaces) {
+		if entry.handler == nil {
+			continue
+		}
+		if err := executor.Register(entry.tool, entry.handler); err != nil {
+			return nil, err
+		}
+	}
+	return executor, nil
+}
+
+// lookup finds an entry by qualified or wire name. The lock must be held.
+func (r *ToolRegistry) lookup(name string) *registryEntry {
+	if entry, ok := r.entries[name]; ok {
+		return entry
+	}
+	return r.wire[name]
+}
+
+// setDisabled updates a tool, or the tools of a namespace
+func (r *ToolRegistry) setDisabled(name string, disabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry := r.lookup(name); entry != nil {
+		entry.disabled = disabled
+		return nil
+	}
+
+	found := false
+	for _, entry := range r.entries {
+		if inToolNamespace(entry.name, name) {
+			entry.disabled = disabled
+			found = true
+		}
+	}
+	if !found {
+		return &Error{
+			Code:    "tool_not_found",
+			Message: fmt.Sprintf("no tool or namespace %q", name),
+			Type:    "validation_error",
+		}
+	}
+	return nil
+}
+
+// enabled returns the enabled entries of some namespaces, sorted by qualified name
+func (r *ToolRegistry) enabled(namespaces []string) []*registryEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var entries []*registryEntry
+	for _, entry := range r.entries {
+		if entry.disabled {
+			continue
+		}
+		selected := len(namespaces) == 0
+		for _, namespace := range namespaces {
+			if inToolNamespace(entry.name, namespace) {
+				selected = true
+				break
+			}
+		}
+		if selected {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
+
+// inToolNamespace reports whether a qualified tool name is in a namespace, or in one of
+// its nested namespaces
+func inToolNamespace(qualified, namespace string) bool {
+	return strings.HasPrefix(qualified, namespace+ToolNamespaceSeparator)
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRegistry creates a registry with file system, web and cloud tools
+func newTestRegistry(t *testing.T) *ToolRegistry {
+	t.Helper()
+	registry := NewToolRegistry()
+	handler := func(ctx context.Context, arguments string) (string, error) { return "ok", nil }
+	require.NoError(t, registry.Register("fs", functionTool("read", nil), handler))
+	require.NoError(t, registry.Register("fs", functionTool("write", nil), handler))
+	require.NoError(t, registry.Register("web", functionTool("search", nil), handler))
+	require.NoError(t, registry.Register("cloud.s3", functionTool("get", nil), nil))
+	require.NoError(t, registry.Register("", functionTool("now", nil), handler))
+	return registry
+}
+
+func TestToolRegistry_Register(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	tests := []struct {
+		name      string
+		namespace string
+		tool      string
+		code      string
+	}{
+		{name: "duplicate", namespace: "fs", tool: "read", code: "duplicate_tool"},
+		{name: "wire name collision", namespace: "", tool: "fs__read", code: "duplicate_tool"},
+		{name: "empty name", namespace: "fs", tool: "", code: "invalid_tool"},
+		{name: "invalid characters", namespace: "f s", tool: "read", code: "invalid_tool"},
+		{name: "too long", namespace: strings.Repeat("n", 60), tool: "read", code: "invalid_tool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Register(tt.namespace, functionTool(tt.tool, nil), nil)
+			var llmErr *Error
+			require.True(t, errors.As(err, &llmErr), "expected an error, got %v", err)
+			assert.Equal(t, tt.code, llmErr.Code)
+		})
+	}
+
+	assert.Equal(t, []string{"cloud", "fs", "web"}, registry.Namespaces())
+	assert.True(t, registry.Unregister("fs__write"))
+	assert.False(t, registry.Unregister("fs.write"))
+}
+
+func TestToolRegistry_Tools(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	assert.Equal(t, []string{"cloud.s3.get", "fs.read", "fs.write", "now", "web.search"}, registry.Names())
+	assert.Equal(t, []string{"cloud.s3.get", "web.search"}, registry.Names("cloud", "web"))
+	assert.Empty(t, registry.Names("f"), "namespaces match whole parts")
+
+	tools := registry.Tools("fs")
+	require.Len(t, tools, 2)
+	assert.Equal(t, "fs__read", tools[0].Function.Name)
+	assert.Equal(t, "function", tools[0].Type)
+
+	name, ok := registry.QualifiedName("cloud__s3__get")
+	assert.True(t, ok)
+	assert.Equal(t, "cloud.s3.get", name)
+	tool, _, ok := registry.Lookup("web.search")
+	assert.True(t, ok)
+	assert.Equal(t, "web__search", tool.Function.Name)
+}
+
+func TestToolRegistry_EnableDisable(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	require.NoError(t, registry.Disable("fs"))
+	require.NoError(t, registry.Disable("web__search"))
+	assert.Equal(t, []string{"cloud.s3.get", "now"}, registry.Names())
+	assert.False(t, registry.Enabled("fs.read"))
+
+	require.NoError(t, registry.Enable("fs.read"))
+	assert.True(t, registry.Enabled("fs__read"))
+	assert.Equal(t, []string{"fs.read"}, registry.Names("fs"))
+
+	var llmErr *Error
+	require.True(t, errors.As(registry.Disable("db"), &llmErr))
+	assert.Equal(t, "tool_not_found", llmErr.Code)
+}
+
+func TestToolRegistry_Executor(t *testing.T) {
+	registry := newTestRegistry(t)
+	require.NoError(t, registry.Disable("fs.write"))
+
+	executor, err := registry.Executor(nil)
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range executor.Tools() {
+		names = append(names, tool.Function.Name)
+	}
+	assert.Equal(t, []string{"fs__read", "now", "web__search"}, names, "disabled tools and tools without handlers are skipped")
+
+	execution := executor.Execute(context.Background(), toolCall("1", "fs__read", "{}"))
+	assert.False(t, execution.Failed())
+	assert.Equal(t, "ok", execution.Result)
+
+	executor, err = registry.Executor(nil, "web")
+	require.NoError(t, err)
+	assert.Len(t, executor.Tools(), 1)
+}
+
+func TestSplitToolName(t *testing.T) {
+	namespace, name := SplitToolName("cloud.s3.get")
+	assert.Equal(t, "cloud.s3", namespace)
+	assert.Equal(t, "get", name)
+
+	namespace, name = SplitToolName("now")
+	assert.Empty(t, namespace)
+	assert.Equal(t, "now", name)
+	assert.Equal(t, "fs.read", QualifiedToolName("fs", "read"))
+}
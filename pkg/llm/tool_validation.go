@@ -0,0 +1,158 @@
+// Tool call argument validation
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidateArguments validates the JSON arguments of a tool call against the tool's
+// parameter schema, returning an "invalid_tool_arguments" *Error on failure
+func (t Tool) ValidateArguments(arguments string) error {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(arguments), &parsed); err != nil {
+		return newInvalidToolArgumentsError(t.Function.Name, fmt.Sprintf("arguments are not valid JSON: %v", err))
+	}
+
+	if err := ValidateValueAgainstSchema(parsed, t.Function.Parameters); err != nil {
+		return newInvalidToolArgumentsError(t.Function.Name, err.Error())
+	}
+	return nil
+}
+
+// ValidateToolCalls validates the arguments of every tool call against the matching tool
+// definition. Calls to tools that are not defined fail with an "unknown_tool" *Error.
+func ValidateToolCalls(calls []ToolCall, tools []Tool) error {
+	var errs []error
+	for _, call := range calls {
+		if err := validateToolCall(call, tools); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return &MultiError{Errors: errs}
+	}
+}
+
+func validateToolCall(call ToolCall, tools []Tool) error {
+	for _, tool := range tools {
+		if tool.Function.Name == call.Function.Name {
+			return tool.ValidateArguments(call.Function.Arguments)
+		}
+	}
+	return &Error{
+		Code:    "unknown_tool",
+		Message: fmt.Sprintf("tool %q is not defined in the request", call.Function.Name),
+		Type:    "validation_error",
+	}
+}
+
+func newInvalidToolArgumentsError(name, details string) *Error {
+	return &Error{
+		Code:    "invalid_tool_arguments",
+		Message: fmt.Sprintf("invalid arguments for tool %q: %s", name, details),
+		Type:    "validation_error",
+	}
+}
+
+// ToolValidationConfig configures tool call argument validation
+type ToolValidationConfig struct {
+	// RepairAttempts is the number of extra turns in which the model is told what was wrong
+	// and asked to repeat its tool calls with corrected arguments (0 disables repair)
+	RepairAttempts int
+}
+
+// ToolValidatingChatCompleter wraps a ChatCompleter and validates the tool calls in its responses
+type ToolValidatingChatCompleter struct {
+	client ChatCompleter
+	config ToolValidationConfig
+}
+
+// ValidateToolCallsChatCompletion creates a wrapper around any ChatCompleter that validates
+// the arguments of returned tool calls against the request's tool schemas, so handlers only
+// ever receive well-formed arguments.
+//
+// Example:
+//
+//	client := llm.ValidateToolCallsChatCompletion(baseClient, llm.ToolValidationConfig{RepairAttempts: 1})
+//	resp, err := client.ChatCompletion(ctx, req)
+//	if llm.IsInvalidToolArgumentsError(err) {
+//	    // the model kept producing invalid arguments
+//	}
+func ValidateToolCallsChatCompletion(client ChatCompleter, config ...ToolValidationConfig) ChatCompleter {
+	cfg := ToolValidationConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return &ToolValidatingChatCompleter{client: client, config: cfg}
+}
+
+// ChatCompletion executes the chat completion and validates the tool calls of the first choice,
+// running repair turns when configured
+func (v *ToolValidatingChatCompleter) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := v.client.ChatCompletion(ctx, req)
+		if err != nil || resp == nil || len(req.Tools) == 0 || len(resp.Choices) == 0 {
+			return resp, err
+		}
+
+		message := resp.Choices[0].Message
+		validationErr := ValidateToolCalls(message.ToolCalls, req.Tools)
+		if validationErr == nil {
+			return resp, nil
+		}
+		if attempt >= v.config.RepairAttempts {
+			return nil, validationErr
+		}
+
+		req = repairRequest(req, message)
+	}
+}
+
+// repairRequest extends the conversation with the assistant's tool calls and one tool
+// result per call explaining what must be fixed
+func repairRequest(req ChatRequest, assistant Message) ChatRequest {
+	repaired := req
+	repaired.Messages = append(append([]Message{}, req.Messages...), assistant)
+
+	for _, call := range assistant.ToolCalls {
+		feedback := "Not executed: another tool call in this turn had invalid arguments. Repeat all tool calls with valid arguments."
+		if err := validateToolCall(call, req.Tools); err != nil {
+			feedback = fmt.Sprintf("Error: %s. Call the tool again with arguments that match its parameter schema.", err)
+		}
+
+		result := NewTextMessage(RoleTool, feedback)
+		result.ToolCallID = call.ID
+		repaired.Messages = append(repaired.Messages, result)
+	}
+
+	return repaired
+}
+
+// IsInvalidToolArgumentsError checks if an error (or any error it wraps) is an invalid_tool_arguments error
+func IsInvalidToolArgumentsError(err error) bool {
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi.Errors {
+			if IsInvalidToolArgumentsError(e) {
+				return true
+			}
+		}
+	}
+
+	var llmErr *Error
+	return errors.As(err, &llmErr) && llmErr.Code == "invalid_tool_arguments"
+}
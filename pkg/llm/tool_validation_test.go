@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWeatherTool() Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name: "get_weather",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"city":  map[string]interface{}{"type": "string", "minLength": 1},
+					"units": map[string]interface{}{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+					"days":  map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 7},
+				},
+				"required":             []string{"city"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+func newToolCallResponse(arguments string) ChatResponse {
+	return ChatResponse{
+		Choices: []Choice{{
+			Message: Message{
+				Role: RoleAssistant,
+				ToolCalls: []ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: ToolCallFunction{Name: "get_weather", Arguments: arguments},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+	}
+}
+
+func TestTool_ValidateArguments(t *testing.T) {
+	tool := newWeatherTool()
+
+	tests := []struct {
+		name      string
+		arguments string
+		wantErr   string
+	}{
+		{name: "valid", arguments: `{"city": "Paris", "units": "celsius", "days": 3}`},
+		{name: "malformed JSON", arguments: `{"city": "Paris"`, wantErr: "not valid JSON"},
+		{name: "missing required", arguments: `{"units": "celsius"}`, wantErr: `missing required property "city"`},
+		{name: "wrong type", arguments: `{"city": 42}`, wantErr: "$.city: expected string, got number"},
+		{name: "enum", arguments: `{"city": "Paris", "units": "kelvin"}`, wantErr: "$.units"},
+		{name: "not an integer", arguments: `{"city": "Paris", "days": 2.5}`, wantErr: "expected integer"},
+		{name: "out of range", arguments: `{"city": "Paris", "days": 10}`, wantErr: "greater than maximum"},
+		{name: "unexpected property", arguments: `{"city": "Paris", "country": "FR"}`, wantErr: "$.country: unexpected property"},
+		{name: "empty arguments", arguments: "", wantErr: `missing required property "city"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateArguments(tt.arguments)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var llmErr *Error
+			require.ErrorAs(t, err, &llmErr)
+			assert.Equal(t, "invalid_tool_arguments", llmErr.Code)
+			assert.Contains(t, llmErr.Message, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateToolCalls_UnknownTool(t *testing.T) {
+	err := ValidateToolCalls([]ToolCall{{Function: ToolCallFunction{Name: "delete_everything", Arguments: "{}"}}}, []Tool{newWeatherTool()})
+
+	var llmErr *Error
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "unknown_tool", llmErr.Code)
+	assert.False(t, IsInvalidToolArgumentsError(err))
+}
+
+func TestValidateToolCallsChatCompletion(t *testing.T) {
+	req := ChatRequest{
+		Messages: []Message{NewTextMessage(RoleUser, "Weather in Paris?")},
+		Tools:    []Tool{newWeatherTool()},
+	}
+
+	t.Run("invalid arguments fail without repair", func(t *testing.T) {
+		mockClient := NewMockClient("test-model", "test")
+		mockClient.AddResponse(newToolCallResponse(`{"city": 1}`))
+
+		_, err := ValidateToolCallsChatCompletion(mockClient).ChatCompletion(context.Background(), req)
+		assert.True(t, IsInvalidToolArgumentsError(err))
+	})
+
+	t.Run("repair turn fixes arguments", func(t *testing.T) {
+		mockClient := NewMockClient("test-model", "test")
+		mockClient.AddResponse(newToolCallResponse(`{"city": 1}`))
+		mockClient.AddResponse(newToolCallResponse(`{"city": "Paris"}`))
+
+		client := ValidateToolCallsChatCompletion(mockClient, ToolValidationConfig{RepairAttempts: 1})
+		resp, err := client.ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, `{"city": "Paris"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+
+		calls := mockClient.GetCallLog()
+		require.Len(t, calls, 2)
+		repairMessages := calls[1].Messages
+		require.Len(t, repairMessages, 3)
+		assert.Equal(t, RoleAssistant, repairMessages[1].Role)
+		assert.Equal(t, RoleTool, repairMessages[2].Role)
+		assert.Equal(t, "call_1", repairMessages[2].ToolCallID)
+		assert.Contains(t, repairMessages[2].GetText(), "expected string")
+		assert.Len(t, req.Messages, 1, "caller request should not be modified")
+	})
+
+	t.Run("responses without tools pass through", func(t *testing.T) {
+		mockClient := NewMockClient("test-model", "test").WithSimpleResponse("Sunny")
+
+		resp, err := ValidateToolCallsChatCompletion(mockClient).ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "Sunny", resp.Choices[0].Message.GetText())
+	})
+	t.Run("nil responses pass through", func(t *testing.T) {
+		mockClient := NewMockClient("test-model", "test")
+		mockClient.responses = []*ChatResponse{nil}
+
+		resp, err := ValidateToolCallsChatCompletion(mockClient).ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+		assert.Nil(t, resp)
+	})
+}
@@ -0,0 +1,150 @@
+// Speech-to-text interfaces
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AudioContent is a recording for the speech APIs, such as the input of a
+// TranscriptionClient. It is not a message content: chat models take audio through
+// realtime sessions (RealtimeClient).
+type AudioContent struct {
+	Data     []byte `json:"-"`                  // Encoded audio (omitted from JSON)
+	MimeType string `json:"mime_type"`          // Encoding of the audio, e.g. "audio/mpeg"
+	Filename string `json:"filename,omitempty"` // Original filename, which some APIs use to tell the encoding
+}
+
+// audioMimeTypes are the MIME types of the audio file extensions
+var audioMimeTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".mpga": "audio/mpeg",
+	".mpeg": "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".mp4":  "audio/mp4",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".flac": "audio/flac",
+	".webm": "audio/webm",
+	".aac":  "audio/aac",
+	".aiff": "audio/aiff",
+}
+
+// NewAudioContentFromBytes creates audio from encoded data
+func NewAudioContentFromBytes(data []byte, mimeType string) *AudioContent {
+	return &AudioContent{Data: data, MimeType: mimeType}
+}
+
+// NewAudioContentFromFile reads an audio file, telling its MIME type from the extension
+func NewAudioContentFromFile(path string) (*AudioContent, error) {
+	mimeType, ok := audioMimeTypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, audioError(fmt.Sprintf("unsupported audio file extension %q", filepath.Ext(path)))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	return &AudioContent{Data: data, MimeType: mimeType, Filename: filepath.Base(path)}, nil
+}
+
+// Validate checks the audio has data and an audio MIME type
+func (a *AudioContent) Validate() error {
+	if a == nil || len(a.Data) == 0 {
+		return audioError("audio content has no data")
+	}
+	if !strings.HasPrefix(a.MimeType, "audio/") {
+		return audioError(fmt.Sprintf("invalid audio MIME type %q", a.MimeType))
+	}
+	return nil
+}
+
+// Size returns the size of the encoded audio in bytes
+func (a *AudioContent) Size() int64 {
+	if a == nil {
+		return 0
+	}
+	return int64(len(a.Data))
+}
+
+// FilenameOrDefault returns the filename of the audio, or a name with the extension of
+// its MIME type for the APIs that require one
+func (a *AudioContent) FilenameOrDefault() string {
+	if a.Filename != "" {
+		return a.Filename
+	}
+	mimeType, _, _ := strings.Cut(a.MimeType, ";")
+	if ext, ok := audioExtensions[strings.TrimSpace(mimeType)]; ok {
+		return "audio" + ext
+	}
+	return "audio"
+}
+
+// audioExtensions are the usual file extensions of the audio MIME types
+var audioExtensions = map[string]string{
+	"audio/mpeg":  ".mp3",
+	"audio/mp3":   ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/m4a":   ".m4a",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/ogg":   ".ogg",
+	"audio/flac":  ".flac",
+	"audio/webm":  ".webm",
+	"audio/aac":   ".aac",
+	"audio/aiff":  ".aiff",
+}
+
+// TranscriptionClient converts speech to text (OpenAI, Gemini)
+type TranscriptionClient interface {
+	// Transcribe returns the transcript of a recording
+	Transcribe(ctx context.Context, audio *AudioContent, opts TranscriptionOptions) (*Transcription, error)
+}
+
+// TranscriptionOptions configures a transcription. Zero values let the provider choose.
+type TranscriptionOptions struct {
+	// Model is the transcription model (provider default when empty)
+	Model string `json:"model,omitempty"`
+
+	// Language is the ISO-639-1 code of the spoken language, improving accuracy and
+	// latency when known
+	Language string `json:"language,omitempty"`
+
+	// Prompt guides the style of the transcript or gives the spelling of names and terms
+	Prompt string `json:"prompt,omitempty"`
+
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+// Transcription is the transcript of a recording
+type Transcription struct {
+	Text string `json:"text"`
+
+	// Language is the detected or given language, when reported by the provider
+	Language string `json:"language,omitempty"`
+
+	// Duration is the length of the recording, when reported by the provider
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Segments are the timed parts of the transcript, when reported by the model
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+
+	Model string `json:"model,omitempty"`
+}
+
+// TranscriptionSegment is a timed part of a transcript
+type TranscriptionSegment struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+}
+
+// audioError creates an invalid audio error
+func audioError(message string) *Error {
+	return &Error{Code: "invalid_audio", Message: message, Type: "validation_error"}
+}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Meeting.M4A")
+	require.NoError(t, os.WriteFile(path, []byte("audio"), 0o600))
+
+	audio, err := NewAudioContentFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "audio/mp4", audio.MimeType)
+	assert.Equal(t, "Meeting.M4A", audio.FilenameOrDefault())
+	assert.Equal(t, int64(5), audio.Size())
+	assert.NoError(t, audio.Validate())
+
+	var llmErr *Error
+	_, err = NewAudioContentFromFile(filepath.Join(t.TempDir(), "notes.txt"))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, "invalid_audio", llmErr.Code)
+
+	assert.Equal(t, "audio.webm", NewAudioContentFromBytes([]byte{1}, "audio/webm;codecs=opus").FilenameOrDefault())
+	assert.Equal(t, "audio", NewAudioContentFromBytes([]byte{1}, "audio/x-unknown").FilenameOrDefault())
+	assert.Error(t, NewAudioContentFromBytes(nil, "audio/wav").Validate())
+	assert.Error(t, NewAudioContentFromBytes([]byte{1}, "image/png").Validate())
+	assert.Error(t, (*AudioContent)(nil).Validate())
+}
@@ -0,0 +1,147 @@
+// Content transformation hooks for content a model cannot accept natively
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// AnyProvider registers a content transformer for every provider without a more
+// specific registration
+const AnyProvider = "*"
+
+// ContentTransformer converts content a model cannot accept natively into content it
+// can, e.g. replacing an image with a caption generated by a vision model or a file
+// with its extracted text. Returning no content drops the item.
+type ContentTransformer interface {
+	TransformContent(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error)
+}
+
+// ContentTransformerFunc adapts a function to the ContentTransformer interface
+type ContentTransformerFunc func(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error)
+
+// TransformContent calls f(ctx, content, model)
+func (f ContentTransformerFunc) TransformContent(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+	return f(ctx, content, model)
+}
+
+// transformerKey identifies a registration by provider and content type
+type transformerKey struct {
+	provider    string
+	contentType MessageType
+}
+
+// transformerRegistry holds the registered content transformers
+type transformerRegistry struct {
+	mu           sync.RWMutex
+	transformers map[transformerKey]ContentTransformer
+}
+
+var globalTransformers = &transformerRegistry{
+	transformers: make(map[transformerKey]ContentTransformer),
+}
+
+// RegisterContentTransformer registers the transformer used by a provider for content
+// of the given type that its model does not support. Use AnyProvider to register a
+// default for all providers; provider-specific registrations take precedence.
+func RegisterContentTransformer(provider string, contentType MessageType, transformer ContentTransformer) {
+	globalTransformers.mu.Lock()
+	defer globalTransformers.mu.Unlock()
+	globalTransformers.transformers[transformerKey{strings.ToLower(provider), contentType}] = transformer
+}
+
+// UnregisterContentTransformer removes a transformer registration
+func UnregisterContentTransformer(provider string, contentType MessageType) {
+	globalTransformers.mu.Lock()
+	defer globalTransformers.mu.Unlock()
+	delete(globalTransformers.transformers, transformerKey{strings.ToLower(provider), contentType})
+}
+
+// GetContentTransformer returns the transformer a provider uses for the given content
+// type, falling back to the AnyProvider registration
+func GetContentTransformer(provider string, contentType MessageType) (ContentTransformer, bool) {
+	globalTransformers.mu.RLock()
+	defer globalTransformers.mu.RUnlock()
+	if t, ok := globalTransformers.transformers[transformerKey{strings.ToLower(provider), contentType}]; ok {
+		return t, true
+	}
+	t, ok := globalTransformers.transformers[transformerKey{AnyProvider, contentType}]
+	return t, ok
+}
+
+// IsContentSupported reports whether the model accepts the content natively
+func IsContentSupported(model ModelInfo, content MessageContent) bool {
+	switch content.Type() {
+	case MessageTypeImage:
+		return model.SupportsVision
+	case MessageTypeFile:
+		return model.SupportsFiles
+	default:
+		return true
+	}
+}
+
+// TransformContent applies the registered content transformers of the model provider
+// to the content the model does not support. The request is returned unchanged when
+// nothing is transformed; otherwise a copy is returned and the original messages are
+// left untouched. Content without a registered transformer is left to the provider's
+// built-in handling.
+func (r ChatRequest) TransformContent(ctx context.Context, model ModelInfo) (ChatRequest, error) {
+	return r.transformContent(ctx, model, func(contentType MessageType) (ContentTransformer, bool) {
+		return GetContentTransformer(model.Provider, contentType)
+	})
+}
+
+// transformContent applies the transformers returned by lookup to the content the model
+// does not support
+func (r ChatRequest) transformContent(ctx context.Context, model ModelInfo,
+	lookup func(MessageType) (ContentTransformer, bool)) (ChatRequest, error) {
+	var messages []Message
+	for i, msg := range r.Messages {
+		var content []MessageContent
+		for j, item := range msg.Content {
+			if item == nil || IsContentSupported(model, item) {
+				if content != nil {
+					content = append(content, item)
+				}
+				continue
+			}
+			transformer, ok := lookup(item.Type())
+			if !ok {
+				if content != nil {
+					content = append(content, item)
+				}
+				continue
+			}
+
+			replacement, err := transformer.TransformContent(ctx, item, model)
+			if err != nil {
+				return r, err
+			}
+			if content == nil {
+				content = append(make([]MessageContent, 0, len(msg.Content)), msg.Content[:j]...)
+			}
+			content = append(content, replacement...)
+		}
+
+		if content == nil {
+			if messages != nil {
+				messages = append(messages, msg)
+			}
+			continue
+		}
+		if messages == nil {
+			messages = append(make([]Message, 0, len(r.Messages)), r.Messages[:i]...)
+		}
+		msg.Content = content
+		messages = append(messages, msg)
+	}
+
+	if messages == nil {
+		return r, nil
+	}
+	transformed := r
+	transformed.Messages = messages
+	return transformed, nil
+}
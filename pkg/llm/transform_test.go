@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContentTransformerRegistry(t *testing.T) {
+	caption := ContentTransformerFunc(func(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+		return []MessageContent{NewTextContent("caption")}, nil
+	})
+	drop := ContentTransformerFunc(func(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+		return nil, nil
+	})
+
+	RegisterContentTransformer(AnyProvider, MessageTypeImage, caption)
+	RegisterContentTransformer("OpenAI", MessageTypeImage, drop)
+	defer UnregisterContentTransformer(AnyProvider, MessageTypeImage)
+	defer UnregisterContentTransformer("openai", MessageTypeImage)
+
+	img := NewImageContentFromBytes([]byte{1}, "image/png")
+
+	transformer, ok := GetContentTransformer("openai", MessageTypeImage)
+	if !ok {
+		t.Fatal("Expected provider-specific transformer")
+	}
+	if out, _ := transformer.TransformContent(context.Background(), img, ModelInfo{}); len(out) != 0 {
+		t.Errorf("Expected provider-specific transformer to take precedence, got %v", out)
+	}
+
+	transformer, ok = GetContentTransformer("gemini", MessageTypeImage)
+	if !ok {
+		t.Fatal("Expected fallback transformer")
+	}
+	if out, _ := transformer.TransformContent(context.Background(), img, ModelInfo{}); len(out) != 1 {
+		t.Errorf("Expected fallback transformer, got %v", out)
+	}
+
+	if _, ok := GetContentTransformer("gemini", MessageTypeFile); ok {
+		t.Error("Expected no transformer for files")
+	}
+}
+
+func TestChatRequest_TransformContent(t *testing.T) {
+	RegisterContentTransformer("test", MessageTypeImage, ContentTransformerFunc(
+		func(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+			return []MessageContent{NewTextContent("[Image: a chart]")}, nil
+		}))
+	defer UnregisterContentTransformer("test", MessageTypeImage)
+
+	req := ChatRequest{Messages: []Message{
+		NewTextMessage(RoleSystem, "Be brief"),
+		{Role: RoleUser, Content: []MessageContent{
+			NewTextContent("Explain"),
+			NewImageContentFromBytes([]byte{1}, "image/png"),
+			NewFileContentFromBytes([]byte("%PDF"), "doc.pdf", "application/pdf"),
+		}},
+	}}
+
+	// Supported content is never transformed
+	out, err := req.TransformContent(context.Background(), ModelInfo{Provider: "test", SupportsVision: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if &out.Messages[0] != &req.Messages[0] {
+		t.Error("Expected request to be returned unchanged")
+	}
+
+	out, err = req.TransformContent(context.Background(), ModelInfo{Provider: "test"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content := out.Messages[1].Content
+	if len(content) != 3 || content[1].(*TextContent).GetText() != "[Image: a chart]" {
+		t.Errorf("Expected image to be replaced, got %v", content)
+	}
+	if _, ok := content[2].(*FileContent); !ok {
+		t.Error("Expected content without a transformer to be kept for the provider")
+	}
+	if _, ok := req.Messages[1].Content[1].(*ImageContent); !ok {
+		t.Error("Expected original request to be left untouched")
+	}
+	if out.Messages[0].GetText() != "Be brief" {
+		t.Errorf("Expected other messages to be kept, got %v", out.Messages)
+	}
+}
+
+func TestChatRequest_TransformContentError(t *testing.T) {
+	RegisterContentTransformer("test", MessageTypeFile, ContentTransformerFunc(
+		func(ctx context.Context, content MessageContent, model ModelInfo) ([]MessageContent, error) {
+			return nil, &Error{Code: "extraction_failed", Message: "cannot read file", Type: "validation_error"}
+		}))
+	defer UnregisterContentTransformer("test", MessageTypeFile)
+
+	req := ChatRequest{Messages: []Message{{Role: RoleUser, Content: []MessageContent{
+		NewFileContentFromBytes([]byte("%PDF"), "doc.pdf", "application/pdf"),
+	}}}}
+	_, err := req.TransformContent(context.Background(), ModelInfo{Provider: "test"})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "extraction_failed" {
+		t.Errorf("Expected transformer error, got %v", err)
+	}
+}
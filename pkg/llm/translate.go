@@ -0,0 +1,357 @@
+// Translation of requests between providers with different capabilities
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxToolNameLength is the longest tool name accepted by most providers
+const DefaultMaxToolNameLength = 64
+
+// ProviderCapabilities describes what a model accepts, to translate requests built for
+// one model into requests valid for another (see TranslateRequest)
+type ProviderCapabilities struct {
+	ModelInfo
+
+	// Rules are the message ordering rules of the provider
+	Rules NormalizationRules `json:"rules"`
+
+	// MaxSystemPromptLength is the longest system message, in characters, the model
+	// follows reliably. Longer ones are split. Zero means no limit.
+	MaxSystemPromptLength int `json:"max_system_prompt_length,omitempty"`
+
+	// MaxToolNameLength is the longest tool name accepted. Zero means no limit.
+	MaxToolNameLength int `json:"max_tool_name_length,omitempty"`
+}
+
+// CapabilitiesFor returns the capabilities of a model, with the ordering rules of its
+// provider (see NormalizationRulesFor)
+func CapabilitiesFor(model ModelInfo) ProviderCapabilities {
+	return ProviderCapabilities{
+		ModelInfo:         model,
+		Rules:             NormalizationRulesFor(model.Provider),
+		MaxToolNameLength: DefaultMaxToolNameLength,
+	}
+}
+
+// TranslationReport describes the changes made by TranslateRequest
+type TranslationReport struct {
+	// Warnings describe the content degraded and the settings dropped
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	// ToolNames maps the tool names changed for the target to the original ones
+	ToolNames map[string]string `json:"tool_names,omitempty"`
+
+	// Normalization reports the message ordering fixes for the rules of the target
+	Normalization *NormalizationReport `json:"normalization,omitempty"`
+}
+
+// RestoreToolNames renames the tool calls of a response to the translated request back
+// to their original names, so tool handlers registered with them keep working
+func (r *TranslationReport) RestoreToolNames(resp *ChatResponse) {
+	if r == nil || resp == nil || len(r.ToolNames) == 0 {
+		return
+	}
+	for i := range resp.Choices {
+		calls := resp.Choices[i].Message.ToolCalls
+		for j := range calls {
+			if original, ok := r.ToolNames[calls[j].Function.Name]; ok {
+				calls[j].Function.Name = original
+			}
+		}
+	}
+}
+
+// Codes of the warnings of TranslateRequest
+const (
+	// WarningToolCallsFlattened is a tool call or result turned into text for a model
+	// without tool support
+	WarningToolCallsFlattened = "tool_calls_flattened"
+
+	// WarningSystemPromptSplit is a system message longer than the model follows,
+	// whose end was moved to a user message
+	WarningSystemPromptSplit = "system_prompt_split"
+)
+
+// validToolName matches the tool names accepted by all providers
+var validToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// invalidToolNameChars matches the characters replaced in tool names
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// TranslateRequest rewrites a request built for a model into one valid for another, to
+// switch providers in the middle of a conversation. The model of the request is
+// replaced when it is the source model (or unset), and then:
+//   - content the target does not accept is degraded: text files are inlined and other
+//     images and files replaced by a placeholder
+//   - without tool support, tools are dropped and past tool calls and results turned
+//     into text; otherwise tool names the target rejects are renamed (see ToolNames)
+//   - system messages longer than MaxSystemPromptLength are split, their end moved to a
+//     user message that follows them
+//   - messages are reordered for the rules of the target (see MessageNormalizer)
+//
+// The given request is not modified. Content degradation does not call the registered
+// content transformers: call TransformContent before translating to caption images or
+// extract the text of files instead.
+//
+// Example:
+//
+//	translated, report := llm.TranslateRequest(req,
+//	    llm.CapabilitiesFor(openaiClient.GetModelInfo()),
+//	    llm.CapabilitiesFor(ollamaClient.GetModelInfo()))
+//	resp, err := ollamaClient.ChatCompletion(ctx, translated)
+//	report.RestoreToolNames(resp)
+func TranslateRequest(req ChatRequest, from, to ProviderCapabilities) (ChatRequest, *TranslationReport) {
+	report := &TranslationReport{}
+	out := req
+	if to.Name != "" && (req.Model == "" || req.Model == from.Name) {
+		out.Model = to.Name
+	}
+
+	messages := make([]Message, len(req.Messages))
+	copy(messages, req.Messages)
+
+	if to.SupportsTools {
+		out.Tools = translateTools(req.Tools, messages, to, report)
+	} else {
+		if len(req.Tools) > 0 {
+			report.Warnings = append(report.Warnings, Warning{
+				Code:    WarningParameterIgnored,
+				Message: fmt.Sprintf("%s does not support tools, %d tools were not sent", describeModel(to), len(req.Tools)),
+				Param:   "tools",
+			})
+			out.Tools = nil
+		}
+		flattenToolHistory(messages, report)
+	}
+
+	for i := range messages {
+		messages[i].Content = degradeContent(messages[i].Content, i, to.ModelInfo, report)
+	}
+	if to.MaxSystemPromptLength > 0 {
+		messages = splitSystemPrompts(messages, to.MaxSystemPromptLength, report)
+	}
+
+	normalizer := NewMessageNormalizer(&MessageNormalizerConfig{Rules: &to.Rules})
+	out.Messages, report.Normalization = normalizer.Normalize(messages)
+	return out, report
+}
+
+// translateTools returns the tool definitions for the target, renaming the tools it
+// rejects in the definitions and in the tool calls of the messages
+func translateTools(tools []Tool, messages []Message, to ProviderCapabilities, report *TranslationReport) []Tool {
+	// Valid names are kept, so renamed ones must not take them
+	valid := func(name string) bool {
+		return validToolName.MatchString(name) && (to.MaxToolNameLength <= 0 || len(name) <= to.MaxToolNameLength)
+	}
+	used := make(map[string]bool)
+	for _, tool := range tools {
+		if valid(tool.Function.Name) {
+			used[tool.Function.Name] = true
+		}
+	}
+
+	renamed := make(map[string]string)
+	rename := func(name string) string {
+		if valid(name) {
+			return name
+		}
+		if translated, ok := renamed[name]; ok {
+			return translated
+		}
+
+		base := invalidToolNameChars.ReplaceAllString(name, "_")
+		if base == "" {
+			base = "tool"
+		}
+		truncate := func(name string, room int) string {
+			if to.MaxToolNameLength > 0 && len(name) > to.MaxToolNameLength-room {
+				return name[:max(0, to.MaxToolNameLength-room)]
+			}
+			return name
+		}
+		translated := truncate(base, 0)
+		for n := 2; used[translated]; n++ {
+			suffix := fmt.Sprintf("_%d", n)
+			translated = truncate(base, len(suffix)) + suffix
+		}
+
+		used[translated] = true
+		renamed[name] = translated
+		if report.ToolNames == nil {
+			report.ToolNames = make(map[string]string)
+		}
+		report.ToolNames[translated] = name
+		return translated
+	}
+
+	var out []Tool
+	if tools != nil {
+		out = make([]Tool, len(tools))
+	}
+	for i, tool := range tools {
+		if tool.Type == "" {
+			tool.Type = "function"
+		}
+		if tool.Function.Parameters == nil {
+			tool.Function.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		if name := rename(tool.Function.Name); name != tool.Function.Name {
+			report.Warnings = append(report.Warnings, Warning{
+				Code:    WarningParameterIgnored,
+				Message: fmt.Sprintf("tool %q is not a valid name for %s and was renamed %q", tool.Function.Name, describeModel(to), name),
+				Param:   fmt.Sprintf("tools[%d].function.name", i),
+			})
+			tool.Function.Name = name
+		}
+		out[i] = tool
+	}
+
+	for i, msg := range messages {
+		if len(msg.ToolCalls) == 0 {
+			continue
+		}
+		calls := make([]ToolCall, len(msg.ToolCalls))
+		for j, call := range msg.ToolCalls {
+			call.Function.Name = rename(call.Function.Name)
+			calls[j] = call
+		}
+		messages[i].ToolCalls = calls
+	}
+	return out
+}
+
+// flattenToolHistory turns the tool calls and results of the messages into text, for
+// models without tool support
+func flattenToolHistory(messages []Message, report *TranslationReport) {
+	names := make(map[string]string)
+	for i, msg := range messages {
+		switch {
+		case len(msg.ToolCalls) > 0:
+			content := append([]MessageContent(nil), msg.Content...)
+			for _, call := range msg.ToolCalls {
+				names[call.ID] = call.Function.Name
+				content = append(content, NewTextContent(fmt.Sprintf("[Called tool %s with %s]", call.Function.Name, call.Function.Arguments)))
+			}
+			messages[i].Content, messages[i].ToolCalls = content, nil
+		case msg.Role == RoleTool:
+			name := names[msg.ToolCallID]
+			if name == "" {
+				name = msg.ToolCallID
+			}
+			messages[i] = Message{
+				Role:    RoleUser,
+				Content: append([]MessageContent{NewTextContent(fmt.Sprintf("[Result of tool %s]", name))}, msg.Content...),
+			}
+		default:
+			continue
+		}
+		report.Warnings = append(report.Warnings, Warning{
+			Code:    WarningToolCallsFlattened,
+			Message: fmt.Sprintf("the tool calls of message %d were sent as text", i),
+			Param:   fmt.Sprintf("messages[%d]", i),
+		})
+	}
+}
+
+// degradeContent replaces the content the model does not accept, returning the content
+// unchanged when it accepts it all
+func degradeContent(content []MessageContent, index int, model ModelInfo, report *TranslationReport) []MessageContent {
+	var out []MessageContent
+	for j, item := range content {
+		if item == nil || IsContentSupported(model, item) {
+			if out != nil {
+				out = append(out, item)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]MessageContent, 0, len(content)), content[:j]...)
+		}
+
+		warning := Warning{Param: fmt.Sprintf("messages[%d].content[%d]", index, j)}
+		if file, ok := item.(*FileContent); ok && file.HasData() && isTextFile(file.MimeType) && utf8.Valid(file.Data) {
+			out = append(out, NewTextContent(fmt.Sprintf("[File: %s]\n%s", file.Filename, file.Data)))
+			warning.Code, warning.Message = WarningFileSentAsText, describeContent(item)+" was sent as text"
+		} else {
+			out = append(out, NewTextContent(fmt.Sprintf("[%s omitted]", describeContent(item))))
+			warning.Code, warning.Message = WarningContentDropped, describeContent(item)+" is not supported and was replaced by a placeholder"
+		}
+		report.Warnings = append(report.Warnings, warning)
+	}
+	if out == nil {
+		return content
+	}
+	return out
+}
+
+// isTextFile reports whether files of the MIME type can be inlined as text
+func isTextFile(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	switch strings.TrimSpace(mimeType) {
+	case "application/json", "application/xml", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return strings.HasPrefix(mimeType, "text/")
+}
+
+// splitSystemPrompts moves the end of the system messages longer than limit characters
+// to a user message following them
+func splitSystemPrompts(messages []Message, limit int, report *TranslationReport) []Message {
+	var out []Message
+	for i, msg := range messages {
+		text := messageText(msg)
+		if msg.Role != RoleSystem || utf8.RuneCountInString(text) <= limit {
+			if out != nil {
+				out = append(out, msg)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]Message, 0, len(messages)+1), messages[:i]...)
+		}
+
+		head, rest := splitText(text, limit)
+		out = append(out, NewTextMessage(RoleSystem, head), NewTextMessage(RoleUser, "Additional instructions:\n"+rest))
+		report.Warnings = append(report.Warnings, Warning{
+			Code:    WarningSystemPromptSplit,
+			Message: fmt.Sprintf("system message %d is longer than %d characters, its end was sent as a user message", i, limit),
+			Param:   fmt.Sprintf("messages[%d]", i),
+		})
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
+
+// splitText splits a text in a head of at most limit characters and the rest, at the
+// last paragraph, line or word break of the head when there is one
+func splitText(text string, limit int) (string, string) {
+	cut := len(text)
+	for i := range text {
+		if limit == 0 {
+			cut = i
+			break
+		}
+		limit--
+	}
+	head := text[:cut]
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(head, sep); i > 0 {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i:])
+		}
+	}
+	return head, text[cut:]
+}
+
+// describeModel names a model in warnings
+func describeModel(caps ProviderCapabilities) string {
+	if caps.Name == "" {
+		return "the model"
+	}
+	return "model " + caps.Name
+}
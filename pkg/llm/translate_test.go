@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func warningCodes(warnings []Warning) []string {
+	codes := make([]string, len(warnings))
+	for i, warning := range warnings {
+		codes[i] = warning.Code
+	}
+	return codes
+}
+
+func TestTranslateRequest_Tools(t *testing.T) {
+	from := CapabilitiesFor(ModelInfo{Name: "gpt-4o", Provider: "openai", SupportsTools: true, SupportsVision: true})
+	to := CapabilitiesFor(ModelInfo{Name: "gemini-2.5-flash", Provider: "gemini", SupportsTools: true})
+
+	req := ChatRequest{
+		Model: "gpt-4o",
+		Tools: []Tool{
+			{Function: ToolFunction{Name: "web.search"}},
+			{Type: "function", Function: ToolFunction{Name: "web_search", Parameters: map[string]any{"type": "object"}}},
+		},
+		Messages: []Message{
+			NewTextMessage(RoleUser, "news?"),
+			{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall("call_1", "web.search", `{"q":"news"}`)}},
+			{Role: RoleTool, ToolCallID: "call_1", Content: []MessageContent{NewTextContent("none")}},
+		},
+	}
+
+	translated, report := TranslateRequest(req, from, to)
+	assert.Equal(t, "gemini-2.5-flash", translated.Model)
+	assert.Equal(t, "web_search_2", translated.Tools[0].Function.Name, "renamed tools do not take valid names")
+	assert.Equal(t, "function", translated.Tools[0].Type)
+	assert.NotNil(t, translated.Tools[0].Function.Parameters)
+	assert.Equal(t, "web_search", translated.Tools[1].Function.Name)
+	assert.Equal(t, "web_search_2", translated.Messages[1].ToolCalls[0].Function.Name)
+	assert.Equal(t, map[string]string{"web_search_2": "web.search"}, report.ToolNames)
+
+	// The original request is untouched
+	assert.Equal(t, "web.search", req.Tools[0].Function.Name)
+	assert.Equal(t, "web.search", req.Messages[1].ToolCalls[0].Function.Name)
+
+	resp := &ChatResponse{Choices: []Choice{{Message: Message{ToolCalls: []ToolCall{toolCall("call_2", "web_search_2", `{}`)}}}}}
+	report.RestoreToolNames(resp)
+	assert.Equal(t, "web.search", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+}
+
+func TestTranslateRequest_NoTools(t *testing.T) {
+	from := CapabilitiesFor(ModelInfo{Name: "gpt-4o", Provider: "openai", SupportsTools: true})
+	to := CapabilitiesFor(ModelInfo{Name: "llama3.1", Provider: "ollama"})
+
+	req := ChatRequest{
+		Model: "custom-deployment",
+		Tools: []Tool{{Type: "function", Function: ToolFunction{Name: "weather"}}},
+		Messages: []Message{
+			NewTextMessage(RoleUser, "weather in Paris?"),
+			{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall("call_1", "weather", `{"city":"Paris"}`)}},
+			{Role: RoleTool, ToolCallID: "call_1", Content: []MessageContent{NewTextContent("sunny")}},
+		},
+	}
+
+	translated, report := TranslateRequest(req, from, to)
+	assert.Equal(t, "custom-deployment", translated.Model, "only the source model is replaced")
+	assert.Nil(t, translated.Tools)
+	assert.Equal(t, `[Called tool weather with {"city":"Paris"}]`, translated.Messages[1].GetText())
+	assert.Empty(t, translated.Messages[1].ToolCalls)
+	assert.Equal(t, RoleUser, translated.Messages[2].Role)
+	assert.Contains(t, translated.Messages[2].GetText(), "[Result of tool weather]")
+	assert.Equal(t, []string{WarningParameterIgnored, WarningToolCallsFlattened, WarningToolCallsFlattened}, warningCodes(report.Warnings))
+}
+
+func TestTranslateRequest_Content(t *testing.T) {
+	from := CapabilitiesFor(ModelInfo{Name: "gpt-4o", Provider: "openai", SupportsVision: true, SupportsFiles: true})
+	to := CapabilitiesFor(ModelInfo{Name: "mistral", Provider: "ollama"})
+
+	req := ChatRequest{Messages: []Message{{
+		Role: RoleUser,
+		Content: []MessageContent{
+			NewTextContent("compare"),
+			NewImageContentFromBytes([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+			NewFileContentFromBytes([]byte("a,b\n1,2"), "data.csv", "text/csv"),
+		},
+	}}}
+
+	translated, report := TranslateRequest(req, from, to)
+	content := translated.Messages[0].Content
+	require.Len(t, content, 3)
+	assert.Equal(t, "[image (image/png) omitted]", content[1].(*TextContent).GetText())
+	assert.Equal(t, "[File: data.csv]\na,b\n1,2", content[2].(*TextContent).GetText())
+	assert.Equal(t, []string{WarningContentDropped, WarningFileSentAsText}, warningCodes(report.Warnings))
+	assert.IsType(t, &ImageContent{}, req.Messages[0].Content[1])
+}
+
+func TestTranslateRequest_SystemPrompt(t *testing.T) {
+	from := CapabilitiesFor(ModelInfo{Name: "claude", Provider: "anthropic"})
+	to := CapabilitiesFor(ModelInfo{Name: "phi3", Provider: "ollama"})
+	to.MaxSystemPromptLength = 30
+
+	system := "You are a helpful assistant.\n\nAlways answer in French, with short sentences."
+	req := ChatRequest{Messages: []Message{NewTextMessage(RoleSystem, system), NewTextMessage(RoleUser, "hi")}}
+
+	translated, report := TranslateRequest(req, from, to)
+	require.Len(t, translated.Messages, 3)
+	assert.Equal(t, "You are a helpful assistant.", translated.Messages[0].GetText())
+	assert.Equal(t, RoleUser, translated.Messages[1].Role)
+	assert.Equal(t, "Additional instructions:\nAlways answer in French, with short sentences.", translated.Messages[1].GetText())
+	assert.Equal(t, []string{WarningSystemPromptSplit}, warningCodes(report.Warnings))
+
+	// Targets requiring alternating turns get the user messages merged
+	to.Rules = NormalizationRulesFor("gemini")
+	translated, report = TranslateRequest(req, from, to)
+	require.Len(t, translated.Messages, 2)
+	assert.True(t, strings.HasSuffix(messageText(translated.Messages[1]), "hi"))
+	assert.Equal(t, 1, report.Normalization.Applied())
+}
+
+func TestSplitText(t *testing.T) {
+	head, rest := splitText("abcdefghij", 4)
+	assert.Equal(t, "abcd", head)
+	assert.Equal(t, "efghij", rest)
+
+	head, rest = splitText("héllo wörld again", 12)
+	assert.Equal(t, "héllo wörld", head)
+	assert.Equal(t, "again", rest)
+}
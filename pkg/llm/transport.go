@@ -0,0 +1,123 @@
+// Shared HTTP transport with connection pooling for the provider clients
+package llm
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportConfig tunes the connection pool of the HTTP transport used by the provider
+// clients
+type TransportConfig struct {
+	// MaxIdleConns limits the idle connections kept across all hosts
+	MaxIdleConns int `json:"max_idle_conns"`
+
+	// MaxIdleConnsPerHost limits the idle connections kept per host. Go defaults to 2,
+	// which makes concurrent completions to a provider open and close a connection each.
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host"`
+
+	// MaxConnsPerHost limits the connections per host, idle or not. Zero means no limit.
+	MaxConnsPerHost int `json:"max_conns_per_host"`
+
+	// IdleConnTimeout closes connections idle for longer
+	IdleConnTimeout time.Duration `json:"idle_conn_timeout"`
+
+	// DialTimeout limits the time to establish a TCP connection
+	DialTimeout time.Duration `json:"dial_timeout"`
+
+	// KeepAlive is the interval of TCP keep-alive probes. Negative disables them.
+	KeepAlive time.Duration `json:"keep_alive"`
+
+	// TLSHandshakeTimeout limits the TLS handshake
+	TLSHandshakeTimeout time.Duration `json:"tls_handshake_timeout"`
+
+	// ResponseHeaderTimeout limits the wait for the response headers once the request
+	// is sent. Zero means no limit (the client timeout still applies).
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout"`
+
+	// DisableHTTP2 forces HTTP/1.1, with a connection per in-flight request
+	DisableHTTP2 bool `json:"disable_http2"`
+
+	// HTTP2PingTimeout sends a health-check ping on HTTP/2 connections without frames for
+	// this long, and closes them when the ping is not answered in time, so a stuck
+	// connection does not hang every request multiplexed on it. Zero disables pings.
+	HTTP2PingTimeout time.Duration `json:"http2_ping_timeout"`
+}
+
+// DefaultTransportConfig returns a configuration sized for many concurrent completions
+func DefaultTransportConfig() *TransportConfig {
+	return &TransportConfig{
+		MaxIdleConns:        1000,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		HTTP2PingTimeout:    30 * time.Second,
+	}
+}
+
+// NewTransport creates a transport with its own connection pool, using the default
+// configuration when config is nil. Proxies are taken from the environment.
+func NewTransport(config *TransportConfig) *http.Transport {
+	if config == nil {
+		config = DefaultTransportConfig()
+	}
+	dialer := &net.Dialer{Timeout: config.DialTimeout, KeepAlive: config.KeepAlive}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     !config.DisableHTTP2,
+	}
+	if config.DisableHTTP2 {
+		// An empty map (not nil) prevents the upgrade to HTTP/2 during the TLS handshake
+		transport.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+	} else if config.HTTP2PingTimeout > 0 {
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout: config.HTTP2PingTimeout,
+			PingTimeout:     config.HTTP2PingTimeout / 2,
+		}
+	}
+	return transport
+}
+
+var (
+	sharedTransportMu sync.Mutex
+	sharedTransport   *http.Transport
+)
+
+// SharedTransport returns the transport shared by the provider clients, so all the
+// clients of a process reuse the same connection pool instead of opening sockets per
+// client. It is created with the default configuration on first use.
+func SharedTransport() *http.Transport {
+	sharedTransportMu.Lock()
+	defer sharedTransportMu.Unlock()
+	if sharedTransport == nil {
+		sharedTransport = NewTransport(nil)
+	}
+	return sharedTransport
+}
+
+// ConfigureSharedTransport replaces the shared transport with one using config. Clients
+// created before keep the previous transport, so it should be called at startup, before
+// creating clients. The idle connections of the previous transport are closed.
+func ConfigureSharedTransport(config *TransportConfig) {
+	transport := NewTransport(config)
+	sharedTransportMu.Lock()
+	previous := sharedTransport
+	sharedTransport = transport
+	sharedTransportMu.Unlock()
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+}
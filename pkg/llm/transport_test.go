@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer starts a server counting the connections opened to it
+func newCountingServer(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	var opened atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &opened
+}
+
+// get sends a request and drains the response so its connection can be reused
+func get(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func TestNewTransportReusesConnections(t *testing.T) {
+	server, opened := newCountingServer(t)
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	const concurrency = 50
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, get(client, server.URL))
+			}()
+		}
+		wg.Wait()
+	}
+
+	assert.LessOrEqual(t, opened.Load(), int64(concurrency), "idle connections should be reused across rounds")
+}
+
+func TestNewTransportConfig(t *testing.T) {
+	transport := NewTransport(&TransportConfig{MaxIdleConnsPerHost: 7, DisableHTTP2: true})
+	assert.Equal(t, 7, transport.MaxIdleConnsPerHost)
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto, "HTTP/2 upgrades should be disabled")
+	assert.Nil(t, transport.HTTP2)
+
+	transport = NewTransport(nil)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	require.NotNil(t, transport.HTTP2)
+	assert.Equal(t, DefaultTransportConfig().HTTP2PingTimeout, transport.HTTP2.SendPingTimeout)
+}
+
+func TestConfigureSharedTransport(t *testing.T) {
+	previous := SharedTransport()
+	assert.Same(t, previous, SharedTransport())
+
+	ConfigureSharedTransport(&TransportConfig{MaxIdleConnsPerHost: 3})
+	t.Cleanup(func() { ConfigureSharedTransport(nil) })
+
+	assert.NotSame(t, previous, SharedTransport())
+	assert.Equal(t, 3, SharedTransport().MaxIdleConnsPerHost)
+
+	// Clients without an explicit base use the shared transport
+	server, _ := newCountingServer(t)
+	client := &http.Client{Transport: &RateLimitTransport{}}
+	require.NoError(t, get(client, server.URL))
+}
+
+func BenchmarkConcurrentRequests(b *testing.B) {
+	transports := map[string]func() *http.Transport{
+		"shared": func() *http.Transport { return NewTransport(nil) },
+		"go-default": func() *http.Transport {
+			return http.DefaultTransport.(*http.Transport).Clone()
+		},
+	}
+	for name, newTransport := range transports {
+		b.Run(name, func(b *testing.B) {
+			server, opened := newCountingServer(b)
+			client := &http.Client{Transport: newTransport()}
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := get(client, server.URL); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(opened.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
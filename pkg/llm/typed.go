@@ -0,0 +1,164 @@
+// Typed client returning Go values decoded from structured outputs
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// TypedConfig configures a TypedClient
+type TypedConfig struct {
+	// Name is the schema name sent to the provider. It defaults to the name of the type.
+	Name string `json:"name,omitempty"`
+
+	// Description describes the expected output to the model
+	Description string `json:"description,omitempty"`
+
+	// SystemPrompt is sent before the prompt by Complete
+	SystemPrompt string `json:"system_prompt,omitempty"`
+
+	// MaxRetries is the number of times the model is asked again, with the error, when
+	// its output cannot be parsed or does not match the schema. Negative disables it.
+	MaxRetries int `json:"max_retries"`
+}
+
+// DefaultTypedConfig returns the default typed client configuration
+func DefaultTypedConfig() *TypedConfig {
+	return &TypedConfig{MaxRetries: 2}
+}
+
+// TypedClient asks a model for values of type T. The response format is generated from
+// T (strict when T can be expressed in strict mode), and every response is parsed and
+// validated against it, asking the model again with the error when it is invalid.
+type TypedClient[T any] struct {
+	client ChatCompleter
+	config *TypedConfig
+
+	formatOnce sync.Once
+	format     *ResponseFormat
+	formatErr  error
+}
+
+// Typed creates a typed client with the default configuration, for the common case of
+// getting a struct from a prompt:
+//
+//	analysis, err := llm.Typed[Analysis](client).Complete(ctx, "Analyze this review: ...")
+func Typed[T any](client ChatCompleter) *TypedClient[T] {
+	return NewTypedClient[T](client, nil)
+}
+
+// NewTypedClient creates a typed client, filling unset config fields with defaults
+func NewTypedClient[T any](client ChatCompleter, config *TypedConfig) *TypedClient[T] {
+	if config == nil {
+		config = DefaultTypedConfig()
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultTypedConfig().MaxRetries
+	}
+	return &TypedClient[T]{client: client, config: config}
+}
+
+// ResponseFormat returns the response format generated from T
+func (c *TypedClient[T]) ResponseFormat() (*ResponseFormat, error) {
+	c.formatOnce.Do(func() {
+		var zero T
+		name := c.config.Name
+		if name == "" {
+			name = typedSchemaName(reflect.TypeOf(zero))
+		}
+		c.format, c.formatErr = NewJSONSchemaResponseFormatStrictFromStruct(name, c.config.Description, zero)
+		if c.formatErr != nil {
+			// Types strict mode cannot express (e.g. maps) are requested without it
+			c.format, c.formatErr = NewJSONSchemaResponseFormatFromStruct(name, c.config.Description, zero)
+		}
+	})
+	return c.format, c.formatErr
+}
+
+// Complete sends the prompt, after the configured system prompt, and returns the value
+// the model answered with
+func (c *TypedClient[T]) Complete(ctx context.Context, prompt string) (T, error) {
+	var messages []Message
+	if c.config.SystemPrompt != "" {
+		messages = append(messages, NewTextMessage(RoleSystem, c.config.SystemPrompt))
+	}
+	messages = append(messages, NewTextMessage(RoleUser, prompt))
+	return c.CompleteRequest(ctx, ChatRequest{Messages: messages})
+}
+
+// CompleteRequest sends a request, with its response format replaced by the one of T,
+// and returns the value the model answered with. When the answer is still invalid after
+// MaxRetries, the error has the "invalid_structured_output" code.
+func (c *TypedClient[T]) CompleteRequest(ctx context.Context, req ChatRequest) (T, error) {
+	var value T
+	format, err := c.ResponseFormat()
+	if err != nil {
+		return value, err
+	}
+	req.ResponseFormat = format
+	req.Messages = append([]Message(nil), req.Messages...)
+
+	retries := max(c.config.MaxRetries, 0)
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.ChatCompletion(ctx, req)
+		if err != nil {
+			return value, err
+		}
+		if len(resp.Choices) == 0 {
+			return value, &Error{Code: "empty_response", Message: "the response has no choices", Type: "api_error"}
+		}
+
+		text := resp.Choices[0].Message.GetText()
+		decodeErr := decodeTyped(text, format, &value)
+		if decodeErr == nil {
+			return value, nil
+		}
+		if attempt == retries {
+			return value, &Error{
+				Code:    "invalid_structured_output",
+				Message: fmt.Sprintf("invalid output after %d attempts: %v", attempt+1, decodeErr),
+				Type:    "validation_error",
+			}
+		}
+
+		req.Messages = append(req.Messages,
+			NewTextMessage(RoleAssistant, text),
+			NewTextMessage(RoleUser, fmt.Sprintf(
+				"Your answer is not valid: %v. Answer again with only a JSON value matching the schema.", decodeErr)),
+		)
+	}
+}
+
+// decodeTyped extracts the JSON of a response, validates it against the format schema
+// and decodes it
+func decodeTyped[T any](text string, format *ResponseFormat, value *T) error {
+	jsonStr, err := ExtractAndValidateJSON(text, format.JSONSchema.Schema)
+	if err != nil {
+		return err
+	}
+	var decoded T
+	if err := json.Unmarshal([]byte(jsonStr), &decoded); err != nil {
+		return err
+	}
+	*value = decoded
+	return nil
+}
+
+// invalidSchemaNameChars matches the characters providers reject in schema names
+var invalidSchemaNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// typedSchemaName returns the schema name of a type: its name, with the characters of
+// generic type arguments replaced
+func typedSchemaName(t reflect.Type) string {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "response"
+	}
+	return invalidSchemaNameChars.ReplaceAllString(t.Name(), "_")
+}
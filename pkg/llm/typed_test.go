@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedAnswer struct {
+	City       string   `json:"city" description:"City name"`
+	Population int      `json:"population" minimum:"0"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+func textResponse(text string) *ChatResponse {
+	return &ChatResponse{Choices: []Choice{{Message: NewTextMessage(RoleAssistant, text)}}}
+}
+
+func TestTypedComplete(t *testing.T) {
+	client := NewMockClient("test-model", "test")
+	client.responses = []*ChatResponse{
+		textResponse("```json\n{\"city\": \"Paris\", \"population\": 2100000, \"tags\": null}\n```"),
+	}
+
+	answer, err := Typed[typedAnswer](client).Complete(context.Background(), "Largest city in France?")
+	require.NoError(t, err)
+	assert.Equal(t, typedAnswer{City: "Paris", Population: 2100000}, answer)
+
+	calls := client.GetCallLog()
+	require.Len(t, calls, 1)
+	format := calls[0].ResponseFormat
+	require.NotNil(t, format)
+	assert.Equal(t, ResponseFormatJSONSchema, format.Type)
+	assert.Equal(t, "typedAnswer", format.JSONSchema.Name)
+	require.NotNil(t, format.JSONSchema.Strict)
+	assert.True(t, *format.JSONSchema.Strict)
+}
+
+func TestTypedRetriesInvalidOutput(t *testing.T) {
+	client := NewMockClient("test-model", "test")
+	client.responses = []*ChatResponse{
+		textResponse("Paris, obviously"),
+		textResponse(`{"city": "Paris", "population": -1, "tags": null}`),
+		textResponse(`{"city": "Paris", "population": 2100000, "tags": ["capital"]}`),
+	}
+
+	typed := NewTypedClient[typedAnswer](client, &TypedConfig{SystemPrompt: "Answer in JSON"})
+	answer, err := typed.Complete(context.Background(), "Largest city in France?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"capital"}, answer.Tags)
+
+	calls := client.GetCallLog()
+	require.Len(t, calls, 3)
+	assert.Equal(t, RoleSystem, calls[0].Messages[0].Role)
+	// Every retry carries the invalid answer and the error
+	retry := calls[2].Messages
+	require.Len(t, retry, 6)
+	assert.Equal(t, RoleAssistant, retry[4].Role)
+	assert.Contains(t, retry[5].GetText(), "not valid")
+}
+
+func TestTypedGivesUp(t *testing.T) {
+	client := NewMockClient("test-model", "test")
+	client.responses = []*ChatResponse{textResponse("no"), textResponse("still no")}
+
+	_, err := NewTypedClient[typedAnswer](client, &TypedConfig{MaxRetries: 1}).Complete(context.Background(), "?")
+	var llmErr *Error
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "invalid_structured_output", llmErr.Code)
+	assert.Len(t, client.GetCallLog(), 2)
+}
+
+func TestTypedNonStrictTypes(t *testing.T) {
+	// Maps cannot be expressed in strict mode, so they are requested without it
+	format, err := Typed[map[string]int](NewMockClient("test-model", "test")).ResponseFormat()
+	require.NoError(t, err)
+	assert.Nil(t, format.JSONSchema.Strict)
+	assert.Equal(t, "response", format.JSONSchema.Name)
+}
@@ -1,6 +1,12 @@
 // Core request and response types
 package llm
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // ChatRequest represents a chat completion request (provider-agnostic)
 type ChatRequest struct {
 	Model          string          `json:"model"`
@@ -11,6 +17,33 @@ type ChatRequest struct {
 	TopP           *float32        `json:"top_p,omitempty"`
 	Stream         bool            `json:"stream,omitempty"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// ResponseFormatPolicy selects between native structured outputs and prompt instructions.
+	// Defaults to ResponseFormatPolicyPreferNative.
+	ResponseFormatPolicy ResponseFormatPolicy `json:"response_format_policy,omitempty"`
+
+	// User identifies the end user of the application (e.g. a hash of their account ID),
+	// for the providers that monitor abuse per user (OpenAI, OpenRouter, Fireworks, NIM).
+	// Other providers ignore it.
+	User string `json:"user,omitempty"`
+
+	// Metadata holds caller-defined tags (e.g. tenant or user IDs) for the request.
+	// Providers that support request metadata (OpenAI, OpenRouter) forward it as strings.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// ProviderOptions holds provider-specific settings, created with the helpers of the
+	// provider packages (e.g. openai.WithLogitBias). Other providers ignore them.
+	ProviderOptions []ProviderOption `json:"-"`
+
+	// ExtraBody holds fields merged into the JSON body sent by the providers with an
+	// OpenAI-compatible API (OpenAI, OpenRouter, DeepSeek), replacing the fields they
+	// generate, so vendor parameters can be used before the library supports them. A nil
+	// value removes a generated field. Other providers ignore it.
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+
+	// Validators check the response, re-asking the model when it fails, if the client is
+	// a ValidatingClient. Other clients ignore them.
+	Validators []ResponseValidator `json:"-"`
 }
 
 // ChatResponse represents a chat completion response (provider-agnostic)
@@ -19,13 +52,38 @@ type ChatResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage,omitempty"`
+
+	// Metadata holds tags associated with the response, including those propagated from the request
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// Warnings report request settings the provider ignored instead of failing
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	// Raw is the provider-native response, when the provider client keeps it. It is not
+	// serialized.
+	Raw RawResponse `json:"-"`
+}
+
+// Warning describes a request setting that was not applied as requested
+type Warning struct {
+	// Code identifies the kind of warning (e.g. "parameter_ignored")
+	Code string `json:"code"`
+
+	// Message is a human readable description
+	Message string `json:"message"`
+
+	// Param is the request field concerned, when there is one (e.g. "temperature")
+	Param string `json:"param,omitempty"`
 }
 
 // Choice represents a single response choice
 type Choice struct {
-	Index        int     `json:"index"`
-	Message      Message `json:"message"`
-	FinishReason string  `json:"finish_reason,omitempty"`
+	Index        int          `json:"index"`
+	Message      Message      `json:"message"`
+	FinishReason FinishReason `json:"finish_reason,omitempty"`
+
+	// RawFinishReason is the finish reason as reported by the provider (e.g. "end_turn")
+	RawFinishReason string `json:"raw_finish_reason,omitempty"`
 }
 
 // Usage represents token usage information
@@ -33,6 +91,84 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// CacheReadTokens are the prompt tokens served from the provider prompt cache. They
+	// are included in PromptTokens.
+	CacheReadTokens int `json:"cache_read_tokens,omitempty"`
+
+	// CacheWriteTokens are the prompt tokens written to the provider prompt cache, for
+	// providers charging for them (e.g. Anthropic). They are included in PromptTokens.
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		CacheReadTokens:  u.CacheReadTokens + other.CacheReadTokens,
+		CacheWriteTokens: u.CacheWriteTokens + other.CacheWriteTokens,
+	}
+}
+
+// SetMetadata sets a metadata key-value pair on the request
+func (r *ChatRequest) SetMetadata(key string, value any) {
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]any)
+	}
+	r.Metadata[key] = value
+}
+
+// GetMetadata retrieves a request metadata value by key
+func (r ChatRequest) GetMetadata(key string) (any, bool) {
+	if r.Metadata == nil {
+		return nil, false
+	}
+	value, exists := r.Metadata[key]
+	return value, exists
+}
+
+// MetadataAsStrings returns the request metadata with all values converted to strings,
+// as required by providers that only accept string metadata
+func (r ChatRequest) MetadataAsStrings() map[string]string {
+	if len(r.Metadata) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(r.Metadata))
+	for k, v := range r.Metadata {
+		switch value := v.(type) {
+		case string:
+			result[k] = value
+		case nil:
+			result[k] = ""
+		default:
+			if data, err := json.Marshal(value); err == nil {
+				result[k] = strings.Trim(string(data), `"`)
+			} else {
+				result[k] = fmt.Sprint(value)
+			}
+		}
+	}
+	return result
+}
+
+// SetMetadata sets a metadata key-value pair on the response
+func (r *ChatResponse) SetMetadata(key string, value any) {
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]any)
+	}
+	r.Metadata[key] = value
+}
+
+// GetMetadata retrieves a response metadata value by key
+func (r ChatResponse) GetMetadata(key string) (any, bool) {
+	if r.Metadata == nil {
+		return nil, false
+	}
+	value, exists := r.Metadata[key]
+	return value, exists
 }
 
 // WantsToolExecution checks if this choice indicates the LLM wants to execute tools
@@ -71,11 +207,17 @@ func (r ChatResponse) DeepCopy() ChatResponse {
 	copy := ChatResponse{
 		ID:    r.ID,
 		Model: r.Model,
-		Usage: Usage{
-			PromptTokens:     r.Usage.PromptTokens,
-			CompletionTokens: r.Usage.CompletionTokens,
-			TotalTokens:      r.Usage.TotalTokens,
-		},
+		Usage: r.Usage,
+		// Raw responses are not modified, so they are shared
+		Raw: r.Raw,
+	}
+
+	// Deep copy the Metadata map
+	if len(r.Metadata) > 0 {
+		copy.Metadata = make(map[string]any, len(r.Metadata))
+		for k, v := range r.Metadata {
+			copy.Metadata[k] = deepCopyValue(v)
+		}
 	}
 
 	// Deep copy the Choices slice
@@ -83,12 +225,17 @@ func (r ChatResponse) DeepCopy() ChatResponse {
 		copy.Choices = make([]Choice, 0, len(r.Choices))
 		for _, choice := range r.Choices {
 			copy.Choices = append(copy.Choices, Choice{
-				Index:        choice.Index,
-				Message:      choice.Message.DeepCopy(), // Use Message's DeepCopy method
-				FinishReason: choice.FinishReason,
+				Index:           choice.Index,
+				Message:         choice.Message.DeepCopy(), // Use Message's DeepCopy method
+				FinishReason:    choice.FinishReason,
+				RawFinishReason: choice.RawFinishReason,
 			})
 		}
 	}
 
+	if len(r.Warnings) > 0 {
+		copy.Warnings = append([]Warning(nil), r.Warnings...)
+	}
+
 	return copy
 }
@@ -0,0 +1,241 @@
+// Chunked and resumable uploads to provider file APIs
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultUploadChunkSize is the size of the chunks sent by the provider uploaders
+	DefaultUploadChunkSize = 8 * 1024 * 1024
+
+	// DefaultUploadThreshold is the file size above which uploading a file, instead of
+	// inlining it in requests, is recommended
+	DefaultUploadThreshold = 20 * 1024 * 1024
+)
+
+// FileUploader is implemented by the clients of providers with a file API (OpenAI,
+// Gemini). Files are streamed from their reader in chunks, so their size does not
+// affect memory usage.
+type FileUploader interface {
+	UploadFile(ctx context.Context, upload *FileUpload) (*UploadedFile, error)
+}
+
+// UploadProgress reports the bytes sent of an upload
+type UploadProgress struct {
+	Uploaded int64 `json:"uploaded"`
+	Total    int64 `json:"total"`
+}
+
+// UploadSession identifies an upload started on a provider, to resume it after an
+// interruption. It is reported in the Upload field of the "upload_interrupted" errors.
+type UploadSession struct {
+	Provider string `json:"provider"`
+
+	// ID is the provider upload ID (OpenAI)
+	ID string `json:"id,omitempty"`
+
+	// URL is the provider upload URL (Gemini)
+	URL string `json:"url,omitempty"`
+
+	// Offset is the number of bytes received by the provider
+	Offset int64 `json:"offset"`
+
+	// Parts are the IDs of the parts received by the provider (OpenAI)
+	Parts []string `json:"parts,omitempty"`
+}
+
+// FileUpload describes a file to upload
+type FileUpload struct {
+	// Reader provides the content of the file, from its start even when resuming
+	Reader io.Reader `json:"-"`
+
+	// Size is the size of the file, required by the provider APIs
+	Size int64 `json:"size"`
+
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+
+	// Purpose is the intended use of the file, for the providers that need one (e.g.
+	// "user_data" or "batch" for OpenAI)
+	Purpose string `json:"purpose,omitempty"`
+
+	// ChunkSize is the size of the chunks sent, DefaultUploadChunkSize when zero.
+	// Providers may round it to their own constraints.
+	ChunkSize int64 `json:"chunk_size,omitempty"`
+
+	// SHA256 is the expected hex digest of the content. When set, the upload is not
+	// completed if the content read does not match.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// OnProgress is called after every chunk sent
+	OnProgress func(UploadProgress) `json:"-"`
+
+	// Resume continues an interrupted upload. The content before the session offset is
+	// read (to compute its digest) but not sent again.
+	Resume *UploadSession `json:"resume,omitempty"`
+}
+
+// NewFileUpload describes a file to upload from a reader
+func NewFileUpload(reader io.Reader, size int64, filename, mimeType string) *FileUpload {
+	return &FileUpload{Reader: reader, Size: size, Filename: filename, MimeType: mimeType}
+}
+
+// NewFileUploadFromFile describes the upload of an opened file, which must stay open
+// until the upload ends. The MIME type is guessed from the extension when empty.
+func NewFileUploadFromFile(file *os.File, mimeType string) (*FileUpload, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if mimeType == "" {
+		mimeType = mimeTypeFromExtension(file.Name())
+	}
+	return NewFileUpload(file, info.Size(), filepath.Base(file.Name()), mimeType), nil
+}
+
+// mimeTypeFromExtension guesses the MIME type of the supported files from their name
+func mimeTypeFromExtension(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return "application/pdf"
+	case ".txt":
+		return "text/plain"
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv"
+	case ".docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Validate checks that an upload can be started
+func (u *FileUpload) Validate() error {
+	switch {
+	case u == nil || u.Reader == nil:
+		return uploadError("invalid_upload", "an upload needs a reader")
+	case u.Size <= 0:
+		return uploadError("invalid_upload", "an upload needs the size of the file")
+	case strings.TrimSpace(u.Filename) == "":
+		return uploadError("invalid_upload", "an upload needs a filename")
+	case u.Resume != nil && (u.Resume.Offset < 0 || u.Resume.Offset > u.Size):
+		return uploadError("invalid_upload", fmt.Sprintf("invalid resume offset %d", u.Resume.Offset))
+	}
+	return nil
+}
+
+// UploadedFile is a file stored by a provider
+type UploadedFile struct {
+	// ID is the provider file ID (e.g. "file-abc" for OpenAI, "files/abc" for Gemini)
+	ID string `json:"id"`
+
+	// URI references the file in requests, for the providers using URIs (Gemini)
+	URI string `json:"uri,omitempty"`
+
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+
+	// SHA256 is the hex digest of the content sent
+	SHA256 string `json:"sha256"`
+}
+
+// ChunkSender sends a chunk of an upload, starting at offset. last is set for the final
+// chunk, sent only once the digest of the whole content has been verified.
+type ChunkSender func(ctx context.Context, chunk []byte, offset int64, last bool) error
+
+// SendChunks reads an upload in chunks of chunkSize bytes, passing the chunks after the
+// resume offset to send and reporting the progress. A single chunk buffer is used for
+// the whole upload. It returns the hex SHA-256 digest of the content, failing with an
+// "integrity_mismatch" error when it does not match the expected one, or when the reader
+// ends before Size bytes.
+func SendChunks(ctx context.Context, upload *FileUpload, chunkSize int64, send ChunkSender) (string, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultUploadChunkSize
+	}
+	var offset int64
+	if upload.Resume != nil {
+		offset = upload.Resume.Offset
+	}
+
+	hash := sha256.New()
+	if offset > 0 {
+		if _, err := io.CopyN(hash, upload.Reader, offset); err != nil {
+			return "", uploadError("integrity_mismatch", fmt.Sprintf("cannot skip the %d bytes already uploaded: %v", offset, err))
+		}
+	}
+
+	// An upload interrupted while completing has no data left, only an empty last chunk
+	if offset == upload.Size {
+		if err := verifyUploadDigest(upload, hash.Sum(nil)); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), send(ctx, nil, offset, true)
+	}
+
+	buffer := make([]byte, min(chunkSize, upload.Size-offset))
+	for offset < upload.Size {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := io.ReadFull(upload.Reader, buffer[:min(int64(len(buffer)), upload.Size-offset)])
+		if err != nil {
+			return "", uploadError("integrity_mismatch", fmt.Sprintf("the file is shorter than its size of %d bytes: %v", upload.Size, err))
+		}
+		chunk := buffer[:n]
+		hash.Write(chunk)
+
+		last := offset+int64(n) == upload.Size
+		if last {
+			if err := verifyUploadDigest(upload, hash.Sum(nil)); err != nil {
+				return "", err
+			}
+		}
+		if err := send(ctx, chunk, offset, last); err != nil {
+			return "", err
+		}
+		offset += int64(n)
+		if upload.OnProgress != nil {
+			upload.OnProgress(UploadProgress{Uploaded: offset, Total: upload.Size})
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyUploadDigest compares a digest with the expected one of an upload
+func verifyUploadDigest(upload *FileUpload, digest []byte) error {
+	if upload.SHA256 == "" || strings.EqualFold(upload.SHA256, hex.EncodeToString(digest)) {
+		return nil
+	}
+	return uploadError("integrity_mismatch", fmt.Sprintf("the SHA-256 digest of %s is %x, expected %s",
+		upload.Filename, digest, upload.SHA256))
+}
+
+// NewUploadInterruptedError reports an upload that failed after being started, with the
+// session to resume it
+func NewUploadInterruptedError(session *UploadSession, err error) *Error {
+	llmErr := uploadError("upload_interrupted", fmt.Sprintf("upload interrupted at byte %d: %v", session.Offset, err))
+	var cause *Error
+	if errors.As(err, &cause) {
+		llmErr.StatusCode = cause.StatusCode
+		llmErr.RateLimit = cause.RateLimit
+	}
+	llmErr.Upload = session
+	return llmErr
+}
+
+// uploadError creates an upload error
+func uploadError(code, message string) *Error {
+	return &Error{Code: code, Message: message, Type: "upload_error"}
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedChunk is a chunk passed to a ChunkSender
+type recordedChunk struct {
+	data   string
+	offset int64
+	last   bool
+}
+
+func TestSendChunks(t *testing.T) {
+	content := "0123456789abcdefghij"
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name   string
+		upload *FileUpload
+		chunks []recordedChunk
+		code   string
+	}{
+		{
+			name:   "chunks",
+			upload: &FileUpload{Size: 20, SHA256: digest},
+			chunks: []recordedChunk{{"01234567", 0, false}, {"89abcdef", 8, false}, {"ghij", 16, true}},
+		},
+		{
+			name:   "resumed",
+			upload: &FileUpload{Size: 20, Resume: &UploadSession{Offset: 16}},
+			chunks: []recordedChunk{{"ghij", 16, true}},
+		},
+		{
+			name:   "resumed at the end",
+			upload: &FileUpload{Size: 20, Resume: &UploadSession{Offset: 20}},
+			chunks: []recordedChunk{{"", 20, true}},
+		},
+		{
+			name:   "digest mismatch",
+			upload: &FileUpload{Size: 20, SHA256: hex.EncodeToString(make([]byte, 32))},
+			chunks: []recordedChunk{{"01234567", 0, false}, {"89abcdef", 8, false}},
+			code:   "integrity_mismatch",
+		},
+		{
+			name:   "short file",
+			upload: &FileUpload{Size: 30},
+			chunks: []recordedChunk{{"01234567", 0, false}, {"89abcdef", 8, false}},
+			code:   "integrity_mismatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progress []int64
+			tt.upload.Reader = bytes.NewReader([]byte(content))
+			tt.upload.OnProgress = func(p UploadProgress) { progress = append(progress, p.Uploaded) }
+
+			var chunks []recordedChunk
+			got, err := SendChunks(context.Background(), tt.upload, 8, func(ctx context.Context, chunk []byte, offset int64, last bool) error {
+				chunks = append(chunks, recordedChunk{string(chunk), offset, last})
+				return nil
+			})
+			assert.Equal(t, tt.chunks, chunks)
+			if tt.code != "" {
+				var llmErr *Error
+				require.True(t, errors.As(err, &llmErr), "expected an error, got %v", err)
+				assert.Equal(t, tt.code, llmErr.Code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, digest, got)
+			if len(progress) > 0 {
+				assert.Equal(t, int64(20), progress[len(progress)-1])
+			}
+		})
+	}
+}
+
+func TestFileUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	require.NoError(t, os.WriteFile(path, []byte("%PDF-1.4"), 0o600))
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	upload, err := NewFileUploadFromFile(file, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), upload.Size)
+	assert.Equal(t, "report.pdf", upload.Filename)
+	assert.Equal(t, "application/pdf", upload.MimeType)
+	assert.NoError(t, upload.Validate())
+
+	upload.Resume = &UploadSession{Offset: 9}
+	assert.Error(t, upload.Validate())
+	assert.Error(t, NewFileUpload(nil, 8, "a.txt", "text/plain").Validate())
+
+	interrupted := NewUploadInterruptedError(&UploadSession{ID: "upload_1", Offset: 8}, &Error{Code: "server_error", StatusCode: 503})
+	assert.Equal(t, "upload_interrupted", interrupted.Code)
+	assert.Equal(t, 503, interrupted.StatusCode)
+	assert.Equal(t, "upload_1", interrupted.Upload.ID)
+}
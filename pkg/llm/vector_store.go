@@ -0,0 +1,234 @@
+// Vector stores: documents retrieved by similarity, for retrieval-augmented generation
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Document is a chunk of text stored in a VectorStore
+type Document struct {
+	// ID identifies the document in the store, so upserting it again replaces it
+	ID string `json:"id"`
+
+	Text string `json:"text"`
+
+	// Metadata are attributes of the document, e.g. its source. Stores may restrict
+	// the values to strings, numbers and booleans.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// ScoredDocument is a document matching a query, with its similarity score (higher is
+// more similar, in a range that depends on the store)
+type ScoredDocument struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// VectorStore stores documents and retrieves those most similar to a query, the
+// retrieval step of RAGMiddleware. Implementations must be safe for concurrent use.
+type VectorStore interface {
+	// Upsert adds documents, replacing those with the same IDs
+	Upsert(ctx context.Context, docs ...Document) error
+
+	// Query returns at most topK documents similar to the text, most similar first
+	Query(ctx context.Context, text string, topK int) ([]ScoredDocument, error)
+
+	// Delete removes documents by ID, ignoring the unknown ones
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Embedder computes the embedding vectors of texts, one per text. The Embeddings
+// method of the Ollama client satisfies it.
+type Embedder interface {
+	Embeddings(ctx context.Context, texts ...string) ([][]float64, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface
+type EmbedderFunc func(ctx context.Context, texts ...string) ([][]float64, error)
+
+// Embeddings calls the function
+func (f EmbedderFunc) Embeddings(ctx context.Context, texts ...string) ([][]float64, error) {
+	return f(ctx, texts...)
+}
+
+// MemoryVectorStore is a VectorStore keeping the documents and their embeddings in
+// memory, scored by cosine similarity. Queries compare the query with every document, so
+// it suits small corpora and tests.
+type MemoryVectorStore struct {
+	embedder Embedder
+
+	mu      sync.RWMutex
+	docs    map[string]memoryDocument
+	ordered []string // IDs in insertion order, so ties are stable
+}
+
+// memoryDocument is a document with its embedding
+type memoryDocument struct {
+	doc    Document
+	vector []float64
+	norm   float64
+}
+
+// NewMemoryVectorStore creates an in-memory vector store using an embedder for the
+// documents and the queries
+func NewMemoryVectorStore(embedder Embedder) *MemoryVectorStore {
+	return &MemoryVectorStore{
+		embedder: embedder,
+		docs:     make(map[string]memoryDocument),
+	}
+}
+
+// Upsert embeds the documents and adds them, replacing those with the same IDs
+func (s *MemoryVectorStore) Upsert(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		if err := doc.Validate(); err != nil {
+			return err
+		}
+		texts[i] = doc.Text
+	}
+	vectors, err := s.embed(ctx, texts...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, doc := range docs {
+		if _, exists := s.docs[doc.ID]; !exists {
+			s.ordered = append(s.ordered, doc.ID)
+		}
+		doc.Metadata = copyMetadata(doc.Metadata)
+		s.docs[doc.ID] = memoryDocument{doc: doc, vector: vectors[i], norm: vectorNorm(vectors[i])}
+	}
+	return nil
+}
+
+// Query embeds the text and returns the topK documents with the highest cosine
+// similarity, between -1 and 1
+func (s *MemoryVectorStore) Query(ctx context.Context, text string, topK int) ([]ScoredDocument, error) {
+	if topK <= 0 {
+		return nil, vectorStoreError("invalid_query", fmt.Sprintf("topK must be positive, got %d", topK))
+	}
+	vectors, err := s.embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	query, queryNorm := vectors[0], vectorNorm(vectors[0])
+
+	s.mu.RLock()
+	matches := make([]ScoredDocument, 0, len(s.ordered))
+	for _, id := range s.ordered {
+		stored := s.docs[id]
+		doc := stored.doc
+		doc.Metadata = copyMetadata(doc.Metadata)
+		matches = append(matches, ScoredDocument{
+			Document: doc,
+			Score:    cosineSimilarity(query, queryNorm, stored.vector, stored.norm),
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// Delete removes documents by ID
+func (s *MemoryVectorStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	kept := s.ordered[:0]
+	for _, id := range s.ordered {
+		if _, ok := s.docs[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	s.ordered = kept
+	return nil
+}
+
+// Len returns the number of documents stored
+func (s *MemoryVectorStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// embed computes the embeddings of texts, checking the embedder returned one per text
+func (s *MemoryVectorStore) embed(ctx context.Context, texts ...string) ([][]float64, error) {
+	if s.embedder == nil {
+		return nil, vectorStoreError("invalid_config", "the vector store has no embedder")
+	}
+	vectors, err := s.embedder.Embeddings(ctx, texts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, vectorStoreError("invalid_embeddings",
+			fmt.Sprintf("expected %d embeddings, got %d", len(texts), len(vectors)))
+	}
+	return vectors, nil
+}
+
+// Validate checks the document has an ID and a text
+func (d Document) Validate() error {
+	if d.ID == "" {
+		return vectorStoreError("invalid_document", "document ID is required")
+	}
+	if d.Text == "" {
+		return vectorStoreError("invalid_document", fmt.Sprintf("document %q has no text", d.ID))
+	}
+	return nil
+}
+
+// copyMetadata returns a shallow copy of the metadata of a document
+func copyMetadata(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]any, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+// vectorNorm returns the Euclidean norm of a vector
+func vectorNorm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, zero when they
+// have different dimensions or one of them is null
+func cosineSimilarity(a []float64, normA float64, b []float64, normB float64) float64 {
+	if len(a) != len(b) || normA == 0 || normB == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot / (normA * normB)
+}
+
+// vectorStoreError creates a validation error of a vector store
+func vectorStoreError(code, message string) *Error {
+	return &Error{Code: code, Message: message, Type: "validation_error"}
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordEmbedder embeds texts as the counts of a few words
+func wordEmbedder() Embedder {
+	vocabulary := []string{"cat", "dog", "fish", "water", "bark"}
+	return EmbedderFunc(func(ctx context.Context, texts ...string) ([][]float64, error) {
+		vectors := make([][]float64, len(texts))
+		for i, text := range texts {
+			vectors[i] = make([]float64, len(vocabulary))
+			for _, word := range strings.Fields(strings.ToLower(text)) {
+				for j, known := range vocabulary {
+					if strings.Trim(word, ".,?!") == known {
+						vectors[i][j]++
+					}
+				}
+			}
+		}
+		return vectors, nil
+	})
+}
+
+func TestMemoryVectorStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryVectorStore(wordEmbedder())
+	require.NoError(t, store.Upsert(ctx,
+		Document{ID: "cats", Text: "The cat sleeps."},
+		Document{ID: "dogs", Text: "The dog may bark.", Metadata: map[string]any{"source": "dogs.md"}},
+		Document{ID: "fish", Text: "Fish live in water."},
+	))
+	assert.Equal(t, 3, store.Len())
+
+	matches, err := store.Query(ctx, "Why does my dog bark?", 2)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "dogs", matches[0].ID)
+	assert.Equal(t, "dogs.md", matches[0].Metadata["source"])
+	assert.InDelta(t, 1.0, matches[0].Score, 1e-9)
+	assert.Zero(t, matches[1].Score)
+
+	// Upserting replaces the document, keeping its place for ties
+	require.NoError(t, store.Upsert(ctx, Document{ID: "cats", Text: "The cat drinks water."}))
+	matches, err = store.Query(ctx, "water", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "cats", matches[0].ID)
+	assert.Equal(t, "fish", matches[1].ID)
+	assert.Equal(t, matches[0].Score, matches[1].Score)
+	assert.Equal(t, 3, store.Len())
+
+	require.NoError(t, store.Delete(ctx, "fish", "unknown"))
+	matches, err = store.Query(ctx, "water", 3)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "cats", matches[0].ID)
+
+	var llmErr *Error
+	_, err = store.Query(ctx, "water", 0)
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "invalid_query", llmErr.Code)
+	err = store.Upsert(ctx, Document{ID: "empty"})
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, "invalid_document", llmErr.Code)
+}
+
+func TestRAGMiddleware(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryVectorStore(wordEmbedder())
+	require.NoError(t, store.Upsert(ctx,
+		Document{ID: "dogs", Text: "Dogs bark to communicate."},
+		Document{ID: "fish", Text: "Fish need clean water."},
+		Document{ID: "cats", Text: "A cat purrs."},
+	))
+
+	mock := NewMockClient("mock-model", "mock")
+	client := NewEnhancedClient(mock, []Middleware{NewRAGMiddleware(store, &RAGConfig{TopK: 2, MinScore: 0.1})})
+	_, err := client.ChatCompletion(ctx, ChatRequest{Model: "m", Messages: []Message{
+		NewTextMessage(RoleSystem, "You are a vet."),
+		NewTextMessage(RoleUser, "Tell me about fish."),
+		NewTextMessage(RoleAssistant, "They swim."),
+		NewTextMessage(RoleUser, "Why does my dog bark?"),
+	}})
+	require.NoError(t, err)
+
+	sent := mock.callLog[len(mock.callLog)-1].Messages
+	require.Len(t, sent, 5)
+	assert.Equal(t, "You are a vet.", sent[0].GetText())
+	assert.Equal(t, RoleSystem, sent[1].Role)
+	assert.Equal(t, "Use the following context to answer the user. Ignore it if it is not relevant.\n\n[1] Dogs bark to communicate.",
+		sent[1].GetText(), "only the documents scoring at least MinScore are added")
+	assert.Equal(t, "Tell me about fish.", sent[2].GetText())
+
+	// Requests without matching documents are unchanged
+	_, err = client.ChatCompletion(ctx, ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, "Hello")}})
+	require.NoError(t, err)
+	assert.Len(t, mock.callLog[len(mock.callLog)-1].Messages, 1)
+
+	// Retrieval errors fail the request
+	failing := NewMemoryVectorStore(EmbedderFunc(func(ctx context.Context, texts ...string) ([][]float64, error) {
+		return nil, errors.New("embedding service down")
+	}))
+	client = NewEnhancedClient(mock, []Middleware{NewRAGMiddleware(failing, nil)})
+	_, err = client.ChatCompletion(ctx, ChatRequest{Model: "m", Messages: []Message{NewTextMessage(RoleUser, "Hello")}})
+	assert.ErrorContains(t, err, "retrieving context: embedding service down")
+}
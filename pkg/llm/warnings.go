@@ -0,0 +1,74 @@
+// Warnings about request features a provider degraded instead of failing the request
+package llm
+
+import "fmt"
+
+// Codes of the warnings returned in ChatResponse.Warnings
+const (
+	// WarningParameterIgnored is a request parameter the model does not support, which
+	// was not sent
+	WarningParameterIgnored = "parameter_ignored"
+
+	// WarningResponseFormatPrompted is a response format requested through a system
+	// message because the provider cannot enforce it, so the output may not follow it
+	WarningResponseFormatPrompted = "response_format_prompted"
+
+	// WarningFileSentAsText is a file attachment flattened into the text of its message
+	WarningFileSentAsText = "file_sent_as_text"
+
+	// WarningContentDropped is a content item the provider cannot accept, which was
+	// replaced by a placeholder or left out
+	WarningContentDropped = "content_dropped"
+)
+
+// ResponseFormatWarnings returns a WarningResponseFormatPrompted warning when
+// PrepareResponseFormat requests the response format through a system message rather than
+// the provider enforcing it, unless the request asked for prompting with
+// ResponseFormatPolicyPromptOnly
+func (r ChatRequest) ResponseFormatWarnings(nativeSupported bool) []Warning {
+	if r.ResponseFormatPolicy == ResponseFormatPolicyPromptOnly {
+		return nil
+	}
+	if native, err := r.ResolveResponseFormat(nativeSupported); err != nil || native || ResponseFormatInstruction(r.ResponseFormat) == "" {
+		return nil
+	}
+	return []Warning{{
+		Code:    WarningResponseFormatPrompted,
+		Message: fmt.Sprintf("the %s response format is not enforced by the provider and was requested in the prompt", r.ResponseFormat.Type),
+		Param:   "response_format",
+	}}
+}
+
+// ContentWarnings returns a warning with the code for every content item of the type in
+// the messages, saying what happened to it (e.g. "was sent as text")
+func ContentWarnings(messages []Message, contentType MessageType, code, outcome string) []Warning {
+	var warnings []Warning
+	for i, msg := range messages {
+		for j, content := range msg.Content {
+			if content == nil || content.Type() != contentType {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Code:    code,
+				Message: fmt.Sprintf("%s %s", describeContent(content), outcome),
+				Param:   fmt.Sprintf("messages[%d].content[%d]", i, j),
+			})
+		}
+	}
+	return warnings
+}
+
+// describeContent names a content item in warnings
+func describeContent(content MessageContent) string {
+	switch c := content.(type) {
+	case *FileContent:
+		return fmt.Sprintf("file %q (%s)", c.Filename, c.MimeType)
+	case *ImageContent:
+		if c.HasURL() && !c.HasData() {
+			return "image URL"
+		}
+		return fmt.Sprintf("image (%s)", c.MimeType)
+	default:
+		return string(content.Type()) + " content"
+	}
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFormatWarnings(t *testing.T) {
+	req := ChatRequest{ResponseFormat: &ResponseFormat{Type: ResponseFormatJSON}}
+
+	warnings := req.ResponseFormatWarnings(false)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, WarningResponseFormatPrompted, warnings[0].Code)
+	assert.Equal(t, "response_format", warnings[0].Param)
+
+	assert.Empty(t, req.ResponseFormatWarnings(true), "native formats are not degraded")
+	assert.Empty(t, ChatRequest{}.ResponseFormatWarnings(false))
+
+	req.ResponseFormatPolicy = ResponseFormatPolicyPromptOnly
+	assert.Empty(t, req.ResponseFormatWarnings(true), "prompting was requested")
+
+	req.ResponseFormatPolicy = ResponseFormatPolicyRequireNative
+	assert.Empty(t, req.ResponseFormatWarnings(false), "the request fails instead")
+}
+
+func TestContentWarnings(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, "Be brief"),
+		{Role: RoleUser, Content: []MessageContent{
+			NewTextContent("Summarize"),
+			NewFileContentFromBytes([]byte("a,b"), "data.csv", "text/csv"),
+			NewImageContentFromURL("https://example.com/a.png", "image/png"),
+		}},
+	}
+
+	warnings := ContentWarnings(messages, MessageTypeFile, WarningFileSentAsText, "was sent as text")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, Warning{
+		Code:    WarningFileSentAsText,
+		Message: `file "data.csv" (text/csv) was sent as text`,
+		Param:   "messages[1].content[1]",
+	}, warnings[0])
+
+	warnings = ContentWarnings(messages, MessageTypeImage, WarningContentDropped, "was dropped")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "image URL was dropped", warnings[0].Message)
+}
@@ -0,0 +1,462 @@
+package llmtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultTimeout is the time limit of each check when ConformanceSuite.Timeout is zero
+const DefaultTimeout = 60 * time.Second
+
+// ConformanceToolName is the tool the model is asked to call by the tools check
+const ConformanceToolName = "get_weather"
+
+// ConformanceSuite checks that an llm.Client implementation honors the contract of the
+// interface. Every check creates its own client with NewClient and closes it at the end,
+// so clients do not need to be safe for concurrent use.
+type ConformanceSuite struct {
+	// NewClient creates the client under test. It is required.
+	NewClient func(t *testing.T) llm.Client
+
+	// NewErrorClient creates a client whose requests fail (e.g. with an invalid API key
+	// or an unreachable base URL). When nil, the errors check sends InvalidRequest to a
+	// client created with NewClient.
+	NewErrorClient func(t *testing.T) llm.Client
+
+	// InvalidRequest is the request expected to fail in the errors check when there is
+	// no NewErrorClient. A request without messages is used when nil.
+	InvalidRequest *llm.ChatRequest
+
+	// Timeout limits each check, DefaultTimeout when zero
+	Timeout time.Duration
+
+	// Skip flags disable the checks of capabilities the client cannot run
+	SkipStreaming    bool
+	SkipTools        bool
+	SkipMultimodal   bool
+	SkipErrors       bool
+	SkipCancellation bool
+	SkipClose        bool
+}
+
+// Run runs the checks of the suite as subtests of t
+func (s *ConformanceSuite) Run(t *testing.T) {
+	t.Helper()
+	if s.NewClient == nil {
+		t.Fatal("llmtest: the conformance suite needs a NewClient function")
+	}
+
+	t.Run("ModelInfo", s.testModelInfo)
+	t.Run("Chat", s.testChat)
+	t.Run("Streaming", s.testStreaming)
+	t.Run("Tools", s.testTools)
+	t.Run("Multimodal", s.testMultimodal)
+	t.Run("Errors", s.testErrors)
+	t.Run("Cancellation", s.testCancellation)
+	t.Run("Close", s.testClose)
+}
+
+// client creates a client that is closed at the end of the test
+func (s *ConformanceSuite) client(t *testing.T) llm.Client {
+	t.Helper()
+	client := s.NewClient(t)
+	if client == nil {
+		t.Fatal("NewClient returned a nil client")
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// context returns a context limited by the timeout of the suite
+func (s *ConformanceSuite) context(t *testing.T) context.Context {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// testModelInfo checks the model information
+func (s *ConformanceSuite) testModelInfo(t *testing.T) {
+	info := s.client(t).GetModelInfo()
+	if info.Provider == "" {
+		t.Error("ModelInfo.Provider is empty")
+	}
+	if info.MaxTokens < 0 {
+		t.Errorf("ModelInfo.MaxTokens is negative: %d", info.MaxTokens)
+	}
+}
+
+// testChat sends a basic completion
+func (s *ConformanceSuite) testChat(t *testing.T) {
+	client := s.client(t)
+	resp, err := client.ChatCompletion(s.context(t), textRequest("Reply with the word OK."))
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	checkResponse(t, resp)
+	if resp.Choices[0].Message.GetText() == "" && !resp.Choices[0].Message.HasToolCalls() {
+		t.Error("the response message has no text")
+	}
+}
+
+// testStreaming streams a completion, requiring deltas, no errors and a closed channel
+func (s *ConformanceSuite) testStreaming(t *testing.T) {
+	if s.SkipStreaming {
+		t.Skip("streaming skipped")
+	}
+	client := s.client(t)
+	if !client.GetModelInfo().SupportsStreaming {
+		t.Skip("the model does not support streaming")
+	}
+
+	ctx := s.context(t)
+	req := textRequest("Count from one to five.")
+	req.Stream = true
+	events, err := client.StreamChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+
+	var text string
+	var deltas, done int
+	var lastSeq uint64
+	for _, event := range collect(ctx, t, events) {
+		if event.Seq != 0 {
+			if event.Seq <= lastSeq {
+				t.Errorf("event sequence numbers are not increasing: %d after %d", event.Seq, lastSeq)
+			}
+			lastSeq = event.Seq
+		}
+		switch {
+		case event.IsError():
+			t.Fatalf("the stream reported an error: %v", event.Error)
+		case event.IsDelta():
+			deltas++
+			if event.Choice != nil && event.Choice.Delta != nil {
+				for _, content := range event.Choice.Delta.Content {
+					if textContent, ok := content.(*llm.TextContent); ok {
+						text += textContent.Text
+					}
+				}
+			}
+		case event.IsDone():
+			done++
+		}
+	}
+	if deltas == 0 {
+		t.Error("the stream produced no deltas")
+	}
+	if text == "" {
+		t.Error("the stream produced no text")
+	}
+	if done > 1 {
+		t.Errorf("the stream produced %d done events", done)
+	}
+}
+
+// testTools asks for a tool call and answers it with a tool result
+func (s *ConformanceSuite) testTools(t *testing.T) {
+	if s.SkipTools {
+		t.Skip("tools skipped")
+	}
+	client := s.client(t)
+	if !client.GetModelInfo().SupportsTools {
+		t.Skip("the model does not support tools")
+	}
+
+	ctx := s.context(t)
+	req := textRequest("What is the weather in Paris? Use the " + ConformanceToolName + " tool.")
+	req.Tools = []llm.Tool{{
+		Type: "function",
+		Function: llm.ToolFunction{
+			Name:        ConformanceToolName,
+			Description: "Get the current weather of a city",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"location": map[string]interface{}{"type": "string", "description": "The city"},
+				},
+				"required": []string{"location"},
+			},
+		},
+	}}
+	resp, err := client.ChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatalf("ChatCompletion with tools failed: %v", err)
+	}
+	checkResponse(t, resp)
+
+	message := resp.Choices[0].Message
+	if !message.HasToolCalls() {
+		t.Fatal("the model did not call the tool")
+	}
+	for _, call := range message.ToolCalls {
+		if call.ID == "" {
+			t.Error("a tool call has no ID")
+		}
+		if call.Function.Name != ConformanceToolName {
+			t.Errorf("the model called the undeclared tool %q", call.Function.Name)
+		}
+		if !json.Valid([]byte(call.Function.Arguments)) {
+			t.Errorf("the arguments of the tool call are not JSON: %q", call.Function.Arguments)
+		}
+	}
+
+	// Answer every tool call and expect a final answer
+	req.Messages = append(req.Messages, message)
+	for _, call := range message.ToolCalls {
+		req.Messages = append(req.Messages, llm.Message{
+			Role:       llm.RoleTool,
+			ToolCallID: call.ID,
+			Content:    []llm.MessageContent{llm.NewTextContent(`{"temperature": 21, "conditions": "sunny"}`)},
+		})
+	}
+	resp, err = client.ChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatalf("ChatCompletion with tool results failed: %v", err)
+	}
+	checkResponse(t, resp)
+}
+
+// testMultimodal sends a small image
+func (s *ConformanceSuite) testMultimodal(t *testing.T) {
+	if s.SkipMultimodal {
+		t.Skip("multimodal skipped")
+	}
+	client := s.client(t)
+	if !client.GetModelInfo().SupportsVision {
+		t.Skip("the model does not support vision")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.ChatCompletion(s.context(t), llm.ChatRequest{
+		Messages: []llm.Message{{
+			Role: llm.RoleUser,
+			Content: []llm.MessageContent{
+				llm.NewTextContent("What color is this image? Answer with one word."),
+				llm.NewImageContentFromBytes(buf.Bytes(), "image/png"),
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion with an image failed: %v", err)
+	}
+	checkResponse(t, resp)
+}
+
+// testErrors checks that failures are reported as *llm.Error
+func (s *ConformanceSuite) testErrors(t *testing.T) {
+	if s.SkipErrors {
+		t.Skip("errors skipped")
+	}
+
+	newClient := s.client
+	req := llm.ChatRequest{}
+	if s.InvalidRequest != nil {
+		req = *s.InvalidRequest
+	}
+	if s.NewErrorClient != nil {
+		newClient = func(t *testing.T) llm.Client {
+			client := s.NewErrorClient(t)
+			t.Cleanup(func() { _ = client.Close() })
+			return client
+		}
+		req = textRequest("Reply with the word OK.")
+	}
+
+	t.Run("ChatCompletion", func(t *testing.T) {
+		resp, err := newClient(t).ChatCompletion(s.context(t), req)
+		if err == nil {
+			t.Fatal("the request did not fail")
+		}
+		if resp != nil {
+			t.Error("a failed request returned a response")
+		}
+		checkError(t, err)
+	})
+
+	t.Run("StreamChatCompletion", func(t *testing.T) {
+		client := newClient(t)
+		if s.SkipStreaming || !client.GetModelInfo().SupportsStreaming {
+			t.Skip("streaming skipped")
+		}
+		ctx := s.context(t)
+		streamReq := req
+		streamReq.Stream = true
+		events, err := client.StreamChatCompletion(ctx, streamReq)
+		if err != nil {
+			checkError(t, err)
+			return
+		}
+		var failed bool
+		for _, event := range collect(ctx, t, events) {
+			if event.IsError() {
+				failed = true
+				if event.Error == nil {
+					t.Error("an error event has no error")
+				} else {
+					checkError(t, event.Error)
+				}
+			}
+		}
+		if !failed {
+			t.Error("the stream did not report the failure")
+		}
+	})
+}
+
+// testCancellation checks that cancelled requests fail and cancelled streams close
+func (s *ConformanceSuite) testCancellation(t *testing.T) {
+	if s.SkipCancellation {
+		t.Skip("cancellation skipped")
+	}
+
+	t.Run("ChatCompletion", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resp, err := s.client(t).ChatCompletion(ctx, textRequest("Reply with the word OK."))
+		if err == nil {
+			t.Fatal("a request with a cancelled context did not fail")
+		}
+		if resp != nil {
+			t.Error("a cancelled request returned a response")
+		}
+	})
+
+	t.Run("StreamChatCompletion", func(t *testing.T) {
+		client := s.client(t)
+		if s.SkipStreaming || !client.GetModelInfo().SupportsStreaming {
+			t.Skip("streaming skipped")
+		}
+		ctx, cancel := context.WithCancel(s.context(t))
+		defer cancel()
+		req := textRequest("Write a long story about a lighthouse keeper.")
+		req.Stream = true
+		events, err := client.StreamChatCompletion(ctx, req)
+		if err != nil {
+			t.Fatalf("StreamChatCompletion failed: %v", err)
+		}
+
+		// Cancel after the first event and expect the stream to close without blocking
+		// the producer, draining what was already buffered
+		deadline := time.After(s.closeTimeout())
+		select {
+		case <-events:
+		case <-deadline:
+			t.Fatalf("the stream sent no event in %v", s.closeTimeout())
+		}
+		cancel()
+		deadline = time.After(s.closeTimeout())
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("the stream was not closed %v after its context was cancelled", s.closeTimeout())
+			}
+		}
+	})
+}
+
+// testClose checks that Close succeeds and can be repeated
+func (s *ConformanceSuite) testClose(t *testing.T) {
+	if s.SkipClose {
+		t.Skip("close skipped")
+	}
+	client := s.NewClient(t)
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("a second Close failed: %v", err)
+	}
+}
+
+// closeTimeout is the time a cancelled stream has to close
+func (s *ConformanceSuite) closeTimeout() time.Duration {
+	if s.Timeout > 0 && s.Timeout < 5*time.Second {
+		return s.Timeout
+	}
+	return 5 * time.Second
+}
+
+// collect reads the events of a stream until it is closed, failing the test when it is
+// not closed before the context is done
+func collect(ctx context.Context, t *testing.T, events <-chan llm.StreamEvent) []llm.StreamEvent {
+	t.Helper()
+	var collected []llm.StreamEvent
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return collected
+			}
+			collected = append(collected, event)
+		case <-ctx.Done():
+			t.Fatalf("the stream was not closed: %v", ctx.Err())
+		}
+	}
+}
+
+// textRequest returns a request with a single user message
+func textRequest(text string) llm.ChatRequest {
+	return llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, text)}}
+}
+
+// checkResponse checks the shape of a successful response
+func checkResponse(t *testing.T, resp *llm.ChatResponse) {
+	t.Helper()
+	if resp == nil {
+		t.Fatal("the response is nil")
+	}
+	if len(resp.Choices) == 0 {
+		t.Fatal("the response has no choices")
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Role != llm.RoleAssistant {
+		t.Errorf("the response message has the role %q, expected %q", choice.Message.Role, llm.RoleAssistant)
+	}
+	if choice.FinishReason == "" {
+		t.Error("the response has no finish reason")
+	}
+	if resp.Usage.TotalTokens < 0 || resp.Usage.PromptTokens < 0 || resp.Usage.CompletionTokens < 0 {
+		t.Errorf("the response has negative usage: %+v", resp.Usage)
+	}
+}
+
+// checkError checks that an error is an *llm.Error with a code or a message
+func checkError(t *testing.T, err error) {
+	t.Helper()
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Errorf("the error is a %T, not an *llm.Error: %v", err, err)
+		return
+	}
+	if llmErr.Code == "" && llmErr.Message == "" {
+		t.Error("the error has neither a code nor a message")
+	}
+}
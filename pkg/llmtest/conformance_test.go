@@ -0,0 +1,59 @@
+package llmtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/mock"
+)
+
+// newMockClient creates a mock client supporting every capability of the suite
+func newMockClient(t *testing.T) llm.Client {
+	client, err := mock.NewClient("mock-model", "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.WithModelCapabilities(4096, true, true, false, true).WithLatency(time.Millisecond)
+}
+
+func TestConformanceSuiteMock(t *testing.T) {
+	suite := &ConformanceSuite{
+		NewClient: newMockClient,
+		NewErrorClient: func(t *testing.T) llm.Client {
+			client, err := mock.NewClient("mock-model", "mock")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return client.WithError("invalid_api_key", "Incorrect API key provided", "authentication_error")
+		},
+		Timeout: 10 * time.Second,
+	}
+	suite.Run(t)
+}
+
+func TestConformanceSuiteSkipsUnsupportedCapabilities(t *testing.T) {
+	suite := &ConformanceSuite{
+		NewClient: func(t *testing.T) llm.Client {
+			client, err := mock.NewClient("mock-model", "mock")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return client.WithModelCapabilities(4096, false, false, false, false).WithLatency(time.Millisecond)
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	// Skipped checks end before reaching the error
+	checks := map[string]func(*testing.T){
+		"Streaming":  suite.testStreaming,
+		"Tools":      suite.testTools,
+		"Multimodal": suite.testMultimodal,
+	}
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			check(t)
+			t.Error("the check was not skipped")
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package llmtest provides a conformance suite for llm.Client implementations.
+//
+// ConformanceSuite runs the same checks against any client, so new providers and forks
+// can verify that they honor the contract of the llm.Client interface:
+//   - Chat: completions return choices with an assistant message and a finish reason
+//   - Streaming: streams deliver deltas, never report errors on valid requests and close
+//   - Tools: tool calls name a declared tool with JSON arguments, and tool results are
+//     accepted in the following turn
+//   - Multimodal: requests with images are answered
+//   - Errors: failures are reported as *llm.Error, by ChatCompletion and by streams
+//   - Cancellation: cancelled requests fail and cancelled streams close promptly
+//   - Close: Close succeeds and can be called more than once
+//
+// Capabilities the model does not declare in its ModelInfo (tools, vision, streaming)
+// are skipped, and every check can be skipped explicitly for clients that cannot run it.
+//
+// Usage:
+//
+//	func TestConformance(t *testing.T) {
+//	    suite := &llmtest.ConformanceSuite{
+//	        NewClient: func(t *testing.T) llm.Client {
+//	            client, err := myprovider.NewClient(config)
+//	            if err != nil {
+//	                t.Fatal(err)
+//	            }
+//	            return client
+//	        },
+//	        SkipMultimodal: true,
+//	    }
+//	    suite.Run(t)
+//	}
+package llmtest
@@ -2,13 +2,13 @@ package bedrock
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -91,16 +91,40 @@ var modelCapabilitiesList = []modelCapabilities{
 
 // Client implements the llm.Client interface for AWS Bedrock
 type Client struct {
-	bedrockClient        *bedrock.Client
-	bedrockRuntimeClient *bedrockruntime.Client
-	model                string
-	region               string
-	provider             string
-	timeout              time.Duration
+	bedrockClient *bedrock.Client
+	model         string
+	provider      string
+	timeout       time.Duration
+
+	// regions are the runtime clients models are invoked with, in failover order
+	regions []regionClient
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
 
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
+}
+
+var (
+	bedrockTransportOnce   sync.Once
+	sharedBedrockTransport *http.Transport
+)
+
+// bedrockTransport returns the HTTP/1.1 transport shared by the Bedrock clients, pooled
+// like the llm shared transport but with shorter connection and header timeouts
+func bedrockTransport() *http.Transport {
+	bedrockTransportOnce.Do(func() {
+		config := llm.DefaultTransportConfig()
+		config.DisableHTTP2 = true
+		config.DialTimeout = 5 * time.Second
+		config.TLSHandshakeTimeout = 5 * time.Second
+		config.ResponseHeaderTimeout = 30 * time.Second
+		sharedBedrockTransport = llm.NewTransport(config)
+	})
+	return sharedBedrockTransport
 }
 
 // noAuthSchemeResolver disables AWS authentication when using bearer tokens
@@ -118,14 +142,16 @@ func (r *noAuthSchemeResolver) ResolveAuthSchemes(ctx context.Context, params *b
 
 // NewClient creates a new AWS Bedrock client
 func NewClient(config llm.ClientConfig) (*Client, error) {
-	// Get region from Extra config or use default
-	region := DefaultRegion
-	if config.Extra != nil {
-		if r, exists := config.Extra["region"]; exists {
-			region = r
-		}
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
 	}
 
+	// Get the regions from Extra config or use the default one. The first region is
+	// the primary one, the others are used when it is throttled or unavailable.
+	regions := configuredRegions(config.Extra)
+	region := regions[0]
+	crossRegion := config.Extra["cross_region_inference"] == "true"
+
 	// Check if AWS_BEDROCK_TOKEN is provided (bearer token authentication)
 	var bearerToken string
 	if config.Extra != nil {
@@ -141,28 +167,11 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		timeout = 30 * time.Second // Default 30s timeout - aggressive to prevent hangs
 	}
 
-	// Create HTTP client with aggressive timeouts to prevent hangs
+	// Create HTTP client with aggressive timeouts to prevent hangs, pooling connections
 	// Disable HTTP/2 to avoid connection multiplexing issues where one stuck connection hangs all requests
 	httpClient := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   5 * time.Second, // Shorter connection timeout
-				KeepAlive: 0,               // Disable keep-alive
-			}).DialContext,
-			DisableKeepAlives:     true,            // Force close connections after each request
-			ForceAttemptHTTP2:     false,           // Disable HTTP/2
-			MaxIdleConns:          0,               // No idle connections
-			MaxIdleConnsPerHost:   0,               // No idle connections per host
-			IdleConnTimeout:       1 * time.Second, // Very short idle timeout
-			TLSHandshakeTimeout:   5 * time.Second, // Shorter TLS timeout
-			ExpectContinueTimeout: 1 * time.Second,
-			ResponseHeaderTimeout: 30 * time.Second, // Max time waiting for response headers
-			MaxConnsPerHost:       10,               // Limit concurrent connections
-			// Disable HTTP/2 by setting TLSNextProto to empty map (prevents HTTP/2 upgrade)
-			TLSNextProto: make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
-		},
+		Timeout:   timeout,
+		Transport: &llm.DryRunTransport{Base: bedrockTransport()},
 	}
 
 	// Create AWS configuration with custom HTTP client
@@ -221,7 +230,7 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		}
 	})
 
-	bedrockRuntimeClient := bedrockruntime.NewFromConfig(awsConfig, func(o *bedrockruntime.Options) {
+	runtimeOptions := func(o *bedrockruntime.Options) {
 		// Set custom endpoint if provided
 		var customEndpoint string
 		if config.Extra != nil {
@@ -253,24 +262,40 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		if authMiddleware != nil {
 			o.APIOptions = append(o.APIOptions, authMiddleware)
 		}
-	})
+
+		// With failover regions, a throttled region is left at once instead of retried
+		if len(regions) > 1 {
+			o.RetryMaxAttempts = 1
+		}
+	}
+
+	regionClients := make([]regionClient, len(regions))
+	for i, r := range regions {
+		regionClients[i] = regionClient{
+			region:  r,
+			modelID: modelIDForRegion(config.Model, r, crossRegion),
+			runtime: bedrockruntime.NewFromConfig(awsConfig, runtimeOptions, func(o *bedrockruntime.Options) {
+				o.Region = r
+			}),
+		}
+	}
 
 	client := &Client{
-		bedrockClient:        bedrockClient,
-		bedrockRuntimeClient: bedrockRuntimeClient,
-		model:                config.Model,
-		region:               region,
-		provider:             "bedrock",
-		timeout:              timeout,
+		stats:         llm.NewHealthStatsWithClock(config.Clock),
+		clock:         config.GetClock(),
+		bedrockClient: bedrockClient,
+		model:         config.Model,
+		provider:      "bedrock",
+		timeout:       timeout,
+		regions:       regionClients,
+		streamBuffer:  config.StreamBuffer,
 	}
 
 	// If using bearer token, disable health checks (they won't work with bearer token auth)
 	if bearerToken != "" {
 		// Pre-set health status to avoid attempts to call ListFoundationModels
-		now := time.Now()
-		healthy := true
+		now := client.clock.Now()
 		client.lastHealthCheck = &now
-		client.lastHealthStatus = &healthy
 	}
 
 	return client, nil
@@ -290,6 +315,20 @@ func (c *Client) ensureTimeout(ctx context.Context) (context.Context, context.Ca
 
 // ChatCompletion performs a chat completion request
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.chatCompletion(ctx, req)
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply timeout if context doesn't have a deadline
 	ctx, cancel := c.ensureTimeout(ctx)
 	defer cancel()
@@ -300,22 +339,55 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		return nil, err
 	}
 
-	// Invoke model
-	response, err := c.bedrockRuntimeClient.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(c.model),
-		ContentType: aws.String("application/json"),
-		Body:        payload,
+	// Invoke model, with the guardrail of the request if any, failing over to the
+	// next region when needed
+	guardrailID, guardrailVersion := guardrail(req)
+	response, rc, err := invokeWithFailover(ctx, c, func(rc regionClient) (*bedrockruntime.InvokeModelOutput, error) {
+		return rc.runtime.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+			ModelId:             aws.String(rc.modelID),
+			ContentType:         aws.String("application/json"),
+			Body:                payload,
+			GuardrailIdentifier: guardrailID,
+			GuardrailVersion:    guardrailVersion,
+		})
 	})
 	if err != nil {
-		return nil, c.convertError(err)
+		return nil, err
 	}
 
 	// Convert response back to our format
-	return c.convertResponse(response.Body)
+	resp, err := c.convertResponse(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.SetMetadata("bedrock_region", rc.region)
+	resp.SetMetadata("bedrock_model_id", rc.modelID)
+	// The body is in the format of the model family, decoded by convertResponse
+	resp.Raw = llm.NewRawResponse(json.RawMessage(response.Body), response.Body)
+	// Structured outputs are always requested through prompt instructions
+	resp.Warnings = req.ResponseFormatWarnings(false)
+	return resp, nil
 }
 
 // StreamChatCompletion performs a streaming chat completion request
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply timeout if context doesn't have a deadline
 	ctx, cancel := c.ensureTimeout(ctx)
 	// Note: We don't defer cancel() here because the goroutine will use the context
@@ -327,20 +399,28 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		return nil, err
 	}
 
-	// Invoke model with streaming
-	response, err := c.bedrockRuntimeClient.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
-		ModelId:     aws.String(c.model),
-		ContentType: aws.String("application/json"),
-		Body:        payload,
+	// Invoke model with streaming, failing over to the next region when the stream
+	// cannot be started
+	guardrailID, guardrailVersion := guardrail(req)
+	response, _, err := invokeWithFailover(ctx, c, func(rc regionClient) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+		return rc.runtime.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+			ModelId:             aws.String(rc.modelID),
+			ContentType:         aws.String("application/json"),
+			Body:                payload,
+			GuardrailIdentifier: guardrailID,
+			GuardrailVersion:    guardrailVersion,
+		})
 	})
 	if err != nil {
-		return nil, c.convertError(err)
+		cancel()
+		return nil, err
 	}
 
-	ch := make(chan llm.StreamEvent, 10)
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		defer cancel() // Cancel the context when the goroutine exits
 
 		eventStream := response.GetStream()
@@ -357,44 +437,48 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 			select {
 			case <-ctx.Done():
 				// Context cancelled, send error and exit
-				ch <- llm.NewErrorEvent(c.convertError(ctx.Err()))
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(ctx.Err()))))
 				return
 			case <-timeout.C:
 				// Safety timeout reached, close stream
-				ch <- llm.NewErrorEvent(&llm.Error{
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
 					Code:    "timeout",
 					Message: "stream timeout after 30 seconds",
 					Type:    "timeout_error",
-				})
+				})))
 				return
 			case event, ok := <-eventCh:
 				if !ok {
 					// Channel closed, check for stream errors
 					if err := eventStream.Err(); err != nil {
-						ch <- llm.NewErrorEvent(c.convertError(err))
+						emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
 						return
 					}
 					// Normal completion
-					ch <- llm.NewDoneEvent(0, "stop")
+					emitter.Emit(seq.Stamp(llm.NewDoneEvent(0, "stop")))
 					return
 				}
 
 				eventCount++
 				if eventCount > maxEvents {
 					// Safety limit reached
-					ch <- llm.NewErrorEvent(&llm.Error{
+					emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
 						Code:    "max_events_exceeded",
 						Message: fmt.Sprintf("exceeded maximum event limit of %d", maxEvents),
 						Type:    "limit_error",
-					})
+					})))
 					return
 				}
 
 				switch v := event.(type) {
 				case *types.ResponseStreamMemberChunk:
 					// Parse the chunk and send delta event
-					if err := c.processStreamChunk(v.Value.Bytes, ch); err != nil {
-						ch <- llm.NewErrorEvent(c.convertError(err))
+					delta, err := c.processStreamChunk(v.Value.Bytes)
+					if err != nil {
+						emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
+						return
+					}
+					if delta != nil && !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
 						return
 					}
 				case *types.UnknownUnionMember:
@@ -408,7 +492,7 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		}
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
 }
 
 // GetRemote returns information about the remote client
@@ -418,32 +502,32 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on AWS Bedrock
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Try to list foundation models as a health check
 	_, err := c.bedrockClient.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
-	return err == nil
+	if err != nil {
+		return c.convertError(err)
+	}
+	return nil
 }
 
 // GetModelInfo returns information about the model being used
@@ -475,6 +559,16 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	}
 }
 
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
 // Close cleans up any resources used by the client
 func (c *Client) Close() error {
 	// AWS SDK clients don't require explicit cleanup
@@ -483,6 +577,12 @@ func (c *Client) Close() error {
 
 // convertRequest converts our ChatRequest to the appropriate format based on model
 func (c *Client) convertRequest(req llm.ChatRequest) ([]byte, error) {
+	// Structured outputs are requested through prompt instructions
+	req, err := req.PrepareResponseFormat(false)
+	if err != nil {
+		return nil, err
+	}
+
 	if c.isClaudeModel() {
 		return c.convertToClaudeRequest(req)
 	} else if c.isTitanModel() {
@@ -745,16 +845,41 @@ func (c *Client) convertClaudeResponse(body []byte) (*llm.ChatResponse, error) {
 	choice := llm.Choice{
 		Index:        0,
 		Message:      message,
-		FinishReason: "stop",
+		FinishReason: llm.FinishReasonStop,
+	}
+	if reason, ok := claudeResp["stop_reason"].(string); ok && reason != "" {
+		choice.SetFinishReason(c.provider, reason)
 	}
 
 	return &llm.ChatResponse{
 		ID:      fmt.Sprintf("bedrock-%s", time.Now().Format(time.RFC3339Nano)),
 		Model:   c.model,
 		Choices: []llm.Choice{choice},
+		Usage:   claudeUsage(claudeResp),
 	}, nil
 }
 
+// claudeUsage converts the usage of a Claude response. Claude reports the prompt tokens
+// read from and written to the prompt cache apart from the other input tokens.
+func claudeUsage(claudeResp map[string]interface{}) llm.Usage {
+	usage, ok := claudeResp["usage"].(map[string]interface{})
+	if !ok {
+		return llm.Usage{}
+	}
+	count := func(key string) int {
+		value, _ := usage[key].(float64)
+		return int(value)
+	}
+	result := llm.Usage{
+		CompletionTokens: count("output_tokens"),
+		CacheReadTokens:  count("cache_read_input_tokens"),
+		CacheWriteTokens: count("cache_creation_input_tokens"),
+	}
+	result.PromptTokens = count("input_tokens") + result.CacheReadTokens + result.CacheWriteTokens
+	result.TotalTokens = result.PromptTokens + result.CompletionTokens
+	return result
+}
+
 // convertTitanResponse converts Titan response format
 func (c *Client) convertTitanResponse(body []byte) (*llm.ChatResponse, error) {
 	var titanResp map[string]interface{}
@@ -779,7 +904,10 @@ func (c *Client) convertTitanResponse(body []byte) (*llm.ChatResponse, error) {
 	choice := llm.Choice{
 		Index:        0,
 		Message:      message,
-		FinishReason: "stop",
+		FinishReason: llm.FinishReasonStop,
+	}
+	if reason, ok := titanCompletionReason(titanResp); ok && reason != "" {
+		choice.SetFinishReason(c.provider, reason)
 	}
 
 	return &llm.ChatResponse{
@@ -789,6 +917,17 @@ func (c *Client) convertTitanResponse(body []byte) (*llm.ChatResponse, error) {
 	}, nil
 }
 
+// titanCompletionReason returns the completion reason of the first Titan result
+func titanCompletionReason(titanResp map[string]interface{}) (string, bool) {
+	if results, ok := titanResp["results"].([]interface{}); ok && len(results) > 0 {
+		if result, ok := results[0].(map[string]interface{}); ok {
+			reason, ok := result["completionReason"].(string)
+			return reason, ok
+		}
+	}
+	return "", false
+}
+
 // convertLlamaResponse converts Llama response format
 func (c *Client) convertLlamaResponse(body []byte) (*llm.ChatResponse, error) {
 	var llamaResp map[string]interface{}
@@ -809,7 +948,10 @@ func (c *Client) convertLlamaResponse(body []byte) (*llm.ChatResponse, error) {
 	choice := llm.Choice{
 		Index:        0,
 		Message:      message,
-		FinishReason: "stop",
+		FinishReason: llm.FinishReasonStop,
+	}
+	if reason, ok := llamaResp["stop_reason"].(string); ok && reason != "" {
+		choice.SetFinishReason(c.provider, reason)
 	}
 
 	return &llm.ChatResponse{
@@ -819,25 +961,26 @@ func (c *Client) convertLlamaResponse(body []byte) (*llm.ChatResponse, error) {
 	}, nil
 }
 
-// processStreamChunk processes a streaming chunk and sends appropriate events
-func (c *Client) processStreamChunk(chunkData []byte, ch chan<- llm.StreamEvent) error {
+// processStreamChunk processes a streaming chunk, returning its delta or nil when it has
+// no text
+func (c *Client) processStreamChunk(chunkData []byte) (*llm.MessageDelta, error) {
 	if c.isClaudeModel() {
-		return c.processClaudeStreamChunk(chunkData, ch)
+		return c.processClaudeStreamChunk(chunkData)
 	} else if c.isTitanModel() {
-		return c.processTitanStreamChunk(chunkData, ch)
+		return c.processTitanStreamChunk(chunkData)
 	} else if c.isLlamaModel() {
-		return c.processLlamaStreamChunk(chunkData, ch)
+		return c.processLlamaStreamChunk(chunkData)
 	}
 
 	// Default to Claude
-	return c.processClaudeStreamChunk(chunkData, ch)
+	return c.processClaudeStreamChunk(chunkData)
 }
 
 // processClaudeStreamChunk processes Claude streaming chunks
-func (c *Client) processClaudeStreamChunk(chunkData []byte, ch chan<- llm.StreamEvent) error {
+func (c *Client) processClaudeStreamChunk(chunkData []byte) (*llm.MessageDelta, error) {
 	var chunk map[string]interface{}
 	if err := json.Unmarshal(chunkData, &chunk); err != nil {
-		return err
+		return nil, err
 	}
 
 	var text string
@@ -852,51 +995,48 @@ func (c *Client) processClaudeStreamChunk(chunkData []byte, ch chan<- llm.Stream
 	}
 
 	if text != "" {
-		delta := &llm.MessageDelta{
+		return &llm.MessageDelta{
 			Content: []llm.MessageContent{llm.NewTextContent(text)},
-		}
-		ch <- llm.NewDeltaEvent(0, delta)
+		}, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 // processTitanStreamChunk processes Titan streaming chunks
-func (c *Client) processTitanStreamChunk(chunkData []byte, ch chan<- llm.StreamEvent) error {
+func (c *Client) processTitanStreamChunk(chunkData []byte) (*llm.MessageDelta, error) {
 	var chunk map[string]interface{}
 	if err := json.Unmarshal(chunkData, &chunk); err != nil {
-		return err
+		return nil, err
 	}
 
 	if outputText, ok := chunk["outputText"].(string); ok {
 		if outputText != "" {
-			delta := &llm.MessageDelta{
+			return &llm.MessageDelta{
 				Content: []llm.MessageContent{llm.NewTextContent(outputText)},
-			}
-			ch <- llm.NewDeltaEvent(0, delta)
+			}, nil
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
 // processLlamaStreamChunk processes Llama streaming chunks
-func (c *Client) processLlamaStreamChunk(chunkData []byte, ch chan<- llm.StreamEvent) error {
+func (c *Client) processLlamaStreamChunk(chunkData []byte) (*llm.MessageDelta, error) {
 	var chunk map[string]interface{}
 	if err := json.Unmarshal(chunkData, &chunk); err != nil {
-		return err
+		return nil, err
 	}
 
 	if generation, ok := chunk["generation"].(string); ok {
 		if generation != "" {
-			delta := &llm.MessageDelta{
+			return &llm.MessageDelta{
 				Content: []llm.MessageContent{llm.NewTextContent(generation)},
-			}
-			ch <- llm.NewDeltaEvent(0, delta)
+			}, nil
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
 // Model type detection helpers
@@ -923,7 +1063,7 @@ func (c *Client) convertError(err error) *llm.Error {
 		return ourErr
 	}
 
-	errMsg := err.Error()
+	errMsg := llm.RedactSecrets(err.Error())
 
 	// Check for authentication errors
 	if strings.Contains(errMsg, "UnauthorizedOperation") ||
@@ -940,12 +1080,36 @@ func (c *Client) convertError(err error) *llm.Error {
 	// Check for throttling errors
 	if strings.Contains(errMsg, "ThrottlingException") ||
 		strings.Contains(errMsg, "TooManyRequestsException") {
-		return &llm.Error{
+		llmErr := &llm.Error{
 			Code:       "rate_limit_error",
 			Message:    errMsg,
 			Type:       "rate_limit_error",
 			StatusCode: 429,
 		}
+		var respErr *smithyhttp.ResponseError
+		if errors.As(err, &respErr) && respErr.Response != nil {
+			llmErr.RateLimit = llm.ParseRateLimitHeaders(respErr.Response.Header)
+		}
+		return llmErr
+	}
+
+	// Check for unavailable regions and models
+	if strings.Contains(errMsg, "ServiceUnavailableException") ||
+		strings.Contains(errMsg, "ModelNotReadyException") {
+		return &llm.Error{
+			Code:       "service_unavailable",
+			Message:    errMsg,
+			Type:       "api_error",
+			StatusCode: 503,
+		}
+	}
+	if strings.Contains(errMsg, "InternalServerException") {
+		return &llm.Error{
+			Code:       "server_error",
+			Message:    errMsg,
+			Type:       "api_error",
+			StatusCode: 500,
+		}
 	}
 
 	// Check for model not found
@@ -1,6 +1,7 @@
 package bedrock
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/inercia/go-llm/pkg/llm"
@@ -160,3 +161,29 @@ func TestModelDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestWithGuardrail(t *testing.T) {
+	req := llm.ChatRequest{}.WithProviderOptions(WithGuardrail("gr-123", "DRAFT"))
+	identifier, version := guardrail(req)
+	if identifier == nil || *identifier != "gr-123" || version == nil || *version != "DRAFT" {
+		t.Errorf("Unexpected guardrail %v %v", identifier, version)
+	}
+
+	if identifier, version := guardrail(llm.ChatRequest{}); identifier != nil || version != nil {
+		t.Error("Expected no guardrail without the option")
+	}
+}
+
+func TestClaudeUsage(t *testing.T) {
+	var resp map[string]interface{}
+	body := `{"usage": {"input_tokens": 20, "output_tokens": 10, "cache_read_input_tokens": 300, "cache_creation_input_tokens": 100}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := claudeUsage(resp)
+	expected := llm.Usage{PromptTokens: 420, CompletionTokens: 10, TotalTokens: 430, CacheReadTokens: 300, CacheWriteTokens: 100}
+	if usage != expected {
+		t.Errorf("Expected usage %+v, got %+v", expected, usage)
+	}
+}
@@ -14,7 +14,9 @@
 //   - Streaming and non-streaming chat completions
 //   - Multi-modal support for Claude 3 models
 //   - Health checks and error standardization
-//   - Regional configuration support
+//   - Regional configuration support, with failover across Extra["regions"] on throttling
+//     or unavailability and cross-region inference profiles (us.anthropic.* model IDs)
+//   - Per-request guardrails (WithGuardrail)
 //
 // Usage:
 //
@@ -0,0 +1,31 @@
+package bedrock
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// guardrailOption holds the guardrail applied to a request
+type guardrailOption struct {
+	identifier string
+	version    string
+}
+
+func (guardrailOption) Provider() string { return "bedrock" }
+
+// WithGuardrail returns a request option that applies a Bedrock guardrail, given its ID
+// or ARN and its version (a number or "DRAFT"), to the model invocation.
+func WithGuardrail(identifier, version string) llm.ProviderOption {
+	return guardrailOption{identifier: identifier, version: version}
+}
+
+// guardrail returns the guardrail identifier and version of a request, or nil values
+// when no guardrail is set
+func guardrail(req llm.ChatRequest) (identifier, version *string) {
+	option, ok := llm.FindProviderOption[guardrailOption](req)
+	if !ok || option.identifier == "" {
+		return nil, nil
+	}
+	return aws.String(option.identifier), aws.String(option.version)
+}
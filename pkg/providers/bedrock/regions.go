@@ -0,0 +1,113 @@
+package bedrock
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// regionClient is the runtime client of one of the regions a client invokes models in
+type regionClient struct {
+	region  string
+	modelID string
+	runtime *bedrockruntime.Client
+}
+
+// inferenceProfilePrefixes are the geography prefixes of cross-region inference profile
+// IDs (e.g. "us.anthropic.claude-3-5-sonnet-20240620-v1:0")
+var inferenceProfilePrefixes = []string{"us-gov", "us", "eu", "apac", "jp", "au", "ca", "global"}
+
+// configuredRegions returns the regions of a client, in failover order: the
+// comma-separated Extra["regions"], or the single Extra["region"], or DefaultRegion
+func configuredRegions(extra map[string]string) []string {
+	var regions []string
+	for _, region := range strings.Split(extra["regions"], ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) > 0 {
+		return regions
+	}
+	if region := extra["region"]; region != "" {
+		return []string{region}
+	}
+	return []string{DefaultRegion}
+}
+
+// regionGeography returns the inference profile geography of a region, or "" when it
+// has none
+func regionGeography(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "us-gov"
+	case strings.HasPrefix(region, "us-"):
+		return "us"
+	case strings.HasPrefix(region, "eu-"):
+		return "eu"
+	case strings.HasPrefix(region, "ap-"):
+		return "apac"
+	}
+	return ""
+}
+
+// splitInferenceProfile splits a cross-region inference profile ID into its geography
+// and the base model ID. The geography is "" for base model IDs.
+func splitInferenceProfile(model string) (geography, base string) {
+	for _, prefix := range inferenceProfilePrefixes {
+		if rest, ok := strings.CutPrefix(model, prefix+"."); ok {
+			return prefix, rest
+		}
+	}
+	return "", model
+}
+
+// modelIDForRegion returns the model ID to invoke in a region. Inference profiles are
+// rewritten for the geography of the region, so "us.anthropic..." becomes
+// "eu.anthropic..." when failing over to eu-west-1, and base model IDs are turned into
+// inference profiles when crossRegion is set. Global profiles and ARNs are kept.
+func modelIDForRegion(model, region string, crossRegion bool) string {
+	if strings.HasPrefix(model, "arn:") {
+		return model
+	}
+	geography, base := splitInferenceProfile(model)
+	if geography == "global" || (geography == "" && !crossRegion) {
+		return model
+	}
+	if target := regionGeography(region); target != "" {
+		return target + "." + base
+	}
+	return model
+}
+
+// shouldFailover reports whether a failed invocation should be retried in the next
+// region: when the region throttled the request or was unavailable
+func shouldFailover(err *llm.Error) bool {
+	switch err.Code {
+	case "rate_limit_error", "service_unavailable", "server_error":
+		return true
+	}
+	return err.StatusCode >= 500
+}
+
+// invokeWithFailover calls invoke in the regions of the client in order, moving to the
+// next region when the previous one is throttled or unavailable. It returns the result
+// and the region that produced it, or the error of the last region tried.
+func invokeWithFailover[T any](ctx context.Context, c *Client, invoke func(regionClient) (T, error)) (T, regionClient, error) {
+	var zero T
+	var lastErr *llm.Error
+	for i, rc := range c.regions {
+		result, err := invoke(rc)
+		if err == nil {
+			return result, rc, nil
+		}
+		lastErr = c.convertError(err)
+		if i == len(c.regions)-1 || ctx.Err() != nil || !shouldFailover(lastErr) {
+			break
+		}
+	}
+	return zero, regionClient{}, lastErr
+}
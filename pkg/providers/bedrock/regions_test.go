@@ -0,0 +1,114 @@
+package bedrock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestConfiguredRegions(t *testing.T) {
+	if got := configuredRegions(nil); len(got) != 1 || got[0] != DefaultRegion {
+		t.Errorf("Expected the default region, got %v", got)
+	}
+	if got := configuredRegions(map[string]string{"region": "eu-west-1"}); len(got) != 1 || got[0] != "eu-west-1" {
+		t.Errorf("Expected the single region, got %v", got)
+	}
+	got := configuredRegions(map[string]string{"region": "eu-west-1", "regions": "us-east-1, us-west-2,"})
+	if len(got) != 2 || got[0] != "us-east-1" || got[1] != "us-west-2" {
+		t.Errorf("Expected the failover regions, got %v", got)
+	}
+}
+
+func TestModelIDForRegion(t *testing.T) {
+	const base = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+	tests := []struct {
+		model       string
+		region      string
+		crossRegion bool
+		want        string
+	}{
+		{base, "us-east-1", false, base},
+		{base, "us-east-1", true, "us." + base},
+		{base, "ap-northeast-1", true, "apac." + base},
+		{"us." + base, "eu-west-1", false, "eu." + base},
+		{"us." + base, "us-gov-west-1", false, "us-gov." + base},
+		{"us." + base, "me-central-1", false, "us." + base},
+		{"global." + base, "eu-west-1", false, "global." + base},
+		{"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us." + base, "eu-west-1", true,
+			"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us." + base},
+	}
+	for _, tt := range tests {
+		if got := modelIDForRegion(tt.model, tt.region, tt.crossRegion); got != tt.want {
+			t.Errorf("modelIDForRegion(%q, %q, %v) = %q, want %q", tt.model, tt.region, tt.crossRegion, got, tt.want)
+		}
+	}
+}
+
+func TestRegionFailover(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(r.URL.Path, "us.anthropic"):
+			w.Header().Set("X-Amzn-ErrorType", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"Too many requests"}`))
+		case strings.Contains(r.URL.Path, "eu.anthropic"):
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Hello from eu"}],"stop_reason":"end_turn"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{
+		Model:   "us.anthropic.claude-3-5-sonnet-20240620-v1:0",
+		BaseURL: server.URL,
+		Extra: map[string]string{
+			"regions":           "us-east-1,eu-west-1",
+			"aws_bedrock_token": "test-token",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")}}
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the request to fail over, got %v", err)
+	}
+	if got := resp.Choices[0].Message.GetText(); got != "Hello from eu" {
+		t.Errorf("Unexpected response text %q", got)
+	}
+	if region, _ := resp.GetMetadata("bedrock_region"); region != "eu-west-1" {
+		t.Errorf("Expected the region in the metadata, got %v", region)
+	}
+	if modelID, _ := resp.GetMetadata("bedrock_model_id"); modelID != "eu.anthropic.claude-3-5-sonnet-20240620-v1:0" {
+		t.Errorf("Expected the regional profile in the metadata, got %v", modelID)
+	}
+	if len(paths) != 2 {
+		t.Errorf("Expected one attempt per region without retries, got %v", paths)
+	}
+
+	// Errors that are not throttling or unavailability are not failed over
+	paths = nil
+	client.regions[1].modelID = "anthropic.other-model"
+	client.regions[0].modelID = "anthropic.invalid-model"
+	if _, err := client.ChatCompletion(context.Background(), req); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(paths) != 1 {
+		t.Errorf("Expected no failover for a bad request, got %v", paths)
+	}
+}
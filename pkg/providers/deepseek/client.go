@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cohesion-org/deepseek-go"
@@ -11,6 +13,22 @@ import (
 	"github.com/inercia/go-llm/pkg/llm"
 )
 
+// MultimodalFallback defines how image and file content is handled when the model
+// cannot accept it natively
+type MultimodalFallback string
+
+const (
+	// FallbackError rejects requests containing unsupported content (default)
+	FallbackError MultimodalFallback = "error"
+	// FallbackDescribe replaces unsupported content with a text description, inlining text files
+	FallbackDescribe MultimodalFallback = "describe"
+	// FallbackDrop silently removes unsupported content
+	FallbackDrop MultimodalFallback = "drop"
+)
+
+// visionModelMarkers identifies DeepSeek models that accept images natively
+var visionModelMarkers = []string{"deepseek-vl", "janus"}
+
 // Client implements the llm.Client interface for DeepSeek
 type Client struct {
 	client   *deepseek.Client
@@ -18,13 +36,22 @@ type Client struct {
 	provider string
 	config   llm.ClientConfig
 
+	// Multimodal handling
+	supportsVision bool
+	fallback       MultimodalFallback
+
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new DeepSeek client
 func NewClient(config llm.ClientConfig) (*Client, error) {
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
+
 	if config.APIKey == "" {
 		return nil, &llm.Error{
 			Code:    "missing_api_key",
@@ -42,6 +69,27 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		}
 	}
 
+	fallback := FallbackError
+	supportsVision := isVisionModel(config.Model)
+	if config.Extra != nil {
+		if mode, ok := config.Extra["multimodal_fallback"]; ok && mode != "" {
+			fallback = MultimodalFallback(strings.ToLower(mode))
+			switch fallback {
+			case FallbackError, FallbackDescribe, FallbackDrop:
+			default:
+				return nil, &llm.Error{
+					Code:    "invalid_config",
+					Message: fmt.Sprintf("invalid multimodal_fallback %q: must be error, describe or drop", mode),
+					Type:    "validation_error",
+				}
+			}
+		}
+		// Allows enabling native images for models not known to support them yet
+		if vision, ok := config.Extra["supports_vision"]; ok {
+			supportsVision = vision == "true"
+		}
+	}
+
 	// Prepare client options
 	var opts []deepseek.Option
 
@@ -80,16 +128,80 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		client = deepseek.NewClient(config.APIKey)
 	}
 
+	// Record rate-limit headers so they can be attached to errors, and merge the ExtraBody
+	// fields of requests into their payload
+	client.HTTPClient = &http.Client{Transport: &llm.RateLimitTransport{Base: &llm.ExtraBodyTransport{Base: &llm.DryRunTransport{}}}}
+
 	return &Client{
-		client:   client,
-		model:    config.Model,
-		provider: "deepseek",
-		config:   config,
+		stats:          llm.NewHealthStatsWithClock(config.Clock),
+		clock:          config.GetClock(),
+		client:         client,
+		model:          config.Model,
+		provider:       "deepseek",
+		config:         config,
+		supportsVision: supportsVision,
+		fallback:       fallback,
 	}, nil
 }
 
+// isVisionModel checks if the model is known to accept images natively
+func isVisionModel(model string) bool {
+	model = strings.ToLower(model)
+	for _, marker := range visionModelMarkers {
+		if strings.Contains(model, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // ChatCompletion performs a chat completion request
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	resp, err := c.chatCompletion(ctx, req)
+	err = llm.AttachRateLimit(err, rateLimit.Info())
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	// DeepSeek supports JSON mode natively, but not JSON schemas. Files are flattened
+	// into the text of their message.
+	nativeFormat := req.ResponseFormat != nil && req.ResponseFormat.Type == llm.ResponseFormatJSON
+	warnings := append(req.ResponseFormatWarnings(nativeFormat),
+		llm.ContentWarnings(req.Messages, llm.MessageTypeFile, llm.WarningFileSentAsText, "was sent as text")...)
+	req, err = req.PrepareResponseFormat(nativeFormat)
+	if err != nil {
+		return nil, err
+	}
+	req, ignored := c.dropIgnoredParams(req)
+	warnings = append(warnings, ignored...)
+
+	if c.hasNativeImages(req) {
+		imageReq, err := c.convertImageRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.CreateChatCompletionWithImage(ctx, &imageReq)
+		if err != nil {
+			return nil, c.convertError(err)
+		}
+		response := c.convertResponse(*resp)
+		response.Warnings = warnings
+		response.Raw = llm.NewRawResponse(resp, nil)
+		return response, nil
+	}
+
 	// Convert our request to DeepSeek format
 	deepseekReq, err := c.convertRequest(req)
 	if err != nil {
@@ -103,50 +215,96 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Convert response back to our format
-	return c.convertResponse(*resp), nil
+	response := c.convertResponse(*resp)
+	response.Warnings = warnings
+	response.Raw = llm.NewRawResponse(resp, nil)
+	return response, nil
 }
 
 // StreamChatCompletion performs a streaming chat completion request
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
-	// Convert our request to DeepSeek streaming format
-	deepseekReq, err := c.convertStreamRequest(req)
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	stream, err := c.streamChatCompletion(ctx, req)
 	if err != nil {
+		err = llm.AttachRateLimit(err, rateLimit.Info())
+		c.stats.Track(ctx, start, err)
 		return nil, err
 	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
 
-	// Create the streaming request
-	stream, err := c.client.CreateChatCompletionStream(ctx, &deepseekReq)
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
 	if err != nil {
-		return nil, c.convertError(err)
+		return nil, err
+	}
+
+	// deepseek-go does not support response formats in streaming requests
+	req, err = req.PrepareResponseFormat(false)
+	if err != nil {
+		return nil, err
+	}
+	req, _ = c.dropIgnoredParams(req)
+
+	var stream deepseek.ChatCompletionStream
+	if c.hasNativeImages(req) {
+		imageReq, err := c.convertImageStreamRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		stream, err = c.client.CreateChatCompletionStreamWithImage(ctx, &imageReq)
+		if err != nil {
+			return nil, c.convertError(err)
+		}
+	} else {
+		// Convert our request to DeepSeek streaming format
+		deepseekReq, err := c.convertStreamRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create the streaming request
+		stream, err = c.client.CreateChatCompletionStream(ctx, &deepseekReq)
+		if err != nil {
+			return nil, c.convertError(err)
+		}
 	}
 
-	ch := make(chan llm.StreamEvent, 10)
+	emitter := llm.NewStreamEmitter(ctx, c.config.StreamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		defer func() { _ = stream.Close() }()
 
 		for {
 			response, err := stream.Recv()
 			if err == io.EOF {
 				// Stream complete
-				ch <- llm.NewDoneEvent(0, "stop")
+				emitter.Emit(seq.Stamp(llm.NewDoneEvent(0, "stop")))
 				return
 			}
 			if err != nil {
-				ch <- llm.NewErrorEvent(c.convertError(err))
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
 				return
 			}
 
 			// Convert chunk to stream event
 			event := c.convertStreamEvent(response)
 			if event != nil {
-				ch <- *event
+				if !emitter.Emit(seq.Stamp(*event)) {
+					return
+				}
 			}
 		}
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
 }
 
 // GetRemote returns information about the remote client
@@ -156,26 +314,23 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on the DeepSeek API
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -192,7 +347,10 @@ func (c *Client) performHealthCheck() bool {
 	}
 
 	_, err := c.client.CreateChatCompletion(ctx, &req)
-	return err == nil
+	if err != nil {
+		return c.convertError(err)
+	}
+	return nil
 }
 
 // GetModelInfo returns information about the model
@@ -201,12 +359,22 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	return llm.ModelInfo{
 		Name:              c.model,
 		Provider:          c.provider,
-		MaxTokens:         32768, // DeepSeek models typically support 32K context
-		SupportsTools:     true,  // DeepSeek supports function calling
-		SupportsVision:    false, // Most DeepSeek models are text-only
-		SupportsFiles:     false, // Basic file support
-		SupportsStreaming: true,  // DeepSeek supports streaming
+		MaxTokens:         32768,            // DeepSeek models typically support 32K context
+		SupportsTools:     true,             // DeepSeek supports function calling
+		SupportsVision:    c.supportsVision, // Only VL models accept images natively
+		SupportsFiles:     false,            // Basic file support
+		SupportsStreaming: true,             // DeepSeek supports streaming
+	}
+}
+
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
 	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
 }
 
 // Close cleans up resources
@@ -221,6 +389,37 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// isReasoningModel reports whether a model is a reasoning model (DeepSeek-R1)
+func isReasoningModel(model string) bool {
+	model = strings.ToLower(model)
+	return model == deepseek.DeepSeekReasoner || strings.Contains(model, "-r1")
+}
+
+// dropIgnoredParams removes the sampling parameters that reasoning models accept but
+// ignore, returning a warning for each of them instead of silently accepting them
+func (c *Client) dropIgnoredParams(req llm.ChatRequest) (llm.ChatRequest, []llm.Warning) {
+	if !isReasoningModel(c.model) {
+		return req, nil
+	}
+	var warnings []llm.Warning
+	ignored := func(param string) llm.Warning {
+		return llm.Warning{
+			Code:    llm.WarningParameterIgnored,
+			Message: fmt.Sprintf("%s is not supported by the reasoning model %s and was not sent", param, c.model),
+			Param:   param,
+		}
+	}
+	if req.Temperature != nil {
+		req.Temperature = nil
+		warnings = append(warnings, ignored("temperature"))
+	}
+	if req.TopP != nil {
+		req.TopP = nil
+		warnings = append(warnings, ignored("top_p"))
+	}
+	return req, warnings
+}
+
 // convertRequest converts our llm.ChatRequest to DeepSeek format
 func (c *Client) convertRequest(req llm.ChatRequest) (deepseek.ChatCompletionRequest, error) {
 	messages := make([]deepseek.ChatCompletionMessage, len(req.Messages))
@@ -240,42 +439,16 @@ func (c *Client) convertRequest(req llm.ChatRequest) (deepseek.ChatCompletionReq
 		messages[i] = convertedMsg
 	}
 
-	// Convert tools if present
-	var tools []deepseek.Tool
-	if len(req.Tools) > 0 {
-		// Check if model supports tools
-		modelInfo := c.GetModelInfo()
-		if !modelInfo.SupportsTools {
-			return deepseek.ChatCompletionRequest{}, &llm.Error{
-				Code:    "tools_not_supported",
-				Message: "Model " + c.model + " does not support tools",
-				Type:    "validation_error",
-			}
-		}
-
-		tools = make([]deepseek.Tool, len(req.Tools))
-		for i, tool := range req.Tools {
-			// Convert parameters to FunctionParameters
-			var params *deepseek.FunctionParameters
-			if tool.Function.Parameters != nil {
-				params = c.convertToolParameters(tool.Function.Parameters)
-			}
-
-			tools[i] = deepseek.Tool{
-				Type: tool.Type,
-				Function: deepseek.Function{
-					Name:        tool.Function.Name,
-					Description: tool.Function.Description,
-					Parameters:  params,
-				},
-			}
-		}
+	tools, err := c.convertTools(req.Tools)
+	if err != nil {
+		return deepseek.ChatCompletionRequest{}, err
 	}
 
 	deepseekReq := deepseek.ChatCompletionRequest{
-		Model:    c.model,
-		Messages: messages,
-		Tools:    tools,
+		Model:          c.model,
+		Messages:       messages,
+		Tools:          tools,
+		ResponseFormat: convertResponseFormat(req.ResponseFormat),
 	}
 
 	// Set optional parameters
@@ -311,39 +484,78 @@ func (c *Client) convertStreamRequest(req llm.ChatRequest) (deepseek.StreamChatC
 		messages[i] = convertedMsg
 	}
 
-	// Convert tools if present
-	var tools []deepseek.Tool
-	if len(req.Tools) > 0 {
-		// Check if model supports tools
-		modelInfo := c.GetModelInfo()
-		if !modelInfo.SupportsTools {
-			return deepseek.StreamChatCompletionRequest{}, &llm.Error{
-				Code:    "tools_not_supported",
-				Message: "Model " + c.model + " does not support tools",
-				Type:    "validation_error",
-			}
-		}
+	tools, err := c.convertTools(req.Tools)
+	if err != nil {
+		return deepseek.StreamChatCompletionRequest{}, err
+	}
 
-		tools = make([]deepseek.Tool, len(req.Tools))
-		for i, tool := range req.Tools {
-			// Convert parameters to FunctionParameters
-			var params *deepseek.FunctionParameters
-			if tool.Function.Parameters != nil {
-				params = c.convertToolParameters(tool.Function.Parameters)
-			}
+	deepseekReq := deepseek.StreamChatCompletionRequest{
+		Model:    c.model,
+		Messages: messages,
+		Tools:    tools,
+		Stream:   true, // Always true for streaming requests
+	}
 
-			tools[i] = deepseek.Tool{
-				Type: tool.Type,
-				Function: deepseek.Function{
-					Name:        tool.Function.Name,
-					Description: tool.Function.Description,
-					Parameters:  params,
-				},
-			}
-		}
+	// Set optional parameters
+	if req.Temperature != nil {
+		deepseekReq.Temperature = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		deepseekReq.MaxTokens = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		deepseekReq.TopP = *req.TopP
 	}
 
-	deepseekReq := deepseek.StreamChatCompletionRequest{
+	return deepseekReq, nil
+}
+
+// convertImageRequest converts our llm.ChatRequest to the DeepSeek format with native image parts
+func (c *Client) convertImageRequest(req llm.ChatRequest) (deepseek.ChatCompletionRequestWithImage, error) {
+	messages, err := c.convertMessagesWithImages(req.Messages)
+	if err != nil {
+		return deepseek.ChatCompletionRequestWithImage{}, err
+	}
+
+	tools, err := c.convertTools(req.Tools)
+	if err != nil {
+		return deepseek.ChatCompletionRequestWithImage{}, err
+	}
+
+	deepseekReq := deepseek.ChatCompletionRequestWithImage{
+		Model:          c.model,
+		Messages:       messages,
+		Tools:          tools,
+		ResponseFormat: convertResponseFormat(req.ResponseFormat),
+	}
+
+	// Set optional parameters
+	if req.Temperature != nil {
+		deepseekReq.Temperature = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		deepseekReq.MaxTokens = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		deepseekReq.TopP = *req.TopP
+	}
+
+	return deepseekReq, nil
+}
+
+// convertImageStreamRequest converts our llm.ChatRequest to the DeepSeek streaming format with native image parts
+func (c *Client) convertImageStreamRequest(req llm.ChatRequest) (deepseek.StreamChatCompletionRequestWithImage, error) {
+	messages, err := c.convertMessagesWithImages(req.Messages)
+	if err != nil {
+		return deepseek.StreamChatCompletionRequestWithImage{}, err
+	}
+
+	tools, err := c.convertTools(req.Tools)
+	if err != nil {
+		return deepseek.StreamChatCompletionRequestWithImage{}, err
+	}
+
+	deepseekReq := deepseek.StreamChatCompletionRequestWithImage{
 		Model:    c.model,
 		Messages: messages,
 		Tools:    tools,
@@ -364,6 +576,129 @@ func (c *Client) convertStreamRequest(req llm.ChatRequest) (deepseek.StreamChatC
 	return deepseekReq, nil
 }
 
+// convertResponseFormat enables DeepSeek's JSON mode, its only native format
+func convertResponseFormat(format *llm.ResponseFormat) *deepseek.ResponseFormat {
+	compatible := format.Compatible()
+	if compatible == nil || compatible.Type != string(llm.ResponseFormatJSON) {
+		return nil
+	}
+	return &deepseek.ResponseFormat{Type: compatible.Type}
+}
+
+// convertTools converts our tools to DeepSeek format
+func (c *Client) convertTools(reqTools []llm.Tool) ([]deepseek.Tool, error) {
+	if len(reqTools) == 0 {
+		return nil, nil
+	}
+
+	// Check if model supports tools
+	if !c.GetModelInfo().SupportsTools {
+		return nil, &llm.Error{
+			Code:    "tools_not_supported",
+			Message: "Model " + c.model + " does not support tools",
+			Type:    "validation_error",
+		}
+	}
+
+	tools := make([]deepseek.Tool, len(reqTools))
+	for i, tool := range reqTools {
+		// Convert parameters to FunctionParameters
+		var params *deepseek.FunctionParameters
+		if tool.Function.Parameters != nil {
+			params = c.convertToolParameters(tool.Function.Parameters)
+		}
+
+		tools[i] = deepseek.Tool{
+			Type: tool.Type,
+			Function: deepseek.Function{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  params,
+			},
+		}
+	}
+	return tools, nil
+}
+
+// hasNativeImages checks if the request must be sent with native image parts
+func (c *Client) hasNativeImages(req llm.ChatRequest) bool {
+	if !c.supportsVision {
+		return false
+	}
+	for _, msg := range req.Messages {
+		for _, content := range msg.Content {
+			if content.Type() == llm.MessageTypeImage {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convertMessagesWithImages converts our messages to DeepSeek format, keeping images as native parts.
+// Files are still subject to the configured multimodal fallback.
+func (c *Client) convertMessagesWithImages(msgs []llm.Message) ([]deepseek.ChatCompletionMessageWithImage, error) {
+	messages := make([]deepseek.ChatCompletionMessageWithImage, len(msgs))
+	for i, msg := range msgs {
+		if err := c.validateMessageSize(msg.TotalSize()); err != nil {
+			return nil, err
+		}
+
+		var items []deepseek.ContentItem
+		var texts []string
+		hasImages := false
+		for _, content := range msg.Content {
+			var text string
+			switch content.Type() {
+			case llm.MessageTypeText:
+				if textContent, ok := content.(*llm.TextContent); ok {
+					text = textContent.GetText()
+				}
+			case llm.MessageTypeImage:
+				img := content.(*llm.ImageContent)
+				if err := c.validateImageContent(img); err != nil {
+					return nil, err
+				}
+				items = append(items, deepseek.ContentItem{
+					Type:  "image_url",
+					Image: &deepseek.ImageContent{URL: imageURL(img)},
+				})
+				hasImages = true
+				continue
+			case llm.MessageTypeFile:
+				flattened, err := c.flattenContent(content)
+				if err != nil {
+					return nil, err
+				}
+				text = flattened
+			}
+			if text != "" {
+				items = append(items, deepseek.ContentItem{Type: "text", Text: text})
+				texts = append(texts, text)
+			}
+		}
+
+		messages[i] = deepseek.ChatCompletionMessageWithImage{
+			Role:       c.convertRoleToDeepSeek(msg.Role),
+			Content:    strings.Join(texts, "\n\n"),
+			ToolCalls:  c.convertToolCallsToDeepSeek(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		}
+		if hasImages {
+			messages[i].Content = items
+		}
+	}
+	return messages, nil
+}
+
+// imageURL returns the URL of an image, encoding inline data as a data URL
+func imageURL(img *llm.ImageContent) string {
+	if img.HasURL() {
+		return img.URL
+	}
+	return llm.EncodeDataURL(img.MimeType, img.Data)
+}
+
 // convertMessage converts our Message to DeepSeek format
 func (c *Client) convertMessage(msg llm.Message) (deepseek.ChatCompletionMessage, error) {
 	deepseekMsg := deepseek.ChatCompletionMessage{
@@ -376,9 +711,6 @@ func (c *Client) convertMessage(msg llm.Message) (deepseek.ChatCompletionMessage
 	if len(msg.Content) == 0 {
 		deepseekMsg.Content = ""
 	} else {
-		// Get model capabilities for validation
-		modelInfo := c.GetModelInfo()
-
 		// Validate total message size
 		totalSize := msg.TotalSize()
 		if err := c.validateMessageSize(totalSize); err != nil {
@@ -394,29 +726,15 @@ func (c *Client) convertMessage(msg llm.Message) (deepseek.ChatCompletionMessage
 				if textContent, ok := content.(*llm.TextContent); ok {
 					contentBuilder = append(contentBuilder, textContent.GetText())
 				}
-			case llm.MessageTypeImage:
-				// Validate image content and model capabilities
-				if err := c.validateImageContent(content.(*llm.ImageContent), modelInfo); err != nil {
-					return deepseek.ChatCompletionMessage{}, err
-				}
-
-				convertedContent, err := c.convertImageContent(content.(*llm.ImageContent), modelInfo)
+			case llm.MessageTypeImage, llm.MessageTypeFile:
+				// Images and files are flattened to text according to the multimodal fallback
+				convertedContent, err := c.flattenContent(content)
 				if err != nil {
 					return deepseek.ChatCompletionMessage{}, err
 				}
-				contentBuilder = append(contentBuilder, convertedContent)
-
-			case llm.MessageTypeFile:
-				// Validate file content and model capabilities
-				if err := c.validateFileContent(content.(*llm.FileContent), modelInfo); err != nil {
-					return deepseek.ChatCompletionMessage{}, err
+				if convertedContent != "" {
+					contentBuilder = append(contentBuilder, convertedContent)
 				}
-
-				convertedContent, err := c.convertFileContent(content.(*llm.FileContent), modelInfo)
-				if err != nil {
-					return deepseek.ChatCompletionMessage{}, err
-				}
-				contentBuilder = append(contentBuilder, convertedContent)
 			}
 		}
 
@@ -484,8 +802,10 @@ func (c *Client) convertResponse(resp deepseek.ChatCompletionResponse) *llm.Chat
 				Role:      c.convertRoleFromDeepSeek(choice.Message.Role),
 				Content:   []llm.MessageContent{llm.NewTextContent(choice.Message.Content)},
 				ToolCalls: c.convertToolCallsFromDeepSeek(choice.Message.ToolCalls),
+				Reasoning: choice.Message.ReasoningContent,
 			},
-			FinishReason: choice.FinishReason,
+			FinishReason:    llm.NormalizeFinishReason(c.provider, choice.FinishReason),
+			RawFinishReason: choice.FinishReason,
 		}
 	}
 
@@ -497,6 +817,7 @@ func (c *Client) convertResponse(resp deepseek.ChatCompletionResponse) *llm.Chat
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
 			TotalTokens:      resp.Usage.TotalTokens,
+			CacheReadTokens:  resp.Usage.PromptCacheHitTokens,
 		},
 	}
 }
@@ -547,7 +868,7 @@ func (c *Client) convertStreamEvent(resp *deepseek.StreamChatCompletionResponse)
 
 	// Handle finish reason - if present, this is a done event
 	if choice.FinishReason != "" {
-		event := llm.NewDoneEvent(choice.Index, choice.FinishReason)
+		event := llm.NewProviderDoneEvent(choice.Index, c.provider, choice.FinishReason)
 		return &event
 	}
 
@@ -555,6 +876,12 @@ func (c *Client) convertStreamEvent(resp *deepseek.StreamChatCompletionResponse)
 	delta := &llm.MessageDelta{}
 	hasContent := false
 
+	// Handle reasoning delta (deepseek-reasoner streams its reasoning before the answer)
+	if choice.Delta.ReasoningContent != "" {
+		delta.Reasoning = choice.Delta.ReasoningContent
+		hasContent = true
+	}
+
 	// Handle text content delta
 	if choice.Delta.Content != "" {
 		delta.Content = []llm.MessageContent{llm.NewTextContent(choice.Delta.Content)}
@@ -596,7 +923,7 @@ func (c *Client) convertError(err error) *llm.Error {
 
 	// Try to extract structured error information
 	// This is a generic implementation - adjust based on actual deepseek-go error types
-	errorMsg := err.Error()
+	errorMsg := llm.RedactSecrets(err.Error())
 
 	// Default error mapping
 	code := "api_error"
@@ -749,21 +1076,48 @@ func (c *Client) validateMessageSize(totalSize int64) error {
 	return nil
 }
 
-// validateImageContent validates image content for DeepSeek compatibility
-func (c *Client) validateImageContent(img *llm.ImageContent, modelInfo llm.ModelInfo) error {
-	if img == nil {
-		return &llm.Error{
-			Code:    "invalid_content",
-			Message: "Image content cannot be nil",
+// flattenContent applies the multimodal fallback to image or file content that cannot be
+// sent natively, returning an empty string when the content must be dropped
+func (c *Client) flattenContent(content llm.MessageContent) (string, error) {
+	switch c.fallback {
+	case FallbackDrop:
+		return "", nil
+	case FallbackDescribe:
+		switch v := content.(type) {
+		case *llm.ImageContent:
+			if err := c.validateImageContent(v); err != nil {
+				return "", err
+			}
+			return c.convertImageContent(v)
+		case *llm.FileContent:
+			if err := c.validateFileContent(v); err != nil {
+				return "", err
+			}
+			return c.convertFileContent(v)
+		}
+		return "", nil
+	}
+
+	if content.Type() == llm.MessageTypeImage {
+		return "", &llm.Error{
+			Code:    "vision_not_supported",
+			Message: fmt.Sprintf("Model %s does not support vision/image content (set multimodal_fallback to describe or drop)", c.model),
 			Type:    "validation_error",
 		}
 	}
+	return "", &llm.Error{
+		Code:    "files_not_supported",
+		Message: fmt.Sprintf("Model %s does not support file content (set multimodal_fallback to describe or drop)", c.model),
+		Type:    "validation_error",
+	}
+}
 
-	// Check if model supports vision
-	if !modelInfo.SupportsVision {
+// validateImageContent validates image content for DeepSeek compatibility
+func (c *Client) validateImageContent(img *llm.ImageContent) error {
+	if img == nil {
 		return &llm.Error{
-			Code:    "vision_not_supported",
-			Message: fmt.Sprintf("Model %s does not support vision/image content", c.model),
+			Code:    "invalid_content",
+			Message: "Image content cannot be nil",
 			Type:    "validation_error",
 		}
 	}
@@ -800,7 +1154,7 @@ func (c *Client) validateImageContent(img *llm.ImageContent, modelInfo llm.Model
 }
 
 // validateFileContent validates file content for DeepSeek compatibility
-func (c *Client) validateFileContent(file *llm.FileContent, modelInfo llm.ModelInfo) error {
+func (c *Client) validateFileContent(file *llm.FileContent) error {
 	if file == nil {
 		return &llm.Error{
 			Code:    "invalid_content",
@@ -809,15 +1163,6 @@ func (c *Client) validateFileContent(file *llm.FileContent, modelInfo llm.ModelI
 		}
 	}
 
-	// Check if model supports files
-	if !modelInfo.SupportsFiles {
-		return &llm.Error{
-			Code:    "files_not_supported",
-			Message: fmt.Sprintf("Model %s does not support file content", c.model),
-			Type:    "validation_error",
-		}
-	}
-
 	// Validate file size (10MB limit)
 	maxFileSize := int64(10 * 1024 * 1024)
 	if file.Size() > maxFileSize {
@@ -849,16 +1194,12 @@ func (c *Client) validateFileContent(file *llm.FileContent, modelInfo llm.ModelI
 	return nil
 }
 
-// convertImageContent converts ImageContent to DeepSeek format
-func (c *Client) convertImageContent(img *llm.ImageContent, modelInfo llm.ModelInfo) (string, error) {
+// convertImageContent converts ImageContent to a text description for models without vision
+func (c *Client) convertImageContent(img *llm.ImageContent) (string, error) {
 	if img == nil {
 		return "", fmt.Errorf("image content is nil")
 	}
 
-	// Since DeepSeek may not support native multi-modal content in all models,
-	// we convert images to text descriptions for now
-	// This can be enhanced when DeepSeek adds full multi-modal support
-
 	if img.HasURL() {
 		return fmt.Sprintf("[Image: %s, Type: %s]", img.URL, img.MimeType), nil
 	} else if img.HasData() {
@@ -876,7 +1217,7 @@ func (c *Client) convertImageContent(img *llm.ImageContent, modelInfo llm.ModelI
 }
 
 // convertFileContent converts FileContent to DeepSeek format
-func (c *Client) convertFileContent(file *llm.FileContent, modelInfo llm.ModelInfo) (string, error) {
+func (c *Client) convertFileContent(file *llm.FileContent) (string, error) {
 	if file == nil {
 		return "", fmt.Errorf("file content is nil")
 	}
@@ -0,0 +1,181 @@
+package deepseek
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cohesion-org/deepseek-go"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func newTestClient(t *testing.T, model string, extra map[string]string) *Client {
+	t.Helper()
+	client, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: model, Extra: extra})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func newImageMessage() llm.Message {
+	return llm.Message{
+		Role: llm.RoleUser,
+		Content: []llm.MessageContent{
+			llm.NewTextContent("What is in this picture?"),
+			llm.NewImageContentFromURL("https://example.com/cat.png", "image/png"),
+		},
+	}
+}
+
+func TestConvertMessage_MultimodalFallback(t *testing.T) {
+	t.Run("error by default", func(t *testing.T) {
+		client := newTestClient(t, "deepseek-chat", nil)
+
+		_, err := client.convertMessage(newImageMessage())
+		var llmErr *llm.Error
+		if !errors.As(err, &llmErr) || llmErr.Code != "vision_not_supported" {
+			t.Errorf("Expected vision_not_supported, got %v", err)
+		}
+	})
+
+	t.Run("describe", func(t *testing.T) {
+		client := newTestClient(t, "deepseek-chat", map[string]string{"multimodal_fallback": "describe"})
+
+		msg, err := client.convertMessage(newImageMessage())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(msg.Content, "[Image: https://example.com/cat.png") {
+			t.Errorf("Expected image description, got %q", msg.Content)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		client := newTestClient(t, "deepseek-chat", map[string]string{"multimodal_fallback": "drop"})
+
+		msg, err := client.convertMessage(newImageMessage())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if msg.Content != "What is in this picture?" {
+			t.Errorf("Expected image to be dropped, got %q", msg.Content)
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: "deepseek-chat", Extra: map[string]string{"multimodal_fallback": "ignore"}})
+		if err == nil {
+			t.Error("Expected error for invalid fallback mode")
+		}
+	})
+}
+
+func TestConvertImageRequest_NativeImages(t *testing.T) {
+	client := newTestClient(t, "deepseek-vl2", nil)
+	if !client.GetModelInfo().SupportsVision {
+		t.Fatal("Expected VL model to support vision")
+	}
+
+	req := llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "You are helpful"),
+			{
+				Role: llm.RoleUser,
+				Content: []llm.MessageContent{
+					llm.NewTextContent("Compare these"),
+					llm.NewImageContentFromURL("https://example.com/a.png", "image/png"),
+					llm.NewImageContentFromBytes([]byte{0x89, 0x50, 0x4e, 0x47}, "image/png"),
+				},
+			},
+		},
+	}
+	if !client.hasNativeImages(req) {
+		t.Fatal("Expected request to use native images")
+	}
+
+	imageReq, err := client.convertImageRequest(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if content, ok := imageReq.Messages[0].Content.(string); !ok || content != "You are helpful" {
+		t.Errorf("Expected text-only message to stay a string, got %#v", imageReq.Messages[0].Content)
+	}
+
+	items, ok := imageReq.Messages[1].Content.([]deepseek.ContentItem)
+	if !ok || len(items) != 3 {
+		t.Fatalf("Expected 3 content items, got %#v", imageReq.Messages[1].Content)
+	}
+	if items[1].Type != "image_url" || items[1].Image.URL != "https://example.com/a.png" {
+		t.Errorf("Unexpected URL image item: %#v", items[1])
+	}
+	if url, _ := items[2].Image.URL.(string); !strings.HasPrefix(url, "data:image/png;base64,") {
+		t.Errorf("Expected data URL for inline image, got %v", items[2].Image.URL)
+	}
+
+	// Vision can be disabled explicitly, falling back to text
+	client = newTestClient(t, "deepseek-vl2", map[string]string{"supports_vision": "false"})
+	if client.hasNativeImages(req) {
+		t.Error("Expected native images to be disabled")
+	}
+}
+
+func TestReasoningModelIgnoredParams(t *testing.T) {
+	temperature := float32(0.7)
+	topP := float32(0.9)
+	req := llm.ChatRequest{
+		Messages:    []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+		Temperature: &temperature,
+		TopP:        &topP,
+	}
+
+	client := newTestClient(t, deepseek.DeepSeekReasoner, nil)
+	cleaned, warnings := client.dropIgnoredParams(req)
+	if cleaned.Temperature != nil || cleaned.TopP != nil {
+		t.Errorf("Expected sampling parameters to be dropped, got %v and %v", cleaned.Temperature, cleaned.TopP)
+	}
+	if req.Temperature == nil {
+		t.Error("Expected the original request to be left unchanged")
+	}
+	if len(warnings) != 2 || warnings[0].Param != "temperature" || warnings[1].Param != "top_p" {
+		t.Fatalf("Unexpected warnings: %#v", warnings)
+	}
+	if warnings[0].Code != "parameter_ignored" {
+		t.Errorf("Expected parameter_ignored code, got %q", warnings[0].Code)
+	}
+
+	client = newTestClient(t, deepseek.DeepSeekChat, nil)
+	if cleaned, warnings := client.dropIgnoredParams(req); len(warnings) != 0 || cleaned.Temperature == nil {
+		t.Errorf("Expected chat model to keep its parameters, got warnings %#v", warnings)
+	}
+}
+
+func TestReasoningContent(t *testing.T) {
+	client := newTestClient(t, deepseek.DeepSeekReasoner, nil)
+
+	resp := client.convertResponse(deepseek.ChatCompletionResponse{
+		Model: deepseek.DeepSeekReasoner,
+		Choices: []deepseek.Choice{{
+			Message:      deepseek.Message{Role: "assistant", Content: "42", ReasoningContent: "6 times 7"},
+			FinishReason: "stop",
+		}},
+	})
+	if got := resp.Choices[0].Message.Reasoning; got != "6 times 7" {
+		t.Errorf("Expected reasoning in the response, got %q", got)
+	}
+	if got := resp.Choices[0].Message.GetText(); got != "42" {
+		t.Errorf("Expected the answer to exclude the reasoning, got %q", got)
+	}
+
+	event := client.convertStreamEvent(&deepseek.StreamChatCompletionResponse{
+		Choices: []deepseek.StreamChoices{{Delta: deepseek.StreamDelta{ReasoningContent: "6 times"}}},
+	})
+	if event == nil || !event.IsDelta() {
+		t.Fatalf("Expected a delta event for streamed reasoning, got %#v", event)
+	}
+	if event.Choice.Delta.Reasoning != "6 times" || len(event.Choice.Delta.Content) != 0 {
+		t.Errorf("Unexpected reasoning delta: %#v", event.Choice.Delta)
+	}
+}
@@ -9,7 +9,14 @@
 //   - Tool calling and function execution support
 //   - Comprehensive error handling and validation
 //   - Configurable timeouts and model selection
-//   - Image and file content handling (converted to text descriptions)
+//   - Native image parts for vision models (deepseek-vl*, janus*), detected from the
+//     model name or forced with Extra["supports_vision"]
+//   - Configurable fallback for content the model cannot accept natively, set with
+//     Extra["multimodal_fallback"]: "error" (default), "describe" (text descriptions,
+//     text files inlined) or "drop"
+//   - Reasoning content of deepseek-reasoner (R1) models in Message.Reasoning and
+//     MessageDelta.Reasoning; Temperature and TopP, ignored by these models, are
+//     dropped with ChatResponse.Warnings
 //
 // The client automatically registers itself with the LLM provider registry
 // during package initialization, making it available for use with the
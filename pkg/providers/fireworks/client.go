@@ -0,0 +1,85 @@
+package fireworks
+
+import (
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// DefaultBaseURL is the Fireworks AI OpenAI-compatible inference endpoint
+const DefaultBaseURL = "https://api.fireworks.ai/inference/v1"
+
+// Model name markers used to detect capabilities. Fireworks model IDs look like
+// "accounts/fireworks/models/llama-v3p1-70b-instruct".
+var (
+	toolModelMarkers   = []string{"firefunction", "llama-v3p1", "llama-v3p3", "llama4", "qwen", "deepseek-v3", "mixtral-8x22b", "kimi", "glm", "gpt-oss"}
+	visionModelMarkers = []string{"vision", "llava", "-vl", "llama4", "phi-3-vision"}
+)
+
+// Client implements the llm.Client interface for Fireworks AI
+type Client struct {
+	*oaicompat.Client
+}
+
+// NewClient creates a new Fireworks AI client
+func NewClient(config llm.ClientConfig) (*Client, error) {
+	if config.APIKey == "" {
+		return nil, &llm.Error{
+			Code:    "missing_api_key",
+			Message: "API key is required for Fireworks AI",
+			Type:    "authentication_error",
+		}
+	}
+
+	client, err := oaicompat.NewClient(config, oaicompat.Config{
+		Provider:           "fireworks",
+		Name:               "Fireworks AI",
+		DefaultBaseURL:     DefaultBaseURL,
+		ToolModelMarkers:   toolModelMarkers,
+		VisionModelMarkers: visionModelMarkers,
+		MaxTokens:          maxTokensForModel(config.Model),
+		// Fireworks enforces JSON (with or without schema) and GBNF grammars natively
+		NativeFormat: oaicompat.JSONOrGrammar,
+		BuildRequest: func(req llm.ChatRequest, body oaicompat.Request) (interface{}, error) {
+			return &chatRequest{Request: body, ResponseFormat: convertResponseFormat(req.ResponseFormat)}, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: client}, nil
+}
+
+// maxTokensForModel returns the context length of well-known Fireworks models
+func maxTokensForModel(model string) int {
+	model = strings.ToLower(model)
+	switch {
+	case strings.Contains(model, "llama-v3p1"), strings.Contains(model, "llama-v3p3"),
+		strings.Contains(model, "deepseek-v3"), strings.Contains(model, "qwen2p5"),
+		strings.Contains(model, "gpt-oss"), strings.Contains(model, "kimi"):
+		return 131072
+	case strings.Contains(model, "mixtral-8x22b"), strings.Contains(model, "qwen3"):
+		return 65536
+	case strings.Contains(model, "firefunction"):
+		return 32768
+	default:
+		return 8192
+	}
+}
+
+// convertResponseFormat maps structured output requests onto Fireworks JSON and grammar modes,
+// where JSON schemas are sent with a json_object format
+func convertResponseFormat(format *llm.ResponseFormat) *chatResponseFormat {
+	compatible := format.Compatible()
+	if compatible == nil {
+		return nil
+	}
+
+	result := &chatResponseFormat{Type: compatible.Type, Grammar: compatible.Grammar}
+	if compatible.JSONSchema != nil {
+		result.Type = string(llm.ResponseFormatJSON)
+		result.Schema = compatible.JSONSchema.Schema
+	}
+	return result
+}
@@ -0,0 +1,304 @@
+package fireworks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// newTestServer starts a server that records the last request body and replies with handler
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]interface{})) (*Client, *map[string]interface{}) {
+	t.Helper()
+
+	var lastBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		lastBody = nil
+		_ = json.Unmarshal(data, &lastBody)
+		handler(w, lastBody)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(llm.ClientConfig{
+		APIKey:  "test-key",
+		Model:   "accounts/fireworks/models/llama-v3p1-8b-instruct",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client, &lastBody
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(llm.ClientConfig{Model: "m"}); err == nil {
+		t.Error("Expected error for missing API key")
+	}
+	if _, err := NewClient(llm.ClientConfig{APIKey: "k"}); err == nil {
+		t.Error("Expected error for missing model")
+	}
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "accounts/fireworks/models/firefunction-v2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info := client.GetModelInfo()
+	if info.Provider != "fireworks" || !info.SupportsTools || info.SupportsVision {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+	dryRun, err := llm.DryRun(context.Background(), client, llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")}})
+	if err != nil || dryRun.URL != DefaultBaseURL+"/chat/completions" {
+		t.Errorf("Expected default base URL, got %+v (%v)", dryRun, err)
+	}
+
+	client, _ = NewClient(llm.ClientConfig{APIKey: "k", Model: "custom", Extra: map[string]string{"supports_vision": "true"}})
+	if !client.GetModelInfo().SupportsVision {
+		t.Error("Expected supports_vision override")
+	}
+}
+
+func TestChatCompletion_ToolCalls(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{
+			"id": "resp-1",
+			"model": "llama",
+			"choices": [{
+				"index": 0,
+				"message": {
+					"role": "assistant",
+					"content": "",
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 7, "total_tokens": 19}
+		}`)
+	})
+
+	req := llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Weather in Paris?")},
+		Tools: []llm.Tool{{
+			Type: "function",
+			Function: llm.ToolFunction{
+				Name:       "get_weather",
+				Parameters: map[string]interface{}{"type": "object"},
+			},
+		}},
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tools, _ := (*lastBody)["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("Expected tools in request, got %v", (*lastBody)["tools"])
+	}
+	if (*lastBody)["model"] != "accounts/fireworks/models/llama-v3p1-8b-instruct" {
+		t.Errorf("Unexpected model %v", (*lastBody)["model"])
+	}
+
+	if len(resp.Choices) != 1 || len(resp.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %+v", resp.Choices)
+	}
+	call := resp.Choices[0].Message.ToolCalls[0]
+	if call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if resp.Usage.TotalTokens != 19 || resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestChatCompletion_ResponseFormats(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id": "r", "choices": [{"message": {"role": "assistant", "content": "yes"}}]}`)
+	})
+
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}}
+
+	tests := []struct {
+		name    string
+		format  *llm.ResponseFormat
+		want    string
+		checkFn func(format map[string]interface{}) bool
+	}{
+		{
+			name:   "json schema",
+			format: llm.NewJSONSchemaResponseFormat("person", "", schema),
+			want:   "json_object",
+			checkFn: func(format map[string]interface{}) bool {
+				return format["schema"] != nil
+			},
+		},
+		{
+			name:   "grammar",
+			format: &llm.ResponseFormat{Type: llm.ResponseFormatGrammar, Grammar: `root ::= "yes" | "no"`},
+			want:   "grammar",
+			checkFn: func(format map[string]interface{}) bool {
+				return format["grammar"] == `root ::= "yes" | "no"`
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := llm.ChatRequest{
+				Messages:       []llm.Message{llm.NewTextMessage(llm.RoleUser, "Answer")},
+				ResponseFormat: tt.format,
+			}
+			if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			format, _ := (*lastBody)["response_format"].(map[string]interface{})
+			if format["type"] != tt.want || !tt.checkFn(format) {
+				t.Errorf("Unexpected response_format: %v", format)
+			}
+			if messages, _ := (*lastBody)["messages"].([]interface{}); len(messages) != 1 {
+				t.Errorf("Native formats should not add prompt instructions, got %d messages", len(messages))
+			}
+		})
+	}
+}
+
+func TestChatCompletion_Errors(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"error": {"object": "error", "type": "", "message": "rate limit exceeded"}}`)
+	})
+
+	_, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.StatusCode != 429 || llmErr.Type != "rate_limit_error" || llmErr.Message != "rate limit exceeded" {
+		t.Errorf("Unexpected error: %+v", llmErr)
+	}
+	if llmErr.RateLimit == nil || llmErr.RateLimit.RetryAfter != 2*time.Second {
+		t.Errorf("Expected Retry-After to be reported, got %+v", llmErr.RateLimit)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"x\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var text strings.Builder
+	var arguments strings.Builder
+	var finishReason llm.FinishReason
+	tracker := llm.NewStreamSequenceTracker()
+	for event := range stream {
+		if err := tracker.Observe(event); err != nil || event.Seq == 0 {
+			t.Errorf("Expected sequenced events, got seq %d (%v)", event.Seq, err)
+		}
+		switch {
+		case event.IsError():
+			t.Fatalf("Unexpected error event: %v", event.Error)
+		case event.IsDelta():
+			for _, content := range event.Choice.Delta.Content {
+				text.WriteString(content.(*llm.TextContent).GetText())
+			}
+			for _, tc := range event.Choice.Delta.ToolCalls {
+				if tc.Function != nil {
+					arguments.WriteString(tc.Function.Arguments)
+				}
+			}
+		case event.IsDone():
+			finishReason = event.Choice.FinishReason
+		}
+	}
+
+	if (*lastBody)["stream"] != true {
+		t.Error("Expected stream to be requested")
+	}
+	if text.String() != "Hello" {
+		t.Errorf("Expected 'Hello', got %q", text.String())
+	}
+	if arguments.String() != `{"q":"x"}` {
+		t.Errorf("Unexpected tool arguments %q", arguments.String())
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", finishReason)
+	}
+}
+
+func TestChatCompletion_ContentTransformer(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id": "r", "choices": [{"message": {"role": "assistant", "content": "A cat"}}]}`)
+	})
+
+	llm.RegisterContentTransformer("fireworks", llm.MessageTypeImage, llm.ContentTransformerFunc(
+		func(ctx context.Context, content llm.MessageContent, model llm.ModelInfo) ([]llm.MessageContent, error) {
+			return []llm.MessageContent{llm.NewTextContent("[Image: a cat on a sofa]")}, nil
+		}))
+	defer llm.UnregisterContentTransformer("fireworks", llm.MessageTypeImage)
+
+	req := llm.ChatRequest{Messages: []llm.Message{{
+		Role: llm.RoleUser,
+		Content: []llm.MessageContent{
+			llm.NewTextContent("What is this?"),
+			llm.NewImageContentFromBytes([]byte{1, 2, 3}, "image/png"),
+		},
+	}}}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("Expected transformed image to be accepted by a text-only model, got %v", err)
+	}
+
+	data, _ := json.Marshal((*lastBody)["messages"])
+	if !strings.Contains(string(data), "a cat on a sofa") || strings.Contains(string(data), "image_url") {
+		t.Errorf("Expected image to be replaced by its caption, got %s", data)
+	}
+	if _, ok := req.Messages[0].Content[1].(*llm.ImageContent); !ok {
+		t.Error("Expected the caller's request to be left untouched")
+	}
+}
+
+func TestChatCompletion_User(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id": "r", "choices": [{"message": {"role": "assistant", "content": "yes"}}]}`)
+	})
+
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Answer")}, User: "user-42"}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if (*lastBody)["user"] != "user-42" {
+		t.Errorf("Expected the user in the payload, got %v", (*lastBody)["user"])
+	}
+}
@@ -0,0 +1,32 @@
+// Package fireworks provides an LLM client for Fireworks AI.
+//
+// This provider implements the llm.Client interface on top of Fireworks'
+// OpenAI-compatible chat completions API, supporting both streaming and
+// non-streaming chat completions.
+//
+// Key features:
+//   - Function calling for models that support it (firefunction, Llama 3.1+, Qwen,
+//     DeepSeek V3...), detected from the model name or forced with Extra["supports_tools"]
+//   - Image inputs for vision models, detected from the model name or forced with
+//     Extra["supports_vision"]
+//   - Structured outputs through JSON mode: llm.ResponseFormatJSONSchema requests are
+//     sent as JSON mode with the schema enforced by Fireworks
+//   - Grammar mode: llm.ResponseFormatGrammar constrains the output to a GBNF grammar
+//   - Text files are inlined in the prompt; other file attachments are rejected
+//
+// Usage:
+//
+//	config := llm.ClientConfig{
+//	    Provider: "fireworks",
+//	    APIKey:   "your-api-key",
+//	    Model:    "accounts/fireworks/models/llama-v3p1-70b-instruct",
+//	}
+//	client, err := factory.New().CreateClient(config)
+//
+// Grammar mode:
+//
+//	req.ResponseFormat = &llm.ResponseFormat{
+//	    Type:    llm.ResponseFormatGrammar,
+//	    Grammar: `root ::= "yes" | "no"`,
+//	}
+package fireworks
@@ -0,0 +1,19 @@
+package fireworks
+
+import (
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// Wire types for the Fireworks OpenAI-compatible chat completions API
+
+type chatRequest struct {
+	oaicompat.Request
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+}
+
+// chatResponseFormat selects JSON mode (optionally with a schema) or grammar mode
+type chatResponseFormat struct {
+	Type    string      `json:"type"`
+	Schema  interface{} `json:"schema,omitempty"`
+	Grammar string      `json:"grammar,omitempty"`
+}
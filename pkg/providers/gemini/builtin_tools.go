@@ -0,0 +1,108 @@
+package gemini
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// builtinToolOption enables a tool run by Gemini itself
+type builtinToolOption struct {
+	tool llm.BuiltinToolType
+}
+
+func (builtinToolOption) Provider() string { return "gemini" }
+
+// WithCodeExecution returns a request option letting the model write and run Python code
+// in a sandbox, e.g. for calculations. The code and its output are the
+// llm.BuiltinToolCodeExecution calls of the response message.
+func WithCodeExecution() llm.ProviderOption {
+	return builtinToolOption{tool: llm.BuiltinToolCodeExecution}
+}
+
+// WithGoogleSearch returns a request option grounding the response with Google Search.
+// The queries and the sources are the llm.BuiltinToolWebSearch call of the response
+// message, and the sources supporting each span are its citations.
+func WithGoogleSearch() llm.ProviderOption {
+	return builtinToolOption{tool: llm.BuiltinToolWebSearch}
+}
+
+// builtinTools returns the tools enabled by the options of a request
+func builtinTools(req llm.ChatRequest) []*genai.Tool {
+	var tools []*genai.Tool
+	enabled := make(map[llm.BuiltinToolType]bool)
+	for _, option := range req.ProviderOptionsFor("gemini") {
+		builtin, ok := option.(builtinToolOption)
+		if !ok || enabled[builtin.tool] {
+			continue
+		}
+		enabled[builtin.tool] = true
+		switch builtin.tool {
+		case llm.BuiltinToolCodeExecution:
+			tools = append(tools, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}})
+		case llm.BuiltinToolWebSearch:
+			tools = append(tools, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+		}
+	}
+	return tools
+}
+
+// candidateBuiltinToolCalls returns the code run by the model, with its output, and the
+// searches grounding the response
+func candidateBuiltinToolCalls(candidate *genai.Candidate) []llm.BuiltinToolCall {
+	if candidate == nil {
+		return nil
+	}
+
+	var calls []llm.BuiltinToolCall
+	if candidate.Content != nil {
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part == nil:
+			case part.ExecutableCode != nil:
+				calls = append(calls, llm.BuiltinToolCall{
+					Type:     llm.BuiltinToolCodeExecution,
+					Language: string(part.ExecutableCode.Language),
+					Code:     part.ExecutableCode.Code,
+				})
+			case part.CodeExecutionResult != nil:
+				// The result follows the code it is the output of
+				last := len(calls) - 1
+				if last < 0 || calls[last].Type != llm.BuiltinToolCodeExecution || calls[last].Status != "" {
+					calls = append(calls, llm.BuiltinToolCall{Type: llm.BuiltinToolCodeExecution})
+					last = len(calls) - 1
+				}
+				calls[last].Output = part.CodeExecutionResult.Output
+				calls[last].Status = string(part.CodeExecutionResult.Outcome)
+			}
+		}
+	}
+
+	if grounding := candidate.GroundingMetadata; grounding != nil && len(grounding.WebSearchQueries) > 0 {
+		search := llm.BuiltinToolCall{Type: llm.BuiltinToolWebSearch, Queries: grounding.WebSearchQueries}
+		for _, chunk := range grounding.GroundingChunks {
+			if citation, ok := groundingCitation(chunk); ok {
+				search.Results = append(search.Results, citation)
+			}
+		}
+		calls = append(calls, search)
+	}
+	return calls
+}
+
+// partsText returns the text of the parts of a content, skipping the thoughts of the
+// model: with code execution, the answer is split around the code and its output
+func partsText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range content.Parts {
+		if part != nil && !part.Thought {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,65 @@
+package gemini
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestBuiltinTools(t *testing.T) {
+	req := llm.ChatRequest{}.WithProviderOptions(WithCodeExecution(), WithGoogleSearch(), WithCodeExecution())
+	tools := builtinTools(req)
+	if len(tools) != 2 || tools[0].CodeExecution == nil || tools[1].GoogleSearch == nil {
+		t.Fatalf("Expected code execution and Google Search once, got %+v", tools)
+	}
+	if tools := builtinTools(llm.ChatRequest{}); tools != nil {
+		t.Errorf("Expected no tools without options, got %+v", tools)
+	}
+}
+
+func TestConvertResponse_BuiltinToolCalls(t *testing.T) {
+	client := &Client{model: "gemini-2.5-flash", provider: "gemini"}
+
+	resp, err := client.convertResponse(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{Text: "Let me compute it. "},
+				{ExecutableCode: &genai.ExecutableCode{Language: genai.LanguagePython, Code: "print(2**10)"}},
+				{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "1024\n"}},
+				{Text: "2^10 is 1024."},
+			}},
+			FinishReason: genai.FinishReasonStop,
+			GroundingMetadata: &genai.GroundingMetadata{
+				WebSearchQueries: []string{"powers of two"},
+				GroundingChunks: []*genai.GroundingChunk{
+					{Web: &genai.GroundingChunkWeb{URI: "https://example.com/powers", Title: "Powers"}},
+				},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("convertResponse failed: %v", err)
+	}
+
+	message := resp.Choices[0].Message
+	if text := message.GetText(); text != "Let me compute it. 2^10 is 1024." {
+		t.Errorf("Expected the text of all the parts, got %q", text)
+	}
+	want := []llm.BuiltinToolCall{
+		{Type: llm.BuiltinToolCodeExecution, Language: "PYTHON", Code: "print(2**10)", Output: "1024\n", Status: "OUTCOME_OK"},
+		{
+			Type:    llm.BuiltinToolWebSearch,
+			Queries: []string{"powers of two"},
+			Results: []llm.Citation{{URL: "https://example.com/powers", Title: "Powers"}},
+		},
+	}
+	if !reflect.DeepEqual(message.BuiltinToolCalls, want) {
+		t.Errorf("Unexpected builtin tool calls:\n got %+v\nwant %+v", message.BuiltinToolCalls, want)
+	}
+	if calls := message.BuiltinToolCallsOf(llm.BuiltinToolWebSearch); len(calls) != 1 {
+		t.Errorf("Expected a single search, got %+v", calls)
+	}
+}
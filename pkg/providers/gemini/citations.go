@@ -0,0 +1,81 @@
+package gemini
+
+import (
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// candidateCitations returns the citations of a candidate: the sources recited by the
+// model (CitationMetadata) followed by the grounding sources supporting each segment of
+// the response (GroundingMetadata, e.g. with the Google Search tool). Grounding sources
+// that support no segment are listed without a span.
+func candidateCitations(candidate *genai.Candidate) []llm.Citation {
+	if candidate == nil {
+		return nil
+	}
+
+	var citations []llm.Citation
+	if candidate.CitationMetadata != nil {
+		for _, citation := range candidate.CitationMetadata.Citations {
+			if citation == nil {
+				continue
+			}
+			citations = append(citations, llm.Citation{
+				URL:        citation.URI,
+				Title:      citation.Title,
+				StartIndex: int(citation.StartIndex),
+				EndIndex:   int(citation.EndIndex),
+			})
+		}
+	}
+
+	grounding := candidate.GroundingMetadata
+	if grounding == nil {
+		return citations
+	}
+	supported := make([]bool, len(grounding.GroundingChunks))
+	for _, support := range grounding.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		for _, index := range support.GroundingChunkIndices {
+			if index < 0 || int(index) >= len(grounding.GroundingChunks) {
+				continue
+			}
+			citation, ok := groundingCitation(grounding.GroundingChunks[index])
+			if !ok {
+				continue
+			}
+			supported[index] = true
+			citation.StartIndex = int(support.Segment.StartIndex)
+			citation.EndIndex = int(support.Segment.EndIndex)
+			citation.Text = support.Segment.Text
+			citations = append(citations, citation)
+		}
+	}
+	for i, chunk := range grounding.GroundingChunks {
+		if supported[i] {
+			continue
+		}
+		if citation, ok := groundingCitation(chunk); ok {
+			citations = append(citations, citation)
+		}
+	}
+	return citations
+}
+
+// groundingCitation returns the source of a grounding chunk, from the web or from a
+// retrieval corpus
+func groundingCitation(chunk *genai.GroundingChunk) (llm.Citation, bool) {
+	switch {
+	case chunk == nil:
+		return llm.Citation{}, false
+	case chunk.Web != nil:
+		return llm.Citation{URL: chunk.Web.URI, Title: chunk.Web.Title}, true
+	case chunk.RetrievedContext != nil:
+		return llm.Citation{URL: chunk.RetrievedContext.URI, Title: chunk.RetrievedContext.Title}, true
+	default:
+		return llm.Citation{}, false
+	}
+}
@@ -0,0 +1,47 @@
+package gemini
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestConvertResponse_Citations(t *testing.T) {
+	client := &Client{model: "gemini-2.0-flash", provider: "gemini"}
+
+	resp, err := client.convertResponse(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromText("Go was released in 2009. It is popular.", genai.RoleModel),
+			FinishReason: genai.FinishReasonStop,
+			CitationMetadata: &genai.CitationMetadata{Citations: []*genai.Citation{
+				{URI: "https://go.dev/doc/faq", Title: "Go FAQ", StartIndex: 0, EndIndex: 24},
+			}},
+			GroundingMetadata: &genai.GroundingMetadata{
+				GroundingChunks: []*genai.GroundingChunk{
+					{Web: &genai.GroundingChunkWeb{URI: "https://go.dev", Title: "go.dev"}},
+					{RetrievedContext: &genai.GroundingChunkRetrievedContext{URI: "gs://docs/survey.pdf", Title: "Survey"}},
+					nil,
+				},
+				GroundingSupports: []*genai.GroundingSupport{{
+					Segment:               &genai.Segment{StartIndex: 25, EndIndex: 39, Text: "It is popular."},
+					GroundingChunkIndices: []int32{0, 2, 7},
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("convertResponse failed: %v", err)
+	}
+
+	want := []llm.Citation{
+		{URL: "https://go.dev/doc/faq", Title: "Go FAQ", StartIndex: 0, EndIndex: 24},
+		{URL: "https://go.dev", Title: "go.dev", StartIndex: 25, EndIndex: 39, Text: "It is popular."},
+		{URL: "gs://docs/survey.pdf", Title: "Survey"},
+	}
+	if got := resp.Choices[0].Message.Citations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected citations:\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -2,8 +2,9 @@ package gemini
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -67,9 +68,21 @@ type Client struct {
 	provider string
 	genai    *genai.Client
 
+	// safetySettings are the block thresholds sent with every request
+	safetySettings SafetySettings
+
+	// apiKey, baseURL and httpClient are used by the file API, not covered by genai
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
+
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new Gemini client using the official Google Generative AI library.
@@ -81,16 +94,29 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		config.Model = "gemini-1.5-flash"
 	}
 
+	safetySettings, err := safetySettingsFromExtra(config.Extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Create genai client config
+	httpClient := &http.Client{Transport: &llm.DryRunTransport{}}
 	genaiConfig := &genai.ClientConfig{
-		APIKey:  config.APIKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     config.APIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
 	}
 
 	// Set timeout if specified
 	if config.Timeout > 0 {
 		genaiConfig.HTTPOptions.Timeout = &config.Timeout
 	}
+	if config.BaseURL != "" {
+		genaiConfig.HTTPOptions.BaseURL = config.BaseURL
+	}
 
 	// Create the genai client
 	genaiClient, err := genai.NewClient(context.Background(), genaiConfig)
@@ -103,14 +129,35 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 	}
 
 	return &Client{
-		model:    config.Model,
-		provider: "gemini",
-		genai:    genaiClient,
+		stats:          llm.NewHealthStatsWithClock(config.Clock),
+		clock:          config.GetClock(),
+		model:          config.Model,
+		provider:       "gemini",
+		genai:          genaiClient,
+		safetySettings: safetySettings,
+		streamBuffer:   config.StreamBuffer,
+		apiKey:         config.APIKey,
+		baseURL:        config.BaseURL,
+		httpClient:     httpClient,
 	}, nil
 }
 
 // ChatCompletion performs a non-streaming content generation request.
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.chatCompletion(ctx, req)
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert our messages to genai Content format
 	contents, err := c.convertMessages(req.Messages)
 	if err != nil {
@@ -118,7 +165,10 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Create generation config
-	config := &genai.GenerateContentConfig{}
+	config := &genai.GenerateContentConfig{
+		SafetySettings: c.requestSafetySettings(req).genaiSettings(),
+		Tools:          builtinTools(req),
+	}
 	if req.Temperature != nil {
 		config.Temperature = req.Temperature
 	}
@@ -126,10 +176,9 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		config.MaxOutputTokens = safeIntToInt32(*req.MaxTokens)
 	}
 
-	// Handle ResponseFormat by adding instructions to the system message
-	// Gemini doesn't support structured outputs natively, so we use prompt engineering
-	if req.ResponseFormat != nil {
-		contents = c.addResponseFormatInstructions(contents, req.ResponseFormat)
+	contents, err = c.applyResponseFormat(req, config, contents)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a chat session with history
@@ -161,7 +210,7 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Convert response to our format
-	return c.convertResponse(response), nil
+	return c.convertResponse(response)
 }
 
 // convertMessages converts our internal message format to genai Content format
@@ -203,41 +252,67 @@ func (c *Client) convertMessages(messages []llm.Message) ([]*genai.Content, erro
 	return contents, nil
 }
 
-// convertResponse converts genai response to our internal format
-func (c *Client) convertResponse(resp *genai.GenerateContentResponse) *llm.ChatResponse {
+// convertResponse converts genai response to our internal format, failing with a
+// "content_filtered" error when the safety filters blocked the prompt or the response
+func (c *Client) convertResponse(resp *genai.GenerateContentResponse) (*llm.ChatResponse, error) {
+	if err := blockedError(resp); err != nil {
+		return nil, err
+	}
 	if len(resp.Candidates) == 0 {
 		return &llm.ChatResponse{
 			ID:      fmt.Sprintf("gemini-%s", time.Now().Format(time.RFC3339Nano)),
 			Model:   c.model,
 			Choices: []llm.Choice{},
-		}
+			Raw:     rawResponse(resp),
+		}, nil
 	}
 
 	candidate := resp.Candidates[0]
-	text := candidate.Content.Parts[0].Text
-
-	finishReason := "stop"
-	if candidate.FinishReason == genai.FinishReasonMaxTokens {
-		finishReason = "length"
-	} else if strings.Contains(string(candidate.FinishReason), "SAFETY") {
-		finishReason = "content_filter"
-	}
-
 	message := llm.Message{
-		Role:    llm.RoleAssistant,
-		Content: []llm.MessageContent{llm.NewTextContent(text)},
+		Role:             llm.RoleAssistant,
+		Content:          []llm.MessageContent{llm.NewTextContent(partsText(candidate.Content))},
+		Citations:        candidateCitations(candidate),
+		BuiltinToolCalls: candidateBuiltinToolCalls(candidate),
 	}
 
 	choice := llm.Choice{
-		Index:        0,
-		Message:      message,
-		FinishReason: finishReason,
+		Index:   0,
+		Message: message,
+	}
+	choice.SetFinishReason(c.provider, string(candidate.FinishReason))
+	if candidate.FinishReason == "" {
+		choice.FinishReason = llm.FinishReasonStop
 	}
 
 	return &llm.ChatResponse{
 		ID:      fmt.Sprintf("gemini-%s", time.Now().Format(time.RFC3339Nano)),
 		Model:   c.model,
 		Choices: []llm.Choice{choice},
+		Usage:   convertUsage(resp.UsageMetadata),
+		Raw:     rawResponse(resp),
+	}, nil
+}
+
+// rawResponse wraps a native response, with its body when the SDK kept it
+func rawResponse(resp *genai.GenerateContentResponse) llm.RawResponse {
+	var body []byte
+	if resp.SDKHTTPResponse != nil && resp.SDKHTTPResponse.Body != "" {
+		body = []byte(resp.SDKHTTPResponse.Body)
+	}
+	return llm.NewRawResponse(resp, body)
+}
+
+// convertUsage converts the usage metadata of a response, including the prompt tokens
+// served from cached content
+func convertUsage(metadata *genai.GenerateContentResponseUsageMetadata) llm.Usage {
+	if metadata == nil {
+		return llm.Usage{}
+	}
+	return llm.Usage{
+		PromptTokens:     int(metadata.PromptTokenCount),
+		CompletionTokens: int(metadata.CandidatesTokenCount),
+		TotalTokens:      int(metadata.TotalTokenCount),
+		CacheReadTokens:  int(metadata.CachedContentTokenCount),
 	}
 }
 
@@ -253,7 +328,7 @@ func (c *Client) convertError(err error) *llm.Error {
 	}
 
 	// Convert specific error types based on error message/content
-	errMsg := err.Error()
+	errMsg := llm.RedactSecrets(err.Error())
 
 	// Check for authentication errors
 	if strings.Contains(errMsg, "API key") ||
@@ -276,6 +351,7 @@ func (c *Client) convertError(err error) *llm.Error {
 			Message:    errMsg,
 			Type:       "rate_limit_error",
 			StatusCode: 429,
+			RateLimit:  retryInfo(err),
 		}
 	}
 
@@ -298,7 +374,44 @@ func (c *Client) convertError(err error) *llm.Error {
 	}
 }
 
+// retryInfo extracts the retry delay from the google.rpc.RetryInfo details of an API error
+func retryInfo(err error) *llm.RateLimitInfo {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	for _, detail := range apiErr.Details {
+		if kind, _ := detail["@type"].(string); !strings.HasSuffix(kind, "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(delay); err == nil {
+				return &llm.RateLimitInfo{RetryAfter: d}
+			}
+		}
+	}
+	return nil
+}
+
+// StreamChatCompletion performs a streaming chat completion request using Gemini
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert our messages to genai Content format
 	contents, err := c.convertMessages(req.Messages)
 	if err != nil {
@@ -306,7 +419,10 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 	}
 
 	// Create generation config
-	config := &genai.GenerateContentConfig{}
+	config := &genai.GenerateContentConfig{
+		SafetySettings: c.requestSafetySettings(req).genaiSettings(),
+		Tools:          builtinTools(req),
+	}
 	if req.Temperature != nil {
 		config.Temperature = req.Temperature
 	}
@@ -314,6 +430,11 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		config.MaxOutputTokens = safeIntToInt32(*req.MaxTokens)
 	}
 
+	contents, err = c.applyResponseFormat(req, config, contents)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a chat session with history
 	var history []*genai.Content
 	if len(contents) > 1 {
@@ -337,59 +458,82 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 	}
 
 	// Create output channel
-	ch := make(chan llm.StreamEvent)
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 
-		// Send streaming message
+		// Send streaming message, keeping the last finish reason and chunk, with the usage,
+		// for the done event
+		finishReason := string(genai.FinishReasonStop)
+		var last *genai.GenerateContentResponse
 		for response, err := range chat.SendMessageStream(ctx, parts...) {
 			if err != nil {
-				ch <- llm.NewErrorEvent(c.convertError(err))
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
 				return
 			}
+			last = response
+
+			if blocked := blockedError(response); blocked != nil {
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(blocked)))
+				return
+			}
+
+			if len(response.Candidates) > 0 && response.Candidates[0].FinishReason != "" {
+				finishReason = string(response.Candidates[0].FinishReason)
+			}
 
 			// Convert response to delta
-			if len(response.Candidates) > 0 && len(response.Candidates[0].Content.Parts) > 0 {
-				text := response.Candidates[0].Content.Parts[0].Text
-				if text != "" {
+			if len(response.Candidates) > 0 {
+				if text := partsText(response.Candidates[0].Content); text != "" {
 					delta := &llm.MessageDelta{Content: []llm.MessageContent{llm.NewTextContent(text)}}
-					ch <- llm.NewDeltaEvent(0, delta)
+					if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
+						return
+					}
 				}
 			}
 		}
 
 		// Send done event
-		ch <- llm.NewDoneEvent(0, "stop")
+		done := llm.NewProviderDoneEvent(0, c.provider, finishReason)
+		if last != nil {
+			done.Raw = rawResponse(last)
+		}
+		emitter.Emit(seq.Stamp(done))
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
+}
+
+// applyResponseFormat enforces the request's ResponseFormat with Gemini's JSON mode
+// (responseMimeType, with the schema in responseJsonSchema) or, for grammars and when the
+// policy asks for it, with prompt instructions
+func (c *Client) applyResponseFormat(req llm.ChatRequest, config *genai.GenerateContentConfig, contents []*genai.Content) ([]*genai.Content, error) {
+	native, err := req.ResolveResponseFormat(req.ResponseFormat.IsJSON())
+	if err != nil {
+		return nil, err
+	}
+	if !native {
+		return c.addResponseFormatInstructions(contents, req.ResponseFormat), nil
+	}
+
+	config.ResponseMIMEType = "application/json"
+	if schema := req.ResponseFormat.JSONSchema; schema != nil && schema.Schema != nil {
+		config.ResponseJsonSchema = schema.Schema
+	}
+	return contents, nil
 }
 
-// addResponseFormatInstructions adds JSON formatting instructions to the content when ResponseFormat is specified
-// Since Gemini doesn't support structured outputs natively, we use prompt engineering
+// addResponseFormatInstructions adds formatting instructions to the content when ResponseFormat
+// is not enforced natively
 func (c *Client) addResponseFormatInstructions(contents []*genai.Content, responseFormat *llm.ResponseFormat) []*genai.Content {
 	if responseFormat == nil {
 		return contents
 	}
 
-	var instruction string
-	switch responseFormat.Type {
-	case llm.ResponseFormatJSON:
-		instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-	case llm.ResponseFormatJSONSchema:
-		if responseFormat.JSONSchema != nil && responseFormat.JSONSchema.Schema != nil {
-			// Convert schema to string for instruction
-			schemaBytes, err := json.Marshal(responseFormat.JSONSchema.Schema)
-			if err == nil {
-				instruction = fmt.Sprintf("Please respond only with valid JSON that conforms to this schema: %s. Do not include any text before or after the JSON object.", string(schemaBytes))
-			} else {
-				instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-			}
-		} else {
-			instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-		}
-	default:
+	instruction := llm.ResponseFormatInstruction(responseFormat)
+	if instruction == "" {
 		return contents // No formatting needed for text responses
 	}
 
@@ -412,26 +556,23 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on the Gemini API
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -443,12 +584,14 @@ func (c *Client) performHealthCheck() bool {
 	// Create a simple chat session
 	chat, err := c.genai.Chats.Create(ctx, c.model, config, nil)
 	if err != nil {
-		return false
+		return c.convertError(err)
 	}
 
 	// Try a simple message
-	_, err = chat.SendMessage(ctx, *genai.NewPartFromText("test"))
-	return err == nil
+	if _, err = chat.SendMessage(ctx, *genai.NewPartFromText("test")); err != nil {
+		return c.convertError(err)
+	}
+	return nil
 }
 
 func (c *Client) GetModelInfo() llm.ModelInfo {
@@ -479,6 +622,16 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	}
 }
 
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
 func (c *Client) Close() error {
 	// The genai client doesn't provide a Close method, so we don't need to do anything
 	return nil
@@ -0,0 +1,46 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestApplyResponseFormat(t *testing.T) {
+	c := &Client{}
+	contents := []*genai.Content{genai.NewContentFromText("hi", genai.RoleUser)}
+	schema := map[string]any{"type": "object"}
+
+	req := llm.ChatRequest{
+		ResponseFormat:       llm.NewJSONSchemaResponseFormat("answer", "", schema),
+		ResponseFormatPolicy: llm.ResponseFormatPolicyRequireNative,
+	}
+	config := &genai.GenerateContentConfig{}
+	got, err := c.applyResponseFormat(req, config, contents)
+	if err != nil {
+		t.Fatalf("applyResponseFormat failed: %v", err)
+	}
+	if len(got) != 1 || config.ResponseMIMEType != "application/json" || config.ResponseJsonSchema == nil {
+		t.Errorf("Expected native JSON schema mode, got %d contents and config %+v", len(got), config)
+	}
+
+	req.ResponseFormatPolicy = llm.ResponseFormatPolicyPromptOnly
+	config = &genai.GenerateContentConfig{}
+	got, err = c.applyResponseFormat(req, config, contents)
+	if err != nil {
+		t.Fatalf("applyResponseFormat failed: %v", err)
+	}
+	if len(got) != 2 || config.ResponseMIMEType != "" {
+		t.Errorf("Expected prompt instructions only, got %d contents and MIME type %q", len(got), config.ResponseMIMEType)
+	}
+
+	req = llm.ChatRequest{
+		ResponseFormat:       &llm.ResponseFormat{Type: llm.ResponseFormatGrammar, Grammar: `root ::= "yes"`},
+		ResponseFormatPolicy: llm.ResponseFormatPolicyRequireNative,
+	}
+	if _, err := c.applyResponseFormat(req, &genai.GenerateContentConfig{}, contents); err == nil {
+		t.Error("Expected grammars to fail when native enforcement is required")
+	}
+}
@@ -8,8 +8,15 @@
 //   - Text and multimodal (text + image) content support
 //   - Streaming chat completions
 //   - Automatic error conversion to standardized format
-//   - Response format instructions via prompt engineering
+//   - Native JSON mode and response schemas, with prompt instructions for other formats
 //   - Temperature and token limit controls
+//   - Per-category safety thresholds (SafetySettings) and typed errors for blocked content
+//   - Per-request safety overrides (WithSafety)
+//   - Code execution and Google Search grounding, reported as llm.BuiltinToolCall (WithCodeExecution, WithGoogleSearch)
+//   - Citations from citation and grounding metadata (llm.Citation)
+//   - Chunked, resumable file uploads with the File API (UploadFile)
+//   - Speech to text with timed segments (Transcribe)
+//   - Text to speech with the TTS models, whole or streamed (Speak, StreamSpeech)
 //
 // The client automatically registers itself with the LLM provider registry
 // during package initialization, making it available for use with the
@@ -0,0 +1,238 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// defaultAPIURL is the Gemini API URL, used when the client has no base URL
+	defaultAPIURL = "https://generativelanguage.googleapis.com"
+
+	// uploadGranularity is the multiple of the size of the chunks of resumable uploads,
+	// except the last one
+	uploadGranularity = 256 * 1024
+)
+
+// fileObject is a file of the File API
+type fileObject struct {
+	Name       string `json:"name"`
+	MimeType   string `json:"mimeType"`
+	SizeBytes  string `json:"sizeBytes"`
+	URI        string `json:"uri"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// UploadFile uploads a file with the resumable upload protocol of the File API, in
+// chunks of upload.ChunkSize bytes (rounded to a multiple of 256KB) read from the
+// reader, so large files are never held in memory. The upload is finalized once the
+// SHA-256 digest of the content has been verified, and the digest computed by Gemini is
+// checked too. On failure after the upload was started, the error has the
+// "upload_interrupted" code and the session to resume from in its Upload field; when
+// resuming, the offset is confirmed with Gemini first.
+func (c *Client) UploadFile(ctx context.Context, upload *llm.FileUpload) (*llm.UploadedFile, error) {
+	if err := upload.Validate(); err != nil {
+		return nil, err
+	}
+
+	var session *llm.UploadSession
+	if upload.Resume != nil {
+		received, err := c.queryUpload(ctx, upload.Resume.URL)
+		if err != nil {
+			return nil, err
+		}
+		session = &llm.UploadSession{Provider: c.provider, URL: upload.Resume.URL, Offset: received}
+		resumed := *upload
+		resumed.Resume = session
+		upload = &resumed
+	} else {
+		uploadURL, err := c.startUpload(ctx, upload)
+		if err != nil {
+			return nil, err
+		}
+		session = &llm.UploadSession{Provider: c.provider, URL: uploadURL}
+	}
+
+	chunkSize := upload.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = llm.DefaultUploadChunkSize
+	}
+	chunkSize = max(chunkSize/uploadGranularity, 1) * uploadGranularity
+
+	var file fileObject
+	digest, err := llm.SendChunks(ctx, upload, chunkSize, func(ctx context.Context, chunk []byte, offset int64, last bool) error {
+		command := "upload"
+		if last {
+			command = "upload, finalize"
+		}
+		resp, err := c.send(ctx, session.URL, chunk, map[string]string{
+			"X-Goog-Upload-Command": command,
+			"X-Goog-Upload-Offset":  strconv.FormatInt(offset, 10),
+		})
+		if err != nil {
+			return err
+		}
+		session.Offset = offset + int64(len(chunk))
+		if last {
+			var created struct {
+				File fileObject `json:"file"`
+			}
+			if err := json.Unmarshal(resp.body, &created); err != nil {
+				return err
+			}
+			file = created.File
+		}
+		return nil
+	})
+	if err != nil {
+		if llmErr, ok := err.(*llm.Error); ok && llmErr.Code == "integrity_mismatch" {
+			return nil, err
+		}
+		return nil, llm.NewUploadInterruptedError(session, err)
+	}
+
+	if remote := remoteDigest(file.SHA256Hash); remote != "" && remote != digest {
+		return nil, &llm.Error{
+			Code:    "integrity_mismatch",
+			Message: fmt.Sprintf("Gemini stored %s with the SHA-256 digest %s, %s was sent", file.Name, remote, digest),
+			Type:    "upload_error",
+		}
+	}
+
+	size, _ := strconv.ParseInt(file.SizeBytes, 10, 64)
+	if size == 0 {
+		size = upload.Size
+	}
+	return &llm.UploadedFile{
+		ID:       file.Name,
+		URI:      file.URI,
+		Filename: upload.Filename,
+		MimeType: file.MimeType,
+		Size:     size,
+		SHA256:   digest,
+	}, nil
+}
+
+// startUpload starts a resumable upload, returning its URL
+func (c *Client) startUpload(ctx context.Context, upload *llm.FileUpload) (string, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"file": map[string]string{"display_name": upload.Filename},
+	})
+	if err != nil {
+		return "", err
+	}
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = defaultAPIURL
+	}
+	resp, err := c.send(ctx, strings.TrimSuffix(baseURL, "/")+"/upload/v1beta/files", metadata, map[string]string{
+		"Content-Type":                        "application/json",
+		"X-Goog-Upload-Protocol":              "resumable",
+		"X-Goog-Upload-Command":               "start",
+		"X-Goog-Upload-Header-Content-Length": strconv.FormatInt(upload.Size, 10),
+		"X-Goog-Upload-Header-Content-Type":   upload.MimeType,
+	})
+	if err != nil {
+		return "", err
+	}
+	uploadURL := resp.header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return "", &llm.Error{Code: "upload_error", Message: "Gemini did not return an upload URL", Type: "upload_error"}
+	}
+	return uploadURL, nil
+}
+
+// queryUpload returns the number of bytes received for a resumable upload
+func (c *Client) queryUpload(ctx context.Context, uploadURL string) (int64, error) {
+	resp, err := c.send(ctx, uploadURL, nil, map[string]string{"X-Goog-Upload-Command": "query"})
+	if err != nil {
+		return 0, err
+	}
+	received, err := strconv.ParseInt(resp.header.Get("X-Goog-Upload-Size-Received"), 10, 64)
+	if err != nil {
+		return 0, &llm.Error{Code: "upload_error", Message: "Gemini did not return the size received", Type: "upload_error"}
+	}
+	return received, nil
+}
+
+// uploadResponse is the response to a request of the upload protocol
+type uploadResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// send posts a body with headers to a URL of the upload protocol, converting errors
+func (c *Client) send(ctx context.Context, url string, body []byte, headers map[string]string) (*uploadResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("x-goog-api-key", c.apiKey)
+	for name, value := range headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: llm.SharedTransport()}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		llmErr := &llm.Error{
+			Code:       "upload_error",
+			Message:    fmt.Sprintf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data))),
+			Type:       "api_error",
+			StatusCode: resp.StatusCode,
+			RateLimit:  llm.ParseRateLimitHeaders(resp.Header),
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			llmErr.Message = apiErr.Error.Message
+			if apiErr.Error.Status != "" {
+				llmErr.Code = strings.ToLower(apiErr.Error.Status)
+			}
+		}
+		return nil, llm.RedactError(llmErr)
+	}
+	return &uploadResponse{header: resp.Header, body: data}, nil
+}
+
+// remoteDigest returns the hex digest of the sha256Hash of a file, which Gemini encodes
+// in base64 (of the raw digest or of its hex form), or "" when it is missing or unknown
+func remoteDigest(hash string) string {
+	decoded, err := base64.StdEncoding.DecodeString(hash)
+	switch {
+	case hash == "" || err != nil:
+		return ""
+	case len(decoded) == 32:
+		return hex.EncodeToString(decoded)
+	case len(decoded) == 64:
+		return strings.ToLower(string(decoded))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,99 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// resumableServer emulates the resumable upload protocol of the File API, failing the
+// chunk at failOffset once
+func resumableServer(t *testing.T, received *[]byte, failOffset int64) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("Missing API key")
+		}
+		switch r.Header.Get("X-Goog-Upload-Command") {
+		case "start":
+			if r.URL.Path != "/upload/v1beta/files" || r.Header.Get("X-Goog-Upload-Header-Content-Length") != strconv.Itoa(2*uploadGranularity+10) {
+				t.Errorf("Unexpected start request: %s %v", r.URL.Path, r.Header)
+			}
+			w.Header().Set("X-Goog-Upload-URL", server.URL+"/session/1")
+		case "query":
+			w.Header().Set("X-Goog-Upload-Size-Received", strconv.Itoa(len(*received)))
+		case "upload", "upload, finalize":
+			offset, _ := strconv.ParseInt(r.Header.Get("X-Goog-Upload-Offset"), 10, 64)
+			if offset == failOffset {
+				failOffset = -1
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error": {"code": 503, "message": "try again", "status": "UNAVAILABLE"}}`))
+				return
+			}
+			if offset != int64(len(*received)) {
+				t.Errorf("Unexpected offset %d, received %d", offset, len(*received))
+			}
+			data, _ := io.ReadAll(r.Body)
+			*received = append(*received, data...)
+			if r.Header.Get("X-Goog-Upload-Command") == "upload, finalize" {
+				sum := sha256.Sum256(*received)
+				_, _ = w.Write([]byte(`{"file": {"name": "files/abc", "mimeType": "text/plain", "sizeBytes": "` +
+					strconv.Itoa(len(*received)) + `", "uri": "https://example.com/v1beta/files/abc", "sha256Hash": "` +
+					base64.StdEncoding.EncodeToString(sum[:]) + `"}}`))
+			}
+		default:
+			t.Errorf("Unexpected command %q", r.Header.Get("X-Goog-Upload-Command"))
+		}
+	}))
+	return server
+}
+
+func TestUploadFile(t *testing.T) {
+	var received []byte
+	server := resumableServer(t, &received, uploadGranularity)
+	defer server.Close()
+	client := &Client{provider: "gemini", apiKey: "test-key", baseURL: server.URL}
+
+	content := strings.Repeat("x", 2*uploadGranularity+10)
+	upload := llm.NewFileUpload(strings.NewReader(content), int64(len(content)), "notes.txt", "text/plain")
+	upload.ChunkSize = uploadGranularity + 1 // rounded down to the granularity
+	_, err := client.UploadFile(context.Background(), upload)
+
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "upload_interrupted" || llmErr.Upload.Offset != uploadGranularity {
+		t.Fatalf("Expected an upload interrupted after the first chunk, got %v", err)
+	}
+
+	upload = llm.NewFileUpload(strings.NewReader(content), int64(len(content)), "notes.txt", "text/plain")
+	upload.Resume = &llm.UploadSession{URL: llmErr.Upload.URL}
+	file, err := client.UploadFile(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.ID != "files/abc" || file.URI == "" || file.Size != int64(len(content)) || string(received) != content {
+		t.Errorf("Unexpected file: %+v", file)
+	}
+}
+
+func TestRemoteDigest(t *testing.T) {
+	sum := sha256.Sum256([]byte("data"))
+	hexDigest := "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"
+	if got := remoteDigest(base64.StdEncoding.EncodeToString(sum[:])); got != hexDigest {
+		t.Errorf("Unexpected digest of raw hash: %s", got)
+	}
+	if got := remoteDigest(base64.StdEncoding.EncodeToString([]byte(hexDigest))); got != hexDigest {
+		t.Errorf("Unexpected digest of hex hash: %s", got)
+	}
+	if remoteDigest("") != "" || remoteDigest("not base64!") != "" {
+		t.Error("Expected no digest for invalid hashes")
+	}
+}
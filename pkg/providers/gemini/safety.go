@@ -0,0 +1,201 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// safetyExtraPrefix prefixes the ClientConfig.Extra keys holding safety thresholds
+const safetyExtraPrefix = "safety_"
+
+// harmCategoryPrefix is the common prefix of the Gemini harm category names
+const harmCategoryPrefix = "HARM_CATEGORY_"
+
+// SafetySettings are the per-category block thresholds applied to every request of a
+// client (e.g. genai.HarmCategoryHarassment: genai.HarmBlockThresholdBlockOnlyHigh).
+// Categories without a threshold use the Gemini defaults. They are set with
+// ClientConfig.Extra, one "safety_<category>" entry per category; see Extra.
+type SafetySettings map[genai.HarmCategory]genai.HarmBlockThreshold
+
+// Extra returns the ClientConfig.Extra entries that configure these settings, keyed by
+// the lowercased category name without its prefix (e.g. "safety_dangerous_content").
+func (s SafetySettings) Extra() map[string]string {
+	extra := make(map[string]string, len(s))
+	for category, threshold := range s {
+		name := strings.ToLower(strings.TrimPrefix(string(category), harmCategoryPrefix))
+		extra[safetyExtraPrefix+name] = string(threshold)
+	}
+	return extra
+}
+
+// genaiSettings returns the settings in the request config format, sorted by category
+func (s SafetySettings) genaiSettings() []*genai.SafetySetting {
+	if len(s) == 0 {
+		return nil
+	}
+	settings := make([]*genai.SafetySetting, 0, len(s))
+	for category, threshold := range s {
+		settings = append(settings, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Category < settings[j].Category })
+	return settings
+}
+
+// safetySettingsFromExtra parses the "safety_<category>" entries of ClientConfig.Extra.
+// Thresholds are case-insensitive Gemini names ("BLOCK_ONLY_HIGH", "off"...).
+func safetySettingsFromExtra(extra map[string]string) (SafetySettings, error) {
+	settings := SafetySettings{}
+	for key, value := range extra {
+		name, ok := strings.CutPrefix(key, safetyExtraPrefix)
+		if !ok {
+			continue
+		}
+		category := genai.HarmCategory(harmCategoryPrefix + strings.ToUpper(name))
+		if !knownHarmCategories[category] {
+			return nil, &llm.Error{
+				Code:    "invalid_config",
+				Message: fmt.Sprintf("unknown Gemini harm category in %s", key),
+				Type:    "validation_error",
+			}
+		}
+		threshold := genai.HarmBlockThreshold(strings.ToUpper(strings.TrimSpace(value)))
+		if !knownBlockThresholds[threshold] {
+			return nil, &llm.Error{
+				Code:    "invalid_config",
+				Message: fmt.Sprintf("invalid Gemini block threshold %q for %s", value, key),
+				Type:    "validation_error",
+			}
+		}
+		settings[category] = threshold
+	}
+	return settings, nil
+}
+
+var knownHarmCategories = map[genai.HarmCategory]bool{
+	genai.HarmCategoryHateSpeech:            true,
+	genai.HarmCategoryDangerousContent:      true,
+	genai.HarmCategoryHarassment:            true,
+	genai.HarmCategorySexuallyExplicit:      true,
+	genai.HarmCategoryCivicIntegrity:        true,
+	genai.HarmCategoryImageHate:             true,
+	genai.HarmCategoryImageDangerousContent: true,
+	genai.HarmCategoryImageHarassment:       true,
+	genai.HarmCategoryImageSexuallyExplicit: true,
+}
+
+var knownBlockThresholds = map[genai.HarmBlockThreshold]bool{
+	genai.HarmBlockThresholdBlockLowAndAbove:    true,
+	genai.HarmBlockThresholdBlockMediumAndAbove: true,
+	genai.HarmBlockThresholdBlockOnlyHigh:       true,
+	genai.HarmBlockThresholdBlockNone:           true,
+	genai.HarmBlockThresholdOff:                 true,
+}
+
+// blockingFinishReasons are the finish reasons of candidates withheld by the filters
+var blockingFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:                 true,
+	genai.FinishReasonBlocklist:              true,
+	genai.FinishReasonProhibitedContent:      true,
+	genai.FinishReasonSPII:                   true,
+	genai.FinishReasonImageSafety:            true,
+	genai.FinishReasonImageProhibitedContent: true,
+}
+
+// blockedError returns a "content_filtered" error when the prompt or the first candidate
+// of a response was blocked by the safety filters, and nil otherwise
+func blockedError(resp *genai.GenerateContentResponse) *llm.Error {
+	if resp == nil {
+		return nil
+	}
+
+	var info *llm.ContentFilterInfo
+	var message string
+	switch {
+	case resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "":
+		feedback := resp.PromptFeedback
+		info = &llm.ContentFilterInfo{
+			Stage:      "prompt",
+			Reason:     string(feedback.BlockReason),
+			Categories: filterCategories(feedback.SafetyRatings),
+		}
+		message = "Gemini blocked the prompt"
+		if feedback.BlockReasonMessage != "" {
+			message += ": " + feedback.BlockReasonMessage
+		}
+	case len(resp.Candidates) > 0 && blockingFinishReasons[resp.Candidates[0].FinishReason]:
+		candidate := resp.Candidates[0]
+		info = &llm.ContentFilterInfo{
+			Stage:      "response",
+			Reason:     string(candidate.FinishReason),
+			Categories: filterCategories(candidate.SafetyRatings),
+		}
+		message = "Gemini blocked the response"
+		if candidate.FinishMessage != "" {
+			message += ": " + candidate.FinishMessage
+		}
+	default:
+		return nil
+	}
+
+	reason := info.Reason
+	if blocked := info.BlockedCategories(); len(blocked) > 0 {
+		reason += " " + strings.Join(blocked, ", ")
+	}
+	return &llm.Error{
+		Code:          "content_filtered",
+		Message:       fmt.Sprintf("%s (%s)", message, reason),
+		Type:          "validation_error",
+		StatusCode:    400,
+		ContentFilter: info,
+	}
+}
+
+// filterCategories converts the Gemini safety ratings
+func filterCategories(ratings []*genai.SafetyRating) []llm.ContentFilterCategory {
+	var categories []llm.ContentFilterCategory
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		categories = append(categories, llm.ContentFilterCategory{
+			Category:    string(rating.Category),
+			Probability: string(rating.Probability),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return categories
+}
+
+// safetyOption holds the safety thresholds of a request
+type safetyOption struct {
+	settings SafetySettings
+}
+
+func (safetyOption) Provider() string { return "gemini" }
+
+// WithSafety returns a request option that sets block thresholds for the request. They
+// override the client thresholds of the same categories.
+func WithSafety(settings SafetySettings) llm.ProviderOption {
+	return safetyOption{settings: settings}
+}
+
+// requestSafetySettings returns the client thresholds merged with those of the request
+func (c *Client) requestSafetySettings(req llm.ChatRequest) SafetySettings {
+	option, ok := llm.FindProviderOption[safetyOption](req)
+	if !ok || len(option.settings) == 0 {
+		return c.safetySettings
+	}
+	settings := make(SafetySettings, len(c.safetySettings)+len(option.settings))
+	for category, threshold := range c.safetySettings {
+		settings[category] = threshold
+	}
+	for category, threshold := range option.settings {
+		settings[category] = threshold
+	}
+	return settings
+}
@@ -0,0 +1,148 @@
+package gemini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestSafetySettings_Extra(t *testing.T) {
+	settings := SafetySettings{
+		genai.HarmCategoryHarassment:       genai.HarmBlockThresholdBlockOnlyHigh,
+		genai.HarmCategoryDangerousContent: genai.HarmBlockThresholdBlockNone,
+	}
+
+	extra := settings.Extra()
+	want := map[string]string{
+		"safety_harassment":        "BLOCK_ONLY_HIGH",
+		"safety_dangerous_content": "BLOCK_NONE",
+	}
+	if !reflect.DeepEqual(extra, want) {
+		t.Errorf("Unexpected extra: %v", extra)
+	}
+
+	extra["safety_hate_speech"] = "block_low_and_above"
+	extra["unrelated"] = "ignored"
+	parsed, err := safetySettingsFromExtra(extra)
+	if err != nil {
+		t.Fatalf("safetySettingsFromExtra failed: %v", err)
+	}
+	if len(parsed) != 3 || parsed[genai.HarmCategoryHateSpeech] != genai.HarmBlockThresholdBlockLowAndAbove {
+		t.Errorf("Unexpected settings: %v", parsed)
+	}
+
+	genaiSettings := parsed.genaiSettings()
+	if len(genaiSettings) != 3 || genaiSettings[0].Category != genai.HarmCategoryDangerousContent {
+		t.Errorf("Expected settings sorted by category, got %+v", genaiSettings[0])
+	}
+}
+
+func TestWithSafety(t *testing.T) {
+	client := &Client{safetySettings: SafetySettings{
+		genai.HarmCategoryHarassment: genai.HarmBlockThresholdBlockOnlyHigh,
+		genai.HarmCategoryHateSpeech: genai.HarmBlockThresholdBlockOnlyHigh,
+	}}
+	req := llm.ChatRequest{}.WithProviderOptions(WithSafety(SafetySettings{
+		genai.HarmCategoryHarassment:       genai.HarmBlockThresholdBlockNone,
+		genai.HarmCategoryDangerousContent: genai.HarmBlockThresholdOff,
+	}))
+
+	settings := client.requestSafetySettings(req)
+	want := SafetySettings{
+		genai.HarmCategoryHarassment:       genai.HarmBlockThresholdBlockNone,
+		genai.HarmCategoryHateSpeech:       genai.HarmBlockThresholdBlockOnlyHigh,
+		genai.HarmCategoryDangerousContent: genai.HarmBlockThresholdOff,
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Unexpected request settings: %v", settings)
+	}
+	if client.safetySettings[genai.HarmCategoryHarassment] != genai.HarmBlockThresholdBlockOnlyHigh {
+		t.Error("Expected the client settings to be untouched")
+	}
+}
+
+func TestSafetySettingsFromExtra_Invalid(t *testing.T) {
+	var llmErr *llm.Error
+	for _, extra := range []map[string]string{
+		{"safety_gossip": "BLOCK_NONE"},
+		{"safety_harassment": "BLOCK_SOME"},
+	} {
+		if _, err := safetySettingsFromExtra(extra); !errors.As(err, &llmErr) || llmErr.Code != "invalid_config" {
+			t.Errorf("Expected invalid_config for %v, got %v", extra, err)
+		}
+	}
+}
+
+func TestConvertResponse_Blocked(t *testing.T) {
+	client := &Client{model: "gemini-1.5-flash", provider: "gemini"}
+
+	tests := []struct {
+		name     string
+		resp     *genai.GenerateContentResponse
+		stage    string
+		reason   string
+		category string
+	}{
+		{
+			name: "prompt",
+			resp: &genai.GenerateContentResponse{
+				PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+					BlockReason: genai.BlockedReasonSafety,
+					SafetyRatings: []*genai.SafetyRating{
+						{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+						{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh, Blocked: true},
+					},
+				},
+			},
+			stage:    "prompt",
+			reason:   "SAFETY",
+			category: "HARM_CATEGORY_DANGEROUS_CONTENT",
+		},
+		{
+			name: "response",
+			resp: &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{
+					FinishReason: genai.FinishReasonSafety,
+					SafetyRatings: []*genai.SafetyRating{
+						{Category: genai.HarmCategoryHateSpeech, Probability: genai.HarmProbabilityMedium, Blocked: true},
+					},
+				}},
+			},
+			stage:    "response",
+			reason:   "SAFETY",
+			category: "HARM_CATEGORY_HATE_SPEECH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.convertResponse(tt.resp)
+			var llmErr *llm.Error
+			if !errors.As(err, &llmErr) || llmErr.Code != "content_filtered" || llmErr.ContentFilter == nil {
+				t.Fatalf("Expected a content_filtered error, got %v", err)
+			}
+			info := llmErr.ContentFilter
+			if info.Stage != tt.stage || info.Reason != tt.reason {
+				t.Errorf("Unexpected stage %q or reason %q", info.Stage, info.Reason)
+			}
+			if blocked := info.BlockedCategories(); len(blocked) != 1 || blocked[0] != tt.category {
+				t.Errorf("Unexpected blocked categories: %v", blocked)
+			}
+		})
+	}
+
+	// Regular responses are converted as before
+	resp, err := client.convertResponse(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromText("Hello", genai.RoleModel),
+			FinishReason: genai.FinishReasonStop,
+		}},
+	})
+	if err != nil || resp.Choices[0].Message.GetText() != "Hello" {
+		t.Errorf("Unexpected response %+v, %v", resp, err)
+	}
+}
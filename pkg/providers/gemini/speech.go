@@ -0,0 +1,156 @@
+package gemini
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// DefaultSpeechModel is the speech model used when none is given
+	DefaultSpeechModel = "gemini-2.5-flash-preview-tts"
+
+	// DefaultSpeechVoice is the prebuilt voice used when none is given
+	DefaultSpeechVoice = "Kore"
+
+	// speechSampleRate is the sample rate of the PCM audio of the speech models
+	speechSampleRate = 24000
+)
+
+// Speak implements llm.SpeechClient with the speech models. The chat model of the client
+// is not used: the model is given in the options. The models produce 24kHz mono PCM,
+// returned as is for the default and PCM formats or in a WAV container for the WAV
+// format; other formats are not supported. Speed is not supported either: the pace is
+// set with the instructions, as the tone or the accent.
+func (c *Client) Speak(ctx context.Context, text string, opts llm.SpeechOptions) (*llm.AudioContent, error) {
+	model, contents, config, err := c.speechRequest(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.genai.Models.GenerateContent(ctx, model, contents, config)
+	if err != nil {
+		err = c.convertError(err)
+	} else if blocked := blockedError(resp); blocked != nil {
+		err = blocked
+	}
+	c.stats.Track(ctx, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	pcm := speechData(resp)
+	if len(pcm) == 0 {
+		return nil, &llm.Error{Code: "invalid_response", Message: model + " returned no audio", Type: "api_error"}
+	}
+	if opts.Format == llm.AudioFormatWAV {
+		return &llm.AudioContent{Data: llm.PCMToWAV(pcm, speechSampleRate, 1), MimeType: llm.AudioFormatWAV.MimeType()}, nil
+	}
+	return &llm.AudioContent{Data: pcm, MimeType: llm.AudioFormatPCM.MimeType()}, nil
+}
+
+// StreamSpeech implements llm.SpeechClient, sending the PCM audio of each response of the
+// stream. Only the default and PCM formats are supported, as a WAV header needs the size
+// of the whole recording.
+func (c *Client) StreamSpeech(ctx context.Context, text string, opts llm.SpeechOptions) (<-chan llm.SpeechChunk, error) {
+	if opts.Format == llm.AudioFormatWAV {
+		return nil, llm.UnsupportedAudioFormatError(c.provider, opts.Format)
+	}
+	model, contents, config, err := c.speechRequest(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	chunks := make(chan llm.SpeechChunk, llm.DefaultStreamBufferSize)
+	go func() {
+		defer close(chunks)
+
+		var streamErr *llm.Error
+		defer func() {
+			if streamErr != nil {
+				c.stats.Track(ctx, start, streamErr)
+				select {
+				case chunks <- llm.SpeechChunk{Error: streamErr}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			c.stats.Track(ctx, start, nil)
+		}()
+
+		for response, err := range c.genai.Models.GenerateContentStream(ctx, model, contents, config) {
+			if err != nil {
+				streamErr = c.convertError(err)
+				return
+			}
+			if blocked := blockedError(response); blocked != nil {
+				streamErr = blocked
+				return
+			}
+			if data := speechData(response); len(data) > 0 {
+				select {
+				case chunks <- llm.SpeechChunk{Data: data}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// speechRequest creates the model, contents and configuration of a speech request
+func (c *Client) speechRequest(text string, opts llm.SpeechOptions) (string, []*genai.Content, *genai.GenerateContentConfig, error) {
+	if text == "" {
+		return "", nil, nil, &llm.Error{Code: "invalid_request", Message: "no text to speak", Type: "validation_error"}
+	}
+	switch opts.Format {
+	case "", llm.AudioFormatPCM, llm.AudioFormatWAV:
+	default:
+		return "", nil, nil, llm.UnsupportedAudioFormatError(c.provider, opts.Format)
+	}
+	model := opts.Model
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	voice := opts.Voice
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+
+	// The speech models take the style as a natural language instruction before the text
+	prompt := text
+	if opts.Instructions != "" {
+		prompt = opts.Instructions + ": " + text
+	}
+	contents := []*genai.Content{genai.NewContentFromText(prompt, genai.RoleUser)}
+	config := &genai.GenerateContentConfig{
+		ResponseModalities: []string{"AUDIO"},
+		SpeechConfig: &genai.SpeechConfig{
+			VoiceConfig: &genai.VoiceConfig{
+				PrebuiltVoiceConfig: &genai.PrebuiltVoiceConfig{VoiceName: voice},
+			},
+		},
+		SafetySettings: c.safetySettings.genaiSettings(),
+	}
+	return model, contents, config, nil
+}
+
+// speechData returns the audio of the inline data of a response
+func speechData(resp *genai.GenerateContentResponse) []byte {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil
+	}
+	var data []byte
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part.InlineData != nil {
+			data = append(data, part.InlineData.Data...)
+		}
+	}
+	return data
+}
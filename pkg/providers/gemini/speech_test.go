@@ -0,0 +1,121 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// audioResponse is a response with PCM audio as inline data
+func audioResponse(pcm []byte) map[string]any {
+	return map[string]any{
+		"candidates": []any{map[string]any{
+			"content": map[string]any{"role": "model", "parts": []any{map[string]any{
+				"inlineData": map[string]any{"mimeType": "audio/L16;codec=pcm;rate=24000", "data": base64.StdEncoding.EncodeToString(pcm)},
+			}}},
+		}},
+	}
+}
+
+func TestSpeak(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+			GenerationConfig struct {
+				ResponseModalities []string `json:"responseModalities"`
+				SpeechConfig       struct {
+					VoiceConfig struct {
+						PrebuiltVoiceConfig struct {
+							VoiceName string `json:"voiceName"`
+						} `json:"prebuiltVoiceConfig"`
+					} `json:"voiceConfig"`
+				} `json:"speechConfig"`
+			} `json:"generationConfig"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Invalid request: %v", err)
+		}
+		if len(body.GenerationConfig.ResponseModalities) != 1 || body.GenerationConfig.ResponseModalities[0] != "AUDIO" {
+			t.Errorf("Expected the audio modality, got %v", body.GenerationConfig.ResponseModalities)
+		}
+		voice := body.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/models/"+DefaultSpeechModel+":generateContent"):
+			if voice != DefaultSpeechVoice || body.Contents[0].Parts[0].Text != "Hello" {
+				t.Errorf("Unexpected request: voice %q, %+v", voice, body.Contents)
+			}
+			_ = json.NewEncoder(w).Encode(audioResponse([]byte{1, 2, 3, 4}))
+		case strings.HasSuffix(r.URL.Path, "/models/gemini-2.5-pro-preview-tts:streamGenerateContent"):
+			if voice != "Puck" || body.Contents[0].Parts[0].Text != "Say cheerfully: Hello" {
+				t.Errorf("Unexpected request: voice %q, %+v", voice, body.Contents)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, pcm := range [][]byte{{1, 2}, {3, 4}} {
+				data, _ := json.Marshal(audioResponse(pcm))
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{Provider: "gemini", APIKey: "test-key", Model: "gemini-2.0-flash", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	var speaker llm.SpeechClient = client
+
+	speech, err := speaker.Speak(context.Background(), "Hello", llm.SpeechOptions{})
+	if err != nil {
+		t.Fatalf("Speak failed: %v", err)
+	}
+	if !bytes.Equal(speech.Data, []byte{1, 2, 3, 4}) || speech.MimeType != "audio/pcm" {
+		t.Errorf("Unexpected speech: %v (%s)", speech.Data, speech.MimeType)
+	}
+
+	speech, err = speaker.Speak(context.Background(), "Hello", llm.SpeechOptions{Format: llm.AudioFormatWAV})
+	if err != nil {
+		t.Fatalf("Speak failed: %v", err)
+	}
+	if len(speech.Data) != 48 || string(speech.Data[:4]) != "RIFF" || speech.MimeType != "audio/wav" {
+		t.Errorf("Unexpected WAV speech: %d bytes (%s)", len(speech.Data), speech.MimeType)
+	}
+
+	opts := llm.SpeechOptions{Model: "gemini-2.5-pro-preview-tts", Voice: "Puck", Instructions: "Say cheerfully"}
+	chunks, err := speaker.StreamSpeech(context.Background(), "Hello", opts)
+	if err != nil {
+		t.Fatalf("StreamSpeech failed: %v", err)
+	}
+	streamed, err := llm.CollectSpeech(chunks, llm.AudioFormatPCM)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if !bytes.Equal(streamed.Data, []byte{1, 2, 3, 4}) {
+		t.Errorf("Unexpected streamed speech: %v", streamed.Data)
+	}
+
+	var llmErr *llm.Error
+	_, err = speaker.Speak(context.Background(), "Hello", llm.SpeechOptions{Format: llm.AudioFormatMP3})
+	if !errors.As(err, &llmErr) || llmErr.Code != "unsupported_audio_format" {
+		t.Errorf("Expected an unsupported_audio_format error, got %v", err)
+	}
+	_, err = speaker.StreamSpeech(context.Background(), "Hello", llm.SpeechOptions{Format: llm.AudioFormatWAV})
+	if !errors.As(err, &llmErr) || llmErr.Code != "unsupported_audio_format" {
+		t.Errorf("Expected an unsupported_audio_format error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// maxInlineAudioSize is the largest recording sent inline: requests are limited to 20MB
+const maxInlineAudioSize = 20 * 1024 * 1024
+
+// transcriptionPrompt asks for the transcript in the format of transcriptionSchema
+const transcriptionPrompt = "Generate a verbatim transcript of the speech in this audio. " +
+	"Give the ISO-639-1 code of the spoken language, and split the transcript in segments " +
+	"with their start and end times in seconds from the beginning of the audio."
+
+// transcriptionSchema is the response schema of the transcriptions
+var transcriptionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"text":     map[string]interface{}{"type": "string"},
+		"language": map[string]interface{}{"type": "string"},
+		"segments": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"start": map[string]interface{}{"type": "number"},
+					"end":   map[string]interface{}{"type": "number"},
+					"text":  map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"start", "end", "text"},
+			},
+		},
+	},
+	"required": []interface{}{"text", "language", "segments"},
+}
+
+// Transcribe implements llm.TranscriptionClient by asking the model (the model of the
+// client unless another one is given) for a transcript with a response schema. The
+// segment times are estimated by the model, so they are less precise than those of
+// dedicated speech models. Recordings are sent inline, up to 20MB.
+func (c *Client) Transcribe(ctx context.Context, audio *llm.AudioContent, opts llm.TranscriptionOptions) (*llm.Transcription, error) {
+	if err := audio.Validate(); err != nil {
+		return nil, err
+	}
+	if audio.Size() > maxInlineAudioSize {
+		return nil, &llm.Error{
+			Code:    "invalid_audio",
+			Message: fmt.Sprintf("audio of %d bytes exceeds the %d bytes of inline data", audio.Size(), maxInlineAudioSize),
+			Type:    "validation_error",
+		}
+	}
+	model := opts.Model
+	if model == "" {
+		model = c.model
+	}
+
+	prompt := transcriptionPrompt
+	if opts.Language != "" {
+		prompt += fmt.Sprintf(" The speech is in the language with the ISO-639-1 code %q.", opts.Language)
+	}
+	if opts.Prompt != "" {
+		prompt += "\n\n" + opts.Prompt
+	}
+	config := &genai.GenerateContentConfig{
+		ResponseMIMEType:   "application/json",
+		ResponseJsonSchema: transcriptionSchema,
+		SafetySettings:     c.safetySettings.genaiSettings(),
+	}
+	if opts.Temperature != nil {
+		config.Temperature = opts.Temperature
+	}
+	contents := []*genai.Content{{
+		Role:  genai.RoleUser,
+		Parts: []*genai.Part{genai.NewPartFromBytes(audio.Data, audio.MimeType), genai.NewPartFromText(prompt)},
+	}}
+
+	start := time.Now()
+	resp, err := c.genai.Models.GenerateContent(ctx, model, contents, config)
+	if err != nil {
+		err = c.convertError(err)
+	} else if blocked := blockedError(resp); blocked != nil {
+		err = blocked
+	}
+	c.stats.Track(ctx, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		return nil, &llm.Error{
+			Code:    "invalid_response",
+			Message: fmt.Sprintf("invalid transcript returned by %s: %v", model, err),
+			Type:    "api_error",
+		}
+	}
+
+	transcription := &llm.Transcription{
+		Text:     strings.TrimSpace(result.Text),
+		Language: result.Language,
+		Model:    model,
+	}
+	if transcription.Language == "" {
+		transcription.Language = opts.Language
+	}
+	for _, segment := range result.Segments {
+		transcription.Segments = append(transcription.Segments, llm.TranscriptionSegment{
+			Start: time.Duration(segment.Start * float64(time.Second)),
+			End:   time.Duration(segment.End * float64(time.Second)),
+			Text:  strings.TrimSpace(segment.Text),
+		})
+	}
+	return transcription, nil
+}
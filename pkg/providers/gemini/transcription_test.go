@@ -0,0 +1,80 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/gemini-2.0-flash:generateContent") {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text       string `json:"text"`
+					InlineData *struct {
+						MimeType string `json:"mimeType"`
+					} `json:"inlineData"`
+				} `json:"parts"`
+			} `json:"contents"`
+			GenerationConfig struct {
+				ResponseMimeType   string         `json:"responseMimeType"`
+				ResponseJsonSchema map[string]any `json:"responseJsonSchema"`
+			} `json:"generationConfig"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Invalid request: %v", err)
+		}
+		parts := body.Contents[0].Parts
+		if len(parts) != 2 || parts[0].InlineData == nil || parts[0].InlineData.MimeType != "audio/mpeg" ||
+			!strings.Contains(parts[1].Text, `"fr"`) || !strings.Contains(parts[1].Text, "Names: Zoé") {
+			t.Errorf("Unexpected parts: %+v", parts)
+		}
+		if body.GenerationConfig.ResponseMimeType != "application/json" || body.GenerationConfig.ResponseJsonSchema == nil {
+			t.Errorf("Expected a response schema, got %+v", body.GenerationConfig)
+		}
+
+		transcript := `{"text": "Bonjour Zoé. ", "language": "fr", "segments": [{"start": 0, "end": 1.5, "text": "Bonjour Zoé."}]}`
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"candidates": []any{map[string]any{
+				"content":      map[string]any{"role": "model", "parts": []any{map[string]any{"text": transcript}}},
+				"finishReason": "STOP",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{Provider: "gemini", APIKey: "test-key", Model: "gemini-2.0-flash", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	var transcriber llm.TranscriptionClient = client
+
+	audio := llm.NewAudioContentFromBytes([]byte{0xff, 0xfb}, "audio/mpeg")
+	transcription, err := transcriber.Transcribe(context.Background(), audio, llm.TranscriptionOptions{Language: "fr", Prompt: "Names: Zoé"})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if transcription.Text != "Bonjour Zoé." || transcription.Language != "fr" || transcription.Model != "gemini-2.0-flash" {
+		t.Errorf("Unexpected transcription: %+v", transcription)
+	}
+	if len(transcription.Segments) != 1 || transcription.Segments[0].End != 1500*time.Millisecond {
+		t.Errorf("Unexpected segments: %+v", transcription.Segments)
+	}
+
+	var llmErr *llm.Error
+	_, err = transcriber.Transcribe(context.Background(), llm.NewAudioContentFromBytes([]byte{1}, "video/mp4"), llm.TranscriptionOptions{})
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_audio" {
+		t.Errorf("Expected an invalid_audio error, got %v", err)
+	}
+}
@@ -0,0 +1,712 @@
+package oaicompat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// defaultTimeout is used when the configuration does not set a timeout
+const defaultTimeout = 60 * time.Second
+
+// Config describes an OpenAI-compatible API and the provider serving it
+type Config struct {
+	// Provider is the name of the provider, e.g. "fireworks"
+	Provider string
+
+	// Name names the service in error messages, e.g. "Fireworks AI"
+	Name string
+
+	// DefaultBaseURL is used when the client configuration sets no BaseURL
+	DefaultBaseURL string
+
+	// ToolModelMarkers and VisionModelMarkers are substrings of the lower case names of
+	// the models supporting tools and image inputs. Extra["supports_tools"] and
+	// Extra["supports_vision"] override them.
+	ToolModelMarkers   []string
+	VisionModelMarkers []string
+
+	// MaxTokens is the context length of the model
+	MaxTokens int
+
+	// NativeFormat reports whether the API enforces a response format natively, the
+	// others being emulated with prompt instructions. Only JSON formats are native when
+	// nil.
+	NativeFormat func(format *llm.ResponseFormat) bool
+
+	// BuildRequest returns the body sent for a request, adding the fields of the provider
+	// to the common ones. The common body is sent when nil.
+	BuildRequest func(req llm.ChatRequest, body Request) (interface{}, error)
+
+	// ConvertResponse completes a converted response with the fields of the provider,
+	// decoded from the body of the response
+	ConvertResponse func(data []byte, resp *llm.ChatResponse)
+
+	// ChatPath is the path of the chat completion requests ("/chat/completions" when
+	// empty). StreamPath is the path of the streaming requests, for the APIs streaming on
+	// a separate endpoint instead of with the stream field.
+	ChatPath   string
+	StreamPath string
+
+	// HealthPath is requested by the health checks ("/models" when empty)
+	HealthPath string
+
+	// Query holds the parameters added to the URL of every request, e.g. a version
+	Query url.Values
+
+	// Token returns the bearer token of a request, instead of the API key. When
+	// Invalidate is set, a request rejected with 401 drops its token and is retried once
+	// with a new one, since tokens may expire before their announced expiration.
+	Token      func(ctx context.Context) (string, error)
+	Invalidate func(token string)
+
+	// ParseError decodes the errors of the API instead of ParseError
+	ParseError func(statusCode int, data []byte) *llm.Error
+}
+
+// Client implements the llm.Client interface for an OpenAI-compatible API. Providers
+// embed it in their clients.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	apiKey     string
+	baseURL    string
+	model      string
+
+	supportsTools  bool
+	supportsVision bool
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
+
+	// Health check caching
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
+}
+
+// NewClient creates a client for the API described by api. The API key is optional and
+// sent as a bearer token when set.
+func NewClient(config llm.ClientConfig, api Config) (*Client, error) {
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Model == "" {
+		return nil, &llm.Error{
+			Code:    "missing_model",
+			Message: fmt.Sprintf("model is required for %s client", api.Name),
+			Type:    "validation_error",
+		}
+	}
+
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = api.DefaultBaseURL
+	}
+	if api.ChatPath == "" {
+		api.ChatPath = "/chat/completions"
+	}
+	if api.HealthPath == "" {
+		api.HealthPath = "/models"
+	}
+	if api.ParseError == nil {
+		api.ParseError = ParseError
+	}
+
+	model := strings.ToLower(config.Model)
+	supportsTools := containsAny(model, api.ToolModelMarkers)
+	supportsVision := containsAny(model, api.VisionModelMarkers)
+	if config.Extra != nil {
+		// Allows enabling capabilities for models not known to support them yet
+		if tools, ok := config.Extra["supports_tools"]; ok {
+			supportsTools = tools == "true"
+		}
+		if vision, ok := config.Extra["supports_vision"]; ok {
+			supportsVision = vision == "true"
+		}
+	}
+
+	return &Client{
+		config:         api,
+		streamBuffer:   config.StreamBuffer,
+		stats:          llm.NewHealthStatsWithClock(config.Clock),
+		clock:          config.GetClock(),
+		httpClient:     NewHTTPClient(config),
+		apiKey:         config.APIKey,
+		baseURL:        baseURL,
+		model:          config.Model,
+		supportsTools:  supportsTools,
+		supportsVision: supportsVision,
+	}, nil
+}
+
+// NewHTTPClient returns an HTTP client with the timeout of a configuration (60 seconds
+// when unset), answering the requests of dry runs without sending them
+func NewHTTPClient(config llm.ClientConfig) *http.Client {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &http.Client{Timeout: timeout, Transport: &llm.DryRunTransport{}}
+}
+
+// JSONOrGrammar is a Config.NativeFormat for the APIs enforcing JSON formats (with or
+// without schema) and grammars natively
+func JSONOrGrammar(format *llm.ResponseFormat) bool {
+	return format.IsJSON() || (format != nil && format.Type == llm.ResponseFormatGrammar)
+}
+
+// ChatCompletion performs a chat completion request
+func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.chatCompletion(ctx, req)
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	body, warnings, err := c.convertRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.do(ctx, http.MethodPost, c.config.ChatPath, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, &llm.Error{
+			Code:    "invalid_response",
+			Message: fmt.Sprintf("failed to decode %s response: %v", c.config.Name, err),
+			Type:    "api_error",
+		}
+	}
+
+	response := c.convertResponse(resp)
+	if c.config.ConvertResponse != nil {
+		c.config.ConvertResponse(data, response)
+	}
+	response.Warnings = warnings
+	// The response types are internal, so the native value is the body itself
+	response.Raw = llm.NewRawResponse(json.RawMessage(data), data)
+	return response, nil
+}
+
+// StreamChatCompletion performs a streaming chat completion request
+func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request, answered with
+// Server-Sent Events
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	body, _, err := c.convertRequest(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	path := c.config.ChatPath
+	if c.config.StreamPath != "" {
+		path = c.config.StreamPath
+	}
+	httpResp, err := c.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
+
+	go func() {
+		defer emitter.Close()
+		defer func() { _ = httpResp.Body.Close() }()
+
+		finishReason := "stop"
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "" {
+				continue
+			}
+			if data == "[DONE]" {
+				break
+			}
+
+			var chunk StreamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
+					Code:    "invalid_response",
+					Message: fmt.Sprintf("failed to decode %s stream chunk: %v", c.config.Name, err),
+					Type:    "api_error",
+				})))
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				// Errors are sent as chunks without choices
+				if llmErr := c.config.ParseError(0, []byte(data)); llmErr != nil {
+					emitter.Emit(seq.Stamp(llm.NewErrorEvent(llm.RedactError(llmErr))))
+					return
+				}
+				continue
+			}
+
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+
+			delta := &llm.MessageDelta{}
+			if choice.Delta.Content != "" {
+				delta.Content = []llm.MessageContent{llm.NewTextContent(choice.Delta.Content)}
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				toolCallDelta := llm.ToolCallDelta{
+					Index: tc.Index,
+					ID:    tc.ID,
+					Type:  tc.Type,
+				}
+				if tc.Function.Name != "" || tc.Function.Arguments != "" {
+					toolCallDelta.Function = &llm.ToolCallFunctionDelta{
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					}
+				}
+				delta.ToolCalls = append(delta.ToolCalls, toolCallDelta)
+			}
+
+			if len(delta.Content) > 0 || len(delta.ToolCalls) > 0 {
+				if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
+				Code:    "stream_error",
+				Message: fmt.Sprintf("failed to read %s stream: %v", c.config.Name, err),
+				Type:    "api_error",
+			})))
+			return
+		}
+
+		emitter.Emit(seq.Stamp(llm.NewProviderDoneEvent(0, c.config.Provider, finishReason)))
+	}()
+
+	return emitter.Events(), nil
+}
+
+// GetRemote returns information about the remote client
+func (c *Client) GetRemote() llm.ClientRemoteInfo {
+	info := llm.ClientRemoteInfo{
+		Name: c.config.Provider,
+	}
+
+	// Check if we need to refresh the health status
+	now := c.clock.Now()
+	needsRefresh := c.lastHealthCheck == nil ||
+		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
+
+	if needsRefresh {
+		c.stats.Record(0, c.performHealthCheck())
+		c.lastHealthCheck = &now
+	}
+
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
+
+	return info
+}
+
+// performHealthCheck requests the health path, e.g. the list of the models, as a
+// lightweight health check
+func (c *Client) performHealthCheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.do(ctx, http.MethodGet, c.config.HealthPath, nil)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// GetModelInfo returns information about the model being used
+func (c *Client) GetModelInfo() llm.ModelInfo {
+	return llm.ModelInfo{
+		Name:              c.model,
+		Provider:          c.config.Provider,
+		MaxTokens:         c.config.MaxTokens,
+		SupportsTools:     c.supportsTools,
+		SupportsVision:    c.supportsVision,
+		SupportsFiles:     false,
+		SupportsStreaming: true,
+	}
+}
+
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
+// Close cleans up any resources used by the client
+func (c *Client) Close() error {
+	// The idle connections belong to the shared transport, used by other clients
+	return nil
+}
+
+// do sends an authenticated request with an optional JSON body to an API path, and
+// converts non-2xx responses into *llm.Error
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token := c.apiKey
+		if c.config.Token != nil {
+			var err error
+			if token, err = c.config.Token(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(ctx, method, path, body, token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && c.config.Invalidate != nil {
+			_ = resp.Body.Close()
+			c.config.Invalidate(token)
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			defer func() { _ = resp.Body.Close() }()
+			return nil, c.convertError(resp)
+		}
+		return resp, nil
+	}
+}
+
+// send sends a single request with an optional bearer token
+func (c *Client) send(ctx context.Context, method, path string, body interface{}, token string) (*http.Response, error) {
+	var payload *llm.JSONPayload
+	if body != nil {
+		var err error
+		if payload, err = llm.NewJSONPayload(body); err != nil {
+			return nil, &llm.Error{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("failed to encode %s request: %v", c.config.Name, err),
+				Type:    "validation_error",
+			}
+		}
+	}
+
+	endpoint := c.baseURL + path
+	if len(c.config.Query) > 0 {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		endpoint += separator + c.config.Query.Encode()
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = payload
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		if payload != nil {
+			_ = payload.Close()
+		}
+		return nil, &llm.Error{
+			Code:    "invalid_request",
+			Message: fmt.Sprintf("failed to create %s request: %v", c.config.Name, err),
+			Type:    "validation_error",
+		}
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if payload != nil {
+		// The client closes the payload once sent, returning its buffer to the pool
+		httpReq.ContentLength = payload.Len()
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &llm.Error{
+			Code:    "request_failed",
+			Message: fmt.Sprintf("Request failed: %v", err),
+			Type:    "network_error",
+		}
+	}
+	return resp, nil
+}
+
+// convertError converts a failed HTTP response into *llm.Error
+func (c *Client) convertError(resp *http.Response) *llm.Error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	llmErr := c.config.ParseError(resp.StatusCode, data)
+	if llmErr == nil {
+		llmErr = (&APIError{}).LLMError(resp.StatusCode)
+	}
+	if llmErr.Message == "" {
+		llmErr.Message = strings.TrimSpace(string(data))
+	}
+	if llmErr.Message == "" {
+		llmErr.Message = http.StatusText(resp.StatusCode)
+	}
+	llmErr.RateLimit = llm.ParseRateLimitHeaders(resp.Header)
+	return llm.RedactError(llmErr)
+}
+
+// convertRequest converts our ChatRequest to the body sent to the API, with the warnings
+// of the features the conversion degraded
+func (c *Client) convertRequest(ctx context.Context, req llm.ChatRequest, stream bool) (interface{}, []llm.Warning, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nativeFormat := req.ResponseFormat.IsJSON()
+	if c.config.NativeFormat != nil {
+		nativeFormat = c.config.NativeFormat(req.ResponseFormat)
+	}
+	// The API accepts no file attachments: text files are flattened into their message
+	warnings := append(req.ResponseFormatWarnings(nativeFormat),
+		llm.ContentWarnings(req.Messages, llm.MessageTypeFile, llm.WarningFileSentAsText, "was sent as text")...)
+	req, err = req.PrepareResponseFormat(nativeFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := req.ResponseFormat.Lint(); err != nil {
+		return nil, nil, err
+	}
+
+	model := req.Model
+	if model == "" {
+		model = c.model
+	}
+
+	body := Request{
+		Model:       model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Stream:      stream && c.config.StreamPath == "",
+		User:        req.User,
+	}
+
+	for _, msg := range req.Messages {
+		converted, err := c.convertMessage(msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		body.Messages = append(body.Messages, converted)
+	}
+
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, Tool{
+			Type: tool.Type,
+			Function: Function{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+
+	if c.config.BuildRequest == nil {
+		return body, warnings, nil
+	}
+	built, err := c.config.BuildRequest(req, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return built, warnings, nil
+}
+
+// convertMessage converts a message, using content parts only when it carries images
+func (c *Client) convertMessage(msg llm.Message) (Message, error) {
+	converted := Message{
+		Role:       string(msg.Role),
+		ToolCallID: msg.ToolCallID,
+	}
+
+	for _, tc := range msg.ToolCalls {
+		converted.ToolCalls = append(converted.ToolCalls, ToolCall{
+			ID:   tc.ID,
+			Type: tc.Type,
+			Function: ToolCallFunction{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+
+	if !msg.HasContentType(llm.MessageTypeImage) {
+		text, err := c.flattenContent(msg.Content)
+		if err != nil {
+			return Message{}, err
+		}
+		// Assistant messages with tool calls have no content
+		if text != "" || len(converted.ToolCalls) == 0 {
+			converted.Content = text
+		}
+		return converted, nil
+	}
+
+	if !c.supportsVision {
+		return Message{}, &llm.Error{
+			Code:    "vision_not_supported",
+			Message: fmt.Sprintf("model %s does not support image inputs (set Extra[\"supports_vision\"] to override)", c.model),
+			Type:    "validation_error",
+		}
+	}
+
+	var parts []ContentPart
+	for _, content := range msg.Content {
+		switch item := content.(type) {
+		case *llm.ImageContent:
+			parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: imageContentURL(item)}})
+		default:
+			text, err := c.flattenContent([]llm.MessageContent{content})
+			if err != nil {
+				return Message{}, err
+			}
+			if text != "" {
+				parts = append(parts, ContentPart{Type: "text", Text: text})
+			}
+		}
+	}
+	converted.Content = parts
+
+	return converted, nil
+}
+
+// flattenContent joins text content, inlining text files which the API cannot accept as attachments
+func (c *Client) flattenContent(contents []llm.MessageContent) (string, error) {
+	var texts []string
+	for _, content := range contents {
+		switch item := content.(type) {
+		case *llm.TextContent:
+			texts = append(texts, item.GetText())
+		case *llm.FileContent:
+			if !item.HasData() || !isTextMimeType(item.MimeType) || !utf8.Valid(item.Data) {
+				return "", &llm.Error{
+					Code:    "files_not_supported",
+					Message: fmt.Sprintf("%s does not support %s file attachments", c.config.Name, item.MimeType),
+					Type:    "validation_error",
+				}
+			}
+			texts = append(texts, fmt.Sprintf("[File: %s]\n%s", item.Filename, string(item.Data)))
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// convertResponse converts a response of the API to our format
+func (c *Client) convertResponse(resp Response) *llm.ChatResponse {
+	chatResp := &llm.ChatResponse{
+		ID:    resp.ID,
+		Model: resp.Model,
+		Usage: llm.Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}
+
+	for _, choice := range resp.Choices {
+		msg := llm.Message{
+			Role:    llm.MessageRole(choice.Message.Role),
+			Content: []llm.MessageContent{},
+		}
+		if msg.Role == "" {
+			msg.Role = llm.RoleAssistant
+		}
+		if choice.Message.Content != "" {
+			msg.Content = append(msg.Content, llm.NewTextContent(choice.Message.Content))
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: llm.ToolCallFunction{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+
+		chatResp.Choices = append(chatResp.Choices, llm.Choice{
+			Index:           choice.Index,
+			Message:         msg,
+			FinishReason:    llm.NormalizeFinishReason(c.config.Provider, choice.FinishReason),
+			RawFinishReason: choice.FinishReason,
+		})
+	}
+
+	return chatResp
+}
+
+// imageContentURL returns the image URL, or a data URL for inline image data
+func imageContentURL(img *llm.ImageContent) string {
+	if img.HasURL() {
+		return img.URL
+	}
+	return llm.EncodeDataURL(img.MimeType, img.Data)
+}
+
+func isTextMimeType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || mimeType == "application/json"
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package oaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// testConfig describes an API whose "-vision" models accept images, and which adds a
+// "seed" field to the requests
+var testConfig = Config{
+	Provider:           "test",
+	Name:               "Test AI",
+	DefaultBaseURL:     "https://api.test.ai/v1",
+	VisionModelMarkers: []string{"-vision"},
+	BuildRequest: func(req llm.ChatRequest, body Request) (interface{}, error) {
+		return struct {
+			Request
+			Seed int `json:"seed"`
+		}{Request: body, Seed: 42}, nil
+	},
+}
+
+func newTestClient(t *testing.T, model, baseURL string) *Client {
+	t.Helper()
+	client, err := NewClient(llm.ClientConfig{Model: model, BaseURL: baseURL}, testConfig)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestConvertMessage_Multimodal(t *testing.T) {
+	client := newTestClient(t, "test-vision", "")
+
+	msg := llm.Message{
+		Role: llm.RoleUser,
+		Content: []llm.MessageContent{
+			llm.NewTextContent("Describe"),
+			llm.NewImageContentFromBytes([]byte{1, 2, 3}, "image/png"),
+		},
+	}
+	converted, err := client.convertMessage(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parts, ok := converted.Content.([]ContentPart)
+	if !ok || len(parts) != 2 || parts[1].ImageURL == nil || parts[1].ImageURL.URL != "data:image/png;base64,AQID" {
+		t.Errorf("Unexpected content parts: %+v", converted.Content)
+	}
+
+	textOnly := newTestClient(t, "test-text", "")
+	var llmErr *llm.Error
+	if _, err := textOnly.convertMessage(msg); !errors.As(err, &llmErr) || llmErr.Code != "vision_not_supported" {
+		t.Errorf("Expected vision_not_supported, got %v", err)
+	}
+
+	fileMsg := llm.Message{Role: llm.RoleUser, Content: []llm.MessageContent{
+		llm.NewFileContentFromBytes([]byte("a,b"), "data.csv", "text/csv"),
+	}}
+	converted, err = textOnly.convertMessage(fileMsg)
+	if err != nil || !strings.Contains(converted.Content.(string), "[File: data.csv]\na,b") {
+		t.Errorf("Expected inlined text file, got %v (%v)", converted.Content, err)
+	}
+	pdfMsg := llm.Message{Role: llm.RoleUser, Content: []llm.MessageContent{
+		llm.NewFileContentFromBytes([]byte("%PDF"), "doc.pdf", "application/pdf"),
+	}}
+	if _, err := textOnly.convertMessage(pdfMsg); !errors.As(err, &llmErr) || llmErr.Message != "Test AI does not support application/pdf file attachments" {
+		t.Errorf("Expected files_not_supported, got %v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		data       string
+		wantNil    bool
+		wantType   string
+		wantCode   string
+		wantString string
+	}{
+		{name: "envelope", status: 429, data: `{"error": {"type": "", "message": "slow down"}}`, wantType: "rate_limit_error", wantCode: "rate_limit_exceeded", wantString: "slow down"},
+		{name: "plain message", status: 401, data: `{"error": "bad key"}`, wantType: "authentication_error", wantCode: "invalid_api_key", wantString: "bad key"},
+		{name: "top level", status: 400, data: `{"object": "error", "message": "too long", "type": "BadRequestError", "code": 400}`, wantType: "invalid_request_error", wantCode: "invalid_request", wantString: "too long"},
+		{name: "problem details", status: 404, data: `{"status": 404, "title": "Not Found", "detail": "unknown model"}`, wantType: "invalid_request_error", wantCode: "invalid_request", wantString: "unknown model"},
+		{name: "string code", status: 500, data: `{"error": {"message": "overloaded", "code": "engine_overloaded"}}`, wantType: "api_error", wantCode: "engine_overloaded", wantString: "overloaded"},
+		{name: "stream chunk", status: 0, data: `{"id": "1", "choices": [], "usage": {"total_tokens": 3}}`, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseError(tt.status, []byte(tt.data))
+			if tt.wantNil {
+				if err != nil {
+					t.Errorf("Expected no error, got %+v", err)
+				}
+				return
+			}
+			if err == nil || err.Type != tt.wantType || err.Code != tt.wantCode || err.Message != tt.wantString || err.StatusCode != tt.status {
+				t.Errorf("Unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+func TestStreamChatCompletion_ErrorChunk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		_, _ = fmt.Fprint(w, "data: {\"error\":{\"message\":\"model crashed\"}}\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := newTestClient(t, "test-text", server.URL).StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var events []llm.StreamEvent
+	for event := range stream {
+		events = append(events, event)
+	}
+	if len(events) != 2 || !events[0].IsDelta() || !events[1].IsError() || events[1].Error.Message != "model crashed" {
+		t.Errorf("Expected a delta then the error, got %+v", events)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	client := newTestClient(t, "test-text", "")
+
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")}, User: "user-42"}
+	dryRun, err := llm.DryRun(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if dryRun.URL != "https://api.test.ai/v1/chat/completions" {
+		t.Errorf("Unexpected URL %s", dryRun.URL)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(dryRun.Body, &body); err != nil || body["seed"] != float64(42) || body["model"] != "test-text" || body["user"] != "user-42" {
+		t.Errorf("Expected the common and the provider fields, got %s (%v)", dryRun.Body, err)
+	}
+
+	// Conversion errors are returned as usual, and not recorded
+	image := llm.Message{Role: llm.RoleUser, Content: []llm.MessageContent{llm.NewImageContentFromURL("https://example.com/a.png", "image/png")}}
+	_, err = llm.DryRun(context.Background(), client, llm.ChatRequest{Messages: []llm.Message{image}})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "vision_not_supported" {
+		t.Errorf("Expected vision_not_supported, got %v", err)
+	}
+	if status := client.stats.Status(); status.LastError != nil {
+		t.Errorf("Expected dry runs not to be recorded, got %v", status.LastError)
+	}
+}
@@ -0,0 +1,43 @@
+// Package oaicompat implements the client of the OpenAI-compatible chat completions APIs
+// served by several providers, so that each of them only describes what sets it apart.
+//
+// A Client implements llm.Client over plain HTTP: it converts the requests (messages,
+// image parts for vision models, text files inlined in the prompt, tools and response
+// formats), decodes the responses and the Server-Sent Events of the streams, converts the
+// API errors into *llm.Error, and checks the health of the API by listing its models.
+// Providers embed it in their clients and describe their API with a Config:
+//
+//   - Provider, Name and DefaultBaseURL identify the provider and its endpoint
+//   - ToolModelMarkers and VisionModelMarkers detect the capabilities of the models from
+//     their names, overridden with Extra["supports_tools"] and Extra["supports_vision"]
+//   - NativeFormat selects the response formats the API enforces natively (e.g.
+//     JSONOrGrammar)
+//   - BuildRequest adds the fields of the provider to the Request sent, e.g. its
+//     structured output extensions, and ConvertResponse the fields of the responses
+//   - ChatPath, StreamPath, HealthPath and Query locate the endpoints of APIs departing
+//     from OpenAI's paths, e.g. versioned ones
+//   - Token and Invalidate provide the bearer tokens of APIs not using the API key
+//     directly, e.g. tokens obtained from an identity service
+//
+// Errors are decoded by ParseError, which accepts OpenAI-style errors, in an "error"
+// envelope or at the top level, and problem details (title/detail), and classifies them
+// by HTTP status. APIs with other error formats set Config.ParseError.
+//
+// Usage:
+//
+//	type Client struct {
+//	    *oaicompat.Client
+//	}
+//
+//	func NewClient(config llm.ClientConfig) (*Client, error) {
+//	    client, err := oaicompat.NewClient(config, oaicompat.Config{
+//	        Provider:       "example",
+//	        Name:           "Example AI",
+//	        DefaultBaseURL: "https://api.example.com/v1",
+//	    })
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return &Client{Client: client}, nil
+//	}
+package oaicompat
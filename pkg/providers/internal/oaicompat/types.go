@@ -0,0 +1,167 @@
+package oaicompat
+
+import (
+	"encoding/json"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// Wire types of the OpenAI-compatible chat completions API
+
+// Request is the body of a chat completion request. Providers embed it in their request
+// types to add their own fields (see Config.BuildRequest).
+type Request struct {
+	Model       string    `json:"model,omitempty"`
+	Messages    []Message `json:"messages"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	Temperature *float32  `json:"temperature,omitempty"`
+	MaxTokens   *int      `json:"max_tokens,omitempty"`
+	TopP        *float32  `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+	User        string    `json:"user,omitempty"`
+}
+
+// Message content is either a string or a list of ContentPart
+type Message struct {
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+type Tool struct {
+	Type     string   `json:"type"`
+	Function Function `json:"function"`
+}
+
+type Function struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+type ToolCall struct {
+	Index    int              `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type Response struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+type StreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// APIError covers the error shapes of the OpenAI-compatible servers: OpenAI-style errors,
+// in an "error" envelope or at the top level as inference servers return them, and
+// problem details (title/detail) from API gateways
+type APIError struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Code    interface{} `json:"code"`
+	Title   string      `json:"title"`
+	Detail  string      `json:"detail"`
+}
+
+// LLMError converts an API error, classifying it by HTTP status since the error types
+// of the servers vary (e.g. "BadRequestError"). Numeric codes, which repeat the status,
+// are ignored.
+func (e *APIError) LLMError(statusCode int) *llm.Error {
+	message := e.Message
+	if message == "" {
+		message = e.Detail
+	}
+	if message == "" {
+		message = e.Title
+	}
+
+	code, _ := e.Code.(string)
+	errType := "api_error"
+	switch statusCode {
+	case 401, 403:
+		errType, code = "authentication_error", orDefault(code, "invalid_api_key")
+	case 429:
+		errType, code = "rate_limit_error", orDefault(code, "rate_limit_exceeded")
+	case 400, 404, 422:
+		errType, code = "invalid_request_error", orDefault(code, "invalid_request")
+	}
+	if code == "" {
+		code = "api_error"
+	}
+
+	return &llm.Error{
+		Code:       code,
+		Message:    message,
+		Type:       errType,
+		StatusCode: statusCode,
+	}
+}
+
+// ParseError decodes the error of a response body, or of a stream chunk with a zero
+// status, returning nil when the data holds no error message
+func ParseError(statusCode int, data []byte) *llm.Error {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	var apiErr APIError
+	if err := json.Unmarshal(data, &envelope); err == nil && len(envelope.Error) > 0 {
+		// The error is either an object or a plain message
+		if err := json.Unmarshal(envelope.Error, &apiErr); err != nil {
+			_ = json.Unmarshal(envelope.Error, &apiErr.Message)
+		}
+	} else {
+		_ = json.Unmarshal(data, &apiErr)
+	}
+	if apiErr.Message == "" && apiErr.Detail == "" && apiErr.Title == "" {
+		return nil
+	}
+	return apiErr.LLMError(statusCode)
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	mathrand "math/rand/v2"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/inercia/go-llm/pkg/llm"
@@ -33,19 +35,37 @@ type Client struct {
 	callLog           []llm.ChatRequest
 	streamResponses   [][]llm.StreamEvent
 	streamIndex       int
-	latencySimulation time.Duration
 	failureRate       float64
 	conversationState map[string]interface{}
 	toolCallHandlers  map[string]func(args string) (string, error)
 
+	// Values returned for structured output requests, by schema name
+	structuredResponses map[string]any
+
+	// Simulated latencies and scripted error bursts, guarded by faultMu as resilience
+	// tests call the client concurrently
+	faultMu sync.Mutex
+	latency LatencyDistribution
+	rng     *mathrand.Rand
+	bursts  []*errorBurst
+
+	// Behaviors of the emulated provider, when created with NewClientEmulating
+	emulation *emulation
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
+
 	// Health check caching (even for mock)
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new mock LLM client for testing
 func NewClient(modelName, provider string) (*Client, error) {
 	return &Client{
+		stats: llm.NewHealthStats(),
+		clock: llm.SystemClock,
 		modelInfo: llm.ModelInfo{
 			Name:              modelName,
 			Provider:          provider,
@@ -62,10 +82,11 @@ func NewClient(modelName, provider string) (*Client, error) {
 		callLog:           []llm.ChatRequest{},
 		streamResponses:   [][]llm.StreamEvent{},
 		streamIndex:       0,
-		latencySimulation: 0,
 		failureRate:       0,
 		conversationState: make(map[string]interface{}),
 		toolCallHandlers:  make(map[string]func(args string) (string, error)),
+
+		structuredResponses: make(map[string]any),
 	}, nil
 }
 
@@ -235,18 +256,46 @@ func (m *Client) generateToolCallResponse(req llm.ChatRequest, trigger, userMess
 
 // ChatCompletion returns pre-configured responses or errors
 func (m *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	resp, err := m.chatCompletion(ctx, req)
+	m.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (m *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, m.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Log the request for testing assertions
 	m.callLog = append(m.callLog, req)
 
-	// Simulate latency if configured
-	if m.latencySimulation > 0 {
-		select {
-		case <-time.After(m.latencySimulation):
-		case <-ctx.Done():
-			return nil, ctx.Err()
+	// Simulate latency and error bursts if configured
+	if err := m.simulateLatency(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.burstError(); err != nil {
+		if m.emulation != nil {
+			return nil, m.emulation.shapeError(err)
+		}
+		return nil, err
+	}
+
+	resp, err := m.respond(req)
+	if m.emulation != nil {
+		if err != nil {
+			return nil, m.emulation.shapeError(err)
 		}
+		return m.emulation.shapeResponse(resp), nil
 	}
+	return resp, err
+}
 
+// respond returns the configured error or response for a request, or generates one
+func (m *Client) respond(req llm.ChatRequest) (*llm.ChatResponse, error) {
 	// Simulate random failures if configured
 	if m.failureRate > 0 {
 		randomValue, err := secureRandomFloat64()
@@ -279,6 +328,11 @@ func (m *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		return nil, err
 	}
 
+	// Answer structured output requests with the registered values
+	if value, ok := m.lookupStructuredResponse(req); ok {
+		return m.structuredResponse(req, value, true)
+	}
+
 	// Return response if configured
 	if m.responseIndex < len(m.responses) {
 		resp := m.responses[m.responseIndex]
@@ -286,22 +340,48 @@ func (m *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		return &resp, nil
 	}
 
+	// Generate a schema-valid answer for structured output requests
+	if req.ResponseFormat.IsJSON() {
+		return m.structuredResponse(req, nil, false)
+	}
+
 	// Generate intelligent response based on message content
 	return m.generateIntelligentResponse(req)
 }
 
 // StreamChatCompletion simulates streaming by sending chunked events
 func (m *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	stream, err := m.streamChatCompletion(ctx, req)
+	if err != nil {
+		m.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return m.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (m *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, m.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
 	// Log the stream request
 	m.callLog = append(m.callLog, req)
 
-	// Simulate latency if configured
-	if m.latencySimulation > 0 {
-		select {
-		case <-time.After(m.latencySimulation):
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
+	// Simulate latency and error bursts if configured
+	if err := m.simulateLatency(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.burstError(); err != nil {
+		return m.streamError(ctx, err)
+	}
+
+	// Emulated providers stream their responses like the provider client
+	if m.emulation != nil && m.streamIndex >= len(m.streamResponses) {
+		return m.emulatedStream(ctx, req)
 	}
 
 	// Return error if configured for first call
@@ -309,15 +389,25 @@ func (m *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		err := m.errors[m.errorIndex]
 		m.errorIndex++
 		ch := make(chan llm.StreamEvent, 1)
-		ch <- llm.NewErrorEvent(&llm.Error{
+		seq := llm.NewStreamSequencer()
+		ch <- seq.Stamp(llm.NewErrorEvent(&llm.Error{
 			Code:    "mock_error",
 			Message: err.Error(),
 			Type:    "simulation_error",
-		})
+		}))
 		close(ch)
 		return ch, nil
 	}
 
+	// Answer structured output requests with the registered values
+	if value, ok := m.lookupStructuredResponse(req); ok {
+		events, err := m.structuredStream(req, value, true)
+		if err != nil {
+			return nil, err
+		}
+		return m.sendStreamEvents(ctx, events), nil
+	}
+
 	// Return pre-configured stream if available
 	if m.streamIndex < len(m.streamResponses) {
 		events := m.streamResponses[m.streamIndex]
@@ -325,36 +415,54 @@ func (m *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		return m.sendStreamEvents(ctx, events), nil
 	}
 
+	// Generate a schema-valid answer for structured output requests
+	if req.ResponseFormat.IsJSON() {
+		events, err := m.structuredStream(req, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		return m.sendStreamEvents(ctx, events), nil
+	}
+
 	// Generate intelligent streaming response
 	return m.generateStreamingResponse(ctx, req), nil
 }
 
 // sendStreamEvents sends pre-configured stream events
 func (m *Client) sendStreamEvents(ctx context.Context, events []llm.StreamEvent) <-chan llm.StreamEvent {
-	ch := make(chan llm.StreamEvent, len(events))
+	return m.sendStreamEventsWithDelay(ctx, events, 50*time.Millisecond)
+}
+
+// sendStreamEventsWithDelay sends stream events, waiting the delay after each one
+func (m *Client) sendStreamEventsWithDelay(ctx context.Context, events []llm.StreamEvent, delay time.Duration) <-chan llm.StreamEvent {
+	emitter := llm.NewStreamEmitter(ctx, m.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		for _, event := range events {
-			select {
-			case <-ctx.Done():
+			// Events configured with sequence numbers are sent as-is, so tests can simulate gaps
+			if event.Seq == 0 {
+				event = seq.Stamp(event)
+			}
+			if !emitter.Emit(event) {
 				return
-			case ch <- event:
 			}
 			// Simulate streaming delay
-			time.Sleep(50 * time.Millisecond)
+			_ = m.sleep(ctx, delay)
 		}
 	}()
 
-	return ch
+	return emitter.Events()
 }
 
 // generateStreamingResponse creates intelligent streaming responses
 func (m *Client) generateStreamingResponse(ctx context.Context, req llm.ChatRequest) <-chan llm.StreamEvent {
-	ch := make(chan llm.StreamEvent, 20)
+	emitter := llm.NewStreamEmitter(ctx, m.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 
 		// Determine response type based on request
 		var fullText string
@@ -390,22 +498,17 @@ func (m *Client) generateStreamingResponse(ctx context.Context, req llm.ChatRequ
 		// Stream the text response
 		words := strings.Split(fullText, " ")
 		for _, word := range words {
-			select {
-			case <-ctx.Done():
-				return
-			case ch <- llm.NewDeltaEvent(0, &llm.MessageDelta{
+			if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, &llm.MessageDelta{
 				Content: []llm.MessageContent{llm.NewTextContent(word + " ")},
-			}):
+			}))) {
+				return
 			}
-			time.Sleep(100 * time.Millisecond)
+			_ = m.sleep(ctx, 100*time.Millisecond)
 		}
 
 		// Add tool call if needed
 		if shouldCallTool {
-			select {
-			case <-ctx.Done():
-				return
-			case ch <- llm.NewDeltaEvent(0, &llm.MessageDelta{
+			if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, &llm.MessageDelta{
 				ToolCalls: []llm.ToolCallDelta{
 					{
 						Index: 0,
@@ -417,24 +520,17 @@ func (m *Client) generateStreamingResponse(ctx context.Context, req llm.ChatRequ
 						},
 					},
 				},
-			}):
-			}
-
-			select {
-			case <-ctx.Done():
+			}))) {
 				return
-			case ch <- llm.NewDoneEvent(0, "tool_calls"):
 			}
+
+			emitter.Emit(seq.Stamp(llm.NewDoneEvent(0, "tool_calls")))
 		} else {
-			select {
-			case <-ctx.Done():
-				return
-			case ch <- llm.NewDoneEvent(0, "stop"):
-			}
+			emitter.Emit(seq.Stamp(llm.NewDoneEvent(0, "stop")))
 		}
 	}()
 
-	return ch
+	return emitter.Events()
 }
 
 // GetRemote returns information about the remote client
@@ -444,20 +540,16 @@ func (m *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := m.clock.Now()
 	needsRefresh := m.lastHealthCheck == nil ||
 		now.Sub(*m.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := true // Mock client is always healthy
-		m.lastHealthStatus = &healthy
 		m.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     m.lastHealthStatus,
-		LastChecked: m.lastHealthCheck,
-	}
+	info.Status = m.stats.Status()
+	info.Status.LastChecked = m.lastHealthCheck
 
 	return info
 }
@@ -472,37 +564,14 @@ func (m *Client) Close() error {
 	return nil
 }
 
-// StreamChatCompletionWithTools performs streaming chat completion with tool execution capabilities
-func (m *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStream <-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
-	// Log the request
-	m.callLog = append(m.callLog, req)
-
-	// Simulate latency if configured
-	if m.latencySimulation > 0 {
-		select {
-		case <-time.After(m.latencySimulation):
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
-	}
-
-	// Get LLM stream
-	llmStream, err := m.StreamChatCompletion(ctx, req)
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (m *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := m.StreamChatCompletion(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-
-	// Prepare tool streams
-	var toolStreams []<-chan llm.StreamEvent
-	if toolStream != nil {
-		toolStreams = append(toolStreams, toolStream)
-	}
-
-	// Create stream merger
-	merger := llm.NewStreamMerger(ctx, llmStream, toolStreams)
-
-	// Start merging and return the output channel
-	return merger.Start(), nil
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
 }
 
 // Test helper methods
@@ -532,13 +601,17 @@ func (m *Client) GetLastCall() *llm.ChatRequest {
 	return &m.callLog[len(m.callLog)-1]
 }
 
-// Reset clears all responses, errors, and call logs
+// Reset clears all responses, errors, error bursts, and call logs
 func (m *Client) Reset() *Client {
 	m.responses = []llm.ChatResponse{}
 	m.responseIndex = 0
 	m.errors = []error{}
 	m.errorIndex = 0
 	m.callLog = []llm.ChatRequest{}
+	m.structuredResponses = make(map[string]any)
+	m.faultMu.Lock()
+	m.bursts = nil
+	m.faultMu.Unlock()
 	return m
 }
 
@@ -565,9 +638,8 @@ func (m *Client) WithSimpleResponse(content string) *Client {
 // WithToolCall adds a response that includes a tool call
 func (m *Client) WithToolCall(toolName string, args map[string]interface{}) *Client {
 	argsJSON := "{}"
-	if len(args) > 0 {
-		// In a real implementation, we'd properly serialize this
-		argsJSON = `{"mock_args": true}`
+	if encoded, err := json.Marshal(args); err == nil && len(args) > 0 {
+		argsJSON = string(encoded)
 	}
 
 	return m.AddResponse(llm.ChatResponse{
@@ -607,10 +679,10 @@ func (m *Client) WithError(code, message, errorType string) *Client {
 
 // Configuration methods for enhanced testing
 
-// WithLatency configures simulated latency for requests
+// WithLatency configures a constant simulated latency for requests (see
+// WithLatencyDistribution for variable latencies)
 func (m *Client) WithLatency(duration time.Duration) *Client {
-	m.latencySimulation = duration
-	return m
+	return m.WithLatencyDistribution(ConstantLatency(duration))
 }
 
 // WithFailureRate configures random failure simulation (0.0 to 1.0)
@@ -641,6 +713,13 @@ func (m *Client) WithStreamResponse(events []llm.StreamEvent) *Client {
 	return m
 }
 
+// WithStreamBuffer configures the buffer of the streams and the policy with slow
+// consumers, like ClientConfig.StreamBuffer for the providers
+func (m *Client) WithStreamBuffer(config llm.StreamBufferConfig) *Client {
+	m.streamBuffer = config
+	return m
+}
+
 // WithToolCallHandler registers a handler for specific tool calls
 func (m *Client) WithToolCallHandler(toolName string, handler func(args string) (string, error)) *Client {
 	m.toolCallHandlers[toolName] = handler
@@ -838,7 +917,7 @@ func TestToolCallingScenario(t *testing.T) {
 		},
 	})
 	assert.NoError(t, err)
-	assert.Equal(t, "tool_calls", resp1.Choices[0].FinishReason)
+	assert.Equal(t, llm.FinishReasonToolCalls, resp1.Choices[0].FinishReason)
 
 	// Second call - after tool result
 	resp2, err := mockLLM.ChatCompletion(context.Background(), ChatRequest{
@@ -7,8 +7,11 @@
 // - Pre-configured responses and errors
 // - Intelligent context-aware responses
 // - Tool call simulation
+// - Structured output simulation honouring the request ResponseFormat (WithStructuredResponse)
 // - Streaming response simulation
-// - Latency and failure rate simulation
+// - Latency distributions, random failures and scripted error bursts (FailNext, FailBetween)
+// - Fast-forwarded time with a fake clock instead of sleeps (WithClock)
+// - Provider emulation with per-provider errors, finish reasons and streaming (NewClientEmulating)
 // - Conversation state tracking
 // - Call logging and assertions
 //
@@ -0,0 +1,307 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// EmulatedError is a kind of failure reproduced with the error shape of the emulated
+// provider (see WithEmulatedError)
+type EmulatedError string
+
+const (
+	EmulatedRateLimit      EmulatedError = "rate_limit"
+	EmulatedAuthentication EmulatedError = "authentication"
+	EmulatedContextLength  EmulatedError = "context_length"
+	EmulatedServerError    EmulatedError = "server_error"
+)
+
+// emulation describes the behaviors of a provider client reproduced by the mock: the
+// errors, finish reasons and stream events the provider client produces
+type emulation struct {
+	provider string
+	model    string
+	idPrefix string
+
+	// textChunk is the length of the text of every stream delta
+	textChunk int
+
+	// argumentChunk is the length of the tool call argument fragments of streams, after
+	// a first delta with the ID and the name. Zero sends every call in a single delta.
+	argumentChunk int
+
+	// finishReasons are the raw finish reasons of the provider, by normalized reason
+	finishReasons map[llm.FinishReason]string
+
+	// toolCallsFinish is the raw finish reason of responses calling tools, which some
+	// providers report as a normal stop
+	toolCallsFinish string
+
+	// rawStreamFinish reports the raw finish reason in done events; clients reading
+	// streams without one always report a stop
+	rawStreamFinish bool
+
+	// inBandStreamErrors sends the errors of streams as error events instead of
+	// returning them from StreamChatCompletion
+	inBandStreamErrors bool
+
+	errors map[EmulatedError]llm.Error
+}
+
+// openAICompatible returns the emulation of a provider with the OpenAI API shape
+func openAICompatible(provider, model, idPrefix string) *emulation {
+	return &emulation{
+		provider:      provider,
+		model:         model,
+		idPrefix:      idPrefix,
+		textChunk:     4,
+		argumentChunk: 8,
+		finishReasons: map[llm.FinishReason]string{
+			llm.FinishReasonStop:          "stop",
+			llm.FinishReasonLength:        "length",
+			llm.FinishReasonContentFilter: "content_filter",
+		},
+		toolCallsFinish: "tool_calls",
+		rawStreamFinish: true,
+		errors: map[EmulatedError]llm.Error{
+			EmulatedRateLimit:      {Code: "rate_limit_exceeded", Message: "Rate limit reached for requests", Type: "requests", StatusCode: 429},
+			EmulatedAuthentication: {Code: "invalid_api_key", Message: "Incorrect API key provided", Type: "invalid_request_error", StatusCode: 401},
+			EmulatedContextLength:  {Code: "context_length_exceeded", Message: "This model's maximum context length has been exceeded", Type: "invalid_request_error", StatusCode: 400},
+			EmulatedServerError:    {Code: "unknown", Message: "The server had an error while processing your request", Type: "server_error", StatusCode: 500},
+		},
+	}
+}
+
+// emulations are the emulated providers, by name
+var emulations = map[string]func() *emulation{
+	"openai":     func() *emulation { return openAICompatible("openai", llm.DefaultOpenAIModel, "chatcmpl-") },
+	"deepseek":   func() *emulation { return openAICompatible("deepseek", llm.DefaultDeepSeekModel, "") },
+	"openrouter": func() *emulation { return openAICompatible("openrouter", llm.DefaultOpenRouterModel, "gen-") },
+	"fireworks":  func() *emulation { return openAICompatible("fireworks", llm.DefaultFireworksModel, "") },
+	"nim":        func() *emulation { return openAICompatible("nim", llm.DefaultNIMModel, "chat-") },
+	"gemini": func() *emulation {
+		return &emulation{
+			provider:  "gemini",
+			model:     llm.DefaultGeminiModel,
+			idPrefix:  "gemini-",
+			textChunk: 64,
+			finishReasons: map[llm.FinishReason]string{
+				llm.FinishReasonStop:          "STOP",
+				llm.FinishReasonLength:        "MAX_TOKENS",
+				llm.FinishReasonContentFilter: "SAFETY",
+			},
+			toolCallsFinish:    "STOP",
+			rawStreamFinish:    true,
+			inBandStreamErrors: true,
+			errors: map[EmulatedError]llm.Error{
+				EmulatedRateLimit:      {Code: "rate_limit_error", Message: "Error 429, Message: Resource has been exhausted, Status: RESOURCE_EXHAUSTED", Type: "rate_limit_error", StatusCode: 429},
+				EmulatedAuthentication: {Code: "authentication_error", Message: "Error 400, Message: API key not valid, Status: INVALID_ARGUMENT", Type: "authentication_error", StatusCode: 401},
+				EmulatedContextLength:  {Code: "api_error", Message: "Error 400, Message: The input token count exceeds the maximum number of tokens allowed, Status: INVALID_ARGUMENT", Type: "api_error"},
+				EmulatedServerError:    {Code: "api_error", Message: "Error 500, Message: An internal error has occurred, Status: INTERNAL", Type: "api_error"},
+			},
+		}
+	},
+	"ollama": func() *emulation {
+		return &emulation{
+			provider:  "ollama",
+			model:     llm.DefaultOllamaModel,
+			idPrefix:  "ollama-",
+			textChunk: 3,
+			finishReasons: map[llm.FinishReason]string{
+				llm.FinishReasonStop:   "stop",
+				llm.FinishReasonLength: "length",
+			},
+			toolCallsFinish:    "stop",
+			rawStreamFinish:    true,
+			inBandStreamErrors: true,
+			errors: map[EmulatedError]llm.Error{
+				EmulatedRateLimit:      {Code: "ollama_503", Message: "server busy, please try again", Type: "api_error", StatusCode: 503},
+				EmulatedAuthentication: {Code: "ollama_401", Message: "unauthorized", Type: "api_error", StatusCode: 401},
+				EmulatedContextLength:  {Code: "ollama_400", Message: "input length exceeds the context length", Type: "api_error", StatusCode: 400},
+				EmulatedServerError:    {Code: "ollama_500", Message: "llama runner process has terminated", Type: "api_error", StatusCode: 500},
+			},
+		}
+	},
+	"bedrock": func() *emulation {
+		return &emulation{
+			provider:  "bedrock",
+			model:     llm.DefaultBedrockModel,
+			idPrefix:  "bedrock-",
+			textChunk: 16,
+			finishReasons: map[llm.FinishReason]string{
+				llm.FinishReasonStop:          "end_turn",
+				llm.FinishReasonLength:        "max_tokens",
+				llm.FinishReasonContentFilter: "guardrail_intervened",
+			},
+			toolCallsFinish: "tool_use",
+			errors: map[EmulatedError]llm.Error{
+				EmulatedRateLimit:      {Code: "rate_limit_error", Message: "ThrottlingException: Too many requests, please wait before trying again.", Type: "rate_limit_error", StatusCode: 429},
+				EmulatedAuthentication: {Code: "authentication_error", Message: "UnauthorizedOperation: The security token included in the request is invalid.", Type: "authentication_error", StatusCode: 401},
+				EmulatedContextLength:  {Code: "api_error", Message: "ValidationException: Input is too long for requested model.", Type: "api_error"},
+				EmulatedServerError:    {Code: "server_error", Message: "InternalServerException: The server encountered an internal error.", Type: "api_error", StatusCode: 500},
+			},
+		}
+	},
+}
+
+// EmulatedProviders returns the names of the providers NewClientEmulating can emulate
+func EmulatedProviders() []string {
+	names := make([]string, 0, len(emulations))
+	for name := range emulations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewClientEmulating creates a mock client reproducing the behaviors of a provider
+// client, so integration code can be tested against its quirks without network access:
+//   - responses have the provider's ID format and raw finish reasons (e.g. Gemini and
+//     Ollama report tool calls as a normal stop)
+//   - streams are chunked like the provider's (token-sized OpenAI deltas, sentence-sized
+//     Gemini ones) and tool calls are streamed as the provider client does: as argument
+//     fragments after a first delta with the name, or whole
+//   - WithEmulatedError and simulated failures produce the provider's error shapes,
+//     returned or sent as stream error events like the provider client does
+func NewClientEmulating(provider string) (*Client, error) {
+	newEmulation, ok := emulations[provider]
+	if !ok {
+		return nil, &llm.Error{
+			Code:    "unsupported_provider",
+			Message: fmt.Sprintf("provider %q cannot be emulated, use one of %v", provider, EmulatedProviders()),
+			Type:    "validation_error",
+		}
+	}
+	e := newEmulation()
+	client, err := NewClient(e.model, e.provider)
+	if err != nil {
+		return nil, err
+	}
+	client.emulation = e
+	return client, nil
+}
+
+// WithEmulatedError adds an error response with the shape of the emulated provider
+func (m *Client) WithEmulatedError(kind EmulatedError) *Client {
+	if m.emulation == nil {
+		return m.WithError(string(kind), "emulated "+string(kind), "api_error")
+	}
+	err := m.emulation.errors[kind]
+	return m.AddError(&err)
+}
+
+// shapeError gives simulated failures (random failures and the default error of error
+// bursts) the server error shape of the provider
+func (e *emulation) shapeError(err error) error {
+	if llmErr, ok := err.(*llm.Error); ok && (llmErr.Code == "mock_random_failure" || llmErr.Code == "mock_burst_failure") {
+		shaped := e.errors[EmulatedServerError]
+		return &shaped
+	}
+	return err
+}
+
+// shapeResponse returns a copy of a response with the ID and raw finish reasons of the
+// provider
+func (e *emulation) shapeResponse(resp *llm.ChatResponse) *llm.ChatResponse {
+	shaped := *resp
+	shaped.ID = fmt.Sprintf("%s%d", e.idPrefix, time.Now().UnixNano())
+	shaped.Choices = append([]llm.Choice(nil), resp.Choices...)
+	for i := range shaped.Choices {
+		choice := &shaped.Choices[i]
+		raw := e.finishReasons[llm.FinishReasonStop]
+		switch {
+		case choice.Message.HasToolCalls():
+			raw = e.toolCallsFinish
+		case e.finishReasons[choice.FinishReason] != "":
+			raw = e.finishReasons[choice.FinishReason]
+		}
+		choice.SetFinishReason(e.provider, raw)
+	}
+	return &shaped
+}
+
+// streamEvents returns the stream events of a response, chunked like the provider
+func (e *emulation) streamEvents(resp *llm.ChatResponse) []llm.StreamEvent {
+	var events []llm.StreamEvent
+	for _, choice := range resp.Choices {
+		text := []rune(choice.Message.GetText())
+		for start := 0; start < len(text); start += e.textChunk {
+			end := min(start+e.textChunk, len(text))
+			events = append(events, llm.NewDeltaEvent(choice.Index, &llm.MessageDelta{
+				Content: []llm.MessageContent{llm.NewTextContent(string(text[start:end]))},
+			}))
+		}
+
+		for i, call := range choice.Message.ToolCalls {
+			events = append(events, e.toolCallEvents(choice.Index, i, call)...)
+		}
+
+		if e.rawStreamFinish {
+			events = append(events, llm.NewProviderDoneEvent(choice.Index, e.provider, choice.RawFinishReason))
+		} else {
+			events = append(events, llm.NewDoneEvent(choice.Index, llm.FinishReasonStop))
+		}
+	}
+	return events
+}
+
+// toolCallEvents returns the deltas streaming a tool call
+func (e *emulation) toolCallEvents(choiceIndex, callIndex int, call llm.ToolCall) []llm.StreamEvent {
+	if e.argumentChunk == 0 {
+		return []llm.StreamEvent{llm.NewDeltaEvent(choiceIndex, &llm.MessageDelta{
+			ToolCalls: []llm.ToolCallDelta{{
+				Index:    callIndex,
+				ID:       call.ID,
+				Type:     "function",
+				Function: &llm.ToolCallFunctionDelta{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			}},
+		})}
+	}
+
+	events := []llm.StreamEvent{llm.NewDeltaEvent(choiceIndex, &llm.MessageDelta{
+		ToolCalls: []llm.ToolCallDelta{{
+			Index:    callIndex,
+			ID:       call.ID,
+			Type:     "function",
+			Function: &llm.ToolCallFunctionDelta{Name: call.Function.Name},
+		}},
+	})}
+	args := call.Function.Arguments
+	for start := 0; start < len(args); start += e.argumentChunk {
+		end := min(start+e.argumentChunk, len(args))
+		events = append(events, llm.NewDeltaEvent(choiceIndex, &llm.MessageDelta{
+			ToolCalls: []llm.ToolCallDelta{{
+				Index:    callIndex,
+				Function: &llm.ToolCallFunctionDelta{Arguments: args[start:end]},
+			}},
+		}))
+	}
+	return events
+}
+
+// emulatedStream answers a streaming request like the emulated provider client
+func (m *Client) emulatedStream(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	resp, err := m.respond(req)
+	if err != nil {
+		return m.streamError(ctx, err)
+	}
+	return m.sendStreamEventsWithDelay(ctx, m.emulation.streamEvents(m.emulation.shapeResponse(resp)), 0), nil
+}
+
+// streamError fails a streaming request: emulated providers reporting stream errors as
+// events get an error event with the provider's error shape, other clients get the error
+func (m *Client) streamError(ctx context.Context, err error) (<-chan llm.StreamEvent, error) {
+	if m.emulation == nil {
+		return nil, err
+	}
+	err = m.emulation.shapeError(err)
+	llmErr, ok := err.(*llm.Error)
+	if !m.emulation.inBandStreamErrors || !ok {
+		return nil, err
+	}
+	return m.sendStreamEventsWithDelay(ctx, []llm.StreamEvent{llm.NewErrorEvent(llmErr)}, 0), nil
+}
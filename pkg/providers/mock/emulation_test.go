@@ -0,0 +1,183 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func collectEvents(t *testing.T, stream <-chan llm.StreamEvent) []llm.StreamEvent {
+	t.Helper()
+	var events []llm.StreamEvent
+	for event := range stream {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestNewClientEmulatingUnknownProvider(t *testing.T) {
+	_, err := NewClientEmulating("nope")
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "unsupported_provider" {
+		t.Fatalf("Expected an unsupported_provider error, got %v", err)
+	}
+}
+
+func TestEmulatedFinishReasons(t *testing.T) {
+	tests := []struct {
+		provider string
+		text     string
+		tool     bool
+		raw      string
+		reason   llm.FinishReason
+	}{
+		{provider: "openai", text: "Hello", raw: "stop", reason: llm.FinishReasonStop},
+		{provider: "openai", tool: true, raw: "tool_calls", reason: llm.FinishReasonToolCalls},
+		{provider: "gemini", text: "Hello", raw: "STOP", reason: llm.FinishReasonStop},
+		{provider: "gemini", tool: true, raw: "STOP", reason: llm.FinishReasonStop},
+		{provider: "ollama", tool: true, raw: "stop", reason: llm.FinishReasonStop},
+		{provider: "bedrock", tool: true, raw: "tool_use", reason: llm.FinishReasonToolCalls},
+	}
+
+	for _, tt := range tests {
+		client, err := NewClientEmulating(tt.provider)
+		if err != nil {
+			t.Fatalf("NewClientEmulating(%q) failed: %v", tt.provider, err)
+		}
+		if tt.tool {
+			client.WithToolCall("get_weather", map[string]interface{}{"city": "Paris"})
+		} else {
+			client.WithSimpleResponse(tt.text)
+		}
+
+		resp, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+			Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+		})
+		if err != nil {
+			t.Fatalf("%s: ChatCompletion failed: %v", tt.provider, err)
+		}
+		choice := resp.Choices[0]
+		if choice.RawFinishReason != tt.raw || choice.FinishReason != tt.reason {
+			t.Errorf("%s: expected finish reason %q (%q), got %q (%q)",
+				tt.provider, tt.reason, tt.raw, choice.FinishReason, choice.RawFinishReason)
+		}
+	}
+
+	client, _ := NewClientEmulating("openai")
+	resp, _ := client.WithSimpleResponse("Hello").ChatCompletion(context.Background(), llm.ChatRequest{})
+	if !strings.HasPrefix(resp.ID, "chatcmpl-") {
+		t.Errorf("Expected an OpenAI response ID, got %q", resp.ID)
+	}
+}
+
+func TestEmulatedErrors(t *testing.T) {
+	client, _ := NewClientEmulating("openai")
+	_, err := client.WithEmulatedError(EmulatedRateLimit).ChatCompletion(context.Background(), llm.ChatRequest{})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "rate_limit_exceeded" || llmErr.StatusCode != 429 {
+		t.Fatalf("Expected an OpenAI rate limit error, got %v", err)
+	}
+
+	// OpenAI stream errors are returned when the stream is opened
+	stream, err := client.WithEmulatedError(EmulatedAuthentication).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+	if stream != nil || !errors.As(err, &llmErr) || llmErr.Code != "invalid_api_key" {
+		t.Fatalf("Expected an OpenAI authentication error, got %v", err)
+	}
+
+	// Gemini stream errors are sent as events
+	client, _ = NewClientEmulating("gemini")
+	stream, err = client.WithEmulatedError(EmulatedRateLimit).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	events := collectEvents(t, stream)
+	if len(events) != 1 || !events[0].IsError() || events[0].Error.Type != "rate_limit_error" {
+		t.Fatalf("Expected a single Gemini rate limit event, got %+v", events)
+	}
+
+	// Simulated failures have the server error shape of the provider
+	client, _ = NewClientEmulating("ollama")
+	_, err = client.WithFailureRate(1).ChatCompletion(context.Background(), llm.ChatRequest{})
+	if !errors.As(err, &llmErr) || llmErr.Code != "ollama_500" {
+		t.Fatalf("Expected an Ollama server error, got %v", err)
+	}
+}
+
+func TestEmulatedStreamChunking(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog"
+	deltas := map[string]int{}
+	for _, provider := range []string{"openai", "gemini"} {
+		client, _ := NewClientEmulating(provider)
+		stream, err := client.WithSimpleResponse(text).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+		if err != nil {
+			t.Fatalf("%s: StreamChatCompletion failed: %v", provider, err)
+		}
+
+		var got strings.Builder
+		events := collectEvents(t, stream)
+		for _, event := range events {
+			if event.IsDelta() {
+				deltas[provider]++
+				got.WriteString(event.Choice.Delta.Content[0].(*llm.TextContent).Text)
+			}
+		}
+		if got.String() != text {
+			t.Errorf("%s: expected the streamed text %q, got %q", provider, text, got.String())
+		}
+		if last := events[len(events)-1]; !last.IsDone() {
+			t.Errorf("%s: expected the stream to end with a done event, got %+v", provider, last)
+		}
+	}
+	if deltas["openai"] <= deltas["gemini"] {
+		t.Errorf("Expected OpenAI to stream smaller deltas than Gemini, got %v", deltas)
+	}
+}
+
+func TestEmulatedToolCallStreaming(t *testing.T) {
+	args := map[string]interface{}{"city": "Paris", "units": "celsius"}
+
+	// OpenAI streams the arguments in fragments after the name
+	client, _ := NewClientEmulating("openai")
+	stream, _ := client.WithToolCall("get_weather", args).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+	var fragments int
+	var arguments strings.Builder
+	for _, event := range collectEvents(t, stream) {
+		if !event.IsDelta() || len(event.Choice.Delta.ToolCalls) == 0 {
+			continue
+		}
+		call := event.Choice.Delta.ToolCalls[0]
+		if call.Function.Name == "" {
+			fragments++
+			if call.ID != "" {
+				t.Errorf("Expected argument fragments without ID, got %q", call.ID)
+			}
+		}
+		arguments.WriteString(call.Function.Arguments)
+	}
+	if fragments < 2 {
+		t.Errorf("Expected several argument fragments, got %d", fragments)
+	}
+	if !strings.Contains(arguments.String(), `"city":"Paris"`) {
+		t.Errorf("Expected the streamed arguments to be complete, got %q", arguments.String())
+	}
+
+	// Bedrock sends the whole call and reports a stop
+	client, _ = NewClientEmulating("bedrock")
+	stream, _ = client.WithToolCall("get_weather", args).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+	events := collectEvents(t, stream)
+	var calls []llm.ToolCallDelta
+	for _, event := range events {
+		if event.IsDelta() {
+			calls = append(calls, event.Choice.Delta.ToolCalls...)
+		}
+	}
+	if len(calls) != 1 || calls[0].Function.Name != "get_weather" || calls[0].Function.Arguments == "" {
+		t.Fatalf("Expected a single complete tool call delta, got %+v", calls)
+	}
+	if done := events[len(events)-1]; done.Choice.FinishReason != llm.FinishReasonStop || done.Choice.RawFinishReason != "" {
+		t.Errorf("Expected a stop without raw reason, got %+v", done.Choice)
+	}
+}
@@ -0,0 +1,252 @@
+package mock
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// Quantiles of the standard normal distribution
+const (
+	normalZ95 = 1.6448536269514722
+	normalZ99 = 2.3263478740408408
+)
+
+// LatencyDistribution draws the simulated latencies of requests (see
+// WithLatencyDistribution)
+type LatencyDistribution interface {
+	// Sample returns the latency of a request, drawn with the random generator of the
+	// client
+	Sample(r *rand.Rand) time.Duration
+}
+
+// ConstantLatency delays every request by the same duration
+type ConstantLatency time.Duration
+
+// Sample implements LatencyDistribution
+func (c ConstantLatency) Sample(*rand.Rand) time.Duration {
+	return time.Duration(c)
+}
+
+// NormalLatency draws latencies from a normal distribution. Negative samples are
+// clamped to zero.
+type NormalLatency struct {
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// Sample implements LatencyDistribution
+func (n NormalLatency) Sample(r *rand.Rand) time.Duration {
+	return clampLatency(float64(n.Mean) + r.NormFloat64()*float64(n.StdDev))
+}
+
+// LogNormalLatency draws latencies from a lognormal distribution, the usual shape of
+// network and inference latencies: most requests are close to the median, with a long
+// tail of slow ones
+type LogNormalLatency struct {
+	// Median is the 50th percentile of the latencies
+	Median time.Duration
+
+	// Sigma is the standard deviation of the logarithm of the latencies: the larger,
+	// the longer the tail (0.5 makes the 95th percentile about 2.3 times the median)
+	Sigma float64
+}
+
+// Sample implements LatencyDistribution
+func (l LogNormalLatency) Sample(r *rand.Rand) time.Duration {
+	return clampLatency(float64(l.Median) * math.Exp(l.Sigma*r.NormFloat64()))
+}
+
+// PercentileLatency draws latencies matching percentile targets, as reported by the
+// dashboards of providers. Latencies up to the 95th percentile follow the lognormal
+// distribution with those P50 and P95, and the tail is stretched to reach P99. Zero
+// targets are ignored: with only P50 every request takes P50.
+type PercentileLatency struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// Sample implements LatencyDistribution
+func (p PercentileLatency) Sample(r *rand.Rand) time.Duration {
+	if p.P50 <= 0 {
+		return 0
+	}
+	p95 := p.P95
+	if p95 <= 0 {
+		p95 = p.P50
+	}
+	if p95 <= p.P50 && p.P99 <= p.P50 {
+		return p.P50
+	}
+
+	// The lognormal distribution through P50 and P95, and the slope of the logarithm
+	// of the tail between P95 and P99
+	median, upper := math.Log(float64(p.P50)), math.Log(float64(max(p95, p.P50)))
+	sigma := (upper - median) / normalZ95
+	tail := sigma
+	if p.P99 > 0 {
+		tail = max(0, math.Log(float64(p.P99))-upper) / (normalZ99 - normalZ95)
+	}
+
+	z := r.NormFloat64()
+	if z <= normalZ95 {
+		return clampLatency(math.Exp(median + sigma*z))
+	}
+	return clampLatency(math.Exp(upper + tail*(z-normalZ95)))
+}
+
+// clampLatency converts a sampled latency to a duration, clamped to zero
+func clampLatency(nanoseconds float64) time.Duration {
+	if nanoseconds <= 0 || math.IsNaN(nanoseconds) {
+		return 0
+	}
+	if nanoseconds >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(nanoseconds)
+}
+
+// errorBurst is a scripted sequence of failures: a number of calls or a period of time
+type errorBurst struct {
+	// calls is the number of calls left to fail, for bursts of calls
+	calls int
+
+	// start and end delimit the failures, for bursts of time
+	start, end time.Time
+
+	err error
+}
+
+// burstFailure creates the default error of error bursts: a server error, retried by
+// llm.RetryableChatCompleter and failed over by routers
+func burstFailure() *llm.Error {
+	return &llm.Error{
+		Code:       "mock_burst_failure",
+		Message:    "Simulated failure burst",
+		Type:       "server_error",
+		StatusCode: 503,
+	}
+}
+
+// WithLatencyDistribution draws the latency of every request from the distribution,
+// so retries, hedging and timeouts can be tested with realistic latencies. Use WithSeed
+// for reproducible latencies.
+func (m *Client) WithLatencyDistribution(distribution LatencyDistribution) *Client {
+	m.faultMu.Lock()
+	defer m.faultMu.Unlock()
+	m.latency = distribution
+	return m
+}
+
+// WithSeed seeds the random generator of the simulated latencies
+func (m *Client) WithSeed(seed uint64) *Client {
+	m.faultMu.Lock()
+	defer m.faultMu.Unlock()
+	m.rng = rand.New(rand.NewPCG(seed, seed))
+	return m
+}
+
+// FailNext makes the next n calls fail with err, or with a retryable server error
+// (503) when err is nil. Bursts are consumed in the order they are added, before the
+// queued responses and errors.
+func (m *Client) FailNext(n int, err error) *Client {
+	if err == nil {
+		err = burstFailure()
+	}
+	m.faultMu.Lock()
+	defer m.faultMu.Unlock()
+	m.bursts = append(m.bursts, &errorBurst{calls: n, err: err})
+	return m
+}
+
+// FailBetween makes the calls made between start and end fail with err, or with a
+// retryable server error (503) when err is nil, simulating an outage of the provider
+func (m *Client) FailBetween(start, end time.Time, err error) *Client {
+	if err == nil {
+		err = burstFailure()
+	}
+	m.faultMu.Lock()
+	defer m.faultMu.Unlock()
+	m.bursts = append(m.bursts, &errorBurst{start: start, end: end, err: err})
+	return m
+}
+
+// simulateLatency waits for the latency of a request, if configured
+func (m *Client) simulateLatency(ctx context.Context) error {
+	m.faultMu.Lock()
+	var delay time.Duration
+	if m.latency != nil {
+		if m.rng == nil {
+			m.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+		}
+		delay = m.latency.Sample(m.rng)
+	}
+	m.faultMu.Unlock()
+
+	return m.sleep(ctx, delay)
+}
+
+// WithClock makes the client tell the time with a clock: the health checks, the error
+// bursts of FailBetween and the simulated latencies. With a *llm.FakeClock, latencies
+// advance the clock instead of sleeping, so scenarios run instantly and deterministically.
+func (m *Client) WithClock(clock llm.Clock) *Client {
+	if clock == nil {
+		clock = llm.SystemClock
+	}
+	m.clock = clock
+	m.stats = llm.NewHealthStatsWithClock(clock)
+	return m
+}
+
+// sleep waits for a delay on the clock of the client, or advances it with a fake clock
+func (m *Client) sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	if fake, ok := m.clock.(*llm.FakeClock); ok {
+		fake.Advance(delay)
+		return ctx.Err()
+	}
+	select {
+	case <-m.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// burstError returns the error of the error burst the call belongs to, if any,
+// dropping the bursts that are over
+func (m *Client) burstError() error {
+	m.faultMu.Lock()
+	defer m.faultMu.Unlock()
+
+	now := m.clock.Now()
+	var err error
+	active := m.bursts[:0]
+	for _, burst := range m.bursts {
+		if burst.calls > 0 {
+			if err == nil {
+				err = burst.err
+				burst.calls--
+			}
+			if burst.calls > 0 {
+				active = append(active, burst)
+			}
+			continue
+		}
+		if burst.end.After(now) {
+			if err == nil && !now.Before(burst.start) {
+				err = burst.err
+			}
+			active = append(active, burst)
+		}
+	}
+	clear(m.bursts[len(active):])
+	m.bursts = active
+	return err
+}
@@ -0,0 +1,185 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// latencyPercentile samples a distribution and returns the given percentile
+func latencyPercentile(distribution LatencyDistribution, samples int, percentiles ...float64) []time.Duration {
+	r := rand.New(rand.NewPCG(1, 2))
+	latencies := make([]time.Duration, samples)
+	for i := range latencies {
+		latencies[i] = distribution.Sample(r)
+	}
+	slices.Sort(latencies)
+
+	result := make([]time.Duration, len(percentiles))
+	for i, p := range percentiles {
+		result[i] = latencies[int(p*float64(samples))]
+	}
+	return result
+}
+
+// within checks a duration is within a relative tolerance of the expected one
+func within(got, expected time.Duration, tolerance float64) bool {
+	diff := float64(got - expected)
+	return diff >= -tolerance*float64(expected) && diff <= tolerance*float64(expected)
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	targets := PercentileLatency{P50: 100 * time.Millisecond, P95: 300 * time.Millisecond, P99: time.Second}
+	got := latencyPercentile(targets, 50000, 0.5, 0.95, 0.99)
+	for i, expected := range []time.Duration{targets.P50, targets.P95, targets.P99} {
+		if !within(got[i], expected, 0.08) {
+			t.Errorf("Expected percentile %d to be about %v, got %v", i, expected, got[i])
+		}
+	}
+
+	got = latencyPercentile(LogNormalLatency{Median: 200 * time.Millisecond, Sigma: 0.5}, 20000, 0.5)
+	if !within(got[0], 200*time.Millisecond, 0.05) {
+		t.Errorf("Expected a median of about 200ms, got %v", got[0])
+	}
+
+	got = latencyPercentile(NormalLatency{Mean: 10 * time.Millisecond, StdDev: 20 * time.Millisecond}, 1000, 0)
+	if got[0] != 0 {
+		t.Errorf("Expected negative latencies to be clamped to zero, got %v", got[0])
+	}
+
+	if latency := (PercentileLatency{P50: time.Second}).Sample(rand.New(rand.NewPCG(1, 2))); latency != time.Second {
+		t.Errorf("Expected a constant latency with only P50, got %v", latency)
+	}
+}
+
+func TestWithLatencyDistribution(t *testing.T) {
+	client, _ := NewClient("model", "mock")
+	client.WithSeed(42).WithLatencyDistribution(ConstantLatency(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.ChatCompletion(ctx, llm.ChatRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the request to be canceled while waiting, got %v", err)
+	}
+
+	start := time.Now()
+	client.WithLatencyDistribution(NormalLatency{Mean: 30 * time.Millisecond, StdDev: time.Millisecond})
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("Expected a simulated latency of about 30ms, got %v", elapsed)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	client, _ := NewClient("model", "mock")
+	custom := &llm.Error{Code: "rate_limit_exceeded", Type: "rate_limit_error", StatusCode: 429}
+	client.FailNext(2, nil).FailNext(1, custom).WithSimpleResponse("Recovered")
+
+	for i, code := range []string{"mock_burst_failure", "mock_burst_failure", "rate_limit_exceeded"} {
+		_, err := client.ChatCompletion(context.Background(), llm.ChatRequest{})
+		var llmErr *llm.Error
+		if !errors.As(err, &llmErr) || llmErr.Code != code {
+			t.Fatalf("Call %d: expected a %s error, got %v", i, code, err)
+		}
+	}
+	resp, err := client.ChatCompletion(context.Background(), llm.ChatRequest{})
+	if err != nil || resp.Choices[0].Message.GetText() != "Recovered" {
+		t.Fatalf("Expected the queued response after the burst, got %v %v", resp, err)
+	}
+
+	// Streams fail when they are opened
+	client.FailNext(1, nil)
+	if stream, err := client.StreamChatCompletion(context.Background(), llm.ChatRequest{}); stream != nil || err == nil {
+		t.Fatalf("Expected the stream to fail, got %v", err)
+	}
+
+	// Emulated providers report the failures with their shape
+	emulated, _ := NewClientEmulating("gemini")
+	stream, err := emulated.FailNext(1, nil).StreamChatCompletion(context.Background(), llm.ChatRequest{})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	events := collectEvents(t, stream)
+	if len(events) != 1 || !events[0].IsError() || events[0].Error.Code != "api_error" {
+		t.Fatalf("Expected a Gemini server error event, got %+v", events)
+	}
+}
+
+func TestFailNext_Retried(t *testing.T) {
+	client, _ := NewClient("model", "mock")
+	client.FailNext(2, nil).WithSimpleResponse("Recovered")
+
+	retrying := llm.RetryChatCompletion(client, llm.RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	resp, err := retrying.ChatCompletion(context.Background(), llm.ChatRequest{})
+	if err != nil || resp.Choices[0].Message.GetText() != "Recovered" {
+		t.Fatalf("Expected the burst to be retried, got %v", err)
+	}
+	if len(client.GetCallLog()) != 3 {
+		t.Errorf("Expected 3 calls, got %d", len(client.GetCallLog()))
+	}
+}
+
+func TestFailBetween(t *testing.T) {
+	client, _ := NewClient("model", "mock")
+	now := time.Now()
+	client.FailBetween(now.Add(-time.Hour), now.Add(-time.Minute), nil) // over
+	client.FailBetween(now.Add(time.Hour), now.Add(2*time.Hour), nil)   // not started
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err != nil {
+		t.Fatalf("Expected no failure outside of the bursts, got %v", err)
+	}
+	if len(client.bursts) != 1 {
+		t.Errorf("Expected the burst that is over to be dropped, got %d bursts", len(client.bursts))
+	}
+
+	client.FailBetween(now.Add(-time.Second), now.Add(50*time.Millisecond), nil)
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err == nil {
+		t.Fatal("Expected a failure during the burst")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err != nil {
+		t.Fatalf("Expected no failure after the burst, got %v", err)
+	}
+
+	client.Reset()
+	if len(client.bursts) != 0 {
+		t.Errorf("Expected Reset to clear the bursts, got %d", len(client.bursts))
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	clock := llm.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	client, _ := NewClient("model", "mock")
+	client.WithClock(clock).WithLatency(10 * time.Minute)
+	start := clock.Now()
+	client.FailBetween(start.Add(15*time.Minute), start.Add(time.Hour), nil)
+
+	// Latencies fast-forward the clock instead of sleeping
+	began := time.Now()
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err != nil {
+		t.Fatalf("Expected no failure before the outage, got %v", err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err == nil {
+		t.Fatal("Expected a failure during the outage")
+	}
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("Expected the latencies not to be slept, took %v", elapsed)
+	}
+	if got := clock.Now().Sub(start); got != 20*time.Minute {
+		t.Errorf("Expected the clock to advance by 20m, got %v", got)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := client.ChatCompletion(context.Background(), llm.ChatRequest{}); err != nil {
+		t.Fatalf("Expected no failure after the outage, got %v", err)
+	}
+	if checked := client.GetRemote().Status.LastChecked; checked == nil || !checked.Equal(clock.Now()) {
+		t.Errorf("Expected the health check to be timed by the clock, got %v", checked)
+	}
+}
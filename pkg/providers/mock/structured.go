@@ -0,0 +1,301 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// maxExampleDepth limits the nesting of the values generated from schemas, so recursive
+// schemas terminate
+const maxExampleDepth = 8
+
+// WithStructuredResponse registers the value returned, serialized as JSON, for requests
+// whose ResponseFormat asks for JSON with the given schema name. An empty name matches
+// any JSON request without a more specific registration. The value is validated against
+// the request schema, so a value that does not match fails the request with an
+// "invalid_structured_response" error.
+func (m *Client) WithStructuredResponse(schemaName string, value any) *Client {
+	m.structuredResponses[schemaName] = value
+	return m
+}
+
+// lookupStructuredResponse returns the value registered for a JSON request
+func (m *Client) lookupStructuredResponse(req llm.ChatRequest) (any, bool) {
+	if !req.ResponseFormat.IsJSON() {
+		return nil, false
+	}
+	if req.ResponseFormat.JSONSchema != nil {
+		if value, ok := m.structuredResponses[req.ResponseFormat.JSONSchema.Name]; ok {
+			return value, true
+		}
+	}
+	value, ok := m.structuredResponses[""]
+	return value, ok
+}
+
+// structuredText returns the JSON answer for a request: the given value, or when nil a
+// value generated from the request schema
+func (m *Client) structuredText(req llm.ChatRequest, value any, registered bool) (string, error) {
+	var schema interface{}
+	if req.ResponseFormat.JSONSchema != nil {
+		schema = req.ResponseFormat.JSONSchema.Schema
+	}
+	if !registered {
+		value = exampleFromSchema(schema)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", &llm.Error{
+			Code:    "invalid_structured_response",
+			Message: fmt.Sprintf("cannot serialize structured response: %v", err),
+			Type:    "validation_error",
+		}
+	}
+	if registered && schema != nil {
+		if err := llm.ValidateAgainstSchema(data, schema); err != nil {
+			return "", &llm.Error{
+				Code:    "invalid_structured_response",
+				Message: fmt.Sprintf("structured response does not match schema %q: %v", req.ResponseFormat.JSONSchema.Name, err),
+				Type:    "validation_error",
+			}
+		}
+	}
+	return string(data), nil
+}
+
+// structuredResponse creates the response of a JSON request
+func (m *Client) structuredResponse(req llm.ChatRequest, value any, registered bool) (*llm.ChatResponse, error) {
+	text, err := m.structuredText(req, value, registered)
+	if err != nil {
+		return nil, err
+	}
+	return &llm.ChatResponse{
+		ID:    fmt.Sprintf("mock-structured-%d", time.Now().UnixNano()),
+		Model: m.modelInfo.Name,
+		Choices: []llm.Choice{
+			{
+				Index: 0,
+				Message: llm.Message{
+					Role:    llm.RoleAssistant,
+					Content: []llm.MessageContent{llm.NewTextContent(text)},
+				},
+				FinishReason: "stop",
+			},
+		},
+	}, nil
+}
+
+// structuredStream creates the stream events of a JSON request, sending the JSON in
+// small chunks
+func (m *Client) structuredStream(req llm.ChatRequest, value any, registered bool) ([]llm.StreamEvent, error) {
+	text, err := m.structuredText(req, value, registered)
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 32
+	var events []llm.StreamEvent
+	for start := 0; start < len(text); start += chunkSize {
+		end := min(start+chunkSize, len(text))
+		events = append(events, llm.NewDeltaEvent(0, &llm.MessageDelta{
+			Content: []llm.MessageContent{llm.NewTextContent(text[start:end])},
+		}))
+	}
+	return append(events, llm.NewDoneEvent(0, "stop")), nil
+}
+
+// exampleFromSchema generates a value satisfying the common constraints of a schema
+// (types, enums, required properties, minimum lengths and bounds). Patterns are not
+// honoured. A nil schema generates an empty object.
+func exampleFromSchema(schema interface{}) interface{} {
+	if schema == nil {
+		return map[string]interface{}{}
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return map[string]interface{}{}
+	}
+	return (&exampleGenerator{root: root}).generate(root, 0)
+}
+
+// exampleGenerator generates values from a schema, resolving local references
+type exampleGenerator struct {
+	root map[string]interface{}
+}
+
+func (g *exampleGenerator) generate(schema map[string]interface{}, depth int) interface{} {
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved := g.resolve(ref); resolved != nil && depth < maxExampleDepth {
+			return g.generate(resolved, depth+1)
+		}
+		return nil
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			if option, ok := options[0].(map[string]interface{}); ok {
+				return g.generate(option, depth+1)
+			}
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return g.generateObject(schema, depth)
+	case "array":
+		var items []interface{}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i := 0; i < intKeyword(schema, "minItems"); i++ {
+				items = append(items, g.generate(itemSchema, depth+1))
+			}
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		return items
+	case "string":
+		return exampleString(schema)
+	case "integer":
+		return int(exampleNumber(schema, 1))
+	case "number":
+		return exampleNumber(schema, 0.5)
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// generateObject generates an object with every property (only the required ones once
+// deeply nested, to stop recursion)
+func (g *exampleGenerator) generateObject(schema map[string]interface{}, depth int) map[string]interface{} {
+	obj := map[string]interface{}{}
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !ok || (depth >= maxExampleDepth/2 && !required[name]) {
+			continue
+		}
+		obj[name] = g.generate(propSchema, depth+1)
+	}
+	return obj
+}
+
+// resolve returns the schema of a local reference ("#/$defs/Name")
+func (g *exampleGenerator) resolve(ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node interface{} = g.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	resolved, _ := node.(map[string]interface{})
+	return resolved
+}
+
+// schemaType returns the type of a schema, preferring non-null types in type lists and
+// inferring objects from their properties
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+		return "null"
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// exampleString generates a string honouring the format and minimum length
+func exampleString(schema map[string]interface{}) string {
+	var s string
+	switch schema["format"] {
+	case "date-time":
+		s = "2024-01-01T00:00:00Z"
+	case "date":
+		s = "2024-01-01"
+	case "time":
+		s = "00:00:00"
+	case "email":
+		s = "user@example.com"
+	case "uri", "url":
+		s = "https://example.com"
+	case "uuid":
+		s = "00000000-0000-0000-0000-000000000000"
+	default:
+		s = "string"
+	}
+	if minLength := intKeyword(schema, "minLength"); len(s) < minLength {
+		s += strings.Repeat("x", minLength-len(s))
+	}
+	if maxLength, ok := schema["maxLength"].(float64); ok && len(s) > int(maxLength) {
+		s = s[:int(maxLength)]
+	}
+	return s
+}
+
+// exampleNumber generates a number within the schema bounds
+func exampleNumber(schema map[string]interface{}, step float64) float64 {
+	if minimum, ok := schema["minimum"].(float64); ok {
+		return minimum
+	}
+	if minimum, ok := schema["exclusiveMinimum"].(float64); ok {
+		return minimum + step
+	}
+	if maximum, ok := schema["maximum"].(float64); ok && maximum < 0 {
+		return maximum
+	}
+	if maximum, ok := schema["exclusiveMaximum"].(float64); ok && maximum <= 0 {
+		return maximum - step
+	}
+	return 0
+}
+
+// intKeyword returns an integer keyword of a schema, or 0
+func intKeyword(schema map[string]interface{}, key string) int {
+	if n, ok := schema[key].(float64); ok {
+		return int(n)
+	}
+	return 0
+}
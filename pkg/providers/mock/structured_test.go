@@ -0,0 +1,119 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+type weather struct {
+	City        string   `json:"city"`
+	Temperature float64  `json:"temperature"`
+	Conditions  []string `json:"conditions"`
+}
+
+func weatherRequest(t *testing.T) llm.ChatRequest {
+	t.Helper()
+	format, err := llm.NewJSONSchemaResponseFormatFromStruct("weather", "Weather report", weather{})
+	if err != nil {
+		t.Fatalf("Failed to create response format: %v", err)
+	}
+	return llm.ChatRequest{
+		Messages:       []llm.Message{llm.NewTextMessage(llm.RoleUser, "Weather in Paris?")},
+		ResponseFormat: format,
+	}
+}
+
+func TestWithStructuredResponse(t *testing.T) {
+	client, _ := NewClient("mock-model", "mock")
+	client.WithStructuredResponse("weather", weather{City: "Paris", Temperature: 21.5, Conditions: []string{"sunny"}})
+
+	resp, err := client.ChatCompletion(context.Background(), weatherRequest(t))
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	var got weather
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.GetText()), &got); err != nil {
+		t.Fatalf("Response is not JSON: %v", err)
+	}
+	if got.City != "Paris" || got.Temperature != 21.5 {
+		t.Errorf("Unexpected structured response: %+v", got)
+	}
+
+	// Streaming sends the same JSON in chunks
+	stream, err := client.StreamChatCompletion(context.Background(), weatherRequest(t))
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	var text strings.Builder
+	for event := range stream {
+		if event.IsDelta() {
+			text.WriteString(event.Choice.Delta.Content[0].(*llm.TextContent).GetText())
+		}
+	}
+	if text.String() != resp.Choices[0].Message.GetText() {
+		t.Errorf("Unexpected streamed JSON: %s", text.String())
+	}
+
+	// Requests without a JSON format are unaffected
+	resp, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hello")},
+	})
+	if err != nil || strings.HasPrefix(resp.Choices[0].Message.GetText(), "{") {
+		t.Errorf("Expected a text response, got %v, %v", resp, err)
+	}
+}
+
+func TestWithStructuredResponse_SchemaMismatch(t *testing.T) {
+	client, _ := NewClient("mock-model", "mock")
+	client.WithStructuredResponse("weather", map[string]any{"city": 42})
+
+	_, err := client.ChatCompletion(context.Background(), weatherRequest(t))
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_structured_response" {
+		t.Errorf("Expected invalid_structured_response, got %v", err)
+	}
+}
+
+func TestStructuredResponse_GeneratedFromSchema(t *testing.T) {
+	client, _ := NewClient("mock-model", "mock")
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":     map[string]any{"type": "string", "format": "uuid"},
+			"status": map[string]any{"type": "string", "enum": []any{"open", "closed"}},
+			"count":  map[string]any{"type": "integer", "minimum": 1},
+			"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 2},
+			"owner":  map[string]any{"$ref": "#/$defs/user"},
+		},
+		"required":             []any{"id", "status", "count", "tags", "owner"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"user": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"email": map[string]any{"type": "string", "format": "email"}},
+				"required":   []any{"email"},
+			},
+		},
+	}
+	req := llm.ChatRequest{ResponseFormat: llm.NewJSONSchemaResponseFormat("ticket", "", schema)}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	text := resp.Choices[0].Message.GetText()
+	if err := llm.ValidateAgainstSchema([]byte(text), schema); err != nil {
+		t.Errorf("Generated response %s is not schema-valid: %v", text, err)
+	}
+
+	// Plain JSON mode returns an object
+	resp, _ = client.ChatCompletion(context.Background(), llm.ChatRequest{ResponseFormat: llm.NewJSONResponseFormat()})
+	if got := resp.Choices[0].Message.GetText(); got != "{}" {
+		t.Errorf("Expected an empty object, got %s", got)
+	}
+}
@@ -0,0 +1,108 @@
+package nim
+
+import (
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// DefaultBaseURL is the NVIDIA API catalog endpoint, serving NIM models hosted by NVIDIA.
+// Self-hosted NIM containers serve the same API under http://<host>:8000/v1.
+const DefaultBaseURL = "https://integrate.api.nvidia.com/v1"
+
+// Model name markers used to detect capabilities. NIM model IDs look like
+// "meta/llama-3.1-70b-instruct" or "nvidia/llama-3.1-nemotron-70b-instruct".
+var (
+	toolModelMarkers   = []string{"llama-3.1", "llama-3.2", "llama-3.3", "llama-4", "nemotron", "mistral-large", "mixtral-8x22b", "qwen2.5", "qwen3", "deepseek-v3", "gpt-oss", "kimi"}
+	visionModelMarkers = []string{"vision", "neva", "vila", "-vl", "llama-4", "paligemma"}
+)
+
+// Client implements the llm.Client interface for NVIDIA NIM
+type Client struct {
+	*oaicompat.Client
+}
+
+// NewClient creates a new NVIDIA NIM client. The API key is required for the NVIDIA API
+// catalog, and optional for self-hosted NIM endpoints set with BaseURL.
+func NewClient(config llm.ClientConfig) (*Client, error) {
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if config.APIKey == "" && (baseURL == "" || baseURL == DefaultBaseURL) {
+		return nil, &llm.Error{
+			Code:    "missing_api_key",
+			Message: "API key is required for the NVIDIA API catalog",
+			Type:    "authentication_error",
+		}
+	}
+
+	client, err := oaicompat.NewClient(config, oaicompat.Config{
+		Provider:           "nim",
+		Name:               "NVIDIA NIM",
+		DefaultBaseURL:     DefaultBaseURL,
+		ToolModelMarkers:   toolModelMarkers,
+		VisionModelMarkers: visionModelMarkers,
+		MaxTokens:          maxTokensForModel(config.Model),
+		// NIM enforces JSON (with or without schema) and grammars with guided decoding
+		NativeFormat: oaicompat.JSONOrGrammar,
+		BuildRequest: func(req llm.ChatRequest, body oaicompat.Request) (interface{}, error) {
+			ext, err := guidedDecoding(req)
+			if err != nil {
+				return nil, err
+			}
+			return &chatRequest{Request: body, NVExt: ext}, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: client}, nil
+}
+
+// maxTokensForModel returns the context length of well-known NIM models
+func maxTokensForModel(model string) int {
+	model = strings.ToLower(model)
+	switch {
+	case strings.Contains(model, "llama-3.1"), strings.Contains(model, "llama-3.2"),
+		strings.Contains(model, "llama-3.3"), strings.Contains(model, "nemotron"),
+		strings.Contains(model, "mistral-large"), strings.Contains(model, "qwen2.5"),
+		strings.Contains(model, "deepseek-v3"), strings.Contains(model, "gpt-oss"):
+		return 131072
+	case strings.Contains(model, "mixtral-8x22b"), strings.Contains(model, "qwen3"):
+		return 65536
+	default:
+		return 8192
+	}
+}
+
+// guidedDecoding maps the response format and the guided decoding options of a request
+// onto the NVIDIA extensions, which accept a single guide per request
+func guidedDecoding(req llm.ChatRequest) (*nvExt, error) {
+	ext := guidedOptions(req)
+	if ext == nil {
+		ext = &nvExt{}
+	}
+
+	if compatible := req.ResponseFormat.Compatible(); compatible != nil {
+		switch {
+		case compatible.JSONSchema != nil:
+			ext.GuidedJSON = compatible.JSONSchema.Schema
+		case compatible.Type == string(llm.ResponseFormatJSON):
+			ext.GuidedJSON = map[string]interface{}{"type": "object"}
+		case compatible.Type == string(llm.ResponseFormatGrammar):
+			ext.GuidedGrammar = compatible.Grammar
+		}
+	}
+
+	switch ext.guides() {
+	case 0:
+		return nil, nil
+	case 1:
+		return ext, nil
+	default:
+		return nil, &llm.Error{
+			Code:    "invalid_request",
+			Message: "NVIDIA NIM accepts a single guided decoding constraint per request (response format, guided choice or guided regex)",
+			Type:    "validation_error",
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package nim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// newTestServer starts a server that records the last request body and replies with handler
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]interface{})) (*Client, *map[string]interface{}) {
+	t.Helper()
+
+	var lastBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		lastBody = nil
+		_ = json.Unmarshal(data, &lastBody)
+		handler(w, lastBody)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(llm.ClientConfig{
+		APIKey:  "test-key",
+		Model:   "meta/llama-3.1-8b-instruct",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client, &lastBody
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(llm.ClientConfig{Model: "m"}); err == nil {
+		t.Error("Expected error for missing API key with the API catalog")
+	}
+	if _, err := NewClient(llm.ClientConfig{APIKey: "k"}); err == nil {
+		t.Error("Expected error for missing model")
+	}
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "nvidia/llama-3.1-nemotron-70b-instruct"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info := client.GetModelInfo()
+	if info.Provider != "nim" || !info.SupportsTools || info.SupportsVision || info.MaxTokens != 131072 {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+	hi := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")}}
+	dryRun, err := llm.DryRun(context.Background(), client, hi)
+	if err != nil || dryRun.URL != DefaultBaseURL+"/chat/completions" {
+		t.Errorf("Expected default base URL, got %+v (%v)", dryRun, err)
+	}
+
+	// Self-hosted NIM endpoints do not require an API key
+	client, err = NewClient(llm.ClientConfig{BaseURL: "http://dgx-01:8000/v1/", Model: "meta/llama-3.2-11b-vision-instruct"})
+	if err != nil {
+		t.Fatalf("Unexpected error for self-hosted endpoint: %v", err)
+	}
+	dryRun, err = llm.DryRun(context.Background(), client, hi)
+	if err != nil || dryRun.URL != "http://dgx-01:8000/v1/chat/completions" || !client.GetModelInfo().SupportsVision {
+		t.Errorf("Unexpected client: %+v (%+v, %v)", client.GetModelInfo(), dryRun, err)
+	}
+}
+
+func TestChatCompletion_ToolCalls(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{
+			"id": "resp-1",
+			"model": "llama",
+			"choices": [{
+				"index": 0,
+				"message": {
+					"role": "assistant",
+					"content": "",
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 7, "total_tokens": 19}
+		}`)
+	})
+
+	req := llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Weather in Paris?")},
+		User:     "user-42",
+		Tools: []llm.Tool{{
+			Type: "function",
+			Function: llm.ToolFunction{
+				Name:       "get_weather",
+				Parameters: map[string]interface{}{"type": "object"},
+			},
+		}},
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tools, _ := (*lastBody)["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("Expected tools in request, got %v", (*lastBody)["tools"])
+	}
+	if (*lastBody)["model"] != "meta/llama-3.1-8b-instruct" || (*lastBody)["user"] != "user-42" {
+		t.Errorf("Unexpected model or user: %v", *lastBody)
+	}
+
+	if len(resp.Choices) != 1 || len(resp.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %+v", resp.Choices)
+	}
+	call := resp.Choices[0].Message.ToolCalls[0]
+	if call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if resp.Usage.TotalTokens != 19 || resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestChatCompletion_GuidedDecoding(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id": "r", "choices": [{"message": {"role": "assistant", "content": "yes"}}]}`)
+	})
+
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}}
+
+	tests := []struct {
+		name    string
+		req     llm.ChatRequest
+		checkFn func(nvext map[string]interface{}) bool
+	}{
+		{
+			name: "json schema",
+			req:  llm.ChatRequest{ResponseFormat: llm.NewJSONSchemaResponseFormat("person", "", schema)},
+			checkFn: func(nvext map[string]interface{}) bool {
+				guided, _ := nvext["guided_json"].(map[string]interface{})
+				return guided["properties"] != nil
+			},
+		},
+		{
+			name: "json object",
+			req:  llm.ChatRequest{ResponseFormat: llm.NewJSONResponseFormat()},
+			checkFn: func(nvext map[string]interface{}) bool {
+				guided, _ := nvext["guided_json"].(map[string]interface{})
+				return guided["type"] == "object"
+			},
+		},
+		{
+			name: "grammar",
+			req:  llm.ChatRequest{ResponseFormat: &llm.ResponseFormat{Type: llm.ResponseFormatGrammar, Grammar: `root ::= "yes" | "no"`}},
+			checkFn: func(nvext map[string]interface{}) bool {
+				return nvext["guided_grammar"] == `root ::= "yes" | "no"`
+			},
+		},
+		{
+			name: "guided choice",
+			req:  llm.ChatRequest{}.WithProviderOptions(WithGuidedChoice("yes", "no")),
+			checkFn: func(nvext map[string]interface{}) bool {
+				choices, _ := nvext["guided_choice"].([]interface{})
+				return len(choices) == 2 && choices[0] == "yes"
+			},
+		},
+		{
+			name: "guided regex",
+			req:  llm.ChatRequest{}.WithProviderOptions(WithGuidedRegex(`(yes|no)`)),
+			checkFn: func(nvext map[string]interface{}) bool {
+				return nvext["guided_regex"] == `(yes|no)`
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Messages = []llm.Message{llm.NewTextMessage(llm.RoleUser, "Answer")}
+			if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			nvext, _ := (*lastBody)["nvext"].(map[string]interface{})
+			if len(nvext) != 1 || !tt.checkFn(nvext) {
+				t.Errorf("Unexpected nvext: %v", nvext)
+			}
+			if (*lastBody)["response_format"] != nil {
+				t.Errorf("Expected no response_format, got %v", (*lastBody)["response_format"])
+			}
+			if messages, _ := (*lastBody)["messages"].([]interface{}); len(messages) != 1 {
+				t.Errorf("Guided decoding should not add prompt instructions, got %d messages", len(messages))
+			}
+		})
+	}
+
+	// A single guide is accepted per request
+	req := llm.ChatRequest{
+		Messages:       []llm.Message{llm.NewTextMessage(llm.RoleUser, "Answer")},
+		ResponseFormat: llm.NewJSONResponseFormat(),
+	}.WithProviderOptions(WithGuidedChoice("yes", "no"))
+	if _, err := client.ChatCompletion(context.Background(), req); err == nil {
+		t.Error("Expected error for conflicting guided decoding constraints")
+	}
+}
+
+func TestChatCompletion_Errors(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"status": 429, "title": "Too Many Requests", "detail": "rate limit exceeded"}`)
+	})
+
+	_, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) {
+		t.Fatalf("Expected *llm.Error, got %v", err)
+	}
+	if llmErr.StatusCode != 429 || llmErr.Type != "rate_limit_error" || llmErr.Message != "rate limit exceeded" {
+		t.Errorf("Unexpected error: %+v", llmErr)
+	}
+	if llmErr.RateLimit == nil || llmErr.RateLimit.RetryAfter != 2*time.Second {
+		t.Errorf("Expected Retry-After to be reported, got %+v", llmErr.RateLimit)
+	}
+
+	// Inference servers return OpenAI-style errors at the top level
+	client, _ = newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"object": "error", "message": "max_tokens is too large", "type": "BadRequestError", "code": 400}`)
+	})
+	_, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	if !errors.As(err, &llmErr) || llmErr.Type != "invalid_request_error" || llmErr.Message != "max_tokens is too large" {
+		t.Errorf("Unexpected error: %+v", err)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"x\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var text strings.Builder
+	var arguments strings.Builder
+	var finishReason llm.FinishReason
+	tracker := llm.NewStreamSequenceTracker()
+	for event := range stream {
+		if err := tracker.Observe(event); err != nil || event.Seq == 0 {
+			t.Errorf("Expected sequenced events, got seq %d (%v)", event.Seq, err)
+		}
+		switch {
+		case event.IsError():
+			t.Fatalf("Unexpected error event: %v", event.Error)
+		case event.IsDelta():
+			for _, content := range event.Choice.Delta.Content {
+				text.WriteString(content.(*llm.TextContent).GetText())
+			}
+			for _, tc := range event.Choice.Delta.ToolCalls {
+				if tc.Function != nil {
+					arguments.WriteString(tc.Function.Arguments)
+				}
+			}
+		case event.IsDone():
+			finishReason = event.Choice.FinishReason
+		}
+	}
+
+	if (*lastBody)["stream"] != true {
+		t.Error("Expected stream to be requested")
+	}
+	if text.String() != "Hello" {
+		t.Errorf("Expected 'Hello', got %q", text.String())
+	}
+	if arguments.String() != `{"q":"x"}` {
+		t.Errorf("Unexpected tool arguments %q", arguments.String())
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", finishReason)
+	}
+}
+
+func TestChatCompletion_ContentTransformer(t *testing.T) {
+	client, lastBody := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id": "r", "choices": [{"message": {"role": "assistant", "content": "A cat"}}]}`)
+	})
+
+	llm.RegisterContentTransformer("nim", llm.MessageTypeImage, llm.ContentTransformerFunc(
+		func(ctx context.Context, content llm.MessageContent, model llm.ModelInfo) ([]llm.MessageContent, error) {
+			return []llm.MessageContent{llm.NewTextContent("[Image: a cat on a sofa]")}, nil
+		}))
+	defer llm.UnregisterContentTransformer("nim", llm.MessageTypeImage)
+
+	req := llm.ChatRequest{Messages: []llm.Message{{
+		Role: llm.RoleUser,
+		Content: []llm.MessageContent{
+			llm.NewTextContent("What is this?"),
+			llm.NewImageContentFromBytes([]byte{1, 2, 3}, "image/png"),
+		},
+	}}}
+	if _, err := client.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("Expected transformed image to be accepted by a text-only model, got %v", err)
+	}
+
+	data, _ := json.Marshal((*lastBody)["messages"])
+	if !strings.Contains(string(data), "a cat on a sofa") || strings.Contains(string(data), "image_url") {
+		t.Errorf("Expected image to be replaced by its caption, got %s", data)
+	}
+	if _, ok := req.Messages[0].Content[1].(*llm.ImageContent); !ok {
+		t.Error("Expected the caller's request to be left untouched")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		t.Error("The dry run must not send the request")
+	})
+
+	req := llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "Be brief"),
+			llm.NewTextMessage(llm.RoleUser, "Hello"),
+		},
+	}
+	dryRun, err := llm.DryRun(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if !strings.HasSuffix(dryRun.URL, "/chat/completions") {
+		t.Errorf("Unexpected URL %s", dryRun.URL)
+	}
+	var body chatRequest
+	if err := json.Unmarshal(dryRun.Body, &body); err != nil || body.Model != "meta/llama-3.1-8b-instruct" || len(body.Messages) != 2 {
+		t.Errorf("Unexpected payload %s (%v)", dryRun.Body, err)
+	}
+
+	// Conversion errors are returned as usual
+	image := llm.Message{Role: llm.RoleUser, Content: []llm.MessageContent{llm.NewImageContentFromURL("https://example.com/a.png", "image/png")}}
+	_, err = llm.DryRun(context.Background(), client, llm.ChatRequest{Messages: []llm.Message{image}})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "vision_not_supported" {
+		t.Errorf("Expected vision_not_supported, got %v", err)
+	}
+}
+
+func TestChatCompletion_Warnings(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		_, _ = fmt.Fprint(w, `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	})
+
+	req := llm.ChatRequest{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: []llm.MessageContent{
+			llm.NewTextContent("Summarize"),
+			llm.NewFileContentFromBytes([]byte("a,b\n1,2"), "data.csv", "text/csv"),
+		}}},
+	}
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Code != llm.WarningFileSentAsText || resp.Warnings[0].Param != "messages[0].content[1]" {
+		t.Errorf("Expected a file_sent_as_text warning, got %+v", resp.Warnings)
+	}
+}
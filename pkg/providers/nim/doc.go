@@ -0,0 +1,39 @@
+// Package nim provides an LLM client for NVIDIA NIM.
+//
+// This provider implements the llm.Client interface on top of the OpenAI-compatible
+// chat completions API served by NIM microservices, both the models hosted in the
+// NVIDIA API catalog and self-hosted NIM containers on DGX or other GPU infrastructure.
+//
+// Key features:
+//   - Function calling for models that support it (Llama 3.1+, Nemotron, Mistral Large,
+//     Qwen...), detected from the model name or forced with Extra["supports_tools"]
+//   - Image inputs for vision models, detected from the model name or forced with
+//     Extra["supports_vision"]
+//   - Structured outputs through guided decoding: llm.ResponseFormatJSON and
+//     llm.ResponseFormatJSONSchema are sent as nvext.guided_json, and
+//     llm.ResponseFormatGrammar as nvext.guided_grammar
+//   - Guided choice and regex decoding with the WithGuidedChoice and WithGuidedRegex
+//     request options
+//   - The API key is optional for self-hosted endpoints
+//
+// Usage:
+//
+//	config := llm.ClientConfig{
+//	    Provider: "nim",
+//	    APIKey:   "nvapi-...",
+//	    Model:    "meta/llama-3.1-70b-instruct",
+//	}
+//	client, err := factory.New().CreateClient(config)
+//
+// Self-hosted NIM:
+//
+//	config := llm.ClientConfig{
+//	    Provider: "nim",
+//	    BaseURL:  "http://dgx-01:8000/v1",
+//	    Model:    "meta/llama-3.1-8b-instruct",
+//	}
+//
+// Guided choice:
+//
+//	req := llm.ChatRequest{Messages: messages}.WithProviderOptions(nim.WithGuidedChoice("yes", "no"))
+package nim
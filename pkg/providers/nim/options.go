@@ -0,0 +1,48 @@
+package nim
+
+import (
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// guidedChoiceOption restricts the output to one of a list of strings
+type guidedChoiceOption struct {
+	choices []string
+}
+
+func (guidedChoiceOption) Provider() string { return "nim" }
+
+// WithGuidedChoice returns a request option that constrains the response to exactly one
+// of the given strings, using NIM guided decoding:
+//
+//	req := llm.ChatRequest{Messages: messages}.WithProviderOptions(nim.WithGuidedChoice("positive", "negative"))
+func WithGuidedChoice(choices ...string) llm.ProviderOption {
+	return guidedChoiceOption{choices: choices}
+}
+
+// guidedRegexOption restricts the output to a regular expression
+type guidedRegexOption struct {
+	pattern string
+}
+
+func (guidedRegexOption) Provider() string { return "nim" }
+
+// WithGuidedRegex returns a request option that constrains the response to match a
+// regular expression, using NIM guided decoding.
+func WithGuidedRegex(pattern string) llm.ProviderOption {
+	return guidedRegexOption{pattern: pattern}
+}
+
+// guidedOptions returns the NVIDIA extensions set by the request options, or nil
+func guidedOptions(req llm.ChatRequest) *nvExt {
+	ext := &nvExt{}
+	if option, ok := llm.FindProviderOption[guidedChoiceOption](req); ok {
+		ext.GuidedChoice = option.choices
+	}
+	if option, ok := llm.FindProviderOption[guidedRegexOption](req); ok {
+		ext.GuidedRegex = option.pattern
+	}
+	if ext.guides() == 0 {
+		return nil
+	}
+	return ext
+}
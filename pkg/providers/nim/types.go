@@ -0,0 +1,32 @@
+package nim
+
+import (
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// Wire types for the NVIDIA NIM OpenAI-compatible chat completions API
+
+type chatRequest struct {
+	oaicompat.Request
+	NVExt *nvExt `json:"nvext,omitempty"`
+}
+
+// nvExt holds the NVIDIA extensions of the request. At most one guided decoding
+// field can be set.
+type nvExt struct {
+	GuidedJSON    interface{} `json:"guided_json,omitempty"`
+	GuidedRegex   string      `json:"guided_regex,omitempty"`
+	GuidedChoice  []string    `json:"guided_choice,omitempty"`
+	GuidedGrammar string      `json:"guided_grammar,omitempty"`
+}
+
+// guides returns the number of guided decoding fields set
+func (e *nvExt) guides() int {
+	n := 0
+	for _, set := range []bool{e.GuidedJSON != nil, e.GuidedRegex != "", len(e.GuidedChoice) > 0, e.GuidedGrammar != ""} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
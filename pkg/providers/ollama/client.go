@@ -2,15 +2,14 @@ package ollama
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/inercia/go-llm/pkg/llm"
@@ -72,13 +71,35 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 
+	// Default model options and keep-alive, from ClientConfig.Extra
+	options   OllamaOptions
+	keepAlive string
+
+	// Model computing embeddings, from Extra["embedding_model"] (the chat model when empty)
+	embeddingModel string
+
+	// Chat template applied client-side with the raw completion endpoint, from
+	// Extra["chat_template"]: a preset, or the template shipped with the model
+	template          *llm.ChatTemplate
+	templateFromModel bool
+	templateMu        sync.Mutex
+	modelTemplate     *llm.ChatTemplate
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
+
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new Ollama client
 func NewClient(config llm.ClientConfig) (*Client, error) {
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
+
 	baseURL := config.BaseURL
 	if baseURL == "" {
 		baseURL = DefaultOllamaBaseURL
@@ -97,25 +118,79 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		timeout = 60 * time.Second // Ollama can be slower for local inference
 	}
 
+	options, err := optionsFromExtra(config.Extra)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := chatTemplateFromExtra(config.Extra)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
-		model:   model,
-		baseURL: baseURL,
+		streamBuffer: config.StreamBuffer,
+		stats:        llm.NewHealthStatsWithClock(config.Clock),
+		clock:        config.GetClock(),
+		model:        model,
+		baseURL:      baseURL,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: &llm.DryRunTransport{},
 		},
+		options:           options,
+		keepAlive:         config.Extra["keep_alive"],
+		embeddingModel:    config.Extra["embedding_model"],
+		template:          template,
+		templateFromModel: config.Extra["chat_template"] == ChatTemplateFromModel,
 	}, nil
 }
 
 // ChatCompletion performs a chat completion request using Ollama's API
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.chatCompletion(ctx, req)
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := append(req.ResponseFormatWarnings(req.ResponseFormat.IsJSON()), droppedContentWarnings(req.Messages)...)
+
+	// JSON formats use Ollama's native format field, others fall back to prompt instructions
+	req, err = req.PrepareResponseFormat(req.ResponseFormat.IsJSON())
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to Ollama format
 	ollamaReq := c.convertToOllamaRequest(req)
 
 	// Build URL - Ollama uses /api/chat endpoint
 	url := fmt.Sprintf("%s/api/chat", c.baseURL)
+	var payload interface{} = ollamaReq
+
+	// With a chat template, the prompt is rendered client-side for the raw completion endpoint
+	template, err := c.chatTemplate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if template != nil {
+		if payload, err = c.convertToGenerateRequest(req, ollamaReq, template); err != nil {
+			return nil, err
+		}
+		url = fmt.Sprintf("%s/api/generate", c.baseURL)
+	}
 
-	// Serialize request
-	reqBody, err := json.Marshal(ollamaReq)
+	// Serialize request into a pooled buffer, released by the HTTP client once sent
+	reqBody, err := llm.NewJSONPayload(payload)
 	if err != nil {
 		return nil, &llm.Error{
 			Code:    "request_error",
@@ -125,8 +200,9 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
 	if err != nil {
+		_ = reqBody.Close()
 		return nil, &llm.Error{
 			Code:    "request_error",
 			Message: fmt.Sprintf("Failed to create request: %v", err),
@@ -134,6 +210,7 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		}
 	}
 
+	httpReq.ContentLength = reqBody.Len()
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Make request
@@ -172,21 +249,85 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 		}
 	}
 
+	raw := llm.NewRawResponse(ollamaResp, body)
+
+	// Raw completions return the generated text as the response
+	if ollamaResp.Response != "" {
+		ollamaResp.Message = OllamaMessage{Role: "assistant", Content: ollamaResp.Response}
+	}
+
 	// Convert to our format
-	return c.convertFromOllamaResponse(ollamaResp), nil
+	response := c.convertFromOllamaResponse(ollamaResp)
+	response.Warnings = warnings
+	response.Raw = raw
+	return response, nil
+}
+
+// droppedContentWarnings reports the content replaced by a placeholder in the messages sent
+// to Ollama: file attachments and images given by URL
+func droppedContentWarnings(messages []llm.Message) []llm.Warning {
+	warnings := llm.ContentWarnings(messages, llm.MessageTypeFile, llm.WarningContentDropped, "is not supported and was replaced by a placeholder")
+	for i, msg := range messages {
+		for j, content := range msg.Content {
+			if img, ok := content.(*llm.ImageContent); ok && !img.HasData() && img.HasURL() {
+				warnings = append(warnings, llm.Warning{
+					Code:    llm.WarningContentDropped,
+					Message: "image URLs are not supported and the image was replaced by a placeholder",
+					Param:   fmt.Sprintf("messages[%d].content[%d]", i, j),
+				})
+			}
+		}
+	}
+	return warnings
 }
 
 // StreamChatCompletion performs a streaming chat completion request using Ollama
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON formats use Ollama's native format field, others fall back to prompt instructions
+	req, err = req.PrepareResponseFormat(req.ResponseFormat.IsJSON())
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to Ollama format with stream enabled
 	ollamaReq := c.convertToOllamaRequest(req)
 	ollamaReq.Stream = true
 
 	// Build URL
 	url := fmt.Sprintf("%s/api/chat", c.baseURL)
+	var payload interface{} = ollamaReq
+
+	// With a chat template, the prompt is rendered client-side for the raw completion endpoint
+	template, err := c.chatTemplate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if template != nil {
+		if payload, err = c.convertToGenerateRequest(req, ollamaReq, template); err != nil {
+			return nil, err
+		}
+		url = fmt.Sprintf("%s/api/generate", c.baseURL)
+	}
 
-	// Serialize request
-	reqBody, err := json.Marshal(ollamaReq)
+	// Serialize request into a pooled buffer, released by the HTTP client once sent
+	reqBody, err := llm.NewJSONPayload(payload)
 	if err != nil {
 		return nil, &llm.Error{
 			Code:    "request_error",
@@ -196,8 +337,9 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, reqBody)
 	if err != nil {
+		_ = reqBody.Close()
 		return nil, &llm.Error{
 			Code:    "request_error",
 			Message: fmt.Sprintf("Failed to create request: %v", err),
@@ -205,6 +347,7 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		}
 	}
 
+	httpReq.ContentLength = reqBody.Len()
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Make request
@@ -217,15 +360,16 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		}
 	}
 
-	ch := make(chan llm.StreamEvent, 10)
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		defer func() { _ = resp.Body.Close() }()
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			ch <- llm.NewErrorEvent(c.convertOllamaError(body, resp.StatusCode))
+			emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertOllamaError(body, resp.StatusCode))))
 			return
 		}
 
@@ -242,49 +386,62 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 
 			var ollamaChunk OllamaStreamChunk
 			if err := json.Unmarshal([]byte(line), &ollamaChunk); err != nil {
-				ch <- llm.NewErrorEvent(&llm.Error{
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
 					Code:    "parse_error",
 					Message: fmt.Sprintf("Failed to parse chunk: %v", err),
 					Type:    "client_error",
-				})
+				})))
 				return
 			}
 
 			if ollamaChunk.Done {
-				ch <- llm.NewDoneEvent(0, "stop")
+				reason := ollamaChunk.DoneReason
+				if reason == "" {
+					reason = "stop"
+				}
+				// The final chunk has the token counts and timings
+				done := llm.NewProviderDoneEvent(0, "ollama", reason)
+				done.Raw = llm.NewRawResponse(ollamaChunk, []byte(line))
+				emitter.Emit(seq.Stamp(done))
 				return
 			}
 
-			if ollamaChunk.Message.Content != "" {
+			// Raw completions stream the text in the response field
+			text := ollamaChunk.Message.Content + ollamaChunk.Response
+			if text != "" {
 				// Append to accumulated content
-				accumulatedContent += ollamaChunk.Message.Content
+				accumulatedContent += text
 				delta := &llm.MessageDelta{
-					Content: []llm.MessageContent{llm.NewTextContent(ollamaChunk.Message.Content)},
+					Content: []llm.MessageContent{llm.NewTextContent(text)},
+				}
+				if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
+					return
 				}
-				ch <- llm.NewDeltaEvent(0, delta)
 			}
 
 			// Ollama doesn't support streaming tool calls, so skip if present
 		}
 
 		if err := scanner.Err(); err != nil {
-			ch <- llm.NewErrorEvent(&llm.Error{
+			emitter.Emit(seq.Stamp(llm.NewErrorEvent(&llm.Error{
 				Code:    "stream_error",
 				Message: fmt.Sprintf("Stream scan error: %v", err),
 				Type:    "client_error",
-			})
+			})))
 		}
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
 }
 
 // OllamaStreamChunk represents a streaming chunk from Ollama
 type OllamaStreamChunk struct {
-	Model   string        `json:"model"`
-	Message OllamaMessage `json:"message"`
-	Done    bool          `json:"done"`
-	Error   string        `json:"error,omitempty"`
+	Model      string        `json:"model"`
+	Message    OllamaMessage `json:"message"`
+	Response   string        `json:"response,omitempty"` // raw completions (/api/generate)
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason,omitempty"`
+	Error      string        `json:"error,omitempty"`
 }
 
 // GetRemote returns information about the remote client
@@ -294,26 +451,23 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on the Ollama API
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -323,18 +477,22 @@ func (c *Client) performHealthCheck() bool {
 	// Create HTTP request for listing models (lightweight check)
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return false
+		return err
 	}
 
 	// Make request
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return false
+		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	// Check if we got a successful response
-	return resp.StatusCode == http.StatusOK
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return c.convertOllamaError(body, resp.StatusCode)
+	}
+	return nil
 }
 
 // GetModelInfo returns information about the model
@@ -366,6 +524,16 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	}
 }
 
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
 // Close cleans up resources
 func (c *Client) Close() error {
 	// No cleanup needed for HTTP client
@@ -374,11 +542,13 @@ func (c *Client) Close() error {
 
 // Ollama API structures
 type OllamaRequest struct {
-	Model    string          `json:"model"`
-	Messages []OllamaMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
-	Options  *OllamaOptions  `json:"options,omitempty"`
-	Images   []string        `json:"images,omitempty"` // Base64 encoded images for vision models
+	Model     string          `json:"model"`
+	Messages  []OllamaMessage `json:"messages"`
+	Stream    bool            `json:"stream"`
+	Options   *OllamaOptions  `json:"options,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+	Images    []string        `json:"images,omitempty"` // Base64 encoded images for vision models
+	Format    json.RawMessage `json:"format,omitempty"` // "json" or a JSON schema constraining the output
 }
 
 type OllamaMessage struct {
@@ -386,17 +556,13 @@ type OllamaMessage struct {
 	Content string `json:"content"`
 }
 
-type OllamaOptions struct {
-	Temperature *float32 `json:"temperature,omitempty"`
-	TopP        *float32 `json:"top_p,omitempty"`
-	NumPredict  *int     `json:"num_predict,omitempty"` // Ollama's equivalent to max_tokens
-}
-
 type OllamaResponse struct {
-	Model   string        `json:"model"`
-	Message OllamaMessage `json:"message"`
-	Done    bool          `json:"done"`
-	Error   string        `json:"error,omitempty"`
+	Model      string        `json:"model"`
+	Message    OllamaMessage `json:"message"`
+	Response   string        `json:"response,omitempty"` // raw completions (/api/generate)
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason,omitempty"`
+	Error      string        `json:"error,omitempty"`
 }
 
 // Ollama error structure
@@ -425,18 +591,14 @@ func (c *Client) convertToOllamaRequest(req llm.ChatRequest) OllamaRequest {
 					}
 				case llm.MessageTypeImage:
 					if img, ok := cont.(*llm.ImageContent); ok {
-						var imgData string
 						if img.HasData() {
-							imgData = base64.StdEncoding.EncodeToString(img.Data)
+							images = append(images, llm.EncodeDataURL(img.MimeType, img.Data))
+							contentBuilder.WriteString("[Image attached]")
 						} else if img.HasURL() {
 							// For URLs, we'd need to fetch and encode, but for simplicity, skip or placeholder
 							contentBuilder.WriteString(fmt.Sprintf("[Image: %s]", img.URL))
 							continue
 						}
-						if imgData != "" {
-							images = append(images, fmt.Sprintf("data:%s;base64,%s", img.MimeType, imgData))
-							contentBuilder.WriteString("[Image attached]")
-						}
 					}
 				case llm.MessageTypeFile:
 					if file, ok := cont.(*llm.FileContent); ok {
@@ -470,21 +632,22 @@ func (c *Client) convertToOllamaRequest(req llm.ChatRequest) OllamaRequest {
 	}
 
 	ollamaReq := OllamaRequest{
-		Model:    c.model, // Use client model if not specified
-		Messages: messages,
-		Stream:   req.Stream,
-		Images:   images, // Add collected images at request level
+		Model:     c.model, // Use client model if not specified
+		Messages:  messages,
+		Stream:    req.Stream,
+		Images:    images, // Add collected images at request level
+		KeepAlive: c.keepAlive,
 	}
 
-	// Handle ResponseFormat by adding instructions to the system message
-	// Ollama doesn't support structured outputs natively, so we use prompt engineering
-	if req.ResponseFormat != nil {
-		ollamaReq.Messages = c.addResponseFormatInstructions(ollamaReq.Messages, req.ResponseFormat)
-	}
+	// Formats not enforced natively were turned into instructions by PrepareResponseFormat
+	ollamaReq.Format = convertResponseFormat(req.ResponseFormat)
 
-	// Add options if specified
-	if req.Temperature != nil || req.MaxTokens != nil || req.TopP != nil {
-		options := &OllamaOptions{}
+	// Add options if specified, request options and parameters overriding the client defaults
+	options := c.options
+	if option, ok := llm.FindProviderOption[optionsOption](req); ok {
+		options = options.merge(option.options)
+	}
+	if req.Temperature != nil || req.MaxTokens != nil || req.TopP != nil || !options.isZero() {
 		if req.Temperature != nil {
 			temp := float32(*req.Temperature)
 			options.Temperature = &temp
@@ -496,7 +659,7 @@ func (c *Client) convertToOllamaRequest(req llm.ChatRequest) OllamaRequest {
 			p := float32(*req.TopP)
 			options.TopP = &p
 		}
-		ollamaReq.Options = options
+		ollamaReq.Options = &options
 	}
 
 	return ollamaReq
@@ -514,10 +677,13 @@ func (c *Client) convertFromOllamaResponse(resp OllamaResponse) *llm.ChatRespons
 		},
 	}
 
-	if resp.Done {
-		choice.FinishReason = "stop"
-	} else {
-		choice.FinishReason = "length"
+	switch {
+	case resp.DoneReason != "":
+		choice.SetFinishReason("ollama", resp.DoneReason)
+	case resp.Done:
+		choice.FinishReason = llm.FinishReasonStop
+	default:
+		choice.FinishReason = llm.FinishReasonLength
 	}
 
 	return &llm.ChatResponse{
@@ -534,21 +700,21 @@ func (c *Client) convertOllamaError(body []byte, statusCode int) *llm.Error {
 	// Try to parse as Ollama error format
 	var ollamaErr OllamaError
 	if err := json.Unmarshal(body, &ollamaErr); err == nil && ollamaErr.Error != "" {
-		return &llm.Error{
+		return llm.RedactError(&llm.Error{
 			Code:       fmt.Sprintf("ollama_%d", statusCode),
 			Message:    ollamaErr.Error,
 			Type:       "api_error",
 			StatusCode: statusCode,
-		}
+		})
 	}
 
 	// Fallback for unparseable errors
-	return &llm.Error{
+	return llm.RedactError(&llm.Error{
 		Code:       "ollama_error",
 		Message:    fmt.Sprintf("HTTP %d: %s", statusCode, string(body)),
 		Type:       "api_error",
 		StatusCode: statusCode,
-	}
+	})
 }
 
 // Helper functions
@@ -580,41 +746,18 @@ func (c *Client) convertRoleFromOllama(role string) llm.MessageRole {
 	}
 }
 
-// addResponseFormatInstructions adds JSON formatting instructions to the messages when ResponseFormat is specified
-// Since Ollama doesn't support structured outputs natively, we use prompt engineering
-func (c *Client) addResponseFormatInstructions(messages []OllamaMessage, responseFormat *llm.ResponseFormat) []OllamaMessage {
-	if responseFormat == nil {
-		return messages
-	}
-
-	var instruction string
-	switch responseFormat.Type {
-	case llm.ResponseFormatJSON:
-		instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-	case llm.ResponseFormatJSONSchema:
-		if responseFormat.JSONSchema != nil && responseFormat.JSONSchema.Schema != nil {
-			// Convert schema to string for instruction
-			schemaBytes, err := json.Marshal(responseFormat.JSONSchema.Schema)
-			if err == nil {
-				instruction = fmt.Sprintf("Please respond only with valid JSON that conforms to this schema: %s. Do not include any text before or after the JSON object.", string(schemaBytes))
-			} else {
-				instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-			}
-		} else {
-			instruction = "Please respond only with valid JSON. Do not include any text before or after the JSON object."
-		}
-	default:
-		return messages // No formatting needed for text responses
+// convertResponseFormat converts a JSON response format to Ollama's format field: the
+// schema when there is one, "json" otherwise
+func convertResponseFormat(format *llm.ResponseFormat) json.RawMessage {
+	if !format.IsJSON() {
+		return nil
 	}
-
-	// Add the instruction as a system message at the beginning
-	systemMessage := OllamaMessage{
-		Role:    "system",
-		Content: instruction,
+	if compatible := format.Compatible(); compatible.JSONSchema != nil {
+		if schema, err := compatible.JSONSchema.Schema.MarshalJSON(); err == nil {
+			return schema
+		}
 	}
-
-	// Prepend the system instruction
-	return append([]OllamaMessage{systemMessage}, messages...)
+	return json.RawMessage(`"json"`)
 }
 
 // Model capabilities are now handled by the centralized model registry
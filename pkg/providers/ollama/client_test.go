@@ -0,0 +1,96 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestConvertToOllamaRequest_ResponseFormat(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{model: "gpt-oss:20b"}
+	schema := map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}}
+	tests := []struct {
+		name   string
+		format *llm.ResponseFormat
+		want   string
+	}{
+		{"text", nil, ""},
+		{"json", llm.NewJSONResponseFormat(), `"json"`},
+		{"schema", llm.NewJSONSchemaResponseFormat("person", "", schema), `{"properties":{"name":{"type":"string"}},"type":"object"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := llm.ChatRequest{
+				Messages:             []llm.Message{llm.NewTextMessage(llm.RoleUser, "hi")},
+				ResponseFormat:       tt.format,
+				ResponseFormatPolicy: llm.ResponseFormatPolicyRequireNative,
+			}
+			prepared, err := req.PrepareResponseFormat(req.ResponseFormat.IsJSON())
+			if err != nil {
+				t.Fatalf("PrepareResponseFormat failed: %v", err)
+			}
+			ollamaReq := client.convertToOllamaRequest(prepared)
+			if string(ollamaReq.Format) != tt.want {
+				t.Errorf("Expected format %s, got %s", tt.want, ollamaReq.Format)
+			}
+			if len(ollamaReq.Messages) != 1 {
+				t.Errorf("Expected no format instructions, got %d messages", len(ollamaReq.Messages))
+			}
+		})
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			_, _ = fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hello"}}`)
+			_, _ = fmt.Fprintln(w, `{"done":true,"done_reason":"stop","eval_count":7,"total_duration":1200}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"Hello"},"done":true,"eval_count":7}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{BaseURL: server.URL, Model: "llama3"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")}}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if native, ok := llm.RawValue[OllamaResponse](resp.Raw); !ok || native.Model != "llama3" {
+		t.Errorf("Expected the native response, got %+v", resp.Raw)
+	}
+	if count, _ := resp.Raw.Field("eval_count"); string(count) != "7" {
+		t.Errorf("Expected the fields of the body, got %s", count)
+	}
+
+	events, err := client.StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	var done llm.StreamEvent
+	for event := range events {
+		if event.IsDone() {
+			done = event
+		}
+	}
+	if done.Raw == nil {
+		t.Fatal("Expected the final chunk in the done event")
+	}
+	if duration, _ := done.Raw.Field("total_duration"); string(duration) != "1200" {
+		t.Errorf("Expected the trailing timings, got %s", duration)
+	}
+}
@@ -9,6 +9,11 @@
 // - Multiple model support (Llama, Mistral, CodeLlama, etc.)
 // - Automatic model detection and configuration
 // - Multi-modal content (text, images)
+// - Native JSON mode and JSON schema outputs (the format field)
+// - Client-side chat templates for the raw completion endpoint, from Extra["chat_template"] (a preset, or "model" for the template shipped with the model) or per request with WithChatTemplate
+// - Embeddings (Client.Embeddings), with Extra["embedding_model"] or the chat model
+// - Model warm-up with the keep-alive of the client (Client.WarmUp)
+// - Model options (num_ctx, num_gpu, mirostat...) and keep_alive from ClientConfig.Extra (OllamaOptions), and per request with WithOptions
 //
 // The client connects to a local Ollama instance running on localhost:11434
 // by default, but can be configured to use any Ollama endpoint.
@@ -0,0 +1,124 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// OllamaEmbeddingRequest is the body of the /api/embeddings endpoint
+type OllamaEmbeddingRequest struct {
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// OllamaEmbeddingResponse is the response of the /api/embeddings endpoint
+type OllamaEmbeddingResponse struct {
+	Embedding []float64 `json:"embedding"`
+}
+
+// Client computes the embeddings of llm.MemoryVectorStore documents
+var _ llm.Embedder = (*Client)(nil)
+
+// Embeddings returns the embedding of every text, in order. They are computed by the
+// model of Extra["embedding_model"] (e.g. "nomic-embed-text"), or by the chat model
+// when unset, with the client keep-alive. Texts are sent one at a time.
+func (c *Client) Embeddings(ctx context.Context, texts ...string) ([][]float64, error) {
+	model := c.embeddingModel
+	if model == "" {
+		model = c.model
+	}
+
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		var resp OllamaEmbeddingResponse
+		err := c.post(ctx, "/api/embeddings", OllamaEmbeddingRequest{
+			Model:     model,
+			Prompt:    text,
+			KeepAlive: c.keepAlive,
+		}, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embedding) == 0 {
+			return nil, &llm.Error{
+				Code:    "empty_embedding",
+				Message: fmt.Sprintf("model %s returned no embedding for text %d", model, i),
+				Type:    "api_error",
+			}
+		}
+		embeddings[i] = resp.Embedding
+	}
+	return embeddings, nil
+}
+
+// WarmUp loads the model into memory with an empty generate request, so the first user
+// request does not wait for it. The model stays loaded for the keep-alive duration of
+// the client (Extra["keep_alive"], e.g. "-1" to keep it loaded), or Ollama's default.
+func (c *Client) WarmUp(ctx context.Context) error {
+	return c.post(ctx, "/api/generate", OllamaGenerateRequest{
+		Model:     c.model,
+		KeepAlive: c.keepAlive,
+	}, nil)
+}
+
+// post sends a JSON request to an endpoint, decoding the response into out when given
+func (c *Client) post(ctx context.Context, path string, payload, out interface{}) error {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return &llm.Error{
+			Code:    "request_error",
+			Message: fmt.Sprintf("Failed to serialize request: %v", err),
+			Type:    "client_error",
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return &llm.Error{
+			Code:    "request_error",
+			Message: fmt.Sprintf("Failed to create request: %v", err),
+			Type:    "client_error",
+		}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return &llm.Error{
+			Code:    "network_error",
+			Message: fmt.Sprintf("Request failed: %v", err),
+			Type:    "network_error",
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &llm.Error{
+			Code:    "response_error",
+			Message: fmt.Sprintf("Failed to read response: %v", err),
+			Type:    "client_error",
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c.convertOllamaError(body, resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return &llm.Error{
+				Code:    "parse_error",
+				Message: fmt.Sprintf("Failed to parse response: %v", err),
+				Type:    "client_error",
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestEmbeddings(t *testing.T) {
+	var requests []OllamaEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		var req OllamaEmbeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		if req.Prompt == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"prompt is required"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(OllamaEmbeddingResponse{Embedding: []float64{float64(len(req.Prompt)), 0.5}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{
+		BaseURL: server.URL,
+		Model:   "llama3.1",
+		Extra:   map[string]string{"embedding_model": "nomic-embed-text", "keep_alive": "10m"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	embeddings, err := client.Embeddings(context.Background(), "hi", "hello")
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if want := [][]float64{{2, 0.5}, {5, 0.5}}; !reflect.DeepEqual(embeddings, want) {
+		t.Errorf("Expected embeddings %v, got %v", want, embeddings)
+	}
+	if len(requests) != 2 || requests[0].Model != "nomic-embed-text" || requests[0].KeepAlive != "10m" {
+		t.Errorf("Unexpected requests: %+v", requests)
+	}
+
+	_, err = client.Embeddings(context.Background(), "")
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.StatusCode != http.StatusBadRequest || llmErr.Message != "prompt is required" {
+		t.Errorf("Expected the Ollama error, got %v", err)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	var raw map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&raw)
+		_, _ = w.Write([]byte(`{"model":"llama3.1","response":"","done":true,"done_reason":"load"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{
+		BaseURL: server.URL,
+		Model:   "llama3.1",
+		Extra:   map[string]string{"keep_alive": "-1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if err := client.WarmUp(context.Background()); err != nil {
+		t.Fatalf("WarmUp failed: %v", err)
+	}
+	if raw["model"] != "llama3.1" || raw["prompt"] != "" || raw["keep_alive"] != "-1" || raw["stream"] != false {
+		t.Errorf("Unexpected warm-up request: %v", raw)
+	}
+}
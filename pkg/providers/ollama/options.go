@@ -0,0 +1,161 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// OllamaOptions are the model parameters of an Ollama request (the "options" object of
+// the /api/chat endpoint). Client defaults are set with ClientConfig.Extra, one entry
+// per option using its JSON name (e.g. Extra["num_ctx"] = "8192"); see Extra. Request
+// temperature, top_p and max tokens override the defaults.
+type OllamaOptions struct {
+	// Sampling
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	MinP             *float32 `json:"min_p,omitempty"`
+	TypicalP         *float32 `json:"typical_p,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	NumPredict       *int     `json:"num_predict,omitempty"` // Ollama's equivalent to max_tokens
+	Stop             []string `json:"stop,omitempty"`
+	RepeatPenalty    *float32 `json:"repeat_penalty,omitempty"`
+	RepeatLastN      *int     `json:"repeat_last_n,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	PenalizeNewline  *bool    `json:"penalize_newline,omitempty"`
+	Mirostat         *int     `json:"mirostat,omitempty"` // 0 disabled, 1 Mirostat, 2 Mirostat 2.0
+	MirostatTau      *float32 `json:"mirostat_tau,omitempty"`
+	MirostatEta      *float32 `json:"mirostat_eta,omitempty"`
+
+	// Runtime
+	NumCtx    *int  `json:"num_ctx,omitempty"` // context window size
+	NumKeep   *int  `json:"num_keep,omitempty"`
+	NumBatch  *int  `json:"num_batch,omitempty"`
+	NumGPU    *int  `json:"num_gpu,omitempty"` // layers offloaded to the GPU
+	MainGPU   *int  `json:"main_gpu,omitempty"`
+	NumThread *int  `json:"num_thread,omitempty"`
+	LowVRAM   *bool `json:"low_vram,omitempty"`
+	UseMMap   *bool `json:"use_mmap,omitempty"`
+	UseMLock  *bool `json:"use_mlock,omitempty"`
+	NUMA      *bool `json:"numa,omitempty"`
+}
+
+// Extra returns the ClientConfig.Extra entries that configure these options as client
+// defaults. The keep-alive duration is set separately with Extra["keep_alive"] (e.g.
+// "10m", or "-1" to keep the model loaded).
+func (o OllamaOptions) Extra() map[string]string {
+	extra := make(map[string]string)
+	value := reflect.ValueOf(o)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.IsNil() {
+			continue
+		}
+		name := optionName(value.Type().Field(i))
+		if field.Kind() == reflect.Pointer {
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.Float32:
+			extra[name] = strconv.FormatFloat(field.Float(), 'g', -1, 32)
+		default:
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			_ = encoder.Encode(field.Interface())
+			extra[name] = strings.TrimSpace(buf.String())
+		}
+	}
+	return extra
+}
+
+// isZero reports whether no option is set
+func (o OllamaOptions) isZero() bool {
+	return reflect.ValueOf(o).IsZero()
+}
+
+// optionName returns the JSON name of an option field
+func optionName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// optionsFromExtra parses the options set in ClientConfig.Extra. Entries that are not
+// option names are ignored.
+func optionsFromExtra(extra map[string]string) (OllamaOptions, error) {
+	var options OllamaOptions
+	if len(extra) == 0 {
+		return options, nil
+	}
+
+	names := make(map[string]bool)
+	optionsType := reflect.TypeOf(options)
+	for i := 0; i < optionsType.NumField(); i++ {
+		names[optionName(optionsType.Field(i))] = true
+	}
+
+	values := make(map[string]json.RawMessage)
+	for key, value := range extra {
+		if !names[key] {
+			continue
+		}
+		raw := json.RawMessage(value)
+		if !json.Valid(raw) {
+			return options, &llm.Error{
+				Code:    "invalid_config",
+				Message: fmt.Sprintf("invalid value %q for Ollama option %s", value, key),
+				Type:    "validation_error",
+			}
+		}
+		values[key] = raw
+	}
+	if len(values) == 0 {
+		return options, nil
+	}
+
+	data, err := json.Marshal(values)
+	if err == nil {
+		err = json.Unmarshal(data, &options)
+	}
+	if err != nil {
+		return options, &llm.Error{
+			Code:    "invalid_config",
+			Message: fmt.Sprintf("invalid Ollama options: %v", err),
+			Type:    "validation_error",
+		}
+	}
+	return options, nil
+}
+
+// optionsOption holds the model options of a request
+type optionsOption struct {
+	options OllamaOptions
+}
+
+func (optionsOption) Provider() string { return "ollama" }
+
+// WithOptions returns a request option that sets model options for the request. The
+// options that are set override the client defaults; request temperature, top_p and max
+// tokens still take precedence.
+func WithOptions(options OllamaOptions) llm.ProviderOption {
+	return optionsOption{options: options}
+}
+
+// merge returns the options with the fields set in overrides replaced
+func (o OllamaOptions) merge(overrides OllamaOptions) OllamaOptions {
+	merged := reflect.ValueOf(&o).Elem()
+	value := reflect.ValueOf(overrides)
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Field(i).IsNil() {
+			merged.Field(i).Set(value.Field(i))
+		}
+	}
+	return o
+}
@@ -0,0 +1,136 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestOllamaOptions_Extra(t *testing.T) {
+	t.Parallel()
+
+	numCtx, mirostat := 8192, 2
+	repeatPenalty := float32(1.1)
+	useMMap := false
+	options := OllamaOptions{
+		NumCtx:        &numCtx,
+		Mirostat:      &mirostat,
+		RepeatPenalty: &repeatPenalty,
+		UseMMap:       &useMMap,
+		Stop:          []string{"</answer>"},
+	}
+
+	extra := options.Extra()
+	want := map[string]string{
+		"num_ctx":        "8192",
+		"mirostat":       "2",
+		"repeat_penalty": "1.1",
+		"use_mmap":       "false",
+		"stop":           `["</answer>"]`,
+	}
+	if !reflect.DeepEqual(extra, want) {
+		t.Errorf("Unexpected extra: %v", extra)
+	}
+
+	parsed, err := optionsFromExtra(extra)
+	if err != nil {
+		t.Fatalf("optionsFromExtra failed: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, options) {
+		t.Errorf("Expected options to round-trip, got %+v", parsed)
+	}
+}
+
+func TestNewClient_Options(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(llm.ClientConfig{
+		Model: DefaultOllamaModel,
+		Extra: map[string]string{
+			"keep_alive":  "10m",
+			"num_ctx":     "16384",
+			"num_gpu":     "20",
+			"temperature": "0.2",
+			"unrelated":   "ignored value",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	maxTokens := 128
+	temperature := float32(0.9)
+	ollamaReq := client.convertToOllamaRequest(llm.ChatRequest{
+		Messages:    []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	})
+
+	data, err := json.Marshal(ollamaReq)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var body struct {
+		KeepAlive string         `json:"keep_alive"`
+		Options   map[string]any `json:"options"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if body.KeepAlive != "10m" {
+		t.Errorf("Expected keep_alive 10m, got %q", body.KeepAlive)
+	}
+	if body.Options["num_ctx"] != 16384.0 || body.Options["num_gpu"] != 20.0 || body.Options["num_predict"] != 128.0 {
+		t.Errorf("Unexpected options: %v", body.Options)
+	}
+	if temp, _ := body.Options["temperature"].(float64); float32(temp) != temperature {
+		t.Errorf("Expected the request temperature to win, got %v", body.Options["temperature"])
+	}
+	if client.options.Temperature == nil || *client.options.Temperature != 0.2 {
+		t.Error("Expected the client defaults to be untouched")
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(llm.ClientConfig{
+		Model: DefaultOllamaModel,
+		Extra: map[string]string{"num_ctx": "4096", "seed": "1"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	numCtx := 32768
+	req := llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	}.WithProviderOptions(WithOptions(OllamaOptions{NumCtx: &numCtx}))
+
+	options := client.convertToOllamaRequest(req).Options
+	if options == nil || *options.NumCtx != numCtx || options.Seed == nil || *options.Seed != 1 {
+		t.Errorf("Expected the request options merged over the defaults, got %+v", options)
+	}
+	if *client.options.NumCtx != 4096 {
+		t.Error("Expected the client defaults to be untouched")
+	}
+}
+
+func TestNewClient_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	var llmErr *llm.Error
+	for _, extra := range []map[string]string{
+		{"num_ctx": "large"},
+		{"num_ctx": `"8192"`},
+	} {
+		_, err := NewClient(llm.ClientConfig{Model: DefaultOllamaModel, Extra: extra})
+		if !errors.As(err, &llmErr) || llmErr.Code != "invalid_config" {
+			t.Errorf("Expected invalid_config for %v, got %v", extra, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// ChatTemplateFromModel is the ClientConfig.Extra["chat_template"] value that applies the
+// template shipped with the model (from /api/show) client-side
+const ChatTemplateFromModel = "model"
+
+// OllamaGenerateRequest is a raw completion request of the /api/generate endpoint, used
+// when the chat template is applied client-side
+type OllamaGenerateRequest struct {
+	Model     string          `json:"model"`
+	Prompt    string          `json:"prompt"`
+	Raw       bool            `json:"raw"`
+	Stream    bool            `json:"stream"`
+	Options   *OllamaOptions  `json:"options,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+	Images    []string        `json:"images,omitempty"`
+	Format    json.RawMessage `json:"format,omitempty"`
+}
+
+// chatTemplateOption holds the chat template of a request
+type chatTemplateOption struct {
+	template *llm.ChatTemplate
+}
+
+func (chatTemplateOption) Provider() string { return "ollama" }
+
+// WithChatTemplate returns a request option that renders the conversation with a chat
+// template and sends it to the raw completion endpoint (/api/generate with raw mode),
+// overriding Extra["chat_template"]:
+//
+//	tmpl, _ := llm.ChatTemplatePreset("chatml")
+//	req := llm.ChatRequest{Messages: messages}.WithProviderOptions(ollama.WithChatTemplate(tmpl))
+func WithChatTemplate(template *llm.ChatTemplate) llm.ProviderOption {
+	return chatTemplateOption{template: template}
+}
+
+// chatTemplateFromExtra returns the chat template preset configured with
+// Extra["chat_template"], or nil for none or ChatTemplateFromModel
+func chatTemplateFromExtra(extra map[string]string) (*llm.ChatTemplate, error) {
+	name := extra["chat_template"]
+	if name == "" || name == ChatTemplateFromModel {
+		return nil, nil
+	}
+	template, ok := llm.ChatTemplatePreset(name)
+	if !ok {
+		return nil, &llm.Error{
+			Code:    "invalid_config",
+			Message: fmt.Sprintf("unknown chat template %q (available: %s, or %q)", name, strings.Join(llm.ChatTemplatePresets(), ", "), ChatTemplateFromModel),
+			Type:    "validation_error",
+		}
+	}
+	return template, nil
+}
+
+// chatTemplate returns the chat template to apply to a request, or nil to use the chat endpoint
+func (c *Client) chatTemplate(ctx context.Context, req llm.ChatRequest) (*llm.ChatTemplate, error) {
+	if option, ok := llm.FindProviderOption[chatTemplateOption](req); ok && option.template != nil {
+		return option.template, nil
+	}
+	if c.template != nil || !c.templateFromModel {
+		return c.template, nil
+	}
+
+	c.templateMu.Lock()
+	defer c.templateMu.Unlock()
+	if c.modelTemplate == nil {
+		template, err := c.fetchModelTemplate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.modelTemplate = template
+	}
+	return c.modelTemplate, nil
+}
+
+// fetchModelTemplate parses the template and the stop parameters shipped with the model
+func (c *Client) fetchModelTemplate(ctx context.Context) (*llm.ChatTemplate, error) {
+	reqBody, _ := json.Marshal(map[string]string{"model": c.model})
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/show", c.baseURL), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, &llm.Error{
+			Code:    "request_error",
+			Message: fmt.Sprintf("Failed to create request: %v", err),
+			Type:    "client_error",
+		}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &llm.Error{
+			Code:    "network_error",
+			Message: fmt.Sprintf("Request failed: %v", err),
+			Type:    "network_error",
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.convertOllamaError(body, resp.StatusCode)
+	}
+
+	var show struct {
+		Template   string `json:"template"`
+		Parameters string `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &show); err != nil {
+		return nil, &llm.Error{
+			Code:    "parse_error",
+			Message: fmt.Sprintf("Failed to parse model information: %v", err),
+			Type:    "client_error",
+		}
+	}
+	if show.Template == "" {
+		return nil, &llm.Error{
+			Code:    "invalid_chat_template",
+			Message: fmt.Sprintf("model %s does not ship a chat template", c.model),
+			Type:    "validation_error",
+		}
+	}
+	return llm.NewChatTemplate(c.model, show.Template, parseStopParameters(show.Parameters)...)
+}
+
+// parseStopParameters returns the stop sequences of the Modelfile parameters, one
+// parameter per line (e.g. `stop "<|im_end|>"`)
+func parseStopParameters(parameters string) []string {
+	var stop []string
+	for _, line := range strings.Split(parameters, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || name != "stop" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		stop = append(stop, value)
+	}
+	return stop
+}
+
+// convertToGenerateRequest renders the request with a chat template into a raw completion
+// request, keeping the options, images and format of the chat request
+func (c *Client) convertToGenerateRequest(req llm.ChatRequest, chatReq OllamaRequest, template *llm.ChatTemplate) (OllamaGenerateRequest, error) {
+	prompt, err := template.Render(req.Messages, req.Tools)
+	if err != nil {
+		return OllamaGenerateRequest{}, err
+	}
+
+	options := chatReq.Options
+	if stop := template.Stop(); len(stop) > 0 && (options == nil || len(options.Stop) == 0) {
+		if options == nil {
+			options = &OllamaOptions{}
+		}
+		options.Stop = stop
+	}
+
+	return OllamaGenerateRequest{
+		Model:     chatReq.Model,
+		Prompt:    prompt,
+		Raw:       true,
+		Stream:    chatReq.Stream,
+		Options:   options,
+		KeepAlive: chatReq.KeepAlive,
+		Images:    chatReq.Images,
+		Format:    chatReq.Format,
+	}, nil
+}
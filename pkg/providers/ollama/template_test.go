@@ -0,0 +1,137 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// newRawServer starts a server answering /api/show with the given model template, and
+// /api/generate with a raw completion, recording the generate requests
+func newRawServer(t *testing.T, modelTemplate string) (*httptest.Server, *[]OllamaGenerateRequest) {
+	t.Helper()
+
+	var requests []OllamaGenerateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"template":   modelTemplate,
+				"parameters": "stop \"<|end|>\"\ntemperature 0.7",
+			})
+		case "/api/generate":
+			var req OllamaGenerateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			requests = append(requests, req)
+			if req.Stream {
+				for _, chunk := range []string{`{"response":"Hel"}`, `{"response":"lo"}`, `{"done":true,"done_reason":"stop"}`} {
+					_, _ = fmt.Fprintln(w, chunk)
+				}
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"model":"phi3","response":"Hello","done":true,"done_reason":"stop"}`)
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestChatTemplate_Preset(t *testing.T) {
+	server, requests := newRawServer(t, "")
+
+	client, err := NewClient(llm.ClientConfig{
+		BaseURL: server.URL,
+		Model:   "qwen2.5-base",
+		Extra:   map[string]string{"chat_template": "chatml"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "Be brief."),
+			llm.NewTextMessage(llm.RoleUser, "Hi"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.GetText() != "Hello" || resp.Choices[0].Message.Role != llm.RoleAssistant {
+		t.Errorf("Unexpected response: %+v", resp.Choices[0].Message)
+	}
+
+	req := (*requests)[0]
+	want := "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n"
+	if !req.Raw || req.Prompt != want {
+		t.Errorf("Unexpected raw request: raw=%v prompt=%q", req.Raw, req.Prompt)
+	}
+	if req.Options == nil || len(req.Options.Stop) != 1 || req.Options.Stop[0] != "<|im_end|>" {
+		t.Errorf("Expected the template stop sequences, got %+v", req.Options)
+	}
+
+	if _, err := NewClient(llm.ClientConfig{Extra: map[string]string{"chat_template": "unknown"}}); err == nil {
+		t.Error("Expected error for unknown chat template")
+	}
+}
+
+func TestChatTemplate_FromModel(t *testing.T) {
+	server, requests := newRawServer(t, "{{ if .System }}<|system|>\n{{ .System }}<|end|>\n{{ end }}<|user|>\n{{ .Prompt }}<|end|>\n<|assistant|>\n")
+
+	client, err := NewClient(llm.ClientConfig{
+		BaseURL: server.URL,
+		Model:   "phi3",
+		Extra:   map[string]string{"chat_template": ChatTemplateFromModel},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	stream, err := client.StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var text strings.Builder
+	for event := range stream {
+		if event.IsError() {
+			t.Fatalf("Unexpected error event: %v", event.Error)
+		}
+		if event.IsDelta() {
+			text.WriteString(event.Choice.Delta.Content[0].(*llm.TextContent).GetText())
+		}
+	}
+	if text.String() != "Hello" {
+		t.Errorf("Expected streamed 'Hello', got %q", text.String())
+	}
+
+	req := (*requests)[0]
+	if req.Prompt != "<|user|>\nHi<|end|>\n<|assistant|>\n" || !req.Stream {
+		t.Errorf("Unexpected prompt %q", req.Prompt)
+	}
+	if req.Options == nil || len(req.Options.Stop) != 1 || req.Options.Stop[0] != "<|end|>" {
+		t.Errorf("Expected the stop parameters of the model, got %+v", req.Options)
+	}
+
+	// Request templates override the client configuration
+	tmpl, _ := llm.ChatTemplatePreset("gemma")
+	_, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	}.WithProviderOptions(WithChatTemplate(tmpl)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if prompt := (*requests)[1].Prompt; !strings.HasPrefix(prompt, "<start_of_turn>user\nHi") {
+		t.Errorf("Expected the request template, got %q", prompt)
+	}
+}
@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"io"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -71,9 +72,19 @@ type Client struct {
 	provider string
 	baseURL  string
 
+	// apiKey, organization and httpClient are used by the endpoints not covered by the
+	// SDK (uploads)
+	apiKey       string
+	organization string
+	httpClient   *http.Client
+
+	// streamBuffer configures the buffer of the streams
+	streamBuffer llm.StreamBufferConfig
+
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new OpenAI client
@@ -85,28 +96,94 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 			Type:    "authentication_error",
 		}
 	}
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
 
 	clientConfig := openai.DefaultConfig(config.APIKey)
 	if config.BaseURL != "" {
 		clientConfig.BaseURL = config.BaseURL
 	}
+	clientConfig.OrgID = config.Organization
 
-	// Note: go-openai doesn't expose HTTPClient.Timeout directly
-	// This would be handled differently in the actual implementation
+	// Record rate-limit headers so they can be attached to errors, and merge the ExtraBody
+	// fields of requests into their payload
+	var transport http.RoundTripper = &llm.RateLimitTransport{Base: &llm.ExtraBodyTransport{Base: &llm.DryRunTransport{}}}
+	if config.Project != "" {
+		// The SDK has no setting for the project
+		transport = &projectTransport{base: transport, project: config.Project}
+	}
+	httpClient := &http.Client{Transport: transport}
+	clientConfig.HTTPClient = httpClient
 
 	return &Client{
-		client:   openai.NewClientWithConfig(clientConfig),
-		model:    config.Model,
-		provider: "openai",
-		baseURL:  config.BaseURL,
+		stats:        llm.NewHealthStatsWithClock(config.Clock),
+		clock:        config.GetClock(),
+		client:       openai.NewClientWithConfig(clientConfig),
+		model:        config.Model,
+		provider:     "openai",
+		baseURL:      config.BaseURL,
+		apiKey:       config.APIKey,
+		organization: config.Organization,
+		httpClient:   httpClient,
+		streamBuffer: config.StreamBuffer,
 	}, nil
 }
 
+// projectTransport adds the OpenAI-Project header to the requests, for all the endpoints
+// of the API
+type projectTransport struct {
+	base    http.RoundTripper
+	project string
+}
+
+// RoundTrip sends a copy of the request with the header
+func (t *projectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("OpenAI-Project", t.project)
+	return t.base.RoundTrip(req)
+}
+
 // ChatCompletion performs a chat completion request
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	resp, err := c.chatCompletion(ctx, req)
+	err = llm.AttachRateLimit(err, rateLimit.Info())
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	// File attachments are not supported by the Chat Completions API and are left out
+	warnings := append(req.ResponseFormatWarnings(req.ResponseFormat.IsJSON()),
+		llm.ContentWarnings(req.Messages, llm.MessageTypeFile, llm.WarningContentDropped, "is not supported and was not sent")...)
+	req, err = prepareResponseFormat(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Auto-select appropriate model for multi-modal content
 	model := c.selectModelForRequest(req)
 
+	// Hosted tools are only available with the Responses API
+	if tools := hostedTools(req); tools != nil {
+		response, err := c.responsesCompletion(ctx, req, model, tools)
+		if err != nil {
+			return nil, err
+		}
+		response.Warnings = warnings
+		return response, nil
+	}
+
 	// Convert our request to OpenAI format
 	openaiReq := c.convertRequest(req, model)
 
@@ -117,13 +194,43 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Convert response back to our format
-	return c.convertResponse(resp), nil
+	response := c.convertResponse(resp)
+	response.Warnings = warnings
+	return response, nil
 }
 
 // StreamChatCompletion performs a streaming chat completion request using OpenAI
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		err = llm.AttachRateLimit(err, rateLimit.Info())
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = prepareResponseFormat(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Auto-select appropriate model for multi-modal content
 	model := c.selectModelForRequest(req)
+	if tools := hostedTools(req); tools != nil {
+		return c.streamResponses(ctx, req, model, tools)
+	}
 
 	// Convert our request to OpenAI format (Stream is already set to true if requested)
 	openaiReq := c.convertRequest(req, model)
@@ -137,28 +244,40 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		return nil, c.convertError(err)
 	}
 
-	ch := make(chan llm.StreamEvent, 10)
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		defer func() { _ = stream.Close() }()
 
+		finishReason := string(openai.FinishReasonStop)
+		// The last chunk has the trailing metadata, e.g. the usage with include_usage
+		var last *openai.ChatCompletionStreamResponse
 		for {
 			response, err := stream.Recv()
 			if err == io.EOF {
 				// Stream complete
-				ch <- llm.NewDoneEvent(0, "stop")
+				done := llm.NewProviderDoneEvent(0, c.provider, finishReason)
+				if last != nil {
+					done.Raw = llm.NewRawResponse(*last, nil)
+				}
+				emitter.Emit(seq.Stamp(done))
 				return
 			}
 			if err != nil {
-				ch <- llm.NewErrorEvent(c.convertError(err))
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
 				return
 			}
+			last = &response
 
 			// Convert chunk to delta event
 			delta := &llm.MessageDelta{}
 			if len(response.Choices) > 0 {
 				choice := response.Choices[0]
+				if choice.FinishReason != "" {
+					finishReason = string(choice.FinishReason)
+				}
 				if choice.Delta.Content != "" {
 					delta.Content = []llm.MessageContent{llm.NewTextContent(choice.Delta.Content)}
 				}
@@ -180,12 +299,14 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 					}
 				}
 
-				ch <- llm.NewDeltaEvent(0, delta)
+				if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
+					return
+				}
 			}
 		}
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
 }
 
 // GetRemote returns information about the remote client
@@ -195,32 +316,32 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on the OpenAI API
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	// Try to list models as a health check
 	_, err := c.client.ListModels(ctx)
-	return err == nil
+	if err != nil {
+		return c.convertError(err)
+	}
+	return nil
 }
 
 // GetModelInfo returns information about the model being used
@@ -236,6 +357,16 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	}
 }
 
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
 // Close cleans up any resources used by the client
 func (c *Client) Close() error {
 	// OpenAI client doesn't require explicit cleanup
@@ -266,7 +397,23 @@ func (c *Client) selectModelForRequest(req llm.ChatRequest) string {
 	return c.model
 }
 
-// convertRequest converts our ChatRequest to OpenAI format
+// prepareResponseFormat applies the ResponseFormatPolicy, OpenAI only enforcing JSON formats
+// natively, and checks the schema of native formats
+func prepareResponseFormat(req llm.ChatRequest) (llm.ChatRequest, error) {
+	req, err := req.PrepareResponseFormat(req.ResponseFormat.IsJSON())
+	if err != nil {
+		return req, err
+	}
+	if req.ResponseFormat.IsJSON() {
+		if err := req.ResponseFormat.Lint(); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// convertRequest converts our ChatRequest to OpenAI format, with the response format
+// already prepared by prepareResponseFormat
 func (c *Client) convertRequest(req llm.ChatRequest, model string) openai.ChatCompletionRequest {
 	openaiReq := openai.ChatCompletionRequest{
 		Model:    model,
@@ -285,6 +432,15 @@ func (c *Client) convertRequest(req llm.ChatRequest, model string) openai.ChatCo
 		openaiReq.TopP = *req.TopP
 	}
 
+	// Forward request metadata (OpenAI only accepts string values)
+	if len(req.Metadata) > 0 {
+		openaiReq.Metadata = req.MetadataAsStrings()
+	}
+	openaiReq.User = req.User
+
+	// Apply the OpenAI provider options
+	openaiReq.LogitBias = logitBias(req)
+
 	// Convert tools
 	if len(req.Tools) > 0 {
 		for _, tool := range req.Tools {
@@ -301,31 +457,16 @@ func (c *Client) convertRequest(req llm.ChatRequest, model string) openai.ChatCo
 	}
 
 	// Handle response format
-	if req.ResponseFormat != nil {
-		switch req.ResponseFormat.Type {
-		case llm.ResponseFormatJSON:
-			openaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
-				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
-			}
-		case llm.ResponseFormatJSONSchema:
-			if req.ResponseFormat.JSONSchema != nil {
-				jsonSchema := &openai.ChatCompletionResponseFormatJSONSchema{
-					Name:        req.ResponseFormat.JSONSchema.Name,
-					Description: req.ResponseFormat.JSONSchema.Description,
-				}
-				// Handle Schema conversion - skip for now due to interface{} to json.Marshaler issue
-				// TODO: Implement proper schema conversion
-				// if req.ResponseFormat.JSONSchema.Schema != nil {
-				//     jsonSchema.Schema = req.ResponseFormat.JSONSchema.Schema
-				// }
-				if req.ResponseFormat.JSONSchema.Strict != nil {
-					jsonSchema.Strict = *req.ResponseFormat.JSONSchema.Strict
-				}
-
-				openaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
-					Type:       openai.ChatCompletionResponseFormatTypeJSONSchema,
-					JSONSchema: jsonSchema,
-				}
+	if format := req.ResponseFormat.Compatible(); format != nil {
+		openaiReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatType(format.Type),
+		}
+		if format.JSONSchema != nil {
+			openaiReq.ResponseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        format.JSONSchema.Name,
+				Description: format.JSONSchema.Description,
+				Schema:      format.JSONSchema.Schema,
+				Strict:      format.JSONSchema.Strict,
 			}
 		}
 	}
@@ -442,13 +583,18 @@ func (c *Client) convertResponse(resp openai.ChatCompletionResponse) *llm.ChatRe
 			CompletionTokens: resp.Usage.CompletionTokens,
 			TotalTokens:      resp.Usage.TotalTokens,
 		},
+		Raw: llm.NewRawResponse(resp, nil),
+	}
+	if details := resp.Usage.PromptTokensDetails; details != nil {
+		chatResp.Usage.CacheReadTokens = details.CachedTokens
 	}
 
 	for _, choice := range resp.Choices {
 		ourChoice := llm.Choice{
-			Index:        choice.Index,
-			Message:      c.convertMessage(choice.Message),
-			FinishReason: string(choice.FinishReason),
+			Index:           choice.Index,
+			Message:         c.convertMessage(choice.Message),
+			FinishReason:    llm.NormalizeFinishReason(c.provider, string(choice.FinishReason)),
+			RawFinishReason: string(choice.FinishReason),
 		}
 		chatResp.Choices = append(chatResp.Choices, ourChoice)
 	}
@@ -503,20 +649,20 @@ func (c *Client) convertError(err error) *llm.Error {
 				code = codeStr
 			}
 		}
-		return &llm.Error{
+		return llm.RedactError(&llm.Error{
 			Code:       code,
 			Message:    apiErr.Message,
 			Type:       apiErr.Type,
 			StatusCode: apiErr.HTTPStatusCode,
-		}
+		})
 	}
 
 	// Generic error
-	return &llm.Error{
+	return llm.RedactError(&llm.Error{
 		Code:    "unknown_error",
 		Message: err.Error(),
 		Type:    "api_error",
-	}
+	})
 }
 
 // getMaxTokensForModel returns max tokens for the model
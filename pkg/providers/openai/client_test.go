@@ -1,8 +1,17 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
 
 	"github.com/inercia/go-llm/pkg/llm"
 )
@@ -166,3 +175,208 @@ func TestOpenAI_PublicAPI_ImageFromURL(t *testing.T) {
 		t.Errorf("Expected model gpt-4o, got %s", req.Model)
 	}
 }
+
+// TestOpenAI_ConvertRequestMetadata tests that request metadata is forwarded as strings
+func TestOpenAI_ConvertRequestMetadata(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{model: "gpt-4o-mini"}
+	req := llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hello")},
+		Metadata: map[string]any{"tenant_id": "acme", "user_id": 7},
+	}
+
+	openaiReq := client.convertRequest(req, client.model)
+	if openaiReq.Metadata["tenant_id"] != "acme" || openaiReq.Metadata["user_id"] != "7" {
+		t.Errorf("unexpected metadata: %v", openaiReq.Metadata)
+	}
+
+	openaiReq = client.convertRequest(llm.ChatRequest{Messages: req.Messages}, client.model)
+	if openaiReq.Metadata != nil {
+		t.Errorf("expected no metadata, got %v", openaiReq.Metadata)
+	}
+}
+
+// TestOpenAI_ConvertRequestLogitBias tests that the logit bias option is applied and
+// options of other providers are ignored
+func TestOpenAI_ConvertRequestLogitBias(t *testing.T) {
+	t.Parallel()
+
+	client := &Client{model: "gpt-4o-mini"}
+	req := llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "hello")},
+	}.WithProviderOptions(otherProviderOption{}, WithLogitBias(map[int]int{50256: -100}))
+
+	openaiReq := client.convertRequest(req, client.model)
+	if len(openaiReq.LogitBias) != 1 || openaiReq.LogitBias["50256"] != -100 {
+		t.Errorf("unexpected logit bias: %v", openaiReq.LogitBias)
+	}
+
+	openaiReq = client.convertRequest(llm.ChatRequest{Messages: req.Messages}, client.model)
+	if openaiReq.LogitBias != nil {
+		t.Errorf("expected no logit bias, got %v", openaiReq.LogitBias)
+	}
+}
+
+// otherProviderOption is an option for another provider
+type otherProviderOption struct{}
+
+func (otherProviderOption) Provider() string { return "other" }
+
+// TestOpenAI_RateLimitInfo tests that rate-limit headers are attached to errors
+func TestOpenAI_RateLimitInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: "gpt-4o-mini", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+	})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 error, got %v", err)
+	}
+	if llmErr.RateLimit == nil || llmErr.RateLimit.Delay() != 7*time.Second {
+		t.Errorf("Expected rate-limit details with a 7s delay, got %+v", llmErr.RateLimit)
+	}
+	if llmErr.RateLimit.RemainingRequests == nil || *llmErr.RateLimit.RemainingRequests != 0 {
+		t.Errorf("Expected remaining requests to be reported, got %+v", llmErr.RateLimit)
+	}
+}
+
+// TestOpenAI_ExtraBody tests that the ExtraBody fields are merged into the payload
+func TestOpenAI_ExtraBody(t *testing.T) {
+	client, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: "gpt-4o-mini"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	sent, err := llm.DryRun(context.Background(), client, llm.ChatRequest{
+		Messages:  []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+		ExtraBody: map[string]any{"reasoning_effort": "low", "model": "gpt-4o", "messages": nil},
+	})
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	body := string(sent.Body)
+	if !strings.Contains(body, `"reasoning_effort": "low"`) || !strings.Contains(body, `"model": "gpt-4o"`) {
+		t.Errorf("Expected the extra fields in the payload, got %s", body)
+	}
+	if strings.Contains(body, `"messages"`) {
+		t.Errorf("Expected the messages to be removed, got %s", body)
+	}
+}
+
+// TestOpenAI_AccountHeaders tests that the organization and project headers are sent to
+// all the endpoints, with the user of the requests
+func TestOpenAI_AccountHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("OpenAI-Organization") != "org-acme" || r.Header.Get("OpenAI-Project") != "proj_support" {
+			t.Errorf("Missing account headers on %s: %v", r.URL.Path, r.Header)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat/completions":
+			var body struct {
+				User string `json:"user"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.User != "user-42" {
+				t.Errorf("Expected the user, got %q", body.User)
+			}
+			_, _ = fmt.Fprint(w, `{"id": "1", "choices": [{"message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}]}`)
+		case "/audio/transcriptions":
+			_, _ = fmt.Fprint(w, `{"text": "Hello"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{
+		APIKey:       "test-key",
+		Model:        "gpt-4o-mini",
+		BaseURL:      server.URL,
+		Organization: "org-acme",
+		Project:      "proj_support",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")},
+		User:     "user-42",
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	audio := llm.NewAudioContentFromBytes([]byte("RIFF"), "audio/wav")
+	if _, err := client.Transcribe(context.Background(), audio, llm.TranscriptionOptions{}); err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+}
+
+// TestOpenAI_RawResponse tests that responses keep the native response, and done events
+// the trailing usage chunk of streams
+func TestOpenAI_RawResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"id": "1", "system_fingerprint": "fp_1", "choices": [{"message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}]}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"id\": \"2\", \"choices\": [{\"delta\": {\"content\": \"Hello\"}, \"finish_reason\": \"stop\"}]}\n\n")
+		_, _ = fmt.Fprint(w, "data: {\"id\": \"2\", \"choices\": [], \"usage\": {\"prompt_tokens\": 3, \"completion_tokens\": 1, \"total_tokens\": 4}}\n\n")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: "gpt-4o-mini", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hi")}}
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	native, ok := llm.RawValue[openai.ChatCompletionResponse](resp.Raw)
+	if !ok || native.SystemFingerprint != "fp_1" {
+		t.Errorf("Expected the native response, got %+v", resp.Raw)
+	}
+	if fingerprint, _ := resp.Raw.Field("system_fingerprint"); string(fingerprint) != `"fp_1"` {
+		t.Errorf("Unexpected fingerprint field: %s", fingerprint)
+	}
+
+	events, err := client.StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	var done llm.StreamEvent
+	for event := range events {
+		if event.IsDone() {
+			done = event
+		}
+	}
+	if done.Raw == nil {
+		t.Fatal("Expected the last chunk in the done event")
+	}
+	if tokens, _ := done.Raw.Field("usage.total_tokens"); string(tokens) != "4" {
+		t.Errorf("Expected the trailing usage, got %s", tokens)
+	}
+}
@@ -11,6 +11,15 @@
 // - Multi-modal content (text, images, files)
 // - JSON mode and structured output
 // - Automatic model selection for multi-modal content
+// - Per-request logit bias (WithLogitBias)
+// - Hosted web search and file search through the Responses API (WithWebSearch, WithFileSearch)
+// - Vector stores of documents for file search and RAGMiddleware (VectorStore)
+// - Usage attribution with the organization and project headers and the end user of requests
+// - Realtime API sessions over WebSocket (RealtimeClient) with session resumption
+// - Chunked, resumable file uploads with the Uploads API (UploadFile)
+// - Speech to text with Whisper and gpt-4o-transcribe (Transcribe)
+// - Text to speech, whole or streamed (Speak, StreamSpeech)
+// - Fine-tuning jobs and training file uploads (FineTuneClient)
 //
 // The client automatically handles provider-specific request/response
 // transformations while maintaining compatibility with the common llm interfaces.
@@ -0,0 +1,216 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// defaultAPIURL is the OpenAI API URL, used when the client has no base URL
+	defaultAPIURL = "https://api.openai.com/v1"
+
+	// DefaultUploadPurpose is the purpose of uploads without one, for files used as
+	// inputs of chat requests
+	DefaultUploadPurpose = "user_data"
+
+	// maxUploadPartSize is the largest part accepted by the Uploads API
+	maxUploadPartSize = 64 * 1024 * 1024
+)
+
+// uploadObject is an upload of the Uploads API
+type uploadObject struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	File   *struct {
+		ID       string `json:"id"`
+		Bytes    int64  `json:"bytes"`
+		Filename string `json:"filename"`
+	} `json:"file,omitempty"`
+}
+
+// UploadFile uploads a file with the Uploads API, in parts of upload.ChunkSize bytes (at
+// most 64MB) read from the reader, so large files are never held in memory. The parts
+// are sent in order and the upload is completed once the SHA-256 digest of the content
+// has been verified. On failure after the upload was created, the error has the
+// "upload_interrupted" code and the session to resume from in its Upload field.
+func (c *Client) UploadFile(ctx context.Context, upload *llm.FileUpload) (*llm.UploadedFile, error) {
+	if err := upload.Validate(); err != nil {
+		return nil, err
+	}
+
+	session := upload.Resume
+	if session == nil {
+		purpose := upload.Purpose
+		if purpose == "" {
+			purpose = DefaultUploadPurpose
+		}
+		var created uploadObject
+		err := c.postJSON(ctx, "/uploads", map[string]interface{}{
+			"purpose":   purpose,
+			"filename":  upload.Filename,
+			"bytes":     upload.Size,
+			"mime_type": upload.MimeType,
+		}, &created)
+		if err != nil {
+			return nil, err
+		}
+		session = &llm.UploadSession{Provider: c.provider, ID: created.ID}
+	} else {
+		// Do not modify the session of the caller
+		resumed := *session
+		resumed.Parts = append([]string(nil), session.Parts...)
+		session = &resumed
+	}
+
+	var completed uploadObject
+	digest, err := llm.SendChunks(ctx, upload, min(upload.ChunkSize, maxUploadPartSize), func(ctx context.Context, chunk []byte, offset int64, last bool) error {
+		if len(chunk) > 0 {
+			partID, err := c.uploadPart(ctx, session.ID, chunk)
+			if err != nil {
+				return err
+			}
+			session.Parts = append(session.Parts, partID)
+			session.Offset = offset + int64(len(chunk))
+		}
+		if !last {
+			return nil
+		}
+		return c.postJSON(ctx, "/uploads/"+session.ID+"/complete", map[string]interface{}{
+			"part_ids": session.Parts,
+		}, &completed)
+	})
+	if err != nil {
+		if llmErr, ok := err.(*llm.Error); ok && llmErr.Code == "integrity_mismatch" {
+			return nil, err
+		}
+		return nil, llm.NewUploadInterruptedError(session, err)
+	}
+
+	file := &llm.UploadedFile{
+		ID:       completed.ID,
+		Filename: upload.Filename,
+		MimeType: upload.MimeType,
+		Size:     upload.Size,
+		SHA256:   digest,
+	}
+	if completed.File != nil {
+		file.ID = completed.File.ID
+		file.Size = completed.File.Bytes
+	}
+	return file, nil
+}
+
+// uploadPart sends a part of an upload, returning its ID
+func (c *Client) uploadPart(ctx context.Context, uploadID string, chunk []byte) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("data", "part")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(chunk); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	err = c.do(ctx, http.MethodPost, "/uploads/"+uploadID+"/parts", writer.FormDataContentType(), &body, &created)
+	return created.ID, err
+}
+
+// postJSON posts a JSON body to an API path and decodes the response into out
+func (c *Client) postJSON(ctx context.Context, path string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, "application/json", bytes.NewReader(data), out)
+}
+
+// do sends a request with an optional body to an API path and decodes the response into
+// out, converting API errors
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	resp, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// send sends a request with an optional body to an API path, converting API errors. The
+// caller must close the body of the response.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = defaultAPIURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(baseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", c.organization)
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &llm.RateLimitTransport{}}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var apiErr struct {
+		Error struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"`
+		} `json:"error"`
+	}
+	llmErr := &llm.Error{
+		Code:       "api_error",
+		Message:    fmt.Sprintf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data))),
+		Type:       "api_error",
+		StatusCode: resp.StatusCode,
+		RateLimit:  llm.ParseRateLimitHeaders(resp.Header),
+	}
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+		llmErr.Message = apiErr.Error.Message
+		llmErr.Type = apiErr.Error.Type
+		if code, ok := apiErr.Error.Code.(string); ok && code != "" {
+			llmErr.Code = code
+		}
+	}
+	return nil, llm.RedactError(llmErr)
+}
@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// uploadServer emulates the Uploads API, failing the part requests listed in failParts
+// (by their index)
+func uploadServer(t *testing.T, parts *[]string, failParts map[int]bool) *httptest.Server {
+	var completed []string
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Missing authorization header")
+		}
+		switch {
+		case r.URL.Path == "/uploads":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["purpose"] != DefaultUploadPurpose || body["bytes"] != float64(10) {
+				t.Errorf("Unexpected upload creation: %v", body)
+			}
+			_, _ = w.Write([]byte(`{"id": "upload_1", "status": "pending"}`))
+		case strings.HasSuffix(r.URL.Path, "/parts"):
+			if failParts[len(*parts)] {
+				delete(failParts, len(*parts))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error": {"message": "overloaded", "type": "server_error"}}`))
+				return
+			}
+			file, _, err := r.FormFile("data")
+			if err != nil {
+				t.Fatalf("Missing part data: %v", err)
+			}
+			data, _ := io.ReadAll(file)
+			*parts = append(*parts, string(data))
+			_, _ = w.Write([]byte(`{"id": "part_` + string(data) + `"}`))
+		case strings.HasSuffix(r.URL.Path, "/complete"):
+			var body struct {
+				PartIDs []string `json:"part_ids"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			completed = body.PartIDs
+			if len(completed) != len(*parts) {
+				t.Errorf("Expected all the parts to be completed, got %v", completed)
+			}
+			_, _ = w.Write([]byte(`{"id": "upload_1", "status": "completed", "file": {"id": "file-abc", "bytes": 10}}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestUploadFile(t *testing.T) {
+	var parts []string
+	server := uploadServer(t, &parts, nil)
+	defer server.Close()
+
+	client := &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}
+	var progress []llm.UploadProgress
+	upload := llm.NewFileUpload(strings.NewReader("0123456789"), 10, "data.txt", "text/plain")
+	upload.ChunkSize = 4
+	upload.OnProgress = func(p llm.UploadProgress) { progress = append(progress, p) }
+
+	file, err := client.UploadFile(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.ID != "file-abc" || file.Size != 10 || len(file.SHA256) != 64 {
+		t.Errorf("Unexpected file: %+v", file)
+	}
+	if strings.Join(parts, ",") != "0123,4567,89" {
+		t.Errorf("Unexpected parts: %v", parts)
+	}
+	if len(progress) != 3 || progress[2] != (llm.UploadProgress{Uploaded: 10, Total: 10}) {
+		t.Errorf("Unexpected progress: %v", progress)
+	}
+}
+
+func TestUploadFile_Resume(t *testing.T) {
+	var parts []string
+	server := uploadServer(t, &parts, map[int]bool{1: true})
+	defer server.Close()
+	client := &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}
+
+	upload := llm.NewFileUpload(strings.NewReader("0123456789"), 10, "data.txt", "text/plain")
+	upload.ChunkSize = 4
+	_, err := client.UploadFile(context.Background(), upload)
+
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Code != "upload_interrupted" || llmErr.Upload == nil {
+		t.Fatalf("Expected an interrupted upload, got %v", err)
+	}
+	if llmErr.StatusCode != http.StatusServiceUnavailable || llmErr.Upload.Offset != 4 || len(llmErr.Upload.Parts) != 1 {
+		t.Errorf("Unexpected session: %+v (%d)", llmErr.Upload, llmErr.StatusCode)
+	}
+
+	// The file is read again from the start, and only the missing parts are sent
+	upload = llm.NewFileUpload(strings.NewReader("0123456789"), 10, "data.txt", "text/plain")
+	upload.ChunkSize = 4
+	upload.Resume = llmErr.Upload
+	file, err := client.UploadFile(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.ID != "file-abc" || strings.Join(parts, ",") != "0123,4567,89" {
+		t.Errorf("Unexpected upload: %+v, parts %v", file, parts)
+	}
+}
@@ -0,0 +1,275 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// FineTuneClient implements llm.FineTuneClient for the OpenAI fine-tuning API
+type FineTuneClient struct {
+	client *Client
+}
+
+// NewFineTuneClient creates a new OpenAI fine-tuning client. The Model of the config is
+// not used: the base model is given in every job request.
+func NewFineTuneClient(config llm.ClientConfig) (*FineTuneClient, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &FineTuneClient{client: client}, nil
+}
+
+// UploadTrainingFile uploads a JSONL training or validation file with the Uploads API
+// (see Client.UploadFile), with the "fine-tune" purpose unless another one is given
+func (c *FineTuneClient) UploadTrainingFile(ctx context.Context, upload *llm.FileUpload) (*llm.UploadedFile, error) {
+	if upload != nil {
+		training := *upload
+		if training.Purpose == "" {
+			training.Purpose = llm.DefaultFineTunePurpose
+		}
+		if training.MimeType == "" || training.MimeType == "application/octet-stream" {
+			training.MimeType = "application/jsonl"
+		}
+		upload = &training
+	}
+	return c.client.UploadFile(ctx, upload)
+}
+
+// CreateJob starts a supervised fine-tuning job
+func (c *FineTuneClient) CreateJob(ctx context.Context, req llm.FineTuneJobRequest) (*llm.FineTuneJob, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	body := fineTuneJobRequest{
+		Model:          req.Model,
+		TrainingFile:   req.TrainingFile,
+		ValidationFile: req.ValidationFile,
+		Suffix:         req.Suffix,
+		Seed:           req.Seed,
+		Metadata:       req.Metadata,
+	}
+	if hp := req.Hyperparameters; hp != nil {
+		body.Method = &fineTuneMethod{Type: "supervised"}
+		body.Method.Supervised.Hyperparameters = fineTuneHyperparameters{
+			Epochs:                 autoNumber(hp.Epochs),
+			BatchSize:              autoNumber(hp.BatchSize),
+			LearningRateMultiplier: autoNumber(hp.LearningRateMultiplier),
+		}
+	}
+
+	var job fineTuneJob
+	if err := c.client.postJSON(ctx, "/fine_tuning/jobs", body, &job); err != nil {
+		return nil, err
+	}
+	return job.toLLM(), nil
+}
+
+// GetJob returns the current state of a job
+func (c *FineTuneClient) GetJob(ctx context.Context, id string) (*llm.FineTuneJob, error) {
+	var job fineTuneJob
+	if err := c.client.do(ctx, http.MethodGet, "/fine_tuning/jobs/"+url.PathEscape(id), "", nil, &job); err != nil {
+		return nil, err
+	}
+	return job.toLLM(), nil
+}
+
+// ListJobs returns a page of the jobs of the organization, most recent first
+func (c *FineTuneClient) ListJobs(ctx context.Context, opts llm.FineTuneListOptions) (*llm.FineTuneJobPage, error) {
+	var list struct {
+		Data    []fineTuneJob `json:"data"`
+		HasMore bool          `json:"has_more"`
+	}
+	if err := c.client.do(ctx, http.MethodGet, "/fine_tuning/jobs"+listQuery(opts), "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	page := &llm.FineTuneJobPage{Jobs: make([]llm.FineTuneJob, 0, len(list.Data)), HasMore: list.HasMore}
+	for _, job := range list.Data {
+		page.Jobs = append(page.Jobs, *job.toLLM())
+	}
+	return page, nil
+}
+
+// CancelJob cancels a job that has not finished yet
+func (c *FineTuneClient) CancelJob(ctx context.Context, id string) (*llm.FineTuneJob, error) {
+	var job fineTuneJob
+	if err := c.client.do(ctx, http.MethodPost, "/fine_tuning/jobs/"+url.PathEscape(id)+"/cancel", "", nil, &job); err != nil {
+		return nil, err
+	}
+	return job.toLLM(), nil
+}
+
+// ListEvents returns a page of the events of a job, most recent first
+func (c *FineTuneClient) ListEvents(ctx context.Context, id string, opts llm.FineTuneListOptions) (*llm.FineTuneEventPage, error) {
+	var list struct {
+		Data    []fineTuneEvent `json:"data"`
+		HasMore bool            `json:"has_more"`
+	}
+	if err := c.client.do(ctx, http.MethodGet, "/fine_tuning/jobs/"+url.PathEscape(id)+"/events"+listQuery(opts), "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	page := &llm.FineTuneEventPage{Events: make([]llm.FineTuneEvent, 0, len(list.Data)), HasMore: list.HasMore}
+	for _, event := range list.Data {
+		if string(event.Data) == "null" {
+			event.Data = nil
+		}
+		page.Events = append(page.Events, llm.FineTuneEvent{
+			ID:        event.ID,
+			Level:     event.Level,
+			Type:      event.Type,
+			Message:   event.Message,
+			CreatedAt: time.Unix(event.CreatedAt, 0),
+			Data:      event.Data,
+		})
+	}
+	return page, nil
+}
+
+// listQuery encodes the pagination options as a query string
+func listQuery(opts llm.FineTuneListOptions) string {
+	query := url.Values{}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// Wire types of the fine-tuning API
+
+type fineTuneJobRequest struct {
+	Model          string            `json:"model"`
+	TrainingFile   string            `json:"training_file"`
+	ValidationFile string            `json:"validation_file,omitempty"`
+	Suffix         string            `json:"suffix,omitempty"`
+	Seed           *int              `json:"seed,omitempty"`
+	Method         *fineTuneMethod   `json:"method,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+type fineTuneMethod struct {
+	Type       string `json:"type"`
+	Supervised struct {
+		Hyperparameters fineTuneHyperparameters `json:"hyperparameters"`
+	} `json:"supervised"`
+}
+
+type fineTuneHyperparameters struct {
+	Epochs                 autoNumber `json:"n_epochs"`
+	BatchSize              autoNumber `json:"batch_size"`
+	LearningRateMultiplier autoNumber `json:"learning_rate_multiplier"`
+}
+
+// autoNumber is a hyperparameter that is either a number or "auto", encoded from and
+// decoded to zero
+type autoNumber float64
+
+func (n autoNumber) MarshalJSON() ([]byte, error) {
+	if n == 0 {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(float64(n))
+}
+
+func (n *autoNumber) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		// "auto" and null
+		*n = 0
+		return nil
+	}
+	*n = autoNumber(value)
+	return nil
+}
+
+type fineTuneJob struct {
+	ID              string                  `json:"id"`
+	Model           string                  `json:"model"`
+	Status          string                  `json:"status"`
+	FineTunedModel  string                  `json:"fine_tuned_model"`
+	TrainingFile    string                  `json:"training_file"`
+	ValidationFile  string                  `json:"validation_file"`
+	ResultFiles     []string                `json:"result_files"`
+	TrainedTokens   int                     `json:"trained_tokens"`
+	Hyperparameters fineTuneHyperparameters `json:"hyperparameters"`
+	Method          *fineTuneMethod         `json:"method"`
+	Metadata        map[string]string       `json:"metadata"`
+	CreatedAt       int64                   `json:"created_at"`
+	FinishedAt      int64                   `json:"finished_at"`
+	EstimatedFinish int64                   `json:"estimated_finish"`
+	Error           *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Param   string `json:"param"`
+	} `json:"error"`
+}
+
+// toLLM converts a job, reading the hyperparameters of the method when set
+func (j fineTuneJob) toLLM() *llm.FineTuneJob {
+	hp := j.Hyperparameters
+	if j.Method != nil && j.Method.Type == "supervised" {
+		hp = j.Method.Supervised.Hyperparameters
+	}
+
+	job := &llm.FineTuneJob{
+		ID:             j.ID,
+		Model:          j.Model,
+		Status:         llm.FineTuneJobStatus(j.Status),
+		FineTunedModel: j.FineTunedModel,
+		TrainingFile:   j.TrainingFile,
+		ValidationFile: j.ValidationFile,
+		ResultFiles:    j.ResultFiles,
+		TrainedTokens:  j.TrainedTokens,
+		Hyperparameters: llm.FineTuneHyperparameters{
+			Epochs:                 int(hp.Epochs),
+			BatchSize:              int(hp.BatchSize),
+			LearningRateMultiplier: float64(hp.LearningRateMultiplier),
+		},
+		Metadata:  j.Metadata,
+		CreatedAt: time.Unix(j.CreatedAt, 0),
+	}
+	if j.FinishedAt > 0 {
+		finished := time.Unix(j.FinishedAt, 0)
+		job.FinishedAt = &finished
+	}
+	if j.EstimatedFinish > 0 {
+		estimated := time.Unix(j.EstimatedFinish, 0)
+		job.EstimatedFinish = &estimated
+	}
+	// Jobs without an error report an empty error object
+	if j.Error != nil && j.Error.Message != "" {
+		message := j.Error.Message
+		if j.Error.Param != "" && !strings.Contains(message, j.Error.Param) {
+			message += " (" + j.Error.Param + ")"
+		}
+		job.Error = &llm.Error{Code: j.Error.Code, Message: message, Type: "fine_tune_error"}
+		if job.Error.Code == "" {
+			job.Error.Code = "fine_tune_failed"
+		}
+	}
+	return job
+}
+
+type fineTuneEvent struct {
+	ID        string          `json:"id"`
+	Level     string          `json:"level"`
+	Type      string          `json:"type"`
+	Message   string          `json:"message"`
+	CreatedAt int64           `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func fineTuneServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Missing authorization header")
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/fine_tuning/jobs":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			method, _ := body["method"].(map[string]interface{})
+			supervised, _ := method["supervised"].(map[string]interface{})
+			hp, _ := supervised["hyperparameters"].(map[string]interface{})
+			if body["model"] != "gpt-4o-mini" || body["training_file"] != "file-train" ||
+				hp["n_epochs"] != float64(3) || hp["batch_size"] != "auto" {
+				t.Errorf("Unexpected job request: %v", body)
+			}
+			_, _ = w.Write([]byte(`{"id": "ftjob-1", "model": "gpt-4o-mini", "status": "validating_files",
+				"training_file": "file-train", "created_at": 1700000000, "finished_at": null, "error": {},
+				"method": {"type": "supervised", "supervised": {"hyperparameters": {"n_epochs": 3, "batch_size": "auto", "learning_rate_multiplier": "auto"}}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/fine_tuning/jobs/ftjob-1":
+			_, _ = w.Write([]byte(`{"id": "ftjob-1", "model": "gpt-4o-mini", "status": "succeeded",
+				"fine_tuned_model": "ft:gpt-4o-mini:org::abc", "result_files": ["file-result"], "trained_tokens": 1200,
+				"created_at": 1700000000, "finished_at": 1700003600, "hyperparameters": {"n_epochs": 3}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/fine_tuning/jobs":
+			if r.URL.Query().Get("after") != "ftjob-0" || r.URL.Query().Get("limit") != "2" {
+				t.Errorf("Unexpected pagination: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"data": [{"id": "ftjob-2", "status": "failed", "error": {"code": "invalid_training_file", "message": "bad line", "param": "training_file"}}], "has_more": true}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/fine_tuning/jobs/ftjob-1/cancel":
+			_, _ = w.Write([]byte(`{"id": "ftjob-1", "status": "cancelled"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/fine_tuning/jobs/ftjob-1/events":
+			_, _ = w.Write([]byte(`{"data": [{"id": "ev-1", "level": "info", "type": "metrics", "message": "Step 10",
+				"created_at": 1700000100, "data": {"step": 10}}, {"id": "ev-2", "level": "info", "message": "Job started", "data": null}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"message": "no such job", "type": "invalid_request_error"}}`))
+		}
+	}))
+}
+
+func TestFineTuneClient(t *testing.T) {
+	server := fineTuneServer(t)
+	defer server.Close()
+
+	var client llm.FineTuneClient = &FineTuneClient{client: &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}}
+	ctx := context.Background()
+
+	job, err := client.CreateJob(ctx, llm.FineTuneJobRequest{
+		Model:           "gpt-4o-mini",
+		TrainingFile:    "file-train",
+		Hyperparameters: &llm.FineTuneHyperparameters{Epochs: 3},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.ID != "ftjob-1" || job.Status != llm.FineTuneStatusValidatingFiles || job.Error != nil ||
+		job.FinishedAt != nil || job.Hyperparameters.Epochs != 3 || job.CreatedAt.Unix() != 1700000000 {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	job, err = client.GetJob(ctx, "ftjob-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !job.Status.IsTerminal() || job.FineTunedModel != "ft:gpt-4o-mini:org::abc" || job.FinishedAt == nil ||
+		job.TrainedTokens != 1200 || job.Hyperparameters.Epochs != 3 {
+		t.Errorf("Unexpected job: %+v", job)
+	}
+
+	page, err := client.ListJobs(ctx, llm.FineTuneListOptions{After: "ftjob-0", Limit: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !page.HasMore || len(page.Jobs) != 1 || page.Jobs[0].Error == nil || page.Jobs[0].Error.Code != "invalid_training_file" ||
+		!strings.Contains(page.Jobs[0].Error.Message, "training_file") {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	job, err = client.CancelJob(ctx, "ftjob-1")
+	if err != nil || job.Status != llm.FineTuneStatusCancelled {
+		t.Errorf("Unexpected cancellation: %+v, %v", job, err)
+	}
+
+	events, err := client.ListEvents(ctx, "ftjob-1", llm.FineTuneListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events.Events) != 2 || string(events.Events[0].Data) != `{"step": 10}` || events.Events[1].Data != nil {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+
+	_, err = client.GetJob(ctx, "missing")
+	if llmErr, ok := err.(*llm.Error); !ok || llmErr.StatusCode != http.StatusNotFound || llmErr.Message != "no such job" {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestFineTuneClient_InvalidJob(t *testing.T) {
+	client := &FineTuneClient{client: &Client{provider: "openai", apiKey: "test-key"}}
+	if _, err := client.CreateJob(context.Background(), llm.FineTuneJobRequest{Model: "gpt-4o-mini"}); err == nil {
+		t.Error("Expected an error for a job without a training file")
+	}
+}
+
+func TestFineTuneClient_UploadTrainingFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/uploads":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["purpose"] != llm.DefaultFineTunePurpose || body["mime_type"] != "application/jsonl" {
+				t.Errorf("Unexpected upload creation: %v", body)
+			}
+			_, _ = w.Write([]byte(`{"id": "upload_1"}`))
+		case strings.HasSuffix(r.URL.Path, "/parts"):
+			_, _ = w.Write([]byte(`{"id": "part_1"}`))
+		default:
+			_, _ = w.Write([]byte(`{"id": "upload_1", "status": "completed", "file": {"id": "file-train", "bytes": 12}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &FineTuneClient{client: &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}}
+	upload := llm.NewFileUpload(strings.NewReader(`{"a": "b"}`+"\n\n"), 12, "train.jsonl", "")
+	file, err := client.UploadTrainingFile(context.Background(), upload)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if file.ID != "file-train" {
+		t.Errorf("Unexpected file: %+v", file)
+	}
+	if upload.Purpose != "" {
+		t.Error("The upload of the caller should not be modified")
+	}
+}
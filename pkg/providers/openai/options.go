@@ -0,0 +1,104 @@
+package openai
+
+import (
+	"strconv"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// logitBiasOption holds the logit bias of a request
+type logitBiasOption struct {
+	bias map[int]int
+}
+
+func (logitBiasOption) Provider() string { return "openai" }
+
+// WithLogitBias returns a request option that biases the likelihood of tokens, mapping
+// token IDs of the model tokenizer to a bias from -100 (ban) to 100 (exclusive selection):
+//
+//	req := llm.ChatRequest{Messages: messages}.WithProviderOptions(openai.WithLogitBias(map[int]int{50256: -100}))
+func WithLogitBias(bias map[int]int) llm.ProviderOption {
+	return logitBiasOption{bias: bias}
+}
+
+// logitBias returns the logit bias of a request in the API format (token IDs as strings)
+func logitBias(req llm.ChatRequest) map[string]int {
+	option, ok := llm.FindProviderOption[logitBiasOption](req)
+	if !ok || len(option.bias) == 0 {
+		return nil
+	}
+	bias := make(map[string]int, len(option.bias))
+	for token, value := range option.bias {
+		bias[strconv.Itoa(token)] = value
+	}
+	return bias
+}
+
+// WebSearch configures the hosted web search tool (see WithWebSearch)
+type WebSearch struct {
+	// ContextSize is the amount of search results given to the model: "low", "medium"
+	// (the default) or "high"
+	ContextSize string `json:"search_context_size,omitempty"`
+
+	// AllowedDomains limits the search to domains (e.g. "go.dev"), when not empty
+	AllowedDomains []string `json:"-"`
+}
+
+// FileSearch configures the hosted file search tool (see WithFileSearch)
+type FileSearch struct {
+	// VectorStoreIDs are the vector stores searched, created with the vector stores API
+	VectorStoreIDs []string `json:"vector_store_ids"`
+
+	// MaxResults limits the chunks given to the model, when positive
+	MaxResults int `json:"max_num_results,omitempty"`
+
+	// IncludeResults returns the chunks found in the results of the call, not only the
+	// files cited by the answer
+	IncludeResults bool `json:"-"`
+}
+
+// hostedToolsOption holds the hosted tools of a request
+type hostedToolsOption struct {
+	webSearch  *WebSearch
+	fileSearch *FileSearch
+}
+
+func (hostedToolsOption) Provider() string { return "openai" }
+
+// WithWebSearch returns a request option letting the model search the web. Requests with
+// hosted tools are sent to the Responses API; the searches are the
+// llm.BuiltinToolWebSearch calls of the response message and the cited pages its
+// citations.
+func WithWebSearch(search WebSearch) llm.ProviderOption {
+	return hostedToolsOption{webSearch: &search}
+}
+
+// WithFileSearch returns a request option letting the model search the files of vector
+// stores. Requests with hosted tools are sent to the Responses API; the searches are the
+// llm.BuiltinToolFileSearch calls of the response message and the cited files its
+// citations, with the file ID as URL.
+func WithFileSearch(search FileSearch) llm.ProviderOption {
+	return hostedToolsOption{fileSearch: &search}
+}
+
+// hostedTools returns the hosted tools of a request, the last option of each tool
+// winning, or nil without hosted tools
+func hostedTools(req llm.ChatRequest) *hostedToolsOption {
+	var tools *hostedToolsOption
+	for _, option := range req.ProviderOptionsFor("openai") {
+		hosted, ok := option.(hostedToolsOption)
+		if !ok {
+			continue
+		}
+		if tools == nil {
+			tools = &hostedToolsOption{}
+		}
+		if hosted.webSearch != nil {
+			tools.webSearch = hosted.webSearch
+		}
+		if hosted.fileSearch != nil {
+			tools.fileSearch = hosted.fileSearch
+		}
+	}
+	return tools
+}
@@ -0,0 +1,641 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// DefaultRealtimeModel is the model used by realtime sessions when none is configured
+	DefaultRealtimeModel = "gpt-4o-realtime-preview"
+
+	// DefaultRealtimeURL is the WebSocket endpoint of the OpenAI Realtime API
+	DefaultRealtimeURL = "wss://api.openai.com/v1/realtime"
+
+	// defaultMaxReconnects is the number of attempts made to re-establish a dropped session
+	defaultMaxReconnects = 3
+
+	// realtimeEventBuffer is the size of the session event channel
+	realtimeEventBuffer = 64
+)
+
+// RealtimeClient implements llm.RealtimeClient for the OpenAI Realtime API
+type RealtimeClient struct {
+	apiKey string
+	model  string
+	url    string
+	dialer *websocket.Dialer
+
+	// header holds the organization and project headers
+	header http.Header
+
+	maxReconnects  int
+	reconnectDelay time.Duration
+}
+
+// NewRealtimeClient creates a new OpenAI Realtime client. The BaseURL may be given as an
+// HTTP(S) API URL (e.g. "https://api.openai.com/v1"), which is converted to its WebSocket
+// endpoint. Extra["max_reconnects"] sets how many times a dropped session is re-established.
+func NewRealtimeClient(config llm.ClientConfig) (*RealtimeClient, error) {
+	if config.APIKey == "" {
+		return nil, &llm.Error{
+			Code:    "missing_api_key",
+			Message: "API key is required for OpenAI",
+			Type:    "authentication_error",
+		}
+	}
+
+	model := config.Model
+	if model == "" {
+		model = DefaultRealtimeModel
+	}
+
+	maxReconnects := defaultMaxReconnects
+	if value, ok := config.Extra["max_reconnects"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, &llm.Error{
+				Code:    "invalid_config",
+				Message: fmt.Sprintf("invalid max_reconnects %q", value),
+				Type:    "validation_error",
+			}
+		}
+		maxReconnects = n
+	}
+
+	header := http.Header{}
+	if config.Organization != "" {
+		header.Set("OpenAI-Organization", config.Organization)
+	}
+	if config.Project != "" {
+		header.Set("OpenAI-Project", config.Project)
+	}
+
+	dialer := *websocket.DefaultDialer
+	if config.Timeout > 0 {
+		dialer.HandshakeTimeout = config.Timeout
+	}
+
+	return &RealtimeClient{
+		apiKey:         config.APIKey,
+		model:          model,
+		url:            realtimeURL(config.BaseURL),
+		dialer:         &dialer,
+		header:         header,
+		maxReconnects:  maxReconnects,
+		reconnectDelay: 500 * time.Millisecond,
+	}, nil
+}
+
+// realtimeURL converts an API base URL to the realtime WebSocket endpoint
+func realtimeURL(baseURL string) string {
+	if baseURL == "" {
+		return DefaultRealtimeURL
+	}
+
+	endpoint := strings.TrimSuffix(baseURL, "/")
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		endpoint = "wss://" + strings.TrimPrefix(endpoint, "https://")
+	case strings.HasPrefix(endpoint, "http://"):
+		endpoint = "ws://" + strings.TrimPrefix(endpoint, "http://")
+	}
+	if !strings.HasSuffix(endpoint, "/realtime") {
+		endpoint += "/realtime"
+	}
+	return endpoint
+}
+
+// Connect opens a realtime session
+func (c *RealtimeClient) Connect(ctx context.Context, config llm.RealtimeSessionConfig) (llm.RealtimeSession, error) {
+	session := &realtimeSession{
+		client: c,
+		config: config,
+		events: make(chan llm.RealtimeEvent, realtimeEventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	conn, err := session.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session.conn = conn
+
+	go session.readLoop()
+
+	return session, nil
+}
+
+// dial opens the WebSocket connection
+func (c *RealtimeClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	header := c.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Authorization", "Bearer "+c.apiKey)
+	header.Set("OpenAI-Beta", "realtime=v1")
+
+	conn, resp, err := c.dialer.DialContext(ctx, c.url+"?model="+url.QueryEscape(c.model), header)
+	if err != nil {
+		llmErr := &llm.Error{
+			Code:    "connection_failed",
+			Message: fmt.Sprintf("failed to connect to realtime API: %v", err),
+			Type:    "api_error",
+		}
+		if resp != nil {
+			llmErr.StatusCode = resp.StatusCode
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				llmErr.Type = "authentication_error"
+			}
+		}
+		return nil, llmErr
+	}
+
+	return conn, nil
+}
+
+// realtimeSession implements llm.RealtimeSession
+type realtimeSession struct {
+	client *RealtimeClient
+	config llm.RealtimeSessionConfig
+	events chan llm.RealtimeEvent
+
+	// writeMu serializes writes to the connection
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	// history holds the conversation items replayed when the session is resumed
+	history []realtimeItem
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// open dials a new connection, configures the session and replays the conversation history
+func (s *realtimeSession) open(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := s.client.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []any{realtimeClientEvent{Type: "session.update", Session: s.sessionUpdate()}}
+	s.mu.Lock()
+	for i := range s.history {
+		messages = append(messages, realtimeClientEvent{Type: "conversation.item.create", Item: &s.history[i]})
+	}
+	s.mu.Unlock()
+
+	for _, message := range messages {
+		if err := conn.WriteJSON(message); err != nil {
+			_ = conn.Close()
+			return nil, &llm.Error{
+				Code:    "connection_failed",
+				Message: fmt.Sprintf("failed to configure realtime session: %v", err),
+				Type:    "api_error",
+			}
+		}
+	}
+
+	return conn, nil
+}
+
+// Events returns the channel of events received from the model
+func (s *realtimeSession) Events() <-chan llm.RealtimeEvent {
+	return s.events
+}
+
+// SendText adds a user text message to the conversation
+func (s *realtimeSession) SendText(ctx context.Context, text string) error {
+	item := realtimeItem{
+		Type:    "message",
+		Role:    "user",
+		Content: []realtimeContent{{Type: "input_text", Text: text}},
+	}
+	if err := s.send(ctx, realtimeClientEvent{Type: "conversation.item.create", Item: &item}); err != nil {
+		return err
+	}
+	s.remember(item)
+	return nil
+}
+
+// SendAudio appends a frame of input audio
+func (s *realtimeSession) SendAudio(ctx context.Context, audio []byte) error {
+	return s.send(ctx, realtimeClientEvent{
+		Type:  "input_audio_buffer.append",
+		Audio: base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
+// CommitAudio marks the end of the user audio turn
+func (s *realtimeSession) CommitAudio(ctx context.Context) error {
+	return s.send(ctx, realtimeClientEvent{Type: "input_audio_buffer.commit"})
+}
+
+// SendToolResult returns the output of a tool call requested by the model
+func (s *realtimeSession) SendToolResult(ctx context.Context, callID string, output string) error {
+	item := realtimeItem{Type: "function_call_output", CallID: callID, Output: output}
+	if err := s.send(ctx, realtimeClientEvent{Type: "conversation.item.create", Item: &item}); err != nil {
+		return err
+	}
+	s.remember(item)
+	return nil
+}
+
+// CreateResponse asks the model to respond
+func (s *realtimeSession) CreateResponse(ctx context.Context) error {
+	return s.send(ctx, realtimeClientEvent{Type: "response.create"})
+}
+
+// CancelResponse interrupts the response being generated
+func (s *realtimeSession) CancelResponse(ctx context.Context) error {
+	return s.send(ctx, realtimeClientEvent{Type: "response.cancel"})
+}
+
+// Close ends the session
+func (s *realtimeSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+
+		s.writeMu.Lock()
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		s.writeMu.Unlock()
+		err = conn.Close()
+	})
+	return err
+}
+
+// isClosed checks if Close was called
+func (s *realtimeSession) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send writes a client event to the current connection
+func (s *realtimeSession) send(ctx context.Context, event realtimeClientEvent) error {
+	if s.isClosed() {
+		return &llm.Error{
+			Code:    "session_closed",
+			Message: "realtime session is closed",
+			Type:    "api_error",
+		}
+	}
+
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetWriteDeadline(deadline)
+	if err := conn.WriteJSON(event); err != nil {
+		return &llm.Error{
+			Code:    "send_failed",
+			Message: fmt.Sprintf("failed to send %s: %v", event.Type, err),
+			Type:    "api_error",
+		}
+	}
+	return nil
+}
+
+// remember records a conversation item for session resumption
+func (s *realtimeSession) remember(item realtimeItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, item)
+}
+
+// emit delivers an event unless the session was closed
+func (s *realtimeSession) emit(event llm.RealtimeEvent) {
+	select {
+	case s.events <- event:
+	case <-s.done:
+	}
+}
+
+// readLoop reads server events until the session is closed or cannot be resumed
+func (s *realtimeSession) readLoop() {
+	defer close(s.events)
+
+	for {
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if s.isClosed() {
+				return
+			}
+			if reconnectErr := s.reconnect(); reconnectErr != nil {
+				s.emit(llm.RealtimeEvent{Type: llm.RealtimeEventError, Error: reconnectErr})
+				return
+			}
+			s.emit(llm.RealtimeEvent{Type: llm.RealtimeEventReconnected})
+			continue
+		}
+
+		s.handle(data)
+	}
+}
+
+// reconnect re-establishes a dropped connection, restoring the session configuration
+// and the conversation history
+func (s *realtimeSession) reconnect() *llm.Error {
+	s.mu.Lock()
+	_ = s.conn.Close()
+	s.mu.Unlock()
+
+	lastErr := &llm.Error{
+		Code:    "connection_lost",
+		Message: "realtime connection lost",
+		Type:    "api_error",
+	}
+
+	for attempt := 1; attempt <= s.client.maxReconnects; attempt++ {
+		select {
+		case <-s.done:
+			return lastErr
+		case <-time.After(s.client.reconnectDelay * time.Duration(attempt)):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		conn, err := s.open(ctx)
+		cancel()
+		if err != nil {
+			var llmErr *llm.Error
+			if errors.As(err, &llmErr) {
+				lastErr = llmErr
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+
+		// The session may have been closed while reconnecting
+		if s.isClosed() {
+			_ = conn.Close()
+		}
+		return nil
+	}
+
+	return lastErr
+}
+
+// handle converts a server event and delivers it
+func (s *realtimeSession) handle(data []byte) {
+	var event realtimeServerEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.emit(llm.RealtimeEvent{
+			Type: llm.RealtimeEventError,
+			Error: &llm.Error{
+				Code:    "invalid_event",
+				Message: fmt.Sprintf("failed to parse realtime event: %v", err),
+				Type:    "api_error",
+			},
+			Raw: data,
+		})
+		return
+	}
+
+	out := llm.RealtimeEvent{Raw: data}
+	switch event.Type {
+	case "session.created":
+		out.Type = llm.RealtimeEventSessionCreated
+	case "response.text.delta":
+		out.Type = llm.RealtimeEventTextDelta
+		out.Text = event.Delta
+	case "response.audio_transcript.delta":
+		out.Type = llm.RealtimeEventTranscriptDelta
+		out.Text = event.Delta
+	case "response.audio.delta":
+		audio, err := base64.StdEncoding.DecodeString(event.Delta)
+		if err != nil {
+			out.Type = llm.RealtimeEventError
+			out.Error = &llm.Error{Code: "invalid_audio", Message: "failed to decode audio delta", Type: "api_error"}
+			break
+		}
+		out.Type = llm.RealtimeEventAudioDelta
+		out.Audio = audio
+	case "conversation.item.input_audio_transcription.completed":
+		out.Type = llm.RealtimeEventInputTranscript
+		out.Text = event.Transcript
+		s.remember(realtimeItem{
+			Type:    "message",
+			Role:    "user",
+			Content: []realtimeContent{{Type: "input_text", Text: event.Transcript}},
+		})
+	case "input_audio_buffer.speech_started":
+		out.Type = llm.RealtimeEventSpeechStarted
+	case "input_audio_buffer.speech_stopped":
+		out.Type = llm.RealtimeEventSpeechStopped
+	case "response.function_call_arguments.done":
+		out.Type = llm.RealtimeEventToolCall
+		out.ToolCall = &llm.ToolCall{
+			ID:       event.CallID,
+			Type:     "function",
+			Function: llm.ToolCallFunction{Name: event.Name, Arguments: event.Arguments},
+		}
+	case "response.done":
+		out.Type = llm.RealtimeEventResponseDone
+		if event.Response != nil {
+			if usage := event.Response.Usage; usage != nil {
+				out.Usage = &llm.Usage{
+					PromptTokens:     usage.InputTokens,
+					CompletionTokens: usage.OutputTokens,
+					TotalTokens:      usage.TotalTokens,
+				}
+			}
+			s.rememberOutput(event.Response.Output)
+		}
+	case "error":
+		out.Type = llm.RealtimeEventError
+		out.Error = &llm.Error{Code: "unknown", Message: "realtime API error", Type: "api_error"}
+		if event.Error != nil {
+			out.Error.Message = event.Error.Message
+			if event.Error.Code != "" {
+				out.Error.Code = event.Error.Code
+			}
+			if event.Error.Type != "" {
+				out.Error.Type = event.Error.Type
+			}
+		}
+	default:
+		// Acknowledgements and lifecycle events not exposed by llm.RealtimeEvent
+		return
+	}
+
+	s.emit(out)
+}
+
+// rememberOutput records the items generated by the model, keeping audio as its transcript
+func (s *realtimeSession) rememberOutput(output []realtimeItem) {
+	for _, item := range output {
+		switch item.Type {
+		case "message":
+			var text strings.Builder
+			for _, content := range item.Content {
+				text.WriteString(content.Text)
+				text.WriteString(content.Transcript)
+			}
+			if text.Len() == 0 {
+				continue
+			}
+			s.remember(realtimeItem{
+				Type:    "message",
+				Role:    "assistant",
+				Content: []realtimeContent{{Type: "text", Text: text.String()}},
+			})
+		case "function_call":
+			s.remember(realtimeItem{
+				Type:      "function_call",
+				CallID:    item.CallID,
+				Name:      item.Name,
+				Arguments: item.Arguments,
+			})
+		}
+	}
+}
+
+// sessionUpdate converts the session configuration to the OpenAI format
+func (s *realtimeSession) sessionUpdate() *realtimeSessionUpdate {
+	config := s.config
+	update := &realtimeSessionUpdate{
+		Modalities:        config.Modalities,
+		Instructions:      config.Instructions,
+		Voice:             config.Voice,
+		InputAudioFormat:  config.InputAudioFormat,
+		OutputAudioFormat: config.OutputAudioFormat,
+		Temperature:       config.Temperature,
+	}
+
+	if config.TranscribeInput {
+		update.InputAudioTranscription = &realtimeTranscription{Model: "whisper-1"}
+	}
+	if td := config.TurnDetection; td != nil {
+		update.TurnDetection = &realtimeTurnDetection{
+			Type:              "server_vad",
+			Threshold:         td.Threshold,
+			PrefixPaddingMs:   td.PrefixPaddingMs,
+			SilenceDurationMs: td.SilenceDurationMs,
+		}
+	}
+	for _, tool := range config.Tools {
+		update.Tools = append(update.Tools, realtimeTool{
+			Type:        "function",
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+
+	return update
+}
+
+// realtimeClientEvent is an event sent to the Realtime API
+type realtimeClientEvent struct {
+	Type    string                 `json:"type"`
+	Session *realtimeSessionUpdate `json:"session,omitempty"`
+	Item    *realtimeItem          `json:"item,omitempty"`
+	Audio   string                 `json:"audio,omitempty"`
+}
+
+// realtimeSessionUpdate holds the session settings of a session.update event
+type realtimeSessionUpdate struct {
+	Modalities              []string               `json:"modalities,omitempty"`
+	Instructions            string                 `json:"instructions,omitempty"`
+	Voice                   string                 `json:"voice,omitempty"`
+	InputAudioFormat        string                 `json:"input_audio_format,omitempty"`
+	OutputAudioFormat       string                 `json:"output_audio_format,omitempty"`
+	InputAudioTranscription *realtimeTranscription `json:"input_audio_transcription,omitempty"`
+	// TurnDetection is sent as null to disable server-side voice activity detection
+	TurnDetection *realtimeTurnDetection `json:"turn_detection"`
+	Tools         []realtimeTool         `json:"tools,omitempty"`
+	Temperature   *float32               `json:"temperature,omitempty"`
+}
+
+type realtimeTranscription struct {
+	Model string `json:"model"`
+}
+
+type realtimeTurnDetection struct {
+	Type              string  `json:"type"`
+	Threshold         float64 `json:"threshold,omitempty"`
+	PrefixPaddingMs   int     `json:"prefix_padding_ms,omitempty"`
+	SilenceDurationMs int     `json:"silence_duration_ms,omitempty"`
+}
+
+type realtimeTool struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+// realtimeItem is a conversation item
+type realtimeItem struct {
+	Type      string            `json:"type"`
+	Role      string            `json:"role,omitempty"`
+	Content   []realtimeContent `json:"content,omitempty"`
+	CallID    string            `json:"call_id,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Arguments string            `json:"arguments,omitempty"`
+	Output    string            `json:"output,omitempty"`
+}
+
+type realtimeContent struct {
+	Type       string `json:"type"`
+	Text       string `json:"text,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// realtimeServerEvent holds the fields of the server events handled by the session
+type realtimeServerEvent struct {
+	Type       string `json:"type"`
+	Delta      string `json:"delta,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+	CallID     string `json:"call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Arguments  string `json:"arguments,omitempty"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Response *struct {
+		Status string         `json:"status"`
+		Output []realtimeItem `json:"output"`
+		Usage  *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage,omitempty"`
+	} `json:"response,omitempty"`
+}
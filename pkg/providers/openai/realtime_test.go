@@ -0,0 +1,276 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// newRealtimeServer starts a WebSocket server that hands every connection (numbered from 0) to handler
+func newRealtimeServer(t *testing.T, handler func(n int, conn *websocket.Conn)) *RealtimeClient {
+	t.Helper()
+
+	var connections atomic.Int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("model") != DefaultRealtimeModel {
+			t.Errorf("Unexpected model %q", r.URL.Query().Get("model"))
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		handler(int(connections.Add(1)-1), conn)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewRealtimeClient(llm.ClientConfig{APIKey: "test-key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.reconnectDelay = time.Millisecond
+	return client
+}
+
+// readClientEvent reads the next client event as a generic map
+func readClientEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	var event map[string]interface{}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Errorf("Failed to read client event: %v", err)
+	}
+	return event
+}
+
+// nextEvent waits for the next session event
+func nextEvent(t *testing.T, session llm.RealtimeSession) llm.RealtimeEvent {
+	t.Helper()
+	select {
+	case event, ok := <-session.Events():
+		if !ok {
+			t.Fatal("Events channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return llm.RealtimeEvent{}
+}
+
+func TestRealtimeURL(t *testing.T) {
+	tests := map[string]string{
+		"":                          DefaultRealtimeURL,
+		"https://api.openai.com/v1": "wss://api.openai.com/v1/realtime",
+		"http://localhost:8080/v1/": "ws://localhost:8080/v1/realtime",
+		"wss://proxy/v1/realtime":   "wss://proxy/v1/realtime",
+	}
+	for baseURL, want := range tests {
+		if got := realtimeURL(baseURL); got != want {
+			t.Errorf("realtimeURL(%q) = %q, want %q", baseURL, got, want)
+		}
+	}
+}
+
+func TestRealtimeSession(t *testing.T) {
+	client := newRealtimeServer(t, func(n int, conn *websocket.Conn) {
+		update := readClientEvent(t, conn)
+		session, _ := update["session"].(map[string]interface{})
+		if update["type"] != "session.update" || session["instructions"] != "Be brief" {
+			t.Errorf("Unexpected session update: %v", update)
+		}
+		if td, ok := session["turn_detection"]; !ok || td != nil {
+			t.Errorf("Expected turn_detection to be null, got %v", td)
+		}
+		if tools, _ := session["tools"].([]interface{}); len(tools) != 1 {
+			t.Errorf("Expected one tool, got %v", session["tools"])
+		}
+		_ = conn.WriteJSON(map[string]interface{}{"type": "session.created"})
+
+		item := readClientEvent(t, conn)["item"].(map[string]interface{})
+		if item["role"] != "user" || !strings.Contains(mustJSON(item["content"]), "What's the weather?") {
+			t.Errorf("Unexpected item: %v", item)
+		}
+		if event := readClientEvent(t, conn); event["type"] != "response.create" {
+			t.Errorf("Expected response.create, got %v", event)
+		}
+
+		_ = conn.WriteJSON(map[string]interface{}{"type": "response.text.delta", "delta": "Let me check"})
+		_ = conn.WriteJSON(map[string]interface{}{"type": "response.audio.delta", "delta": "AQID"})
+		_ = conn.WriteJSON(map[string]interface{}{
+			"type": "response.function_call_arguments.done", "call_id": "call_1",
+			"name": "get_weather", "arguments": `{"city":"Paris"}`,
+		})
+		_ = conn.WriteJSON(map[string]interface{}{
+			"type":     "response.done",
+			"response": map[string]interface{}{"usage": map[string]interface{}{"input_tokens": 10, "output_tokens": 5, "total_tokens": 15}},
+		})
+
+		output := readClientEvent(t, conn)["item"].(map[string]interface{})
+		if output["type"] != "function_call_output" || output["call_id"] != "call_1" || output["output"] != "sunny" {
+			t.Errorf("Unexpected tool output: %v", output)
+		}
+		if event := readClientEvent(t, conn); event["type"] != "input_audio_buffer.append" || event["audio"] != "BAU=" {
+			t.Errorf("Unexpected audio event: %v", event)
+		}
+		_ = conn.WriteJSON(map[string]interface{}{"type": "error", "error": map[string]interface{}{
+			"type": "invalid_request_error", "code": "bad_audio", "message": "audio too short",
+		}})
+		_, _, _ = conn.ReadMessage()
+	})
+
+	ctx := context.Background()
+	session, err := client.Connect(ctx, llm.RealtimeSessionConfig{
+		Instructions: "Be brief",
+		Tools:        []llm.Tool{{Type: "function", Function: llm.ToolFunction{Name: "get_weather"}}},
+	})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	if event := nextEvent(t, session); event.Type != llm.RealtimeEventSessionCreated {
+		t.Errorf("Expected session_created, got %+v", event)
+	}
+	if err := session.SendText(ctx, "What's the weather?"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if err := session.CreateResponse(ctx); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	if event := nextEvent(t, session); event.Type != llm.RealtimeEventTextDelta || event.Text != "Let me check" {
+		t.Errorf("Unexpected text event: %+v", event)
+	}
+	if event := nextEvent(t, session); event.Type != llm.RealtimeEventAudioDelta || string(event.Audio) != "\x01\x02\x03" {
+		t.Errorf("Unexpected audio event: %+v", event)
+	}
+	event := nextEvent(t, session)
+	if event.Type != llm.RealtimeEventToolCall || event.ToolCall.ID != "call_1" || event.ToolCall.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("Unexpected tool call event: %+v", event)
+	}
+	event = nextEvent(t, session)
+	if event.Type != llm.RealtimeEventResponseDone || event.Usage == nil || event.Usage.TotalTokens != 15 {
+		t.Errorf("Unexpected response done event: %+v", event)
+	}
+
+	if err := session.SendToolResult(ctx, "call_1", "sunny"); err != nil {
+		t.Fatalf("SendToolResult failed: %v", err)
+	}
+	if err := session.SendAudio(ctx, []byte{4, 5}); err != nil {
+		t.Fatalf("SendAudio failed: %v", err)
+	}
+
+	event = nextEvent(t, session)
+	if event.Type != llm.RealtimeEventError || event.Error.Code != "bad_audio" || event.Error.Type != "invalid_request_error" {
+		t.Errorf("Unexpected error event: %+v", event)
+	}
+
+	if err := session.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	var llmErr *llm.Error
+	if err := session.SendText(ctx, "again"); !errors.As(err, &llmErr) || llmErr.Code != "session_closed" {
+		t.Errorf("Expected session_closed, got %v", err)
+	}
+}
+
+func TestRealtimeSession_Reconnect(t *testing.T) {
+	replayed := make(chan []string, 1)
+
+	client := newRealtimeServer(t, func(n int, conn *websocket.Conn) {
+		readClientEvent(t, conn) // session.update
+		if n == 0 {
+			readClientEvent(t, conn) // user text
+			_ = conn.WriteJSON(map[string]interface{}{
+				"type": "response.done",
+				"response": map[string]interface{}{"output": []interface{}{
+					map[string]interface{}{"type": "message", "role": "assistant", "content": []interface{}{
+						map[string]interface{}{"type": "audio", "transcript": "Hi there"},
+					}},
+				}},
+			})
+			// Drop the connection without a close handshake
+			time.Sleep(50 * time.Millisecond)
+			return
+		}
+
+		var items []string
+		for i := 0; i < 2; i++ {
+			event := readClientEvent(t, conn)
+			items = append(items, mustJSON(event["item"]))
+		}
+		replayed <- items
+		_, _, _ = conn.ReadMessage()
+	})
+
+	ctx := context.Background()
+	session, err := client.Connect(ctx, llm.RealtimeSessionConfig{})
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	if err := session.SendText(ctx, "Hello"); err != nil {
+		t.Fatalf("SendText failed: %v", err)
+	}
+	if event := nextEvent(t, session); event.Type != llm.RealtimeEventResponseDone {
+		t.Errorf("Expected response_done, got %+v", event)
+	}
+	if event := nextEvent(t, session); event.Type != llm.RealtimeEventReconnected {
+		t.Fatalf("Expected reconnected, got %+v", event)
+	}
+
+	select {
+	case items := <-replayed:
+		if !strings.Contains(items[0], `"role":"user"`) || !strings.Contains(items[0], "Hello") {
+			t.Errorf("Expected user message to be replayed first, got %s", items[0])
+		}
+		if !strings.Contains(items[1], `"role":"assistant"`) || !strings.Contains(items[1], "Hi there") {
+			t.Errorf("Expected assistant transcript to be replayed, got %s", items[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for replayed history")
+	}
+}
+
+func TestRealtimeClient_Errors(t *testing.T) {
+	if _, err := NewRealtimeClient(llm.ClientConfig{}); err == nil {
+		t.Error("Expected error for missing API key")
+	}
+	if _, err := NewRealtimeClient(llm.ClientConfig{APIKey: "k", Extra: map[string]string{"max_reconnects": "x"}}); err == nil {
+		t.Error("Expected error for invalid max_reconnects")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client, _ := NewRealtimeClient(llm.ClientConfig{APIKey: "bad", BaseURL: server.URL})
+	_, err := client.Connect(context.Background(), llm.RealtimeSessionConfig{})
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.StatusCode != http.StatusUnauthorized || llmErr.Type != "authentication_error" {
+		t.Errorf("Expected authentication_error, got %v", err)
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,368 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// responsesRequest is a request of the Responses API, used for the requests with hosted
+// tools
+type responsesRequest struct {
+	Model           string            `json:"model"`
+	Input           []map[string]any  `json:"input"`
+	Tools           []map[string]any  `json:"tools,omitempty"`
+	Include         []string          `json:"include,omitempty"`
+	Temperature     *float32          `json:"temperature,omitempty"`
+	TopP            *float32          `json:"top_p,omitempty"`
+	MaxOutputTokens *int              `json:"max_output_tokens,omitempty"`
+	User            string            `json:"user,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	Text            map[string]any    `json:"text,omitempty"`
+}
+
+// responsesResponse is a response of the Responses API
+type responsesResponse struct {
+	ID                string                `json:"id"`
+	Model             string                `json:"model"`
+	Status            string                `json:"status"`
+	Output            []responsesOutputItem `json:"output"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Usage struct {
+		InputTokens        int `json:"input_tokens"`
+		OutputTokens       int `json:"output_tokens"`
+		TotalTokens        int `json:"total_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+	} `json:"usage"`
+}
+
+// responsesOutputItem is an item of the output of a response: a message, a function call
+// or a call of a hosted tool
+type responsesOutputItem struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Status string `json:"status"`
+
+	// Message
+	Content []struct {
+		Type        string                `json:"type"`
+		Text        string                `json:"text"`
+		Annotations []responsesAnnotation `json:"annotations"`
+	} `json:"content"`
+
+	// Function call
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+
+	// Web search call
+	Action *struct {
+		Query   string `json:"query"`
+		Sources []struct {
+			URL string `json:"url"`
+		} `json:"sources"`
+	} `json:"action"`
+
+	// File search call
+	Queries []string `json:"queries"`
+	Results []struct {
+		FileID   string `json:"file_id"`
+		Filename string `json:"filename"`
+		Text     string `json:"text"`
+	} `json:"results"`
+}
+
+// responsesAnnotation is a citation of the text of a message
+type responsesAnnotation struct {
+	Type       string `json:"type"`
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	FileID     string `json:"file_id"`
+	Filename   string `json:"filename"`
+}
+
+// responsesCompletion performs a request with hosted tools with the Responses API
+func (c *Client) responsesCompletion(ctx context.Context, req llm.ChatRequest, model string, tools *hostedToolsOption) (*llm.ChatResponse, error) {
+	data, err := json.Marshal(c.responsesRequest(req, model, tools))
+	if err != nil {
+		return nil, err
+	}
+	var body json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/responses", "application/json", bytes.NewReader(data), &body); err != nil {
+		return nil, err
+	}
+	var resp responsesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	chatResp := c.convertResponsesResponse(resp)
+	// The response types are not exported, so the native value is the body itself
+	chatResp.Raw = llm.NewRawResponse(body, body)
+	return chatResp, nil
+}
+
+// streamResponses streams a request with hosted tools. The Responses API is called
+// without streaming, and the response is sent as a single delta once complete.
+func (c *Client) streamResponses(ctx context.Context, req llm.ChatRequest, model string, tools *hostedToolsOption) (<-chan llm.StreamEvent, error) {
+	emitter := llm.NewStreamEmitter(ctx, c.streamBuffer)
+	seq := llm.NewStreamSequencer()
+
+	go func() {
+		defer emitter.Close()
+
+		resp, err := c.responsesCompletion(ctx, req, model, tools)
+		if err != nil {
+			var llmErr *llm.Error
+			if !errors.As(err, &llmErr) {
+				llmErr = c.convertError(err)
+			}
+			emitter.Emit(seq.Stamp(llm.NewErrorEvent(llmErr)))
+			return
+		}
+		if len(resp.Choices) == 0 {
+			done := llm.NewProviderDoneEvent(0, c.provider, "stop")
+			done.Raw = resp.Raw
+			emitter.Emit(seq.Stamp(done))
+			return
+		}
+		choice := resp.Choices[0]
+		delta := &llm.MessageDelta{Content: choice.Message.Content}
+		for i, call := range choice.Message.ToolCalls {
+			delta.ToolCalls = append(delta.ToolCalls, llm.ToolCallDelta{
+				Index:    i,
+				ID:       call.ID,
+				Type:     call.Type,
+				Function: &llm.ToolCallFunctionDelta{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			})
+		}
+		if !emitter.Emit(seq.Stamp(llm.NewDeltaEvent(0, delta))) {
+			return
+		}
+		done := llm.NewProviderDoneEvent(0, c.provider, choice.RawFinishReason)
+		done.Raw = resp.Raw
+		emitter.Emit(seq.Stamp(done))
+	}()
+
+	return emitter.Events(), nil
+}
+
+// responsesRequest converts a request, with the response format already prepared by
+// prepareResponseFormat
+func (c *Client) responsesRequest(req llm.ChatRequest, model string, tools *hostedToolsOption) responsesRequest {
+	out := responsesRequest{
+		Model:           model,
+		Input:           responsesInput(req.Messages),
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		MaxOutputTokens: req.MaxTokens,
+		User:            req.User,
+	}
+	if len(req.Metadata) > 0 {
+		out.Metadata = req.MetadataAsStrings()
+	}
+
+	if search := tools.webSearch; search != nil {
+		tool := map[string]any{"type": "web_search"}
+		if search.ContextSize != "" {
+			tool["search_context_size"] = search.ContextSize
+		}
+		if len(search.AllowedDomains) > 0 {
+			tool["filters"] = map[string]any{"allowed_domains": search.AllowedDomains}
+		}
+		out.Tools = append(out.Tools, tool)
+		out.Include = append(out.Include, "web_search_call.action.sources")
+	}
+	if search := tools.fileSearch; search != nil {
+		tool := map[string]any{"type": "file_search", "vector_store_ids": search.VectorStoreIDs}
+		if search.MaxResults > 0 {
+			tool["max_num_results"] = search.MaxResults
+		}
+		out.Tools = append(out.Tools, tool)
+		if search.IncludeResults {
+			out.Include = append(out.Include, "file_search_call.results")
+		}
+	}
+	for _, tool := range req.Tools {
+		out.Tools = append(out.Tools, map[string]any{
+			"type":        "function",
+			"name":        tool.Function.Name,
+			"description": tool.Function.Description,
+			"parameters":  tool.Function.Parameters,
+		})
+	}
+
+	if format := req.ResponseFormat.Compatible(); format != nil {
+		textFormat := map[string]any{"type": format.Type}
+		if format.JSONSchema != nil {
+			textFormat["name"] = format.JSONSchema.Name
+			textFormat["schema"] = format.JSONSchema.Schema
+			textFormat["strict"] = format.JSONSchema.Strict
+			if format.JSONSchema.Description != "" {
+				textFormat["description"] = format.JSONSchema.Description
+			}
+		}
+		out.Text = map[string]any{"format": textFormat}
+	}
+	return out
+}
+
+// responsesInput converts messages to the input items of the Responses API: messages
+// with their text and images, function calls and their outputs
+func responsesInput(messages []llm.Message) []map[string]any {
+	var input []map[string]any
+	for _, msg := range messages {
+		if msg.Role == llm.RoleTool {
+			input = append(input, map[string]any{
+				"type":    "function_call_output",
+				"call_id": msg.ToolCallID,
+				"output":  msg.GetText(),
+			})
+			continue
+		}
+
+		textType := "input_text"
+		if msg.Role == llm.RoleAssistant {
+			textType = "output_text"
+		}
+		var content []map[string]any
+		for _, part := range msg.Content {
+			switch part := part.(type) {
+			case *llm.TextContent:
+				if strings.TrimSpace(part.GetText()) != "" {
+					content = append(content, map[string]any{"type": textType, "text": part.GetText()})
+				}
+			case *llm.ImageContent:
+				url := part.URL
+				if part.HasData() {
+					url = fmt.Sprintf("data:%s;base64,%s", part.MimeType, base64.StdEncoding.EncodeToString(part.Data))
+				}
+				if url != "" && msg.Role != llm.RoleAssistant {
+					content = append(content, map[string]any{"type": "input_image", "image_url": url})
+				}
+			}
+		}
+		if len(content) > 0 {
+			input = append(input, map[string]any{"role": string(msg.Role), "content": content})
+		}
+
+		for _, call := range msg.ToolCalls {
+			input = append(input, map[string]any{
+				"type":      "function_call",
+				"call_id":   call.ID,
+				"name":      call.Function.Name,
+				"arguments": call.Function.Arguments,
+			})
+		}
+	}
+	return input
+}
+
+// convertResponsesResponse converts a response of the Responses API, with the calls of
+// the hosted tools as builtin tool calls and the annotations of the text as citations
+func (c *Client) convertResponsesResponse(resp responsesResponse) *llm.ChatResponse {
+	message := llm.Message{Role: llm.RoleAssistant}
+	var text strings.Builder
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, part := range item.Content {
+				if part.Type != "output_text" {
+					continue
+				}
+				// Annotations are relative to their part, the parts are joined
+				offset := text.Len()
+				text.WriteString(part.Text)
+				for _, annotation := range part.Annotations {
+					if citation, ok := responsesCitation(annotation, offset); ok {
+						message.Citations = append(message.Citations, citation)
+					}
+				}
+			}
+		case "function_call":
+			message.ToolCalls = append(message.ToolCalls, llm.ToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: llm.ToolCallFunction{Name: item.Name, Arguments: item.Arguments},
+			})
+		case "web_search_call":
+			call := llm.BuiltinToolCall{ID: item.ID, Type: llm.BuiltinToolWebSearch, Status: item.Status}
+			if item.Action != nil {
+				if item.Action.Query != "" {
+					call.Queries = []string{item.Action.Query}
+				}
+				for _, source := range item.Action.Sources {
+					call.Results = append(call.Results, llm.Citation{URL: source.URL})
+				}
+			}
+			message.BuiltinToolCalls = append(message.BuiltinToolCalls, call)
+		case "file_search_call":
+			call := llm.BuiltinToolCall{ID: item.ID, Type: llm.BuiltinToolFileSearch, Status: item.Status, Queries: item.Queries}
+			for _, result := range item.Results {
+				call.Results = append(call.Results, llm.Citation{URL: result.FileID, Title: result.Filename, Text: result.Text})
+			}
+			message.BuiltinToolCalls = append(message.BuiltinToolCalls, call)
+		}
+	}
+	message.Content = []llm.MessageContent{}
+	if text.Len() > 0 {
+		message.Content = []llm.MessageContent{llm.NewTextContent(text.String())}
+	}
+
+	// Map the status to the finish reasons of the Chat Completions API
+	finishReason := "stop"
+	switch {
+	case resp.IncompleteDetails != nil && resp.IncompleteDetails.Reason == "max_output_tokens":
+		finishReason = "length"
+	case resp.IncompleteDetails != nil && resp.IncompleteDetails.Reason == "content_filter":
+		finishReason = "content_filter"
+	case len(message.ToolCalls) > 0:
+		finishReason = "tool_calls"
+	}
+
+	return &llm.ChatResponse{
+		ID:    resp.ID,
+		Model: resp.Model,
+		Choices: []llm.Choice{{
+			Message:         message,
+			FinishReason:    llm.NormalizeFinishReason(c.provider, finishReason),
+			RawFinishReason: finishReason,
+		}},
+		Usage: llm.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+			CacheReadTokens:  resp.Usage.InputTokensDetails.CachedTokens,
+		},
+	}
+}
+
+// responsesCitation converts an annotation of a text part starting at an offset of the
+// message text
+func responsesCitation(annotation responsesAnnotation, offset int) (llm.Citation, bool) {
+	switch annotation.Type {
+	case "url_citation":
+		return llm.Citation{
+			URL:        annotation.URL,
+			Title:      annotation.Title,
+			StartIndex: offset + annotation.StartIndex,
+			EndIndex:   offset + annotation.EndIndex,
+		}, true
+	case "file_citation":
+		return llm.Citation{URL: annotation.FileID, Title: annotation.Filename}, true
+	default:
+		return llm.Citation{}, false
+	}
+}
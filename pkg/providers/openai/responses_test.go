@@ -0,0 +1,160 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// responsesBody is a response of the Responses API with hosted tool calls and citations
+const responsesBody = `{
+	"id": "resp_1",
+	"model": "gpt-4.1-2025-04-14",
+	"status": "completed",
+	"output": [
+		{"type": "web_search_call", "id": "ws_1", "status": "completed",
+		 "action": {"type": "search", "query": "go release date", "sources": [{"type": "url", "url": "https://go.dev/blog"}]}},
+		{"type": "file_search_call", "id": "fs_1", "status": "completed", "queries": ["release policy"],
+		 "results": [{"file_id": "file-1", "filename": "policy.pdf", "text": "Two releases a year.", "score": 0.9}]},
+		{"type": "message", "id": "msg_1", "role": "assistant", "content": [
+			{"type": "output_text", "text": "Go 1.0 shipped in 2012. ",
+			 "annotations": [{"type": "url_citation", "start_index": 0, "end_index": 23, "url": "https://go.dev/blog", "title": "The Go Blog"}]},
+			{"type": "output_text", "text": "Releases are biannual.",
+			 "annotations": [{"type": "url_citation", "start_index": 13, "end_index": 22, "url": "https://go.dev/doc", "title": "Docs"},
+			                 {"type": "file_citation", "index": 22, "file_id": "file-1", "filename": "policy.pdf"}]}
+		]}
+	],
+	"usage": {"input_tokens": 120, "output_tokens": 30, "total_tokens": 150, "input_tokens_details": {"cached_tokens": 100}}
+}`
+
+func TestChatCompletion_HostedTools(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			t.Errorf("Expected a Responses API request, got %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, responsesBody)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "test-key", Model: "gpt-4.1", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	req := llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "Cite your sources."),
+			llm.NewTextMessage(llm.RoleUser, "When was Go released?"),
+		},
+		User: "user-42",
+	}.WithProviderOptions(
+		WithWebSearch(WebSearch{ContextSize: "low", AllowedDomains: []string{"go.dev"}}),
+		WithFileSearch(FileSearch{VectorStoreIDs: []string{"vs_1"}, MaxResults: 5, IncludeResults: true}),
+	)
+
+	resp, err := client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	wantTools := []any{
+		map[string]any{"type": "web_search", "search_context_size": "low", "filters": map[string]any{"allowed_domains": []any{"go.dev"}}},
+		map[string]any{"type": "file_search", "vector_store_ids": []any{"vs_1"}, "max_num_results": float64(5)},
+	}
+	if !reflect.DeepEqual(body["tools"], wantTools) {
+		t.Errorf("Unexpected tools: %v", body["tools"])
+	}
+	if want := []any{"web_search_call.action.sources", "file_search_call.results"}; !reflect.DeepEqual(body["include"], want) {
+		t.Errorf("Unexpected include: %v", body["include"])
+	}
+	wantInput := []any{
+		map[string]any{"role": "system", "content": []any{map[string]any{"type": "input_text", "text": "Cite your sources."}}},
+		map[string]any{"role": "user", "content": []any{map[string]any{"type": "input_text", "text": "When was Go released?"}}},
+	}
+	if !reflect.DeepEqual(body["input"], wantInput) || body["user"] != "user-42" {
+		t.Errorf("Unexpected request: %v", body)
+	}
+
+	message := resp.Choices[0].Message
+	if text := message.GetText(); text != "Go 1.0 shipped in 2012. Releases are biannual." {
+		t.Errorf("Unexpected text: %q", text)
+	}
+	wantCitations := []llm.Citation{
+		{URL: "https://go.dev/blog", Title: "The Go Blog", StartIndex: 0, EndIndex: 23},
+		{URL: "https://go.dev/doc", Title: "Docs", StartIndex: 37, EndIndex: 46},
+		{URL: "file-1", Title: "policy.pdf"},
+	}
+	if !reflect.DeepEqual(message.Citations, wantCitations) {
+		t.Errorf("Unexpected citations:\n got %+v\nwant %+v", message.Citations, wantCitations)
+	}
+	wantCalls := []llm.BuiltinToolCall{
+		{ID: "ws_1", Type: llm.BuiltinToolWebSearch, Status: "completed", Queries: []string{"go release date"},
+			Results: []llm.Citation{{URL: "https://go.dev/blog"}}},
+		{ID: "fs_1", Type: llm.BuiltinToolFileSearch, Status: "completed", Queries: []string{"release policy"},
+			Results: []llm.Citation{{URL: "file-1", Title: "policy.pdf", Text: "Two releases a year."}}},
+	}
+	if !reflect.DeepEqual(message.BuiltinToolCalls, wantCalls) {
+		t.Errorf("Unexpected builtin tool calls:\n got %+v\nwant %+v", message.BuiltinToolCalls, wantCalls)
+	}
+	if resp.Choices[0].FinishReason != llm.FinishReasonStop || resp.Usage.CacheReadTokens != 100 || resp.Usage.TotalTokens != 150 {
+		t.Errorf("Unexpected finish reason or usage: %+v", resp)
+	}
+	if status, _ := resp.Raw.Field("output.0.status"); string(status) != `"completed"` {
+		t.Errorf("Expected the body as raw response, got %s", status)
+	}
+
+	// Streams are sent as a single delta
+	stream, err := client.StreamChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamChatCompletion failed: %v", err)
+	}
+	var text string
+	var done bool
+	for event := range stream {
+		switch {
+		case event.IsDelta():
+			text += event.Choice.Delta.Content[0].(*llm.TextContent).GetText()
+		case event.IsDone():
+			done = true
+		case event.IsError():
+			t.Fatalf("Unexpected error: %v", event.Error)
+		}
+	}
+	if text != message.GetText() || !done {
+		t.Errorf("Unexpected stream: %q, done %v", text, done)
+	}
+}
+
+func TestResponsesInput_ToolCalls(t *testing.T) {
+	assistant := llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{
+		ID: "call_1", Type: "function", Function: llm.ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`},
+	}}}
+	input := responsesInput([]llm.Message{
+		llm.NewTextMessage(llm.RoleUser, "Weather in Paris?"),
+		assistant,
+		{Role: llm.RoleTool, ToolCallID: "call_1", Content: []llm.MessageContent{llm.NewTextContent("Sunny")}},
+	})
+	want := []map[string]any{
+		{"role": "user", "content": []map[string]any{{"type": "input_text", "text": "Weather in Paris?"}}},
+		{"type": "function_call", "call_id": "call_1", "name": "weather", "arguments": `{"city":"Paris"}`},
+		{"type": "function_call_output", "call_id": "call_1", "output": "Sunny"},
+	}
+	if !reflect.DeepEqual(input, want) {
+		t.Errorf("Unexpected input:\n got %v\nwant %v", input, want)
+	}
+
+	resp := (&Client{provider: "openai"}).convertResponsesResponse(responsesResponse{
+		Output: []responsesOutputItem{{Type: "function_call", CallID: "call_2", Name: "weather", Arguments: "{}"}},
+	})
+	if choice := resp.Choices[0]; choice.FinishReason != llm.FinishReasonToolCalls || len(choice.Message.ToolCalls) != 1 {
+		t.Errorf("Expected a tool call, got %+v", choice)
+	}
+}
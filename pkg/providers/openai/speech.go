@@ -0,0 +1,137 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// DefaultSpeechModel is the speech model used when none is given. It follows the
+	// instructions of the options; tts-1 and tts-1-hd ignore them.
+	DefaultSpeechModel = "gpt-4o-mini-tts"
+
+	// DefaultSpeechVoice is the voice used when none is given
+	DefaultSpeechVoice = "alloy"
+
+	// speechChunkSize is the size of the chunks read from streamed speech: about 85ms of
+	// 24kHz PCM audio
+	speechChunkSize = 4096
+)
+
+// speechFormats are the formats of the speech API
+var speechFormats = map[llm.AudioFormat]bool{
+	llm.AudioFormatMP3:  true,
+	llm.AudioFormatOpus: true,
+	llm.AudioFormatAAC:  true,
+	llm.AudioFormatFLAC: true,
+	llm.AudioFormatWAV:  true,
+	llm.AudioFormatPCM:  true,
+}
+
+// Speak implements llm.SpeechClient with the audio speech API. The chat model of the
+// client is not used: the model is given in the options. Audio is MP3 unless another
+// format is given; PCM is 24kHz mono.
+func (c *Client) Speak(ctx context.Context, text string, opts llm.SpeechOptions) (*llm.AudioContent, error) {
+	resp, format, err := c.requestSpeech(ctx, text, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, c.convertError(err)
+	}
+	return &llm.AudioContent{Data: data, MimeType: format.MimeType()}, nil
+}
+
+// StreamSpeech implements llm.SpeechClient, sending the audio as the API transfers it.
+// Use the PCM format for the lowest latency: the other formats have to be decoded as a
+// whole by most players.
+func (c *Client) StreamSpeech(ctx context.Context, text string, opts llm.SpeechOptions) (<-chan llm.SpeechChunk, error) {
+	resp, _, err := c.requestSpeech(ctx, text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan llm.SpeechChunk, llm.DefaultStreamBufferSize)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		for {
+			buf := make([]byte, speechChunkSize)
+			n, err := io.ReadFull(resp.Body, buf)
+			if n > 0 {
+				select {
+				case chunks <- llm.SpeechChunk{Data: buf[:n]}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return
+			}
+			if err != nil {
+				select {
+				case chunks <- llm.SpeechChunk{Error: c.convertError(err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// requestSpeech sends a speech request, returning the response with the audio in its body
+// and the format of the audio
+func (c *Client) requestSpeech(ctx context.Context, text string, opts llm.SpeechOptions) (*http.Response, llm.AudioFormat, error) {
+	if text == "" {
+		return nil, "", &llm.Error{Code: "invalid_request", Message: "no text to speak", Type: "validation_error"}
+	}
+	model := opts.Model
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	voice := opts.Voice
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+	format := opts.Format
+	if format == "" {
+		format = llm.AudioFormatMP3
+	}
+	if !speechFormats[format] {
+		return nil, "", llm.UnsupportedAudioFormatError(c.provider, format)
+	}
+
+	body := map[string]interface{}{
+		"model":           model,
+		"input":           text,
+		"voice":           voice,
+		"response_format": string(format),
+	}
+	if opts.Speed != 0 {
+		body["speed"] = opts.Speed
+	}
+	if opts.Instructions != "" {
+		body["instructions"] = opts.Instructions
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, "/audio/speech", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return resp, format, nil
+}
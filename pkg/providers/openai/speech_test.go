@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestSpeak(t *testing.T) {
+	audio := bytes.Repeat([]byte{1, 2, 3}, speechChunkSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Invalid request: %v", err)
+		}
+		if body["input"] == "fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "invalid voice", "type": "invalid_request_error", "code": "invalid_value"}}`))
+			return
+		}
+		switch body["response_format"] {
+		case "mp3":
+			if body["model"] != DefaultSpeechModel || body["voice"] != DefaultSpeechVoice || body["speed"] != nil || body["instructions"] != nil {
+				t.Errorf("Unexpected defaults: %v", body)
+			}
+		case "pcm":
+			if body["model"] != "tts-1" || body["voice"] != "nova" || body["speed"] != 1.5 || body["instructions"] != "Whisper" {
+				t.Errorf("Unexpected options: %v", body)
+			}
+		}
+		_, _ = w.Write(audio)
+	}))
+	defer server.Close()
+
+	var client llm.SpeechClient = &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}
+
+	speech, err := client.Speak(context.Background(), "Hello", llm.SpeechOptions{})
+	if err != nil {
+		t.Fatalf("Speak failed: %v", err)
+	}
+	if !bytes.Equal(speech.Data, audio) || speech.MimeType != "audio/mpeg" {
+		t.Errorf("Unexpected speech: %d bytes of %s", len(speech.Data), speech.MimeType)
+	}
+
+	opts := llm.SpeechOptions{Model: "tts-1", Voice: "nova", Format: llm.AudioFormatPCM, Speed: 1.5, Instructions: "Whisper"}
+	chunks, err := client.StreamSpeech(context.Background(), "Hello", opts)
+	if err != nil {
+		t.Fatalf("StreamSpeech failed: %v", err)
+	}
+	var count int
+	var streamed []byte
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			t.Fatalf("Unexpected stream error: %v", chunk.Error)
+		}
+		count++
+		streamed = append(streamed, chunk.Data...)
+	}
+	if count != 3 || !bytes.Equal(streamed, audio) {
+		t.Errorf("Expected the audio in 3 chunks, got %d bytes in %d chunks", len(streamed), count)
+	}
+
+	var llmErr *llm.Error
+	_, err = client.Speak(context.Background(), "fail", llm.SpeechOptions{})
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_value" || llmErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an invalid_value error, got %v", err)
+	}
+	_, err = client.StreamSpeech(context.Background(), "Hello", llm.SpeechOptions{Format: "ogg"})
+	if !errors.As(err, &llmErr) || llmErr.Code != "unsupported_audio_format" {
+		t.Errorf("Expected an unsupported_audio_format error, got %v", err)
+	}
+}
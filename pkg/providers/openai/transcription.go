@@ -0,0 +1,109 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultTranscriptionModel is the transcription model used when none is given. Whisper
+// reports the language, duration and segments of the recording; the gpt-4o-transcribe
+// models are more accurate but only return the text.
+const DefaultTranscriptionModel = "whisper-1"
+
+// Transcribe implements llm.TranscriptionClient with the audio transcriptions API. The
+// chat model of the client is not used: the model is given in the options.
+func (c *Client) Transcribe(ctx context.Context, audio *llm.AudioContent, opts llm.TranscriptionOptions) (*llm.Transcription, error) {
+	if err := audio.Validate(); err != nil {
+		return nil, err
+	}
+	model := opts.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+	// Only Whisper supports the verbose format with the segments
+	responseFormat := "json"
+	if strings.HasPrefix(model, "whisper") {
+		responseFormat = "verbose_json"
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+strings.ReplaceAll(audio.FilenameOrDefault(), `"`, "")+`"`)
+	header.Set("Content-Type", audio.MimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return nil, err
+	}
+	fields := [][2]string{
+		{"model", model},
+		{"response_format", responseFormat},
+		{"language", opts.Language},
+		{"prompt", opts.Prompt},
+	}
+	if opts.Temperature != nil {
+		fields = append(fields, [2]string{"temperature", strconv.FormatFloat(float64(*opts.Temperature), 'f', -1, 32)})
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var result transcriptionResponse
+	if err := c.do(ctx, http.MethodPost, "/audio/transcriptions", writer.FormDataContentType(), &body, &result); err != nil {
+		return nil, err
+	}
+
+	transcription := &llm.Transcription{
+		Text:     strings.TrimSpace(result.Text),
+		Language: result.Language,
+		Duration: seconds(result.Duration),
+		Model:    model,
+	}
+	if transcription.Language == "" {
+		transcription.Language = opts.Language
+	}
+	for _, segment := range result.Segments {
+		transcription.Segments = append(transcription.Segments, llm.TranscriptionSegment{
+			Start: seconds(segment.Start),
+			End:   seconds(segment.End),
+			Text:  strings.TrimSpace(segment.Text),
+		})
+	}
+	return transcription, nil
+}
+
+// seconds converts a number of seconds of the API to a duration
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// transcriptionResponse is the json or verbose_json response of the transcriptions API
+type transcriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
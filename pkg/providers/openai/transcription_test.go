@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("Missing file: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != "RIFF" || header.Filename != "audio.wav" || header.Header.Get("Content-Type") != "audio/wav" {
+			t.Errorf("Unexpected file %q (%s, %s)", data, header.Filename, header.Header.Get("Content-Type"))
+		}
+
+		switch model := r.FormValue("model"); model {
+		case "whisper-1":
+			if r.FormValue("response_format") != "verbose_json" || r.FormValue("language") != "es" || r.FormValue("temperature") != "0.2" {
+				t.Errorf("Unexpected fields: %v", r.MultipartForm.Value)
+			}
+			_, _ = w.Write([]byte(`{"task": "transcribe", "language": "spanish", "duration": 3.5, "text": " Hola. ¿Qué tal? ",
+				"segments": [{"id": 0, "start": 0, "end": 1.2, "text": " Hola."}, {"id": 1, "start": 1.2, "end": 3.5, "text": " ¿Qué tal?"}]}`))
+		case "gpt-4o-transcribe":
+			if r.FormValue("response_format") != "json" {
+				t.Errorf("Expected the json format, got %q", r.FormValue("response_format"))
+			}
+			_, _ = w.Write([]byte(`{"text": "Hola."}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "invalid model", "type": "invalid_request_error", "code": "model_not_found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	var client llm.TranscriptionClient = &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}
+	audio := llm.NewAudioContentFromBytes([]byte("RIFF"), "audio/wav")
+	temperature := float32(0.2)
+
+	transcription, err := client.Transcribe(context.Background(), audio, llm.TranscriptionOptions{Language: "es", Temperature: &temperature})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if transcription.Text != "Hola. ¿Qué tal?" || transcription.Language != "spanish" || transcription.Duration != 3500*time.Millisecond || transcription.Model != "whisper-1" {
+		t.Errorf("Unexpected transcription: %+v", transcription)
+	}
+	if len(transcription.Segments) != 2 || transcription.Segments[1].Start != 1200*time.Millisecond || transcription.Segments[1].Text != "¿Qué tal?" {
+		t.Errorf("Unexpected segments: %+v", transcription.Segments)
+	}
+
+	transcription, err = client.Transcribe(context.Background(), audio, llm.TranscriptionOptions{Model: "gpt-4o-transcribe", Language: "es"})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if transcription.Text != "Hola." || transcription.Language != "es" || len(transcription.Segments) != 0 {
+		t.Errorf("Unexpected transcription: %+v", transcription)
+	}
+
+	var llmErr *llm.Error
+	_, err = client.Transcribe(context.Background(), audio, llm.TranscriptionOptions{Model: "unknown"})
+	if !errors.As(err, &llmErr) || llmErr.Code != "model_not_found" || llmErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a model_not_found error, got %v", err)
+	}
+	_, err = client.Transcribe(context.Background(), llm.NewAudioContentFromBytes(nil, "audio/wav"), llm.TranscriptionOptions{})
+	if !errors.As(err, &llmErr) || llmErr.Code != "invalid_audio" {
+		t.Errorf("Expected an invalid_audio error, got %v", err)
+	}
+}
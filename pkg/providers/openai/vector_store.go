@@ -0,0 +1,273 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+const (
+	// documentIDAttribute is the attribute of the vector store files holding the ID of
+	// the document they contain
+	documentIDAttribute = "document_id"
+
+	// vectorStoreFilePurpose is the purpose of the files added to vector stores
+	vectorStoreFilePurpose = "assistants"
+
+	// maxSearchResults is the largest number of results of a vector store search
+	maxSearchResults = 50
+
+	// vectorStorePollInterval is the delay between the checks of files being processed
+	vectorStorePollInterval = time.Second
+)
+
+// VectorStore implements llm.VectorStore with an OpenAI vector store, the one searched
+// by the file_search tool (see WithFileSearch). Every document is uploaded as a text
+// file attached to the store, with its ID and metadata as file attributes, so the
+// metadata values must be strings, numbers or booleans. The store chunks and embeds
+// the files itself: Upsert waits until they are processed.
+type VectorStore struct {
+	client *Client
+	id     string
+}
+
+// vectorStoreFile is a file of a vector store
+type vectorStoreFile struct {
+	ID         string         `json:"id"`
+	Status     string         `json:"status"`
+	Attributes map[string]any `json:"attributes"`
+	LastError  *struct {
+		Message string `json:"message"`
+	} `json:"last_error"`
+}
+
+// VectorStore returns the vector store with the given ID
+func (c *Client) VectorStore(id string) *VectorStore {
+	return &VectorStore{client: c, id: id}
+}
+
+// CreateVectorStore creates an empty vector store
+func (c *Client) CreateVectorStore(ctx context.Context, name string) (*VectorStore, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.postJSON(ctx, "/vector_stores", map[string]interface{}{"name": name}, &created); err != nil {
+		return nil, err
+	}
+	return c.VectorStore(created.ID), nil
+}
+
+// ID returns the ID of the vector store, e.g. for WithFileSearch
+func (s *VectorStore) ID() string {
+	return s.id
+}
+
+// Upsert uploads the documents and adds them to the store, then removes the files of
+// the previous versions of the documents
+func (s *VectorStore) Upsert(ctx context.Context, docs ...llm.Document) error {
+	for _, doc := range docs {
+		if err := doc.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	previous, err := s.documentFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		upload := llm.NewFileUpload(strings.NewReader(doc.Text), int64(len(doc.Text)), doc.ID+".txt", "text/plain")
+		upload.Purpose = vectorStoreFilePurpose
+		uploaded, err := s.client.UploadFile(ctx, upload)
+		if err != nil {
+			return err
+		}
+
+		attributes := make(map[string]any, len(doc.Metadata)+1)
+		for k, v := range doc.Metadata {
+			attributes[k] = v
+		}
+		attributes[documentIDAttribute] = doc.ID
+		var file vectorStoreFile
+		err = s.client.postJSON(ctx, s.path("/files"), map[string]interface{}{
+			"file_id":    uploaded.ID,
+			"attributes": attributes,
+		}, &file)
+		if err != nil {
+			return err
+		}
+		if err := s.waitProcessed(ctx, file); err != nil {
+			return err
+		}
+	}
+
+	for _, doc := range docs {
+		for _, fileID := range previous[doc.ID] {
+			if err := s.deleteFile(ctx, fileID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Query searches the store, returning at most topK (up to 50) documents with their
+// relevance score, between 0 and 1
+func (s *VectorStore) Query(ctx context.Context, text string, topK int) ([]llm.ScoredDocument, error) {
+	if topK <= 0 {
+		return nil, &llm.Error{
+			Code:    "invalid_query",
+			Message: fmt.Sprintf("topK must be positive, got %d", topK),
+			Type:    "validation_error",
+		}
+	}
+	var results struct {
+		Data []struct {
+			FileID     string         `json:"file_id"`
+			Score      float64        `json:"score"`
+			Attributes map[string]any `json:"attributes"`
+			Content    []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"data"`
+	}
+	err := s.client.postJSON(ctx, s.path("/search"), map[string]interface{}{
+		"query":           text,
+		"max_num_results": min(topK, maxSearchResults),
+	}, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]llm.ScoredDocument, 0, len(results.Data))
+	for _, result := range results.Data {
+		var chunks []string
+		for _, content := range result.Content {
+			if content.Type == "text" {
+				chunks = append(chunks, content.Text)
+			}
+		}
+		id, metadata := documentAttributes(result.Attributes)
+		if id == "" {
+			id = result.FileID
+		}
+		matches = append(matches, llm.ScoredDocument{
+			Document: llm.Document{ID: id, Text: strings.Join(chunks, "\n"), Metadata: metadata},
+			Score:    result.Score,
+		})
+	}
+	return matches, nil
+}
+
+// Delete removes the files of the documents from the store, and deletes them
+func (s *VectorStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	files, err := s.documentFiles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		for _, fileID := range files[id] {
+			if err := s.deleteFile(ctx, fileID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// documentFiles lists the files of the store by document ID
+func (s *VectorStore) documentFiles(ctx context.Context) (map[string][]string, error) {
+	files := make(map[string][]string)
+	after := ""
+	for {
+		query := url.Values{"limit": {"100"}}
+		if after != "" {
+			query.Set("after", after)
+		}
+		var page struct {
+			Data    []vectorStoreFile `json:"data"`
+			HasMore bool              `json:"has_more"`
+			LastID  string            `json:"last_id"`
+		}
+		if err := s.client.do(ctx, http.MethodGet, s.path("/files?"+query.Encode()), "", nil, &page); err != nil {
+			return nil, err
+		}
+		for _, file := range page.Data {
+			if id, _ := documentAttributes(file.Attributes); id != "" {
+				files[id] = append(files[id], file.ID)
+			}
+		}
+		if !page.HasMore || len(page.Data) == 0 {
+			return files, nil
+		}
+		after = page.LastID
+		if after == "" {
+			after = page.Data[len(page.Data)-1].ID
+		}
+	}
+}
+
+// waitProcessed polls a file added to the store until it is chunked and embedded
+func (s *VectorStore) waitProcessed(ctx context.Context, file vectorStoreFile) error {
+	for file.Status == "in_progress" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.client.clock.After(vectorStorePollInterval):
+		}
+		if err := s.client.do(ctx, http.MethodGet, s.path("/files/"+url.PathEscape(file.ID)), "", nil, &file); err != nil {
+			return err
+		}
+	}
+	if file.Status == "completed" || file.Status == "" {
+		return nil
+	}
+	message := fmt.Sprintf("file %s of vector store %s is %s", file.ID, s.id, file.Status)
+	if file.LastError != nil && file.LastError.Message != "" {
+		message += ": " + file.LastError.Message
+	}
+	return &llm.Error{Code: "vector_store_file_failed", Message: message, Type: "api_error"}
+}
+
+// deleteFile removes a file from the store, then deletes the file itself
+func (s *VectorStore) deleteFile(ctx context.Context, fileID string) error {
+	var deleted struct{}
+	if err := s.client.do(ctx, http.MethodDelete, s.path("/files/"+url.PathEscape(fileID)), "", nil, &deleted); err != nil {
+		return err
+	}
+	return s.client.do(ctx, http.MethodDelete, "/files/"+url.PathEscape(fileID), "", nil, &deleted)
+}
+
+// path returns the API path of a resource of the store
+func (s *VectorStore) path(resource string) string {
+	return "/vector_stores/" + url.PathEscape(s.id) + resource
+}
+
+// documentAttributes splits the attributes of a file into the ID of its document and
+// the metadata of the document
+func documentAttributes(attributes map[string]any) (string, map[string]any) {
+	id, _ := attributes[documentIDAttribute].(string)
+	var metadata map[string]any
+	for k, v := range attributes {
+		if k == documentIDAttribute {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]any, len(attributes))
+		}
+		metadata[k] = v
+	}
+	return id, metadata
+}
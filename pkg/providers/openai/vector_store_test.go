@@ -0,0 +1,138 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// vectorStoreServer emulates the Uploads, Files and Vector Stores APIs for the vs_1
+// store, recording the deleted files
+func vectorStoreServer(t *testing.T, deleted *[]string) *httptest.Server {
+	var mu sync.Mutex
+	uploads := make(map[string]string)       // upload ID -> content
+	contents := make(map[string]string)      // file ID -> content
+	attached := make(map[string]interface{}) // file ID -> attributes
+	var order []string
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]interface{}
+		if r.Header.Get("Content-Type") == "application/json" {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+		}
+		switch {
+		case r.URL.Path == "/uploads":
+			if body["purpose"] != "assistants" || body["mime_type"] != "text/plain" {
+				t.Errorf("Unexpected upload creation: %v", body)
+			}
+			id := fmt.Sprintf("upload_%d", len(uploads)+1)
+			uploads[id] = ""
+			_, _ = w.Write([]byte(`{"id": "` + id + `", "status": "pending"}`))
+		case strings.HasSuffix(r.URL.Path, "/parts"):
+			file, _, _ := r.FormFile("data")
+			data, _ := io.ReadAll(file)
+			uploads[strings.Split(r.URL.Path, "/")[2]] += string(data)
+			_, _ = w.Write([]byte(`{"id": "part"}`))
+		case strings.HasSuffix(r.URL.Path, "/complete"):
+			id := fmt.Sprintf("file-%d", len(contents)+1)
+			contents[id] = uploads[strings.Split(r.URL.Path, "/")[2]]
+			_, _ = w.Write([]byte(`{"id": "upload", "status": "completed", "file": {"id": "` + id + `"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/vector_stores/vs_1/files":
+			id := body["file_id"].(string)
+			attached[id] = body["attributes"]
+			order = append(order, id)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "status": "completed"})
+		case r.Method == http.MethodGet && r.URL.Path == "/vector_stores/vs_1/files":
+			var data []map[string]interface{}
+			for _, id := range order {
+				if attributes, ok := attached[id]; ok {
+					data = append(data, map[string]interface{}{"id": id, "status": "completed", "attributes": attributes})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "has_more": false})
+		case r.Method == http.MethodPost && r.URL.Path == "/vector_stores/vs_1/search":
+			if body["max_num_results"] != float64(2) {
+				t.Errorf("Unexpected search: %v", body)
+			}
+			data := []map[string]interface{}{}
+			for _, id := range order {
+				if attributes, ok := attached[id]; ok && strings.Contains(contents[id], body["query"].(string)) {
+					data = append(data, map[string]interface{}{
+						"file_id": id, "score": 0.8, "attributes": attributes,
+						"content": []map[string]string{{"type": "text", "text": contents[id]}},
+					})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/vector_stores/vs_1/files/"):
+			delete(attached, strings.TrimPrefix(r.URL.Path, "/vector_stores/vs_1/files/"))
+			_, _ = w.Write([]byte(`{"deleted": true}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/files/"):
+			*deleted = append(*deleted, strings.TrimPrefix(r.URL.Path, "/files/"))
+			_, _ = w.Write([]byte(`{"deleted": true}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVectorStore(t *testing.T) {
+	var deleted []string
+	server := vectorStoreServer(t, &deleted)
+	defer server.Close()
+
+	client := &Client{provider: "openai", baseURL: server.URL, apiKey: "test-key"}
+	var store llm.VectorStore = client.VectorStore("vs_1")
+	ctx := context.Background()
+
+	err := store.Upsert(ctx,
+		llm.Document{ID: "refunds", Text: "Refunds take 5 days.", Metadata: map[string]any{"source": "faq.md"}},
+		llm.Document{ID: "shipping", Text: "Shipping takes 2 days."},
+	)
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	matches, err := store.Query(ctx, "Refunds", 2)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "refunds" || matches[0].Text != "Refunds take 5 days." ||
+		matches[0].Score != 0.8 || matches[0].Metadata["source"] != "faq.md" || len(matches[0].Metadata) != 1 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+
+	// Upserting replaces the file of the document
+	if err := store.Upsert(ctx, llm.Document{ID: "refunds", Text: "Refunds take 3 days."}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	matches, err = store.Query(ctx, "Refunds", 2)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Text != "Refunds take 3 days." || matches[0].Metadata != nil {
+		t.Errorf("Unexpected matches after upsert: %+v", matches)
+	}
+
+	if err := store.Delete(ctx, "shipping", "refunds", "unknown"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	sort.Strings(deleted)
+	if strings.Join(deleted, ",") != "file-1,file-2,file-3" {
+		t.Errorf("Expected all the files to be deleted, got %v", deleted)
+	}
+	if matches, _ := store.Query(ctx, "takes", 2); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+}
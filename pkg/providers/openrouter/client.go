@@ -2,9 +2,9 @@ package openrouter
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"sort"
@@ -23,9 +23,13 @@ type Client struct {
 	provider string
 	config   llm.ClientConfig
 
+	// fetcher inlines remote file URLs, which OpenRouter doesn't accept (opt-in)
+	fetcher *llm.ContentFetcher
+
 	// Health check caching
-	lastHealthCheck  *time.Time
-	lastHealthStatus *bool
+	lastHealthCheck *time.Time
+	stats           *llm.HealthStats
+	clock           llm.Clock
 }
 
 // NewClient creates a new OpenRouter client
@@ -37,6 +41,9 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 			Type:    "authentication_error",
 		}
 	}
+	if err := config.StreamBuffer.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Create OpenRouter client configuration
 	clientConfig := openrouter.DefaultConfig(config.APIKey)
@@ -56,19 +63,62 @@ func NewClient(config llm.ClientConfig) (*Client, error) {
 		}
 	}
 
+	// Record rate-limit headers so they can be attached to errors, and merge the ExtraBody
+	// fields of requests into their payload
+	clientConfig.HTTPClient = &http.Client{Transport: &llm.RateLimitTransport{Base: &llm.ExtraBodyTransport{Base: &llm.DryRunTransport{}}}}
+
 	// Create the OpenRouter client
 	client := openrouter.NewClientWithConfig(*clientConfig)
 
+	// Optionally fetch remote file URLs and send them inline
+	var fetcher *llm.ContentFetcher
+	if config.Extra != nil && config.Extra["fetch_remote_files"] == "true" {
+		var err error
+		fetcher, err = llm.NewContentFetcher(nil)
+		if err != nil {
+			return nil, &llm.Error{
+				Code:    "client_creation_error",
+				Message: fmt.Sprintf("Failed to create content fetcher: %v", err),
+				Type:    "internal_error",
+			}
+		}
+	}
+
 	return &Client{
+		stats:    llm.NewHealthStatsWithClock(config.Clock),
+		clock:    config.GetClock(),
 		client:   client,
 		model:    config.Model,
 		provider: "openrouter",
 		config:   config,
+		fetcher:  fetcher,
 	}, nil
 }
 
 // ChatCompletion performs a chat completion request
 func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	resp, err := c.chatCompletion(ctx, req)
+	err = llm.AttachRateLimit(err, rateLimit.Info())
+	c.stats.Track(ctx, start, err)
+	return resp, err
+}
+
+// chatCompletion performs the chat completion request
+func (c *Client) chatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = c.inlineRemoteFiles(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert our request to OpenRouter format
 	openrouterReq, err := c.convertRequest(req)
 	if err != nil {
@@ -82,11 +132,39 @@ func (c *Client) ChatCompletion(ctx context.Context, req llm.ChatRequest) (*llm.
 	}
 
 	// Convert response back to our format
-	return c.convertResponse(resp), nil
+	response := c.convertResponse(resp)
+	response.Warnings = req.ResponseFormatWarnings(req.ResponseFormat.IsJSON())
+	response.Raw = llm.NewRawResponse(resp, nil)
+	return response, nil
 }
 
 // StreamChatCompletion performs a streaming chat completion request
 func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	start := time.Now()
+	ctx, rateLimit := llm.WithRateLimitCapture(ctx)
+	ctx = llm.WithExtraBody(ctx, req.ExtraBody)
+	stream, err := c.streamChatCompletion(ctx, req)
+	if err != nil {
+		err = llm.AttachRateLimit(err, rateLimit.Info())
+		c.stats.Track(ctx, start, err)
+		return nil, err
+	}
+	return c.stats.TrackStream(ctx, start, stream), nil
+}
+
+// streamChatCompletion performs the streaming chat completion request
+func (c *Client) streamChatCompletion(ctx context.Context, req llm.ChatRequest) (<-chan llm.StreamEvent, error) {
+	// Degrade unsupported content with the registered content transformers
+	req, err := req.TransformContent(ctx, c.GetModelInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = c.inlineRemoteFiles(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert our request to OpenRouter format
 	openrouterReq, err := c.convertRequest(req)
 	if err != nil {
@@ -102,10 +180,11 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 		return nil, c.convertError(err)
 	}
 
-	ch := make(chan llm.StreamEvent, 10)
+	emitter := llm.NewStreamEmitter(ctx, c.config.StreamBuffer)
+	seq := llm.NewStreamSequencer()
 
 	go func() {
-		defer close(ch)
+		defer emitter.Close()
 		defer stream.Close()
 
 		for {
@@ -113,21 +192,23 @@ func (c *Client) StreamChatCompletion(ctx context.Context, req llm.ChatRequest)
 			if err != nil {
 				if err.Error() == "EOF" {
 					// Stream complete
-					ch <- llm.NewDoneEvent(0, "stop")
+					emitter.Emit(seq.Stamp(llm.NewDoneEvent(0, "stop")))
 					return
 				}
-				ch <- llm.NewErrorEvent(c.convertError(err))
+				emitter.Emit(seq.Stamp(llm.NewErrorEvent(c.convertError(err))))
 				return
 			}
 
 			// Convert chunk to delta event
 			if streamEvent := c.convertStreamResponse(response); streamEvent != nil {
-				ch <- *streamEvent
+				if !emitter.Emit(seq.Stamp(*streamEvent)) {
+					return
+				}
 			}
 		}
 	}()
 
-	return ch, nil
+	return emitter.Events(), nil
 }
 
 // GetRemote returns information about the remote client
@@ -137,32 +218,32 @@ func (c *Client) GetRemote() llm.ClientRemoteInfo {
 	}
 
 	// Check if we need to refresh the health status
-	now := time.Now()
+	now := c.clock.Now()
 	needsRefresh := c.lastHealthCheck == nil ||
 		now.Sub(*c.lastHealthCheck) >= llm.DefaultHealthCheckInterval
 
 	if needsRefresh {
-		healthy := c.performHealthCheck()
-		c.lastHealthStatus = &healthy
+		c.stats.Record(0, c.performHealthCheck())
 		c.lastHealthCheck = &now
 	}
 
-	info.Status = &llm.ClientRemoteInfoStatus{
-		Healthy:     c.lastHealthStatus,
-		LastChecked: c.lastHealthCheck,
-	}
+	info.Status = c.stats.Status()
+	info.Status.LastChecked = c.lastHealthCheck
 
 	return info
 }
 
 // performHealthCheck performs a simple health check on the OpenRouter API
-func (c *Client) performHealthCheck() bool {
+func (c *Client) performHealthCheck() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	// Try to list models as a health check
 	_, err := c.client.ListModels(ctx)
-	return err == nil
+	if err != nil {
+		return c.convertError(err)
+	}
+	return nil
 }
 
 // GetModelInfo returns information about the model
@@ -179,6 +260,16 @@ func (c *Client) GetModelInfo() llm.ModelInfo {
 	}
 }
 
+// StreamChatCompletionWithTools streams a chat completion merged with the events of tool
+// streams, implementing llm.StreamingClient
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, req llm.ChatRequest, toolStreams []<-chan llm.StreamEvent) (<-chan llm.StreamEvent, error) {
+	stream, err := c.StreamChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return llm.MergeStreams(ctx, stream, toolStreams...), nil
+}
+
 // Close cleans up resources
 func (c *Client) Close() error {
 	// The go-openrouter client manages its own HTTP client internally
@@ -193,6 +284,14 @@ func (c *Client) Close() error {
 
 // convertRequest converts our llm.ChatRequest to OpenRouter format
 func (c *Client) convertRequest(req llm.ChatRequest) (openrouter.ChatCompletionRequest, error) {
+	req, err := req.PrepareResponseFormat(req.ResponseFormat.IsJSON())
+	if err != nil {
+		return openrouter.ChatCompletionRequest{}, err
+	}
+	if err := req.ResponseFormat.Lint(); err != nil {
+		return openrouter.ChatCompletionRequest{}, err
+	}
+
 	// Use the model from the request if provided, otherwise use the client's model
 	model := req.Model
 	if model == "" {
@@ -216,6 +315,12 @@ func (c *Client) convertRequest(req llm.ChatRequest) (openrouter.ChatCompletionR
 		openrouterReq.TopP = *req.TopP
 	}
 
+	// Forward request metadata (OpenRouter only accepts string values)
+	if len(req.Metadata) > 0 {
+		openrouterReq.Metadata = req.MetadataAsStrings()
+	}
+	openrouterReq.User = req.User
+
 	// Convert messages
 	for _, msg := range req.Messages {
 		openrouterMsg, err := c.convertMessage(msg)
@@ -246,9 +351,33 @@ func (c *Client) convertRequest(req llm.ChatRequest) (openrouter.ChatCompletionR
 		}
 	}
 
+	// Forward the response format natively
+	if req.ResponseFormat != nil {
+		openrouterReq.ResponseFormat = convertResponseFormat(req.ResponseFormat)
+	}
+
 	return openrouterReq, nil
 }
 
+// convertResponseFormat converts our ResponseFormat to OpenRouter format
+func convertResponseFormat(format *llm.ResponseFormat) *openrouter.ChatCompletionResponseFormat {
+	compatible := format.Compatible()
+	if compatible == nil {
+		return nil
+	}
+
+	result := &openrouter.ChatCompletionResponseFormat{Type: openrouter.ChatCompletionResponseFormatType(compatible.Type)}
+	if compatible.JSONSchema != nil {
+		result.JSONSchema = &openrouter.ChatCompletionResponseFormatJSONSchema{
+			Name:        compatible.JSONSchema.Name,
+			Description: compatible.JSONSchema.Description,
+			Schema:      compatible.JSONSchema.Schema,
+			Strict:      compatible.JSONSchema.Strict,
+		}
+	}
+	return result
+}
+
 // convertMessage converts our Message to OpenRouter format
 func (c *Client) convertMessage(msg llm.Message) (openrouter.ChatCompletionMessage, error) {
 	openrouterMsg := openrouter.ChatCompletionMessage{
@@ -318,17 +447,20 @@ func (c *Client) convertResponse(resp openrouter.ChatCompletionResponse) *llm.Ch
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
 			TotalTokens:      resp.Usage.TotalTokens,
+			CacheReadTokens:  resp.Usage.PromptTokenDetails.CachedTokens,
 		}
 	}
 
 	// Convert choices
 	for _, choice := range resp.Choices {
 		ourChoice := llm.Choice{
-			Index:        choice.Index,
-			FinishReason: string(choice.FinishReason),
+			Index:           choice.Index,
+			FinishReason:    llm.NormalizeFinishReason(c.provider, string(choice.FinishReason)),
+			RawFinishReason: string(choice.FinishReason),
 			Message: llm.Message{
-				Role:    llm.MessageRole(choice.Message.Role),
-				Content: []llm.MessageContent{llm.NewTextContent(choice.Message.Content.Text)},
+				Role:      llm.MessageRole(choice.Message.Role),
+				Content:   []llm.MessageContent{llm.NewTextContent(choice.Message.Content.Text)},
+				Citations: convertCitations(choice.Message.Annotations, resp.Citations),
 			},
 		}
 
@@ -354,6 +486,32 @@ func (c *Client) convertResponse(resp openrouter.ChatCompletionResponse) *llm.Ch
 	return response
 }
 
+// convertCitations converts the url_citation annotations of a message (web search
+// results). Models only listing their sources at the top level of the response, like
+// Perplexity, get one citation without a span per source URL.
+func convertCitations(annotations []openrouter.Annotation, sources []string) []llm.Citation {
+	var citations []llm.Citation
+	for _, annotation := range annotations {
+		if annotation.Type != openrouter.AnnotationTypeUrlCitation {
+			continue
+		}
+		citations = append(citations, llm.Citation{
+			URL:        annotation.URLCitation.URL,
+			Title:      annotation.URLCitation.Title,
+			StartIndex: annotation.URLCitation.StartIndex,
+			EndIndex:   annotation.URLCitation.EndIndex,
+			Text:       annotation.URLCitation.Content,
+		})
+	}
+	if len(citations) > 0 {
+		return citations
+	}
+	for _, url := range sources {
+		citations = append(citations, llm.Citation{URL: url})
+	}
+	return citations
+}
+
 // convertStreamResponse converts OpenRouter stream response to our llm.StreamEvent
 func (c *Client) convertStreamResponse(resp openrouter.ChatCompletionStreamResponse) *llm.StreamEvent {
 	if len(resp.Choices) == 0 {
@@ -364,7 +522,7 @@ func (c *Client) convertStreamResponse(resp openrouter.ChatCompletionStreamRespo
 
 	// Handle completion
 	if choice.FinishReason != "" {
-		event := llm.NewDoneEvent(choice.Index, string(choice.FinishReason))
+		event := llm.NewProviderDoneEvent(choice.Index, c.provider, string(choice.FinishReason))
 		return &event
 	}
 
@@ -566,7 +724,7 @@ func (c *Client) convertFileContent(file *llm.FileContent) (openrouter.ChatMessa
 		// OpenRouter doesn't support file URLs directly, so we need to return an error
 		return part, &llm.Error{
 			Code:    "unsupported_feature",
-			Message: "OpenRouter does not support file URLs, only binary file data",
+			Message: "OpenRouter does not support file URLs, only binary file data (set extra.fetch_remote_files=true to fetch them)",
 			Type:    "validation_error",
 		}
 	} else {
@@ -580,6 +738,21 @@ func (c *Client) convertFileContent(file *llm.FileContent) (openrouter.ChatMessa
 	return part, nil
 }
 
+// inlineRemoteFiles downloads URL-based file content when remote fetching is enabled,
+// since OpenRouter only accepts inline file data
+func (c *Client) inlineRemoteFiles(ctx context.Context, req llm.ChatRequest) (llm.ChatRequest, error) {
+	if c.fetcher == nil {
+		return req, nil
+	}
+
+	messages, err := c.fetcher.InlineMessages(ctx, req.Messages, llm.MessageTypeFile)
+	if err != nil {
+		return req, err
+	}
+	req.Messages = messages
+	return req, nil
+}
+
 // convertImageDataToURL converts binary image data to a data URL
 func (c *Client) convertImageDataToURL(img *llm.ImageContent) (string, error) {
 	if !img.HasData() {
@@ -599,12 +772,8 @@ func (c *Client) convertImageDataToURL(img *llm.ImageContent) (string, error) {
 		}
 	}
 
-	// Convert to base64 data URL
-	base64Data := fmt.Sprintf("data:%s;base64,%s",
-		img.MimeType,
-		c.encodeBase64(img.Data))
-
-	return base64Data, nil
+	// Convert to base64 data URL, allocating the encoded size once
+	return llm.EncodeDataURL(img.MimeType, img.Data), nil
 }
 
 // convertFileDataToBase64 converts binary file data to base64 string
@@ -626,12 +795,7 @@ func (c *Client) convertFileDataToBase64(file *llm.FileContent) (string, error)
 		}
 	}
 
-	return c.encodeBase64(file.Data), nil
-}
-
-// encodeBase64 encodes binary data to base64 string
-func (c *Client) encodeBase64(data []byte) string {
-	return base64.StdEncoding.EncodeToString(data)
+	return llm.EncodeBase64(file.Data), nil
 }
 
 // validateToolSupport validates that the model supports tools before sending tool definitions
@@ -765,7 +929,7 @@ func validateParametersSchema(params interface{}) error {
 
 // convertError converts OpenRouter errors to our standardized Error format
 func (c *Client) convertError(err error) *llm.Error {
-	return convertOpenRouterError(err)
+	return llm.RedactError(convertOpenRouterError(err))
 }
 
 // convertOpenRouterError converts OpenRouter errors to our standardized Error format
@@ -824,6 +988,9 @@ func convertAPIError(apiErr *openrouter.APIError) *llm.Error {
 	case 404:
 		errorType = "model_error"
 		errorCode = "model_not_found"
+	case 402:
+		errorType = "quota_error"
+		errorCode = "insufficient_credits"
 	case 429:
 		errorType = "rate_limit_error"
 		errorCode = "rate_limit_exceeded"
@@ -897,12 +1064,17 @@ func convertAPIError(apiErr *openrouter.APIError) *llm.Error {
 		errorCode = "context_length_exceeded"
 	}
 
-	return &llm.Error{
+	llmErr := &llm.Error{
 		Code:       errorCode,
 		Message:    message,
 		Type:       errorType,
 		StatusCode: apiErr.HTTPStatusCode,
 	}
+	// Requests fail with 402 once the account runs out of credits
+	if apiErr.HTTPStatusCode == 402 {
+		llmErr.RateLimit = &llm.RateLimitInfo{CreditsExhausted: true}
+	}
+	return llmErr
 }
 
 // convertRequestError converts OpenRouter RequestError to our Error format
@@ -924,6 +1096,9 @@ func convertRequestError(reqErr *openrouter.RequestError) *llm.Error {
 	case 404:
 		errorType = "model_error"
 		errorCode = "not_found"
+	case 402:
+		errorType = "quota_error"
+		errorCode = "insufficient_credits"
 	case 429:
 		errorType = "rate_limit_error"
 		errorCode = "rate_limit_exceeded"
@@ -944,12 +1119,16 @@ func convertRequestError(reqErr *openrouter.RequestError) *llm.Error {
 	// which includes status code, status, message, and body information
 	message := reqErr.Error()
 
-	return &llm.Error{
+	llmErr := &llm.Error{
 		Code:       errorCode,
 		Message:    message,
 		Type:       errorType,
 		StatusCode: reqErr.HTTPStatusCode,
 	}
+	if reqErr.HTTPStatusCode == 402 {
+		llmErr.RateLimit = &llm.RateLimitInfo{CreditsExhausted: true}
+	}
+	return llmErr
 }
 
 // convertCommonError handles common Go errors that might occur during API calls
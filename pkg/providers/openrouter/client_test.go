@@ -0,0 +1,36 @@
+package openrouter
+
+import (
+	"testing"
+
+	"github.com/revrost/go-openrouter"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+func TestConvertCitations(t *testing.T) {
+	annotations := []openrouter.Annotation{{
+		Type: openrouter.AnnotationTypeUrlCitation,
+		URLCitation: openrouter.URLCitation{
+			URL:        "https://go.dev",
+			Title:      "The Go Programming Language",
+			Content:    "Build simple, secure, scalable systems with Go",
+			StartIndex: 10,
+			EndIndex:   42,
+		},
+	}}
+	sources := []string{"https://go.dev/doc", "https://pkg.go.dev"}
+
+	assert.Equal(t, []llm.Citation{{
+		URL:        "https://go.dev",
+		Title:      "The Go Programming Language",
+		Text:       "Build simple, secure, scalable systems with Go",
+		StartIndex: 10,
+		EndIndex:   42,
+	}}, convertCitations(annotations, sources))
+
+	// Top-level sources are used when the message has no annotations
+	assert.Equal(t, []llm.Citation{{URL: "https://go.dev/doc"}, {URL: "https://pkg.go.dev"}}, convertCitations(nil, sources))
+	assert.Nil(t, convertCitations(nil, nil))
+}
@@ -0,0 +1,13 @@
+// Package openrouter provides an LLM client for the OpenRouter API.
+//
+// This provider implements the llm.Client interface on top of OpenRouter, giving
+// access to the models of many vendors through a single OpenAI-compatible API.
+//
+// Key features:
+//   - Streaming and non-streaming chat completions with tool calling
+//   - Model discovery (ListModels)
+//   - Citations from web search annotations and Perplexity sources (llm.Citation)
+//
+// The client automatically registers itself with the LLM provider registry
+// during package initialization.
+package openrouter
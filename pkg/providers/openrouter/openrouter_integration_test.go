@@ -74,7 +74,7 @@ func TestOpenRouterBasicChatCompletionIntegration(t *testing.T) {
 	// Verify response structure
 	assert.NotEmpty(t, resp.ID)
 	assert.Equal(t, testModel, resp.Model)
-	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Equal(t, llm.FinishReasonStop, resp.Choices[0].FinishReason)
 	assert.Greater(t, resp.Usage.TotalTokens, 0)
 }
 
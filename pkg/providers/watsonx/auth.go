@@ -0,0 +1,157 @@
+package watsonx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// DefaultIAMURL is the IBM Cloud IAM endpoint exchanging API keys for access tokens
+const DefaultIAMURL = "https://iam.cloud.ibm.com"
+
+// tokenRefreshMargin is the time before the expiration of an access token at which it is
+// renewed, so requests never carry a token expiring in flight
+const tokenRefreshMargin = 5 * time.Minute
+
+// iamToken is the response of the IAM token endpoint
+type iamToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Expiration  int64  `json:"expiration"`
+}
+
+// tokenSource exchanges an IBM Cloud API key for IAM access tokens, caching the token
+// until it is about to expire. It is safe for concurrent use; concurrent requests
+// needing a new token wait for a single exchange.
+type tokenSource struct {
+	apiKey     string
+	iamURL     string
+	httpClient *http.Client
+	clock      llm.Clock
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newTokenSource creates a token source for an API key, timing the expiration of the
+// tokens with clock
+func newTokenSource(apiKey, iamURL string, httpClient *http.Client, clock llm.Clock) *tokenSource {
+	return &tokenSource{
+		apiKey:     apiKey,
+		iamURL:     strings.TrimSuffix(iamURL, "/"),
+		httpClient: httpClient,
+		clock:      clock,
+	}
+}
+
+// Token returns a valid access token, exchanging the API key for a new one when there is
+// none or the cached one expires within tokenRefreshMargin
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.clock.Now().Add(tokenRefreshMargin).Before(s.expires) {
+		return s.token, nil
+	}
+
+	token, err := s.exchange(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token.AccessToken
+	switch {
+	case token.Expiration > 0:
+		s.expires = time.Unix(token.Expiration, 0)
+	case token.ExpiresIn > 0:
+		s.expires = s.clock.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	default:
+		// Tokens without an expiration are used for a single request
+		s.expires = s.clock.Now()
+	}
+	return s.token, nil
+}
+
+// Invalidate drops the cached token, after the API rejected it
+func (s *tokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// exchange requests a new access token
+func (s *tokenSource) exchange(ctx context.Context) (*iamToken, error) {
+	form := url.Values{
+		"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"},
+		"apikey":     {s.apiKey},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.iamURL+"/identity/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &llm.Error{
+			Code:    "invalid_request",
+			Message: fmt.Sprintf("failed to create IAM token request: %v", err),
+			Type:    "validation_error",
+		}
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &llm.Error{
+			Code:    "request_failed",
+			Message: fmt.Sprintf("IAM token request failed: %v", err),
+			Type:    "network_error",
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var iamErr struct {
+			ErrorCode    string `json:"errorCode"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		_ = json.Unmarshal(data, &iamErr)
+		message := iamErr.ErrorMessage
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		llmErr := &llm.Error{
+			Code:       "invalid_api_key",
+			Message:    fmt.Sprintf("IBM Cloud IAM rejected the API key: %s", message),
+			Type:       "authentication_error",
+			StatusCode: resp.StatusCode,
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			llmErr.Code, llmErr.Type = "rate_limit_exceeded", "rate_limit_error"
+			llmErr.Message = fmt.Sprintf("IBM Cloud IAM token request was rate limited: %s", message)
+		case resp.StatusCode >= 500:
+			llmErr.Code, llmErr.Type = "iam_unavailable", "api_error"
+			llmErr.Message = fmt.Sprintf("IBM Cloud IAM token request failed: %s", message)
+		}
+		llmErr.RateLimit = llm.ParseRateLimitHeaders(resp.Header)
+		return nil, llm.RedactError(llmErr)
+	}
+
+	var token iamToken
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" {
+		return nil, &llm.Error{
+			Code:    "invalid_response",
+			Message: "IBM Cloud IAM returned no access token",
+			Type:    "authentication_error",
+		}
+	}
+	return &token, nil
+}
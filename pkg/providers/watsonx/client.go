@@ -0,0 +1,138 @@
+package watsonx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// DefaultBaseURL is the watsonx.ai endpoint of the Dallas region. Other regions are
+// selected with Extra["region"] (e.g. "eu-de" for Frankfurt) or a BaseURL.
+const DefaultBaseURL = "https://us-south.ml.cloud.ibm.com"
+
+// DefaultAPIVersion is the version date sent with every watsonx.ai request
+const DefaultAPIVersion = "2024-10-08"
+
+// Model name markers used to detect capabilities. watsonx.ai model IDs look like
+// "ibm/granite-3-8b-instruct" or "meta-llama/llama-3-3-70b-instruct".
+var (
+	toolModelMarkers   = []string{"granite-3", "llama-3-1", "llama-3-2", "llama-3-3", "llama-4", "mistral-large", "mistral-medium", "mistral-small"}
+	visionModelMarkers = []string{"vision", "pixtral", "llama-4"}
+)
+
+// Client implements the llm.Client interface for IBM watsonx.ai
+type Client struct {
+	*oaicompat.Client
+
+	tokens *tokenSource
+}
+
+// NewClient creates a new watsonx.ai client. The API key is an IBM Cloud API key,
+// exchanged for IAM access tokens that are renewed before they expire. Requests are
+// scoped to the project set in Extra["project_id"] or to the deployment space set in
+// Extra["space_id"].
+func NewClient(config llm.ClientConfig) (*Client, error) {
+	if config.APIKey == "" {
+		return nil, &llm.Error{
+			Code:    "missing_api_key",
+			Message: "IBM Cloud API key is required for watsonx.ai",
+			Type:    "authentication_error",
+		}
+	}
+
+	extra := config.Extra
+	if extra == nil {
+		extra = map[string]string{}
+	}
+	projectID, spaceID := extra["project_id"], extra["space_id"]
+	if (projectID == "") == (spaceID == "") {
+		return nil, &llm.Error{
+			Code:    "missing_project",
+			Message: "watsonx.ai requests need either Extra[\"project_id\"] or Extra[\"space_id\"]",
+			Type:    "validation_error",
+		}
+	}
+
+	baseURL := DefaultBaseURL
+	if region := extra["region"]; region != "" {
+		baseURL = fmt.Sprintf("https://%s.ml.cloud.ibm.com", region)
+	}
+
+	apiVersion := extra["api_version"]
+	if apiVersion == "" {
+		apiVersion = DefaultAPIVersion
+	}
+
+	iamURL := extra["iam_url"]
+	if iamURL == "" {
+		iamURL = DefaultIAMURL
+	}
+
+	// The API key is only sent to IAM, in exchange for the tokens of the requests
+	tokens := newTokenSource(config.APIKey, iamURL, oaicompat.NewHTTPClient(config), config.GetClock())
+
+	client, err := oaicompat.NewClient(config, oaicompat.Config{
+		Provider:           "watsonx",
+		Name:               "watsonx.ai",
+		DefaultBaseURL:     baseURL,
+		ToolModelMarkers:   toolModelMarkers,
+		VisionModelMarkers: visionModelMarkers,
+		MaxTokens:          maxTokensForModel(config.Model),
+		ChatPath:           "/ml/v1/text/chat",
+		StreamPath:         "/ml/v1/text/chat_stream",
+		// The specification of the model is a lightweight health check
+		HealthPath: "/ml/v1/foundation_model_specs?limit=1&filters=" + url.QueryEscape("modelid_"+config.Model),
+		Query:      url.Values{"version": {apiVersion}},
+		Token: func(ctx context.Context) (string, error) {
+			// Dry runs do not exchange the API key
+			if llm.IsDryRun(ctx) {
+				return "dry-run", nil
+			}
+			return tokens.Token(ctx)
+		},
+		Invalidate: tokens.Invalidate,
+		ParseError: parseError,
+		BuildRequest: func(req llm.ChatRequest, body oaicompat.Request) (interface{}, error) {
+			wxReq := &chatRequest{
+				ModelID:        body.Model,
+				ProjectID:      projectID,
+				SpaceID:        spaceID,
+				Request:        body,
+				ResponseFormat: req.ResponseFormat.Compatible(),
+			}
+			// The model is sent as model_id, and the API has no end-user field
+			wxReq.Model, wxReq.User = "", ""
+			return wxReq, nil
+		},
+		ConvertResponse: func(data []byte, resp *llm.ChatResponse) {
+			var wxResp chatResponse
+			_ = json.Unmarshal(data, &wxResp)
+			resp.Model = wxResp.ModelID
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: client, tokens: tokens}, nil
+}
+
+// maxTokensForModel returns the context length of well-known watsonx.ai models
+func maxTokensForModel(model string) int {
+	model = strings.ToLower(model)
+	switch {
+	case strings.Contains(model, "granite-3-3"), strings.Contains(model, "granite-3-2"),
+		strings.Contains(model, "llama-3-1"), strings.Contains(model, "llama-3-2"),
+		strings.Contains(model, "llama-3-3"), strings.Contains(model, "llama-4"),
+		strings.Contains(model, "mistral-large"), strings.Contains(model, "mistral-medium"):
+		return 131072
+	case strings.Contains(model, "granite-3"), strings.Contains(model, "pixtral"):
+		return 128000
+	default:
+		return 8192
+	}
+}
@@ -0,0 +1,426 @@
+package watsonx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inercia/go-llm/pkg/llm"
+)
+
+// testServer serves both the IAM token endpoint and the watsonx.ai API
+type testServer struct {
+	client    *Client
+	exchanges atomic.Int32
+	lastBody  map[string]interface{}
+	lastQuery string
+}
+
+// newTestServer starts a server issuing tokens "token-1", "token-2"... valid for an hour
+// and answering API requests with handler
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *testServer {
+	t.Helper()
+
+	ts := &testServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/identity/token" {
+			_ = r.ParseForm()
+			if r.Form.Get("apikey") != "test-key" || r.Form.Get("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"errorCode":"BXNIM0415E","errorMessage":"Provided API key could not be found."}`)
+				return
+			}
+			n := ts.exchanges.Add(1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		ts.lastBody = nil
+		_ = json.Unmarshal(data, &ts.lastBody)
+		ts.lastQuery = r.URL.RawQuery
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(llm.ClientConfig{
+		APIKey:  "test-key",
+		Model:   "ibm/granite-3-8b-instruct",
+		BaseURL: server.URL,
+		Extra: map[string]string{
+			"project_id": "project-1",
+			"iam_url":    server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ts.client = client
+	return ts
+}
+
+func TestNewClient(t *testing.T) {
+	project := map[string]string{"project_id": "p"}
+	if _, err := NewClient(llm.ClientConfig{Model: "m", Extra: project}); err == nil {
+		t.Error("Expected error for missing API key")
+	}
+	if _, err := NewClient(llm.ClientConfig{APIKey: "k", Extra: project}); err == nil {
+		t.Error("Expected error for missing model")
+	}
+	if _, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "m"}); err == nil {
+		t.Error("Expected error for missing project and space")
+	}
+	if _, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "m", Extra: map[string]string{"project_id": "p", "space_id": "s"}}); err == nil {
+		t.Error("Expected error for both project and space")
+	}
+
+	client, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "meta-llama/llama-3-2-90b-vision-instruct", Extra: project})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info := client.GetModelInfo()
+	if info.Provider != "watsonx" || !info.SupportsTools || !info.SupportsVision || info.MaxTokens != 131072 {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")}}
+	dryRun, err := llm.DryRun(context.Background(), client, req)
+	if err != nil || dryRun.URL != DefaultBaseURL+"/ml/v1/text/chat?version="+DefaultAPIVersion {
+		t.Errorf("Expected default base URL and version, got %v (%v)", dryRun, err)
+	}
+
+	client, _ = NewClient(llm.ClientConfig{APIKey: "k", Model: "m", Extra: map[string]string{"space_id": "s", "region": "eu-de"}})
+	dryRun, err = llm.DryRun(context.Background(), client, req)
+	if err != nil || !strings.HasPrefix(dryRun.URL, "https://eu-de.ml.cloud.ibm.com/") || !strings.Contains(string(dryRun.Body), `"space_id": "s"`) {
+		t.Errorf("Unexpected regional request: %v (%v)", dryRun, err)
+	}
+}
+
+func TestChatCompletion(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ml/v1/text/chat" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		_, _ = fmt.Fprint(w, `{
+			"id": "chat-1",
+			"model_id": "ibm/granite-3-8b-instruct",
+			"choices": [{
+				"index": 0,
+				"message": {
+					"role": "assistant",
+					"tool_calls": [{"id": "call-1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+		}`)
+	})
+
+	resp, err := ts.client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.NewTextMessage(llm.RoleSystem, "Be brief"),
+			llm.NewTextMessage(llm.RoleUser, "Weather in Paris?"),
+		},
+		Tools: []llm.Tool{{Type: "function", Function: llm.ToolFunction{Name: "get_weather", Parameters: map[string]interface{}{"type": "object"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ts.lastQuery != "version="+DefaultAPIVersion {
+		t.Errorf("Unexpected query %q", ts.lastQuery)
+	}
+	body := ts.lastBody
+	if body["model_id"] != "ibm/granite-3-8b-instruct" || body["project_id"] != "project-1" {
+		t.Errorf("Unexpected model or project: %v", body)
+	}
+	if _, ok := body["space_id"]; ok {
+		t.Error("Expected no space_id")
+	}
+	if messages := body["messages"].([]interface{}); len(messages) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(messages))
+	}
+	if tools := body["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("Expected 1 tool, got %d", len(tools))
+	}
+
+	if resp.ID != "chat-1" || resp.Model != "ibm/granite-3-8b-instruct" || resp.Usage.TotalTokens != 15 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != llm.FinishReasonToolCalls || len(choice.Message.ToolCalls) != 1 {
+		t.Errorf("Unexpected choice: %+v", choice)
+	}
+	if choice.Message.ToolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("Unexpected tool call: %+v", choice.Message.ToolCalls[0])
+	}
+}
+
+func TestChatCompletion_NormalizesTextGenerationFinishReasons(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "chat-1", "choices": [{"message": {"role": "assistant", "content": "Hi"}, "finish_reason": "max_tokens"}]}`)
+	})
+
+	resp, err := ts.client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Choices[0].FinishReason != llm.FinishReasonLength || resp.Choices[0].RawFinishReason != "max_tokens" {
+		t.Errorf("Unexpected finish reason: %+v", resp.Choices[0])
+	}
+}
+
+func TestTokenCachedAndRenewedBeforeExpiration(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"id": "chat-1", "choices": [{"message": {"role": "assistant", "content": %q}, "finish_reason": "stop"}]}`,
+			r.Header.Get("Authorization"))
+	})
+	clock := llm.NewFakeClock(time.Now())
+	ts.client.tokens.clock = clock
+
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")}}
+	for i := 0; i < 3; i++ {
+		resp, err := ts.client.ChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if text := resp.Choices[0].Message.GetText(); text != "Bearer token-1" {
+			t.Errorf("Expected the cached token, got %q", text)
+		}
+	}
+	if n := ts.exchanges.Load(); n != 1 {
+		t.Errorf("Expected 1 token exchange, got %d", n)
+	}
+
+	// The token is renewed once it expires within the refresh margin
+	clock.Advance(time.Hour - tokenRefreshMargin + time.Second)
+	resp, err := ts.client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text := resp.Choices[0].Message.GetText(); text != "Bearer token-2" {
+		t.Errorf("Expected a renewed token, got %q", text)
+	}
+}
+
+func TestUnauthorizedRetriedWithNewToken(t *testing.T) {
+	var calls atomic.Int32
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"errors": [{"code": "authentication_token_expired", "message": "Failed to authenticate the request due to an expired token"}], "status_code": 401}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"id": "chat-1", "choices": [{"message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]}`)
+	})
+
+	_, err := ts.client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls.Load() != 2 || ts.exchanges.Load() != 2 {
+		t.Errorf("Expected 2 calls and 2 token exchanges, got %d and %d", calls.Load(), ts.exchanges.Load())
+	}
+}
+
+func TestInvalidAPIKey(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("The API should not be called without a token")
+	})
+	ts.client.tokens.apiKey = "wrong-key"
+
+	_, err := ts.client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	llmErr, ok := err.(*llm.Error)
+	if !ok {
+		t.Fatalf("Expected *llm.Error, got %T: %v", err, err)
+	}
+	if llmErr.Type != "authentication_error" || !strings.Contains(llmErr.Message, "could not be found") {
+		t.Errorf("Unexpected error: %+v", llmErr)
+	}
+}
+
+func TestErrorConversion(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = fmt.Fprint(w, `{"errors": [{"code": "too_many_requests", "message": "Rate limit exceeded"}], "trace": "abc", "status_code": 429}`)
+	})
+
+	_, err := ts.client.ChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	llmErr, ok := err.(*llm.Error)
+	if !ok {
+		t.Fatalf("Expected *llm.Error, got %T: %v", err, err)
+	}
+	if llmErr.Type != "rate_limit_error" || llmErr.Code != "too_many_requests" || llmErr.Message != "Rate limit exceeded" {
+		t.Errorf("Unexpected error: %+v", llmErr)
+	}
+	if llmErr.RateLimit == nil || llmErr.RateLimit.RetryAfter != 3*time.Second {
+		t.Errorf("Expected the Retry-After header to be parsed, got %+v", llmErr.RateLimit)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ml/v1/text/chat_stream" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices": [{"index": 0, "delta": {"role": "assistant", "content": "Hel"}}]}`,
+			`{"choices": [{"index": 0, "delta": {"content": "lo"}}]}`,
+			`{"choices": [{"index": 0, "delta": {}, "finish_reason": "eos_token"}], "usage": {"total_tokens": 7}}`,
+		}
+		for i, chunk := range chunks {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", i+1, chunk)
+		}
+	})
+
+	events, err := ts.client.StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var text string
+	var done *llm.StreamEvent
+	for event := range events {
+		switch {
+		case event.IsError():
+			t.Fatalf("Unexpected error event: %v", event.Error)
+		case event.IsDelta():
+			text += event.Choice.Delta.Content[0].(*llm.TextContent).Text
+		case event.IsDone():
+			done = &event
+		}
+	}
+	if text != "Hello" {
+		t.Errorf("Expected 'Hello', got %q", text)
+	}
+	if _, ok := ts.lastBody["stream"]; ok {
+		t.Error("Expected no stream field on the chat_stream endpoint")
+	}
+	if done == nil || done.Choice.FinishReason != llm.FinishReasonStop {
+		t.Errorf("Unexpected done event: %+v", done)
+	}
+}
+
+func TestStreamChatCompletion_ErrorEvent(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "id: 1\nevent: error\ndata: {\"errors\": [{\"code\": \"model_error\", \"message\": \"Generation failed\"}]}\n\n")
+	})
+
+	events, err := ts.client.StreamChatCompletion(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var streamErr *llm.Error
+	for event := range events {
+		if event.IsError() {
+			streamErr = event.Error
+		}
+	}
+	if streamErr == nil || streamErr.Code != "model_error" || streamErr.Message != "Generation failed" {
+		t.Errorf("Unexpected stream error: %+v", streamErr)
+	}
+}
+
+func TestConvertRequest_ResponseFormatAndImages(t *testing.T) {
+	client, err := NewClient(llm.ClientConfig{APIKey: "k", Model: "ibm/granite-3-8b-instruct", Extra: map[string]string{"space_id": "s"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dryRun, err := llm.DryRun(context.Background(), client, llm.ChatRequest{
+		Messages:       []llm.Message{llm.NewTextMessage(llm.RoleUser, "List colors")},
+		ResponseFormat: llm.NewJSONResponseFormat(),
+		User:           "user-42",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var wxReq chatRequest
+	if err := json.Unmarshal(dryRun.Body, &wxReq); err != nil || wxReq.ResponseFormat == nil || wxReq.ResponseFormat.Type != "json_object" || wxReq.SpaceID != "s" {
+		t.Errorf("Unexpected request: %s (%v)", dryRun.Body, err)
+	}
+	if wxReq.ModelID != "ibm/granite-3-8b-instruct" || wxReq.Model != "" || wxReq.User != "" {
+		t.Errorf("Expected the model as model_id only and no user, got %s", dryRun.Body)
+	}
+
+	_, err = llm.DryRun(context.Background(), client, llm.ChatRequest{
+		Messages: []llm.Message{{
+			Role:    llm.RoleUser,
+			Content: []llm.MessageContent{llm.NewImageContentFromBytes([]byte{1, 2, 3}, "image/png")},
+		}},
+	})
+	if llmErr, ok := err.(*llm.Error); !ok || llmErr.Code != "vision_not_supported" {
+		t.Errorf("Expected vision_not_supported, got %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("The dry run must not send the request")
+	})
+
+	req := llm.ChatRequest{Messages: []llm.Message{llm.NewTextMessage(llm.RoleUser, "Hello")}}
+	dryRun, err := llm.DryRun(context.Background(), ts.client, req)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if ts.exchanges.Load() != 0 {
+		t.Error("Expected the dry run not to exchange the API key")
+	}
+	if dryRun.Method != http.MethodPost || !strings.Contains(dryRun.URL, "/ml/v1/text/chat?version=") {
+		t.Errorf("Unexpected request %s %s", dryRun.Method, dryRun.URL)
+	}
+	var body chatRequest
+	if err := json.Unmarshal(dryRun.Body, &body); err != nil || body.ProjectID != "project-1" || len(body.Messages) != 1 {
+		t.Errorf("Unexpected payload %s (%v)", dryRun.Body, err)
+	}
+	if got := dryRun.Header.Get("Authorization"); got != "[REDACTED]" {
+		t.Errorf("Expected the credentials to be redacted, got %q", got)
+	}
+}
+
+func TestChatCompletion_ResponseFormatWarning(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"yes"},"finish_reason":"stop"}]}`)
+	})
+
+	req := llm.ChatRequest{
+		Messages:       []llm.Message{llm.NewTextMessage(llm.RoleUser, "Yes or no?")},
+		ResponseFormat: &llm.ResponseFormat{Type: llm.ResponseFormatGrammar, Grammar: `root ::= "yes" | "no"`},
+	}
+	resp, err := ts.client.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Code != llm.WarningResponseFormatPrompted {
+		t.Errorf("Expected a response_format_prompted warning, got %+v", resp.Warnings)
+	}
+	if ts.lastBody["response_format"] != nil {
+		t.Errorf("Expected the grammar to be requested in the prompt, got %v", ts.lastBody["response_format"])
+	}
+}
@@ -0,0 +1,36 @@
+// Package watsonx provides an LLM client for IBM watsonx.ai.
+//
+// This provider implements the llm.Client interface on top of the watsonx.ai text chat
+// API, serving the foundation models hosted by IBM (Granite, Llama, Mistral...), with
+// both streaming and non-streaming chat completions.
+//
+// Key features:
+//   - IAM authentication: the IBM Cloud API key is exchanged for access tokens, cached
+//     and renewed before they expire; a request rejected with 401 is retried once with
+//     a new token
+//   - Project and deployment space scoping with Extra["project_id"] or Extra["space_id"]
+//   - Regional endpoints selected with Extra["region"] (e.g. "eu-de") or BaseURL
+//   - Function calling for models that support it (Granite 3, Llama 3.1+, Mistral),
+//     detected from the model name or forced with Extra["supports_tools"]
+//   - Image inputs for vision models, detected from the model name or forced with
+//     Extra["supports_vision"]
+//   - Structured outputs: llm.ResponseFormatJSON and llm.ResponseFormatJSONSchema are
+//     sent as the native response_format
+//   - Text files are inlined in the prompt; other file attachments are rejected
+//
+// Usage:
+//
+//	config := llm.ClientConfig{
+//	    Provider: "watsonx",
+//	    APIKey:   "your-ibm-cloud-api-key",
+//	    Model:    "ibm/granite-3-8b-instruct",
+//	    Extra: map[string]string{
+//	        "project_id": "your-project-id",
+//	        "region":     "eu-de", // optional, defaults to us-south
+//	    },
+//	}
+//	client, err := factory.New().CreateClient(config)
+//
+// Other Extra settings are "iam_url", for IAM endpoints other than IBM Cloud's, and
+// "api_version", the version date of the API (DefaultAPIVersion when empty).
+package watsonx
@@ -0,0 +1,54 @@
+package watsonx
+
+import (
+	"encoding/json"
+
+	"github.com/inercia/go-llm/pkg/llm"
+	"github.com/inercia/go-llm/pkg/providers/internal/oaicompat"
+)
+
+// Wire types for the watsonx.ai text chat API, extending the OpenAI-compatible ones
+
+// chatRequest is scoped to a project or a deployment space, exactly one of which is set
+type chatRequest struct {
+	ModelID   string `json:"model_id"`
+	ProjectID string `json:"project_id,omitempty"`
+	SpaceID   string `json:"space_id,omitempty"`
+	oaicompat.Request
+	ResponseFormat *llm.CompatibleResponseFormat `json:"response_format,omitempty"`
+}
+
+// chatResponse holds the fields of the responses named otherwise than in OpenAI's
+type chatResponse struct {
+	ModelID string `json:"model_id"`
+}
+
+// apiErrorResponse is the error envelope of the watsonx.ai API, also sent as the data of
+// the stream error events
+type apiErrorResponse struct {
+	Errors     []apiError `json:"errors"`
+	Trace      string     `json:"trace"`
+	StatusCode int        `json:"status_code"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// parseError decodes the first error of an error envelope, classifying it by HTTP status
+// and error code. It returns nil when the data holds no error.
+func parseError(statusCode int, data []byte) *llm.Error {
+	var envelope apiErrorResponse
+	if err := json.Unmarshal(data, &envelope); err != nil || len(envelope.Errors) == 0 {
+		return nil
+	}
+	apiErr := envelope.Errors[0]
+
+	llmErr := (&oaicompat.APIError{Message: apiErr.Message, Code: apiErr.Code}).LLMError(statusCode)
+	// Expired and rejected tokens are reported with their own codes
+	if apiErr.Code == "authentication_token_expired" || apiErr.Code == "authorization_rejected" {
+		llmErr.Type = "authentication_error"
+	}
+	return llmErr
+}
@@ -41,7 +41,7 @@ func TestStreamingBasicFunctionality(t *testing.T) {
 		deltaCount := 0
 		var fullResponse strings.Builder
 		hasContent := false
-		finishReason := ""
+		var finishReason llm.FinishReason
 
 		for event := range stream {
 			eventCount++